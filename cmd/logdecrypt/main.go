@@ -0,0 +1,112 @@
+// Command logdecrypt decrypts log files written through an encrypt.Writer
+// and prints their entries, so that they can be read or piped to other
+// tools such as logfmt-pretty.
+//
+// Usage:
+//
+//	logdecrypt -key ID=FILE [-key ID=FILE ...] [file ...]
+//
+// It reads the files in order, or standard input when none is given. Each
+// -key names a file holding the hex-encoded secret of the key with that
+// ID; give every key the files were encrypted with since their last
+// rotation:
+//
+//	logdecrypt -key 2024-05=/etc/app/keys/2024-05 -key 2024-06=/etc/app/keys/2024-06 app.log.enc | logfmt-pretty
+//
+// The entries of a file are printed up to its last whole frame. A file
+// ending inside a frame, as a crash can leave it, is reported as truncated;
+// frames that fail authentication, because they were altered or the key is
+// wrong, stop the command. Either way it exits with status 1.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/barnowlsnest/go-logslib/pkg/encrypt"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command with args and returns its exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("logdecrypt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var keys []encrypt.Key
+	flags.Func("key", "ID=FILE of a key, repeated for each key", func(arg string) error {
+		key, err := loadKey(arg)
+		if err == nil {
+			keys = append(keys, key)
+		}
+		return err
+	})
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if len(keys) == 0 {
+		fmt.Fprintln(stderr, "logdecrypt: at least one -key is required")
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		if err := decrypt(stdin, stdout, keys); err != nil {
+			fmt.Fprintln(stderr, "logdecrypt:", err)
+			return 1
+		}
+		return 0
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		if err := decryptFile(path, stdout, keys); err != nil {
+			fmt.Fprintf(stderr, "logdecrypt: %s: %v\n", path, err)
+			if !errors.Is(err, io.ErrUnexpectedEOF) {
+				return 1
+			}
+			status = 1
+		}
+	}
+	return status
+}
+
+// loadKey loads the key given to -key as ID=FILE.
+func loadKey(arg string) (encrypt.Key, error) {
+	id, path, ok := strings.Cut(arg, "=")
+	if !ok {
+		return encrypt.Key{}, errors.New("want ID=FILE")
+	}
+	secret, err := os.ReadFile(path)
+	if err != nil {
+		return encrypt.Key{}, err
+	}
+	return encrypt.ParseKey(id, string(secret))
+}
+
+// decryptFile decrypts the file at path to w.
+func decryptFile(path string, w io.Writer, keys []encrypt.Key) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return decrypt(f, w, keys)
+}
+
+// decrypt decrypts r to w.
+func decrypt(r io.Reader, w io.Writer, keys []encrypt.Key) error {
+	rd, err := encrypt.NewReader(r, keys...)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, rd)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("truncated: %w", err)
+	}
+	return err
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/barnowlsnest/go-logslib/pkg/encrypt"
+)
+
+const (
+	secret1 = "000102030405060708090a0b0c0d0e0f000102030405060708090a0b0c0d0e0f"
+	secret2 = "0f0e0d0c0b0a09080706050403020100"
+)
+
+// setup writes key files for k1 and k2 and returns the -key flags.
+func setup(t *testing.T) (dir string, flags []string) {
+	dir = t.TempDir()
+	for id, secret := range map[string]string{"k1": secret1, "k2": secret2} {
+		path := filepath.Join(dir, id+".key")
+		require.NoError(t, os.WriteFile(path, []byte(secret+"\n"), 0o600))
+		flags = append(flags, "-key", id+"="+path)
+	}
+	return dir, flags
+}
+
+// encryptEntries encrypts entries with k1, then the rest with k2 if given.
+func encryptEntries(t *testing.T, entries []string, rotated ...string) []byte {
+	key1, err := encrypt.ParseKey("k1", secret1)
+	require.NoError(t, err)
+	key2, err := encrypt.ParseKey("k2", secret2)
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	w, err := encrypt.New(buf, encrypt.Config{Key: key1})
+	require.NoError(t, err)
+	for _, e := range entries {
+		_, err := w.Write([]byte(e))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Rotate(key2))
+	for _, e := range rotated {
+		_, err := w.Write([]byte(e))
+		require.NoError(t, err)
+	}
+	return buf.Bytes()
+}
+
+func TestRun_Files(t *testing.T) {
+	dir, keys := setup(t)
+	first := filepath.Join(dir, "app.log.1.enc")
+	second := filepath.Join(dir, "app.log.enc")
+	require.NoError(t, os.WriteFile(first, encryptEntries(t, []string{"a\n", "b\n"}), 0o600))
+	require.NoError(t, os.WriteFile(second, encryptEntries(t, []string{"c\n"}, "d\n"), 0o600))
+	var stdout, stderr bytes.Buffer
+
+	status := run(append(keys, first, second), nil, &stdout, &stderr)
+	assert.Zero(t, status, stderr.String())
+	assert.Equal(t, "a\nb\nc\nd\n", stdout.String())
+}
+
+func TestRun_Stdin(t *testing.T) {
+	_, keys := setup(t)
+	var stdout, stderr bytes.Buffer
+
+	status := run(keys, bytes.NewReader(encryptEntries(t, []string{"from stdin\n"})), &stdout, &stderr)
+	assert.Zero(t, status, stderr.String())
+	assert.Equal(t, "from stdin\n", stdout.String())
+}
+
+func TestRun_Truncated(t *testing.T) {
+	dir, keys := setup(t)
+	torn := filepath.Join(dir, "torn.enc")
+	data := encryptEntries(t, []string{"kept\n", "torn\n"})
+	require.NoError(t, os.WriteFile(torn, data[:len(data)-5], 0o600))
+	next := filepath.Join(dir, "next.enc")
+	require.NoError(t, os.WriteFile(next, encryptEntries(t, []string{"next\n"}), 0o600))
+	var stdout, stderr bytes.Buffer
+
+	status := run(append(keys, torn, next), nil, &stdout, &stderr)
+	assert.Equal(t, 1, status)
+	assert.Equal(t, "kept\nnext\n", stdout.String(), "the following files are still decrypted")
+	assert.Contains(t, stderr.String(), torn+": truncated")
+}
+
+func TestRun_WrongKey(t *testing.T) {
+	dir, _ := setup(t)
+	path := filepath.Join(dir, "app.log.enc")
+	require.NoError(t, os.WriteFile(path, encryptEntries(t, []string{"secret\n"}), 0o600))
+	wrong := filepath.Join(dir, "wrong.key")
+	require.NoError(t, os.WriteFile(wrong, []byte(strings.Repeat("ff", 32)), 0o600))
+	var stdout, stderr bytes.Buffer
+
+	status := run([]string{"-key", "k1=" + wrong, path}, nil, &stdout, &stderr)
+	assert.Equal(t, 1, status)
+	assert.Empty(t, stdout.String())
+	assert.Contains(t, stderr.String(), "corrupt frame")
+}
+
+func TestRun_Usage(t *testing.T) {
+	dir, _ := setup(t)
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 2, run(nil, nil, &stdout, &stderr))
+	assert.Equal(t, 2, run([]string{"-key", "k1"}, nil, &stdout, &stderr))
+	assert.Equal(t, 2, run([]string{"-key", "k1=" + filepath.Join(dir, "missing")}, nil, &stdout, &stderr))
+}
@@ -0,0 +1,247 @@
+// Command logfmt-pretty renders the entries written by the logger
+// package as colored, aligned lines for reading in a terminal, like
+// ConsoleFormat would have written them.
+//
+// Usage:
+//
+//	logfmt-pretty [flags] [file ...]
+//
+// It reads the files in order, or standard input when none is given, so
+// that it can follow a running program:
+//
+//	./service 2>&1 | logfmt-pretty -level warn -fields user_id,trace_id
+//
+// Entries are decoded with logparse, so that text and console lines are
+// understood too. Other lines are printed unchanged. The flags are:
+//
+//	-level        hide entries below this level
+//	-fields       show only these comma-separated fields
+//	-color        "auto", "always" or "never"; auto colors terminals
+//	              unless NO_COLOR is set
+//	-time-key     key of the timestamp (default "timestamp")
+//	-level-key    key of the level (default "level")
+//	-message-key  key of the message (default "message")
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+	"github.com/barnowlsnest/go-logslib/pkg/logparse"
+)
+
+// ANSI escape sequences used for colored output.
+const (
+	colorReset   = "\x1b[0m"
+	colorDim     = "\x1b[2m"
+	colorRed     = "\x1b[31m"
+	colorGreen   = "\x1b[32m"
+	colorYellow  = "\x1b[33m"
+	colorBlue    = "\x1b[34m"
+	colorMagenta = "\x1b[35m"
+	colorCyan    = "\x1b[36m"
+)
+
+// levelWidth is the width levels are padded to.
+const levelWidth = 5
+
+// maxLineSize bounds the length of input lines.
+const maxLineSize = 1 << 20
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// options holds the parsed flags.
+type options struct {
+	parser   logparse.Parser
+	level    logger.Level
+	hasLevel bool
+	fields   map[string]bool
+	color    bool
+}
+
+// run runs the command with args and returns its exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("logfmt-pretty", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	level := flags.String("level", "", "hide entries below this level")
+	fields := flags.String("fields", "", "show only these comma-separated fields")
+	color := flags.String("color", "auto", `"auto", "always" or "never"`)
+	timeKey := flags.String("time-key", logger.DefaultTimeKey, "key of the timestamp")
+	levelKey := flags.String("level-key", logger.DefaultLevelKey, "key of the level")
+	messageKey := flags.String("message-key", logger.DefaultMessageKey, "key of the message")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	opts := options{parser: logparse.Parser{Keys: logger.Keys{Time: *timeKey, Level: *levelKey, Message: *messageKey}}}
+	if *level != "" {
+		l, err := logparse.ParseLevel(*level)
+		if err != nil {
+			fmt.Fprintln(stderr, "logfmt-pretty:", err)
+			return 2
+		}
+		opts.level, opts.hasLevel = l, true
+	}
+	if *fields != "" {
+		opts.fields = make(map[string]bool)
+		for _, key := range strings.Split(*fields, ",") {
+			opts.fields[strings.TrimSpace(key)] = true
+		}
+	}
+	switch *color {
+	case "auto":
+		opts.color = isTerminal(stdout)
+	case "always":
+		opts.color = true
+	case "never":
+	default:
+		fmt.Fprintf(stderr, "logfmt-pretty: invalid -color %q\n", *color)
+		return 2
+	}
+
+	out := bufio.NewWriter(stdout)
+	defer out.Flush()
+
+	if flags.NArg() == 0 {
+		if err := render(out, stdin, &opts); err != nil {
+			fmt.Fprintln(stderr, "logfmt-pretty:", err)
+			return 1
+		}
+		return 0
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintln(stderr, "logfmt-pretty:", err)
+			status = 1
+			continue
+		}
+		err = render(out, f, &opts)
+		f.Close()
+		if err != nil {
+			fmt.Fprintln(stderr, "logfmt-pretty:", err)
+			status = 1
+		}
+	}
+	return status
+}
+
+// isTerminal reports whether w is a terminal and NO_COLOR is unset.
+func isTerminal(w io.Writer) bool {
+	if _, ok := os.LookupEnv(logger.EnvNoColor); ok {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// render writes the lines of r to w, formatting those holding entries.
+func render(w *bufio.Writer, r io.Reader, opts *options) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+
+	var buf []byte
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		e, err := opts.parser.Parse(line)
+		switch {
+		case err != nil:
+			buf = append(buf[:0], line...)
+		case opts.hasLevel && e.Level < opts.level:
+			continue
+		default:
+			buf = opts.format(buf[:0], &e)
+		}
+		buf = append(buf, '\n')
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// format appends the line rendering e.
+func (opts *options) format(buf []byte, e *logparse.Entry) []byte {
+	if !e.Time.IsZero() {
+		buf = opts.paint(buf, colorDim, e.Time.Format(logger.DefaultTimeFormat))
+		buf = append(buf, ' ')
+	}
+
+	level := e.Level.String()
+	buf = opts.paint(buf, levelColor(e.Level), level)
+	for i := len(level); i < levelWidth; i++ {
+		buf = append(buf, ' ')
+	}
+	buf = append(buf, ' ')
+	if e.Caller != "" {
+		buf = opts.paint(buf, colorDim, e.Caller)
+		buf = append(buf, ' ')
+	}
+	buf = append(buf, e.Message...)
+
+	for _, f := range e.Fields {
+		if opts.fields != nil && !opts.fields[f.Key] {
+			continue
+		}
+		buf = append(buf, ' ')
+		buf = opts.paint(buf, colorCyan, f.Key)
+		buf = append(buf, '=')
+		buf = appendValue(buf, f.Value)
+	}
+	return buf
+}
+
+// paint appends s, wrapped in color when colors are enabled.
+func (opts *options) paint(buf []byte, color, s string) []byte {
+	if !opts.color {
+		return append(buf, s...)
+	}
+	buf = append(buf, color...)
+	buf = append(buf, s...)
+	return append(buf, colorReset...)
+}
+
+// levelColor returns the color of a level.
+func levelColor(level logger.Level) string {
+	switch level {
+	case logger.DebugLevel:
+		return colorMagenta
+	case logger.InfoLevel:
+		return colorBlue
+	case logger.WarnLevel:
+		return colorYellow
+	case logger.ErrorLevel, logger.FatalLevel, logger.PanicLevel:
+		return colorRed
+	default:
+		return colorGreen
+	}
+}
+
+// appendValue appends a field value: strings unquoted unless they hold
+// spaces, quotes or '=', and other values as JSON.
+func appendValue(buf []byte, value any) []byte {
+	s, ok := value.(string)
+	if !ok {
+		data, _ := json.Marshal(value)
+		return append(buf, data...)
+	}
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return strconv.AppendQuote(buf, s)
+	}
+	return append(buf, s...)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const input = `{"timestamp":"2024-01-20T15:04:05.000Z","level":"INFO","message":"started","port":8080}
+not json
+{"timestamp":"2024-01-20T15:04:06.000Z","level":"DEBUG","message":"tick"}
+{"timestamp":"2024-01-20T15:04:07.000Z","level":"ERROR","message":"failed","err":"no route","user":{"id":7},"trace_id":"abc"}
+`
+
+func TestRun(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	status := run([]string{"-color", "never"}, strings.NewReader(input), &stdout, &stderr)
+	assert.Zero(t, status)
+	assert.Equal(t, `2024-01-20T15:04:05.000Z INFO  started port=8080
+not json
+2024-01-20T15:04:06.000Z DEBUG tick
+2024-01-20T15:04:07.000Z ERROR failed err="no route" user={"id":7} trace_id=abc
+`, stdout.String())
+	assert.Empty(t, stderr.String())
+}
+
+func TestRun_LevelAndFields(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	status := run([]string{"-color=never", "-level", "info", "-fields", "trace_id,port"}, strings.NewReader(input), &stdout, &stderr)
+	assert.Zero(t, status)
+	assert.Equal(t, `2024-01-20T15:04:05.000Z INFO  started port=8080
+not json
+2024-01-20T15:04:07.000Z ERROR failed trace_id=abc
+`, stdout.String())
+}
+
+func TestRun_KeysAndLevelEncoders(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	in := `{"ts":1705763045,"lvl":4,"msg":"slow"}` + "\n" + `{"lvl":"d","msg":"verbose"}` + "\n"
+	status := run([]string{"-color=always", "-level=warn", "-time-key=ts", "-level-key=lvl", "-message-key=msg"},
+		strings.NewReader(in), &stdout, &stderr)
+	assert.Zero(t, status)
+	assert.Equal(t, colorDim+"2024-01-20T15:04:05.000Z"+colorReset+" "+colorYellow+"WARN"+colorReset+"  slow\n", stdout.String())
+}
+
+func TestRun_Files(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(path, []byte(`{"level":"WARN","message":"from file"}`+"\n"), 0o644))
+
+	status := run([]string{"-color=never", path, filepath.Join(t.TempDir(), "missing.log")}, nil, &stdout, &stderr)
+	assert.Equal(t, 1, status)
+	assert.Equal(t, "WARN  from file\n", stdout.String())
+	assert.Contains(t, stderr.String(), "missing.log")
+
+	assert.Equal(t, 2, run([]string{"-level", "loud"}, nil, &stdout, &stderr))
+	assert.Equal(t, 2, run([]string{"-color", "sometimes"}, nil, &stdout, &stderr))
+}
@@ -0,0 +1,187 @@
+// Command logship tails a log file written by the logger package, rotated
+// or not, and forwards its entries to a remote destination, for programs
+// that write locally and leave delivery to a shipper.
+//
+// Usage:
+//
+//	logship -file /var/log/app/app.log -to http://loki:3100/loki/api/v1/push -labels service=api
+//	logship -file /var/log/app/app.log -to syslog+udp://syslog:514
+//	logship -file /var/log/app/app.log -to tcp://collector:5170 -once
+//
+// Entries are decoded with logparse, in any of the JSON, text and console
+// formats, and re-encoded for the destination with their original time:
+//
+//   - "http://" and "https://" URLs are Loki push endpoints;
+//   - "syslog+udp://", "syslog+tcp://" and "syslog+unix://" URLs are
+//     syslog daemons, sent RFC 5424 messages;
+//   - other URLs, such as "tcp://host:5170" or "unix:///run/collector.sock",
+//     are collectors sent JSON lines.
+//
+// Lines that are not entries are skipped. The position after the last
+// shipped line is saved in a checkpoint file every -checkpoint-interval and
+// on exit, so that a restarted logship resumes where it stopped, including
+// in the uncompressed backups rotate.Writer made of the file meanwhile.
+// Entries still queued for delivery when logship is killed are lost; stop
+// it with SIGINT or SIGTERM to flush them.
+//
+// The flags are:
+//
+//	-file                 log file to tail (required)
+//	-to                   destination URL (required)
+//	-labels               comma-separated name=value Loki labels; "app"
+//	                      is also the syslog APP-NAME
+//	-checkpoint           checkpoint file (default: the file and ".logship")
+//	-checkpoint-interval  how often the checkpoint is saved (default 5s)
+//	-poll                 how often the file is checked for new lines
+//	                      (default 250ms)
+//	-once                 exit at the end of the file instead of following it
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logparse"
+)
+
+// checkpointSuffix is appended to the log file name to name the default
+// checkpoint file.
+const checkpointSuffix = ".logship"
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stderr))
+}
+
+// options holds the parsed flags.
+type options struct {
+	file               string
+	checkpoint         string
+	checkpointInterval time.Duration
+	poll               time.Duration
+	once               bool
+}
+
+// run runs the command with args until ctx is done, or the end of the file
+// with -once, and returns its exit status.
+func run(ctx context.Context, args []string, stderr io.Writer) int {
+	flags := flag.NewFlagSet("logship", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var opts options
+	flags.StringVar(&opts.file, "file", "", "log file to tail")
+	to := flags.String("to", "", "destination URL")
+	labelList := flags.String("labels", "", "comma-separated name=value Loki labels")
+	flags.StringVar(&opts.checkpoint, "checkpoint", "", "checkpoint file")
+	flags.DurationVar(&opts.checkpointInterval, "checkpoint-interval", 5*time.Second, "how often the checkpoint is saved")
+	flags.DurationVar(&opts.poll, "poll", 250*time.Millisecond, "how often the file is checked for new lines")
+	flags.BoolVar(&opts.once, "once", false, "exit at the end of the file")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if opts.file == "" || *to == "" {
+		fmt.Fprintln(stderr, "logship: -file and -to are required")
+		return 2
+	}
+	if opts.checkpoint == "" {
+		opts.checkpoint = opts.file + checkpointSuffix
+	}
+
+	labels, err := parseLabels(*labelList)
+	if err != nil {
+		fmt.Fprintln(stderr, "logship:", err)
+		return 2
+	}
+	sink, closer, err := newSink(*to, labels)
+	if err != nil {
+		fmt.Fprintln(stderr, "logship:", err)
+		return 1
+	}
+
+	cp, err := loadCheckpoint(opts.checkpoint)
+	if err == nil {
+		cp, err = follow(ctx, &opts, cp, newShipper(sink))
+	}
+	if closeErr := closer.Close(); err == nil {
+		err = closeErr
+	}
+	if cp.Head != "" {
+		if saveErr := cp.save(opts.checkpoint); err == nil {
+			err = saveErr
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "logship:", err)
+		return 1
+	}
+	return 0
+}
+
+// follow ships the entries of opts.file from cp until ctx is done, or the
+// end of the file with opts.once, and returns the position reached.
+func follow(ctx context.Context, opts *options, cp checkpoint, sh *shipper) (checkpoint, error) {
+	sources, err := resume(opts.file, cp)
+	if err != nil {
+		return cp, err
+	}
+	cur, rest := sources[0], sources[1:]
+	defer func() {
+		cur.close()
+		for _, s := range rest {
+			s.close()
+		}
+	}()
+
+	saved := time.Now()
+	for {
+		line, err := cur.next()
+		if err == nil {
+			if e, err := logparse.Parse(line); err == nil {
+				sh.ship(&e)
+			}
+			continue
+		}
+		if !errors.Is(err, io.EOF) {
+			return cur.checkpoint(), err
+		}
+
+		if len(rest) > 0 {
+			cur.close()
+			cur, rest = rest[0], rest[1:]
+			continue
+		}
+		if opts.once {
+			return cur.checkpoint(), nil
+		}
+
+		// A rotated file may still have got lines before it was renamed:
+		// they are read before switching to the new file.
+		if cur.replaced(opts.file) {
+			s, err := openSource(opts.file, 0)
+			if err == nil {
+				rest = append(rest, s)
+				continue
+			}
+		}
+
+		if time.Since(saved) >= opts.checkpointInterval {
+			if err := cur.checkpoint().save(opts.checkpoint); err != nil {
+				return cur.checkpoint(), err
+			}
+			saved = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return cur.checkpoint(), nil
+		case <-time.After(opts.poll):
+		}
+	}
+}
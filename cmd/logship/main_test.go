@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collector accepts one TCP connection and sends its lines to the
+// returned channel.
+func collector(t *testing.T) (string, <-chan string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	lines := make(chan string, 16)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	return "tcp://" + ln.Addr().String(), lines
+}
+
+func receive(t *testing.T, lines <-chan string) string {
+	t.Helper()
+
+	select {
+	case line := <-lines:
+		return line
+	case <-time.After(5 * time.Second):
+		t.Fatal("no line received")
+		return ""
+	}
+}
+
+func TestRun_Once(t *testing.T) {
+	url, lines := collector(t)
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(path, []byte(
+		`{"timestamp":"2024-01-20T15:04:05.000Z","level":"WARN","message":"disk","free":42,"caller":"app/disk.go:7"}`+"\n"+
+			"garbage\n"+
+			"2024-01-20T15:04:06.000Z INFO started port=8080\n"), 0o644))
+
+	var stderr bytes.Buffer
+	status := run(context.Background(), []string{"-file", path, "-to", url, "-once"}, &stderr)
+	require.Zero(t, status, stderr.String())
+
+	assert.Equal(t, `{"timestamp":"2024-01-20T15:04:05.000Z","level":"WARN","message":"disk","caller":"app/disk.go:7","free":42}`, receive(t, lines))
+	assert.Equal(t, `{"timestamp":"2024-01-20T15:04:06.000Z","level":"INFO","message":"started","port":"8080"}`, receive(t, lines))
+
+	cp, err := loadCheckpoint(path + checkpointSuffix)
+	require.NoError(t, err)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, info.Size(), cp.Offset)
+
+	// A second run resumes after the checkpoint.
+	url, lines = collector(t)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"level":"ERROR","message":"new"}` + "\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	status = run(context.Background(), []string{"-file", path, "-to", url, "-once"}, &stderr)
+	require.Zero(t, status, stderr.String())
+	assert.Contains(t, receive(t, lines), `"level":"ERROR","message":"new"`)
+}
+
+func TestRun_FollowRotation(t *testing.T) {
+	url, lines := collector(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	require.NoError(t, os.WriteFile(path, []byte(`{"level":"INFO","message":"first"}`+"\n"), 0o644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan int)
+	var stderr bytes.Buffer
+	go func() {
+		done <- run(ctx, []string{"-file", path, "-to", url, "-poll", "10ms"}, &stderr)
+	}()
+	assert.Contains(t, receive(t, lines), `"message":"first"`)
+
+	require.NoError(t, os.Rename(path, filepath.Join(dir, "app-2024-01-20T15-00-00.000.log")))
+	require.NoError(t, os.WriteFile(path, []byte(`{"level":"INFO","message":"second"}`+"\n"), 0o644))
+	assert.Contains(t, receive(t, lines), `"message":"second"`)
+
+	cancel()
+	assert.Zero(t, <-done, stderr.String())
+}
+
+func TestRun_Errors(t *testing.T) {
+	var stderr bytes.Buffer
+	assert.Equal(t, 2, run(context.Background(), []string{"-to", "tcp://localhost:1"}, &stderr))
+	assert.Equal(t, 2, run(context.Background(), []string{"-file", "a.log", "-to", "tcp://localhost:1", "-labels", "x"}, &stderr))
+	assert.Equal(t, 1, run(context.Background(), []string{"-file", filepath.Join(t.TempDir(), "missing.log"), "-to", "tcp://localhost:1", "-once"}, &stderr))
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+	"github.com/barnowlsnest/go-logslib/pkg/logparse"
+	"github.com/barnowlsnest/go-logslib/pkg/loki"
+	"github.com/barnowlsnest/go-logslib/pkg/netwriter"
+	"github.com/barnowlsnest/go-logslib/pkg/syslog"
+)
+
+// syslogScheme prefixes the URL schemes of syslog destinations, such as
+// "syslog+udp".
+const syslogScheme = "syslog+"
+
+// newSink returns the sink writing to the destination described by
+// target, and the writer to close once shipping is over:
+//
+//   - "http://" and "https://" URLs are Loki push endpoints, with labels
+//     attached to every stream;
+//   - "syslog+udp://", "syslog+tcp://" and "syslog+unix://" URLs are
+//     syslog daemons, sent RFC 5424 messages;
+//   - other URLs, such as "tcp://host:5170", are collectors sent JSON
+//     entries through netwriter.
+func newSink(target string, labels map[string]string) (logger.Sink, io.Closer, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return logger.Sink{}, nil, err
+	}
+
+	switch {
+	case u.Scheme == "http" || u.Scheme == "https":
+		sink, w, err := loki.NewSink(loki.Config{URL: target, Labels: labels, Format: logger.JSONFormat})
+		return sink, w, err
+	case strings.HasPrefix(u.Scheme, syslogScheme):
+		address := u.Host
+		if address == "" {
+			address = u.Path
+		}
+		sink, w, err := syslog.NewSink(syslog.Config{
+			Network: strings.TrimPrefix(u.Scheme, syslogScheme),
+			Address: address,
+			AppName: labels["app"],
+		})
+		return sink, w, err
+	default:
+		w, err := netwriter.New(netwriter.Config{URL: target})
+		if err != nil {
+			return logger.Sink{}, nil, err
+		}
+		return logger.Sink{Output: w, Format: logger.JSONFormat}, w, nil
+	}
+}
+
+// shipper re-logs parsed entries through a logger writing to a sink, so
+// that they are encoded and delivered as the sink expects. It is not safe
+// for concurrent use.
+type shipper struct {
+	log *logger.Logger
+
+	// now is the time of the entry being shipped, returned by the clock
+	// of log.
+	now time.Time
+}
+
+func newShipper(sink logger.Sink) *shipper {
+	s := &shipper{}
+	s.log = logger.New(logger.Config{
+		Level: logger.DebugLevel,
+		Sinks: []logger.Sink{sink},
+		Clock: func() time.Time { return s.now },
+	})
+	return s
+}
+
+// ship writes e with its original time, or the current time if it has
+// none.
+func (s *shipper) ship(e *logparse.Entry) {
+	s.now = e.Time
+	if s.now.IsZero() {
+		s.now = time.Now()
+	}
+
+	fields := make([]logger.Field, 0, len(e.Fields)+1)
+	if e.Caller != "" {
+		fields = append(fields, logger.String(logger.DefaultCallerKey, e.Caller))
+	}
+	for _, f := range e.Fields {
+		fields = append(fields, field(f))
+	}
+	s.log.Log(e.Level, e.Message, fields...)
+}
+
+// field converts a parsed field back to a logger field, keeping integers
+// exact.
+func field(f logparse.Field) logger.Field {
+	n, ok := f.Value.(json.Number)
+	if !ok {
+		return logger.Any(f.Key, f.Value)
+	}
+	if i, err := n.Int64(); err == nil {
+		return logger.Int64(f.Key, i)
+	}
+	if v, err := n.Float64(); err == nil {
+		return logger.Float64(f.Key, v)
+	}
+	return logger.String(f.Key, n.String())
+}
+
+// parseLabels parses comma-separated name=value pairs.
+func parseLabels(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+	if s == "" {
+		return labels, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid label %q", pair)
+		}
+		labels[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return labels, nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+	"github.com/barnowlsnest/go-logslib/pkg/logparse"
+	"github.com/barnowlsnest/go-logslib/pkg/loki"
+	"github.com/barnowlsnest/go-logslib/pkg/netwriter"
+)
+
+func TestNewSink(t *testing.T) {
+	_, closer, err := newSink("http://loki:3100/loki/api/v1/push", map[string]string{"service": "api"})
+	require.NoError(t, err)
+	assert.IsType(t, &loki.Writer{}, closer)
+	require.NoError(t, closer.Close())
+
+	sink, closer, err := newSink("tcp://collector:5170", nil)
+	require.NoError(t, err)
+	assert.IsType(t, &netwriter.Writer{}, closer)
+	assert.Equal(t, logger.JSONFormat, sink.Format)
+	require.NoError(t, closer.Close())
+
+	_, _, err = newSink("ftp://collector", nil)
+	assert.Error(t, err)
+}
+
+func TestField(t *testing.T) {
+	assert.Equal(t, logger.Int64("n", 9007199254740993), field(logparse.Field{Key: "n", Value: json.Number("9007199254740993")}))
+	assert.Equal(t, logger.Float64("f", 1.5), field(logparse.Field{Key: "f", Value: json.Number("1.5")}))
+	assert.Equal(t, logger.String("s", "v"), field(logparse.Field{Key: "s", Value: "v"}))
+}
+
+func TestParseLabels(t *testing.T) {
+	labels, err := parseLabels("service=api, env=prod")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"service": "api", "env": "prod"}, labels)
+
+	_, err = parseLabels("service")
+	assert.Error(t, err)
+}
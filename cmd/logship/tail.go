@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// headSize is the number of leading bytes that identify a log file across
+// renames, once it has that many.
+const headSize = 256
+
+// checkpoint is the position saved between runs: the offset after the last
+// shipped line of the file whose first HeadLen bytes hash to Head.
+type checkpoint struct {
+	Head    string `json:"head"`
+	HeadLen int    `json:"head_len"`
+	Offset  int64  `json:"offset"`
+}
+
+// loadCheckpoint reads the checkpoint at path, or returns the zero
+// checkpoint if there is none.
+func loadCheckpoint(path string) (checkpoint, error) {
+	var cp checkpoint
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cp, nil
+	}
+	if err != nil {
+		return cp, err
+	}
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return cp, fmt.Errorf("checkpoint %s: %w", path, err)
+	}
+	return cp, nil
+}
+
+// save writes cp to path atomically.
+func (cp checkpoint) save(path string) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// fileHead returns the hash of the first n bytes of f, or false if f is
+// shorter.
+func fileHead(f *os.File, n int) (string, bool) {
+	buf := make([]byte, n)
+	if read, _ := f.ReadAt(buf, 0); read < n {
+		return "", false
+	}
+	sum := sha256.Sum256(buf)
+	return hex.EncodeToString(sum[:]), true
+}
+
+// source is a log file being read line by line.
+type source struct {
+	file   *os.File
+	reader *bufio.Reader
+
+	// offset is the position after the last complete line returned, and
+	// partial holds the bytes read past it.
+	offset  int64
+	partial []byte
+}
+
+// openSource opens the file at path for reading from offset.
+func openSource(path string, offset int64) (*source, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &source{file: f, reader: bufio.NewReader(f), offset: offset}, nil
+}
+
+// next returns the next complete line, without its newline, or io.EOF if
+// none has been written yet. The line is only valid until the next call.
+func (s *source) next() ([]byte, error) {
+	chunk, err := s.reader.ReadSlice('\n')
+	if err == bufio.ErrBufferFull || err == io.EOF {
+		s.partial = append(s.partial, chunk...)
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return s.next()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	line := chunk
+	if len(s.partial) > 0 {
+		line = append(s.partial, chunk...)
+		s.partial = s.partial[:0]
+	}
+	s.offset += int64(len(line))
+	return bytes.TrimRight(line, "\r\n"), nil
+}
+
+// checkpoint returns the position of s.
+func (s *source) checkpoint() checkpoint {
+	n := headSize
+	if s.offset < headSize {
+		n = int(s.offset)
+	}
+	head, _ := fileHead(s.file, n)
+	return checkpoint{Head: head, HeadLen: n, Offset: s.offset}
+}
+
+// matches reports whether cp is a position in the file of s.
+func (s *source) matches(cp checkpoint) bool {
+	head, ok := fileHead(s.file, cp.HeadLen)
+	if !ok || head != cp.Head {
+		return false
+	}
+	info, err := s.file.Stat()
+	return err == nil && info.Size() >= cp.Offset
+}
+
+// replaced reports whether the file at path is no longer the file of s,
+// because it was rotated or truncated.
+func (s *source) replaced(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	current, err := s.file.Stat()
+	if err != nil {
+		return true
+	}
+	return !os.SameFile(info, current) || info.Size() < s.offset
+}
+
+func (s *source) close() error {
+	return s.file.Close()
+}
+
+// backups returns the uncompressed backups rotate.Writer made of the file
+// at path, oldest first.
+func backups(path string) ([]string, error) {
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	prefix := strings.TrimSuffix(base, ext) + "-"
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.Type().IsRegular() && strings.HasPrefix(name, prefix) && strings.HasSuffix(name, ext) {
+			names = append(names, filepath.Join(filepath.Dir(path), name))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// resume returns the files to read, in order, to continue after cp: the
+// backup holding cp from its offset and the newer backups, if the file was
+// rotated since cp was saved, then the file at path. Without a match, the
+// file at path is read from its start.
+func resume(path string, cp checkpoint) ([]*source, error) {
+	active, err := openSource(path, 0)
+	if err != nil {
+		return nil, err
+	}
+	if cp.Head == "" {
+		return []*source{active}, nil
+	}
+	if active.matches(cp) {
+		active.close()
+		s, err := openSource(path, cp.Offset)
+		if err != nil {
+			return nil, err
+		}
+		return []*source{s}, nil
+	}
+
+	names, err := backups(path)
+	if err != nil {
+		active.close()
+		return nil, err
+	}
+	for i, name := range names {
+		s, err := openSource(name, 0)
+		if err != nil {
+			continue
+		}
+		if !s.matches(cp) {
+			s.close()
+			continue
+		}
+		s.close()
+
+		var sources []*source
+		for j, name := range names[i:] {
+			offset := int64(0)
+			if j == 0 {
+				offset = cp.Offset
+			}
+			s, err := openSource(name, offset)
+			if err != nil {
+				continue
+			}
+			sources = append(sources, s)
+		}
+		return append(sources, active), nil
+	}
+	return []*source{active}, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readAll returns the complete lines of s from its position.
+func readAll(t *testing.T, s *source) []string {
+	t.Helper()
+
+	var lines []string
+	for {
+		line, err := s.next()
+		if err == io.EOF {
+			return lines
+		}
+		require.NoError(t, err)
+		lines = append(lines, string(line))
+	}
+}
+
+func TestSource_PartialLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(path, []byte("one\ntw"), 0o644))
+
+	s, err := openSource(path, 0)
+	require.NoError(t, err)
+	defer s.close()
+
+	assert.Equal(t, []string{"one"}, readAll(t, s))
+	assert.Equal(t, int64(4), s.offset)
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString("o\nthree\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	assert.Equal(t, []string{"two", "three"}, readAll(t, s))
+	assert.Equal(t, int64(14), s.offset)
+}
+
+func TestResume(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	require.NoError(t, os.WriteFile(path, []byte("a1\na2\n"), 0o644))
+
+	sources, err := resume(path, checkpoint{})
+	require.NoError(t, err)
+	require.Len(t, sources, 1)
+	assert.Equal(t, []string{"a1", "a2"}, readAll(t, sources[0]))
+	cp := sources[0].checkpoint()
+	sources[0].close()
+
+	// Resuming the same file continues after the checkpoint.
+	require.NoError(t, os.WriteFile(path, []byte("a1\na2\na3\n"), 0o644))
+	sources, err = resume(path, cp)
+	require.NoError(t, err)
+	require.Len(t, sources, 1)
+	assert.Equal(t, []string{"a3"}, readAll(t, sources[0]))
+	cp = sources[0].checkpoint()
+	sources[0].close()
+
+	// After two rotations, the rest of the first backup, the second backup
+	// and the new file are read in order.
+	require.NoError(t, os.WriteFile(path, []byte("a1\na2\na3\na4\n"), 0o644))
+	require.NoError(t, os.Rename(path, filepath.Join(dir, "app-2024-01-20T15-00-00.000.log")))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app-2024-01-20T16-00-00.000.log"), []byte("b1\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app-2024-01-20T14-00-00.000.log.gz"), []byte("gz"), 0o644))
+	require.NoError(t, os.WriteFile(path, []byte("c1\n"), 0o644))
+
+	sources, err = resume(path, cp)
+	require.NoError(t, err)
+	var lines []string
+	for _, s := range sources {
+		lines = append(lines, readAll(t, s)...)
+		s.close()
+	}
+	assert.Equal(t, []string{"a4", "b1", "c1"}, lines)
+}
+
+func TestCheckpoint_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.logship")
+
+	cp, err := loadCheckpoint(path)
+	require.NoError(t, err)
+	assert.Equal(t, checkpoint{}, cp)
+
+	want := checkpoint{Head: "abc", HeadLen: 3, Offset: 42}
+	require.NoError(t, want.save(path))
+	cp, err = loadCheckpoint(path)
+	require.NoError(t, err)
+	assert.Equal(t, want, cp)
+
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o644))
+	_, err = loadCheckpoint(path)
+	assert.Error(t, err)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/barnowlsnest/go-logslib/pkg/hashchain"
+)
+
+// writeChain writes lines to files through one chained writer, the lines
+// of each file in turn, and returns their paths.
+func writeChain(t *testing.T, config hashchain.Config, files ...[]string) []string {
+	dir := t.TempDir()
+	var buf bytes.Buffer
+	w := hashchain.New(&buf, config)
+
+	var paths []string
+	for i, lines := range files {
+		buf.Reset()
+		for _, line := range lines {
+			_, err := w.Write([]byte(line + "\n"))
+			require.NoError(t, err)
+		}
+		path := filepath.Join(dir, "app.log."+string(rune('a'+i)))
+		require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o600))
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func TestRun(t *testing.T) {
+	paths := writeChain(t, hashchain.Config{}, []string{`{"message":"a"}`, `{"message":"b"}`}, []string{`{"message":"c"}`})
+	var stdout, stderr bytes.Buffer
+
+	status := run(paths, &stdout, &stderr)
+	assert.Zero(t, status, stderr.String())
+	assert.Equal(t, paths[0]+": 2 lines OK\n"+paths[1]+": 1 lines OK\n", stdout.String())
+
+	// Out of order, the files do not form a chain.
+	stdout.Reset()
+	status = run([]string{paths[1], paths[0]}, &stdout, &stderr)
+	assert.Equal(t, 1, status)
+	assert.Contains(t, stderr.String(), paths[1]+": hashchain: chain is broken: line 1")
+}
+
+func TestRun_Tampered(t *testing.T) {
+	paths := writeChain(t, hashchain.Config{}, []string{"INFO a", "INFO b", "INFO c"})
+	data, err := os.ReadFile(paths[0])
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(paths[0], []byte(strings.Replace(string(data), "INFO b", "INFO x", 1)), 0o600))
+	var stdout, stderr bytes.Buffer
+
+	status := run(paths, &stdout, &stderr)
+	assert.Equal(t, 1, status)
+	assert.Contains(t, stderr.String(), "line 3 does not match the line before it")
+}
+
+func TestRun_SecretAndPartial(t *testing.T) {
+	config := hashchain.Config{Key: "chain", Secret: []byte("s3cret")}
+	paths := writeChain(t, config, []string{"INFO a"}, []string{"INFO b", "INFO c"})
+	secret := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(secret, []byte("s3cret\n"), 0o600))
+	var stdout, stderr bytes.Buffer
+
+	status := run([]string{"-key", "chain", "-secret-file", secret, paths[1]}, &stdout, &stderr)
+	assert.Equal(t, 1, status, "the first file is missing")
+
+	stderr.Reset()
+	status = run([]string{"-key", "chain", "-secret-file", secret, "-partial", paths[1]}, &stdout, &stderr)
+	assert.Zero(t, status, stderr.String())
+
+	status = run([]string{"-key", "chain", "-partial", paths[1]}, &stdout, &stderr)
+	assert.Equal(t, 1, status, "the secret is needed")
+}
+
+func TestRun_Usage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 2, run(nil, &stdout, &stderr))
+	assert.Equal(t, 2, run([]string{"-secret-file", filepath.Join(t.TempDir(), "missing"), "app.log"}, &stdout, &stderr))
+}
@@ -1,11 +1,25 @@
 module github.com/barnowlsnest/go-logslib
 
-go 1.25
+go 1.25.0
 
-require github.com/stretchr/testify v1.11.0
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/go-logr/logr v1.4.4
+	github.com/golang/snappy v1.0.0
+	github.com/klauspost/compress v1.20.1
+	github.com/stretchr/testify v1.11.0
+	go.uber.org/zap v1.28.0
+	google.golang.org/grpc v1.84.0
+	google.golang.org/protobuf v1.36.11
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	go.uber.org/multierr v1.10.0 // indirect
+	golang.org/x/net v0.57.0 // indirect
+	golang.org/x/sys v0.47.0 // indirect
+	golang.org/x/text v0.40.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20260706201446-f0a921348800 // indirect
 )
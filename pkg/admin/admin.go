@@ -0,0 +1,213 @@
+// Package admin provides an http.Handler for controlling a logger at
+// runtime: reading and changing its level, reading its write statistics,
+// and tailing its recent entries. Mount it under an existing debug mux so
+// that operators can switch a live service to DEBUG:
+//
+//	tail := admin.NewTail(0)
+//	log := logger.New(logger.Config{
+//		Sinks: []logger.Sink{
+//			{Output: os.Stdout, Format: logger.JSONFormat},
+//			{Output: tail, Level: logger.DebugLevel, Format: logger.JSONFormat},
+//		},
+//	})
+//
+//	mux.Handle("/debug/log/", http.StripPrefix("/debug/log", admin.New(admin.Config{
+//		Logger: log,
+//		Tail:   tail,
+//	})))
+//
+// The handler serves:
+//
+//	GET /level   the current level, as {"level":"INFO"}
+//	PUT /level   sets the level from {"level":"debug"}, and the levels of
+//	             named loggers from {"levels":"db=debug,http=warn"}
+//	GET /stats   the counts of logger.Stats
+//	GET /tail    the recent entries, then new ones as they are written;
+//	             ?n= limits the recent entries and ?follow=false stops
+//	             after them
+//
+// The handler does no authentication; protect it like any debug endpoint.
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// Config holds the configuration of the handler.
+type Config struct {
+	// Logger is the logger to control. Defaults to logger.Default().
+	Logger *logger.Logger
+
+	// Tail serves the tail endpoint when not nil. It must be one of the
+	// outputs of Logger. Without it, the tail endpoint responds with
+	// 404 Not Found.
+	Tail *Tail
+}
+
+// Level is the body of the level endpoint.
+type Level struct {
+	// Level is the logger-wide level, such as "INFO" or "debug".
+	Level string `json:"level,omitempty"`
+
+	// Levels is a spec of the levels of named loggers, such as
+	// "db=debug,http=warn", as parsed by logger.ParseLevels. It replaces
+	// the levels set before. It is only read by PUT.
+	Levels string `json:"levels,omitempty"`
+}
+
+// Stats is the body of the stats endpoint.
+type Stats struct {
+	Written uint64 `json:"written"`
+	Failed  uint64 `json:"failed"`
+	Dropped uint64 `json:"dropped"`
+
+	QueueFull    uint64 `json:"queue_full"`
+	QueueDropped uint64 `json:"queue_dropped"`
+}
+
+// handler serves the admin endpoints for one logger.
+type handler struct {
+	logger *logger.Logger
+	tail   *Tail
+}
+
+// New returns the admin handler described by config.
+func New(config Config) http.Handler {
+	h := &handler{logger: config.Logger, tail: config.Tail}
+	if h.logger == nil {
+		h.logger = logger.Default()
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /level", h.getLevel)
+	mux.HandleFunc("PUT /level", h.putLevel)
+	mux.HandleFunc("GET /stats", h.getStats)
+	mux.HandleFunc("GET /tail", h.getTail)
+	return mux
+}
+
+func (h *handler) getLevel(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Level{Level: h.logger.GetLevel().String()})
+}
+
+func (h *handler) putLevel(w http.ResponseWriter, r *http.Request) {
+	var body Level
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if body.Level == "" && body.Levels == "" {
+		http.Error(w, "missing level", http.StatusBadRequest)
+		return
+	}
+
+	var (
+		level  logger.Level
+		levels map[string]logger.Level
+		err    error
+	)
+	if body.Level != "" {
+		if level, err = logger.ParseLevel(body.Level); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if body.Levels != "" {
+		if levels, err = logger.ParseLevels(body.Levels); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.logger.SetLevels(levels)
+	}
+	if body.Level != "" {
+		h.logger.SetLevel(level)
+	}
+
+	h.getLevel(w, r)
+}
+
+func (h *handler) getStats(w http.ResponseWriter, r *http.Request) {
+	s := h.logger.Stats()
+	writeJSON(w, http.StatusOK, Stats{
+		Written:      s.Written,
+		Failed:       s.Failed,
+		Dropped:      s.Dropped,
+		QueueFull:    s.QueueFull,
+		QueueDropped: s.QueueDropped,
+	})
+}
+
+func (h *handler) getTail(w http.ResponseWriter, r *http.Request) {
+	if h.tail == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	n := -1
+	if s := r.URL.Query().Get("n"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 0 {
+			http.Error(w, "invalid n", http.StatusBadRequest)
+			return
+		}
+		n = v
+	}
+	follow := true
+	if s := r.URL.Query().Get("follow"); s != "" {
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			http.Error(w, "invalid follow", http.StatusBadRequest)
+			return
+		}
+		follow = v
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if !follow {
+		for _, entry := range h.tail.Recent(n) {
+			if _, err := w.Write(entry); err != nil {
+				return
+			}
+		}
+		return
+	}
+
+	recent, sub := h.tail.subscribe(n)
+	defer h.tail.unsubscribe(sub)
+
+	rc := http.NewResponseController(w)
+	for _, entry := range recent {
+		if _, err := w.Write(entry); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	for {
+		select {
+		case entry := <-sub:
+			if _, err := w.Write(entry); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,138 @@
+package admin
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func serve(h http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return rec
+}
+
+func TestHandler_Level(t *testing.T) {
+	log := logger.New(logger.Config{Level: logger.InfoLevel, Output: io.Discard})
+	h := New(Config{Logger: log})
+
+	rec := serve(h, http.MethodGet, "/level", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"level":"INFO"}`, rec.Body.String())
+
+	rec = serve(h, http.MethodPut, "/level", `{"level":"debug","levels":"db=warn"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"level":"DEBUG"}`, rec.Body.String())
+	assert.Equal(t, logger.DebugLevel, log.GetLevel())
+	assert.Equal(t, logger.WarnLevel, log.Named("db").GetLevel())
+
+	for _, body := range []string{`{"level":"loud"}`, `{"levels":"db"}`, `{}`, `level=debug`} {
+		rec = serve(h, http.MethodPut, "/level", body)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+	}
+	assert.Equal(t, logger.DebugLevel, log.GetLevel())
+
+	rec = serve(h, http.MethodPost, "/level", `{"level":"info"}`)
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestHandler_Stats(t *testing.T) {
+	log := logger.New(logger.Config{
+		Sinks: []logger.Sink{
+			{Output: io.Discard},
+			{Output: failingWriter{}},
+		},
+	})
+	log.Info("message")
+
+	rec := serve(New(Config{Logger: log}), http.MethodGet, "/stats", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"written":1,"failed":1,"dropped":1,"queue_full":0,"queue_dropped":0}`, rec.Body.String())
+}
+
+func TestHandler_Tail(t *testing.T) {
+	tail := NewTail(2)
+	log := logger.New(logger.Config{Format: logger.TextFormat, Output: tail, OmitTime: true})
+	h := New(Config{Logger: log, Tail: tail})
+
+	log.Info("first")
+	log.Info("second")
+	log.Info("third")
+
+	rec := serve(h, http.MethodGet, "/tail?follow=false", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "INFO second\nINFO third\n", rec.Body.String())
+
+	rec = serve(h, http.MethodGet, "/tail?follow=false&n=1", "")
+	assert.Equal(t, "INFO third\n", rec.Body.String())
+
+	rec = serve(h, http.MethodGet, "/tail?n=x", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(New(Config{Logger: log}), http.MethodGet, "/tail", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestHandler_TailFollow(t *testing.T) {
+	tail := NewTail(0)
+	log := logger.New(logger.Config{Format: logger.TextFormat, Output: tail, OmitTime: true})
+	log.Info("before")
+
+	srv := httptest.NewServer(New(Config{Logger: log, Tail: tail}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/tail", nil)
+	require.NoError(t, err)
+	resp, err := srv.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	lines := bufio.NewScanner(resp.Body)
+	require.True(t, lines.Scan())
+	assert.Equal(t, "INFO before", lines.Text())
+
+	log.Info("after")
+	require.True(t, lines.Scan())
+	assert.Equal(t, "INFO after", lines.Text())
+
+	cancel()
+	assert.Eventually(t, func() bool {
+		tail.mu.Lock()
+		defer tail.mu.Unlock()
+		return len(tail.subs) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestTail_Write(t *testing.T) {
+	tail := NewTail(3)
+
+	n, err := tail.Write([]byte("a\nb\n\nc\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 7, n)
+	_, _ = tail.Write([]byte("d"))
+
+	assert.Equal(t, [][]byte{[]byte("b\n"), []byte("c\n"), []byte("d\n")}, tail.Recent(-1))
+	assert.Equal(t, [][]byte{[]byte("d\n")}, tail.Recent(1))
+	assert.Empty(t, NewTail(0).Recent(-1))
+}
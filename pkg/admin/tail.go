@@ -0,0 +1,120 @@
+package admin
+
+import (
+	"bytes"
+	"sync"
+)
+
+// DefaultTailSize is the number of entries kept by a Tail created with a
+// size of 0.
+const DefaultTailSize = 100
+
+// tailSubscriberBuffer is the number of entries buffered for each client
+// of the tail endpoint. Entries are dropped for clients that fall further
+// behind, so that a slow client never blocks logging.
+const tailSubscriberBuffer = 256
+
+// Tail is an io.Writer that keeps the most recent entries written to it
+// and passes new entries on to the clients of the tail endpoint. Add it to
+// the logger as a sink:
+//
+//	tail := admin.NewTail(0)
+//	log := logger.New(logger.Config{
+//		Sinks: []logger.Sink{
+//			{Output: os.Stdout, Format: logger.JSONFormat},
+//			{Output: tail, Format: logger.JSONFormat},
+//		},
+//	})
+//
+// It is safe for concurrent use.
+type Tail struct {
+	mu      sync.Mutex
+	entries [][]byte
+	next    int
+	full    bool
+	subs    map[chan []byte]struct{}
+}
+
+// NewTail returns a Tail that keeps the last size entries, or
+// DefaultTailSize entries if size is 0 or less.
+func NewTail(size int) *Tail {
+	if size <= 0 {
+		size = DefaultTailSize
+	}
+	return &Tail{
+		entries: make([][]byte, size),
+		subs:    make(map[chan []byte]struct{}),
+	}
+}
+
+// Write records the entries in p, one per line. It never fails.
+func (t *Tail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for rest := p; len(rest) > 0; {
+		line, tail, _ := bytes.Cut(rest, []byte{'\n'})
+		rest = tail
+		if len(line) == 0 {
+			continue
+		}
+
+		entry := make([]byte, len(line)+1)
+		copy(entry, line)
+		entry[len(line)] = '\n'
+
+		t.entries[t.next] = entry
+		t.next = (t.next + 1) % len(t.entries)
+		t.full = t.full || t.next == 0
+
+		for sub := range t.subs {
+			select {
+			case sub <- entry:
+			default:
+			}
+		}
+	}
+	return len(p), nil
+}
+
+// Recent returns up to n of the most recent entries, oldest first, each
+// ending in a newline. A negative n returns all entries kept.
+func (t *Tail) Recent(n int) [][]byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.recent(n)
+}
+
+// recent must be called with t.mu held.
+func (t *Tail) recent(n int) [][]byte {
+	count := t.next
+	if t.full {
+		count = len(t.entries)
+	}
+	if n < 0 || n > count {
+		n = count
+	}
+
+	recent := make([][]byte, 0, n)
+	for i := t.next - n; i < t.next; i++ {
+		recent = append(recent, t.entries[(i+len(t.entries))%len(t.entries)])
+	}
+	return recent
+}
+
+// subscribe returns up to n recent entries and a channel receiving the
+// entries written from then on, until unsubscribe is called.
+func (t *Tail) subscribe(n int) ([][]byte, chan []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sub := make(chan []byte, tailSubscriberBuffer)
+	t.subs[sub] = struct{}{}
+	return t.recent(n), sub
+}
+
+func (t *Tail) unsubscribe(sub chan []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.subs, sub)
+}
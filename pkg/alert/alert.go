@@ -0,0 +1,236 @@
+// Package alert provides a logger.Hook raising an alert when entries at or
+// above a level come faster than a threshold, which is enough alerting for
+// small services without a monitoring stack.
+//
+// An alert fires when more than Threshold such entries occur within
+// Window. It is passed to a callback and posted as JSON to a webhook, then
+// no other alert fires for Cooldown; entries keep being counted meanwhile,
+// so an alert fires again after the cooldown if the burst goes on.
+//
+// Example usage:
+//
+//	hook, err := alert.New(alert.Config{
+//		Level:      logger.ErrorLevel,
+//		Threshold:  20,
+//		Window:     time.Minute,
+//		Cooldown:   10 * time.Minute,
+//		WebhookURL: "https://hooks.slack.com/services/T000/B000/XXXX",
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	defer hook.Close()
+//
+//	log.AddHook(hook)
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// Defaults applied by New to unset Config fields.
+const (
+	DefaultWindow  = time.Minute
+	DefaultTimeout = 10 * time.Second
+)
+
+// Config configures a Hook.
+type Config struct {
+	// Level is the minimum level of the entries counted.
+	Level logger.Level
+
+	// Threshold is the number of entries within Window that may occur
+	// without an alert; the next one fires it. 0 fires on every entry,
+	// subject to Cooldown.
+	Threshold int
+
+	// Window is the period the entries are counted over. Defaults to
+	// DefaultWindow.
+	Window time.Duration
+
+	// Cooldown is the minimum time between two alerts. Defaults to
+	// Window.
+	Cooldown time.Duration
+
+	// OnAlert, when not nil, is called with each alert on the logging
+	// goroutine. It must not block or log at Level or above.
+	OnAlert func(a Alert)
+
+	// WebhookURL, when set, receives each alert as a JSON POST sent from
+	// a separate goroutine.
+	WebhookURL string
+
+	// Timeout bounds each webhook request. Defaults to DefaultTimeout.
+	Timeout time.Duration
+
+	// HTTPClient sends the webhook requests. Defaults to
+	// http.DefaultClient.
+	HTTPClient *http.Client
+
+	// ErrorHandler, when not nil, is called with the error of every
+	// webhook request that failed.
+	ErrorHandler func(err error)
+}
+
+// Alert describes an alert.
+type Alert struct {
+	// Time is when the alert fired, the time of the entry that fired it.
+	Time time.Time `json:"time"`
+	// Level, Threshold and Window are those of the Config.
+	Level     logger.Level  `json:"level"`
+	Threshold int           `json:"threshold"`
+	Window    time.Duration `json:"-"`
+	// Count is the number of entries at or above Level since the previous
+	// alert, including those during its cooldown.
+	Count int `json:"count"`
+	// Message is the message of the entry that fired the alert.
+	Message string `json:"message"`
+}
+
+// MarshalJSON encodes the alert for the webhook, with Window as a string
+// such as "1m0s".
+func (a Alert) MarshalJSON() ([]byte, error) {
+	type alert Alert
+	return json.Marshal(struct {
+		alert
+		Window string `json:"window"`
+	}{alert(a), a.Window.String()})
+}
+
+// Hook is a logger.Hook firing alerts. It is safe for concurrent use.
+type Hook struct {
+	config Config
+
+	mu sync.Mutex
+	// times holds the times of the last Threshold+1 entries, oldest at
+	// next once full.
+	times   []time.Time
+	next    int
+	full    bool
+	count   int
+	quietTo time.Time
+
+	sending sync.WaitGroup
+}
+
+// New validates config and returns a Hook.
+func New(config Config) (*Hook, error) {
+	if config.Threshold < 0 {
+		return nil, errors.New("alert: negative threshold")
+	}
+	if config.OnAlert == nil && config.WebhookURL == "" {
+		return nil, errors.New("alert: OnAlert or WebhookURL is required")
+	}
+	if config.Window <= 0 {
+		config.Window = DefaultWindow
+	}
+	if config.Cooldown <= 0 {
+		config.Cooldown = config.Window
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	return &Hook{config: config, times: make([]time.Time, config.Threshold+1)}, nil
+}
+
+// Run implements logger.Hook. It counts e if it is at or above
+// Config.Level, fires an alert if needed, and leaves the entry unchanged.
+func (h *Hook) Run(e *logger.Entry) error {
+	if e.Level < h.config.Level {
+		return nil
+	}
+
+	a, fire := h.record(e)
+	if !fire {
+		return nil
+	}
+	if h.config.OnAlert != nil {
+		h.config.OnAlert(a)
+	}
+	if h.config.WebhookURL != "" {
+		h.sending.Add(1)
+		go func() {
+			defer h.sending.Done()
+			if err := h.post(a); err != nil && h.config.ErrorHandler != nil {
+				h.config.ErrorHandler(err)
+			}
+		}()
+	}
+	return nil
+}
+
+// Close waits for the webhook requests in flight.
+func (h *Hook) Close() error {
+	h.sending.Wait()
+	return nil
+}
+
+// record counts e and returns the alert it fires, if any.
+func (h *Hook) record(e *logger.Entry) (Alert, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.count++
+	h.times[h.next] = e.Time
+	h.next = (h.next + 1) % len(h.times)
+	h.full = h.full || h.next == 0
+
+	// With the ring full, its oldest entry is Threshold+1 entries back.
+	if !h.full || e.Time.Sub(h.times[h.next]) > h.config.Window || e.Time.Before(h.quietTo) {
+		return Alert{}, false
+	}
+
+	a := Alert{
+		Time:      e.Time,
+		Level:     h.config.Level,
+		Threshold: h.config.Threshold,
+		Window:    h.config.Window,
+		Count:     h.count,
+		Message:   e.Message,
+	}
+	h.count = 0
+	h.quietTo = e.Time.Add(h.config.Cooldown)
+	return a, true
+}
+
+// post sends a to the webhook.
+func (h *Hook) post(a Alert) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return fmt.Errorf("alert: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.config.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("alert: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.config.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("alert: %w", err)
+	}
+	defer resp.Body.Close()
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("alert: webhook failed with HTTP status %d: %s", resp.StatusCode, msg)
+	}
+	return nil
+}
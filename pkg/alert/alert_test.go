@@ -0,0 +1,149 @@
+package alert
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// clock is a settable logger clock.
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time { return c.now }
+
+func (c *clock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newLogger(t *testing.T, config Config) (*logger.Logger, *clock, *[]Alert) {
+	var alerts []Alert
+	config.OnAlert = func(a Alert) { alerts = append(alerts, a) }
+	h, err := New(config)
+	require.NoError(t, err)
+
+	c := &clock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	log := logger.New(logger.Config{Level: logger.DebugLevel, Output: io.Discard, Clock: c.Now})
+	log.AddHook(h)
+	return log, c, &alerts
+}
+
+func TestHook_Threshold(t *testing.T) {
+	log, c, alerts := newLogger(t, Config{Level: logger.ErrorLevel, Threshold: 3, Window: time.Minute, Cooldown: 5 * time.Minute})
+
+	for i := 0; i < 3; i++ {
+		log.Error("db down")
+		log.Warn("below the level")
+		c.advance(10 * time.Second)
+	}
+	assert.Empty(t, *alerts, "the threshold is not exceeded")
+
+	// The fourth within a minute of the first fires.
+	log.Error("db still down")
+	require.Len(t, *alerts, 1)
+	assert.Equal(t, Alert{
+		Time:      c.now,
+		Level:     logger.ErrorLevel,
+		Threshold: 3,
+		Window:    time.Minute,
+		Count:     4,
+		Message:   "db still down",
+	}, (*alerts)[0])
+
+	// Nothing fires during the cooldown, but entries are counted.
+	for i := 0; i < 14; i++ {
+		c.advance(20 * time.Second)
+		log.Error("db still down")
+	}
+	require.Len(t, *alerts, 1)
+
+	// The burst goes on after it.
+	c.advance(20 * time.Second)
+	log.Error("db still down")
+	require.Len(t, *alerts, 2)
+	assert.Equal(t, 15, (*alerts)[1].Count)
+}
+
+func TestHook_Window(t *testing.T) {
+	log, c, alerts := newLogger(t, Config{Level: logger.WarnLevel, Threshold: 2, Window: time.Minute})
+
+	// Three entries, but never three within a minute.
+	for i := 0; i < 6; i++ {
+		log.Warn("slow request")
+		c.advance(31 * time.Second)
+	}
+	assert.Empty(t, *alerts)
+
+	log.Warn("slow request")
+	log.Warn("slow request")
+	assert.Len(t, *alerts, 1)
+}
+
+func TestHook_ZeroThreshold(t *testing.T) {
+	log, c, alerts := newLogger(t, Config{Level: logger.ErrorLevel, Cooldown: time.Minute})
+
+	log.Error("first")
+	log.Error("second")
+	c.advance(time.Minute)
+	log.Error("third")
+
+	require.Len(t, *alerts, 2)
+	assert.Equal(t, "first", (*alerts)[0].Message)
+	assert.Equal(t, "third", (*alerts)[1].Message)
+	assert.Equal(t, 2, (*alerts)[1].Count)
+}
+
+func TestHook_Webhook(t *testing.T) {
+	bodies := make(chan map[string]interface{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var body map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		bodies <- body
+	}))
+	defer srv.Close()
+
+	h, err := New(Config{Level: logger.ErrorLevel, WebhookURL: srv.URL, Window: 30 * time.Second})
+	require.NoError(t, err)
+	log := logger.New(logger.Config{Output: io.Discard, Clock: func() time.Time { return time.Unix(0, 0).UTC() }})
+	log.AddHook(h)
+
+	log.Error("payment failed")
+	require.NoError(t, h.Close())
+	assert.Equal(t, map[string]interface{}{
+		"time":      "1970-01-01T00:00:00Z",
+		"level":     "error",
+		"threshold": float64(0),
+		"window":    "30s",
+		"count":     float64(1),
+		"message":   "payment failed",
+	}, <-bodies)
+}
+
+func TestHook_WebhookError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusGone)
+	}))
+	defer srv.Close()
+
+	var errs []error
+	h, err := New(Config{WebhookURL: srv.URL, ErrorHandler: func(err error) { errs = append(errs, err) }})
+	require.NoError(t, err)
+	require.NoError(t, h.Run(&logger.Entry{Time: time.Now(), Level: logger.InfoLevel}))
+	require.NoError(t, h.Close())
+
+	require.Len(t, errs, 1)
+	assert.ErrorContains(t, errs[0], "410")
+}
+
+func TestNew_Invalid(t *testing.T) {
+	_, err := New(Config{OnAlert: func(Alert) {}, Threshold: -1})
+	assert.Error(t, err)
+	_, err = New(Config{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,276 @@
+// Package audit provides a logger for audit trails: records of who did
+// what to which resource, and with what outcome, kept apart from the
+// application logs for compliance.
+//
+// Every event has an action, an actor, a target and an outcome, and the
+// fields named by Config.RequiredFields; events missing any of them are
+// rejected with ErrInvalidEvent instead of being written. Each record is
+// stamped with metadata the caller cannot override: a unique event ID, a
+// sequence number, the time in UTC, the host name and process ID, and the
+// service, environment and version of Config.
+//
+// Example usage:
+//
+//	f, err := os.OpenFile("/var/log/app/audit.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+//	if err != nil {
+//		return err
+//	}
+//	trail, err := audit.New(audit.Config{
+//		Output:         f,
+//		Service:        "billing",
+//		RequiredFields: []string{"tenant_id"},
+//		Sync:           true,
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	defer trail.Close()
+//
+//	err = trail.Event("invoice.delete", "alice", "invoice/42", audit.Success,
+//		logger.String("tenant_id", "acme"))
+//
+// Records are JSON lines, written one at a time in sequence order:
+//
+//	{"timestamp":"...","level":"INFO","message":"invoice.delete","hostname":"web-1","pid":42,"service":"billing","event_id":"...","seq":1,"actor":"alice","target":"invoice/42","outcome":"success","tenant_id":"acme"}
+package audit
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/barnowlsnest/go-logslib/pkg/hashchain"
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// Keys of the fields stamped on every record.
+const (
+	EventIDKey = "event_id"
+	SeqKey     = "seq"
+	ActorKey   = "actor"
+	TargetKey  = "target"
+	OutcomeKey = "outcome"
+)
+
+// ErrInvalidEvent is returned by Event for events missing a required
+// field or setting a reserved one. Nothing is written.
+var ErrInvalidEvent = errors.New("audit: invalid event")
+
+// ErrClosed is returned by Event after Close has been called.
+var ErrClosed = errors.New("audit: logger is closed")
+
+// Outcome is the result of an audited action. Any non-empty value is
+// accepted; the constants cover the usual ones.
+type Outcome string
+
+// Common outcomes.
+const (
+	Success Outcome = "success"
+	Failure Outcome = "failure"
+	Denied  Outcome = "denied"
+)
+
+// Config configures an AuditLogger.
+type Config struct {
+	// Output receives the records. It should not be shared with the
+	// application logs.
+	Output io.Writer
+
+	// Service, Env and Version are stamped on every record when set.
+	Service string
+	Env     string
+	Version string
+
+	// RequiredFields are the keys of fields every event must have,
+	// besides the action, actor, target and outcome.
+	RequiredFields []string
+
+	// Chain, when not nil, makes the records tamper-evident: each one
+	// holds the hash of the record before it, as added by a
+	// hashchain.Writer configured by Chain. Set Chain.Prev with
+	// hashchain.LastHash to continue the chain of an existing file.
+	Chain *hashchain.Config
+
+	// Sync makes Event sync Output after each record when it implements
+	// logger.WriteSyncer, so that a record is on disk once Event returns.
+	Sync bool
+
+	// Clock returns the current time. Defaults to time.Now.
+	Clock func() time.Time
+}
+
+// AuditLogger writes audit records. It is safe for concurrent use; records
+// are written one at a time, in the order of their sequence numbers.
+type AuditLogger struct {
+	config Config
+	log    *logger.Logger
+
+	mu     sync.Mutex
+	seq    uint64
+	closed bool
+	// err is the write error reported by the logger for the current
+	// record.
+	err error
+}
+
+// reserved are the keys fields passed to Event may not use.
+var reserved = []string{
+	logger.DefaultTimeKey, logger.DefaultLevelKey, logger.DefaultMessageKey,
+	logger.HostnameKey, logger.PIDKey, logger.ServiceKey, logger.EnvKey, logger.VersionKey,
+	EventIDKey, SeqKey, ActorKey, TargetKey, OutcomeKey,
+}
+
+// New returns an AuditLogger writing to config.Output.
+func New(config Config) (*AuditLogger, error) {
+	if config.Output == nil {
+		return nil, errors.New("audit: missing output")
+	}
+	for _, key := range config.RequiredFields {
+		if slices.Contains(reserved, key) || (config.Chain != nil && key == chainKey(config.Chain)) {
+			return nil, fmt.Errorf("audit: required field %q is reserved", key)
+		}
+	}
+
+	output := config.Output
+	if config.Chain != nil {
+		output = &syncedChain{Writer: hashchain.New(output, *config.Chain), out: output}
+	}
+
+	a := &AuditLogger{config: config}
+	a.log = logger.New(logger.Config{
+		Level:       logger.InfoLevel,
+		Format:      logger.JSONFormat,
+		Output:      output,
+		UseUTC:      true,
+		TimeFormat:  time.RFC3339Nano,
+		Clock:       config.Clock,
+		AddHostInfo: true,
+		Service:     config.Service,
+		Env:         config.Env,
+		Version:     config.Version,
+		ErrorHandler: func(err error, _ []byte) {
+			// Called during Event, with a.mu held.
+			a.err = err
+		},
+	})
+	return a, nil
+}
+
+// Event validates and writes the record of action, done by actor on
+// target, with the given outcome and fields. It returns ErrInvalidEvent
+// if a required field is missing or empty, or if a field uses a reserved
+// key, and the error of the output if the record could not be written.
+func (a *AuditLogger) Event(action, actor, target string, outcome Outcome, fields ...logger.Field) error {
+	if err := a.validate(action, actor, target, outcome, fields); err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return ErrClosed
+	}
+
+	a.seq++
+	record := make([]logger.Field, 0, 5+len(fields))
+	record = append(record,
+		logger.String(EventIDKey, newEventID()),
+		logger.Int64(SeqKey, int64(a.seq)),
+		logger.String(ActorKey, actor),
+		logger.String(TargetKey, target),
+		logger.String(OutcomeKey, string(outcome)),
+	)
+	record = append(record, fields...)
+
+	a.err = nil
+	a.log.Info(action, record...)
+	if a.err == nil && a.config.Sync {
+		a.err = a.log.Sync()
+	}
+	return a.err
+}
+
+// Close flushes the records and stops the AuditLogger. Events after Close
+// fail with ErrClosed. It does not close Config.Output.
+func (a *AuditLogger) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return nil
+	}
+	a.closed = true
+	return a.log.Close()
+}
+
+// validate checks the parts of an event.
+func (a *AuditLogger) validate(action, actor, target string, outcome Outcome, fields []logger.Field) error {
+	switch {
+	case action == "":
+		return fmt.Errorf("%w: missing action", ErrInvalidEvent)
+	case actor == "":
+		return fmt.Errorf("%w: missing actor", ErrInvalidEvent)
+	case target == "":
+		return fmt.Errorf("%w: missing target", ErrInvalidEvent)
+	case outcome == "":
+		return fmt.Errorf("%w: missing outcome", ErrInvalidEvent)
+	}
+	for _, f := range fields {
+		if slices.Contains(reserved, f.Key) || (a.config.Chain != nil && f.Key == chainKey(a.config.Chain)) {
+			return fmt.Errorf("%w: field %q is reserved", ErrInvalidEvent, f.Key)
+		}
+	}
+	for _, key := range a.config.RequiredFields {
+		i := slices.IndexFunc(fields, func(f logger.Field) bool { return f.Key == key })
+		if i < 0 || empty(fields[i]) {
+			return fmt.Errorf("%w: missing field %q", ErrInvalidEvent, key)
+		}
+	}
+	return nil
+}
+
+// chainKey returns the key of the hash field of config.
+func chainKey(config *hashchain.Config) string {
+	if config.Key == "" {
+		return hashchain.DefaultKey
+	}
+	return config.Key
+}
+
+// syncedChain is a hashchain.Writer that syncs the output it wraps, so
+// that Config.Sync still applies.
+type syncedChain struct {
+	*hashchain.Writer
+	out io.Writer
+}
+
+// Sync syncs the wrapped output if it is a logger.WriteSyncer.
+func (s *syncedChain) Sync() error {
+	if ws, ok := s.out.(logger.WriteSyncer); ok {
+		return ws.Sync()
+	}
+	return nil
+}
+
+// empty reports whether f holds nil or an empty string.
+func empty(f logger.Field) bool {
+	switch v := f.Interface().(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	default:
+		return false
+	}
+}
+
+// newEventID returns a random event ID, 32 hex digits.
+func newEventID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
@@ -0,0 +1,183 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/barnowlsnest/go-logslib/pkg/hashchain"
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+func records(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var out []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var rec map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &rec), line)
+		out = append(out, rec)
+	}
+	return out
+}
+
+func TestAuditLogger_Event(t *testing.T) {
+	buf := &bytes.Buffer{}
+	a, err := New(Config{
+		Output:         buf,
+		Service:        "billing",
+		Env:            "prod",
+		RequiredFields: []string{"tenant_id"},
+		Clock:          func() time.Time { return time.Date(2024, 3, 1, 12, 0, 0, 5, time.FixedZone("CET", 3600)) },
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, a.Event("invoice.delete", "alice", "invoice/42", Success, logger.String("tenant_id", "acme")))
+	require.NoError(t, a.Event("invoice.read", "bob", "invoice/7", Denied, logger.String("tenant_id", "acme"), logger.Int("attempt", 2)))
+	require.NoError(t, a.Close())
+
+	recs := records(t, buf)
+	require.Len(t, recs, 2)
+	first := recs[0]
+	assert.Equal(t, "2024-03-01T11:00:00.000000005Z", first["timestamp"])
+	assert.Equal(t, "INFO", first["level"])
+	assert.Equal(t, "invoice.delete", first["message"])
+	assert.Equal(t, "alice", first["actor"])
+	assert.Equal(t, "invoice/42", first["target"])
+	assert.Equal(t, "success", first["outcome"])
+	assert.Equal(t, "acme", first["tenant_id"])
+	assert.Equal(t, "billing", first["service"])
+	assert.Equal(t, "prod", first["env"])
+	assert.Equal(t, float64(os.Getpid()), first["pid"])
+	assert.Contains(t, first, "hostname")
+	assert.Equal(t, float64(1), first["seq"])
+	assert.Len(t, first["event_id"], 32)
+
+	second := recs[1]
+	assert.Equal(t, float64(2), second["seq"])
+	assert.Equal(t, "denied", second["outcome"])
+	assert.Equal(t, float64(2), second["attempt"])
+	assert.NotEqual(t, first["event_id"], second["event_id"])
+
+	assert.ErrorIs(t, a.Event("invoice.delete", "alice", "invoice/42", Success, logger.String("tenant_id", "acme")), ErrClosed)
+}
+
+func TestAuditLogger_Validation(t *testing.T) {
+	buf := &bytes.Buffer{}
+	a, err := New(Config{Output: buf, RequiredFields: []string{"tenant_id"}})
+	require.NoError(t, err)
+	tenant := logger.String("tenant_id", "acme")
+
+	tests := []struct {
+		name                  string
+		action, actor, target string
+		outcome               Outcome
+		fields                []logger.Field
+		want                  string
+	}{
+		{"action", "", "alice", "doc/1", Success, []logger.Field{tenant}, "missing action"},
+		{"actor", "read", "", "doc/1", Success, []logger.Field{tenant}, "missing actor"},
+		{"target", "read", "alice", "", Success, []logger.Field{tenant}, "missing target"},
+		{"outcome", "read", "alice", "doc/1", "", []logger.Field{tenant}, "missing outcome"},
+		{"required", "read", "alice", "doc/1", Success, nil, `missing field "tenant_id"`},
+		{"empty required", "read", "alice", "doc/1", Success, []logger.Field{logger.String("tenant_id", "")}, `missing field "tenant_id"`},
+		{"reserved", "read", "alice", "doc/1", Success, []logger.Field{tenant, logger.String("actor", "mallory")}, `field "actor" is reserved`},
+		{"reserved metadata", "read", "alice", "doc/1", Success, []logger.Field{tenant, logger.Int("seq", 1)}, `field "seq" is reserved`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := a.Event(tt.action, tt.actor, tt.target, tt.outcome, tt.fields...)
+			assert.ErrorIs(t, err, ErrInvalidEvent)
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+	assert.Empty(t, buf.String())
+
+	require.NoError(t, a.Event("read", "alice", "doc/1", Success, tenant))
+	assert.Equal(t, float64(1), records(t, buf)[0]["seq"], "rejected events take no sequence number")
+}
+
+// failingWriter fails every Write.
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestAuditLogger_WriteError(t *testing.T) {
+	a, err := New(Config{Output: failingWriter{}})
+	require.NoError(t, err)
+	assert.ErrorContains(t, a.Event("read", "alice", "doc/1", Failure), "disk full")
+}
+
+// syncCounter counts the calls to Sync.
+type syncCounter struct {
+	bytes.Buffer
+	syncs int
+}
+
+func (s *syncCounter) Sync() error {
+	s.syncs++
+	return nil
+}
+
+func TestAuditLogger_Sync(t *testing.T) {
+	out := &syncCounter{}
+	a, err := New(Config{Output: out, Sync: true})
+	require.NoError(t, err)
+
+	require.NoError(t, a.Event("read", "alice", "doc/1", Success))
+	require.NoError(t, a.Event("read", "alice", "doc/2", Success))
+	assert.Equal(t, 2, out.syncs)
+}
+
+func TestAuditLogger_Concurrent(t *testing.T) {
+	buf := &bytes.Buffer{}
+	a, err := New(Config{Output: buf})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				assert.NoError(t, a.Event("read", "alice", "doc/1", Success))
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i, rec := range records(t, buf) {
+		assert.Equal(t, float64(i+1), rec["seq"], "records are written in sequence order")
+	}
+}
+
+func TestAuditLogger_Chain(t *testing.T) {
+	out := &syncCounter{}
+	a, err := New(Config{Output: out, Chain: &hashchain.Config{}, Sync: true})
+	require.NoError(t, err)
+
+	require.NoError(t, a.Event("read", "alice", "doc/1", Success))
+	require.NoError(t, a.Event("delete", "alice", "doc/1", Success))
+	assert.Equal(t, 2, out.syncs)
+	err = a.Event("read", "alice", "doc/1", Success, logger.String(hashchain.DefaultKey, "forged"))
+	assert.ErrorIs(t, err, ErrInvalidEvent)
+
+	recs := records(t, &out.Buffer)
+	assert.Equal(t, hashchain.Genesis, recs[0][hashchain.DefaultKey])
+	n, err := hashchain.Verify(strings.NewReader(out.String()), hashchain.Config{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+}
+
+func TestNew_Invalid(t *testing.T) {
+	_, err := New(Config{})
+	assert.Error(t, err)
+	_, err = New(Config{Output: &bytes.Buffer{}, RequiredFields: []string{"event_id"}})
+	assert.Error(t, err)
+}
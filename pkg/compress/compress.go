@@ -0,0 +1,350 @@
+// Package compress provides an io.Writer compressing log entries on the fly
+// with gzip or zstd, for files and stream network outputs of verbose
+// services, and a Reader decompressing them.
+//
+// Entries are compressed as they are written and the compressed stream is
+// cut at flush boundaries: every Config.FlushInterval, once
+// Config.FlushBytes were written since the last one, and on Flush, Sync and
+// Close. Each boundary ends a gzip member or zstd frame, so that everything
+// written before it reaches the underlying writer and can be decompressed
+// on its own. Files torn by a crash are readable up to their last boundary,
+// and files may be appended to across runs: concatenated members and
+// frames are read as one stream by the Reader, gzip -d and zstd -d.
+//
+// Example usage:
+//
+//	f, err := os.OpenFile("/var/log/app/app.log.zst", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+//	if err != nil {
+//		return err
+//	}
+//	w, err := compress.New(f, compress.Config{Algorithm: compress.Zstd})
+//	if err != nil {
+//		return err
+//	}
+//	defer w.Close()
+//
+//	log := logger.New(logger.Config{Format: logger.JSONFormat, Output: w})
+//
+// Over a netwriter.Writer, use a stream transport (tcp, unix): a
+// reconnection cuts the stream, and the collector loses the entries from
+// there up to the next boundary.
+package compress
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Algorithm is a compression algorithm.
+type Algorithm uint8
+
+const (
+	// Gzip compresses with gzip, readable everywhere.
+	Gzip Algorithm = iota
+
+	// Zstd compresses with Zstandard, faster and smaller than gzip.
+	Zstd
+)
+
+// String returns the name of the algorithm.
+func (a Algorithm) String() string {
+	switch a {
+	case Gzip:
+		return "gzip"
+	case Zstd:
+		return "zstd"
+	default:
+		return fmt.Sprintf("Algorithm(%d)", uint8(a))
+	}
+}
+
+// Level trades compression speed for size.
+type Level uint8
+
+const (
+	// DefaultLevel is the default level of the algorithm.
+	DefaultLevel Level = iota
+
+	// BestSpeed compresses fastest.
+	BestSpeed
+
+	// BestCompression compresses smallest.
+	BestCompression
+)
+
+// Defaults applied by New to unset Config fields.
+const (
+	DefaultFlushInterval = time.Second
+	DefaultFlushBytes    = 256 << 10
+)
+
+// ErrClosed is returned by Write and Flush after Close has been called.
+var ErrClosed = errors.New("compress: writer is closed")
+
+// ErrUnknownFormat is returned by NewReader for data that is neither gzip
+// nor zstd.
+var ErrUnknownFormat = errors.New("compress: unknown format")
+
+// Config configures a Writer.
+type Config struct {
+	// Algorithm is the compression algorithm. Defaults to Gzip.
+	Algorithm Algorithm
+
+	// Level is the compression level. Defaults to DefaultLevel.
+	Level Level
+
+	// FlushInterval is the period of the flush boundaries, bounding how
+	// long entries stay in the compressor. Defaults to
+	// DefaultFlushInterval; a negative value disables periodic flushes.
+	FlushInterval time.Duration
+
+	// FlushBytes is the number of uncompressed bytes after which a write
+	// ends with a flush boundary. Larger values compress better. Defaults
+	// to DefaultFlushBytes.
+	FlushBytes int
+
+	// ErrorHandler, when not nil, is called with the errors of periodic
+	// flushes, which have no caller to return them to.
+	ErrorHandler func(err error)
+}
+
+// Stats reports the activity of a Writer.
+type Stats struct {
+	// In is the number of bytes written to the Writer.
+	In uint64
+	// Out is the number of compressed bytes written to the underlying
+	// writer.
+	Out uint64
+	// Flushes is the number of flush boundaries written.
+	Flushes uint64
+}
+
+// encoder is implemented by the gzip and zstd writers.
+type encoder interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// Writer is an io.Writer compressing what is written to it to another
+// writer. It is safe for concurrent use.
+type Writer struct {
+	config Config
+	next   io.Writer
+
+	mu      sync.Mutex
+	enc     encoder
+	pending int // bytes written since the last boundary
+	closed  bool
+	stats   Stats
+
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// New returns a Writer compressing to next, with its periodic flushes
+// started.
+func New(next io.Writer, config Config) (*Writer, error) {
+	if config.FlushInterval == 0 {
+		config.FlushInterval = DefaultFlushInterval
+	}
+	if config.FlushBytes <= 0 {
+		config.FlushBytes = DefaultFlushBytes
+	}
+
+	w := &Writer{config: config, next: next, done: make(chan struct{}), stopped: make(chan struct{})}
+	enc, err := newEncoder(config.Algorithm, config.Level, countingWriter{w})
+	if err != nil {
+		return nil, err
+	}
+	w.enc = enc
+
+	if config.FlushInterval > 0 {
+		go w.run()
+	} else {
+		close(w.stopped)
+	}
+	return w, nil
+}
+
+// newEncoder returns an encoder for algorithm at level writing to next.
+func newEncoder(algorithm Algorithm, level Level, next io.Writer) (encoder, error) {
+	switch algorithm {
+	case Gzip:
+		gzipLevel := map[Level]int{
+			DefaultLevel:    gzip.DefaultCompression,
+			BestSpeed:       gzip.BestSpeed,
+			BestCompression: gzip.BestCompression,
+		}[level]
+		return gzip.NewWriterLevel(next, gzipLevel)
+	case Zstd:
+		zstdLevel := map[Level]zstd.EncoderLevel{
+			DefaultLevel:    zstd.SpeedDefault,
+			BestSpeed:       zstd.SpeedFastest,
+			BestCompression: zstd.SpeedBestCompression,
+		}[level]
+		// One goroutine per encoder: entries are small and a logger
+		// writes them one at a time anyway.
+		return zstd.NewWriter(next, zstd.WithEncoderLevel(zstdLevel), zstd.WithEncoderConcurrency(1))
+	default:
+		return nil, fmt.Errorf("compress: unknown algorithm %v", algorithm)
+	}
+}
+
+// countingWriter writes to the underlying writer of a Writer, counting the
+// compressed bytes. It is only used with the Writer's mutex held.
+type countingWriter struct{ w *Writer }
+
+func (c countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.next.Write(p)
+	c.w.stats.Out += uint64(n)
+	return n, err
+}
+
+// Write compresses p. The compressed data reaches the underlying writer
+// at the next flush boundary, or in part before when the compressor's
+// window fills up.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, ErrClosed
+	}
+	n, err := w.enc.Write(p)
+	w.stats.In += uint64(n)
+	w.pending += n
+	if err != nil {
+		return n, err
+	}
+	if w.pending >= w.config.FlushBytes {
+		if err := w.flush(); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// Flush writes a flush boundary if anything was written since the last
+// one.
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrClosed
+	}
+	return w.flush()
+}
+
+// flush ends the current member or frame and starts the next one.
+func (w *Writer) flush() error {
+	if w.pending == 0 {
+		return nil
+	}
+	w.pending = 0
+	err := w.enc.Close()
+	w.enc.Reset(countingWriter{w})
+	if err != nil {
+		return err
+	}
+	w.stats.Flushes++
+	return nil
+}
+
+// Sync flushes, then commits the underlying writer to stable storage if
+// it has a Sync method.
+func (w *Writer) Sync() error {
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if s, ok := w.next.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// Close stops the periodic flushes, flushes, and closes the underlying
+// writer if it is an io.Closer. Further writes return ErrClosed.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.done)
+	err := w.flush()
+	w.mu.Unlock()
+	<-w.stopped
+
+	if c, ok := w.next.(io.Closer); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// Stats returns the activity of the Writer so far.
+func (w *Writer) Stats() Stats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stats
+}
+
+// run writes the periodic flush boundaries until Close.
+func (w *Writer) run() {
+	defer close(w.stopped)
+
+	ticker := time.NewTicker(w.config.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			if err := w.Flush(); err != nil && !errors.Is(err, ErrClosed) && w.config.ErrorHandler != nil {
+				w.config.ErrorHandler(err)
+			}
+		}
+	}
+}
+
+// Magic numbers starting gzip members and zstd frames.
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// NewReader returns a reader decompressing r, whose algorithm is detected
+// from its first bytes. Empty input reads as empty. Reads return
+// io.ErrUnexpectedEOF if the input ends inside a member or frame, after
+// the data before it.
+func NewReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(len(zstdMagic))
+	switch {
+	case len(head) == 0 && errors.Is(err, io.EOF):
+		return io.NopCloser(br), nil
+	case bytes.HasPrefix(head, gzipMagic):
+		return gzip.NewReader(br)
+	case bytes.Equal(head, zstdMagic):
+		dec, err := zstd.NewReader(br, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return dec.IOReadCloser(), nil
+	case err != nil && !errors.Is(err, io.EOF):
+		return nil, err
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
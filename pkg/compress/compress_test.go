@@ -0,0 +1,160 @@
+package compress
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// syncBuffer is a bytes.Buffer safe for the periodic flushes.
+type syncBuffer struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	closed bool
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	return nil
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.Clone(b.buf.Bytes())
+}
+
+func decompress(t *testing.T, data []byte) (string, error) {
+	r, err := NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	defer r.Close()
+	plain, err := io.ReadAll(r)
+	return string(plain), err
+}
+
+func TestWriter_RoundTrip(t *testing.T) {
+	for _, algorithm := range []Algorithm{Gzip, Zstd} {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			out := &syncBuffer{}
+			w, err := New(out, Config{Algorithm: algorithm, Level: BestCompression})
+			require.NoError(t, err)
+			log := logger.New(logger.Config{Level: logger.InfoLevel, Format: logger.TextFormat, Output: w, OmitTime: true})
+
+			want := &strings.Builder{}
+			for i := range 200 {
+				log.Info("request served", logger.String("path", "/api/v1/orders"), logger.Int("n", i))
+				fmt.Fprintf(want, "INFO request served path=/api/v1/orders n=%d\n", i)
+			}
+			require.NoError(t, w.Close())
+			assert.True(t, out.closed)
+
+			plain, err := decompress(t, out.Bytes())
+			require.NoError(t, err)
+			assert.Equal(t, want.String(), plain)
+
+			stats := w.Stats()
+			assert.Equal(t, uint64(want.Len()), stats.In)
+			assert.Equal(t, uint64(len(out.Bytes())), stats.Out)
+			assert.Less(t, stats.Out*5, stats.In, "repetitive entries compress well")
+
+			_, err = w.Write([]byte("late\n"))
+			assert.ErrorIs(t, err, ErrClosed)
+		})
+	}
+}
+
+func TestWriter_FlushBoundaries(t *testing.T) {
+	for _, algorithm := range []Algorithm{Gzip, Zstd} {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			out := &syncBuffer{}
+			w, err := New(out, Config{Algorithm: algorithm, FlushInterval: -1, FlushBytes: 10})
+			require.NoError(t, err)
+
+			_, err = w.Write([]byte("short\n"))
+			require.NoError(t, err)
+			assert.Zero(t, w.Stats().Flushes)
+			_, err = w.Write([]byte("crosses the limit\n"))
+			require.NoError(t, err)
+			assert.Equal(t, uint64(1), w.Stats().Flushes)
+			boundary := len(out.Bytes())
+
+			plain, err := decompress(t, out.Bytes())
+			require.NoError(t, err)
+			assert.Equal(t, "short\ncrosses the limit\n", plain, "everything before a boundary is readable")
+
+			_, err = w.Write([]byte("after\n"))
+			require.NoError(t, err)
+			require.NoError(t, w.Flush())
+			require.NoError(t, w.Flush(), "nothing to flush")
+			assert.Equal(t, uint64(2), w.Stats().Flushes)
+
+			// A file torn inside the second member or frame, as left by a crash.
+			data := out.Bytes()
+			plain, err = decompress(t, data[:boundary+(len(data)-boundary)/2])
+			assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+			assert.Equal(t, "short\ncrosses the limit\n", plain)
+		})
+	}
+}
+
+func TestWriter_PeriodicFlush(t *testing.T) {
+	out := &syncBuffer{}
+	w, err := New(out, Config{Algorithm: Zstd, FlushInterval: 10 * time.Millisecond})
+	require.NoError(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("idle service\n"))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return w.Stats().Flushes == 1 }, time.Second, 5*time.Millisecond)
+
+	plain, err := decompress(t, out.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, "idle service\n", plain)
+}
+
+func TestWriter_Append(t *testing.T) {
+	for _, algorithm := range []Algorithm{Gzip, Zstd} {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			out := &syncBuffer{}
+			for _, run := range []string{"first run\n", "second run\n"} {
+				w, err := New(out, Config{Algorithm: algorithm})
+				require.NoError(t, err)
+				_, err = w.Write([]byte(run))
+				require.NoError(t, err)
+				require.NoError(t, w.Close())
+			}
+
+			plain, err := decompress(t, out.Bytes())
+			require.NoError(t, err)
+			assert.Equal(t, "first run\nsecond run\n", plain)
+		})
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	plain, err := decompress(t, nil)
+	require.NoError(t, err)
+	assert.Empty(t, plain)
+
+	_, err = NewReader(strings.NewReader("{\"level\":\"info\"}\n"))
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+	_, err = New(io.Discard, Config{Algorithm: 7})
+	assert.Error(t, err)
+}
@@ -0,0 +1,310 @@
+// Package encrypt provides an io.Writer encrypting log entries at rest
+// with AES-GCM, for files holding sensitive logs on local disk, and a
+// Reader decrypting them, as the logdecrypt command does.
+//
+// Each Write is sealed as one or more frames of at most Config.ChunkSize
+// bytes. A frame carries the ID of the key it was sealed with, a random
+// nonce and the length of its ciphertext, all authenticated along with
+// it, so that frames can be decrypted one at a time, a file torn by a crash
+// is readable up to its last whole frame, and files may be appended to
+// across runs and keys. Set Config.BufferSize of the logger to seal many
+// entries per frame and amortize the 40 bytes or so each frame adds.
+//
+// Keys are rotated with Writer.Rotate: new frames use the new key, and
+// the Reader picks the key of each frame by its ID among those it is
+// given. With random nonces, rotate keys before 2^32 frames were sealed
+// with one key.
+//
+// Example usage:
+//
+//	key, err := encrypt.ParseKey("2024-06", hexKey)
+//	if err != nil {
+//		return err
+//	}
+//	f, err := os.OpenFile("/var/log/app/app.log.enc", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+//	if err != nil {
+//		return err
+//	}
+//	w, err := encrypt.New(f, encrypt.Config{Key: key})
+//	if err != nil {
+//		return err
+//	}
+//
+//	log := logger.New(logger.Config{Format: logger.JSONFormat, Output: w, BufferSize: 32 << 10})
+package encrypt
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// DefaultChunkSize is the default maximum size of the plaintext of a
+// frame, and MaxChunkSize the largest allowed.
+const (
+	DefaultChunkSize = 1 << 20
+	MaxChunkSize     = 16 << 20
+)
+
+// magic starts every frame, followed by the format version.
+const (
+	magic   = "LE"
+	version = 1
+)
+
+// nonceSize is the size of the AES-GCM nonces.
+const nonceSize = 12
+
+// Errors returned by Reader.
+var (
+	// ErrUnknownKey is returned for frames sealed with a key the Reader
+	// was not given.
+	ErrUnknownKey = errors.New("encrypt: unknown key")
+	// ErrCorrupt is returned for data that is not a valid frame, or whose
+	// authentication failed because it was altered or the key is wrong.
+	ErrCorrupt = errors.New("encrypt: corrupt frame")
+)
+
+// Key is an AES key with the ID frames refer to it by.
+type Key struct {
+	// ID names the key, such as "2024-06". It is stored in the clear in
+	// every frame and is at most 255 bytes.
+	ID string
+	// Secret is the AES key: 16, 24 or 32 bytes for AES-128, AES-192 or
+	// AES-256.
+	Secret []byte
+}
+
+// ParseKey returns the key with the given ID and a hex-encoded secret,
+// as kept in key files. Surrounding white space is ignored.
+func ParseKey(id, secret string) (Key, error) {
+	b, err := hex.DecodeString(strings.TrimSpace(secret))
+	if err != nil {
+		return Key{}, fmt.Errorf("encrypt: key %q: %w", id, err)
+	}
+	key := Key{ID: id, Secret: b}
+	if _, err := key.aead(); err != nil {
+		return Key{}, err
+	}
+	return key, nil
+}
+
+// aead returns the AES-GCM cipher of k.
+func (k Key) aead() (cipher.AEAD, error) {
+	if k.ID == "" || len(k.ID) > 255 {
+		return nil, fmt.Errorf("encrypt: key ID %q must be 1 to 255 bytes", k.ID)
+	}
+	block, err := aes.NewCipher(k.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt: key %q: %w", k.ID, err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// Config configures a Writer.
+type Config struct {
+	// Key encrypts the frames until Writer.Rotate is called.
+	Key Key
+
+	// ChunkSize is the maximum size of the plaintext of a frame; larger
+	// writes are split. Defaults to DefaultChunkSize, and is at most
+	// MaxChunkSize.
+	ChunkSize int
+}
+
+// Writer is an io.Writer sealing what is written to it into encrypted
+// frames written to another writer. It is safe for concurrent use.
+type Writer struct {
+	next      io.Writer
+	chunkSize int
+
+	mu    sync.Mutex
+	keyID string
+	aead  cipher.AEAD
+	buf   []byte
+}
+
+// New returns a Writer encrypting to next with config.Key.
+func New(next io.Writer, config Config) (*Writer, error) {
+	if config.ChunkSize <= 0 {
+		config.ChunkSize = DefaultChunkSize
+	}
+	config.ChunkSize = min(config.ChunkSize, MaxChunkSize)
+	w := &Writer{next: next, chunkSize: config.ChunkSize}
+	if err := w.Rotate(config.Key); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Rotate makes the following writes use key.
+func (w *Writer) Rotate(key Key) error {
+	aead, err := key.aead()
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.keyID = key.ID
+	w.aead = aead
+	return nil
+}
+
+// KeyID returns the ID of the key in use.
+func (w *Writer) KeyID() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.keyID
+}
+
+// Write seals p into frames and writes them to the underlying writer at
+// once.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	buf := w.buf[:0]
+	for rest := p; len(rest) > 0; {
+		chunk := rest[:min(len(rest), w.chunkSize)]
+		rest = rest[len(chunk):]
+		buf = w.seal(buf, chunk)
+	}
+	w.buf = buf
+
+	if _, err := w.next.Write(buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// seal appends the frame of chunk to buf.
+func (w *Writer) seal(buf, chunk []byte) []byte {
+	start := len(buf)
+	buf = append(buf, magic...)
+	buf = append(buf, version, byte(len(w.keyID)))
+	buf = append(buf, w.keyID...)
+
+	nonce := len(buf)
+	buf = append(buf, make([]byte, nonceSize)...)
+	_, _ = rand.Read(buf[nonce:])
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(chunk)+w.aead.Overhead()))
+
+	header := len(buf)
+	buf = w.aead.Seal(buf, buf[nonce:nonce+nonceSize], chunk, buf[start:header])
+	return buf
+}
+
+// Sync commits the underlying writer to stable storage if it has a Sync
+// method.
+func (w *Writer) Sync() error {
+	if s, ok := w.next.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// Close closes the underlying writer if it is an io.Closer.
+func (w *Writer) Close() error {
+	if c, ok := w.next.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Reader is an io.Reader decrypting the frames written by a Writer.
+type Reader struct {
+	r     *bufio.Reader
+	keys  map[string]cipher.AEAD
+	plain []byte
+	frame []byte
+	err   error
+}
+
+// NewReader returns a Reader decrypting r with keys, chosen for each frame
+// by their ID.
+func NewReader(r io.Reader, keys ...Key) (*Reader, error) {
+	rd := &Reader{r: bufio.NewReader(r), keys: make(map[string]cipher.AEAD, len(keys))}
+	for _, key := range keys {
+		aead, err := key.aead()
+		if err != nil {
+			return nil, err
+		}
+		rd.keys[key.ID] = aead
+	}
+	return rd, nil
+}
+
+// Read reads decrypted data. It returns io.ErrUnexpectedEOF if the input
+// ends inside a frame, and ErrCorrupt or ErrUnknownKey, wrapped, for
+// frames that cannot be decrypted; reading stops there.
+func (rd *Reader) Read(p []byte) (int, error) {
+	for len(rd.plain) == 0 {
+		if rd.err != nil {
+			return 0, rd.err
+		}
+		rd.plain, rd.err = rd.next()
+	}
+	n := copy(p, rd.plain)
+	rd.plain = rd.plain[n:]
+	return n, nil
+}
+
+// next decrypts the next frame.
+func (rd *Reader) next() ([]byte, error) {
+	// Magic, version and key ID length.
+	fixed := make([]byte, len(magic)+2)
+	if _, err := io.ReadFull(rd.r, fixed); err != nil {
+		return nil, err
+	}
+	if string(fixed[:len(magic)]) != magic || fixed[len(magic)] != version {
+		return nil, fmt.Errorf("%w: bad frame header", ErrCorrupt)
+	}
+
+	idLen := int(fixed[len(magic)+1])
+	header := make([]byte, len(fixed)+idLen+nonceSize+4)
+	copy(header, fixed)
+	if _, err := io.ReadFull(rd.r, header[len(fixed):]); err != nil {
+		return nil, unexpected(err)
+	}
+	keyID := string(header[len(fixed) : len(fixed)+idLen])
+	nonce := header[len(fixed)+idLen : len(fixed)+idLen+nonceSize]
+	size := binary.BigEndian.Uint32(header[len(header)-4:])
+
+	aead, ok := rd.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, keyID)
+	}
+	if size < uint32(aead.Overhead()) || size > uint32(MaxChunkSize+aead.Overhead()) {
+		return nil, fmt.Errorf("%w: bad frame length %d", ErrCorrupt, size)
+	}
+
+	if cap(rd.frame) < int(size) {
+		rd.frame = make([]byte, size)
+	}
+	frame := rd.frame[:size]
+	if _, err := io.ReadFull(rd.r, frame); err != nil {
+		return nil, unexpected(err)
+	}
+	plain, err := aead.Open(frame[:0], nonce, frame, header)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	return plain, nil
+}
+
+// unexpected turns io.EOF inside a frame into io.ErrUnexpectedEOF.
+func unexpected(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
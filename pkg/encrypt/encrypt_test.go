@@ -0,0 +1,143 @@
+package encrypt
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+var (
+	key1 = Key{ID: "k1", Secret: bytes.Repeat([]byte{1}, 32)}
+	key2 = Key{ID: "k2", Secret: bytes.Repeat([]byte{2}, 16)}
+)
+
+func decrypt(t *testing.T, data []byte, keys ...Key) (string, error) {
+	r, err := NewReader(bytes.NewReader(data), keys...)
+	require.NoError(t, err)
+	plain, err := io.ReadAll(r)
+	return string(plain), err
+}
+
+func TestWriter_RoundTrip(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w, err := New(buf, Config{Key: key1})
+	require.NoError(t, err)
+	log := logger.New(logger.Config{Level: logger.InfoLevel, Format: logger.TextFormat, Output: w, OmitTime: true})
+
+	log.Info("card charged", logger.String("card", "4111-1111-1111-1111"))
+	log.Warn("retrying")
+	assert.NotContains(t, buf.String(), "4111", "entries are not written in the clear")
+
+	plain, err := decrypt(t, buf.Bytes(), key1)
+	require.NoError(t, err)
+	assert.Equal(t, "INFO card charged card=4111-1111-1111-1111\nWARN retrying\n", plain)
+}
+
+func TestWriter_Rotate(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w, err := New(buf, Config{Key: key1})
+	require.NoError(t, err)
+
+	_, err = w.Write([]byte("before\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Rotate(key2))
+	assert.Equal(t, "k2", w.KeyID())
+	_, err = w.Write([]byte("after\n"))
+	require.NoError(t, err)
+
+	plain, err := decrypt(t, buf.Bytes(), key1, key2)
+	require.NoError(t, err)
+	assert.Equal(t, "before\nafter\n", plain)
+
+	plain, err = decrypt(t, buf.Bytes(), key1)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	assert.Equal(t, "before\n", plain, "frames before the unknown key are read")
+
+	assert.Error(t, w.Rotate(Key{ID: "bad", Secret: []byte("short")}))
+	assert.Equal(t, "k2", w.KeyID())
+}
+
+func TestWriter_Chunks(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w, err := New(buf, Config{Key: key1, ChunkSize: 10})
+	require.NoError(t, err)
+
+	entry := strings.Repeat("x", 25) + "\n"
+	n, err := w.Write([]byte(entry))
+	require.NoError(t, err)
+	assert.Equal(t, len(entry), n)
+	assert.Equal(t, 3, bytes.Count(buf.Bytes(), []byte(magic+"\x01")), "split in frames of 10, 10 and 6 bytes")
+
+	plain, err := decrypt(t, buf.Bytes(), key1)
+	require.NoError(t, err)
+	assert.Equal(t, entry, plain)
+}
+
+func TestReader_Damage(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w, err := New(buf, Config{Key: key1})
+	require.NoError(t, err)
+	for _, entry := range []string{"one\n", "two\n"} {
+		_, err := w.Write([]byte(entry))
+		require.NoError(t, err)
+	}
+	data := buf.Bytes()
+	frameLen := len(data) / 2
+
+	// A torn last frame, as left by a crash.
+	plain, err := decrypt(t, data[:len(data)-3], key1)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Equal(t, "one\n", plain)
+
+	// A flipped bit in the ciphertext, in the header, or a wrong key.
+	tampered := bytes.Clone(data)
+	tampered[frameLen+len(tampered[frameLen:])-1] ^= 1
+	plain, err = decrypt(t, tampered, key1)
+	assert.ErrorIs(t, err, ErrCorrupt)
+	assert.Equal(t, "one\n", plain)
+
+	tampered = bytes.Clone(data)
+	tampered[len(magic)+2+len("k1")] ^= 1
+	_, err = decrypt(t, tampered, key1)
+	assert.ErrorIs(t, err, ErrCorrupt)
+
+	_, err = decrypt(t, data, Key{ID: "k1", Secret: key2.Secret})
+	assert.ErrorIs(t, err, ErrCorrupt)
+
+	_, err = decrypt(t, []byte("plain text log\n"), key1)
+	assert.ErrorIs(t, err, ErrCorrupt)
+}
+
+// failingWriter fails every Write.
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestWriter_Error(t *testing.T) {
+	w, err := New(failingWriter{}, Config{Key: key1})
+	require.NoError(t, err)
+	_, err = w.Write([]byte("lost\n"))
+	assert.ErrorContains(t, err, "disk full")
+}
+
+func TestParseKey(t *testing.T) {
+	key, err := ParseKey("k1", " "+strings.Repeat("01", 32)+"\n")
+	require.NoError(t, err)
+	assert.Equal(t, key1, key)
+
+	_, err = ParseKey("k1", "zz")
+	assert.Error(t, err)
+	_, err = ParseKey("k1", "0102")
+	assert.Error(t, err, "not an AES key size")
+	_, err = ParseKey("", strings.Repeat("01", 32))
+	assert.Error(t, err)
+	_, err = New(io.Discard, Config{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,231 @@
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// Keys of the fields returned by the EC2, GCE and Azure providers.
+const (
+	CloudProviderKey = "cloud.provider"
+	CloudRegionKey   = "cloud.region"
+	CloudZoneKey     = "cloud.availability_zone"
+	HostIDKey        = "host.id"
+)
+
+// Values of CloudProviderKey fields.
+const (
+	ProviderAWS   = "aws"
+	ProviderGCP   = "gcp"
+	ProviderAzure = "azure"
+)
+
+// Defaults applied by the cloud providers to unset CloudConfig fields.
+const (
+	DefaultMetadataEndpoint = "http://169.254.169.254"
+	DefaultMetadataTimeout  = time.Second
+)
+
+// maxMetadataSize bounds the metadata responses read.
+const maxMetadataSize = 1 << 20
+
+// CloudConfig configures the EC2, GCE and Azure providers.
+type CloudConfig struct {
+	// Endpoint is the base URL of the instance metadata service. Defaults
+	// to DefaultMetadataEndpoint, the address all three clouds use.
+	Endpoint string
+
+	// Timeout bounds the requests to the metadata service, which does not
+	// answer outside of its cloud. Defaults to DefaultMetadataTimeout.
+	Timeout time.Duration
+
+	// Client sends the requests. Defaults to a client without proxy, as
+	// the metadata service is link-local.
+	Client *http.Client
+}
+
+// metadataClient sends requests to the metadata service.
+type metadataClient struct {
+	endpoint string
+	timeout  time.Duration
+	client   *http.Client
+}
+
+func newMetadataClient(config CloudConfig) *metadataClient {
+	c := &metadataClient{
+		endpoint: strings.TrimSuffix(config.Endpoint, "/"),
+		timeout:  config.Timeout,
+		client:   config.Client,
+	}
+	if c.endpoint == "" {
+		c.endpoint = DefaultMetadataEndpoint
+	}
+	if c.timeout <= 0 {
+		c.timeout = DefaultMetadataTimeout
+	}
+	if c.client == nil {
+		c.client = &http.Client{Transport: &http.Transport{Proxy: nil}}
+	}
+	return c
+}
+
+// errNotFound reports a metadata service that is unreachable or answers
+// with an error status, as outside of its cloud.
+var errNotFound = errors.New("enrich: no metadata service")
+
+// get sends a request for path with header and returns the response body.
+func (c *metadataClient) get(ctx context.Context, method, path string, header http.Header) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, errNotFound
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errNotFound
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxMetadataSize))
+}
+
+// getJSON decodes the JSON response to a request for path into v.
+func (c *metadataClient) getJSON(ctx context.Context, path string, header http.Header, v any) error {
+	body, err := c.get(ctx, http.MethodGet, path, header)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("enrich: %s%s: %w", c.endpoint, path, err)
+	}
+	return nil
+}
+
+// cloudProvider returns a Provider calling fetch with a metadata client
+// for config, once it has succeeded. A missing metadata service yields no
+// fields and no error.
+func cloudProvider(config CloudConfig, fetch func(ctx context.Context, c *metadataClient) ([]logger.Field, error)) Provider {
+	c := newMetadataClient(config)
+
+	var (
+		mu     sync.Mutex
+		done   bool
+		cached []logger.Field
+	)
+	return func(ctx context.Context) ([]logger.Field, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if done {
+			return cached, nil
+		}
+
+		fields, err := fetch(ctx, c)
+		if errors.Is(err, errNotFound) {
+			fields, err = nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		done, cached = true, fields
+		return fields, nil
+	}
+}
+
+// cloudFields returns the fields of an instance, leaving out empty values.
+func cloudFields(provider, id, region, zone string) []logger.Field {
+	fields := []logger.Field{logger.String(CloudProviderKey, provider)}
+	if region != "" {
+		fields = append(fields, logger.String(CloudRegionKey, region))
+	}
+	if zone != "" {
+		fields = append(fields, logger.String(CloudZoneKey, zone))
+	}
+	if id != "" {
+		fields = append(fields, logger.String(HostIDKey, id))
+	}
+	return fields
+}
+
+// EC2 returns a Provider of the instance ID, region and availability zone
+// of an Amazon EC2 instance, read from its instance identity document
+// with an IMDSv2 session token, or without one where IMDSv1 is allowed.
+// The metadata is fetched once, at the first call that reaches the
+// metadata service.
+func EC2(config CloudConfig) Provider {
+	return cloudProvider(config, func(ctx context.Context, c *metadataClient) ([]logger.Field, error) {
+		header := http.Header{}
+		token, err := c.get(ctx, http.MethodPut, "/latest/api/token",
+			http.Header{"X-Aws-Ec2-Metadata-Token-Ttl-Seconds": {"60"}})
+		if err == nil {
+			header.Set("X-Aws-Ec2-Metadata-Token", string(token))
+		}
+
+		var doc struct {
+			InstanceID       string `json:"instanceId"`
+			Region           string `json:"region"`
+			AvailabilityZone string `json:"availabilityZone"`
+		}
+		if err := c.getJSON(ctx, "/latest/dynamic/instance-identity/document", header, &doc); err != nil {
+			return nil, err
+		}
+		return cloudFields(ProviderAWS, doc.InstanceID, doc.Region, doc.AvailabilityZone), nil
+	})
+}
+
+// GCE returns a Provider of the instance ID, region and zone of a Google
+// Compute Engine instance, including GKE nodes. The metadata is fetched
+// once, at the first call that reaches the metadata service.
+func GCE(config CloudConfig) Provider {
+	return cloudProvider(config, func(ctx context.Context, c *metadataClient) ([]logger.Field, error) {
+		var instance struct {
+			ID   json.Number `json:"id"`
+			Zone string      `json:"zone"`
+		}
+		if err := c.getJSON(ctx, "/computeMetadata/v1/instance/?recursive=true",
+			http.Header{"Metadata-Flavor": {"Google"}}, &instance); err != nil {
+			return nil, err
+		}
+
+		// Zones are given as "projects/<number>/zones/<zone>", and regions
+		// are zones without their last dash-separated part.
+		zone := instance.Zone[strings.LastIndexByte(instance.Zone, '/')+1:]
+		region := zone
+		if i := strings.LastIndexByte(zone, '-'); i > 0 {
+			region = zone[:i]
+		}
+		return cloudFields(ProviderGCP, instance.ID.String(), region, zone), nil
+	})
+}
+
+// Azure returns a Provider of the VM ID, region and availability zone of
+// an Azure virtual machine. Zones are numbers, such as "1", and left out
+// for VMs deployed without one. The metadata is fetched once, at the
+// first call that reaches the metadata service.
+func Azure(config CloudConfig) Provider {
+	return cloudProvider(config, func(ctx context.Context, c *metadataClient) ([]logger.Field, error) {
+		var compute struct {
+			VMID     string `json:"vmId"`
+			Location string `json:"location"`
+			Zone     string `json:"zone"`
+		}
+		if err := c.getJSON(ctx, "/metadata/instance/compute?api-version=2021-02-01&format=json",
+			http.Header{"Metadata": {"true"}}, &compute); err != nil {
+			return nil, err
+		}
+		return cloudFields(ProviderAzure, compute.VMID, compute.Location, compute.Zone), nil
+	})
+}
@@ -0,0 +1,59 @@
+// Package enrich provides providers of fields that describe where a
+// program runs, to bind to every entry of a logger so that entries can be
+// attributed even when the collector does not add that metadata itself.
+//
+// Example usage:
+//
+//	fields, err := enrich.Collect(ctx, enrich.Kubernetes(enrich.KubernetesConfig{}))
+//	if err != nil {
+//		// The fields of the providers that succeeded are still returned.
+//		fmt.Fprintln(os.Stderr, "enrich:", err)
+//	}
+//
+//	log := logger.New(logger.Config{
+//		Level:  logger.InfoLevel,
+//		Fields: fields,
+//	})
+//
+// Kubernetes reads the Downward API; EC2, GCE and Azure query the instance
+// metadata service of their cloud once, with a timeout, and return nothing
+// elsewhere, so that a program can collect from all of them:
+//
+//	fields, err := enrich.Collect(ctx,
+//		enrich.Kubernetes(enrich.KubernetesConfig{}),
+//		enrich.EC2(enrich.CloudConfig{}),
+//	)
+//
+// Fields follow the OpenTelemetry semantic conventions for resources, such
+// as "k8s.pod.name", so that they match the attributes collectors add.
+package enrich
+
+import (
+	"context"
+	"errors"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// Provider returns fields describing the environment of the program. It
+// returns no fields and no error when the program does not run in the
+// environment it describes.
+type Provider func(ctx context.Context) ([]logger.Field, error)
+
+// Collect calls providers in order and returns their fields, for
+// Config.Fields or Logger.With. The errors of failed providers are joined,
+// and the fields of the others are returned along with them.
+func Collect(ctx context.Context, providers ...Provider) ([]logger.Field, error) {
+	var (
+		fields []logger.Field
+		errs   []error
+	)
+	for _, provide := range providers {
+		f, err := provide(ctx)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		fields = append(fields, f...)
+	}
+	return fields, errors.Join(errs...)
+}
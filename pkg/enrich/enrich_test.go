@@ -0,0 +1,221 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// values returns fields as a map of their keys to their values.
+func values(fields []logger.Field) map[string]interface{} {
+	m := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		m[f.Key] = f.Interface()
+	}
+	return m
+}
+
+// clearKubernetesEnv unsets the variables read by Kubernetes for the test.
+func clearKubernetesEnv(t *testing.T) {
+	for _, env := range []string{EnvPodName, EnvPodNamespace, EnvNodeName, EnvContainerName, envServiceHost} {
+		t.Setenv(env, "")
+	}
+}
+
+func TestCollect(t *testing.T) {
+	failing := func(context.Context) ([]logger.Field, error) {
+		return nil, errors.New("metadata unavailable")
+	}
+	static := func(context.Context) ([]logger.Field, error) {
+		return []logger.Field{logger.String("a", "1")}, nil
+	}
+
+	fields, err := Collect(context.Background(), static, failing, static)
+	assert.EqualError(t, err, "metadata unavailable")
+	assert.Len(t, fields, 2)
+
+	fields, err = Collect(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, fields)
+}
+
+func TestKubernetes_Env(t *testing.T) {
+	clearKubernetesEnv(t)
+	t.Setenv(EnvPodName, "api-7d9f-x2x")
+	t.Setenv(EnvPodNamespace, "billing")
+	t.Setenv(EnvNodeName, "node-1")
+	t.Setenv(EnvContainerName, "api")
+
+	fields, err := Kubernetes(KubernetesConfig{PodInfoDir: t.TempDir()})(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		K8sPodNameKey:       "api-7d9f-x2x",
+		K8sNamespaceKey:     "billing",
+		K8sNodeNameKey:      "node-1",
+		K8sContainerNameKey: "api",
+	}, values(fields))
+}
+
+func TestKubernetes_Files(t *testing.T) {
+	clearKubernetesEnv(t)
+	t.Setenv(envServiceHost, "10.0.0.1")
+	t.Setenv(EnvNodeName, "node-2")
+
+	podInfo := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(podInfo, "node_name"), []byte("ignored\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(podInfo, "container_name"), []byte("worker\n"), 0o644))
+	serviceAccount := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(serviceAccount, "namespace"), []byte("jobs"), 0o644))
+
+	fields, err := Kubernetes(KubernetesConfig{PodInfoDir: podInfo, ServiceAccountDir: serviceAccount})(context.Background())
+	require.NoError(t, err)
+
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		K8sPodNameKey:       hostname,
+		K8sNamespaceKey:     "jobs",
+		K8sNodeNameKey:      "node-2",
+		K8sContainerNameKey: "worker",
+	}, values(fields))
+}
+
+func TestKubernetes_OutsidePod(t *testing.T) {
+	clearKubernetesEnv(t)
+
+	fields, err := Kubernetes(KubernetesConfig{PodInfoDir: t.TempDir(), ServiceAccountDir: t.TempDir()})(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, fields)
+}
+
+func TestKubernetes_UnreadableFile(t *testing.T) {
+	clearKubernetesEnv(t)
+	podInfo := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(podInfo, "pod_name"), 0o755))
+
+	_, err := Kubernetes(KubernetesConfig{PodInfoDir: podInfo})(context.Background())
+	assert.Error(t, err)
+}
+
+// metadataServer serves the metadata documents of all three clouds,
+// checking the headers each requires, and counts the requests.
+func metadataServer(t *testing.T, requests *atomic.Int32) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("PUT /latest/api/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("token"))
+	})
+	mux.HandleFunc("GET /latest/dynamic/instance-identity/document", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("X-Aws-Ec2-Metadata-Token") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"instanceId":"i-0abc","region":"eu-west-1","availabilityZone":"eu-west-1b"}`))
+	})
+	mux.HandleFunc("GET /computeMetadata/v1/instance/", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"id":5287153937520125367,"zone":"projects/123/zones/us-central1-a"}`))
+	})
+	mux.HandleFunc("GET /metadata/instance/compute", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("Metadata") != "true" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"vmId":"02aab8a4","location":"westeurope","zone":"2"}`))
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCloudProviders(t *testing.T) {
+	var requests atomic.Int32
+	srv := metadataServer(t, &requests)
+	config := CloudConfig{Endpoint: srv.URL}
+
+	tests := map[string]struct {
+		provider Provider
+		want     map[string]interface{}
+	}{
+		"ec2": {EC2(config), map[string]interface{}{
+			CloudProviderKey: ProviderAWS,
+			CloudRegionKey:   "eu-west-1",
+			CloudZoneKey:     "eu-west-1b",
+			HostIDKey:        "i-0abc",
+		}},
+		"gce": {GCE(config), map[string]interface{}{
+			CloudProviderKey: ProviderGCP,
+			CloudRegionKey:   "us-central1",
+			CloudZoneKey:     "us-central1-a",
+			HostIDKey:        "5287153937520125367",
+		}},
+		"azure": {Azure(config), map[string]interface{}{
+			CloudProviderKey: ProviderAzure,
+			CloudRegionKey:   "westeurope",
+			CloudZoneKey:     "2",
+			HostIDKey:        "02aab8a4",
+		}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			requests.Store(0)
+			for i := 0; i < 2; i++ {
+				fields, err := tt.provider(context.Background())
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, values(fields))
+			}
+			assert.Equal(t, int32(1), requests.Load(), "metadata is cached")
+		})
+	}
+}
+
+func TestCloudProviders_Absent(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	// A listener that never answers stands for the unroutable metadata
+	// address outside of a cloud.
+	hang := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer hang.Close()
+
+	for _, config := range []CloudConfig{
+		{Endpoint: srv.URL},
+		{Endpoint: hang.URL, Timeout: 50 * time.Millisecond},
+	} {
+		fields, err := Collect(context.Background(), EC2(config), GCE(config), Azure(config))
+		assert.NoError(t, err)
+		assert.Empty(t, fields)
+	}
+}
+
+func TestCloudProviders_InvalidDocument(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>"))
+	}))
+	defer srv.Close()
+
+	provider := GCE(CloudConfig{Endpoint: srv.URL})
+	_, err := provider(context.Background())
+	assert.Error(t, err)
+}
@@ -0,0 +1,128 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// Keys of the fields returned by the Kubernetes provider.
+const (
+	K8sPodNameKey       = "k8s.pod.name"
+	K8sNamespaceKey     = "k8s.namespace.name"
+	K8sNodeNameKey      = "k8s.node.name"
+	K8sContainerNameKey = "k8s.container.name"
+)
+
+// Defaults applied by Kubernetes to unset KubernetesConfig fields.
+const (
+	DefaultPodInfoDir        = "/etc/podinfo"
+	DefaultServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+)
+
+// Environment variables read by Kubernetes. Set them from the Downward API
+// in the pod spec:
+//
+//	env:
+//	  - name: POD_NAME
+//	    valueFrom: {fieldRef: {fieldPath: metadata.name}}
+//	  - name: POD_NAMESPACE
+//	    valueFrom: {fieldRef: {fieldPath: metadata.namespace}}
+//	  - name: NODE_NAME
+//	    valueFrom: {fieldRef: {fieldPath: spec.nodeName}}
+//	  - name: CONTAINER_NAME
+//	    value: api
+const (
+	EnvPodName       = "POD_NAME"
+	EnvPodNamespace  = "POD_NAMESPACE"
+	EnvNodeName      = "NODE_NAME"
+	EnvContainerName = "CONTAINER_NAME"
+
+	// envServiceHost is set by Kubernetes in every container.
+	envServiceHost = "KUBERNETES_SERVICE_HOST"
+)
+
+// KubernetesConfig configures the Kubernetes provider.
+type KubernetesConfig struct {
+	// PodInfoDir is a Downward API volume read for the values the
+	// environment variables leave unset, from files named as the variables
+	// in lower case, such as "pod_name". Defaults to DefaultPodInfoDir.
+	PodInfoDir string
+
+	// ServiceAccountDir holds the "namespace" file of the pod's service
+	// account token, the last source of the namespace. Defaults to
+	// DefaultServiceAccountDir.
+	ServiceAccountDir string
+}
+
+// Kubernetes returns a Provider of the pod name, namespace, node and
+// container of the program, read from the EnvPodName, EnvPodNamespace,
+// EnvNodeName and EnvContainerName environment variables, then from the
+// files of config.PodInfoDir. Inside a pod, the pod name falls back to the
+// host name and the namespace to that of the service account. Values that
+// cannot be found are left out.
+func Kubernetes(config KubernetesConfig) Provider {
+	if config.PodInfoDir == "" {
+		config.PodInfoDir = DefaultPodInfoDir
+	}
+	if config.ServiceAccountDir == "" {
+		config.ServiceAccountDir = DefaultServiceAccountDir
+	}
+
+	return func(context.Context) ([]logger.Field, error) {
+		inPod := os.Getenv(envServiceHost) != ""
+		lookup := func(env string) (string, error) {
+			if v := os.Getenv(env); v != "" {
+				return v, nil
+			}
+			return readValue(filepath.Join(config.PodInfoDir, strings.ToLower(env)))
+		}
+
+		var (
+			fields []logger.Field
+			errs   []error
+		)
+		add := func(key, env string, fallback func() (string, error)) {
+			v, err := lookup(env)
+			if v == "" && err == nil && fallback != nil {
+				v, err = fallback()
+			}
+			if err != nil {
+				errs = append(errs, err)
+			}
+			if v != "" {
+				fields = append(fields, logger.String(key, v))
+			}
+		}
+
+		var podName, namespace func() (string, error)
+		if inPod {
+			podName = os.Hostname
+			namespace = func() (string, error) {
+				return readValue(filepath.Join(config.ServiceAccountDir, "namespace"))
+			}
+		}
+		add(K8sPodNameKey, EnvPodName, podName)
+		add(K8sNamespaceKey, EnvPodNamespace, namespace)
+		add(K8sNodeNameKey, EnvNodeName, nil)
+		add(K8sContainerNameKey, EnvContainerName, nil)
+		return fields, errors.Join(errs...)
+	}
+}
+
+// readValue returns the trimmed contents of the file at path, or "" if
+// there is none.
+func readValue(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
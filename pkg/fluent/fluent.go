@@ -0,0 +1,191 @@
+// Package fluent provides a logger sink that ships entries to Fluentd or
+// Fluent Bit using the forward protocol: MessagePack-encoded events over
+// TCP or a unix socket.
+//
+// Each entry is sent as one event in message mode, with the level, message,
+// caller and fields in the record. With RequireAck, the Writer waits for
+// the aggregator to acknowledge every event and resends it over a new
+// connection if the acknowledgement does not arrive.
+//
+// Example usage:
+//
+//	sink, w, err := fluent.NewSink(fluent.Config{
+//		Address:    "fluentd:24224",
+//		Tag:        "app.api",
+//		RequireAck: true,
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	defer w.Close()
+//
+//	log := logger.New(logger.Config{
+//		Level: logger.InfoLevel,
+//		Sinks: []logger.Sink{sink},
+//		Async: true,
+//	})
+//
+// The Writer sends one event per Write and blocks until it is written (and
+// acknowledged), so leave Config.BufferSize of the logger unset and
+// consider Async mode to keep network latency off the logging goroutines.
+package fluent
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// Defaults applied by Dial to unset Config fields.
+const (
+	DefaultNetwork      = "tcp"
+	DefaultAddress      = "127.0.0.1:24224"
+	DefaultTimeout      = 5 * time.Second
+	DefaultMaxRetries   = 3
+	DefaultRetryBackoff = 100 * time.Millisecond
+)
+
+// Record keys of the entry attributes.
+const (
+	LevelKey    = "level"
+	MessageKey  = "message"
+	CallerKey   = "caller"
+	FunctionKey = "function"
+)
+
+// Config configures a Writer.
+type Config struct {
+	// Network is "tcp" or "unix". Defaults to DefaultNetwork.
+	Network string
+
+	// Address is the host:port of the aggregator, or the path of its unix
+	// socket. Defaults to DefaultAddress.
+	Address string
+
+	// Tag is the Fluentd tag of the events. Defaults to the program name.
+	Tag string
+
+	// RequireAck makes the Writer wait for the aggregator to acknowledge
+	// each event, for at-least-once delivery.
+	RequireAck bool
+
+	// Timeout bounds dialing, each write and the wait for each
+	// acknowledgement. Defaults to DefaultTimeout.
+	Timeout time.Duration
+
+	// MaxRetries is the number of times an event is resent over a new
+	// connection after a failure. Defaults to DefaultMaxRetries; a
+	// negative value disables retries.
+	MaxRetries int
+
+	// RetryBackoff is the delay before the first retry, doubled for each
+	// further one. Defaults to DefaultRetryBackoff.
+	RetryBackoff time.Duration
+}
+
+// NewSink connects to the aggregator described by config and returns a
+// logger.Sink writing to it, along with the Writer so that the caller can
+// close the connection.
+func NewSink(config Config) (logger.Sink, *Writer, error) {
+	w, err := Dial(config)
+	if err != nil {
+		return logger.Sink{}, nil, err
+	}
+	return logger.Sink{Output: w, Encoder: NewEncoder()}, w, nil
+}
+
+// defaults fills in the unset fields of config.
+func defaults(config Config) Config {
+	if config.Network == "" {
+		config.Network = DefaultNetwork
+	}
+	if config.Address == "" {
+		config.Address = DefaultAddress
+	}
+	if config.Tag == "" {
+		config.Tag = filepath.Base(os.Args[0])
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = DefaultMaxRetries
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = DefaultRetryBackoff
+	}
+	return config
+}
+
+// NewEncoder returns a logger.Encoder that encodes each entry as the time
+// and record of a forward protocol event, for a Writer to send.
+func NewEncoder() logger.Encoder {
+	return encoder{}
+}
+
+// encoder implements logger.Encoder for forward protocol events.
+type encoder struct{}
+
+// EncodeEntry implements logger.Encoder. The time is encoded as an
+// EventTime and the record as a map holding the level, message, caller
+// (when known) and fields.
+func (enc encoder) EncodeEntry(buf []byte, e *logger.Entry) []byte {
+	pairs := 2 + len(e.Fields)
+	if e.Caller != nil {
+		pairs += 2
+	}
+	if n, err := countPairs(e.Context); err == nil {
+		pairs += n
+	}
+
+	buf = appendEventTime(buf, e.Time)
+	buf = appendMapHeader(buf, pairs)
+	buf = appendString(buf, LevelKey)
+	buf = appendString(buf, e.Level.String())
+	buf = appendString(buf, MessageKey)
+	buf = appendString(buf, e.Message)
+
+	if e.Caller != nil {
+		buf = appendString(buf, CallerKey)
+		buf = appendString(buf, e.Caller.File+":"+strconv.Itoa(e.Caller.Line))
+		buf = appendString(buf, FunctionKey)
+		buf = appendString(buf, e.Caller.Function)
+	}
+
+	buf = append(buf, e.Context...)
+	return enc.EncodeFields(buf, e.Fields)
+}
+
+// EncodeFields implements logger.Encoder. Strings, integers, floats and
+// bools keep their type; durations, times and errors are encoded as
+// strings, and other values as their JSON encoding.
+func (encoder) EncodeFields(buf []byte, fields []logger.Field) []byte {
+	for _, f := range fields {
+		buf = appendString(buf, f.Key)
+
+		switch v := f.Interface().(type) {
+		case string:
+			buf = appendString(buf, v)
+		case int64:
+			buf = appendInt(buf, v)
+		case float64:
+			buf = appendFloat(buf, v)
+		case bool:
+			buf = appendBool(buf, v)
+		case time.Duration:
+			buf = appendString(buf, v.String())
+		case time.Time:
+			buf = appendString(buf, v.Format(time.RFC3339Nano))
+		case error:
+			buf = appendString(buf, v.Error())
+		case nil:
+			buf = appendNil(buf)
+		default:
+			buf = appendString(buf, string(f.AppendJSONValue(nil)))
+		}
+	}
+	return buf
+}
@@ -0,0 +1,315 @@
+package fluent
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// decodeValue decodes one MessagePack object of the types written by this
+// package. EventTime extensions are decoded as time.Time.
+func decodeValue(t *testing.T, r *bufio.Reader) interface{} {
+	t.Helper()
+
+	c, err := r.ReadByte()
+	require.NoError(t, err)
+
+	read := func(n int) []byte {
+		b := make([]byte, n)
+		_, err := io.ReadFull(r, b)
+		require.NoError(t, err)
+		return b
+	}
+	readMap := func(n int) map[string]interface{} {
+		m := make(map[string]interface{}, n)
+		for i := 0; i < n; i++ {
+			k := decodeValue(t, r).(string)
+			m[k] = decodeValue(t, r)
+		}
+		return m
+	}
+	readArray := func(n int) []interface{} {
+		a := make([]interface{}, n)
+		for i := range a {
+			a[i] = decodeValue(t, r)
+		}
+		return a
+	}
+
+	switch {
+	case c <= 0x7f:
+		return int64(c)
+	case c&0xf0 == 0x80:
+		return readMap(int(c & 0x0f))
+	case c&0xf0 == 0x90:
+		return readArray(int(c & 0x0f))
+	case c&0xe0 == 0xa0:
+		return string(read(int(c & 0x1f)))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil
+	case 0xc2:
+		return false
+	case 0xc3:
+		return true
+	case 0xcb:
+		return math.Float64frombits(binary.BigEndian.Uint64(read(8)))
+	case 0xd3:
+		return int64(binary.BigEndian.Uint64(read(8)))
+	case 0xd7:
+		b := read(9)
+		require.Equal(t, byte(0), b[0])
+		return time.Unix(int64(binary.BigEndian.Uint32(b[1:])), int64(binary.BigEndian.Uint32(b[5:])))
+	case 0xd9:
+		return string(read(int(read(1)[0])))
+	case 0xda:
+		return string(read(int(binary.BigEndian.Uint16(read(2)))))
+	case 0xde:
+		return readMap(int(binary.BigEndian.Uint16(read(2))))
+	case 0xdc:
+		return readArray(int(binary.BigEndian.Uint16(read(2))))
+	}
+	t.Fatalf("unexpected msgpack type 0x%02x", c)
+	return nil
+}
+
+// aggregator is a forward protocol server recording events. When ack is
+// set, it acknowledges events carrying a chunk option, except on the
+// connections listed in drop, which it closes instead.
+type aggregator struct {
+	t    *testing.T
+	lis  net.Listener
+	ack  bool
+	drop map[int]bool
+
+	mu     sync.Mutex
+	events [][]interface{}
+	conns  int
+}
+
+func newAggregator(t *testing.T, ack bool) *aggregator {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	a := &aggregator{t: t, lis: lis, ack: ack, drop: map[int]bool{}}
+	go a.serve()
+	t.Cleanup(func() { _ = lis.Close() })
+	return a
+}
+
+func (a *aggregator) serve() {
+	for {
+		conn, err := a.lis.Accept()
+		if err != nil {
+			return
+		}
+		a.mu.Lock()
+		a.conns++
+		n := a.conns
+		a.mu.Unlock()
+		go a.handle(conn, n)
+	}
+}
+
+func (a *aggregator) handle(conn net.Conn, n int) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	for {
+		if _, err := r.Peek(1); err != nil {
+			return
+		}
+		event := decodeValue(a.t, r).([]interface{})
+
+		a.mu.Lock()
+		a.events = append(a.events, event)
+		drop := a.drop[n]
+		a.mu.Unlock()
+
+		if drop {
+			return
+		}
+		if a.ack && len(event) == 4 {
+			chunk := event[3].(map[string]interface{})["chunk"].(string)
+			resp := appendMapHeader(nil, 1)
+			resp = appendString(resp, "ack")
+			resp = appendString(resp, chunk)
+			_, _ = conn.Write(resp)
+		}
+	}
+}
+
+func (a *aggregator) received() [][]interface{} {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([][]interface{}(nil), a.events...)
+}
+
+func TestEncoder(t *testing.T) {
+	ts := time.Date(2024, 1, 20, 15, 4, 5, 123456789, time.UTC)
+
+	enc := NewEncoder()
+	buf := enc.EncodeEntry(nil, &logger.Entry{
+		Time:    ts,
+		Level:   logger.WarnLevel,
+		Message: "slow request",
+		Context: enc.EncodeFields(nil, []logger.Field{logger.String("service", "api")}),
+		Fields: []logger.Field{
+			logger.Int("attempt", 300),
+			logger.Float64("ratio", 0.5),
+			logger.Bool("cached", true),
+			logger.Dur("took", 1500*time.Millisecond),
+			logger.Err(errors.New("timeout")),
+			logger.Err(nil),
+			logger.Any("tags", []string{"a", "b"}),
+		},
+		Caller: &logger.Caller{File: "app/main.go", Line: 42, Function: "main.run"},
+	})
+
+	r := bufio.NewReader(bytes.NewReader(buf))
+	assert.True(t, ts.Equal(decodeValue(t, r).(time.Time)))
+	assert.Equal(t, map[string]interface{}{
+		LevelKey:    "WARN",
+		MessageKey:  "slow request",
+		CallerKey:   "app/main.go:42",
+		FunctionKey: "main.run",
+		"service":   "api",
+		"attempt":   int64(300),
+		"ratio":     0.5,
+		"cached":    true,
+		"took":      "1.5s",
+		"error":     nil,
+		"tags":      `["a","b"]`,
+	}, decodeValue(t, r))
+
+	_, err := r.ReadByte()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestWriter_Forward(t *testing.T) {
+	a := newAggregator(t, false)
+
+	sink, w, err := NewSink(Config{Address: a.lis.Addr().String(), Tag: "app.api"})
+	require.NoError(t, err)
+	defer w.Close()
+
+	log := logger.New(logger.Config{Sinks: []logger.Sink{sink}})
+	log.Info("first", logger.Int("n", 1))
+	log.Error("second")
+
+	require.Eventually(t, func() bool { return len(a.received()) == 2 }, time.Second, 5*time.Millisecond)
+	events := a.received()
+
+	require.Len(t, events[0], 3)
+	assert.Equal(t, "app.api", events[0][0])
+	assert.WithinDuration(t, time.Now(), events[0][1].(time.Time), time.Minute)
+	record := events[0][2].(map[string]interface{})
+	assert.Equal(t, "INFO", record[LevelKey])
+	assert.Equal(t, "first", record[MessageKey])
+	assert.Equal(t, int64(1), record["n"])
+
+	assert.Equal(t, "second", events[1][2].(map[string]interface{})[MessageKey])
+}
+
+func TestWriter_Ack(t *testing.T) {
+	a := newAggregator(t, true)
+
+	sink, w, err := NewSink(Config{Address: a.lis.Addr().String(), Tag: "app", RequireAck: true})
+	require.NoError(t, err)
+	defer w.Close()
+
+	log := logger.New(logger.Config{Sinks: []logger.Sink{sink}})
+	log.Info("acked")
+
+	events := a.received()
+	require.Len(t, events, 1)
+	require.Len(t, events[0], 4)
+	assert.NotEmpty(t, events[0][3].(map[string]interface{})["chunk"])
+	assert.Equal(t, logger.Stats{Written: 1}, log.Stats())
+}
+
+func TestWriter_ResendsWithoutAck(t *testing.T) {
+	a := newAggregator(t, true)
+	a.drop[1] = true
+
+	w, err := Dial(Config{Address: a.lis.Addr().String(), Tag: "app", RequireAck: true, RetryBackoff: time.Millisecond})
+	require.NoError(t, err)
+	defer w.Close()
+
+	event := append(NewEncoder().EncodeEntry(nil, &logger.Entry{Time: time.Now(), Message: "retried"}), '\n')
+	n, err := w.Write(event)
+	require.NoError(t, err)
+	assert.Equal(t, len(event), n)
+
+	events := a.received()
+	require.Len(t, events, 2)
+	assert.Equal(t, events[0][3], events[1][3])
+}
+
+func TestWriter_GivesUp(t *testing.T) {
+	a := newAggregator(t, true)
+	a.drop[1], a.drop[2] = true, true
+
+	w, err := Dial(Config{Address: a.lis.Addr().String(), RequireAck: true, MaxRetries: 1, RetryBackoff: time.Millisecond})
+	require.NoError(t, err)
+	defer w.Close()
+
+	_, err = w.Write(NewEncoder().EncodeEntry(nil, &logger.Entry{Time: time.Now(), Message: "lost"}))
+	assert.Error(t, err)
+	assert.Len(t, a.received(), 2)
+}
+
+func TestWriter_Close(t *testing.T) {
+	a := newAggregator(t, false)
+
+	w, err := Dial(Config{Address: a.lis.Addr().String()})
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	_, err = w.Write([]byte{0xc0})
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestDial_Error(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	require.NoError(t, lis.Close())
+
+	_, err = Dial(Config{Address: addr, Timeout: time.Second})
+	assert.Error(t, err)
+}
+
+func TestCountPairs(t *testing.T) {
+	fields := NewEncoder().EncodeFields(nil, []logger.Field{
+		logger.String("a", "x"),
+		logger.Int("b", -1),
+		logger.Float64("c", 1.5),
+		logger.Bool("d", false),
+		logger.Err(nil),
+		logger.String("long", string(make([]byte, 300))),
+	})
+
+	n, err := countPairs(fields)
+	require.NoError(t, err)
+	assert.Equal(t, 6, n)
+
+	_, err = countPairs([]byte{0xc1})
+	assert.ErrorIs(t, err, errMsgpack)
+	_, err = countPairs([]byte{0xa5, 'a'})
+	assert.ErrorIs(t, err, errMsgpack)
+}
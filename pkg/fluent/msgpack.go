@@ -0,0 +1,212 @@
+package fluent
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// errMsgpack is returned for MessagePack data this package cannot skip or
+// decode.
+var errMsgpack = errors.New("fluent: unsupported msgpack data")
+
+// appendMapHeader appends the header of a map with n entries.
+func appendMapHeader(buf []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(buf, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, 0xde), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(buf, 0xdf), uint32(n))
+	}
+}
+
+// appendArrayHeader appends the header of an array with n elements.
+func appendArrayHeader(buf []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(buf, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, 0xdc), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(buf, 0xdd), uint32(n))
+	}
+}
+
+// appendString appends s as a MessagePack str.
+func appendString(buf []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		buf = append(buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		buf = append(buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		buf = binary.BigEndian.AppendUint16(append(buf, 0xda), uint16(n))
+	default:
+		buf = binary.BigEndian.AppendUint32(append(buf, 0xdb), uint32(n))
+	}
+	return append(buf, s...)
+}
+
+// appendInt appends i as a MessagePack int64.
+func appendInt(buf []byte, i int64) []byte {
+	if i >= 0 && i < 128 {
+		return append(buf, byte(i))
+	}
+	return binary.BigEndian.AppendUint64(append(buf, 0xd3), uint64(i))
+}
+
+// appendFloat appends f as a MessagePack float64.
+func appendFloat(buf []byte, f float64) []byte {
+	return binary.BigEndian.AppendUint64(append(buf, 0xcb), math.Float64bits(f))
+}
+
+// appendBool appends b as a MessagePack bool.
+func appendBool(buf []byte, b bool) []byte {
+	if b {
+		return append(buf, 0xc3)
+	}
+	return append(buf, 0xc2)
+}
+
+// appendNil appends the MessagePack nil.
+func appendNil(buf []byte) []byte {
+	return append(buf, 0xc0)
+}
+
+// appendEventTime appends t as the EventTime extension of the forward
+// protocol: type 0 holding seconds and nanoseconds as big-endian uint32.
+func appendEventTime(buf []byte, t time.Time) []byte {
+	buf = append(buf, 0xd7, 0x00)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(t.Unix()))
+	return binary.BigEndian.AppendUint32(buf, uint32(t.Nanosecond()))
+}
+
+// skip returns the size of the MessagePack object at the start of b. Only
+// the types written by this package are supported.
+func skip(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, errMsgpack
+	}
+
+	c := b[0]
+	var n int
+	switch {
+	case c <= 0x7f, c == 0xc0, c == 0xc2, c == 0xc3:
+		n = 1
+	case c&0xe0 == 0xa0:
+		n = 1 + int(c&0x1f)
+	case c == 0xd9 && len(b) >= 2:
+		n = 2 + int(b[1])
+	case c == 0xda && len(b) >= 3:
+		n = 3 + int(binary.BigEndian.Uint16(b[1:]))
+	case c == 0xdb && len(b) >= 5:
+		n = 5 + int(binary.BigEndian.Uint32(b[1:]))
+	case c == 0xcb, c == 0xd3:
+		n = 9
+	default:
+		return 0, errMsgpack
+	}
+
+	if n > len(b) {
+		return 0, errMsgpack
+	}
+	return n, nil
+}
+
+// countPairs returns the number of key-value pairs in b, a sequence of
+// MessagePack objects written by encoder.EncodeFields.
+func countPairs(b []byte) (int, error) {
+	objects := 0
+	for len(b) > 0 {
+		n, err := skip(b)
+		if err != nil {
+			return 0, err
+		}
+		b = b[n:]
+		objects++
+	}
+	return objects / 2, nil
+}
+
+// readString reads a MessagePack str from r.
+func readString(r *bufio.Reader) (string, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+
+	var n int
+	switch {
+	case c&0xe0 == 0xa0:
+		n = int(c & 0x1f)
+	case c == 0xd9:
+		b, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		n = int(b)
+	case c == 0xda:
+		var b [2]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return "", err
+		}
+		n = int(binary.BigEndian.Uint16(b[:]))
+	case c == 0xdb:
+		var b [4]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return "", err
+		}
+		n = int(binary.BigEndian.Uint32(b[:]))
+	default:
+		return "", fmt.Errorf("%w: expected str, got 0x%02x", errMsgpack, c)
+	}
+
+	s := make([]byte, n)
+	if _, err := io.ReadFull(r, s); err != nil {
+		return "", err
+	}
+	return string(s), nil
+}
+
+// readStringMap reads a MessagePack map with str keys and values from r,
+// such as the ack response of the forward protocol.
+func readStringMap(r *bufio.Reader) (map[string]string, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	var n int
+	switch {
+	case c&0xf0 == 0x80:
+		n = int(c & 0x0f)
+	case c == 0xde:
+		var b [2]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return nil, err
+		}
+		n = int(binary.BigEndian.Uint16(b[:]))
+	default:
+		return nil, fmt.Errorf("%w: expected map, got 0x%02x", errMsgpack, c)
+	}
+
+	m := make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		k, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		v, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		m[k] = v
+	}
+	return m, nil
+}
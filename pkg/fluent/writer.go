@@ -0,0 +1,173 @@
+package fluent
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned by Write after Close has been called.
+var ErrClosed = errors.New("fluent: writer is closed")
+
+// Writer is an io.Writer that sends each Write, the output of an encoder
+// from NewEncoder, as one forward protocol event.
+//
+// When a write or an acknowledgement fails, the Writer reconnects and
+// resends the event, backing off between attempts.
+type Writer struct {
+	config Config
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+	closed bool
+}
+
+// Dial connects to the aggregator described by config.
+func Dial(config Config) (*Writer, error) {
+	w := &Writer{config: defaults(config)}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// connect (re)establishes the connection. It must be called with mu held
+// or before the Writer is shared.
+func (w *Writer) connect() error {
+	w.disconnect()
+
+	conn, err := net.DialTimeout(w.config.Network, w.config.Address, w.config.Timeout)
+	if err != nil {
+		return fmt.Errorf("fluent: %w", err)
+	}
+	w.conn = conn
+	w.reader = bufio.NewReader(conn)
+	return nil
+}
+
+// disconnect closes the connection, if any. It must be called with mu held.
+func (w *Writer) disconnect() {
+	if w.conn != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+		w.reader = nil
+	}
+}
+
+// Write sends p, without its trailing newline, as one event tagged with
+// Config.Tag. It returns once the event is written or, with RequireAck,
+// acknowledged.
+func (w *Writer) Write(p []byte) (int, error) {
+	event := bytes.TrimSuffix(p, []byte{'\n'})
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, ErrClosed
+	}
+
+	msg, chunk, err := w.message(event)
+	if err != nil {
+		return 0, err
+	}
+
+	backoff := w.config.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err = w.send(msg, chunk)
+		if err == nil {
+			return len(p), nil
+		}
+		w.disconnect()
+
+		if attempt >= w.config.MaxRetries {
+			return 0, err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// message builds a message mode event, [tag, time, record] or, with
+// RequireAck, [tag, time, record, {"chunk": id}], and returns it along with
+// the chunk id.
+func (w *Writer) message(event []byte) ([]byte, string, error) {
+	var chunk string
+	if w.config.RequireAck {
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return nil, "", fmt.Errorf("fluent: %w", err)
+		}
+		chunk = base64.StdEncoding.EncodeToString(id)
+	}
+
+	n := 3
+	if chunk != "" {
+		n = 4
+	}
+
+	msg := make([]byte, 0, len(event)+len(w.config.Tag)+64)
+	msg = appendArrayHeader(msg, n)
+	msg = appendString(msg, w.config.Tag)
+	msg = append(msg, event...)
+	if chunk != "" {
+		msg = appendMapHeader(msg, 1)
+		msg = appendString(msg, "chunk")
+		msg = appendString(msg, chunk)
+	}
+	return msg, chunk, nil
+}
+
+// send writes msg, connecting first if needed, and waits for the ack of
+// chunk unless it is empty. It must be called with mu held.
+func (w *Writer) send(msg []byte, chunk string) error {
+	if w.conn == nil {
+		if err := w.connect(); err != nil {
+			return err
+		}
+	}
+
+	if err := w.conn.SetWriteDeadline(time.Now().Add(w.config.Timeout)); err != nil {
+		return fmt.Errorf("fluent: %w", err)
+	}
+	if _, err := w.conn.Write(msg); err != nil {
+		return fmt.Errorf("fluent: %w", err)
+	}
+	if chunk == "" {
+		return nil
+	}
+
+	if err := w.conn.SetReadDeadline(time.Now().Add(w.config.Timeout)); err != nil {
+		return fmt.Errorf("fluent: %w", err)
+	}
+	resp, err := readStringMap(w.reader)
+	if err != nil {
+		return fmt.Errorf("fluent: reading ack: %w", err)
+	}
+	if resp["ack"] != chunk {
+		return fmt.Errorf("fluent: ack %q does not match chunk %q", resp["ack"], chunk)
+	}
+	return nil
+}
+
+// Close closes the connection to the aggregator.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.closed = true
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	w.reader = nil
+	return err
+}
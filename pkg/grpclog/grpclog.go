@@ -0,0 +1,228 @@
+// Package grpclog provides gRPC server and client interceptors that log
+// every RPC with its method, status code, latency and peer, and bind a
+// request-scoped logger to the server handler context.
+//
+// Example usage:
+//
+//	cfg := grpclog.Config{Logger: log}
+//	srv := grpc.NewServer(
+//		grpc.ChainUnaryInterceptor(grpclog.UnaryServerInterceptor(cfg)),
+//		grpc.ChainStreamInterceptor(grpclog.StreamServerInterceptor(cfg)),
+//	)
+//
+// Handlers retrieve the request-scoped logger with logger.FromContext.
+package grpclog
+
+import (
+	"context"
+	"maps"
+	"path"
+	"slices"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// Field keys of RPC log entries.
+const (
+	KeyService = "grpc.service"
+	KeyMethod  = "grpc.method"
+	KeyCode    = "grpc.code"
+	KeyLatency = "latency"
+	KeyPeer    = "peer"
+	KeyTarget  = "target"
+)
+
+// DefaultMetadataFields maps the incoming metadata keys logged when
+// Config.MetadataFields is nil to their field keys.
+var DefaultMetadataFields = map[string]string{
+	"x-request-id": "request_id",
+	"x-trace-id":   "trace_id",
+	"traceparent":  "traceparent",
+}
+
+// Config holds the configuration of the interceptors.
+type Config struct {
+	// Logger receives RPC entries and is the parent of request-scoped
+	// loggers. Defaults to logger.Default().
+	Logger *logger.Logger
+
+	// MetadataFields maps incoming metadata keys, such as trace headers, to
+	// the field keys under which server interceptors bind their values to
+	// the request-scoped logger. Defaults to DefaultMetadataFields.
+	MetadataFields map[string]string
+
+	// Skip excludes RPCs, such as health checks, from logging when it
+	// returns true for their full method name. They still get a
+	// request-scoped logger.
+	Skip func(fullMethod string) bool
+}
+
+func (c *Config) logger() *logger.Logger {
+	if c.Logger != nil {
+		return c.Logger
+	}
+	return logger.Default()
+}
+
+// UnaryServerInterceptor returns an interceptor that logs unary RPCs
+// handled by the server.
+func UnaryServerInterceptor(config Config) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		ctx, l := config.requestLogger(ctx)
+
+		resp, err := handler(ctx, req)
+		config.logServer(ctx, l, info.FullMethod, err, time.Since(start))
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns an interceptor that logs streaming RPCs
+// handled by the server once the stream completes.
+func StreamServerInterceptor(config Config) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx, l := config.requestLogger(ss.Context())
+
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		config.logServer(ctx, l, info.FullMethod, err, time.Since(start))
+		return err
+	}
+}
+
+// UnaryClientInterceptor returns an interceptor that logs unary RPCs made
+// by the client.
+func UnaryClientInterceptor(config Config) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		config.logClient(method, cc.Target(), err, time.Since(start))
+		return err
+	}
+}
+
+// StreamClientInterceptor returns an interceptor that logs the
+// establishment of streaming RPCs made by the client.
+func StreamClientInterceptor(config Config) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		config.logClient(method, cc.Target(), err, time.Since(start))
+		return cs, err
+	}
+}
+
+// requestLogger derives the request-scoped logger from the incoming
+// metadata of ctx and returns a context carrying it.
+func (c *Config) requestLogger(ctx context.Context) (context.Context, *logger.Logger) {
+	l := c.logger()
+
+	mapping := c.MetadataFields
+	if mapping == nil {
+		mapping = DefaultMetadataFields
+	}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		var fields []logger.Field
+		for _, key := range slices.Sorted(maps.Keys(mapping)) {
+			if values := md.Get(key); len(values) > 0 {
+				fields = append(fields, logger.String(mapping[key], values[0]))
+			}
+		}
+		if len(fields) > 0 {
+			l = l.With(fields...)
+		}
+	}
+
+	return logger.NewContext(ctx, l), l
+}
+
+func (c *Config) logServer(ctx context.Context, l *logger.Logger, fullMethod string, err error, latency time.Duration) {
+	if c.Skip != nil && c.Skip(fullMethod) {
+		return
+	}
+
+	fields := rpcFields(fullMethod, err, latency)
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		fields = append(fields, logger.String(KeyPeer, p.Addr.String()))
+	}
+	logRPC(l, "finished call", status.Code(err), fields)
+}
+
+func (c *Config) logClient(fullMethod, target string, err error, latency time.Duration) {
+	if c.Skip != nil && c.Skip(fullMethod) {
+		return
+	}
+
+	fields := append(rpcFields(fullMethod, err, latency), logger.String(KeyTarget, target))
+	logRPC(c.logger(), "finished client call", status.Code(err), fields)
+}
+
+// rpcFields returns the fields common to server and client entries.
+func rpcFields(fullMethod string, err error, latency time.Duration) []logger.Field {
+	service, method := splitMethod(fullMethod)
+	fields := []logger.Field{
+		logger.String(KeyService, service),
+		logger.String(KeyMethod, method),
+		logger.String(KeyCode, status.Code(err).String()),
+		logger.Dur(KeyLatency, latency),
+	}
+	if err != nil {
+		fields = append(fields, logger.Err(err))
+	}
+	return fields
+}
+
+// logRPC writes an entry at the level CodeToLevel assigns to code.
+func logRPC(l *logger.Logger, msg string, code codes.Code, fields []logger.Field) {
+	switch CodeToLevel(code) {
+	case logger.ErrorLevel:
+		l.Error(msg, fields...)
+	case logger.WarnLevel:
+		l.Warn(msg, fields...)
+	default:
+		l.Info(msg, fields...)
+	}
+}
+
+// CodeToLevel returns the level at which an RPC that ended with code is
+// logged: InfoLevel for success, WarnLevel for errors usually caused by
+// the caller, and ErrorLevel for server-side failures.
+func CodeToLevel(code codes.Code) logger.Level {
+	switch code {
+	case codes.OK:
+		return logger.InfoLevel
+	case codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists,
+		codes.PermissionDenied, codes.Unauthenticated, codes.ResourceExhausted,
+		codes.FailedPrecondition, codes.Aborted, codes.OutOfRange:
+		return logger.WarnLevel
+	default:
+		return logger.ErrorLevel
+	}
+}
+
+// splitMethod splits "/package.Service/Method" into its service and method.
+func splitMethod(fullMethod string) (string, string) {
+	service, method := path.Split(fullMethod)
+	if len(service) > 1 {
+		service = service[1 : len(service)-1]
+	}
+	return service, method
+}
+
+// serverStream overrides the context of a grpc.ServerStream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,218 @@
+package grpclog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// lockedBuffer is a bytes.Buffer that is safe for concurrent use.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func (b *lockedBuffer) entries(t *testing.T) []map[string]interface{} {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(b.buf.String()), "\n") {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry), line)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// healthServer answers Check with the configured error and streams one
+// response from Watch. It logs through the request-scoped logger.
+type healthServer struct {
+	healthpb.UnimplementedHealthServer
+	err error
+}
+
+func (s *healthServer) Check(ctx context.Context, _ *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	logger.FromContext(ctx).Info("checking")
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+func (s *healthServer) Watch(_ *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	logger.FromContext(stream.Context()).Info("watching")
+	return stream.Send(&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING})
+}
+
+func newTestClient(t *testing.T, server, client Config, svc *healthServer) healthpb.HealthClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor(server)),
+		grpc.StreamInterceptor(StreamServerInterceptor(server)),
+	)
+	healthpb.RegisterHealthServer(srv, svc)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor(client)),
+		grpc.WithStreamInterceptor(StreamClientInterceptor(client)),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return healthpb.NewHealthClient(conn)
+}
+
+func newJSONLogger(buf *lockedBuffer) *logger.Logger {
+	return logger.New(logger.Config{Level: logger.DebugLevel, Format: logger.JSONFormat, Output: buf})
+}
+
+func TestUnaryInterceptors(t *testing.T) {
+	serverBuf, clientBuf := &lockedBuffer{}, &lockedBuffer{}
+	client := newTestClient(t,
+		Config{Logger: newJSONLogger(serverBuf)},
+		Config{Logger: newJSONLogger(clientBuf)},
+		&healthServer{},
+	)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "req-1")
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+
+	entries := serverBuf.entries(t)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "checking", entries[0]["message"])
+	assert.Equal(t, "req-1", entries[0]["request_id"])
+
+	assert.Equal(t, "finished call", entries[1]["message"])
+	assert.Equal(t, "INFO", entries[1]["level"])
+	assert.Equal(t, "grpc.health.v1.Health", entries[1][KeyService])
+	assert.Equal(t, "Check", entries[1][KeyMethod])
+	assert.Equal(t, "OK", entries[1][KeyCode])
+	assert.Equal(t, "req-1", entries[1]["request_id"])
+	assert.Contains(t, entries[1], KeyLatency)
+	assert.Equal(t, "bufconn", entries[1][KeyPeer])
+
+	clientEntries := clientBuf.entries(t)
+	require.Len(t, clientEntries, 1)
+	assert.Equal(t, "finished client call", clientEntries[0]["message"])
+	assert.Equal(t, "OK", clientEntries[0][KeyCode])
+	assert.Equal(t, "passthrough:///bufnet", clientEntries[0][KeyTarget])
+}
+
+func TestUnaryInterceptors_ErrorLevels(t *testing.T) {
+	tests := []struct {
+		err   error
+		level string
+	}{
+		{status.Error(codes.NotFound, "missing"), "WARN"},
+		{status.Error(codes.Internal, "broken"), "ERROR"},
+	}
+
+	for _, tt := range tests {
+		buf := &lockedBuffer{}
+		client := newTestClient(t, Config{Logger: newJSONLogger(buf)}, Config{Logger: logger.New(logger.Config{Output: &lockedBuffer{}})}, &healthServer{err: tt.err})
+
+		_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+		require.Error(t, err)
+
+		entries := buf.entries(t)
+		last := entries[len(entries)-1]
+		assert.Equal(t, tt.level, last["level"])
+		assert.Equal(t, status.Code(tt.err).String(), last[KeyCode])
+		assert.Contains(t, last["error"], status.Convert(tt.err).Message())
+	}
+}
+
+func TestStreamInterceptors(t *testing.T) {
+	serverBuf, clientBuf := &lockedBuffer{}, &lockedBuffer{}
+	client := newTestClient(t,
+		Config{Logger: newJSONLogger(serverBuf), MetadataFields: map[string]string{"tenant": "tenant"}},
+		Config{Logger: newJSONLogger(clientBuf)},
+		&healthServer{},
+	)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "tenant", "acme")
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return strings.Contains(serverBuf.String(), "finished call")
+	}, time.Second, 5*time.Millisecond)
+
+	entries := serverBuf.entries(t)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "watching", entries[0]["message"])
+	assert.Equal(t, "acme", entries[0]["tenant"])
+	assert.Equal(t, "Watch", entries[1][KeyMethod])
+	assert.Equal(t, "acme", entries[1]["tenant"])
+
+	assert.Equal(t, "Watch", clientBuf.entries(t)[0][KeyMethod])
+}
+
+func TestSkip(t *testing.T) {
+	buf := &lockedBuffer{}
+	cfg := Config{
+		Logger: newJSONLogger(buf),
+		Skip:   func(fullMethod string) bool { return strings.HasPrefix(fullMethod, "/grpc.health") },
+	}
+	client := newTestClient(t, cfg, cfg, &healthServer{})
+
+	_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+
+	entries := buf.entries(t)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "checking", entries[0]["message"])
+}
+
+func TestCodeToLevel(t *testing.T) {
+	assert.Equal(t, logger.InfoLevel, CodeToLevel(codes.OK))
+	assert.Equal(t, logger.WarnLevel, CodeToLevel(codes.InvalidArgument))
+	assert.Equal(t, logger.ErrorLevel, CodeToLevel(codes.Unavailable))
+	assert.Equal(t, logger.ErrorLevel, CodeToLevel(codes.Unknown))
+}
+
+func TestSplitMethod(t *testing.T) {
+	service, method := splitMethod("/pkg.v1.Service/Do")
+	assert.Equal(t, "pkg.v1.Service", service)
+	assert.Equal(t, "Do", method)
+}
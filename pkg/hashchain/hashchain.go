@@ -0,0 +1,244 @@
+// Package hashchain makes log files tamper-evident by chaining their
+// entries: a Writer adds to each line the hash of the line before it, so
+// that editing, inserting or deleting a line breaks the chain from there
+// on, which Verify and the logverify command detect.
+//
+// The hash is added as a last field named by Config.Key, "prev_hash" by
+// default: inside the object of JSON lines, and as a key=value pair at the
+// end of other lines. It is the SHA-256 of the previous line, including
+// its own hash, or its HMAC-SHA-256 with Config.Secret when set. Without a
+// secret, someone able to rewrite the whole file can also recompute the
+// chain; keep the secret away from the hosts writing the logs, or record
+// the last hash elsewhere, to detect that too.
+//
+// Example usage:
+//
+//	f, err := os.OpenFile("/var/log/app/app.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+//	if err != nil {
+//		return err
+//	}
+//	config := hashchain.Config{Secret: secret}
+//	config.Prev, err = hashchain.LastHash("/var/log/app/app.log", config)
+//	if err != nil {
+//		return err
+//	}
+//	log := logger.New(logger.Config{Format: logger.JSONFormat, Output: hashchain.New(f, config)})
+//
+// LastHash continues the chain of a file written before; with rotate,
+// the backups and the current file form one chain when verified in order.
+package hashchain
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// DefaultKey is the default name of the field holding the hash.
+const DefaultKey = "prev_hash"
+
+// Genesis is the hash the first line of a chain refers to.
+var Genesis = strings.Repeat("0", hashLen)
+
+// hashLen is the length of a hash in hex digits.
+const hashLen = 2 * sha256.Size
+
+// ErrBroken is wrapped by the errors of Verify for lines whose hash does
+// not match the line before them.
+var ErrBroken = errors.New("hashchain: chain is broken")
+
+// Config configures a Writer and Verify.
+type Config struct {
+	// Key is the name of the field holding the hash. Defaults to
+	// DefaultKey.
+	Key string
+
+	// Secret, when set, keys the hashes with HMAC-SHA-256.
+	Secret []byte
+
+	// Prev is the hash the first line refers to, as returned by LastHash.
+	// Defaults to Genesis.
+	Prev string
+}
+
+func (c Config) key() string {
+	if c.Key == "" {
+		return DefaultKey
+	}
+	return c.Key
+}
+
+func (c Config) prev() string {
+	if c.Prev == "" {
+		return Genesis
+	}
+	return c.Prev
+}
+
+// newHash returns the hash function of the chain.
+func (c Config) newHash() hash.Hash {
+	if len(c.Secret) > 0 {
+		return hmac.New(sha256.New, c.Secret)
+	}
+	return sha256.New()
+}
+
+// Writer is an io.Writer adding the hash of the previous line to each
+// line written to another writer. Writes must hold whole lines, as the
+// logger's do. It is safe for concurrent use.
+type Writer struct {
+	next io.Writer
+	key  string
+
+	mu   sync.Mutex
+	h    hash.Hash
+	prev [hashLen]byte
+	buf  []byte
+}
+
+// New returns a Writer chaining the lines written to next.
+func New(next io.Writer, config Config) *Writer {
+	w := &Writer{next: next, key: config.key(), h: config.newHash()}
+	copy(w.prev[:], config.prev())
+	return w
+}
+
+// Write adds the hash field to each line of p and writes them to the
+// underlying writer at once. The chain only advances if that succeeds.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	prev := w.prev
+	buf := w.buf[:0]
+	for rest := p; len(rest) > 0; {
+		line, after, _ := bytes.Cut(rest, []byte{'\n'})
+		rest = after
+
+		start := len(buf)
+		buf = appendChained(buf, line, w.key, prev[:])
+		w.h.Reset()
+		w.h.Write(buf[start:])
+		hex.Encode(prev[:], w.h.Sum(nil))
+		buf = append(buf, '\n')
+	}
+	w.buf = buf
+
+	if _, err := w.next.Write(buf); err != nil {
+		return 0, err
+	}
+	w.prev = prev
+	return len(p), nil
+}
+
+// appendChained appends line to buf with the hash field added.
+func appendChained(buf, line []byte, key string, prev []byte) []byte {
+	if n := len(line); n > 0 && line[n-1] == '}' {
+		body := bytes.TrimRight(line[:n-1], " ")
+		buf = append(buf, body...)
+		if len(body) > 0 && body[len(body)-1] != '{' {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, '"')
+		buf = append(buf, key...)
+		buf = append(buf, `":"`...)
+		buf = append(buf, prev...)
+		return append(buf, `"}`...)
+	}
+	buf = append(buf, line...)
+	if len(line) > 0 {
+		buf = append(buf, ' ')
+	}
+	buf = append(buf, key...)
+	buf = append(buf, '=')
+	return append(buf, prev...)
+}
+
+// Verify reads the lines of r and checks that each refers to the hash of
+// the line before it, the first one to config.Prev. It returns the number
+// of lines verified and, for the first line that does not match, an error
+// wrapping ErrBroken with its line number.
+func Verify(r io.Reader, config Config) (int, error) {
+	key := config.key()
+	h := config.newHash()
+	want := []byte(config.prev())
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	n := 0
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		got, ok := PrevHash(line, key)
+		if !ok {
+			return n, fmt.Errorf("%w: line %d has no %s field", ErrBroken, n+1, key)
+		}
+		if got != string(want) {
+			return n, fmt.Errorf("%w: line %d does not match the line before it", ErrBroken, n+1)
+		}
+
+		h.Reset()
+		h.Write(line)
+		want = hex.AppendEncode(want[:0], h.Sum(nil))
+		n++
+	}
+	return n, scanner.Err()
+}
+
+// PrevHash returns the hash field named key that a Writer added to line.
+func PrevHash(line []byte, key string) (string, bool) {
+	line = bytes.TrimSuffix(line, []byte{'\n'})
+	if bytes.HasSuffix(line, []byte(`"}`)) {
+		marker := `"` + key + `":"`
+		end := len(line) - 2
+		start := end - hashLen
+		if start-len(marker) >= 0 && string(line[start-len(marker):start]) == marker {
+			return string(line[start:end]), true
+		}
+		return "", false
+	}
+	marker := key + "="
+	start := len(line) - hashLen
+	if start-len(marker) >= 0 && string(line[start-len(marker):start]) == marker {
+		return string(line[start:]), true
+	}
+	return "", false
+}
+
+// LastHash returns the hash of the last line of the file at path, for
+// Config.Prev to continue its chain, or Genesis if the file is empty or
+// does not exist.
+func LastHash(path string, config Config) (string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Genesis, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var last []byte
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for scanner.Scan() {
+		last = append(last[:0], scanner.Bytes()...)
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	if last == nil {
+		return Genesis, nil
+	}
+	h := config.newHash()
+	h.Write(last)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,164 @@
+package hashchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+func sum(line string) string {
+	h := sha256.Sum256([]byte(line))
+	return hex.EncodeToString(h[:])
+}
+
+func TestWriter_JSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := logger.New(logger.Config{Level: logger.InfoLevel, Format: logger.JSONFormat, Output: New(buf, Config{}), OmitTime: true})
+
+	log.Info("first", logger.Int("n", 1))
+	log.Warn("second")
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, `{"level":"INFO","message":"first","n":1,"prev_hash":"`+Genesis+`"}`, lines[0])
+	assert.Equal(t, `{"level":"WARN","message":"second","prev_hash":"`+sum(lines[0])+`"}`, lines[1])
+	for _, line := range lines {
+		assert.True(t, json.Valid([]byte(line)), line)
+	}
+
+	n, err := Verify(strings.NewReader(buf.String()), Config{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+}
+
+func TestWriter_Text(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := New(buf, Config{Key: "chain"})
+
+	// Several lines in one write, as from a buffered logger.
+	_, err := w.Write([]byte("INFO a\nINFO b\n"))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("{}\n"))
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "INFO a chain="+Genesis, lines[0])
+	assert.Equal(t, "INFO b chain="+sum(lines[0]), lines[1])
+	assert.Equal(t, `{"chain":"`+sum(lines[1])+`"}`, lines[2])
+
+	n, err := Verify(strings.NewReader(buf.String()), Config{Key: "chain"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+}
+
+func TestVerify_Tampering(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := logger.New(logger.Config{Level: logger.InfoLevel, Format: logger.JSONFormat, Output: New(buf, Config{}), OmitTime: true})
+	for _, msg := range []string{"login", "transfer", "logout"} {
+		log.Info(msg, logger.String("user", "alice"))
+	}
+	lines := strings.SplitAfter(buf.String(), "\n")[:3]
+
+	tests := map[string]string{
+		"edited":    lines[0] + strings.Replace(lines[1], "alice", "mallory", 1) + lines[2],
+		"deleted":   lines[0] + lines[2],
+		"reordered": lines[0] + lines[2] + lines[1],
+		"truncated": lines[1] + lines[2],
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Verify(strings.NewReader(content), Config{})
+			assert.ErrorIs(t, err, ErrBroken)
+		})
+	}
+
+	_, err := Verify(strings.NewReader("plain line\n"), Config{})
+	assert.ErrorContains(t, err, "line 1 has no prev_hash field")
+}
+
+func TestWriter_Secret(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := New(buf, Config{Secret: []byte("s3cret")})
+	for _, line := range []string{"a\n", "b\n"} {
+		_, err := w.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	_, err := Verify(strings.NewReader(buf.String()), Config{Secret: []byte("s3cret")})
+	assert.NoError(t, err)
+	_, err = Verify(strings.NewReader(buf.String()), Config{})
+	assert.ErrorIs(t, err, ErrBroken, "the chain cannot be checked, nor forged, without the secret")
+}
+
+// failingWriter fails every Write.
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestWriter_ErrorKeepsChain(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := New(buf, Config{})
+	_, err := w.Write([]byte("a\n"))
+	require.NoError(t, err)
+
+	w.next = failingWriter{}
+	_, err = w.Write([]byte("lost\n"))
+	assert.Error(t, err)
+
+	w.next = buf
+	_, err = w.Write([]byte("b\n"))
+	require.NoError(t, err)
+	_, err = Verify(strings.NewReader(buf.String()), Config{})
+	assert.NoError(t, err)
+}
+
+func TestLastHash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	prev, err := LastHash(path, Config{})
+	require.NoError(t, err)
+	assert.Equal(t, Genesis, prev)
+
+	// Two runs appending to the same file form one chain.
+	for _, msg := range []string{"run 1\n", "run 2\n"} {
+		prev, err := LastHash(path, Config{})
+		require.NoError(t, err)
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		require.NoError(t, err)
+		_, err = New(f, Config{Prev: prev}).Write([]byte(msg))
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	n, err := Verify(bytes.NewReader(data), Config{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	prev, err = LastHash(path, Config{})
+	require.NoError(t, err)
+	assert.Equal(t, sum(strings.Split(string(data), "\n")[1]), prev)
+}
+
+func TestPrevHash(t *testing.T) {
+	got, ok := PrevHash([]byte(`{"a":1,"prev_hash":"`+Genesis+`"}`+"\n"), DefaultKey)
+	assert.True(t, ok)
+	assert.Equal(t, Genesis, got)
+
+	_, ok = PrevHash([]byte(`{"a":"x"}`), DefaultKey)
+	assert.False(t, ok)
+	_, ok = PrevHash([]byte("short"), DefaultKey)
+	assert.False(t, ok)
+}
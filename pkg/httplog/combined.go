@@ -0,0 +1,143 @@
+package httplog
+
+import (
+	"strconv"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// combinedTimeFormat is the timestamp layout of the Apache log formats.
+const combinedTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// CombinedEncoder is a logger.Encoder that writes access log entries in the
+// Apache combined log format:
+//
+//	127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /a.gif HTTP/1.1" 200 2326 "http://example.com/" "Mozilla/4.08"
+//
+// It reads the fields written by Middleware and ignores the message and
+// any other field. Missing values are written as "-".
+type CombinedEncoder struct{}
+
+// EncodeEntry implements logger.Encoder.
+func (CombinedEncoder) EncodeEntry(buf []byte, e *logger.Entry) []byte {
+	var (
+		method, path, query, proto string
+		remoteAddr, referer, agent string
+		status, size               int64 = -1, -1
+	)
+	for _, f := range e.Fields {
+		switch v := f.Interface().(type) {
+		case string:
+			switch f.Key {
+			case KeyMethod:
+				method = v
+			case KeyPath:
+				path = v
+			case KeyQuery:
+				query = v
+			case KeyProto:
+				proto = v
+			case KeyRemoteAddr:
+				remoteAddr = v
+			case KeyReferer:
+				referer = v
+			case KeyUserAgent:
+				agent = v
+			}
+		case int64:
+			switch f.Key {
+			case KeyStatus:
+				status = v
+			case KeySize:
+				size = v
+			}
+		}
+	}
+
+	buf = appendOrDash(buf, host(remoteAddr))
+	buf = append(buf, " - - ["...)
+	buf = e.Time.AppendFormat(buf, combinedTimeFormat)
+	buf = append(buf, "] \""...)
+	if method == "" {
+		buf = append(buf, '-')
+	} else {
+		buf = appendEscaped(buf, method)
+		buf = append(buf, ' ')
+		buf = appendEscaped(buf, path)
+		if query != "" {
+			buf = append(buf, '?')
+			buf = appendEscaped(buf, query)
+		}
+		buf = append(buf, ' ')
+		buf = appendEscaped(buf, proto)
+	}
+	buf = append(buf, "\" "...)
+	buf = appendNumber(buf, status)
+	buf = append(buf, ' ')
+	if size <= 0 {
+		buf = append(buf, '-')
+	} else {
+		buf = strconv.AppendInt(buf, size, 10)
+	}
+	buf = append(buf, " \""...)
+	buf = appendOrDash(buf, referer)
+	buf = append(buf, "\" \""...)
+	buf = appendOrDash(buf, agent)
+	return append(buf, '"')
+}
+
+// EncodeFields implements logger.Encoder. Fields bound with Logger.With
+// are not part of the combined format and are dropped.
+func (CombinedEncoder) EncodeFields(buf []byte, _ []logger.Field) []byte {
+	return buf
+}
+
+// host strips the port from a host:port remote address.
+func host(addr string) string {
+	for i := len(addr) - 1; i >= 0; i-- {
+		switch addr[i] {
+		case ':':
+			h := addr[:i]
+			if len(h) > 1 && h[0] == '[' && h[len(h)-1] == ']' {
+				h = h[1 : len(h)-1]
+			}
+			return h
+		case ']':
+			return addr
+		}
+	}
+	return addr
+}
+
+// appendOrDash appends s escaped, or "-" if it is empty.
+func appendOrDash(buf []byte, s string) []byte {
+	if s == "" {
+		return append(buf, '-')
+	}
+	return appendEscaped(buf, s)
+}
+
+// appendNumber appends n, or "-" if it is negative.
+func appendNumber(buf []byte, n int64) []byte {
+	if n < 0 {
+		return append(buf, '-')
+	}
+	return strconv.AppendInt(buf, n, 10)
+}
+
+// appendEscaped appends s with quotes, backslashes and non-printable bytes
+// escaped, as Apache does for request lines and headers.
+func appendEscaped(buf []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			buf = append(buf, '\\', c)
+		case c < 0x20 || c >= 0x7f:
+			buf = append(buf, '\\', 'x', hex[c>>4], hex[c&0xf])
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return buf
+}
@@ -0,0 +1,255 @@
+// Package httplog provides net/http middleware that writes an access log
+// entry for every request, binds a request-scoped logger to the request
+// context, and recovers panics raised by handlers.
+//
+// Example usage:
+//
+//	log := logger.New(logger.Config{Level: logger.InfoLevel, Format: logger.JSONFormat})
+//
+//	mux := http.NewServeMux()
+//	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+//		httplog.Logger(r).Info("handling request")
+//	})
+//
+//	http.ListenAndServe(":8080", httplog.Middleware(httplog.Config{Logger: log})(mux))
+//
+// To write the access log in Apache combined format, give the middleware a
+// logger whose encoder is CombinedEncoder.
+package httplog
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// DefaultRequestIDHeader is the header carrying request IDs when
+// Config.RequestIDHeader is empty.
+const DefaultRequestIDHeader = "X-Request-ID"
+
+// Field keys of access log entries.
+const (
+	KeyMethod     = "method"
+	KeyPath       = "path"
+	KeyQuery      = "query"
+	KeyProto      = "proto"
+	KeyStatus     = "status"
+	KeySize       = "size"
+	KeyLatency    = "latency"
+	KeyRemoteAddr = "remote_addr"
+	KeyUserAgent  = "user_agent"
+	KeyReferer    = "referer"
+	KeyRequestID  = "request_id"
+)
+
+// Config holds the configuration of the middleware.
+type Config struct {
+	// Logger receives access log entries and is the parent of
+	// request-scoped loggers. Defaults to logger.Default().
+	Logger *logger.Logger
+
+	// AccessLogger receives access log entries instead of Logger when not
+	// nil, for example to write them in Apache combined format to a
+	// separate file.
+	AccessLogger *logger.Logger
+
+	// Message is the message of access log entries. Defaults to "request".
+	Message string
+
+	// RequestIDHeader is read for an incoming request ID and set on the
+	// response. A random ID is generated when the request has none.
+	// Defaults to DefaultRequestIDHeader.
+	RequestIDHeader string
+
+	// Skip excludes requests, such as health checks, from the access log
+	// when it returns true. They still get a request-scoped logger.
+	Skip func(r *http.Request) bool
+}
+
+// requestIDKey is the context key of the request ID.
+type requestIDKey struct{}
+
+// Middleware returns middleware that logs every request handled by the
+// wrapped handler. Responses with a 5xx status are logged at ErrorLevel,
+// 4xx at WarnLevel, and others at InfoLevel.
+//
+// If the handler panics, the panic is logged at ErrorLevel with its stack
+// trace and a 500 response is sent if none was started. http.ErrAbortHandler
+// is re-raised without logging, as net/http aborts the response silently.
+func Middleware(config Config) func(http.Handler) http.Handler {
+	if config.Message == "" {
+		config.Message = "request"
+	}
+	if config.RequestIDHeader == "" {
+		config.RequestIDHeader = DefaultRequestIDHeader
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			base := config.Logger
+			if base == nil {
+				base = logger.Default()
+			}
+
+			id := r.Header.Get(config.RequestIDHeader)
+			if id == "" {
+				id = newRequestID()
+			}
+			w.Header().Set(config.RequestIDHeader, id)
+
+			ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+			reqLogger := base.With(logger.String(KeyRequestID, id))
+			r = r.WithContext(logger.NewContext(ctx, reqLogger))
+
+			sw := &statusWriter{ResponseWriter: w}
+			defer func() {
+				if v := recover(); v != nil {
+					if err, ok := v.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+						panic(v)
+					}
+					reqLogger.Error("panic recovered",
+						logger.Any("panic", v),
+						logger.String("stack", string(debug.Stack())),
+					)
+					if !sw.wroteHeader {
+						sw.WriteHeader(http.StatusInternalServerError)
+					}
+				}
+
+				if config.Skip != nil && config.Skip(r) {
+					return
+				}
+				access := config.AccessLogger
+				if access == nil {
+					access = base
+				}
+				logAccess(access, config.Message, r, sw, id, time.Since(start))
+			}()
+
+			next.ServeHTTP(sw, r)
+		})
+	}
+}
+
+// logAccess writes the access log entry of a completed request.
+func logAccess(l *logger.Logger, msg string, r *http.Request, sw *statusWriter, id string, latency time.Duration) {
+	status := sw.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	fields := []logger.Field{
+		logger.String(KeyMethod, r.Method),
+		logger.String(KeyPath, r.URL.Path),
+		logger.String(KeyQuery, r.URL.RawQuery),
+		logger.String(KeyProto, r.Proto),
+		logger.Int(KeyStatus, status),
+		logger.Int64(KeySize, sw.size),
+		logger.Dur(KeyLatency, latency),
+		logger.String(KeyRemoteAddr, r.RemoteAddr),
+		logger.String(KeyUserAgent, r.UserAgent()),
+		logger.String(KeyReferer, r.Referer()),
+		logger.String(KeyRequestID, id),
+	}
+
+	switch {
+	case status >= 500:
+		l.Error(msg, fields...)
+	case status >= 400:
+		l.Warn(msg, fields...)
+	default:
+		l.Info(msg, fields...)
+	}
+}
+
+// Logger returns a ContextLogger for r that includes its request ID and
+// the fields of the logger's context extractors. Outside of Middleware it
+// falls back to logger.FromContext.
+func Logger(r *http.Request) *logger.ContextLogger {
+	ctx := r.Context()
+	return logger.FromContext(ctx).WithStaticContext(ctx)
+}
+
+// RequestID returns the request ID assigned by Middleware, or "" if ctx
+// does not carry one.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// newRequestID returns a random 128-bit hex-encoded ID.
+func newRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// statusWriter records the status code and body size of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	size        int64
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher when the underlying writer does.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if !w.wroteHeader {
+			w.WriteHeader(http.StatusOK)
+		}
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker when the underlying writer does, for
+// WebSocket upgraders and other handlers taking over the connection. A
+// connection hijacked before a header was written is logged with status
+// 101 Switching Protocols.
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("httplog: hijack: %w", http.ErrNotSupported)
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil && !w.wroteHeader {
+		w.status = http.StatusSwitchingProtocols
+		w.wroteHeader = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,250 @@
+package httplog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+func newTestLogger(buf *bytes.Buffer) *logger.Logger {
+	return logger.New(logger.Config{
+		Level:  logger.DebugLevel,
+		Format: logger.JSONFormat,
+		Output: buf,
+	})
+}
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry), line)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestMiddleware_AccessLog(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	handler := Middleware(Config{Logger: newTestLogger(buf)})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Logger(r).Info("handling")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("hello"))
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/items?x=1", nil)
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set(DefaultRequestIDHeader, "req-1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, "req-1", rec.Header().Get(DefaultRequestIDHeader))
+
+	entries := decodeLines(t, buf)
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, "handling", entries[0]["message"])
+	assert.Equal(t, "req-1", entries[0][KeyRequestID])
+
+	access := entries[1]
+	assert.Equal(t, "INFO", access["level"])
+	assert.Equal(t, "request", access["message"])
+	assert.Equal(t, "POST", access[KeyMethod])
+	assert.Equal(t, "/items", access[KeyPath])
+	assert.Equal(t, "x=1", access[KeyQuery])
+	assert.Equal(t, float64(201), access[KeyStatus])
+	assert.Equal(t, float64(5), access[KeySize])
+	assert.Equal(t, "192.0.2.1:1234", access[KeyRemoteAddr])
+	assert.Equal(t, "test-agent", access[KeyUserAgent])
+	assert.Equal(t, "req-1", access[KeyRequestID])
+	assert.Contains(t, access, KeyLatency)
+}
+
+func TestMiddleware_StatusLevels(t *testing.T) {
+	tests := []struct {
+		status int
+		level  string
+	}{
+		{http.StatusOK, "INFO"},
+		{http.StatusNotFound, "WARN"},
+		{http.StatusBadGateway, "ERROR"},
+	}
+
+	for _, tt := range tests {
+		buf := &bytes.Buffer{}
+		handler := Middleware(Config{Logger: newTestLogger(buf)})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+		}))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		entries := decodeLines(t, buf)
+		assert.Equal(t, tt.level, entries[0]["level"], tt.status)
+	}
+}
+
+func TestMiddleware_GeneratesRequestID(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	var id string
+	handler := Middleware(Config{Logger: newTestLogger(buf)})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id = RequestID(r.Context())
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Len(t, id, 32)
+	assert.Equal(t, id, rec.Header().Get(DefaultRequestIDHeader))
+	assert.Equal(t, id, decodeLines(t, buf)[0][KeyRequestID])
+}
+
+func TestMiddleware_RecoversPanic(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	handler := Middleware(Config{Logger: newTestLogger(buf)})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+	rec := httptest.NewRecorder()
+	require.NotPanics(t, func() {
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	entries := decodeLines(t, buf)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "panic recovered", entries[0]["message"])
+	assert.Equal(t, "boom", entries[0]["panic"])
+	assert.Contains(t, entries[0]["stack"], "httplog_test.go")
+	assert.Equal(t, float64(500), entries[1][KeyStatus])
+}
+
+func TestMiddleware_ReraisesAbortHandler(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	handler := Middleware(Config{Logger: newTestLogger(buf)})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+	assert.Empty(t, buf.String(), "aborts are silent")
+}
+
+func TestMiddleware_Hijack(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	handler := Middleware(Config{Logger: newTestLogger(buf)})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, ok := w.(http.Hijacker)
+		if !assert.True(t, ok) {
+			return
+		}
+		conn, rw, err := h.Hijack()
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: test\r\nConnection: Upgrade\r\n\r\n")
+		_ = rw.Flush()
+	}))
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	// Writers that cannot be hijacked report it.
+	sw := &statusWriter{ResponseWriter: httptest.NewRecorder()}
+	_, _, err = sw.Hijack()
+	assert.ErrorIs(t, err, http.ErrNotSupported)
+}
+
+// hijackRecorder is a ResponseRecorder that can be hijacked.
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	conn net.Conn
+}
+
+func (r *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return r.conn, bufio.NewReadWriter(bufio.NewReader(r.conn), bufio.NewWriter(r.conn)), nil
+}
+
+func TestMiddleware_HijackStatus(t *testing.T) {
+	buf := &bytes.Buffer{}
+	server, client := net.Pipe()
+	defer client.Close()
+
+	handler := Middleware(Config{Logger: newTestLogger(buf)})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if assert.NoError(t, err) {
+			conn.Close()
+		}
+	}))
+	handler.ServeHTTP(&hijackRecorder{ResponseRecorder: httptest.NewRecorder(), conn: server}, httptest.NewRequest(http.MethodGet, "/ws", nil))
+
+	entries := decodeLines(t, buf)
+	require.Len(t, entries, 1)
+	assert.Equal(t, float64(http.StatusSwitchingProtocols), entries[0][KeyStatus])
+	assert.Equal(t, "INFO", entries[0]["level"])
+}
+
+func TestMiddleware_Skip(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	handler := Middleware(Config{
+		Logger: newTestLogger(buf),
+		Skip:   func(r *http.Request) bool { return r.URL.Path == "/healthz" },
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Empty(t, buf.String())
+}
+
+func TestCombinedEncoder(t *testing.T) {
+	buf := &bytes.Buffer{}
+	access := logger.New(logger.Config{
+		Level:   logger.InfoLevel,
+		Encoder: CombinedEncoder{},
+		Output:  buf,
+	})
+
+	handler := Middleware(Config{Logger: newTestLogger(&bytes.Buffer{}), AccessLogger: access})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("0123456789"))
+		}))
+
+	req := httptest.NewRequest(http.MethodGet, `/a"b?q=1`, nil)
+	req.RemoteAddr = "[2001:db8::1]:5555"
+	req.Header.Set("Referer", "http://example.com/")
+	req.Header.Set("User-Agent", "Mozilla/4.08")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	line := strings.TrimSpace(buf.String())
+	assert.Regexp(t, `^2001:db8::1 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] `, line)
+	assert.True(t, strings.HasSuffix(line,
+		`] "GET /a\"b?q=1 HTTP/1.1" 200 10 "http://example.com/" "Mozilla/4.08"`), line)
+}
+
+func TestCombinedEncoder_MissingFields(t *testing.T) {
+	ts := time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("", -7*3600))
+
+	out := CombinedEncoder{}.EncodeEntry(nil, &logger.Entry{Time: ts, Message: "not an access entry"})
+
+	assert.Equal(t, `- - - [10/Oct/2000:13:55:36 -0700] "-" - - "-" "-"`, string(out))
+}
@@ -0,0 +1,19 @@
+//go:build !unix
+
+package journald
+
+import (
+	"errors"
+	"net"
+)
+
+// tooLarge reports whether err means that a message does not fit in a
+// datagram. Descriptor passing is only available on unix systems.
+func tooLarge(error) bool {
+	return false
+}
+
+// sendFile is not supported outside unix systems.
+func sendFile(*net.UnixConn, []byte) error {
+	return errors.New("journald: message too large")
+}
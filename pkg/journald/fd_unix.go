@@ -0,0 +1,55 @@
+//go:build unix
+
+package journald
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+)
+
+// tooLarge reports whether err means that a message does not fit in a
+// datagram.
+func tooLarge(err error) bool {
+	return errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS)
+}
+
+// sendFile writes msg to an unlinked temporary file, in memory when
+// /dev/shm is available, and passes its descriptor to the journal.
+func sendFile(conn *net.UnixConn, msg []byte) error {
+	dir := "/dev/shm"
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		dir = ""
+	}
+
+	f, err := os.CreateTemp(dir, "journald-")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := os.Remove(f.Name()); err != nil {
+		return err
+	}
+	if _, err := f.Write(msg); err != nil {
+		return err
+	}
+
+	// WriteMsgUnix refuses connected datagram sockets, so call sendmsg on
+	// the descriptor directly.
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	rights := syscall.UnixRights(int(f.Fd()))
+	var sendErr error
+	err = raw.Write(func(fd uintptr) bool {
+		sendErr = syscall.Sendmsg(int(fd), nil, rights, nil, 0)
+		return sendErr != syscall.EAGAIN
+	})
+	if err != nil {
+		return err
+	}
+	return sendErr
+}
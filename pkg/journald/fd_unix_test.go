@@ -0,0 +1,53 @@
+//go:build unix
+
+package journald
+
+import (
+	"io"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+func TestWriter_LargeMessage(t *testing.T) {
+	conn, path := listen(t)
+
+	sink, w, err := NewSink(Config{SocketPath: path})
+	require.NoError(t, err)
+	defer w.Close()
+
+	large := strings.Repeat("x", 4<<20)
+	logger.New(logger.Config{Sinks: []logger.Sink{sink}}).Info(large)
+
+	oob := make([]byte, syscall.CmsgSpace(4))
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	_, oobn, _, _, err := conn.ReadMsgUnix(nil, oob)
+	require.NoError(t, err)
+
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	require.NoError(t, err)
+	require.Len(t, fds, 1)
+
+	f := os.NewFile(uintptr(fds[0]), "journal")
+	defer f.Close()
+
+	info, err := f.Stat()
+	require.NoError(t, err)
+	assert.Zero(t, info.Sys().(*syscall.Stat_t).Nlink)
+
+	_, err = f.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	msg, err := io.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, []string{large}, parse(t, msg)["MESSAGE"])
+}
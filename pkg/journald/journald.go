@@ -0,0 +1,167 @@
+// Package journald provides a logger sink that writes entries to the
+// systemd journal through its native protocol, so that levels, fields and
+// call sites keep their structure instead of being flattened into text
+// captured from stdout.
+//
+// Each entry becomes a journal entry with MESSAGE, PRIORITY (the syslog
+// severity of its level, see syslog.SeverityOf), SYSLOG_IDENTIFIER, the
+// call site as CODE_FILE, CODE_LINE and CODE_FUNC, and one journal field
+// per logger field, named after the field key in upper case.
+//
+// Example usage:
+//
+//	sink, w, err := journald.NewSink(journald.Config{Identifier: "api"})
+//	if err != nil {
+//		return err
+//	}
+//	defer w.Close()
+//
+//	log := logger.New(logger.Config{
+//		Level:     logger.InfoLevel,
+//		Sinks:     []logger.Sink{sink},
+//		AddCaller: true,
+//	})
+//
+// The Writer sends one entry per Write, so leave Config.BufferSize of the
+// logger unset.
+package journald
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+	"github.com/barnowlsnest/go-logslib/pkg/syslog"
+)
+
+// DefaultSocketPath is the native protocol socket of systemd-journald.
+const DefaultSocketPath = "/run/systemd/journal/socket"
+
+// maxFieldNameLen is the maximum length of a journal field name.
+const maxFieldNameLen = 64
+
+// Config holds the configuration for a journald sink.
+type Config struct {
+	// Identifier is logged as SYSLOG_IDENTIFIER. Defaults to the program
+	// name.
+	Identifier string
+
+	// SocketPath is the journal socket. Defaults to DefaultSocketPath.
+	SocketPath string
+}
+
+// NewSink connects to the journal described by config and returns a
+// logger.Sink writing to it, along with the Writer so that the caller can
+// close the connection.
+func NewSink(config Config) (logger.Sink, *Writer, error) {
+	w, err := Dial(config.SocketPath)
+	if err != nil {
+		return logger.Sink{}, nil, err
+	}
+	return logger.Sink{Output: w, Encoder: NewEncoder(config)}, w, nil
+}
+
+// NewEncoder returns a logger.Encoder that formats entries as journal
+// native protocol messages.
+func NewEncoder(config Config) logger.Encoder {
+	if config.Identifier == "" {
+		config.Identifier = filepath.Base(os.Args[0])
+	}
+	return encoder{identifier: config.Identifier}
+}
+
+// encoder implements logger.Encoder for the journal native protocol.
+type encoder struct {
+	identifier string
+}
+
+// EncodeEntry implements logger.Encoder.
+func (enc encoder) EncodeEntry(buf []byte, e *logger.Entry) []byte {
+	buf = appendField(buf, "MESSAGE", e.Message)
+	buf = append(buf, "PRIORITY="...)
+	buf = strconv.AppendInt(buf, int64(syslog.SeverityOf(e.Level)), 10)
+	buf = append(buf, '\n')
+	buf = appendField(buf, "SYSLOG_IDENTIFIER", enc.identifier)
+
+	if e.Caller != nil {
+		buf = appendField(buf, "CODE_FILE", e.Caller.File)
+		buf = append(buf, "CODE_LINE="...)
+		buf = strconv.AppendInt(buf, int64(e.Caller.Line), 10)
+		buf = append(buf, '\n')
+		buf = appendField(buf, "CODE_FUNC", e.Caller.Function)
+	}
+
+	buf = append(buf, e.Context...)
+	return enc.EncodeFields(buf, e.Fields)
+}
+
+// EncodeFields implements logger.Encoder. Field keys are converted to valid
+// journal field names by FieldName. Strings are logged as is, other values
+// in their text form.
+func (encoder) EncodeFields(buf []byte, fields []logger.Field) []byte {
+	for _, f := range fields {
+		var value string
+		switch v := f.Interface().(type) {
+		case string:
+			value = v
+		case time.Time:
+			value = v.Format(time.RFC3339Nano)
+		case error:
+			value = v.Error()
+		default:
+			value = string(f.AppendTextValue(nil))
+		}
+		buf = appendField(buf, FieldName(f.Key), value)
+	}
+	return buf
+}
+
+// appendField appends a journal field. Values without newlines use the
+// "NAME=value" form; others are length-prefixed.
+func appendField(buf []byte, name, value string) []byte {
+	buf = append(buf, name...)
+	if strings.IndexByte(value, '\n') < 0 {
+		buf = append(buf, '=')
+		buf = append(buf, value...)
+		return append(buf, '\n')
+	}
+
+	buf = append(buf, '\n')
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(len(value)))
+	buf = append(buf, value...)
+	return append(buf, '\n')
+}
+
+// FieldName converts a logger field key to a journal field name: letters
+// are upper-cased, other characters than letters, digits and underscores
+// become underscores, leading underscores are dropped, names starting with
+// a digit are prefixed with "F_", and the result is cut to 64 characters.
+func FieldName(key string) string {
+	b := make([]byte, 0, len(key)+2)
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c >= 'a' && c <= 'z':
+			b = append(b, c-'a'+'A')
+		case c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+			b = append(b, c)
+		case len(b) > 0:
+			b = append(b, '_')
+		}
+	}
+
+	if len(b) == 0 {
+		return "F_"
+	}
+	if b[0] >= '0' && b[0] <= '9' {
+		b = append([]byte("F_"), b...)
+	}
+	if len(b) > maxFieldNameLen {
+		b = b[:maxFieldNameLen]
+	}
+	return string(b)
+}
@@ -0,0 +1,153 @@
+package journald
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// parse decodes a native protocol message into its fields.
+func parse(t *testing.T, msg []byte) map[string][]string {
+	t.Helper()
+
+	fields := make(map[string][]string)
+	for len(msg) > 0 {
+		nl := bytes.IndexByte(msg, '\n')
+		require.GreaterOrEqual(t, nl, 0, "unterminated field")
+		line := msg[:nl]
+
+		if eq := bytes.IndexByte(line, '='); eq >= 0 {
+			fields[string(line[:eq])] = append(fields[string(line[:eq])], string(line[eq+1:]))
+			msg = msg[nl+1:]
+			continue
+		}
+
+		name := string(line)
+		msg = msg[nl+1:]
+		require.GreaterOrEqual(t, len(msg), 8)
+		n := int(binary.LittleEndian.Uint64(msg))
+		msg = msg[8:]
+		require.Greater(t, len(msg), n)
+		fields[name] = append(fields[name], string(msg[:n]))
+		require.Equal(t, byte('\n'), msg[n])
+		msg = msg[n+1:]
+	}
+	return fields
+}
+
+// listen creates a journal socket in a temporary directory.
+func listen(t *testing.T) (*net.UnixConn, string) {
+	path := filepath.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn, path
+}
+
+// receive reads one datagram from conn.
+func receive(t *testing.T, conn *net.UnixConn) []byte {
+	t.Helper()
+
+	buf := make([]byte, 64<<10)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	return buf[:n]
+}
+
+func TestSink(t *testing.T) {
+	conn, path := listen(t)
+
+	sink, w, err := NewSink(Config{Identifier: "api", SocketPath: path})
+	require.NoError(t, err)
+	defer w.Close()
+
+	log := logger.New(logger.Config{Level: logger.DebugLevel, Sinks: []logger.Sink{sink}, AddCaller: true})
+	log.With(logger.String("request_id", "abc")).Warn("slow request",
+		logger.Int("attempt", 3),
+		logger.Dur("took", 1500*time.Millisecond),
+		logger.Err(errors.New("timeout")),
+		logger.String("query", "SELECT 1\nFROM dual"),
+	)
+
+	msg := receive(t, conn)
+	assert.Equal(t, byte('\n'), msg[len(msg)-1])
+	assert.NotEqual(t, "\n\n", string(msg[len(msg)-2:]))
+
+	fields := parse(t, msg)
+	assert.Equal(t, []string{"slow request"}, fields["MESSAGE"])
+	assert.Equal(t, []string{"4"}, fields["PRIORITY"])
+	assert.Equal(t, []string{"api"}, fields["SYSLOG_IDENTIFIER"])
+	assert.Equal(t, []string{"journald/journald_test.go"}, fields["CODE_FILE"])
+	assert.NotEmpty(t, fields["CODE_LINE"])
+	assert.True(t, strings.HasSuffix(fields["CODE_FUNC"][0], "TestSink"))
+	assert.Equal(t, []string{"abc"}, fields["REQUEST_ID"])
+	assert.Equal(t, []string{"3"}, fields["ATTEMPT"])
+	assert.Equal(t, []string{"1.5s"}, fields["TOOK"])
+	assert.Equal(t, []string{"timeout"}, fields["ERROR"])
+	assert.Equal(t, []string{"SELECT 1\nFROM dual"}, fields["QUERY"])
+}
+
+func TestSink_Priorities(t *testing.T) {
+	conn, path := listen(t)
+
+	sink, w, err := NewSink(Config{SocketPath: path})
+	require.NoError(t, err)
+	defer w.Close()
+
+	log := logger.New(logger.Config{Level: logger.DebugLevel, Sinks: []logger.Sink{sink}})
+
+	for _, tt := range []struct {
+		log      func(string, ...logger.Field)
+		priority string
+	}{
+		{log.Info, "6"},
+		{log.Error, "3"},
+	} {
+		tt.log("entry")
+		assert.Equal(t, []string{tt.priority}, parse(t, receive(t, conn))["PRIORITY"])
+	}
+}
+
+func TestWriter_Closed(t *testing.T) {
+	_, path := listen(t)
+
+	w, err := Dial(path)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, w.Close())
+
+	_, err = w.Write([]byte("MESSAGE=late\n\n"))
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestDial_Error(t *testing.T) {
+	_, err := Dial(filepath.Join(t.TempDir(), "missing.sock"))
+	assert.Error(t, err)
+}
+
+func TestFieldName(t *testing.T) {
+	tests := map[string]string{
+		"request_id":            "REQUEST_ID",
+		"userID":                "USERID",
+		"http.status":           "HTTP_STATUS",
+		"_private":              "PRIVATE",
+		"2fa":                   "F_2FA",
+		"":                      "F_",
+		strings.Repeat("a", 70): strings.Repeat("A", 64),
+	}
+
+	for key, want := range tests {
+		assert.Equal(t, want, FieldName(key), key)
+	}
+}
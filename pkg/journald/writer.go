@@ -0,0 +1,66 @@
+package journald
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"sync"
+)
+
+// ErrClosed is returned by Write after Close has been called.
+var ErrClosed = errors.New("journald: writer is closed")
+
+// Writer is an io.Writer that sends each Write, a message produced by an
+// encoder from NewEncoder, to the journal as one entry. Messages too large
+// for a datagram are passed as a file descriptor, like sd_journal_send does.
+type Writer struct {
+	mu     sync.Mutex
+	conn   *net.UnixConn
+	closed bool
+}
+
+// Dial connects to the journal socket at path, or at DefaultSocketPath if
+// path is empty.
+func Dial(path string) (*Writer, error) {
+	if path == "" {
+		path = DefaultSocketPath
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{conn: conn}, nil
+}
+
+// Write sends p, without the newline the logger adds after each entry.
+func (w *Writer) Write(p []byte) (int, error) {
+	msg := bytes.TrimSuffix(p, []byte{'\n'})
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, ErrClosed
+	}
+	if _, err := w.conn.Write(msg); err != nil {
+		if !tooLarge(err) {
+			return 0, err
+		}
+		if err := sendFile(w.conn, msg); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Close closes the connection to the journal.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.conn.Close()
+}
@@ -0,0 +1,384 @@
+package logger
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultAsyncQueueSize is the queue capacity used when Config.Async is set
+// and Config.AsyncQueueSize is not.
+const DefaultAsyncQueueSize = 1024
+
+// maxAsyncBatch is the most queued entries the background goroutine
+// writes at once.
+const maxAsyncBatch = 128
+
+// maxBatchBufSize is the largest batch buffer kept for the next batch.
+const maxBatchBufSize = 1 << 20
+
+// closedBit is set in asyncWriter.tail once the writer is closed, so that
+// producers claiming a slot see the close in the same compare-and-swap.
+const closedBit = 1 << 63
+
+// AsyncOverflow selects what logging does in async mode when the queue is
+// full because entries are logged faster than they can be written,
+// trading completeness for latency.
+type AsyncOverflow int8
+
+const (
+	// AsyncBlock makes the caller wait until the queue has room. No entry
+	// is lost, but logging is as slow as the output. It is the default.
+	AsyncBlock AsyncOverflow = iota
+
+	// AsyncDropNewest discards the entry being logged.
+	AsyncDropNewest
+
+	// AsyncDropOldest discards the oldest queued entry to make room for
+	// the one being logged, keeping the most recent entries.
+	AsyncDropOldest
+
+	// AsyncWriteSync writes the entry on the calling goroutine, as if
+	// Async were not set, so that no entry is lost and the caller never
+	// waits on the queue. Entries written this way may reach the outputs
+	// ahead of entries still queued.
+	AsyncWriteSync
+)
+
+// String returns the name of the policy, as read by ParseAsyncOverflow.
+func (o AsyncOverflow) String() string {
+	switch o {
+	case AsyncBlock:
+		return "block"
+	case AsyncDropNewest:
+		return "drop_newest"
+	case AsyncDropOldest:
+		return "drop_oldest"
+	case AsyncWriteSync:
+		return "sync"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler, with the name of the
+// policy.
+func (o AsyncOverflow) MarshalText() ([]byte, error) {
+	if o < AsyncBlock || o > AsyncWriteSync {
+		return nil, fmt.Errorf("logger: unknown async overflow policy %d", o)
+	}
+	return []byte(o.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, parsing the policy
+// with ParseAsyncOverflow.
+func (o *AsyncOverflow) UnmarshalText(text []byte) error {
+	v, err := ParseAsyncOverflow(string(text))
+	if err != nil {
+		return err
+	}
+	*o = v
+	return nil
+}
+
+// ParseAsyncOverflow returns the policy named s, one of "block",
+// "drop_newest", "drop_oldest" and "sync" in any case.
+func ParseAsyncOverflow(s string) (AsyncOverflow, error) {
+	for o := AsyncBlock; o <= AsyncWriteSync; o++ {
+		if strings.EqualFold(strings.TrimSpace(s), o.String()) {
+			return o, nil
+		}
+	}
+	return 0, fmt.Errorf("logger: unknown async overflow policy %q", s)
+}
+
+// asyncWriter hands encoded entries over to a background goroutine that
+// performs the actual writes, keeping I/O off the caller goroutine.
+//
+// The queue is a bounded lock-free ring with many producers and the
+// background goroutine as its consumer. Producers claim a slot by
+// advancing tail and publish it through the slot's sequence number, so
+// that concurrent callers share no lock. Under AsyncDropOldest, producers
+// finding the queue full also consume, discarding the head entry.
+type asyncWriter struct {
+	slots    []asyncSlot
+	mask     uint64
+	overflow AsyncOverflow
+	stats    *writeStats
+	pool     *bufferPool
+
+	// tail is the position of the next slot producers claim, with
+	// closedBit set once closed, and head that of the next entry to
+	// consume. They are padded apart to avoid false sharing.
+	_    [56]byte
+	tail atomic.Uint64
+	_    [56]byte
+	head atomic.Uint64
+	_    [56]byte
+
+	// sleeping is set while the consumer waits on wake for entries.
+	sleeping atomic.Bool
+	wake     chan struct{}
+	done     chan struct{}
+
+	// written is the position up to which entries have been written or
+	// dropped. sync waits on cond for it to pass a position.
+	mu      sync.Mutex
+	cond    sync.Cond
+	written uint64
+}
+
+// asyncSlot is a slot of the ring. seq equals the position it can be
+// claimed at while free, and that position plus one once published.
+type asyncSlot struct {
+	seq   atomic.Uint64
+	entry asyncEntry
+}
+
+// asyncEntry is an encoded entry waiting to be written to the sinks
+// using encoder enc.
+type asyncEntry struct {
+	bufPtr *[]byte
+	level  Level
+	enc    int
+}
+
+// enqueueResult is the outcome of asyncWriter.enqueue.
+type enqueueResult uint8
+
+const (
+	// entryQueued means the entry was queued.
+	entryQueued enqueueResult = iota
+	// entryRejected means the caller must write the entry itself, because
+	// the writer is closed or under AsyncWriteSync.
+	entryRejected
+	// entryDropped means the entry was discarded under AsyncDropNewest.
+	entryDropped
+)
+
+// newAsyncWriter returns a writer whose queue holds size entries, rounded
+// up to a power of two.
+func newAsyncWriter(size int, overflow AsyncOverflow, stats *writeStats, pool *bufferPool) *asyncWriter {
+	if size <= 0 {
+		size = DefaultAsyncQueueSize
+	}
+	n := uint64(1)
+	for n < uint64(size) && n < math.MaxInt32 {
+		n <<= 1
+	}
+	a := &asyncWriter{
+		slots:    make([]asyncSlot, n),
+		mask:     n - 1,
+		overflow: overflow,
+		stats:    stats,
+		pool:     pool,
+		wake:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	a.cond.L = &a.mu
+	for i := range a.slots {
+		a.slots[i].seq.Store(uint64(i))
+	}
+	return a
+}
+
+// run writes queued entries to the sinks of c until the writer is closed
+// and its queue drained. The entries already queued are written together,
+// so that outputs that accept several entries per Write get a single
+// write per batch.
+func (a *asyncWriter) run(c *core) {
+	defer close(a.done)
+
+	batch := make([]asyncEntry, 0, maxAsyncBatch)
+	for {
+		for len(batch) < maxAsyncBatch {
+			e, ok := a.dequeue()
+			if !ok {
+				break
+			}
+			batch = append(batch, e)
+		}
+		if len(batch) > 0 {
+			c.writeBatch(batch)
+			clear(batch)
+			batch = batch[:0]
+			a.advance()
+			continue
+		}
+
+		tail := a.tail.Load()
+		switch {
+		case tail&^closedBit != a.head.Load():
+			// A producer claimed the next slot but has not published it yet.
+			runtime.Gosched()
+		case tail&closedBit != 0:
+			a.advance()
+			return
+		default:
+			a.sleep()
+		}
+	}
+}
+
+// advance records that the entries consumed so far are written, waking
+// the goroutines waiting in sync.
+func (a *asyncWriter) advance() {
+	a.mu.Lock()
+	a.written = a.head.Load()
+	a.cond.Broadcast()
+	a.mu.Unlock()
+}
+
+// dequeue removes the entry at the head of the queue, if published.
+func (a *asyncWriter) dequeue() (asyncEntry, bool) {
+	for {
+		pos := a.head.Load()
+		s := &a.slots[pos&a.mask]
+		seq := s.seq.Load()
+		if seq < pos+1 {
+			return asyncEntry{}, false
+		}
+		if seq == pos+1 && a.head.CompareAndSwap(pos, pos+1) {
+			e := s.entry
+			s.entry = asyncEntry{}
+			s.seq.Store(pos + a.mask + 1)
+			return e, true
+		}
+		// Another goroutine consumed the entry first.
+	}
+}
+
+// sleep blocks the consumer until a producer publishes an entry or the
+// writer is closed. The queue is checked again once sleeping is set, so
+// that a producer publishing in between either is seen here or sees
+// sleeping and wakes the consumer.
+func (a *asyncWriter) sleep() {
+	a.sleeping.Store(true)
+	if tail := a.tail.Load(); tail&^closedBit != a.head.Load() || tail&closedBit != 0 {
+		if a.sleeping.CompareAndSwap(true, false) {
+			return
+		}
+	}
+	<-a.wake
+}
+
+// notify wakes the consumer if it is sleeping.
+func (a *asyncWriter) notify() {
+	if a.sleeping.Load() && a.sleeping.CompareAndSwap(true, false) {
+		select {
+		case a.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// enqueue passes ownership of e.bufPtr to the background goroutine,
+// applying the overflow policy while the queue is full. Unless the entry
+// is queued, the caller keeps ownership and writes or discards it.
+func (a *asyncWriter) enqueue(e asyncEntry) enqueueResult {
+	full := false
+	for {
+		pos := a.tail.Load()
+		if pos&closedBit != 0 {
+			return entryRejected
+		}
+		s := &a.slots[pos&a.mask]
+		switch seq := s.seq.Load(); {
+		case seq == pos:
+			if a.tail.CompareAndSwap(pos, pos+1) {
+				s.entry = e
+				s.seq.Store(pos + 1)
+				a.notify()
+				return entryQueued
+			}
+		case seq < pos:
+			if !full {
+				full = true
+				a.stats.queueFull.Add(1)
+			}
+			switch a.overflow {
+			case AsyncDropNewest:
+				a.stats.queueDropped.Add(1)
+				return entryDropped
+			case AsyncWriteSync:
+				return entryRejected
+			case AsyncDropOldest:
+				if old, ok := a.dequeue(); ok {
+					a.stats.queueDropped.Add(1)
+					a.pool.put(old.bufPtr)
+					continue
+				}
+			}
+			// Let the consumer catch up.
+			a.notify()
+			runtime.Gosched()
+		}
+	}
+}
+
+// sync waits until the entries queued so far have been written or
+// dropped.
+func (a *asyncWriter) sync() {
+	target := a.tail.Load() &^ closedBit
+	a.notify()
+
+	a.mu.Lock()
+	for a.written < target {
+		a.cond.Wait()
+	}
+	a.mu.Unlock()
+}
+
+// close stops accepting entries and waits for the queue to drain.
+func (a *asyncWriter) close() {
+	for {
+		tail := a.tail.Load()
+		if tail&closedBit != 0 || a.tail.CompareAndSwap(tail, tail|closedBit) {
+			break
+		}
+	}
+	a.sleeping.Store(false)
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+	<-a.done
+}
+
+// Close shuts the logger down: it emits pending rate limit and repeat
+// summaries, drains any entries queued in async mode, stops the periodic
+// flush, and then flushes the buffer and syncs the outputs that are
+// WriteSyncers. With Config.CloseOutputs, it then closes the outputs.
+// It returns the first error of syncing or closing.
+//
+// Entries logged after Close, from the logger or any logger derived from
+// it, are dropped, so that goroutines still running during shutdown can
+// log safely. Close may be called more than once; later calls do nothing
+// and return nil.
+func (l *Logger) Close() error {
+	if l.core.closed.Swap(true) {
+		return nil
+	}
+	if l.core.limiter != nil {
+		l.core.limiter.close()
+	}
+	if l.core.deduper != nil {
+		l.core.deduper.close()
+	}
+	if l.core.async != nil {
+		l.core.async.close()
+	}
+	if l.core.flusher != nil {
+		l.core.flusher.close()
+	}
+	err := l.Sync()
+	if l.config.CloseOutputs {
+		if cerr := l.core.closeOutputs(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,355 @@
+package logger
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syncBuffer is a bytes.Buffer that is safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestLogger_AsyncDrainsOnClose(t *testing.T) {
+	buf := &syncBuffer{}
+
+	logger := New(Config{
+		Level:          InfoLevel,
+		Format:         JSONFormat,
+		Output:         buf,
+		Async:          true,
+		AsyncQueueSize: 4,
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				logger.Info("async message", Int("j", j))
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.NoError(t, logger.Close())
+
+	assert.Equal(t, 200, strings.Count(buf.String(), "async message"))
+}
+
+func TestLogger_AsyncWithBuffering(t *testing.T) {
+	buf := &syncBuffer{}
+
+	logger := New(Config{
+		Level:      InfoLevel,
+		Format:     TextFormat,
+		Output:     buf,
+		BufferSize: 1024,
+		Async:      true,
+	})
+
+	logger.Info("message1")
+	logger.With(String("child", "yes")).Info("message2")
+	require.NoError(t, logger.Close())
+
+	output := buf.String()
+	assert.Contains(t, output, "message1")
+	assert.Contains(t, output, "message2 child=yes")
+}
+
+func TestLogger_AsyncLogAfterClose(t *testing.T) {
+	buf := &syncBuffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: TextFormat,
+		Output: buf,
+		Async:  true,
+	})
+
+	logger.Info("early message")
+	require.NoError(t, logger.Close())
+	require.NoError(t, logger.Close())
+	logger.Info("late message")
+	logger.With(String("k", "v")).Error("late child")
+
+	assert.Contains(t, buf.String(), "early message")
+	assert.NotContains(t, buf.String(), "late")
+}
+
+func TestLogger_AsyncFlushWaitsForQueue(t *testing.T) {
+	buf := &syncBuffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: TextFormat,
+		Output: buf,
+		Async:  true,
+	})
+	defer logger.Close()
+
+	for i := 0; i < 100; i++ {
+		logger.Info("queued")
+	}
+	logger.Flush()
+
+	assert.Equal(t, 100, strings.Count(buf.String(), "queued"))
+}
+
+// writeCounter records each Write it receives.
+type writeCounter struct {
+	writes []string
+}
+
+func (w *writeCounter) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, string(p))
+	return len(p), nil
+}
+
+func TestCore_WriteBatch(t *testing.T) {
+	batched, single := &writeCounter{}, &writeCounter{}
+	logger := New(Config{
+		Level: InfoLevel,
+		Sinks: []Sink{
+			{Output: batched, Format: TextFormat},
+			{Output: single, Format: TextFormat, Level: WarnLevel},
+		},
+	})
+	logger.core.sinks[0].batch = true
+
+	entry := func(level Level, msg string) asyncEntry {
+		buf := []byte(level.String() + " " + msg)
+		return asyncEntry{bufPtr: &buf, level: level, enc: 0}
+	}
+	logger.core.writeBatch([]asyncEntry{
+		entry(InfoLevel, "one"),
+		entry(WarnLevel, "two"),
+		entry(ErrorLevel, "three"),
+	})
+
+	assert.Equal(t, []string{"INFO one\nWARN two\nERROR three\n"}, batched.writes)
+	assert.Equal(t, []string{"WARN two\n", "ERROR three\n"}, single.writes)
+	assert.Equal(t, uint64(5), logger.Stats().Written)
+}
+
+func TestLogger_AsyncBatchesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	logger := New(Config{Level: InfoLevel, Format: TextFormat, Output: f, Async: true})
+	for i := 0; i < 500; i++ {
+		logger.Info("queued", Int("i", i))
+	}
+	require.NoError(t, logger.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	require.Len(t, lines, 500)
+	for i, line := range lines {
+		assert.True(t, strings.HasSuffix(line, "INFO queued i="+strconv.Itoa(i)), line)
+	}
+}
+
+func TestAcceptsBatches(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "log")
+	require.NoError(t, err)
+	defer f.Close()
+	assert.True(t, acceptsBatches(f))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	tcp, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	defer tcp.Close()
+	assert.True(t, acceptsBatches(tcp))
+
+	udp, err := net.Dial("udp", "127.0.0.1:9")
+	require.NoError(t, err)
+	defer udp.Close()
+	assert.False(t, acceptsBatches(udp))
+
+	assert.False(t, acceptsBatches(&bytes.Buffer{}))
+}
+
+func TestLogger_AsyncSmallQueueManyProducers(t *testing.T) {
+	buf := &syncBuffer{}
+	logger := New(Config{Level: InfoLevel, Format: TextFormat, Output: buf, Async: true, AsyncQueueSize: 3})
+	assert.Len(t, logger.core.async.slots, 4)
+
+	var wg sync.WaitGroup
+	for p := 0; p < 8; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 250; i++ {
+				logger.Info("entry", Int("p", p))
+			}
+		}()
+	}
+	wg.Wait()
+	logger.Flush()
+	assert.Equal(t, 2000, strings.Count(buf.String(), "INFO entry"))
+
+	// Producers waiting on a full queue when it is closed either get their
+	// entry queued or return; none is left blocked.
+	for p := 0; p < 8; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 250; i++ {
+				logger.Info("late")
+			}
+		}()
+	}
+	require.NoError(t, logger.Close())
+	wg.Wait()
+}
+
+// gatedWriter records entries, blocking in its first Write until release
+// is closed so that the async queue fills up behind it.
+type gatedWriter struct {
+	mu      sync.Mutex
+	entries []string
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func newGatedWriter() *gatedWriter {
+	return &gatedWriter{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	w.entries = append(w.entries, strings.TrimSuffix(string(p), "\n"))
+	w.mu.Unlock()
+
+	first := false
+	w.once.Do(func() { first = true })
+	if first {
+		close(w.started)
+		<-w.release
+	}
+	return len(p), nil
+}
+
+func (w *gatedWriter) lines() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string(nil), w.entries...)
+}
+
+func TestLogger_AsyncOverflow(t *testing.T) {
+	tests := []struct {
+		overflow AsyncOverflow
+		want     []string
+		dropped  uint64
+	}{
+		{AsyncDropNewest, []string{"INFO e0", "INFO e1", "INFO e2"}, 1},
+		{AsyncDropOldest, []string{"INFO e0", "INFO e2", "INFO e3"}, 1},
+		{AsyncWriteSync, []string{"INFO e0", "INFO e3", "INFO e1", "INFO e2"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.overflow.String(), func(t *testing.T) {
+			out := newGatedWriter()
+			logger := New(Config{
+				Level:          InfoLevel,
+				Format:         TextFormat,
+				Output:         out,
+				OmitTime:       true,
+				Async:          true,
+				AsyncQueueSize: 2,
+				AsyncOverflow:  tt.overflow,
+			})
+
+			logger.Info("e0")
+			<-out.started
+			logger.Info("e1")
+			logger.Info("e2")
+			logger.Info("e3")
+
+			close(out.release)
+			require.NoError(t, logger.Close())
+			assert.Equal(t, tt.want, out.lines())
+
+			stats := logger.Stats()
+			assert.Equal(t, uint64(1), stats.QueueFull)
+			assert.Equal(t, tt.dropped, stats.QueueDropped)
+		})
+	}
+}
+
+func TestLogger_AsyncOverflowBlock(t *testing.T) {
+	out := newGatedWriter()
+	logger := New(Config{Level: InfoLevel, Format: TextFormat, Output: out, OmitTime: true, Async: true, AsyncQueueSize: 2})
+
+	logger.Info("e0")
+	<-out.started
+	logger.Info("e1")
+	logger.Info("e2")
+
+	logged := make(chan struct{})
+	go func() {
+		logger.Info("e3")
+		close(logged)
+	}()
+	select {
+	case <-logged:
+		t.Fatal("Info returned while the queue was full")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(out.release)
+	<-logged
+	require.NoError(t, logger.Close())
+	assert.Equal(t, []string{"INFO e0", "INFO e1", "INFO e2", "INFO e3"}, out.lines())
+	assert.Equal(t, uint64(1), logger.Stats().QueueFull)
+	assert.Zero(t, logger.Stats().QueueDropped)
+}
+
+func TestAsyncOverflow_Text(t *testing.T) {
+	for _, o := range []AsyncOverflow{AsyncBlock, AsyncDropNewest, AsyncDropOldest, AsyncWriteSync} {
+		text, err := o.MarshalText()
+		require.NoError(t, err)
+		var parsed AsyncOverflow
+		require.NoError(t, parsed.UnmarshalText(text))
+		assert.Equal(t, o, parsed)
+	}
+
+	parsed, err := ParseAsyncOverflow(" Drop_Oldest ")
+	require.NoError(t, err)
+	assert.Equal(t, AsyncDropOldest, parsed)
+
+	_, err = ParseAsyncOverflow("spill")
+	assert.Error(t, err)
+	_, err = AsyncOverflow(9).MarshalText()
+	assert.Error(t, err)
+}
@@ -3,10 +3,19 @@ package logger
 import (
 	"context"
 	"io"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 )
 
-var discardWriter = io.Discard
+// discardWriter drops entries like io.Discard, which the logger detects
+// and skips encoding for altogether.
+var discardWriter io.Writer = writerFunc(func(p []byte) (int, error) { return len(p), nil })
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
 
 func BenchmarkLogger_SimpleText(b *testing.B) {
 	logger := New(Config{
@@ -111,10 +120,12 @@ func BenchmarkLogger_WithContext(b *testing.B) {
 		Level:  InfoLevel,
 		Format: JSONFormat,
 		Output: discardWriter,
+
+		ContextExtractors: testContextExtractors,
 	})
 
-	ctx := context.WithValue(context.Background(), contextKey("traceID"), "trace123456")
-	ctx = context.WithValue(ctx, contextKey("spanID"), "span789012")
+	ctx := context.WithValue(context.Background(), traceIDKey{}, "trace123456")
+	ctx = context.WithValue(ctx, spanIDKey{}, "span789012")
 	contextLogger := logger.WithContext(func() context.Context { return ctx })
 
 	b.ResetTimer()
@@ -174,6 +185,29 @@ func BenchmarkLogger_ConcurrentAccess(b *testing.B) {
 	})
 }
 
+func BenchmarkLogger_BufferShards(b *testing.B) {
+	for _, shards := range []int{1, 8} {
+		b.Run("shards="+strconv.Itoa(shards), func(b *testing.B) {
+			logger := New(Config{
+				Level:        InfoLevel,
+				Format:       JSONFormat,
+				Output:       discardWriter,
+				BufferSize:   64 * 1024,
+				BufferShards: shards,
+			})
+
+			b.ResetTimer()
+			b.ReportAllocs()
+
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					logger.Info("concurrent message", Int("status", 200))
+				}
+			})
+		})
+	}
+}
+
 func BenchmarkAppendInt(b *testing.B) {
 	buf := make([]byte, 0, 64)
 
@@ -187,16 +221,17 @@ func BenchmarkAppendInt(b *testing.B) {
 }
 
 func BenchmarkAppendJSON(b *testing.B) {
-	logger := New(Config{
-		Level:  InfoLevel,
-		Format: JSONFormat,
-		Output: discardWriter,
-	})
+	enc := jsonEncoder{}
 
 	buf := make([]byte, 0, 256)
-	fields := []Field{
-		{Key: "key1", Value: "value1"},
-		{Key: "key2", Value: 42},
+	entry := &Entry{
+		Time:    time.Now(),
+		Level:   InfoLevel,
+		Message: "test message",
+		Fields: []Field{
+			{Key: "key1", Value: "value1"},
+			{Key: "key2", Value: 42},
+		},
 	}
 
 	b.ResetTimer()
@@ -204,7 +239,7 @@ func BenchmarkAppendJSON(b *testing.B) {
 
 	for i := 0; i < b.N; i++ {
 		buf = buf[:0]
-		buf = logger.appendJSON(buf, InfoLevel, "test message", fields...)
+		buf = enc.EncodeEntry(buf, entry)
 	}
 }
 
@@ -222,3 +257,192 @@ func BenchmarkFieldAppend(b *testing.B) {
 		buf = appendValue(buf, 3.14159)
 	}
 }
+
+func BenchmarkLogger_TypedFields(b *testing.B) {
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: JSONFormat,
+		Output: discardWriter,
+	})
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		logger.Info("user action",
+			Int("user_id", 12345),
+			String("action", "login"),
+			Bool("success", true),
+		)
+	}
+}
+
+func BenchmarkLogger_WithBoundFields(b *testing.B) {
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: JSONFormat,
+		Output: discardWriter,
+	}).With(
+		String("service", "billing"),
+		String("version", "1.4.2"),
+		String("region", "eu-west-1"),
+	)
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		logger.Info("request processed", Int("status", 200))
+	}
+}
+
+// staticFields are twelve fields a service typically binds once.
+var staticFields = []Field{
+	String("service", "billing"),
+	String("version", "1.4.2"),
+	String("region", "eu-west-1"),
+	String("zone", "eu-west-1a"),
+	String("env", "prod"),
+	String("host", "billing-7d9f8b6c5-x2x4k"),
+	String("namespace", "payments"),
+	String("team", "checkout"),
+	String("commit", "3f2a9c1"),
+	Int("pid", 4242),
+	Int("replicas", 3),
+	Bool("canary", false),
+}
+
+// BenchmarkLogger_StaticFields compares passing static fields with each
+// entry to binding them once with With, which encodes them once per format.
+func BenchmarkLogger_StaticFields(b *testing.B) {
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: JSONFormat,
+		Output: discardWriter,
+	})
+
+	b.Run("per-call", func(b *testing.B) {
+		fields := append(staticFields[:len(staticFields):len(staticFields)], Int("status", 200))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			logger.Info("request processed", fields...)
+		}
+	})
+	b.Run("bound", func(b *testing.B) {
+		bound := logger.With(staticFields...)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			bound.Info("request processed", Int("status", 200))
+		}
+	})
+}
+
+func BenchmarkLogger_Async(b *testing.B) {
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: JSONFormat,
+		Output: discardWriter,
+		Async:  true,
+	})
+	defer logger.Close()
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		logger.Info("async message", Int("iteration", i))
+	}
+}
+
+// BenchmarkLogger_AsyncProducers measures async throughput as the number
+// of goroutines logging concurrently grows. Producers share no lock, so the
+// time per entry should fall with their number up to GOMAXPROCS.
+func BenchmarkLogger_AsyncProducers(b *testing.B) {
+	for _, producers := range []int{1, 2, 4, 8} {
+		b.Run("producers="+strconv.Itoa(producers), func(b *testing.B) {
+			logger := New(Config{
+				Level:  InfoLevel,
+				Format: JSONFormat,
+				Output: discardWriter,
+				Async:  true,
+			})
+			defer logger.Close()
+
+			b.ResetTimer()
+			b.ReportAllocs()
+
+			var wg sync.WaitGroup
+			for p := 0; p < producers; p++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := p; i < b.N; i += producers {
+						logger.Info("async message", Int("iteration", i))
+					}
+				}()
+			}
+			wg.Wait()
+			logger.Flush()
+		})
+	}
+}
+
+func BenchmarkLogger_AddCaller(b *testing.B) {
+	logger := New(Config{
+		Level:     InfoLevel,
+		Format:    JSONFormat,
+		Output:    discardWriter,
+		AddCaller: true,
+	})
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		logger.Info("caller message")
+	}
+}
+
+func BenchmarkAppendTime(b *testing.B) {
+	buf := make([]byte, 0, 64)
+	now := time.Now()
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		buf = appendTime(buf[:0], now)
+	}
+}
+
+func BenchmarkAppendTimeFormat(b *testing.B) {
+	buf := make([]byte, 0, 64)
+	now := time.Now()
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		buf = now.AppendFormat(buf[:0], DefaultTimeFormat)
+	}
+}
+
+func BenchmarkLogger_Nop(b *testing.B) {
+	logger := Nop()
+
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		logger.Info("nop", String("user", "john"), Int("count", i))
+	}
+}
+
+func BenchmarkLogger_IODiscard(b *testing.B) {
+	logger := New(Config{Level: InfoLevel, Format: JSONFormat, Output: io.Discard})
+
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		logger.Info("discarded", String("user", "john"), Int("count", i))
+	}
+}
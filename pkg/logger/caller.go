@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// callerSkip is the number of frames between the user's call site and
+// Logger.log: the public logging method (Info, Warn, ...) and log itself.
+const callerSkip = 2
+
+// Caller describes the call site of a log entry.
+type Caller struct {
+	// File is the source file, trimmed to its last directory and file name.
+	File string
+
+	// Line is the line number within File.
+	Line int
+
+	// Function is the fully qualified name of the calling function.
+	Function string
+}
+
+// captureCaller resolves the call site skip frames above its caller.
+// It reports false when the frame cannot be determined.
+func captureCaller(skip int) (Caller, bool) {
+	pc, file, line, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return Caller{}, false
+	}
+
+	c := Caller{File: trimCallerPath(file), Line: line}
+	if fn := runtime.FuncForPC(pc); fn != nil {
+		c.Function = fn.Name()
+	}
+	return c, true
+}
+
+// captureStacktrace formats the stack of the calling goroutine from skip
+// frames above its caller, one "function\n\tfile:line" pair per frame.
+func captureStacktrace(skip int) string {
+	var pcs [64]uintptr
+	n := runtime.Callers(skip+2, pcs[:])
+	if n == 0 {
+		return ""
+	}
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var b strings.Builder
+	for {
+		frame, more := frames.Next()
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(frame.Function)
+		b.WriteString("\n\t")
+		b.WriteString(frame.File)
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(frame.Line))
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+// trimCallerPath keeps the last directory and the file name of a path,
+// e.g. "/src/app/handler/user.go" becomes "handler/user.go".
+func trimCallerPath(file string) string {
+	idx := strings.LastIndexByte(file, '/')
+	if idx <= 0 {
+		return file
+	}
+	if idx = strings.LastIndexByte(file[:idx], '/'); idx < 0 {
+		return file
+	}
+	return file[idx+1:]
+}
+
+// appendCaller appends the call site as "dir/file.go:line".
+func appendCaller(buf []byte, c *Caller) []byte {
+	buf = append(buf, c.File...)
+	buf = append(buf, ':')
+	return appendInt(buf, int64(c.Line))
+}
@@ -0,0 +1,120 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"regexp"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger_AddCallerJSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:     InfoLevel,
+		Format:    JSONFormat,
+		Output:    buf,
+		AddCaller: true,
+	})
+
+	logger.Info("test message")
+
+	output := buf.String()
+	assert.Regexp(t, `"caller":"logger/caller_test.go:\d+"`, output)
+	assert.Contains(t, output, `"function":"github.com/barnowlsnest/go-logslib/pkg/logger.TestLogger_AddCallerJSON"`)
+}
+
+func TestLogger_AddCallerText(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:     InfoLevel,
+		Format:    TextFormat,
+		Output:    buf,
+		AddCaller: true,
+	})
+
+	logger.With(String("k", "v")).Warn("test message")
+	logger.WithStaticContext(context.Background()).Error("context message")
+
+	output := buf.String()
+	assert.Regexp(t, regexp.MustCompile(`WARN logger/caller_test.go:\d+ test message k=v`), output)
+	assert.Regexp(t, regexp.MustCompile(`ERROR logger/caller_test.go:\d+ context message`), output)
+}
+
+func logHelper(l *Logger) {
+	l.Info("from helper")
+}
+
+func TestLogger_CallerSkip(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:      InfoLevel,
+		Format:     JSONFormat,
+		Output:     buf,
+		AddCaller:  true,
+		CallerSkip: 1,
+	})
+
+	logHelper(logger)
+
+	assert.Contains(t, buf.String(), `"function":"github.com/barnowlsnest/go-logslib/pkg/logger.TestLogger_CallerSkip"`)
+}
+
+func TestTrimCallerPath(t *testing.T) {
+	assert.Equal(t, "handler/user.go", trimCallerPath("/src/app/handler/user.go"))
+	assert.Equal(t, "handler/user.go", trimCallerPath("handler/user.go"))
+	assert.Equal(t, "user.go", trimCallerPath("user.go"))
+}
+
+func TestLogger_WithCallerSkip(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:     InfoLevel,
+		Format:    TextFormat,
+		Output:    buf,
+		AddCaller: true,
+	})
+
+	helper := func(msg string) {
+		logger.WithCallerSkip(1).Info(msg)
+	}
+	_, _, line, _ := runtime.Caller(0)
+	helper("from helper")
+
+	assert.Contains(t, buf.String(), "INFO logger/caller_test.go:"+strconv.Itoa(line+1)+" from helper")
+}
+
+func TestLogger_AddStacktrace(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:         InfoLevel,
+		Format:        JSONFormat,
+		Output:        buf,
+		OmitTime:      true,
+		AddStacktrace: true,
+		Keys:          Keys{Stacktrace: "stack"},
+	})
+
+	logger.Warn("no stack")
+	assert.Equal(t, `{"level":"WARN","message":"no stack"}`+"\n", buf.String())
+
+	buf.Reset()
+	fields := make([]Field, 1, 2)
+	fields[0] = String("k", "v")
+	logger.Error("failed", fields...)
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	stack, _ := entry["stack"].(string)
+	assert.Regexp(t, `^github.com/barnowlsnest/go-logslib/pkg/logger.TestLogger_AddStacktrace\n\t.*/caller_test.go:\d+\n`, stack)
+	assert.Equal(t, Field{}, fields[:2][1], "caller's spare capacity must be left untouched")
+}
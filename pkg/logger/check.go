@@ -0,0 +1,56 @@
+package logger
+
+import "sync"
+
+// CheckedEntry is an entry that passed the level check of Logger.Check,
+// waiting for its fields. Write it once; it must not be used afterwards.
+type CheckedEntry struct {
+	logger *Logger
+	level  Level
+	msg    string
+}
+
+var checkedEntryPool = sync.Pool{
+	New: func() interface{} {
+		return &CheckedEntry{}
+	},
+}
+
+// Check returns a CheckedEntry for a message at level if entries at level
+// are enabled, or nil otherwise. Use it to skip expensive field
+// construction, such as serialization or lookups, for disabled levels:
+//
+//	if ce := log.Check(logger.DebugLevel, "cache state"); ce != nil {
+//		ce.Write(logger.Any("cache", cache.Snapshot()))
+//	}
+//
+// Entries at FatalLevel and PanicLevel are always returned, even when
+// they are not written, and writing them exits or panics like Logger.Fatal
+// and Logger.Panic.
+func (l *Logger) Check(level Level, msg string) *CheckedEntry {
+	if level < FatalLevel && !l.Enabled(level) {
+		return nil
+	}
+	ce := checkedEntryPool.Get().(*CheckedEntry)
+	ce.logger, ce.level, ce.msg = l, level, msg
+	return ce
+}
+
+// Write logs the entry with fields. It is a no-op on a nil CheckedEntry.
+func (ce *CheckedEntry) Write(fields ...Field) {
+	if ce == nil {
+		return
+	}
+	l, level, msg := ce.logger, ce.level, ce.msg
+	*ce = CheckedEntry{}
+	checkedEntryPool.Put(ce)
+
+	l.log(level, msg, fields...)
+	switch level {
+	case FatalLevel:
+		l.exit()
+	case PanicLevel:
+		l.Flush()
+		panic(msg)
+	}
+}
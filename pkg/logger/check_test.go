@@ -0,0 +1,65 @@
+package logger
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger_Check(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(Config{Level: InfoLevel, Format: TextFormat, Output: buf, OmitTime: true, AddCaller: true})
+
+	built := false
+	if ce := logger.Check(DebugLevel, "skipped"); ce != nil {
+		built = true
+		ce.Write(String("k", "v"))
+	}
+	assert.False(t, built)
+	assert.Empty(t, buf.String())
+
+	ce := logger.Check(InfoLevel, "checked")
+	require.NotNil(t, ce)
+	ce.Write(String("k", "v"))
+	assert.Regexp(t, `^INFO logger/check_test.go:\d+ checked k=v\n$`, buf.String())
+
+	var nilEntry *CheckedEntry
+	assert.NotPanics(t, func() { nilEntry.Write() })
+}
+
+func TestLogger_CheckFatalAndPanic(t *testing.T) {
+	var codes []int
+	logger := New(Config{Level: InfoLevel, Output: &bytes.Buffer{}, ExitFunc: func(code int) { codes = append(codes, code) }})
+
+	logger.Check(FatalLevel, "fatal").Write()
+	assert.Equal(t, []int{1}, codes)
+
+	assert.PanicsWithValue(t, "boom", func() { logger.Check(PanicLevel, "boom").Write() })
+}
+
+func TestLogger_CheckFatalAndPanicDisabled(t *testing.T) {
+	var codes []int
+	logger := New(Config{Level: InfoLevel, Output: io.Discard, ExitFunc: func(code int) { codes = append(codes, code) }})
+
+	ce := logger.Check(FatalLevel, "fatal")
+	require.NotNil(t, ce, "Fatal exits even when nothing is written")
+	ce.Write()
+	assert.Equal(t, []int{1}, codes)
+
+	assert.PanicsWithValue(t, "boom", func() { Nop().Check(PanicLevel, "boom").Write() })
+	assert.Nil(t, Nop().Check(ErrorLevel, "dropped"))
+}
+
+func TestLogger_CheckAllocations(t *testing.T) {
+	logger := New(Config{Level: InfoLevel, Format: JSONFormat, Output: discardWriter})
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if ce := logger.Check(InfoLevel, "checked"); ce != nil {
+			ce.Write(String("k", "v"), Int("n", 1))
+		}
+	})
+	assert.Zero(t, allocs)
+}
@@ -0,0 +1,353 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/barnowlsnest/go-logslib/pkg/rotate"
+)
+
+// Outputs of a config file that name the standard streams rather than a
+// file.
+const (
+	FileOutputStdout = "stdout"
+	FileOutputStderr = "stderr"
+)
+
+// fileConfig is the document read by ConfigFromFile. Keys are the same in
+// every format.
+type fileConfig struct {
+	Level          Level            `json:"level" yaml:"level" toml:"level"`
+	Levels         map[string]Level `json:"levels" yaml:"levels" toml:"levels"`
+	Format         Format           `json:"format" yaml:"format" toml:"format"`
+	Output         string           `json:"output" yaml:"output" toml:"output"`
+	Rotation       *fileRotation    `json:"rotation" yaml:"rotation" toml:"rotation"`
+	BufferSize     int              `json:"buffer_size" yaml:"buffer_size" toml:"buffer_size"`
+	BufferShards   int              `json:"buffer_shards" yaml:"buffer_shards" toml:"buffer_shards"`
+	FlushInterval  fileDuration     `json:"flush_interval" yaml:"flush_interval" toml:"flush_interval"`
+	Async          bool             `json:"async" yaml:"async" toml:"async"`
+	AsyncQueueSize int              `json:"async_queue_size" yaml:"async_queue_size" toml:"async_queue_size"`
+	AsyncOverflow  AsyncOverflow    `json:"async_overflow" yaml:"async_overflow" toml:"async_overflow"`
+	UseUTC         bool             `json:"use_utc" yaml:"use_utc" toml:"use_utc"`
+	AddCaller      bool             `json:"add_caller" yaml:"add_caller" toml:"add_caller"`
+	AddStacktrace  bool             `json:"add_stacktrace" yaml:"add_stacktrace" toml:"add_stacktrace"`
+	AddGoroutineID bool             `json:"add_goroutine_id" yaml:"add_goroutine_id" toml:"add_goroutine_id"`
+	TimeFormat     string           `json:"time_format" yaml:"time_format" toml:"time_format"`
+	TimeKey        string           `json:"time_key" yaml:"time_key" toml:"time_key"`
+	LevelEncoder   LevelEncoder     `json:"level_encoder" yaml:"level_encoder" toml:"level_encoder"`
+	Keys           *fileKeys        `json:"keys" yaml:"keys" toml:"keys"`
+	OmitTime       bool             `json:"omit_time" yaml:"omit_time" toml:"omit_time"`
+	CSVColumns     []string         `json:"csv_columns" yaml:"csv_columns" toml:"csv_columns"`
+	SortFields     bool             `json:"sort_fields" yaml:"sort_fields" toml:"sort_fields"`
+	DuplicateKeys  DuplicateKeys    `json:"duplicate_keys" yaml:"duplicate_keys" toml:"duplicate_keys"`
+	Sampling       *fileSampling    `json:"sampling" yaml:"sampling" toml:"sampling"`
+	RateLimit      *fileRateLimit   `json:"rate_limit" yaml:"rate_limit" toml:"rate_limit"`
+	Dedup          *fileDedup       `json:"dedup" yaml:"dedup" toml:"dedup"`
+	Redact         *fileRedact      `json:"redact" yaml:"redact" toml:"redact"`
+	AddHostInfo    bool             `json:"add_host_info" yaml:"add_host_info" toml:"add_host_info"`
+	Service        string           `json:"service" yaml:"service" toml:"service"`
+	Env            string           `json:"env" yaml:"env" toml:"env"`
+	Version        string           `json:"version" yaml:"version" toml:"version"`
+	AddBuildInfo   bool             `json:"add_build_info" yaml:"add_build_info" toml:"add_build_info"`
+	Fields         map[string]any   `json:"fields" yaml:"fields" toml:"fields"`
+	Sinks          []fileSink       `json:"sinks" yaml:"sinks" toml:"sinks"`
+}
+
+type fileSink struct {
+	Output   string        `json:"output" yaml:"output" toml:"output"`
+	Level    Level         `json:"level" yaml:"level" toml:"level"`
+	Levels   []Level       `json:"levels" yaml:"levels" toml:"levels"`
+	Format   Format        `json:"format" yaml:"format" toml:"format"`
+	Rotation *fileRotation `json:"rotation" yaml:"rotation" toml:"rotation"`
+}
+
+type fileRotation struct {
+	MaxSize    int64        `json:"max_size" yaml:"max_size" toml:"max_size"`
+	Interval   fileDuration `json:"interval" yaml:"interval" toml:"interval"`
+	MaxAge     fileDuration `json:"max_age" yaml:"max_age" toml:"max_age"`
+	MaxBackups int          `json:"max_backups" yaml:"max_backups" toml:"max_backups"`
+	Compress   bool         `json:"compress" yaml:"compress" toml:"compress"`
+}
+
+type fileKeys struct {
+	Time       string `json:"time" yaml:"time" toml:"time"`
+	Level      string `json:"level" yaml:"level" toml:"level"`
+	Message    string `json:"message" yaml:"message" toml:"message"`
+	Caller     string `json:"caller" yaml:"caller" toml:"caller"`
+	Stacktrace string `json:"stacktrace" yaml:"stacktrace" toml:"stacktrace"`
+}
+
+type fileSampling struct {
+	Tick       fileDuration `json:"tick" yaml:"tick" toml:"tick"`
+	Initial    int          `json:"initial" yaml:"initial" toml:"initial"`
+	Thereafter int          `json:"thereafter" yaml:"thereafter" toml:"thereafter"`
+}
+
+type fileRateLimit struct {
+	Interval fileDuration `json:"interval" yaml:"interval" toml:"interval"`
+	Limit    int          `json:"limit" yaml:"limit" toml:"limit"`
+	KeyField string       `json:"key_field" yaml:"key_field" toml:"key_field"`
+}
+
+type fileDedup struct {
+	Window fileDuration `json:"window" yaml:"window" toml:"window"`
+}
+
+type fileRedact struct {
+	Keys       []string `json:"keys" yaml:"keys" toml:"keys"`
+	Patterns   []string `json:"patterns" yaml:"patterns" toml:"patterns"`
+	Mode       string   `json:"mode" yaml:"mode" toml:"mode"`
+	Mask       string   `json:"mask" yaml:"mask" toml:"mask"`
+	HashKeys   []string `json:"hash_keys" yaml:"hash_keys" toml:"hash_keys"`
+	HashSecret string   `json:"hash_secret" yaml:"hash_secret" toml:"hash_secret"`
+}
+
+// Names of the built-in patterns and modes of the redact section of a
+// config file.
+const (
+	FileRedactCreditCard = "credit_card"
+	FileRedactEmail      = "email"
+	FileRedactMask       = "mask"
+	FileRedactHash       = "hash"
+)
+
+// fileDuration is a duration written as by time.Duration.String, such as
+// "1m30s".
+type fileDuration time.Duration
+
+func (d *fileDuration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	*d = fileDuration(v)
+	return err
+}
+
+// ConfigFromFile reads a Config from a JSON, YAML or TOML file, picked by
+// its extension: ".json", ".yaml", ".yml" or ".toml". It complements
+// ConfigFromEnv for deployments that manage logging with mounted config
+// maps. A YAML example of every key:
+//
+//	level: info
+//	levels: {db: debug, http: warn}
+//	format: json
+//	output: stdout # stderr, or a file path
+//	rotation: {max_size: 104857600, interval: 24h, max_age: 168h, max_backups: 7, compress: true}
+//	buffer_size: 4096
+//	buffer_shards: 8
+//	flush_interval: 1s
+//	async: true
+//	async_queue_size: 1024
+//	async_overflow: drop_oldest # block, drop_newest or sync
+//	use_utc: true
+//	add_caller: true
+//	add_stacktrace: true
+//	add_goroutine_id: true
+//	time_format: "2006-01-02T15:04:05.000Z07:00"
+//	time_key: timestamp
+//	level_encoder: lower # upper, short, syslog or otel
+//	keys: {time: ts, level: lvl, message: msg, caller: src, stacktrace: stack}
+//	omit_time: false
+//	csv_columns: [timestamp, level, message, user_id]
+//	sort_fields: true
+//	duplicate_keys: last # allow, first or suffix
+//	sampling: {tick: 1s, initial: 100, thereafter: 10}
+//	rate_limit: {interval: 1s, limit: 10, key_field: route}
+//	dedup: {window: 10s}
+//	redact: {keys: [password, token], patterns: [credit_card, email, "\\bSSN-\\d+"], mode: hash, hash_keys: [user_id], hash_secret: s3cr3t}
+//	add_host_info: true
+//	service: billing
+//	env: prod
+//	version: 1.4.2
+//	add_build_info: true
+//	fields: {team: payments}
+//	sinks:
+//	  - {output: stderr, level: warn, format: console}
+//	  - {output: /var/log/app.log, levels: [debug, info], format: json, rotation: {max_size: 104857600}}
+//
+// Levels and formats are written by name, as for ConfigFromEnv, and
+// durations as by time.Duration.String. Redact patterns are regular
+// expressions or the names of CreditCardPattern and EmailPattern:
+// "credit_card" and "email". Unknown keys are an error. Outputs
+// other than the standard streams are files written through
+// rotate.Writer, rotated as set by rotation; the returned Config sets
+// CloseOutputs, so that Logger.Close closes them.
+func ConfigFromFile(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	var fc fileConfig
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&fc)
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err = dec.Decode(&fc); err == io.EOF {
+			err = nil
+		}
+	case ".toml":
+		var md toml.MetaData
+		md, err = toml.Decode(string(data), &fc)
+		if undecoded := md.Undecoded(); err == nil && len(undecoded) > 0 {
+			err = fmt.Errorf("unknown key %q", undecoded[0].String())
+		}
+	default:
+		return Config{}, fmt.Errorf("logger: unsupported config file extension %q", ext)
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("logger: %s: %w", path, err)
+	}
+
+	config, err := fc.config()
+	if err != nil {
+		return Config{}, fmt.Errorf("logger: %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// config converts the document into a Config.
+func (fc *fileConfig) config() (Config, error) {
+	config := Config{
+		Level:          fc.Level,
+		Format:         fc.Format,
+		Output:         fileOutput(fc.Output, fc.Rotation),
+		CloseOutputs:   true,
+		BufferSize:     fc.BufferSize,
+		BufferShards:   fc.BufferShards,
+		FlushInterval:  time.Duration(fc.FlushInterval),
+		Async:          fc.Async,
+		AsyncQueueSize: fc.AsyncQueueSize,
+		AsyncOverflow:  fc.AsyncOverflow,
+		UseUTC:         fc.UseUTC,
+		AddCaller:      fc.AddCaller,
+		AddStacktrace:  fc.AddStacktrace,
+		AddGoroutineID: fc.AddGoroutineID,
+		TimeFormat:     fc.TimeFormat,
+		TimeKey:        fc.TimeKey,
+		LevelEncoder:   fc.LevelEncoder,
+		OmitTime:       fc.OmitTime,
+		CSVColumns:     fc.CSVColumns,
+		SortFields:     fc.SortFields,
+		DuplicateKeys:  fc.DuplicateKeys,
+		AddHostInfo:    fc.AddHostInfo,
+		Service:        fc.Service,
+		Env:            fc.Env,
+		Version:        fc.Version,
+		AddBuildInfo:   fc.AddBuildInfo,
+		Levels:         fc.Levels,
+	}
+
+	if k := fc.Keys; k != nil {
+		config.Keys = Keys{
+			Time:       k.Time,
+			Level:      k.Level,
+			Message:    k.Message,
+			Caller:     k.Caller,
+			Stacktrace: k.Stacktrace,
+		}
+	}
+
+	if s := fc.Sampling; s != nil {
+		config.Sampling = &SamplerConfig{
+			Tick:       time.Duration(s.Tick),
+			Initial:    s.Initial,
+			Thereafter: s.Thereafter,
+		}
+	}
+	if r := fc.RateLimit; r != nil {
+		config.RateLimit = &RateLimitConfig{
+			Interval: time.Duration(r.Interval),
+			Limit:    r.Limit,
+			KeyField: r.KeyField,
+		}
+	}
+
+	if d := fc.Dedup; d != nil {
+		config.Dedup = &DedupConfig{Window: time.Duration(d.Window)}
+	}
+
+	if r := fc.Redact; r != nil {
+		config.Redact = &RedactConfig{Keys: r.Keys, Mask: r.Mask, HashKeys: r.HashKeys}
+		if r.HashSecret != "" {
+			config.Redact.HashSecret = []byte(r.HashSecret)
+		}
+		switch strings.ToLower(r.Mode) {
+		case "", FileRedactMask:
+		case FileRedactHash:
+			config.Redact.Mode = RedactHash
+		default:
+			return Config{}, fmt.Errorf("unknown redact mode %q", r.Mode)
+		}
+		for _, pattern := range r.Patterns {
+			switch pattern {
+			case FileRedactCreditCard:
+				config.Redact.Patterns = append(config.Redact.Patterns, CreditCardPattern)
+			case FileRedactEmail:
+				config.Redact.Patterns = append(config.Redact.Patterns, EmailPattern)
+			default:
+				re, err := regexp.Compile(pattern)
+				if err != nil {
+					return Config{}, fmt.Errorf("redact pattern: %w", err)
+				}
+				config.Redact.Patterns = append(config.Redact.Patterns, re)
+			}
+		}
+	}
+
+	if len(fc.Fields) > 0 {
+		keys := make([]string, 0, len(fc.Fields))
+		for key := range fc.Fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			config.Fields = append(config.Fields, Any(key, fc.Fields[key]))
+		}
+	}
+
+	for _, s := range fc.Sinks {
+		config.Sinks = append(config.Sinks, Sink{
+			Output: fileOutput(s.Output, s.Rotation),
+			Level:  s.Level,
+			Levels: s.Levels,
+			Format: s.Format,
+		})
+	}
+
+	return config, nil
+}
+
+// fileOutput returns the writer for an output of a config file: one of
+// the standard streams, or a file rotated as set by rotation.
+func fileOutput(output string, rotation *fileRotation) io.Writer {
+	switch output {
+	case "", FileOutputStdout:
+		return os.Stdout
+	case FileOutputStderr:
+		return os.Stderr
+	}
+
+	config := rotate.Config{Filename: output}
+	if rotation != nil {
+		config.MaxSize = rotation.MaxSize
+		config.Interval = time.Duration(rotation.Interval)
+		config.MaxAge = time.Duration(rotation.MaxAge)
+		config.MaxBackups = rotation.MaxBackups
+		config.Compress = rotation.Compress
+	}
+	return rotate.New(config)
+}
@@ -0,0 +1,219 @@
+package logger
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/barnowlsnest/go-logslib/pkg/rotate"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestConfigFromFile(t *testing.T) {
+	files := map[string]string{
+		"log.yaml": `
+level: warn
+levels: {db: debug}
+format: json
+output: stderr
+buffer_size: 4096
+buffer_shards: 4
+async_overflow: drop_oldest
+flush_interval: 2s
+add_caller: true
+sampling: {tick: 1s, initial: 100, thereafter: 10}
+rate_limit: {interval: 1m, limit: 5, key_field: route}
+dedup: {window: 30s}
+level_encoder: short
+keys: {level: lvl, message: msg}
+csv_columns: [level, msg, user_id]
+service: billing
+fields: {service: billing, replicas: 3}
+`,
+		"log.json": `{
+	"level": "warn",
+	"levels": {"db": "debug"},
+	"format": "json",
+	"output": "stderr",
+	"buffer_size": 4096,
+	"buffer_shards": 4,
+	"async_overflow": "drop_oldest",
+	"flush_interval": "2s",
+	"add_caller": true,
+	"sampling": {"tick": "1s", "initial": 100, "thereafter": 10},
+	"rate_limit": {"interval": "1m", "limit": 5, "key_field": "route"},
+	"dedup": {"window": "30s"},
+	"level_encoder": "short",
+	"keys": {"level": "lvl", "message": "msg"},
+	"csv_columns": ["level", "msg", "user_id"],
+	"service": "billing",
+	"fields": {"service": "billing", "replicas": 3}
+}`,
+		"log.toml": `
+level = "warn"
+format = "json"
+output = "stderr"
+buffer_size = 4096
+buffer_shards = 4
+async_overflow = "drop_oldest"
+flush_interval = "2s"
+add_caller = true
+level_encoder = "short"
+csv_columns = ["level", "msg", "user_id"]
+service = "billing"
+fields = {service = "billing", replicas = 3}
+
+[levels]
+db = "debug"
+
+[sampling]
+tick = "1s"
+initial = 100
+thereafter = 10
+
+[rate_limit]
+interval = "1m"
+limit = 5
+key_field = "route"
+
+[dedup]
+window = "30s"
+
+[keys]
+level = "lvl"
+message = "msg"
+`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			config, err := ConfigFromFile(writeConfigFile(t, name, content))
+			require.NoError(t, err)
+
+			assert.Equal(t, WarnLevel, config.Level)
+			assert.Equal(t, map[string]Level{"db": DebugLevel}, config.Levels)
+			assert.Equal(t, JSONFormat, config.Format)
+			assert.Same(t, os.Stderr, config.Output)
+			assert.Equal(t, 4096, config.BufferSize)
+			assert.Equal(t, 4, config.BufferShards)
+			assert.Equal(t, AsyncDropOldest, config.AsyncOverflow)
+			assert.Equal(t, 2*time.Second, config.FlushInterval)
+			assert.True(t, config.AddCaller)
+			assert.Equal(t, &SamplerConfig{Tick: time.Second, Initial: 100, Thereafter: 10}, config.Sampling)
+			assert.Equal(t, &RateLimitConfig{Interval: time.Minute, Limit: 5, KeyField: "route"}, config.RateLimit)
+			assert.Equal(t, &DedupConfig{Window: 30 * time.Second}, config.Dedup)
+			assert.Equal(t, ShortLevelEncoder, config.LevelEncoder)
+			assert.Equal(t, Keys{Level: "lvl", Message: "msg"}, config.Keys)
+			assert.Equal(t, []string{"level", "msg", "user_id"}, config.CSVColumns)
+			assert.Equal(t, "billing", config.Service)
+
+			require.Len(t, config.Fields, 2)
+			assert.Equal(t, "replicas", config.Fields[0].Key)
+			assert.Equal(t, "service", config.Fields[1].Key)
+			assert.Equal(t, "billing", config.Fields[1].Interface())
+		})
+	}
+}
+
+func TestConfigFromFile_Sinks(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfigFile(t, "log.yml", `
+sinks:
+  - {output: stdout, levels: [debug, info], format: text}
+  - {output: stderr, level: warn, format: console}
+  - output: `+filepath.Join(dir, "app.log")+`
+    format: json
+    rotation: {max_size: 1048576, interval: 24h, max_backups: 3, compress: true}
+`)
+
+	config, err := ConfigFromFile(path)
+	require.NoError(t, err)
+	require.Len(t, config.Sinks, 3)
+
+	assert.Same(t, os.Stdout, config.Sinks[0].Output)
+	assert.Equal(t, []Level{DebugLevel, InfoLevel}, config.Sinks[0].Levels)
+	assert.Same(t, os.Stderr, config.Sinks[1].Output)
+	assert.Equal(t, WarnLevel, config.Sinks[1].Level)
+	assert.Equal(t, ConsoleFormat, config.Sinks[1].Format)
+
+	file, ok := config.Sinks[2].Output.(*rotate.Writer)
+	require.True(t, ok)
+	defer file.Close()
+	assert.Equal(t, JSONFormat, config.Sinks[2].Format)
+
+	log := New(config)
+	log.Info("to file")
+	data, err := os.ReadFile(filepath.Join(dir, "app.log"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"message":"to file"`)
+}
+
+func TestConfigFromFile_Redact(t *testing.T) {
+	path := writeConfigFile(t, "log.yaml", `
+redact:
+  keys: [password]
+  patterns: [credit_card, email, "\\bSSN-\\d+"]
+  mode: hash
+  hash_keys: [user_id]
+  hash_secret: s3cr3t
+`)
+
+	config, err := ConfigFromFile(path)
+	require.NoError(t, err)
+	require.NotNil(t, config.Redact)
+	assert.Equal(t, []string{"password"}, config.Redact.Keys)
+	assert.Equal(t, RedactHash, config.Redact.Mode)
+	assert.Equal(t, []string{"user_id"}, config.Redact.HashKeys)
+	assert.Equal(t, []byte("s3cr3t"), config.Redact.HashSecret)
+	require.Len(t, config.Redact.Patterns, 3)
+	assert.Same(t, CreditCardPattern, config.Redact.Patterns[0])
+	assert.Same(t, EmailPattern, config.Redact.Patterns[1])
+	assert.True(t, config.Redact.Patterns[2].MatchString("id SSN-1234"))
+}
+
+func TestConfigFromFile_Errors(t *testing.T) {
+	tests := map[string]string{
+		"log.yaml": "level: loud\n",
+		"log.json": `{"format": "xml"}`,
+		"log.toml": "flush_interval = \"soon\"\n",
+		"log.yml":  "levle: info\n",
+		"a.json":   `{"levle": "info"}`,
+		"a.toml":   "levle = \"info\"\n",
+		"log.ini":  "level=info\n",
+		"r.yaml":   "redact: {mode: scramble}\n",
+		"p.yaml":   "redact: {patterns: [\"(\"]}\n",
+		"e.yaml":   "level_encoder: tiny\n",
+	}
+	for name, content := range tests {
+		_, err := ConfigFromFile(writeConfigFile(t, name, content))
+		assert.Error(t, err, name)
+	}
+
+	_, err := ConfigFromFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestConfig_Fields(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(Config{
+		Format:   JSONFormat,
+		Output:   buf,
+		OmitTime: true,
+		Fields:   []Field{String("service", "billing")},
+	})
+
+	logger.Named("db").With(Int("shard", 2)).Info("query")
+	assert.Equal(t, `{"level":"INFO","message":"query","logger":"db","service":"billing","shard":2}`+"\n", buf.String())
+}
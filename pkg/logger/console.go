@@ -0,0 +1,115 @@
+package logger
+
+import (
+	"io"
+	"os"
+)
+
+// ANSI escape sequences used by ConsoleFormat.
+const (
+	colorReset   = "\x1b[0m"
+	colorDim     = "\x1b[2m"
+	colorRed     = "\x1b[31m"
+	colorGreen   = "\x1b[32m"
+	colorYellow  = "\x1b[33m"
+	colorBlue    = "\x1b[34m"
+	colorMagenta = "\x1b[35m"
+
+	// EnvNoColor disables colored console output when set to any value.
+	// See https://no-color.org.
+	EnvNoColor = "NO_COLOR"
+
+	// levelWidth is the width levels are padded to in console output.
+	levelWidth = 5
+)
+
+// levelColor returns the escape sequence used for a level in console output.
+func levelColor(level Level) string {
+	switch level {
+	case DebugLevel:
+		return colorMagenta
+	case InfoLevel:
+		return colorBlue
+	case WarnLevel:
+		return colorYellow
+	case ErrorLevel, FatalLevel, PanicLevel:
+		return colorRed
+	default:
+		return colorGreen
+	}
+}
+
+// useColor reports whether console output to w should be colored:
+// w must be a terminal and NO_COLOR must not be set.
+func useColor(w io.Writer) bool {
+	if _, ok := os.LookupEnv(EnvNoColor); ok {
+		return false
+	}
+
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// consoleEncoder is the Encoder used for ConsoleFormat.
+type consoleEncoder struct {
+	encoderOptions
+	color bool
+}
+
+// EncodeEntry formats a log entry for reading in a terminal: a dimmed
+// timestamp, a padded and colored level, the message, and key=value fields.
+// Colors are only emitted when enc.color is set.
+func (enc consoleEncoder) EncodeEntry(buf []byte, e *Entry) []byte {
+	if !enc.omitTime {
+		if enc.color {
+			buf = append(buf, colorDim...)
+		}
+		buf = enc.appendTimestamp(buf, e.Time, false)
+		if enc.color {
+			buf = append(buf, colorReset...)
+		}
+		buf = append(buf, ' ')
+	}
+
+	level, _ := enc.levelText(e.Level)
+	if enc.color {
+		buf = append(buf, levelColor(e.Level)...)
+	}
+	buf = append(buf, level...)
+	if enc.color {
+		buf = append(buf, colorReset...)
+	}
+	for i := len(level); i < levelWidth; i++ {
+		buf = append(buf, ' ')
+	}
+	buf = append(buf, ' ')
+
+	if e.Caller != nil {
+		if enc.color {
+			buf = append(buf, colorDim...)
+		}
+		buf = appendCaller(buf, e.Caller)
+		if enc.color {
+			buf = append(buf, colorReset...)
+		}
+		buf = append(buf, ' ')
+	}
+
+	buf = append(buf, e.Message...)
+	buf = append(buf, e.Context...)
+
+	return appendTextFields(buf, e.Fields)
+}
+
+// EncodeFields appends fields as space-separated key=value pairs.
+func (enc consoleEncoder) EncodeFields(buf []byte, fields []Field) []byte {
+	return appendTextFields(buf, fields)
+}
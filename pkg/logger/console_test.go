@@ -0,0 +1,62 @@
+package logger
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger_ConsoleFormat(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  DebugLevel,
+		Format: ConsoleFormat,
+		Output: buf,
+	})
+
+	logger.Info("info message", String("k", "v"))
+	logger.Error("error message")
+
+	output := buf.String()
+	assert.Contains(t, output, " INFO  info message k=v\n")
+	assert.Contains(t, output, " ERROR error message\n")
+	assert.NotContains(t, output, "\x1b[")
+}
+
+func TestLogger_ConsoleFormatColor(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: ConsoleFormat,
+		Output: buf,
+	})
+	logger.core.encs[0] = consoleEncoder{color: true}
+
+	logger.Warn("warn message")
+
+	output := buf.String()
+	assert.Contains(t, output, colorDim)
+	assert.Contains(t, output, colorYellow+"WARN"+colorReset+"  warn message")
+}
+
+func TestUseColor(t *testing.T) {
+	assert.False(t, useColor(&bytes.Buffer{}))
+
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	assert.NoError(t, err)
+	defer f.Close()
+	assert.False(t, useColor(f))
+
+	t.Setenv(EnvNoColor, "1")
+	assert.False(t, useColor(os.Stdout))
+}
+
+func TestConfigFromEnv_ConsoleFormat(t *testing.T) {
+	t.Setenv(EnvLogFormat, "console")
+
+	assert.Equal(t, ConsoleFormat, ConfigFromEnv().Format)
+}
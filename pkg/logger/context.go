@@ -0,0 +1,96 @@
+package logger
+
+import "context"
+
+// loggerKey is the context key under which NewContext stores a Logger.
+type loggerKey struct{}
+
+// NewContext returns a copy of ctx carrying l. Retrieve it further down the
+// call stack with FromContext.
+//
+// Example:
+//
+//	func middleware(next http.Handler) http.Handler {
+//		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+//			l := log.With(logger.String("request_id", requestID(r)))
+//			next.ServeHTTP(w, r.WithContext(logger.NewContext(r.Context(), l)))
+//		})
+//	}
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the Logger stored in ctx by NewContext, or the
+// package default logger if ctx carries none.
+func FromContext(ctx context.Context) *Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(loggerKey{}).(*Logger); ok && l != nil {
+			return l
+		}
+	}
+	return Default()
+}
+
+// levelKey is the context key under which NewLevelContext stores a level.
+type levelKey struct{}
+
+// NewLevelContext returns a copy of ctx carrying a level that overrides
+// the level of loggers for ContextLoggers logging with it, so that a
+// single request can be logged at DebugLevel while the rest of the
+// service stays at InfoLevel.
+//
+// Example:
+//
+//	func debugMiddleware(next http.Handler) http.Handler {
+//		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+//			if r.Header.Get("X-Debug") != "" {
+//				r = r.WithContext(logger.NewLevelContext(r.Context(), logger.DebugLevel))
+//			}
+//			next.ServeHTTP(w, r)
+//		})
+//	}
+func NewLevelContext(ctx context.Context, level Level) context.Context {
+	return context.WithValue(ctx, levelKey{}, level)
+}
+
+// LevelFromContext returns the level stored in ctx by NewLevelContext, if
+// any.
+func LevelFromContext(ctx context.Context) (Level, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	level, ok := ctx.Value(levelKey{}).(Level)
+	return level, ok
+}
+
+// sampledKey is the context key under which NewSampledContext stores a
+// trace sampling flag.
+type sampledKey struct{}
+
+// NewSampledContext returns a copy of ctx carrying a flag that decides
+// whether ContextLoggers logging with it write their Debug entries,
+// whatever the level, in place of Config.TraceSampled. Use it with tracers
+// other than OpenTelemetry, or to propagate a sampling decision received
+// from a caller.
+//
+// Example:
+//
+//	func samplingMiddleware(next http.Handler) http.Handler {
+//		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+//			sampled := r.Header.Get("X-B3-Sampled") == "1"
+//			next.ServeHTTP(w, r.WithContext(logger.NewSampledContext(r.Context(), sampled)))
+//		})
+//	}
+func NewSampledContext(ctx context.Context, sampled bool) context.Context {
+	return context.WithValue(ctx, sampledKey{}, sampled)
+}
+
+// SampledFromContext returns the flag stored in ctx by NewSampledContext,
+// if any.
+func SampledFromContext(ctx context.Context) (sampled, ok bool) {
+	if ctx == nil {
+		return false, false
+	}
+	sampled, ok = ctx.Value(sampledKey{}).(bool)
+	return sampled, ok
+}
@@ -0,0 +1,123 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewContext_FromContext(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: TextFormat,
+		Output: buf,
+	})
+	child := logger.With(String("request_id", "abc"))
+
+	ctx := NewContext(context.Background(), child)
+	FromContext(ctx).Info("handled")
+
+	assert.Same(t, child, FromContext(ctx))
+	assert.Contains(t, buf.String(), "handled request_id=abc")
+}
+
+func TestFromContext_FallsBackToDefault(t *testing.T) {
+	assert.Same(t, Default(), FromContext(context.Background()))
+	var nilCtx context.Context
+	assert.Same(t, Default(), FromContext(nilCtx))
+	assert.Same(t, Default(), FromContext(NewContext(context.Background(), nil)))
+}
+
+func TestNewLevelContext(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(Config{
+		Level:             InfoLevel,
+		Format:            TextFormat,
+		Output:            buf,
+		OmitTime:          true,
+		ContextExtractors: []func(context.Context) []Field{ContextValue(tenantKey{}, "tenant")},
+	})
+
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	logger.WithStaticContext(ctx).Debug("hidden")
+	assert.Empty(t, buf.String())
+
+	debugCtx := NewLevelContext(ctx, DebugLevel)
+	logger.WithStaticContext(debugCtx).Debug("shown")
+	assert.Equal(t, "DEBUG shown tenant=acme\n", buf.String())
+
+	buf.Reset()
+	logger.Debug("hidden")
+	logger.WithStaticContext(NewLevelContext(ctx, ErrorLevel)).Warn("hidden")
+	assert.Empty(t, buf.String())
+
+	level, ok := LevelFromContext(debugCtx)
+	assert.True(t, ok)
+	assert.Equal(t, DebugLevel, level)
+	_, ok = LevelFromContext(ctx)
+	assert.False(t, ok)
+}
+
+type tenantKey struct{}
+
+type sampledKeyForTest struct{}
+
+func TestConfig_TraceSampled(t *testing.T) {
+	buf := &bytes.Buffer{}
+	var calls int
+	logger := New(Config{
+		Level:    InfoLevel,
+		Format:   TextFormat,
+		Output:   buf,
+		OmitTime: true,
+		TraceSampled: func(ctx context.Context) bool {
+			calls++
+			return ctx.Value(sampledKeyForTest{}) == true
+		},
+	})
+	sampled := context.WithValue(context.Background(), sampledKeyForTest{}, true)
+	unsampled := context.Background()
+
+	logger.WithContext(func() context.Context { return sampled }).Debug("sampled")
+	logger.WithContext(func() context.Context { return unsampled }).Debug("hidden")
+	logger.WithStaticContext(sampled).Debug("static sampled")
+	logger.WithContext(func() context.Context { return unsampled }).Info("info")
+	assert.Equal(t, "DEBUG sampled\nDEBUG static sampled\nINFO info\n", buf.String())
+	assert.Equal(t, 3, calls, "only consulted for Debug entries")
+
+	buf.Reset()
+	logger.SetLevel(DebugLevel)
+	logger.WithContext(func() context.Context { return unsampled }).Debug("hidden despite the level")
+	logger.Debug("logger entries are not affected")
+	assert.Equal(t, "DEBUG logger entries are not affected\n", buf.String())
+
+	buf.Reset()
+	logger.SetLevel(WarnLevel)
+	logger.WithStaticContext(sampled).Info("hidden")
+	logger.WithStaticContext(NewSampledContext(sampled, false)).Debug("hidden by the flag")
+	logger.WithStaticContext(NewLevelContext(unsampled, DebugLevel)).Debug("level wins")
+	assert.Equal(t, "DEBUG level wins\n", buf.String())
+}
+
+func TestNewSampledContext(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(Config{Level: InfoLevel, Format: TextFormat, Output: buf, OmitTime: true})
+
+	logger.WithStaticContext(NewSampledContext(context.Background(), true)).Debug("shown")
+	logger.WithStaticContext(NewSampledContext(context.Background(), false)).Debug("hidden")
+	logger.WithStaticContext(context.Background()).Debug("hidden")
+	assert.Equal(t, "DEBUG shown\n", buf.String())
+
+	sampled, ok := SampledFromContext(NewSampledContext(context.Background(), true))
+	assert.True(t, ok)
+	assert.True(t, sampled)
+	_, ok = SampledFromContext(context.Background())
+	assert.False(t, ok)
+	var nilCtx context.Context
+	_, ok = SampledFromContext(nilCtx)
+	assert.False(t, ok)
+}
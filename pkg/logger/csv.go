@@ -0,0 +1,214 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// csvColumns are the columns of CSVFormat entries. encoderOptions holds
+// them by pointer to stay comparable.
+type csvColumns struct {
+	names []string
+}
+
+// csvEncoder is the Encoder used for CSVFormat.
+type csvEncoder struct {
+	encoderOptions
+}
+
+// EncodeEntry formats a log entry as one CSV record with a value for each
+// column: the timestamp, level, message and caller for the columns named
+// as their JSON keys, and the value of the field with the column's key
+// otherwise, or an empty value. Values holding commas, quotes, line breaks
+// or surrounding spaces are quoted as RFC 4180 requires.
+func (enc csvEncoder) EncodeEntry(buf []byte, e *Entry) []byte {
+	for i, name := range enc.columns() {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		start := len(buf)
+		switch name {
+		case orDefault(enc.keys.Time, DefaultTimeKey):
+			if !enc.omitTime {
+				buf = enc.appendTimestamp(buf, e.Time, false)
+			}
+		case orDefault(enc.keys.Level, DefaultLevelKey):
+			level, _ := enc.levelText(e.Level)
+			buf = append(buf, level...)
+		case orDefault(enc.keys.Message, DefaultMessageKey):
+			buf = append(buf, e.Message...)
+		case orDefault(enc.keys.Caller, DefaultCallerKey):
+			if e.Caller != nil {
+				buf = appendCaller(buf, e.Caller)
+			}
+		default:
+			buf = appendCSVColumn(buf, name, e)
+		}
+		buf = quoteCSV(buf, start)
+	}
+	return buf
+}
+
+// EncodeFields appends each field as its key and its unquoted CSV value,
+// both preceded by their length, for EncodeEntry to look up in
+// Entry.Context.
+func (csvEncoder) EncodeFields(buf []byte, fields []Field) []byte {
+	for i := range fields {
+		buf = binary.AppendUvarint(buf, uint64(len(fields[i].Key)))
+		buf = append(buf, fields[i].Key...)
+
+		// The value is appended after room for its length, then moved
+		// once the length is known.
+		var size [binary.MaxVarintLen64]byte
+		start := len(buf)
+		buf = append(buf, size[:]...)
+		buf = appendCSVValue(buf, fields[i])
+		n := binary.PutUvarint(size[:], uint64(len(buf)-start-len(size)))
+		copy(buf[start:], size[:n])
+		buf = append(buf[:start+n], buf[start+len(size):]...)
+	}
+	return buf
+}
+
+// columns returns the column names of enc.
+func (enc csvEncoder) columns() []string {
+	if enc.csvColumns == nil {
+		return csvColumnNames(nil, enc.keys)
+	}
+	return enc.csvColumns.names
+}
+
+// csvColumnNames returns a copy of columns, or the timestamp, level and
+// message columns named by keys if columns is empty.
+func csvColumnNames(columns []string, keys Keys) []string {
+	if len(columns) == 0 {
+		return []string{
+			orDefault(keys.Time, DefaultTimeKey),
+			orDefault(keys.Level, DefaultLevelKey),
+			orDefault(keys.Message, DefaultMessageKey),
+		}
+	}
+	return append([]string(nil), columns...)
+}
+
+// appendCSVColumn appends the value of the field named key, the last one
+// of e.Fields or else of e.Context, or nothing if e has none.
+func appendCSVColumn(buf []byte, key string, e *Entry) []byte {
+	for i := len(e.Fields) - 1; i >= 0; i-- {
+		if e.Fields[i].Key == key {
+			return appendCSVValue(buf, e.Fields[i])
+		}
+	}
+
+	var value []byte
+	found := false
+	for ctx := e.Context; len(ctx) > 0; {
+		k, rest, ok := nextCSVContext(ctx)
+		if !ok {
+			break
+		}
+		v, rest, ok := nextCSVContext(rest)
+		if !ok {
+			break
+		}
+		if string(k) == key {
+			value, found = v, true
+		}
+		ctx = rest
+	}
+	if found {
+		buf = append(buf, value...)
+	}
+	return buf
+}
+
+// nextCSVContext splits the length-prefixed value at the start of ctx from
+// the rest.
+func nextCSVContext(ctx []byte) ([]byte, []byte, bool) {
+	size, n := binary.Uvarint(ctx)
+	if n <= 0 || uint64(len(ctx)-n) < size {
+		return nil, nil, false
+	}
+	return ctx[n : n+int(size)], ctx[n+int(size):], true
+}
+
+// appendCSVValue appends the value of f, unquoted.
+func appendCSVValue(buf []byte, f Field) []byte {
+	switch f.kind {
+	case stringKind:
+		return append(buf, f.str...)
+	case errorKind:
+		if f.Value == nil {
+			return buf
+		}
+		return append(buf, f.Value.(error).Error()...)
+	case stringerKind:
+		return append(buf, stringerText(f.Value.(fmt.Stringer))...)
+	case lazyKind:
+		return appendCSVValue(buf, f.resolve())
+	case reflectKind:
+		if s, ok := f.Value.(string); ok {
+			return append(buf, s...)
+		}
+	}
+	return appendFieldValue(buf, f)
+}
+
+// quoteCSV quotes the value written from start in buf, in place, if it
+// holds a comma, a double quote, a line break or surrounding spaces.
+// Double quotes in the value are doubled.
+func quoteCSV(buf []byte, start int) []byte {
+	value := buf[start:]
+	if len(value) == 0 || (bytes.IndexAny(value, ",\"\r\n") < 0 &&
+		value[0] != ' ' && value[len(value)-1] != ' ') {
+		return buf
+	}
+
+	quotes := bytes.Count(value, []byte{'"'})
+	end := len(buf)
+	for i := 0; i < quotes+2; i++ {
+		buf = append(buf, 0)
+	}
+
+	// Bytes are moved from the end so that none is overwritten before it
+	// is read.
+	j := len(buf) - 1
+	buf[j] = '"'
+	for i := end - 1; i >= start; i-- {
+		j--
+		buf[j] = buf[i]
+		if buf[i] == '"' {
+			j--
+			buf[j] = '"'
+		}
+	}
+	buf[start] = '"'
+	return buf
+}
+
+// orDefault returns key, or def if key is empty.
+func orDefault(key, def string) string {
+	if key == "" {
+		return def
+	}
+	return key
+}
+
+// WriteCSVHeader writes the header record naming the columns of CSVFormat
+// entries written by a Logger created with config. Write it once when
+// creating the file the logger writes to.
+func WriteCSVHeader(w io.Writer, config Config) error {
+	var buf []byte
+	for i, name := range newEncoderOptions(&config).csvColumns.names {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		start := len(buf)
+		buf = append(buf, name...)
+		buf = quoteCSV(buf, start)
+	}
+	_, err := w.Write(append(buf, '\n'))
+	return err
+}
@@ -0,0 +1,104 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSVFormat(t *testing.T) {
+	buf := &bytes.Buffer{}
+	ts := time.Date(2024, 1, 20, 15, 4, 5, 0, time.UTC)
+
+	config := Config{
+		Level:      InfoLevel,
+		Format:     CSVFormat,
+		Output:     buf,
+		Clock:      func() time.Time { return ts },
+		UseUTC:     true,
+		CSVColumns: []string{"timestamp", "level", "message", "user", "status", "error", "missing"},
+	}
+	log := New(config).With(String("user", "alice"), Int("status", 100))
+
+	log.Info("plain", Int("status", 200))
+	log.Warn(`said "hi", twice`, String("user", " bob"), Err(errors.New("line1\nline2")))
+	log.With(String("user", "carol")).Info("bound")
+
+	assert.Equal(t, "2024-01-20T15:04:05.000Z,INFO,plain,alice,200,,\n"+
+		"2024-01-20T15:04:05.000Z,WARN,\"said \"\"hi\"\", twice\",\" bob\",100,\"line1\nline2\",\n"+
+		"2024-01-20T15:04:05.000Z,INFO,bound,carol,100,,\n", buf.String())
+}
+
+func TestCSVFormat_Defaults(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := New(Config{
+		Level:        InfoLevel,
+		Format:       CSVFormat,
+		Output:       buf,
+		OmitTime:     true,
+		LevelEncoder: LowercaseLevelEncoder,
+		Keys:         Keys{Message: "msg", Caller: "src"},
+	})
+
+	log.Info("started", Int("port", 8080))
+	assert.Equal(t, ",info,started\n", buf.String())
+
+	format, err := ParseFormat("CSV")
+	require.NoError(t, err)
+	assert.Equal(t, CSVFormat, format)
+}
+
+func TestCSVFormat_Caller(t *testing.T) {
+	e := &Entry{Level: ErrorLevel, Message: "m", Caller: &Caller{File: "app/main.go", Line: 42}}
+	enc := csvEncoder{encoderOptions{omitTime: true, csvColumns: &csvColumns{names: []string{"caller", "message"}}}}
+
+	assert.Equal(t, "app/main.go:42,m", string(enc.EncodeEntry(nil, e)))
+}
+
+func TestWriteCSVHeader(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, WriteCSVHeader(buf, Config{Keys: Keys{Time: "ts"}}))
+	assert.Equal(t, "ts,level,message\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, WriteCSVHeader(buf, Config{CSVColumns: []string{"message", "a,b"}}))
+	assert.Equal(t, "message,\"a,b\"\n", buf.String())
+}
+
+func TestQuoteCSV(t *testing.T) {
+	tests := map[string]string{
+		"":           "",
+		"plain":      "plain",
+		"a,b":        `"a,b"`,
+		`"`:          `""""`,
+		`x "y" z`:    `"x ""y"" z"`,
+		"a\r\nb":     "\"a\r\nb\"",
+		" padded":    `" padded"`,
+		"padded ":    `"padded "`,
+		"in side":    "in side",
+		`end quote"`: `"end quote"""`,
+	}
+	for value, want := range tests {
+		buf := quoteCSV([]byte("prefix,"+value), len("prefix,"))
+		assert.Equal(t, "prefix,"+want, string(buf), value)
+	}
+}
+
+func TestCSVFormat_NoAllocations(t *testing.T) {
+	log := New(Config{
+		Level:      InfoLevel,
+		Format:     CSVFormat,
+		Output:     io.Discard,
+		CSVColumns: []string{"timestamp", "message", "user", "status"},
+	}).With(String("user", "alice"))
+
+	allocs := testing.AllocsPerRun(100, func() {
+		log.Info("request, served", Int("status", 200))
+	})
+	assert.Zero(t, allocs)
+}
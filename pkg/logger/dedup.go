@@ -0,0 +1,169 @@
+package logger
+
+import (
+	"bytes"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultDedupWindow is the window used when DedupConfig.Window is zero.
+	DefaultDedupWindow = 10 * time.Second
+
+	// RepeatCountKey is the key of the field carrying the number of
+	// repeats collapsed by deduplication.
+	RepeatCountKey = "repeat_count"
+)
+
+// DedupConfig configures the collapsing of identical consecutive entries,
+// like syslog's "last message repeated N times", to tame retry loops.
+// Entries are identical when they share the logger, level, message and
+// field values; the time and caller are ignored.
+//
+// The first entry of a run is written as usual and its repeats are
+// counted instead. When the run ends, because a different entry is logged,
+// the Window elapses or the logger is closed, the entry is written once
+// more with a repeat_count=<repeats> field.
+type DedupConfig struct {
+	// Window is the longest time repeats are collapsed into one entry.
+	// Defaults to DefaultDedupWindow.
+	Window time.Duration
+}
+
+// deduper tracks the current run of identical entries.
+type deduper struct {
+	window time.Duration
+
+	mu     sync.Mutex
+	key    []byte
+	logger *Logger
+	level  Level
+	msg    string
+	fields []Field
+	start  time.Time
+	count  int
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// dedupRun is the summary of a run of repeats, written by write.
+type dedupRun struct {
+	logger *Logger
+	level  Level
+	msg    string
+	fields []Field
+	count  int
+}
+
+func newDeduper(config *DedupConfig) *deduper {
+	window := config.Window
+	if window <= 0 {
+		window = DefaultDedupWindow
+	}
+
+	return &deduper{
+		window: window,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// check reports whether an entry of l should be written, or is a repeat.
+// If the entry ends a run of repeats, the summary of the run is written
+// first.
+func (d *deduper) check(l *Logger, level Level, msg string, fields []Field, now time.Time) bool {
+	var tmp [256]byte
+	key := dedupKey(tmp[:0], l, level, msg, fields)
+
+	d.mu.Lock()
+	if d.logger != nil && bytes.Equal(key, d.key) && now.Sub(d.start) < d.window {
+		d.count++
+		d.mu.Unlock()
+		return false
+	}
+
+	run := d.end()
+	d.key = append(d.key[:0], key...)
+	d.logger, d.level, d.msg = l, level, msg
+	d.fields = append([]Field(nil), fields...)
+	d.start = now
+	d.mu.Unlock()
+
+	run.write()
+	return true
+}
+
+// end ends the current run and returns its summary. d.mu must be held.
+func (d *deduper) end() dedupRun {
+	run := dedupRun{logger: d.logger, level: d.level, msg: d.msg, fields: d.fields, count: d.count}
+	d.logger, d.msg, d.fields, d.count = nil, "", nil, 0
+	return run
+}
+
+// write writes the summary of a run that had repeats.
+func (r dedupRun) write() {
+	if r.count == 0 {
+		return
+	}
+	r.logger.emit(&Entry{
+		Time:    r.logger.now(),
+		Level:   r.level,
+		Message: r.msg,
+		Fields:  append(r.fields, Int(RepeatCountKey, r.count)),
+	})
+}
+
+// dedupKey appends what identifies an entry of l to buf.
+func dedupKey(buf []byte, l *Logger, level Level, msg string, fields []Field) []byte {
+	buf = append(buf, byte(level))
+	for _, context := range l.contexts {
+		buf = append(buf, context...)
+		buf = append(buf, 0)
+	}
+	buf = append(buf, msg...)
+	for i := range fields {
+		buf = append(buf, 0)
+		buf = append(buf, fields[i].Key...)
+		buf = append(buf, 0, byte(fields[i].kind))
+		buf = appendFieldValue(buf, fields[i])
+	}
+	return buf
+}
+
+// run writes the summaries of runs whose window has elapsed until close
+// is called.
+func (d *deduper) run() {
+	defer close(d.done)
+
+	ticker := time.NewTicker(d.window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.mu.Lock()
+			var run dedupRun
+			if d.logger != nil && d.logger.now().Sub(d.start) >= d.window {
+				run = d.end()
+			}
+			d.mu.Unlock()
+			run.write()
+		case <-d.stop:
+			d.mu.Lock()
+			run := d.end()
+			d.mu.Unlock()
+			run.write()
+			return
+		}
+	}
+}
+
+// close stops the background goroutine after writing the pending summary.
+func (d *deduper) close() {
+	d.once.Do(func() {
+		close(d.stop)
+	})
+	<-d.done
+}
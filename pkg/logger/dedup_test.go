@@ -0,0 +1,62 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Dedup(t *testing.T) {
+	buf := &syncBuffer{}
+	logger := New(Config{
+		Format:   JSONFormat,
+		Output:   buf,
+		OmitTime: true,
+		Dedup:    &DedupConfig{Window: time.Hour},
+	})
+
+	for i := 0; i < 5; i++ {
+		logger.Error("retrying", String("host", "db1"))
+	}
+	logger.Error("retrying", String("host", "db2"))
+	logger.With(String("k", "v")).Error("retrying", String("host", "db2"))
+	logger.With(String("k", "v")).Error("retrying", String("host", "db2"))
+	require.NoError(t, logger.Close())
+
+	assert.Equal(t, strings.Join([]string{
+		`{"level":"ERROR","message":"retrying","host":"db1"}`,
+		`{"level":"ERROR","message":"retrying","host":"db1","repeat_count":4}`,
+		`{"level":"ERROR","message":"retrying","host":"db2"}`,
+		`{"level":"ERROR","message":"retrying","k":"v","host":"db2"}`,
+		`{"level":"ERROR","message":"retrying","k":"v","host":"db2","repeat_count":1}`,
+	}, "\n")+"\n", buf.String())
+}
+
+func TestConfig_DedupWindow(t *testing.T) {
+	buf := &syncBuffer{}
+	logger := New(Config{
+		Format:   JSONFormat,
+		Output:   buf,
+		OmitTime: true,
+		Dedup:    &DedupConfig{Window: 20 * time.Millisecond},
+	})
+	defer logger.Close()
+
+	logger.Warn("retrying")
+	logger.Warn("retrying")
+	logger.Warn("retrying")
+
+	assert.Eventually(t, func() bool {
+		return strings.Contains(buf.String(), `"repeat_count":2`)
+	}, 5*time.Second, 5*time.Millisecond)
+
+	logger.Warn("retrying")
+	assert.Equal(t, strings.Join([]string{
+		`{"level":"WARN","message":"retrying"}`,
+		`{"level":"WARN","message":"retrying","repeat_count":2}`,
+		`{"level":"WARN","message":"retrying"}`,
+	}, "\n")+"\n", buf.String())
+}
@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	defaultLogger     atomic.Pointer[Logger]
+	defaultLoggerOnce sync.Once
+)
+
+// Default returns the package default logger. Unless replaced with
+// SetDefault, it writes text entries at InfoLevel to os.Stdout.
+func Default() *Logger {
+	defaultLoggerOnce.Do(func() {
+		if defaultLogger.Load() == nil {
+			defaultLogger.CompareAndSwap(nil, New(Config{Level: InfoLevel, Format: TextFormat}))
+		}
+	})
+	return defaultLogger.Load()
+}
+
+// SetDefault replaces the package default logger returned by Default and
+// FromContext. A nil l is ignored.
+func SetDefault(l *Logger) {
+	if l != nil {
+		defaultLogger.Store(l)
+	}
+}
+
+// Debug logs a message at DebugLevel on the default logger.
+func Debug(msg string, fields ...Field) {
+	Default().log(DebugLevel, msg, fields...)
+}
+
+// Info logs a message at InfoLevel on the default logger.
+func Info(msg string, fields ...Field) {
+	Default().log(InfoLevel, msg, fields...)
+}
+
+// Warn logs a message at WarnLevel on the default logger.
+func Warn(msg string, fields ...Field) {
+	Default().log(WarnLevel, msg, fields...)
+}
+
+// Error logs a message at ErrorLevel on the default logger.
+func Error(msg string, fields ...Field) {
+	Default().log(ErrorLevel, msg, fields...)
+}
+
+// Fatal logs a message at FatalLevel on the default logger, then exits
+// like Logger.Fatal.
+func Fatal(msg string, fields ...Field) {
+	l := Default()
+	l.log(FatalLevel, msg, fields...)
+	l.exit()
+}
+
+// Panic logs a message at PanicLevel on the default logger, flushes it,
+// then panics with the message.
+func Panic(msg string, fields ...Field) {
+	l := Default()
+	l.log(PanicLevel, msg, fields...)
+	l.Flush()
+	panic(msg)
+}
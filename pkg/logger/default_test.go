@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetDefault(t *testing.T) {
+	previous := Default()
+	defer SetDefault(previous)
+
+	buf := &bytes.Buffer{}
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: TextFormat,
+		Output: buf,
+	})
+
+	SetDefault(logger)
+	require.Same(t, logger, Default())
+
+	FromContext(context.Background()).Info("via default")
+	assert.Contains(t, buf.String(), "via default")
+
+	SetDefault(nil)
+	assert.Same(t, logger, Default())
+}
+
+func TestGlobalFunctions(t *testing.T) {
+	previous := Default()
+	defer SetDefault(previous)
+
+	buf := &bytes.Buffer{}
+	SetDefault(New(Config{
+		Level:     DebugLevel,
+		Format:    TextFormat,
+		Output:    buf,
+		AddCaller: true,
+	}))
+
+	Debug("debug message")
+	Info("info message", String("k", "v"))
+	Warn("warn message")
+	Error("error message")
+
+	output := buf.String()
+	assert.Regexp(t, regexp.MustCompile(`DEBUG logger/default_test.go:\d+ debug message`), output)
+	assert.Regexp(t, regexp.MustCompile(`INFO logger/default_test.go:\d+ info message k=v`), output)
+	assert.Regexp(t, regexp.MustCompile(`WARN logger/default_test.go:\d+ warn message`), output)
+	assert.Regexp(t, regexp.MustCompile(`ERROR logger/default_test.go:\d+ error message`), output)
+}
+
+func TestGlobalPanic(t *testing.T) {
+	previous := Default()
+	defer SetDefault(previous)
+
+	buf := &bytes.Buffer{}
+	SetDefault(New(Config{Level: InfoLevel, Format: TextFormat, Output: buf}))
+
+	assert.PanicsWithValue(t, "boom", func() { Panic("boom") })
+	assert.Contains(t, buf.String(), "PANIC boom")
+}
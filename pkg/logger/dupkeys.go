@@ -0,0 +1,155 @@
+package logger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DuplicateKeys selects what the logger does with a field whose key is
+// already used by a field bound with With or Config.Fields, or by an
+// earlier field of the same call, as strict JSON parsers reject documents
+// with duplicate keys. The name of Named loggers and the fields added by
+// the logger itself, such as the stack trace or TruncatedKey, are not
+// checked.
+type DuplicateKeys int8
+
+const (
+	// AllowDuplicateKeys writes every field, duplicates included. It is
+	// the default and costs nothing.
+	AllowDuplicateKeys DuplicateKeys = iota
+
+	// KeepFirstDuplicateKeys drops fields whose key is already used.
+	KeepFirstDuplicateKeys
+
+	// KeepLastDuplicateKeys drops the fields whose key is used again
+	// later, so that the fields of a call override the bound ones and
+	// later With calls override earlier ones. Overriding a bound field
+	// re-encodes the bound fields for that entry.
+	KeepLastDuplicateKeys
+
+	// SuffixDuplicateKeys renames fields whose key is already used by
+	// appending "_1", "_2", ..., keeping every value.
+	SuffixDuplicateKeys
+)
+
+// String returns the name of the policy, as read by ParseDuplicateKeys.
+func (d DuplicateKeys) String() string {
+	switch d {
+	case AllowDuplicateKeys:
+		return "allow"
+	case KeepFirstDuplicateKeys:
+		return "first"
+	case KeepLastDuplicateKeys:
+		return "last"
+	case SuffixDuplicateKeys:
+		return "suffix"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler, with the name of the
+// policy.
+func (d DuplicateKeys) MarshalText() ([]byte, error) {
+	if d < AllowDuplicateKeys || d > SuffixDuplicateKeys {
+		return nil, fmt.Errorf("logger: unknown duplicate keys policy %d", d)
+	}
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, parsing the policy
+// with ParseDuplicateKeys.
+func (d *DuplicateKeys) UnmarshalText(text []byte) error {
+	v, err := ParseDuplicateKeys(string(text))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// ParseDuplicateKeys returns the policy named s, one of "allow", "first",
+// "last" and "suffix" in any case.
+func ParseDuplicateKeys(s string) (DuplicateKeys, error) {
+	for d := AllowDuplicateKeys; d <= SuffixDuplicateKeys; d++ {
+		if strings.EqualFold(strings.TrimSpace(s), d.String()) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("logger: unknown duplicate keys policy %q", s)
+}
+
+// dedupe applies d to fields, which follow the bound fields bound. It
+// returns bound, without the fields KeepLastDuplicateKeys overrides,
+// fields, without or with renamed duplicates, and whether bound changed.
+// Neither is copied when there are no duplicates.
+func (d DuplicateKeys) dedupe(bound, fields []Field) ([]Field, []Field, bool) {
+	if d == AllowDuplicateKeys || !hasDuplicateKeys(bound, fields) {
+		return bound, fields, false
+	}
+
+	out := make([]Field, 0, len(fields))
+	switch d {
+	case KeepFirstDuplicateKeys:
+		for i := range fields {
+			if !usesKey(bound, fields[i].Key) && !usesKey(fields[:i], fields[i].Key) {
+				out = append(out, fields[i])
+			}
+		}
+	case SuffixDuplicateKeys:
+		for _, f := range fields {
+			if usesKey(bound, f.Key) || usesKey(out, f.Key) {
+				base := f.Key
+				for n := 1; usesKey(bound, f.Key) || usesKey(out, f.Key); n++ {
+					f.Key = base + "_" + strconv.Itoa(n)
+				}
+			}
+			out = append(out, f)
+		}
+	case KeepLastDuplicateKeys:
+		for i := range fields {
+			if !usesKey(fields[i+1:], fields[i].Key) {
+				out = append(out, fields[i])
+			}
+		}
+		changed := false
+		for i := range bound {
+			if usesKey(out, bound[i].Key) {
+				changed = true
+				break
+			}
+		}
+		if changed {
+			kept := make([]Field, 0, len(bound))
+			for i := range bound {
+				if !usesKey(out, bound[i].Key) {
+					kept = append(kept, bound[i])
+				}
+			}
+			return kept, out, true
+		}
+	}
+	return bound, out, false
+}
+
+// hasDuplicateKeys reports whether a field of fields has the key of a
+// field of bound or of an earlier field of fields.
+func hasDuplicateKeys(bound, fields []Field) bool {
+	for i := range fields {
+		if usesKey(bound, fields[i].Key) || usesKey(fields[:i], fields[i].Key) {
+			return true
+		}
+	}
+	return false
+}
+
+// usesKey reports whether a field of fields has the key key.
+func usesKey(fields []Field, key string) bool {
+	for i := range fields {
+		if fields[i].Key == key {
+			return true
+		}
+	}
+	return false
+}
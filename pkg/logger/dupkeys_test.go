@@ -0,0 +1,104 @@
+package logger
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDuplicateKeys(t *testing.T) {
+	tests := []struct {
+		policy DuplicateKeys
+		want   []string
+	}{
+		{AllowDuplicateKeys, []string{
+			`{"level":"INFO","message":"m","user":"alice","user":"bob","user":"carol","id":1,"id":2}`,
+			`{"level":"INFO","message":"m","logger":"db","user":"alice","user":"bob","user":"dan"}`,
+		}},
+		{KeepFirstDuplicateKeys, []string{
+			`{"level":"INFO","message":"m","user":"alice","id":1}`,
+			`{"level":"INFO","message":"m","logger":"db","user":"alice"}`,
+		}},
+		{KeepLastDuplicateKeys, []string{
+			`{"level":"INFO","message":"m","user":"carol","id":2}`,
+			`{"level":"INFO","message":"m","logger":"db","user":"dan"}`,
+		}},
+		{SuffixDuplicateKeys, []string{
+			`{"level":"INFO","message":"m","user":"alice","user_1":"bob","user_2":"carol","id":1,"id_1":2}`,
+			`{"level":"INFO","message":"m","logger":"db","user":"alice","user_1":"bob","user_2":"dan"}`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			log := New(Config{
+				Level:         InfoLevel,
+				Format:        JSONFormat,
+				Output:        buf,
+				OmitTime:      true,
+				DuplicateKeys: tt.policy,
+			})
+
+			bound := log.With(String("user", "alice")).With(String("user", "bob"))
+			bound.Info("m", String("user", "carol"), Int("id", 1), Int("id", 2))
+			bound.Named("db").Info("m", String("user", "dan"))
+
+			lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+			require.Len(t, lines, 2)
+			assert.Equal(t, tt.want[0], string(lines[0]))
+			assert.Equal(t, tt.want[1], string(lines[1]))
+		})
+	}
+}
+
+func TestDuplicateKeys_Fields(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := New(Config{
+		Level:         InfoLevel,
+		Format:        JSONFormat,
+		Output:        buf,
+		OmitTime:      true,
+		Service:       "api",
+		Fields:        []Field{String("service", "billing")},
+		DuplicateKeys: KeepFirstDuplicateKeys,
+	})
+
+	log.Info("m", String("service", "other"))
+	assert.Equal(t, `{"level":"INFO","message":"m","service":"api"}`+"\n", buf.String())
+}
+
+func TestDuplicateKeys_KeepLastNamed(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := New(Config{Level: InfoLevel, Format: JSONFormat, Output: buf, OmitTime: true, DuplicateKeys: KeepLastDuplicateKeys})
+
+	log.Named("db").With(String("user", "alice"), String("table", "users")).Info("m", String("user", "bob"))
+	assert.Equal(t, `{"level":"INFO","message":"m","logger":"db","table":"users","user":"bob"}`+"\n", buf.String())
+}
+
+func TestDuplicateKeys_Text(t *testing.T) {
+	for _, d := range []DuplicateKeys{AllowDuplicateKeys, KeepFirstDuplicateKeys, KeepLastDuplicateKeys, SuffixDuplicateKeys} {
+		text, err := d.MarshalText()
+		require.NoError(t, err)
+		var parsed DuplicateKeys
+		require.NoError(t, parsed.UnmarshalText(text))
+		assert.Equal(t, d, parsed)
+	}
+
+	_, err := ParseDuplicateKeys("merge")
+	assert.Error(t, err)
+	_, err = DuplicateKeys(9).MarshalText()
+	assert.Error(t, err)
+}
+
+func TestDuplicateKeys_NoAllocations(t *testing.T) {
+	log := New(Config{Level: InfoLevel, Format: JSONFormat, Output: io.Discard, DuplicateKeys: KeepLastDuplicateKeys}).
+		With(String("user", "alice"))
+
+	allocs := testing.AllocsPerRun(100, func() {
+		log.Info("request", Int("status", 200))
+	})
+	assert.Zero(t, allocs)
+}
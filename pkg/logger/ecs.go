@@ -0,0 +1,126 @@
+package logger
+
+import "reflect"
+
+// ECSVersion is the version of Elastic Common Schema that ECSFormat
+// entries declare in "ecs.version".
+const ECSVersion = "8.11.0"
+
+// ecsFieldKeys maps field keys to the ECS fields they are written as.
+var ecsFieldKeys = map[string]string{
+	"trace_id":       "trace.id",
+	"span_id":        "span.id",
+	"transaction_id": "transaction.id",
+}
+
+// ecsEncoder is the Encoder used for ECSFormat.
+type ecsEncoder struct {
+	encoderOptions
+}
+
+// EncodeEntry formats a log entry as an ECS JSON document: "@timestamp",
+// "log.level", "message" and "ecs.version", then the caller as
+// "log.origin.*" (when known) and the fields. Config.TimeFormat, TimeKey
+// and OmitTime do not apply, as ECS requires an ISO 8601 "@timestamp".
+func (enc ecsEncoder) EncodeEntry(buf []byte, e *Entry) []byte {
+	start := len(buf)
+
+	buf = append(buf, `{"@timestamp":"`...)
+	buf = appendTime(buf, e.Time)
+	buf = append(buf, `","log.level":"`...)
+	buf = append(buf, ecsLevel(e.Level)...)
+	buf = append(buf, `","message":"`...)
+	buf = appendJSONString(buf, e.Message)
+	buf = append(buf, `","ecs.version":"`...)
+	buf = append(buf, ECSVersion...)
+	buf = append(buf, '"')
+
+	if e.Caller != nil {
+		buf = append(buf, `,"log.origin.file.name":"`...)
+		buf = appendJSONString(buf, e.Caller.File)
+		buf = append(buf, `","log.origin.file.line":`...)
+		buf = appendInt(buf, int64(e.Caller.Line))
+		buf = append(buf, `,"log.origin.function":"`...)
+		buf = appendJSONString(buf, e.Caller.Function)
+		buf = append(buf, '"')
+	}
+
+	buf = append(buf, e.Context...)
+	buf = enc.EncodeFields(buf, e.Fields)
+
+	buf = append(buf, '}')
+	if enc.escapeHTML {
+		buf = appendHTMLEscaped(buf, start)
+	}
+	return buf
+}
+
+// EncodeFields appends fields as comma-prefixed JSON object members.
+// Trace fields are renamed to "trace.id", "span.id" and "transaction.id",
+// and the error of Err is written as "error.message", "error.type" and
+// "error.stack_trace".
+func (enc ecsEncoder) EncodeFields(buf []byte, fields []Field) []byte {
+	for i := range fields {
+		f := &fields[i]
+		if f.kind == errorKind && f.Key == "error" {
+			buf = appendECSError(buf, f)
+			continue
+		}
+
+		key, ok := ecsFieldKeys[f.Key]
+		if !ok {
+			if enc.lossyFloats {
+				buf = appendLossyJSONFields(buf, fields[i:i+1])
+			} else {
+				buf = appendJSONFields(buf, fields[i:i+1])
+			}
+			continue
+		}
+		buf = append(buf, ',', '"')
+		buf = append(buf, key...)
+		buf = append(buf, '"', ':')
+		buf = appendJSONFieldValue(buf, *f)
+	}
+	return buf
+}
+
+// appendECSError appends the error held by f as ECS error fields.
+func appendECSError(buf []byte, f *Field) []byte {
+	err, ok := f.Value.(error)
+	if !ok || err == nil {
+		return append(buf, `,"error.message":null`...)
+	}
+
+	buf = append(buf, `,"error.message":"`...)
+	buf = appendJSONString(buf, err.Error())
+	buf = append(buf, `","error.type":"`...)
+	buf = appendJSONString(buf, reflect.TypeOf(err).String())
+	buf = append(buf, '"')
+
+	if stack := errorStack(err); stack != "" {
+		buf = append(buf, `,"error.stack_trace":"`...)
+		buf = appendJSONString(buf, stack)
+		buf = append(buf, '"')
+	}
+	return buf
+}
+
+// ecsLevel returns the lower-case level name used for "log.level".
+func ecsLevel(level Level) string {
+	switch level {
+	case DebugLevel:
+		return "debug"
+	case InfoLevel:
+		return "info"
+	case WarnLevel:
+		return "warn"
+	case ErrorLevel:
+		return "error"
+	case FatalLevel:
+		return "fatal"
+	case PanicLevel:
+		return "panic"
+	default:
+		return "unknown"
+	}
+}
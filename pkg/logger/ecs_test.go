@@ -0,0 +1,87 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger_ECSFormat(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:     InfoLevel,
+		Format:    ECSFormat,
+		Output:    buf,
+		AddCaller: true,
+		UseUTC:    true,
+	})
+
+	logger.With(String("trace_id", "abc123")).Warn("slow request",
+		Int("userID", 12345),
+		String("span_id", "def456"),
+	)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry), buf.String())
+
+	assert.Contains(t, entry, "@timestamp")
+	assert.NotContains(t, entry, "timestamp")
+	assert.Equal(t, "warn", entry["log.level"])
+	assert.Equal(t, "slow request", entry["message"])
+	assert.Equal(t, ECSVersion, entry["ecs.version"])
+	assert.Equal(t, "abc123", entry["trace.id"])
+	assert.Equal(t, "def456", entry["span.id"])
+	assert.Equal(t, float64(12345), entry["userID"])
+	assert.Contains(t, entry["log.origin.file.name"], "logger/ecs_test.go")
+	assert.NotZero(t, entry["log.origin.file.line"])
+	assert.Contains(t, entry["log.origin.function"], "TestLogger_ECSFormat")
+}
+
+func TestLogger_ECSFormatError(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: ECSFormat,
+		Output: buf,
+	})
+
+	logger.Error("failed", Err(newStackError("disk full")))
+	logger.Error("no error", Err(nil))
+	logger.Error("custom key", Any("cause", errors.New("timeout")))
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 3)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(lines[0], &entry))
+	assert.Equal(t, "error", entry["log.level"])
+	assert.Equal(t, "disk full", entry["error.message"])
+	assert.Equal(t, "*logger.stackError", entry["error.type"])
+	assert.Contains(t, entry["error.stack_trace"], "newStackError")
+	assert.NotContains(t, entry, "error")
+
+	entry = nil
+	require.NoError(t, json.Unmarshal(lines[1], &entry))
+	assert.Contains(t, entry, "error.message")
+	assert.Nil(t, entry["error.message"])
+
+	entry = nil
+	require.NoError(t, json.Unmarshal(lines[2], &entry))
+	assert.Equal(t, "timeout", entry["cause"])
+}
+
+func TestECSLevel(t *testing.T) {
+	assert.Equal(t, "debug", ecsLevel(DebugLevel))
+	assert.Equal(t, "info", ecsLevel(InfoLevel))
+	assert.Equal(t, "warn", ecsLevel(WarnLevel))
+	assert.Equal(t, "error", ecsLevel(ErrorLevel))
+	assert.Equal(t, "fatal", ecsLevel(FatalLevel))
+	assert.Equal(t, "panic", ecsLevel(PanicLevel))
+	assert.Equal(t, "unknown", ecsLevel(Level(42)))
+}
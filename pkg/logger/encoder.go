@@ -0,0 +1,381 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config.TimeFormat values that write the entry timestamp as an integer
+// number of seconds, milliseconds or nanoseconds since the Unix epoch
+// instead of formatting it with a layout.
+const (
+	TimeFormatUnix      = "unix"
+	TimeFormatUnixMilli = "unixmilli"
+	TimeFormatUnixNano  = "unixnano"
+)
+
+// Default keys of the members of JSON entries, used where Config.Keys
+// leaves them empty.
+const (
+	DefaultTimeKey       = "timestamp"
+	DefaultLevelKey      = "level"
+	DefaultMessageKey    = "message"
+	DefaultCallerKey     = "caller"
+	DefaultStacktraceKey = "stacktrace"
+)
+
+// Keys renames the members that the logger writes in every JSON entry, so
+// that entries match the schema of an existing ingest pipeline. Empty keys
+// keep their defaults.
+//
+// Example:
+//
+//	log := logger.New(logger.Config{
+//		Format: logger.JSONFormat,
+//		Keys:   logger.Keys{Time: "ts", Level: "lvl", Message: "msg"},
+//	})
+type Keys struct {
+	// Time is the key of the timestamp. Defaults to DefaultTimeKey.
+	Time string
+
+	// Level is the key of the level. Defaults to DefaultLevelKey.
+	Level string
+
+	// Message is the key of the message. Defaults to DefaultMessageKey.
+	Message string
+
+	// Caller is the key of the file:line call site written with
+	// Config.AddCaller. Defaults to DefaultCallerKey.
+	Caller string
+
+	// Stacktrace is the key of the field holding the stack trace added
+	// with Config.AddStacktrace, in every format. Defaults to
+	// DefaultStacktraceKey.
+	Stacktrace string
+}
+
+// stacktrace returns the key of stack trace fields.
+func (k Keys) stacktrace() string {
+	if k.Stacktrace == "" {
+		return DefaultStacktraceKey
+	}
+	return k.Stacktrace
+}
+
+// LevelEncoder selects how the built-in JSON, text, console and pretty
+// formats write levels. ECSFormat and GCPFormat always write the levels their
+// schemas define.
+type LevelEncoder int8
+
+const (
+	// UppercaseLevelEncoder writes levels as upper-case names, such as
+	// "INFO". It is the default.
+	UppercaseLevelEncoder LevelEncoder = iota
+
+	// LowercaseLevelEncoder writes levels as lower-case names, such as
+	// "info".
+	LowercaseLevelEncoder
+
+	// ShortLevelEncoder writes levels as their initial, such as "I".
+	ShortLevelEncoder
+
+	// SyslogLevelEncoder writes levels as syslog severity numbers, from 7
+	// for DebugLevel to 1 (alert) for PanicLevel.
+	SyslogLevelEncoder
+
+	// OTelLevelEncoder writes levels as OpenTelemetry severity numbers,
+	// such as 9 for InfoLevel.
+	OTelLevelEncoder
+)
+
+// String returns the name of the level encoder, as read by
+// ParseLevelEncoder.
+func (le LevelEncoder) String() string {
+	switch le {
+	case UppercaseLevelEncoder:
+		return "upper"
+	case LowercaseLevelEncoder:
+		return "lower"
+	case ShortLevelEncoder:
+		return "short"
+	case SyslogLevelEncoder:
+		return "syslog"
+	case OTelLevelEncoder:
+		return "otel"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler, with the name of the
+// level encoder.
+func (le LevelEncoder) MarshalText() ([]byte, error) {
+	if le < UppercaseLevelEncoder || le > OTelLevelEncoder {
+		return nil, fmt.Errorf("logger: unknown level encoder %d", le)
+	}
+	return []byte(le.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, parsing the level
+// encoder with ParseLevelEncoder.
+func (le *LevelEncoder) UnmarshalText(text []byte) error {
+	v, err := ParseLevelEncoder(string(text))
+	if err != nil {
+		return err
+	}
+	*le = v
+	return nil
+}
+
+// ParseLevelEncoder returns the level encoder named s, one of "upper",
+// "lower", "short", "syslog" and "otel" in any case.
+func ParseLevelEncoder(s string) (LevelEncoder, error) {
+	for le := UppercaseLevelEncoder; le <= OTelLevelEncoder; le++ {
+		if strings.EqualFold(strings.TrimSpace(s), le.String()) {
+			return le, nil
+		}
+	}
+	return 0, fmt.Errorf("logger: unknown level encoder %q", s)
+}
+
+// Lower-case and short names of the levels from DebugLevel to PanicLevel.
+var (
+	lowerLevelNames = [...]string{"debug", "info", "warn", "error", "fatal", "panic"}
+	shortLevelNames = [...]string{"D", "I", "W", "E", "F", "P"}
+)
+
+// syslogSeverity returns the syslog severity number of level.
+func syslogSeverity(level Level) int {
+	switch {
+	case level <= DebugLevel:
+		return 7
+	case level == InfoLevel:
+		return 6
+	case level == WarnLevel:
+		return 4
+	case level == ErrorLevel:
+		return 3
+	case level == FatalLevel:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// otelSeverity returns the OpenTelemetry severity number of level.
+// PanicLevel and FatalLevel map to FATAL2 and FATAL3.
+func otelSeverity(level Level) int {
+	switch level {
+	case DebugLevel:
+		return 5
+	case InfoLevel:
+		return 9
+	case WarnLevel:
+		return 13
+	case ErrorLevel:
+		return 17
+	case PanicLevel:
+		return 22
+	case FatalLevel:
+		return 23
+	default:
+		return 0
+	}
+}
+
+// Encoder turns entries into bytes. Implement it and set Config.Encoder to
+// write entries in formats other than the built-in ones, such as a binary
+// protocol.
+//
+// Encoders are called concurrently and must be safe for concurrent use.
+type Encoder interface {
+	// EncodeEntry appends the encoded entry to buf and returns the extended
+	// buffer. The logger adds the trailing newline. Entry.Context holds the
+	// fields bound with Logger.With as previously encoded by EncodeFields
+	// and must be included.
+	EncodeEntry(buf []byte, e *Entry) []byte
+
+	// EncodeFields appends fields in the form EncodeEntry splices in from
+	// Entry.Context. It is called once per Logger.With call.
+	EncodeFields(buf []byte, fields []Field) []byte
+}
+
+// encoderOptions holds the Config settings that tune the built-in encoders.
+type encoderOptions struct {
+	// lossyFloats selects appendLossyJSONFloat for Float64 fields in JSON.
+	lossyFloats bool
+
+	// escapeHTML applies appendHTMLEscaped to JSON entries.
+	escapeHTML bool
+
+	// timeFormat is Config.TimeFormat; empty means DefaultTimeFormat.
+	timeFormat string
+
+	// keys is Config.Keys, with Time defaulting to Config.TimeKey; empty
+	// keys mean their defaults.
+	keys Keys
+
+	// levelEncoder is Config.LevelEncoder.
+	levelEncoder LevelEncoder
+
+	// omitTime is Config.OmitTime.
+	omitTime bool
+
+	// gcpProjectID is Config.GCPProjectID.
+	gcpProjectID string
+
+	// csvColumns are Config.CSVColumns, or their default.
+	csvColumns *csvColumns
+}
+
+// newEncoderOptions extracts the encoder settings from config.
+func newEncoderOptions(config *Config) encoderOptions {
+	opts := encoderOptions{
+		lossyFloats:  config.LossyFloats,
+		escapeHTML:   config.EscapeHTML,
+		timeFormat:   config.TimeFormat,
+		keys:         config.Keys,
+		levelEncoder: config.LevelEncoder,
+		omitTime:     config.OmitTime,
+		gcpProjectID: config.GCPProjectID,
+	}
+	if opts.keys.Time == "" {
+		opts.keys.Time = config.TimeKey
+	}
+	opts.csvColumns = &csvColumns{names: csvColumnNames(config.CSVColumns, opts.keys)}
+	return opts
+}
+
+// appendTimestamp appends the entry timestamp t in the configured format.
+// Layout-formatted timestamps are wrapped in double quotes when quote is
+// set; epoch timestamps are always bare numbers.
+func (o encoderOptions) appendTimestamp(buf []byte, t time.Time, quote bool) []byte {
+	switch o.timeFormat {
+	case TimeFormatUnix:
+		return strconv.AppendInt(buf, t.Unix(), 10)
+	case TimeFormatUnixMilli:
+		return strconv.AppendInt(buf, t.UnixMilli(), 10)
+	case TimeFormatUnixNano:
+		return strconv.AppendInt(buf, t.UnixNano(), 10)
+	}
+
+	if quote {
+		buf = append(buf, '"')
+	}
+	if o.timeFormat == "" || o.timeFormat == DefaultTimeFormat {
+		buf = appendTime(buf, t)
+	} else {
+		buf = t.AppendFormat(buf, o.timeFormat)
+	}
+	if quote {
+		buf = append(buf, '"')
+	}
+	return buf
+}
+
+// levelText returns level as written by the configured LevelEncoder, and
+// whether it is a number.
+func (o encoderOptions) levelText(level Level) (string, bool) {
+	known := level >= DebugLevel && level <= PanicLevel
+	switch o.levelEncoder {
+	case LowercaseLevelEncoder:
+		if known {
+			return lowerLevelNames[level-DebugLevel], false
+		}
+	case ShortLevelEncoder:
+		if known {
+			return shortLevelNames[level-DebugLevel], false
+		}
+	case SyslogLevelEncoder:
+		return strconv.Itoa(syslogSeverity(level)), true
+	case OTelLevelEncoder:
+		return strconv.Itoa(otelSeverity(level)), true
+	}
+	return level.String(), false
+}
+
+// appendKey appends the JSON member name key, or def if key is empty.
+func appendKey(buf []byte, key, def string) []byte {
+	if key == "" {
+		key = def
+	}
+	buf = append(buf, '"')
+	buf = appendJSONString(buf, key)
+	return append(buf, '"', ':')
+}
+
+// NewEncoder returns the Encoder a Logger created with config would use:
+// config.Encoder if set, or else the built-in encoder for config.Format,
+// tuned by the encoding options of config. Sink packages use it to wrap
+// the built-in formats. Config.Sinks is ignored.
+func NewEncoder(config Config) Encoder {
+	return newEncoder(config.Format, config.Encoder, config.Output, newEncoderOptions(&config))
+}
+
+// newEncoder returns enc, or the built-in encoder for format writing to out.
+func newEncoder(format Format, enc Encoder, out io.Writer, opts encoderOptions) Encoder {
+	if enc != nil {
+		return enc
+	}
+
+	switch format {
+	case JSONFormat:
+		return jsonEncoder{opts}
+	case ConsoleFormat:
+		return consoleEncoder{encoderOptions: opts, color: useColor(out)}
+	case ECSFormat:
+		return ecsEncoder{opts}
+	case GCPFormat:
+		return gcpEncoder{opts}
+	case PrettyFormat:
+		return prettyEncoder{jsonEncoder: jsonEncoder{opts}, color: useColor(out)}
+	case CSVFormat:
+		return csvEncoder{opts}
+	default:
+		return textEncoder{opts}
+	}
+}
+
+// isBuiltinEncoder reports whether enc is one of the encoders handled by
+// Logger.emitBuiltin.
+func isBuiltinEncoder(enc Encoder) bool {
+	switch enc.(type) {
+	case jsonEncoder, textEncoder, consoleEncoder, ecsEncoder, gcpEncoder, prettyEncoder, csvEncoder:
+		return true
+	default:
+		return false
+	}
+}
+
+// textEncoder is the Encoder used for TextFormat.
+type textEncoder struct {
+	encoderOptions
+}
+
+// EncodeEntry formats a log entry as a single line of text: timestamp,
+// level, caller (when known), message, and key=value fields.
+func (enc textEncoder) EncodeEntry(buf []byte, e *Entry) []byte {
+	if !enc.omitTime {
+		buf = enc.appendTimestamp(buf, e.Time, false)
+		buf = append(buf, ' ')
+	}
+	level, _ := enc.levelText(e.Level)
+	buf = append(buf, level...)
+	buf = append(buf, ' ')
+	if e.Caller != nil {
+		buf = appendCaller(buf, e.Caller)
+		buf = append(buf, ' ')
+	}
+	buf = append(buf, e.Message...)
+	buf = append(buf, e.Context...)
+
+	return appendTextFields(buf, e.Fields)
+}
+
+// EncodeFields appends fields as space-separated key=value pairs.
+func (textEncoder) EncodeFields(buf []byte, fields []Field) []byte {
+	return appendTextFields(buf, fields)
+}
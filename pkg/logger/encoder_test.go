@@ -0,0 +1,207 @@
+package logger
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pipeEncoder writes entries as "LEVEL|message|key=value|...".
+type pipeEncoder struct{}
+
+func (pipeEncoder) EncodeEntry(buf []byte, e *Entry) []byte {
+	buf = append(buf, e.Level.String()...)
+	buf = append(buf, '|')
+	buf = append(buf, e.Message...)
+	buf = append(buf, e.Context...)
+	return pipeEncoder{}.EncodeFields(buf, e.Fields)
+}
+
+func (pipeEncoder) EncodeFields(buf []byte, fields []Field) []byte {
+	for _, f := range fields {
+		buf = append(buf, '|')
+		buf = append(buf, f.Key...)
+		buf = append(buf, '=')
+		buf = appendFieldValue(buf, f)
+	}
+	return buf
+}
+
+func TestLogger_CustomEncoder(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:   InfoLevel,
+		Format:  JSONFormat,
+		Output:  buf,
+		Encoder: pipeEncoder{},
+	})
+
+	logger.With(String("service", "billing")).Warn("disk low", Int("free_mb", 120))
+
+	assert.Equal(t, "WARN|disk low|service=billing|free_mb=120\n", buf.String())
+}
+
+func TestLogger_CustomEncoderWithHooks(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:   InfoLevel,
+		Output:  buf,
+		Encoder: pipeEncoder{},
+	})
+	logger.AddHook(HookFunc(func(e *Entry) error {
+		e.Fields = append(e.Fields, Bool("hooked", true))
+		return nil
+	}))
+
+	logger.Info("message")
+
+	assert.Equal(t, "INFO|message|hooked=true\n", buf.String())
+}
+
+func TestNewEncoder_Builtin(t *testing.T) {
+	assert.IsType(t, jsonEncoder{}, newEncoder(JSONFormat, nil, io.Discard, encoderOptions{}))
+	assert.IsType(t, textEncoder{}, newEncoder(TextFormat, nil, io.Discard, encoderOptions{}))
+	assert.IsType(t, consoleEncoder{}, newEncoder(ConsoleFormat, nil, io.Discard, encoderOptions{}))
+	assert.IsType(t, ecsEncoder{}, newEncoder(ECSFormat, nil, io.Discard, encoderOptions{}))
+	assert.IsType(t, gcpEncoder{}, newEncoder(GCPFormat, nil, io.Discard, encoderOptions{}))
+	assert.IsType(t, pipeEncoder{}, newEncoder(JSONFormat, pipeEncoder{}, io.Discard, encoderOptions{}))
+	assert.True(t, isBuiltinEncoder(newEncoder(ConsoleFormat, nil, io.Discard, encoderOptions{})))
+	assert.False(t, isBuiltinEncoder(pipeEncoder{}))
+}
+
+func TestNewEncoder(t *testing.T) {
+	e := &Entry{
+		Time:    time.Date(2024, 1, 20, 15, 4, 5, 0, time.UTC),
+		Level:   InfoLevel,
+		Message: "hello",
+		Fields:  []Field{String("k", "v")},
+	}
+
+	enc := NewEncoder(Config{Format: JSONFormat, TimeKey: "ts"})
+	assert.Equal(t, `{"ts":"2024-01-20T15:04:05.000Z","level":"INFO","message":"hello","k":"v"}`, string(enc.EncodeEntry(nil, e)))
+
+	assert.IsType(t, pipeEncoder{}, NewEncoder(Config{Format: JSONFormat, Encoder: pipeEncoder{}}))
+}
+
+func TestTimeFormat(t *testing.T) {
+	ts := time.Date(2024, 1, 20, 15, 4, 5, 123456789, time.UTC)
+
+	tests := []struct {
+		name   string
+		format string
+		json   string
+		text   string
+	}{
+		{"default", "", `"2024-01-20T15:04:05.123Z"`, "2024-01-20T15:04:05.123Z"},
+		{"rfc3339", time.RFC3339, `"2024-01-20T15:04:05Z"`, "2024-01-20T15:04:05Z"},
+		{"rfc3339nano", time.RFC3339Nano, `"2024-01-20T15:04:05.123456789Z"`, "2024-01-20T15:04:05.123456789Z"},
+		{"custom", "02 Jan 15:04", `"20 Jan 15:04"`, "20 Jan 15:04"},
+		{"unix", TimeFormatUnix, "1705763045", "1705763045"},
+		{"unixmilli", TimeFormatUnixMilli, "1705763045123", "1705763045123"},
+		{"unixnano", TimeFormatUnixNano, "1705763045123456789", "1705763045123456789"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := encoderOptions{timeFormat: tt.format}
+			entry := &Entry{Time: ts, Level: InfoLevel, Message: "msg"}
+
+			jsonOut := string(jsonEncoder{opts}.EncodeEntry(nil, entry))
+			assert.True(t, strings.HasPrefix(jsonOut, `{"timestamp":`+tt.json+`,"level":"INFO"`), jsonOut)
+
+			textOut := string(textEncoder{opts}.EncodeEntry(nil, entry))
+			assert.Equal(t, tt.text+" INFO msg", textOut)
+		})
+	}
+}
+
+func TestTimeKeyAndOmitTime(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:   InfoLevel,
+		Format:  JSONFormat,
+		Output:  buf,
+		TimeKey: "ts",
+	})
+	logger.Info("keyed")
+	assert.True(t, strings.HasPrefix(buf.String(), `{"ts":"`), buf.String())
+
+	buf.Reset()
+	logger = New(Config{
+		Level:    InfoLevel,
+		Format:   JSONFormat,
+		Output:   buf,
+		OmitTime: true,
+	})
+	logger.Info("untimed")
+	assert.Equal(t, `{"level":"INFO","message":"untimed"}`+"\n", buf.String())
+
+	buf.Reset()
+	logger = New(Config{
+		Level:    InfoLevel,
+		Format:   TextFormat,
+		Output:   buf,
+		OmitTime: true,
+	})
+	logger.Info("untimed")
+	assert.Equal(t, "INFO untimed\n", buf.String())
+}
+
+func TestConfig_Keys(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:     InfoLevel,
+		Format:    JSONFormat,
+		Output:    buf,
+		Clock:     func() time.Time { return time.Date(2024, 1, 20, 15, 4, 5, 0, time.UTC) },
+		AddCaller: true,
+		TimeKey:   "ignored",
+		Keys:      Keys{Time: "ts", Level: "lvl", Message: "msg", Caller: "src"},
+	})
+
+	logger.Info("keyed", String("k", "v"))
+	assert.Regexp(t, `^\{"ts":"2024-01-20T15:04:05.000Z","lvl":"INFO","msg":"keyed","src":"logger/encoder_test.go:\d+","function":"[^"]+","k":"v"\}\n$`, buf.String())
+}
+
+func TestLevelEncoder(t *testing.T) {
+	tests := []struct {
+		encoder LevelEncoder
+		json    string
+		text    string
+	}{
+		{UppercaseLevelEncoder, `"level":"WARN"`, "WARN msg"},
+		{LowercaseLevelEncoder, `"level":"warn"`, "warn msg"},
+		{ShortLevelEncoder, `"level":"W"`, "W msg"},
+		{SyslogLevelEncoder, `"level":4`, "4 msg"},
+		{OTelLevelEncoder, `"level":13`, "13 msg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoder.String(), func(t *testing.T) {
+			opts := encoderOptions{levelEncoder: tt.encoder, omitTime: true}
+			entry := &Entry{Level: WarnLevel, Message: "msg"}
+
+			assert.Equal(t, `{`+tt.json+`,"message":"msg"}`, string(jsonEncoder{opts}.EncodeEntry(nil, entry)))
+			assert.Equal(t, tt.text, string(textEncoder{opts}.EncodeEntry(nil, entry)))
+
+			parsed, err := ParseLevelEncoder(strings.ToUpper(tt.encoder.String()))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.encoder, parsed)
+		})
+	}
+
+	opts := encoderOptions{levelEncoder: LowercaseLevelEncoder}
+	level, _ := opts.levelText(Level(42))
+	assert.Equal(t, "UNKNOWN", level)
+
+	_, err := ParseLevelEncoder("tiny")
+	assert.Error(t, err)
+}
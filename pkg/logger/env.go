@@ -1,46 +1,57 @@
 package logger
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
 )
 
 const (
-	EnvLogLevel      = "LOG_LEVEL"
-	EnvLogBufferSize = "LOG_BUFFER_SIZE"
-	EnvLogFormat     = "LOG_FORMAT"
-	EnvLogUseUTC     = "LOG_USE_UTC"
-	EnvDebugLevel    = "debug"
-	EnvInfoLevel     = "info"
-	EnvWarnLevel     = "warn"
-	EnvErrorLevel    = "error"
-	EnvFatalLevel    = "fatal"
-	EnvPanicLevel    = "panic"
-	EnvLogFormatJSON = "json"
-	EnvLogFormatText = "text"
+	EnvLogLevel         = "LOG_LEVEL"
+	EnvLogLevels        = "LOG_LEVELS"
+	EnvLogBufferSize    = "LOG_BUFFER_SIZE"
+	EnvLogFormat        = "LOG_FORMAT"
+	EnvLogUseUTC        = "LOG_USE_UTC"
+	EnvLogOutput        = "LOG_OUTPUT"
+	EnvDebugLevel       = "debug"
+	EnvInfoLevel        = "info"
+	EnvWarnLevel        = "warn"
+	EnvErrorLevel       = "error"
+	EnvFatalLevel       = "fatal"
+	EnvPanicLevel       = "panic"
+	EnvLogFormatJSON    = "json"
+	EnvLogFormatText    = "text"
+	EnvLogFormatConsole = "console"
+	EnvLogFormatECS     = "ecs"
+	EnvLogFormatGCP     = "gcp"
+	EnvLogFormatPretty  = "pretty"
+	EnvLogFormatCSV     = "csv"
+	EnvLogOutputStdout  = "stdout"
+	EnvLogOutputStderr  = "stderr"
+	EnvLogOutputDiscard = "discard"
 )
 
+// fromEnvLogLevel parses LOG_LEVEL, defaulting to DebugLevel when it is
+// unset or unknown.
 func fromEnvLogLevel() Level {
-	var envLevel string
-	envLevel = os.Getenv(EnvLogLevel)
-	envLevel = strings.ToLower(envLevel)
-	switch envLevel {
-	case EnvDebugLevel:
-		return DebugLevel
-	case EnvInfoLevel:
-		return InfoLevel
-	case EnvWarnLevel:
-		return WarnLevel
-	case EnvErrorLevel:
-		return ErrorLevel
-	case EnvFatalLevel:
-		return FatalLevel
-	case EnvPanicLevel:
-		return PanicLevel
-	default:
+	level, err := ParseLevel(os.Getenv(EnvLogLevel))
+	if err != nil {
 		return DebugLevel
 	}
+	return level
+}
+
+// fromEnvLogLevels parses the per-logger levels of LOG_LEVELS, such as
+// "db=debug,auth=warn". An invalid spec is ignored as a whole.
+func fromEnvLogLevels() map[string]Level {
+	levels, err := ParseLevels(os.Getenv(EnvLogLevels))
+	if err != nil || len(levels) == 0 {
+		return nil
+	}
+	return levels
 }
 
 func fromEnvBufferSize() int {
@@ -58,18 +69,14 @@ func fromEnvBufferSize() int {
 	return bufSize
 }
 
+// fromEnvLogFormat parses LOG_FORMAT, defaulting to TextFormat when it is
+// unset or unknown.
 func fromEnvLogFormat() Format {
-	var envFormat string
-	envFormat = os.Getenv(EnvLogFormat)
-	envFormat = strings.ToLower(envFormat)
-	switch envFormat {
-	case EnvLogFormatJSON:
-		return JSONFormat
-	case EnvLogFormatText:
-		return TextFormat
-	default:
+	format, err := ParseFormat(os.Getenv(EnvLogFormat))
+	if err != nil {
 		return TextFormat
 	}
+	return format
 }
 
 func fromEnvUseUTC() bool {
@@ -78,11 +85,102 @@ func fromEnvUseUTC() bool {
 	return envUseUTC == "true" || envUseUTC == "1"
 }
 
+// fromEnvLogOutput returns the writer named by LOG_OUTPUT: a standard
+// stream, io.Discard, or a file opened for appending and created if
+// needed. An unset LOG_OUTPUT returns nil, for os.Stdout.
+func fromEnvLogOutput() (io.Writer, error) {
+	output := os.Getenv(EnvLogOutput)
+	switch strings.ToLower(output) {
+	case "":
+		return nil, nil
+	case EnvLogOutputStdout:
+		return os.Stdout, nil
+	case EnvLogOutputStderr:
+		return os.Stderr, nil
+	case EnvLogOutputDiscard:
+		return io.Discard, nil
+	}
+
+	file, err := os.OpenFile(output, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// envErrors receives the LOG_OUTPUT errors of ConfigFromEnv.
+var envErrors io.Writer = os.Stderr
+
+// ConfigFromEnv returns a Config set from LOG_LEVEL, LOG_LEVELS,
+// LOG_FORMAT, LOG_BUFFER_SIZE, LOG_USE_UTC and LOG_OUTPUT. Invalid values
+// are ignored, and an unset LOG_LEVEL means DebugLevel. If the LOG_OUTPUT
+// file cannot be opened, the error is written as one line to os.Stderr
+// and entries go to os.Stdout. See ConfigFromEnvStrict.
 func ConfigFromEnv() Config {
+	output, err := fromEnvLogOutput()
+	if err != nil {
+		fmt.Fprintf(envErrors, "logger: %s: %v; logging to stdout\n", EnvLogOutput, err)
+	}
+
 	return Config{
+		Output:     output,
 		Level:      fromEnvLogLevel(),
+		Levels:     fromEnvLogLevels(),
 		Format:     fromEnvLogFormat(),
 		BufferSize: fromEnvBufferSize(),
 		UseUTC:     fromEnvUseUTC(),
 	}
 }
+
+// ConfigFromEnvStrict is ConfigFromEnv that reports invalid values instead
+// of ignoring them: unknown levels and formats in LOG_LEVEL, LOG_LEVELS and
+// LOG_FORMAT, a non-numeric LOG_BUFFER_SIZE, a LOG_USE_UTC that is not a
+// boolean, and a LOG_OUTPUT file that cannot be opened. The error joins
+// all of them, and the LOG_OUTPUT file is closed again. Unset variables
+// keep the zero value of Config, so that an unset LOG_LEVEL means
+// InfoLevel rather than DebugLevel.
+func ConfigFromEnvStrict() (Config, error) {
+	var (
+		config Config
+		errs   []error
+		err    error
+	)
+
+	if v := os.Getenv(EnvLogLevel); v != "" {
+		if config.Level, err = ParseLevel(v); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", EnvLogLevel, err))
+		}
+	}
+	if v := os.Getenv(EnvLogLevels); v != "" {
+		if config.Levels, err = ParseLevels(v); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", EnvLogLevels, err))
+		}
+	}
+	if v := os.Getenv(EnvLogFormat); v != "" {
+		if config.Format, err = ParseFormat(v); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", EnvLogFormat, err))
+		}
+	}
+	if v := os.Getenv(EnvLogBufferSize); v != "" {
+		if config.BufferSize, err = strconv.Atoi(v); err != nil || config.BufferSize < 0 {
+			errs = append(errs, fmt.Errorf("%s: invalid buffer size %q", EnvLogBufferSize, v))
+		}
+	}
+	if v := os.Getenv(EnvLogUseUTC); v != "" {
+		if config.UseUTC, err = strconv.ParseBool(v); err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid boolean %q", EnvLogUseUTC, v))
+		}
+	}
+	if config.Output, err = fromEnvLogOutput(); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %w", EnvLogOutput, err))
+	}
+
+	if len(errs) > 0 {
+		// The LOG_OUTPUT file is not handed to the caller: close it.
+		if file, ok := config.Output.(*os.File); ok && file != os.Stdout && file != os.Stderr {
+			_ = file.Close()
+		}
+		return Config{}, errors.Join(errs...)
+	}
+	return config, nil
+}
@@ -0,0 +1,129 @@
+package logger
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigFromEnv_Output(t *testing.T) {
+	t.Setenv(EnvLogOutput, "")
+	assert.Nil(t, ConfigFromEnv().Output)
+
+	t.Setenv(EnvLogOutput, "STDERR")
+	assert.Equal(t, io.Writer(os.Stderr), ConfigFromEnv().Output)
+
+	t.Setenv(EnvLogOutput, "stdout")
+	assert.Equal(t, io.Writer(os.Stdout), ConfigFromEnv().Output)
+
+	t.Setenv(EnvLogOutput, "discard")
+	assert.Equal(t, io.Discard, ConfigFromEnv().Output)
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(path, []byte("existing\n"), 0o644))
+	t.Setenv(EnvLogOutput, path)
+	config := ConfigFromEnv()
+	file, ok := config.Output.(*os.File)
+	require.True(t, ok)
+	defer file.Close()
+
+	config.Format = JSONFormat
+	New(config).Info("appended")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "existing\n{")
+	assert.Contains(t, string(data), `"message":"appended"`)
+
+	t.Setenv(EnvLogOutput, filepath.Join(t.TempDir(), "missing", "app.log"))
+	assert.Nil(t, ConfigFromEnv().Output)
+}
+
+func TestConfigFromEnvStrict(t *testing.T) {
+	for _, key := range []string{EnvLogLevel, EnvLogLevels, EnvLogFormat, EnvLogBufferSize, EnvLogUseUTC, EnvLogOutput} {
+		t.Setenv(key, "")
+	}
+
+	config, err := ConfigFromEnvStrict()
+	require.NoError(t, err)
+	assert.Equal(t, InfoLevel, config.Level)
+	assert.Equal(t, TextFormat, config.Format)
+
+	t.Setenv(EnvLogLevel, "warn")
+	t.Setenv(EnvLogLevels, "db=debug")
+	t.Setenv(EnvLogFormat, "json")
+	t.Setenv(EnvLogBufferSize, "4096")
+	t.Setenv(EnvLogUseUTC, "true")
+	t.Setenv(EnvLogOutput, "stderr")
+
+	config, err = ConfigFromEnvStrict()
+	require.NoError(t, err)
+	assert.Equal(t, WarnLevel, config.Level)
+	assert.Equal(t, map[string]Level{"db": DebugLevel}, config.Levels)
+	assert.Equal(t, JSONFormat, config.Format)
+	assert.Equal(t, 4096, config.BufferSize)
+	assert.True(t, config.UseUTC)
+	assert.Equal(t, io.Writer(os.Stderr), config.Output)
+
+	t.Setenv(EnvLogLevel, "verbose")
+	t.Setenv(EnvLogFormat, "xml")
+	t.Setenv(EnvLogBufferSize, "4k")
+	t.Setenv(EnvLogUseUTC, "yes")
+
+	_, err = ConfigFromEnvStrict()
+	require.Error(t, err)
+	for _, key := range []string{EnvLogLevel + ":", EnvLogFormat + ":", EnvLogBufferSize + ":", EnvLogUseUTC + ":"} {
+		assert.Contains(t, err.Error(), key)
+	}
+	assert.NotContains(t, err.Error(), EnvLogLevels+":")
+}
+
+func TestConfigFromEnvStrict_ClosesOutputOnError(t *testing.T) {
+	if _, err := os.Stat("/proc/self/fd"); err != nil {
+		t.Skip("needs /proc/self/fd")
+	}
+	for _, key := range []string{EnvLogLevel, EnvLogLevels, EnvLogFormat, EnvLogBufferSize, EnvLogUseUTC} {
+		t.Setenv(key, "")
+	}
+	path := filepath.Join(t.TempDir(), "app.log")
+	t.Setenv(EnvLogOutput, path)
+	t.Setenv(EnvLogLevel, "verbose")
+
+	_, err := ConfigFromEnvStrict()
+	require.Error(t, err)
+	assert.FileExists(t, path)
+	assert.Zero(t, openDescriptors(t, path), "the file is closed")
+}
+
+// openDescriptors returns the number of descriptors of the process open on
+// path.
+func openDescriptors(t *testing.T, path string) int {
+	t.Helper()
+
+	entries, err := os.ReadDir("/proc/self/fd")
+	require.NoError(t, err)
+	n := 0
+	for _, e := range entries {
+		if target, err := os.Readlink(filepath.Join("/proc/self/fd", e.Name())); err == nil && target == path {
+			n++
+		}
+	}
+	return n
+}
+
+func TestConfigFromEnv_OutputError(t *testing.T) {
+	errs := &bytes.Buffer{}
+	envErrors = errs
+	defer func() { envErrors = os.Stderr }()
+
+	path := filepath.Join(t.TempDir(), "missing", "app.log")
+	t.Setenv(EnvLogOutput, path)
+	assert.Nil(t, ConfigFromEnv().Output, "falls back to stdout")
+	assert.True(t, strings.HasPrefix(errs.String(), "logger: LOG_OUTPUT: open "+path), errs.String())
+	assert.True(t, strings.HasSuffix(errs.String(), "; logging to stdout\n"), errs.String())
+}
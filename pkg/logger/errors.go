@@ -0,0 +1,166 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strconv"
+)
+
+// Suffixes of the keys emitted alongside an error field built with Err.
+const (
+	errorCausesSuffix = "_causes"
+	errorStackSuffix  = "_stack"
+)
+
+// errorCauses returns the messages of the errors wrapped by err, outermost
+// first. Errors wrapping several errors (errors.Join) contribute the
+// message of each of them and end the chain.
+func errorCauses(err error) []string {
+	var causes []string
+	for {
+		switch x := err.(type) {
+		case interface{ Unwrap() error }:
+			err = x.Unwrap()
+			if err == nil {
+				return causes
+			}
+			causes = append(causes, err.Error())
+		case interface{ Unwrap() []error }:
+			for _, e := range x.Unwrap() {
+				if e != nil {
+					causes = append(causes, e.Error())
+				}
+			}
+			return causes
+		default:
+			return causes
+		}
+	}
+}
+
+// errorStack returns the stack trace of the first error in err's chain that
+// has a StackTrace method, or "" if there is none.
+//
+// StackTrace may return []uintptr program counters, a string, or any value
+// implementing fmt.Formatter with a "%+v" verb such as the StackTrace type
+// of github.com/pkg/errors.
+func errorStack(err error) string {
+	for err != nil {
+		if stack, ok := callStackTrace(err); ok {
+			return stack
+		}
+		err = errors.Unwrap(err)
+	}
+	return ""
+}
+
+func callStackTrace(err error) (string, bool) {
+	m := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+		return "", false
+	}
+
+	switch st := m.Call(nil)[0].Interface().(type) {
+	case []uintptr:
+		return formatPCs(st), true
+	case string:
+		return st, true
+	case fmt.Formatter:
+		return fmt.Sprintf("%+v", st), true
+	default:
+		return fmt.Sprint(st), true
+	}
+}
+
+// formatPCs formats program counters as "function\n\tfile:line" lines.
+func formatPCs(pcs []uintptr) string {
+	var buf []byte
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if len(buf) > 0 {
+			buf = append(buf, '\n')
+		}
+		buf = append(buf, frame.Function...)
+		buf = append(buf, "\n\t"...)
+		buf = append(buf, frame.File...)
+		buf = append(buf, ':')
+		buf = appendInt(buf, int64(frame.Line))
+		if !more {
+			break
+		}
+	}
+	return string(buf)
+}
+
+// appendErrorDetailsJSON appends the cause chain and stack trace of the
+// error held by f as extra JSON members, if it has any.
+func appendErrorDetailsJSON(buf []byte, f *Field) []byte {
+	err, ok := f.Value.(error)
+	if !ok || err == nil {
+		return buf
+	}
+
+	if causes := errorCauses(err); len(causes) > 0 {
+		buf = append(buf, ',', '"')
+		buf = appendJSONString(buf, f.Key)
+		buf = append(buf, errorCausesSuffix...)
+		buf = append(buf, `":[`...)
+		for i, cause := range causes {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = append(buf, '"')
+			buf = appendJSONString(buf, cause)
+			buf = append(buf, '"')
+		}
+		buf = append(buf, ']')
+	}
+
+	if stack := errorStack(err); stack != "" {
+		buf = append(buf, ',', '"')
+		buf = appendJSONString(buf, f.Key)
+		buf = append(buf, errorStackSuffix...)
+		buf = append(buf, `":"`...)
+		buf = appendJSONString(buf, stack)
+		buf = append(buf, '"')
+	}
+
+	return buf
+}
+
+// appendErrorDetailsText appends the cause chain and stack trace of the
+// error held by f as extra key=value pairs, if it has any. The stack trace
+// is quoted so that the entry stays on one line.
+func appendErrorDetailsText(buf []byte, f *Field) []byte {
+	err, ok := f.Value.(error)
+	if !ok || err == nil {
+		return buf
+	}
+
+	if causes := errorCauses(err); len(causes) > 0 {
+		buf = append(buf, ' ')
+		buf = append(buf, f.Key...)
+		buf = append(buf, errorCausesSuffix...)
+		buf = append(buf, "=["...)
+		for i, cause := range causes {
+			if i > 0 {
+				buf = append(buf, ' ')
+			}
+			buf = appendValue(buf, cause)
+		}
+		buf = append(buf, ']')
+	}
+
+	if stack := errorStack(err); stack != "" {
+		buf = append(buf, ' ')
+		buf = append(buf, f.Key...)
+		buf = append(buf, errorStackSuffix...)
+		buf = append(buf, '=')
+		buf = strconv.AppendQuote(buf, stack)
+	}
+
+	return buf
+}
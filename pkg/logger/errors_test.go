@@ -0,0 +1,95 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type stackError struct {
+	msg string
+	pcs []uintptr
+}
+
+func newStackError(msg string) *stackError {
+	pcs := make([]uintptr, 8)
+	n := runtime.Callers(1, pcs)
+	return &stackError{msg: msg, pcs: pcs[:n]}
+}
+
+func (e *stackError) Error() string {
+	return e.msg
+}
+
+func (e *stackError) StackTrace() []uintptr {
+	return e.pcs
+}
+
+func TestErr_CausesJSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: JSONFormat,
+		Output: buf,
+	})
+
+	root := errors.New("connection refused")
+	err := fmt.Errorf("query users: %w", fmt.Errorf("dial db: %w", root))
+	logger.Error("request failed", Err(err))
+
+	output := buf.String()
+	assert.Contains(t, output,
+		`"error":"query users: dial db: connection refused","error_causes":["dial db: connection refused","connection refused"]}`)
+	assert.NotContains(t, output, "error_stack")
+}
+
+func TestErr_CausesText(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: TextFormat,
+		Output: buf,
+	})
+
+	err := errors.Join(errors.New("first"), errors.New("second failure"))
+	logger.Error("request failed", Err(fmt.Errorf("batch: %w", err)))
+
+	assert.Contains(t, buf.String(), "error_causes=[\"first\nsecond failure\" first \"second failure\"]")
+}
+
+func TestErr_StackTrace(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: JSONFormat,
+		Output: buf,
+	})
+
+	err := fmt.Errorf("wrapped: %w", newStackError("boom"))
+	logger.Error("request failed", Err(err))
+
+	output := buf.String()
+	assert.Contains(t, output, `"error_causes":["boom"]`)
+	assert.Contains(t, output, `"error_stack":"github.com/barnowlsnest/go-logslib/pkg/logger.newStackError\n\t`)
+}
+
+func TestErr_NoDetails(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: TextFormat,
+		Output: buf,
+	})
+
+	logger.Error("plain", Err(errors.New("boom")), Err(nil))
+
+	assert.Contains(t, buf.String(), "plain error=boom error=<nil>\n")
+}
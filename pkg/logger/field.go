@@ -0,0 +1,406 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// fieldKind identifies which slot of a Field carries its value.
+// The zero value means the value lives in Field.Value.
+type fieldKind uint8
+
+const (
+	anyKind fieldKind = iota
+	stringKind
+	int64Kind
+	float64Kind
+	boolKind
+	durationKind
+	timeKind
+	timeFullKind
+	errorKind
+	reflectKind
+	objectKind
+	arrayKind
+	// hashedKind fields built with Hashed keep the raw value in str and
+	// its SHA-256 pseudonym in Value, which encoders write.
+	hashedKind
+	// stringerKind fields built with Stringer keep the fmt.Stringer in
+	// Value, whose String method is called when the entry is encoded.
+	stringerKind
+	// lazyKind fields built with Lazy keep the function computing their
+	// value in Value. They are resolved with Any before entries are
+	// encoded.
+	lazyKind
+)
+
+// String constructs a field with the given key and string value.
+func String(key, val string) Field {
+	return Field{Key: key, kind: stringKind, str: val}
+}
+
+// Int constructs a field with the given key and int value.
+func Int(key string, val int) Field {
+	return Int64(key, int64(val))
+}
+
+// Int64 constructs a field with the given key and int64 value.
+func Int64(key string, val int64) Field {
+	return Field{Key: key, kind: int64Kind, num: val}
+}
+
+// Float64 constructs a field with the given key and float64 value.
+func Float64(key string, val float64) Field {
+	return Field{Key: key, kind: float64Kind, num: int64(math.Float64bits(val))}
+}
+
+// Bool constructs a field with the given key and bool value.
+func Bool(key string, val bool) Field {
+	var num int64
+	if val {
+		num = 1
+	}
+	return Field{Key: key, kind: boolKind, num: num}
+}
+
+// Dur constructs a field with the given key and time.Duration value.
+func Dur(key string, val time.Duration) Field {
+	return Field{Key: key, kind: durationKind, num: int64(val)}
+}
+
+// Time constructs a field with the given key and time.Time value.
+// Times representable as Unix nanoseconds are stored without allocating.
+func Time(key string, val time.Time) Field {
+	if val.Before(minTimeInt64) || val.After(maxTimeInt64) {
+		return Field{Key: key, kind: timeFullKind, Value: val}
+	}
+	return Field{Key: key, kind: timeKind, num: val.UnixNano(), Value: val.Location()}
+}
+
+// Err constructs a field with the key "error" holding the given error.
+// A nil error is encoded as null in JSON and <nil> in text.
+//
+// Besides the error message, the entry includes the messages of the errors
+// it wraps under "error_causes" and, if an error in the chain has a
+// StackTrace method, its stack trace under "error_stack".
+func Err(err error) Field {
+	return Field{Key: "error", kind: errorKind, Value: err}
+}
+
+// Any constructs a field with the given key and an arbitrary value.
+// Values of the types supported by the typed constructors are stored as
+// such, LogObjectMarshaler and LogArrayMarshaler values are logged as by
+// Object and Array, and values implementing fmt.Stringer but not
+// json.Marshaler are logged as by Stringer. []string, []int,
+// []interface{}, []Field and map[string]interface{} values are encoded as
+// arrays and objects whose elements are logged as by Any, so that errors
+// and nested fields inside them keep their text. Anything else is encoded
+// with encoding/json when the entry is written, instead of being reported
+// as "unknown".
+//
+// The encoding/json fallback uses reflection and allocates, so prefer the
+// typed constructors on hot paths.
+func Any(key string, value interface{}) Field {
+	switch v := value.(type) {
+	case string:
+		return String(key, v)
+	case int:
+		return Int(key, v)
+	case int64:
+		return Int64(key, v)
+	case float64:
+		return Float64(key, v)
+	case bool:
+		return Bool(key, v)
+	case time.Duration:
+		return Dur(key, v)
+	case time.Time:
+		return Time(key, v)
+	case error:
+		return Field{Key: key, kind: errorKind, Value: v}
+	case LogObjectMarshaler:
+		return Object(key, v)
+	case LogArrayMarshaler:
+		return Array(key, v)
+	case json.Marshaler:
+		return Reflect(key, v)
+	case fmt.Stringer:
+		return Stringer(key, v)
+	case []string:
+		return Strings(key, v)
+	case []int:
+		return Ints(key, v)
+	case []interface{}:
+		return Array(key, anyArray(v))
+	case []Field:
+		return Group(key, v...)
+	case map[string]interface{}:
+		return Object(key, mapObject(v))
+	default:
+		return Field{Key: key, kind: reflectKind, Value: value}
+	}
+}
+
+// Lazy constructs a field with the given key whose value is computed by
+// fn only if the entry is written: after the level check, sampling and
+// rate limiting. Use it for expensive diagnostic values on hot paths:
+//
+//	log.Debug("request", logger.Lazy("headers", func() interface{} {
+//		return dumpHeaders(r)
+//	}))
+//
+// Fields bound with With are computed when With is called. The value is
+// logged as by Any.
+func Lazy(key string, fn func() interface{}) Field {
+	return Field{Key: key, kind: lazyKind, Value: fn}
+}
+
+// resolve returns the field fn of a lazyKind field computes, or f itself.
+func (f Field) resolve() Field {
+	if f.kind != lazyKind {
+		return f
+	}
+	return Any(f.Key, f.Value.(func() interface{})())
+}
+
+// resolveLazy returns fields with Lazy fields computed. fields is copied
+// before the first change, so that the caller's slice is left untouched.
+func resolveLazy(fields []Field) []Field {
+	copied := false
+	for i := range fields {
+		if fields[i].kind != lazyKind {
+			continue
+		}
+		if !copied {
+			fields = append([]Field(nil), fields...)
+			copied = true
+		}
+		fields[i] = fields[i].resolve()
+	}
+	return fields
+}
+
+// Stringer constructs a field with the given key whose value is the
+// output of val.String(), called only if the entry is written. Any uses
+// it for values implementing fmt.Stringer, so that enums and IDs log
+// readably; use Reflect to log such a value with encoding/json instead.
+func Stringer(key string, val fmt.Stringer) Field {
+	return Field{Key: key, kind: stringerKind, Value: val}
+}
+
+// Reflect constructs a field with the given key whose value is encoded
+// with encoding/json, whatever its type. It is the opt-out of the
+// detection of fmt.Stringer values by Any.
+func Reflect(key string, value interface{}) Field {
+	return Field{Key: key, kind: reflectKind, Value: value}
+}
+
+// stringerText returns v.String(), or "<nil>" if v is a nil pointer whose
+// String method panics.
+func stringerText(v fmt.Stringer) (s string) {
+	defer func() {
+		if r := recover(); r != nil {
+			if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.IsNil() {
+				s = "<nil>"
+				return
+			}
+			s = fmt.Sprintf("!panic: %v", r)
+		}
+	}()
+	return v.String()
+}
+
+var (
+	minTimeInt64 = time.Unix(0, math.MinInt64)
+	maxTimeInt64 = time.Unix(0, math.MaxInt64)
+)
+
+// timeValue reconstructs the time.Time stored in a timeKind or timeFullKind field.
+func (f Field) timeValue() time.Time {
+	if f.kind == timeFullKind {
+		return f.Value.(time.Time)
+	}
+	t := time.Unix(0, f.num)
+	if loc, ok := f.Value.(*time.Location); ok && loc != nil {
+		t = t.In(loc)
+	}
+	return t
+}
+
+// Interface returns the field value as a Go value: a string, int64,
+// float64, bool, time.Duration, time.Time or error for fields built with
+// the typed constructors, and the original value otherwise.
+// It allocates for typed fields and is meant for custom encoders.
+func (f Field) Interface() interface{} {
+	switch f.kind {
+	case stringKind:
+		return f.str
+	case int64Kind:
+		return f.num
+	case float64Kind:
+		return math.Float64frombits(uint64(f.num))
+	case boolKind:
+		return f.num == 1
+	case durationKind:
+		return time.Duration(f.num)
+	case timeKind, timeFullKind:
+		return f.timeValue()
+	case lazyKind:
+		return f.resolve().Interface()
+	case objectKind, arrayKind:
+		return unwrap(f.Value)
+	default:
+		return f.Value
+	}
+}
+
+// AppendTextValue appends the field value as TextFormat writes it.
+func (f Field) AppendTextValue(buf []byte) []byte {
+	return appendFieldValue(buf, f)
+}
+
+// AppendJSONValue appends the field value as JSONFormat writes it.
+func (f Field) AppendJSONValue(buf []byte) []byte {
+	return appendJSONFieldValue(buf, f)
+}
+
+// fieldText returns the value of f as text, without quoting strings.
+func fieldText(f Field) string {
+	if f.kind == stringKind {
+		return f.str
+	}
+	if s, ok := f.Value.(string); ok && f.kind == anyKind {
+		return s
+	}
+	var tmp [64]byte
+	return string(appendFieldValue(tmp[:0], f))
+}
+
+// appendFieldValue appends the text representation of a field value to the buffer.
+func appendFieldValue(buf []byte, f Field) []byte {
+	switch f.kind {
+	case stringKind:
+		return appendValue(buf, f.str)
+	case int64Kind:
+		return appendInt(buf, f.num)
+	case float64Kind:
+		return appendFloat(buf, math.Float64frombits(uint64(f.num)), 64)
+	case boolKind:
+		return appendBool(buf, f.num == 1)
+	case durationKind:
+		return append(buf, time.Duration(f.num).String()...)
+	case timeKind, timeFullKind:
+		return f.timeValue().AppendFormat(buf, DefaultTimeFormat)
+	case errorKind:
+		if f.Value == nil {
+			return append(buf, "<nil>"...)
+		}
+		return appendValue(buf, f.Value.(error).Error())
+	case reflectKind:
+		return appendReflected(buf, f.Value, false)
+	case objectKind, arrayKind:
+		return appendMarshaler(buf, &f)
+	case stringerKind:
+		return appendValue(buf, stringerText(f.Value.(fmt.Stringer)))
+	case lazyKind:
+		return appendFieldValue(buf, f.resolve())
+	default:
+		return appendValue(buf, f.Value)
+	}
+}
+
+// appendJSONFieldValue appends the JSON representation of a field value to the buffer.
+func appendJSONFieldValue(buf []byte, f Field) []byte {
+	switch f.kind {
+	case stringKind:
+		buf = append(buf, '"')
+		buf = appendJSONString(buf, f.str)
+		return append(buf, '"')
+	case int64Kind:
+		return appendInt(buf, f.num)
+	case float64Kind:
+		return appendJSONFloat(buf, math.Float64frombits(uint64(f.num)), 64)
+	case boolKind:
+		return appendBool(buf, f.num == 1)
+	case durationKind:
+		buf = append(buf, '"')
+		buf = append(buf, time.Duration(f.num).String()...)
+		return append(buf, '"')
+	case timeKind, timeFullKind:
+		buf = append(buf, '"')
+		buf = f.timeValue().AppendFormat(buf, DefaultTimeFormat)
+		return append(buf, '"')
+	case errorKind:
+		if f.Value == nil {
+			return append(buf, "null"...)
+		}
+		buf = append(buf, '"')
+		buf = appendJSONString(buf, f.Value.(error).Error())
+		return append(buf, '"')
+	case reflectKind:
+		return appendReflected(buf, f.Value, true)
+	case objectKind, arrayKind:
+		return appendMarshaler(buf, &f)
+	case stringerKind:
+		buf = append(buf, '"')
+		buf = appendJSONString(buf, stringerText(f.Value.(fmt.Stringer)))
+		return append(buf, '"')
+	case lazyKind:
+		return appendJSONFieldValue(buf, f.resolve())
+	default:
+		return appendJSONValue(buf, f.Value)
+	}
+}
+
+func appendBool(buf []byte, b bool) []byte {
+	if b {
+		return append(buf, "true"...)
+	}
+	return append(buf, "false"...)
+}
+
+// appendReflected appends value using the built-in encoders when they
+// support its type, and its encoding/json representation otherwise.
+// If marshaling fails, the error is written in place of the value.
+func appendReflected(buf []byte, value interface{}, asJSON bool) []byte {
+	if out, ok := appendInteger(buf, value); ok {
+		return out
+	}
+
+	switch value.(type) {
+	case float32, []byte:
+		if asJSON {
+			return appendJSONValue(buf, value)
+		}
+		return appendValue(buf, value)
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		if asJSON {
+			buf = append(buf, '"')
+			buf = appendJSONString(buf, "!marshal: "+err.Error())
+			return append(buf, '"')
+		}
+		return appendValue(buf, "!marshal: "+err.Error())
+	}
+	return append(buf, b...)
+}
+
+// sortFields returns fields ordered by key for Config.SortFields, keeping
+// the order of fields with the same key. fields is copied unless it is
+// already sorted, as it may belong to the caller.
+func sortFields(fields []Field) []Field {
+	byKey := func(a, b Field) int { return strings.Compare(a.Key, b.Key) }
+	if slices.IsSortedFunc(fields, byKey) {
+		return fields
+	}
+	sorted := append([]Field(nil), fields...)
+	slices.SortStableFunc(sorted, byKey)
+	return sorted
+}
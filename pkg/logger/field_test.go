@@ -0,0 +1,268 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypedFields_JSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: JSONFormat,
+		Output: buf,
+	})
+
+	ts := time.Date(2024, 1, 20, 15, 4, 5, 0, time.UTC)
+	logger.Info("typed",
+		String("string", "test"),
+		Int("int", 42),
+		Int64("int64", -123),
+		Float64("float", 3.14),
+		Bool("bool", true),
+		Dur("dur", 1500*time.Millisecond),
+		Time("time", ts),
+		Err(errors.New("boom")),
+	)
+
+	output := buf.String()
+	assert.Contains(t, output, `"string":"test"`)
+	assert.Contains(t, output, `"int":42`)
+	assert.Contains(t, output, `"int64":-123`)
+	assert.Contains(t, output, `"float":3.14`)
+	assert.Contains(t, output, `"bool":true`)
+	assert.Contains(t, output, `"dur":"1.5s"`)
+	assert.Contains(t, output, `"time":"2024-01-20T15:04:05.000Z"`)
+	assert.Contains(t, output, `"error":"boom"`)
+}
+
+func TestTypedFields_Text(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: TextFormat,
+		Output: buf,
+	})
+
+	logger.Info("typed",
+		String("user", "john doe"),
+		Int("count", 7),
+		Bool("ok", false),
+		Dur("took", 2*time.Second),
+		Err(nil),
+	)
+
+	output := buf.String()
+	assert.Contains(t, output, `user="john doe"`)
+	assert.Contains(t, output, "count=7")
+	assert.Contains(t, output, "ok=false")
+	assert.Contains(t, output, "took=2s")
+	assert.Contains(t, output, "error=<nil>")
+}
+
+func TestTypedFields_TimeOutOfRange(t *testing.T) {
+	ts := time.Date(3000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	f := Time("ts", ts)
+
+	assert.True(t, ts.Equal(f.timeValue()))
+}
+
+func TestTypedFields_NoExtraAllocations(t *testing.T) {
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: JSONFormat,
+		Output: discardWriter,
+	})
+
+	baseline := testing.AllocsPerRun(100, func() {
+		logger.Info("typed")
+	})
+	allocs := testing.AllocsPerRun(100, func() {
+		logger.Info("typed",
+			String("user", "john"),
+			Int("count", 7),
+			Float64("ratio", 0.5),
+			Bool("ok", true),
+		)
+	})
+
+	assert.Equal(t, baseline, allocs, "typed fields should not allocate")
+}
+
+type anyPayload struct {
+	ID   int      `json:"id"`
+	Tags []string `json:"tags"`
+}
+
+func TestAny_JSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: JSONFormat,
+		Output: buf,
+	})
+
+	logger.Info("any",
+		Any("struct", anyPayload{ID: 7, Tags: []string{"a", "b"}}),
+		Any("map", map[string]int{"x": 1}),
+		Any("string", "plain"),
+		Any("uint8", uint8(3)),
+		Any("nil", nil),
+		Any("chan", make(chan int)),
+	)
+
+	output := buf.String()
+	assert.Contains(t, output, `"struct":{"id":7,"tags":["a","b"]}`)
+	assert.Contains(t, output, `"map":{"x":1}`)
+	assert.Contains(t, output, `"string":"plain"`)
+	assert.Contains(t, output, `"uint8":3`)
+	assert.Contains(t, output, `"nil":null`)
+	assert.Contains(t, output, `"chan":"!marshal: json: unsupported type: chan int"`)
+}
+
+func TestAny_Text(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: TextFormat,
+		Output: buf,
+	})
+
+	logger.Info("any", Any("ids", []int{1, 2}), Any("err", errors.New("boom")))
+
+	assert.Contains(t, buf.String(), "ids=[1,2] err=boom")
+}
+
+type testColor int
+
+func (c testColor) String() string { return [...]string{"red", "green"}[c] }
+
+type testID struct{ n int }
+
+func (id *testID) String() string { return fmt.Sprintf("id-%d", id.n) }
+
+func TestStringer(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(Config{
+		Level:    InfoLevel,
+		Format:   JSONFormat,
+		Output:   buf,
+		OmitTime: true,
+	})
+
+	var nilID *testID
+	logger.Info("stringer",
+		Any("color", testColor(1)),
+		Stringer("id", &testID{n: 7}),
+		Any("nil", nilID),
+		Reflect("raw", testColor(1)),
+		Any("when", time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC)),
+		Field{Key: "err", Value: fmt.Errorf("wrap: %w", errors.New("boom"))},
+		Field{Key: "plain", Value: testColor(0)},
+	)
+
+	assert.Equal(t, `{"level":"INFO","message":"stringer","color":"green","id":"id-7","nil":"<nil>",`+
+		`"raw":1,"when":"2024-01-20T00:00:00.000Z","err":"wrap: boom","plain":"red"}`+"\n", buf.String())
+
+	text := New(Config{Level: InfoLevel, Format: TextFormat, Output: buf, OmitTime: true})
+	buf.Reset()
+	text.Info("stringer", Any("color", testColor(0)), Field{Key: "err", Value: errors.New("boom")})
+	assert.Contains(t, buf.String(), "color=red err=boom")
+}
+
+func TestAny_TypedFastPath(t *testing.T) {
+	assert.Equal(t, String("k", "v"), Any("k", "v"))
+	assert.Equal(t, Int("k", 1), Any("k", 1))
+	assert.Equal(t, Bool("k", true), Any("k", true))
+	assert.Equal(t, Dur("k", time.Second), Any("k", time.Second))
+}
+
+func TestField_Interface(t *testing.T) {
+	now := time.Date(2024, 1, 20, 15, 4, 5, 0, time.UTC)
+	err := errors.New("boom")
+
+	assert.Equal(t, "v", String("k", "v").Interface())
+	assert.Equal(t, int64(42), Int("k", 42).Interface())
+	assert.Equal(t, 1.5, Float64("k", 1.5).Interface())
+	assert.Equal(t, true, Bool("k", true).Interface())
+	assert.Equal(t, time.Second, Dur("k", time.Second).Interface())
+	assert.True(t, now.Equal(Time("k", now).Interface().(time.Time)))
+	assert.Equal(t, err, Err(err).Interface())
+}
+
+func TestField_AppendValue(t *testing.T) {
+	f := String("k", "a b")
+
+	assert.Equal(t, `"a b"`, string(f.AppendTextValue(nil)))
+	assert.Equal(t, `"a b"`, string(f.AppendJSONValue(nil)))
+	assert.Equal(t, "1s", string(Dur("k", time.Second).AppendTextValue(nil)))
+}
+
+func TestLazy(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(Config{
+		Level:    InfoLevel,
+		Format:   JSONFormat,
+		Output:   buf,
+		OmitTime: true,
+		Sampling: &SamplerConfig{Tick: time.Hour, Initial: 1},
+	})
+
+	calls := 0
+	lazy := Lazy("state", func() interface{} {
+		calls++
+		return map[string]int{"size": calls}
+	})
+
+	logger.Debug("disabled", lazy)
+	assert.Zero(t, calls)
+
+	fields := []Field{lazy}
+	logger.Info("sampled", fields...)
+	logger.Info("sampled", fields...)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, `{"level":"INFO","message":"sampled","state":{"size":1}}`+"\n", buf.String())
+	assert.Equal(t, lazyKind, fields[0].kind, "caller's fields must be left untouched")
+
+	buf.Reset()
+	logger.With(Lazy("bound", func() interface{} { return "once" })).Info("with")
+	assert.Equal(t, `{"level":"INFO","message":"with","bound":"once"}`+"\n", buf.String())
+
+	assert.Equal(t, "value", Lazy("k", func() interface{} { return "value" }).Interface())
+}
+
+func TestConfig_SortFields(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := New(Config{
+		Level:      InfoLevel,
+		Format:     JSONFormat,
+		Output:     buf,
+		OmitTime:   true,
+		SortFields: true,
+		Fields:     []Field{String("service", "api"), String("env", "prod")},
+	})
+
+	fields := []Field{Int("status", 200), String("method", "GET"), String("b", "1"), String("b", "2")}
+	log.With(String("user", "alice"), String("tenant", "acme")).Info("request", fields...)
+
+	assert.Equal(t, `{"level":"INFO","message":"request","env":"prod","service":"api","tenant":"acme","user":"alice","b":"1","b":"2","method":"GET","status":200}`+"\n", buf.String())
+	assert.Equal(t, "status", fields[0].Key, "the caller's fields are not reordered")
+}
+
+func TestSortFields_NoAllocationsWhenSorted(t *testing.T) {
+	fields := []Field{String("a", "1"), Int("b", 2)}
+	allocs := testing.AllocsPerRun(100, func() {
+		sortFields(fields)
+	})
+	assert.Zero(t, allocs)
+}
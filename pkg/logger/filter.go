@@ -0,0 +1,17 @@
+package logger
+
+// filter applies Config.Filter and Config.Transform to an entry and
+// reports whether it should be written. The functions get a copy of
+// fields, so that the caller's fields stay on the stack when they are
+// not set.
+func (l *Logger) filter(level Level, msg string, fields []Field) (string, []Field, bool) {
+	copied := append([]Field(nil), fields...)
+
+	if f := l.config.Filter; f != nil && !f(level, msg, copied) {
+		return msg, nil, false
+	}
+	if t := l.config.Transform; t != nil {
+		return t(level, msg, copied)
+	}
+	return msg, copied, true
+}
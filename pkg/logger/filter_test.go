@@ -0,0 +1,60 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_Filter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(Config{
+		Format:   JSONFormat,
+		Output:   buf,
+		OmitTime: true,
+		Filter: func(level Level, msg string, fields []Field) bool {
+			for _, f := range fields {
+				if f.Key == "path" && f.Interface() == "/healthz" {
+					return false
+				}
+			}
+			return true
+		},
+	})
+
+	logger.Info("request", String("path", "/healthz"))
+	assert.Empty(t, buf.String())
+
+	logger.Info("request", String("path", "/orders"))
+	assert.Equal(t, `{"level":"INFO","message":"request","path":"/orders"}`+"\n", buf.String())
+}
+
+func TestConfig_Transform(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(Config{
+		Format:   JSONFormat,
+		Output:   buf,
+		OmitTime: true,
+		Transform: func(level Level, msg string, fields []Field) (string, []Field, bool) {
+			if msg == "drop" {
+				return msg, fields, false
+			}
+			for i, f := range fields {
+				if f.Key == "user" {
+					fields[i] = String("user", "anonymous")
+				}
+			}
+			return "rewritten: " + msg, append(fields, String("env", "prod")), true
+		},
+	})
+
+	fields := []Field{String("user", "alice")}
+	logger.Info("login", fields...)
+	assert.Equal(t, `{"level":"INFO","message":"rewritten: login","user":"anonymous","env":"prod"}`+"\n", buf.String())
+	assert.Equal(t, "alice", fields[0].Interface(), "caller's fields must be left untouched")
+
+	buf.Reset()
+	logger.Info("drop")
+	assert.Empty(t, buf.String())
+}
@@ -0,0 +1,150 @@
+package logger
+
+import "sync"
+
+const (
+	// DefaultFlightRecorderKey is the field used when
+	// FlightRecorderConfig.KeyField is empty.
+	DefaultFlightRecorderKey = "trace_id"
+
+	// DefaultFlightRecorderSize is the number of entries kept when
+	// FlightRecorderConfig.Size is zero.
+	DefaultFlightRecorderSize = 1000
+
+	// DefaultFlightRecorderEntries is the number of entries written per
+	// error when FlightRecorderConfig.Entries is zero.
+	DefaultFlightRecorderEntries = 100
+)
+
+// FlightRecorderConfig configures the capture of debug context around
+// errors. Entries below the logger's level are not written but kept in
+// memory, grouped by the value of KeyField; when an entry at ErrorLevel or
+// above is logged, the entries kept for its trace or request are written
+// just before it. This gives error-adjacent debug context without paying
+// for debug volume normally:
+//
+//	log := logger.New(logger.Config{
+//		Level:          logger.InfoLevel,
+//		FlightRecorder: &logger.FlightRecorderConfig{KeyField: "request_id"},
+//	})
+//	reqLog := log.With(logger.String("request_id", id))
+//	reqLog.Debug("cache miss") // kept
+//	reqLog.Error("query failed") // writes "cache miss", then "query failed"
+//
+// Entries without the field are not kept. Kept entries are written at
+// their own level and time, and still go only to sinks that accept their
+// level.
+type FlightRecorderConfig struct {
+	// KeyField is the key of the field identifying the trace or request
+	// of an entry, bound with With or passed to the call.
+	// Defaults to DefaultFlightRecorderKey.
+	KeyField string
+
+	// Size is the number of entries kept across all traces; the oldest
+	// are evicted first. Defaults to DefaultFlightRecorderSize.
+	Size int
+
+	// Entries is the number of most recent entries of a trace written
+	// before an error. Defaults to DefaultFlightRecorderEntries.
+	Entries int
+}
+
+// flightRecorder keeps entries below the level, encoded by every encoder,
+// until an error of their trace writes them.
+type flightRecorder struct {
+	keyField string
+	limit    int
+
+	mu      sync.Mutex
+	entries []flightEntry
+	next    int
+}
+
+// flightEntry is an entry kept by a flightRecorder. data holds the entry
+// as encoded by each of core.encs, or nil for encoders without a sink
+// accepting its level. An empty key marks a free or written slot.
+type flightEntry struct {
+	key   string
+	level Level
+	data  [][]byte
+}
+
+func newFlightRecorder(config *FlightRecorderConfig) *flightRecorder {
+	keyField := config.KeyField
+	if keyField == "" {
+		keyField = DefaultFlightRecorderKey
+	}
+	size := config.Size
+	if size <= 0 {
+		size = DefaultFlightRecorderSize
+	}
+	limit := config.Entries
+	if limit <= 0 {
+		limit = DefaultFlightRecorderEntries
+	}
+
+	return &flightRecorder{
+		keyField: keyField,
+		limit:    limit,
+		entries:  make([]flightEntry, size),
+	}
+}
+
+// key returns the value of the key field of an entry of l, taken from
+// fields or else from the fields bound to l, or "" if it has none.
+func (r *flightRecorder) key(l *Logger, fields []Field) string {
+	for i := range fields {
+		if fields[i].Key == r.keyField {
+			return fieldText(fields[i])
+		}
+	}
+	return l.flightKey
+}
+
+// record keeps an entry of the trace key.
+func (r *flightRecorder) record(key string, level Level, data [][]byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries[r.next] = flightEntry{key: key, level: level, data: data}
+	r.next = (r.next + 1) % len(r.entries)
+}
+
+// take removes the entries kept for the trace key and returns the most
+// recent of them, oldest first. Older entries are discarded, so that they
+// are not written after the error.
+func (r *flightRecorder) take(key string) []flightEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var taken []flightEntry
+	for i := 1; i <= len(r.entries); i++ {
+		e := &r.entries[(r.next-i+len(r.entries))%len(r.entries)]
+		if e.key != key {
+			continue
+		}
+		if len(taken) < r.limit {
+			taken = append(taken, *e)
+		}
+		*e = flightEntry{}
+	}
+
+	for i, j := 0, len(taken)-1; i < j; i, j = i+1, j-1 {
+		taken[i], taken[j] = taken[j], taken[i]
+	}
+	return taken
+}
+
+// writeFlight writes the entries kept for the trace key ahead of an error
+// of l.
+func (l *Logger) writeFlight(key string) {
+	for _, e := range l.core.recorder.take(key) {
+		for i, data := range e.data {
+			if data == nil {
+				continue
+			}
+			bufPtr := l.core.pool.get()
+			l.output(bufPtr, append((*bufPtr)[:0], data...), e.level, i)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_FlightRecorder(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(Config{
+		Level:          InfoLevel,
+		Format:         JSONFormat,
+		Output:         buf,
+		OmitTime:       true,
+		FlightRecorder: &FlightRecorderConfig{KeyField: "request_id", Entries: 2},
+	})
+
+	req1 := logger.With(String("request_id", "r1"))
+	req2 := logger.With(String("request_id", "r2"))
+
+	req1.Debug("one")
+	req2.Debug("other request")
+	logger.Debug("no request")
+	req1.Debug("two")
+	logger.Debug("three", String("request_id", "r1"))
+	assert.Empty(t, buf.String())
+
+	req1.Info("written")
+	req1.Error("failed")
+	req1.Error("failed again")
+
+	assert.Equal(t, strings.Join([]string{
+		`{"level":"INFO","message":"written","request_id":"r1"}`,
+		`{"level":"DEBUG","message":"two","request_id":"r1"}`,
+		`{"level":"DEBUG","message":"three","request_id":"r1"}`,
+		`{"level":"ERROR","message":"failed","request_id":"r1"}`,
+		`{"level":"ERROR","message":"failed again","request_id":"r1"}`,
+	}, "\n")+"\n", buf.String())
+
+	buf.Reset()
+	logger.Error("failed", String("request_id", "r2"))
+	assert.Equal(t, strings.Join([]string{
+		`{"level":"DEBUG","message":"other request","request_id":"r2"}`,
+		`{"level":"ERROR","message":"failed","request_id":"r2"}`,
+	}, "\n")+"\n", buf.String())
+}
+
+func TestConfig_FlightRecorderSinks(t *testing.T) {
+	debug := &bytes.Buffer{}
+	errors := &bytes.Buffer{}
+	logger := New(Config{
+		Level:    WarnLevel,
+		OmitTime: true,
+		Sinks: []Sink{
+			{Output: debug, Level: DebugLevel, Format: JSONFormat},
+			{Output: errors, Level: ErrorLevel, Format: TextFormat},
+		},
+		FlightRecorder: &FlightRecorderConfig{Size: 1},
+	})
+
+	logger.Debug("evicted", String("trace_id", "t1"))
+	logger.Info("kept", String("trace_id", "t1"))
+	logger.Error("failed", String("trace_id", "t1"))
+
+	assert.Equal(t, `{"level":"INFO","message":"kept","trace_id":"t1"}`+"\n"+
+		`{"level":"ERROR","message":"failed","trace_id":"t1"}`+"\n", debug.String())
+	assert.Equal(t, "ERROR failed trace_id=t1\n", errors.String())
+}
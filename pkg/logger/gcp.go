@@ -0,0 +1,176 @@
+package logger
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Keys of the Cloud Logging special fields written by GCPFormat.
+const (
+	GCPTraceKey          = "logging.googleapis.com/trace"
+	GCPSpanIDKey         = "logging.googleapis.com/spanId"
+	GCPTraceSampledKey   = "logging.googleapis.com/trace_sampled"
+	GCPSourceLocationKey = "logging.googleapis.com/sourceLocation"
+	GCPHTTPRequestKey    = "httpRequest"
+)
+
+// gcpFieldKeys maps field keys to the Cloud Logging special fields they
+// are written as.
+var gcpFieldKeys = map[string]string{
+	"trace_id":      GCPTraceKey,
+	"span_id":       GCPSpanIDKey,
+	"trace_sampled": GCPTraceSampledKey,
+}
+
+// gcpEncoder is the Encoder used for GCPFormat.
+type gcpEncoder struct {
+	encoderOptions
+}
+
+// EncodeEntry formats a log entry as a Cloud Logging structured JSON
+// payload: "time", "severity" and "message", then the caller as
+// "logging.googleapis.com/sourceLocation" (when known) and the fields.
+// Config.TimeFormat and TimeKey do not apply.
+func (enc gcpEncoder) EncodeEntry(buf []byte, e *Entry) []byte {
+	start := len(buf)
+	buf = append(buf, '{')
+
+	if !enc.omitTime {
+		buf = append(buf, `"time":"`...)
+		buf = appendTime(buf, e.Time)
+		buf = append(buf, `",`...)
+	}
+
+	buf = append(buf, `"severity":"`...)
+	buf = append(buf, gcpSeverity(e.Level)...)
+	buf = append(buf, `","message":"`...)
+	buf = appendJSONString(buf, e.Message)
+	buf = append(buf, '"')
+
+	if e.Caller != nil {
+		buf = append(buf, `,"`+GCPSourceLocationKey+`":{"file":"`...)
+		buf = appendJSONString(buf, e.Caller.File)
+		buf = append(buf, `","line":"`...)
+		buf = appendInt(buf, int64(e.Caller.Line))
+		buf = append(buf, `","function":"`...)
+		buf = appendJSONString(buf, e.Caller.Function)
+		buf = append(buf, `"}`...)
+	}
+
+	buf = append(buf, e.Context...)
+	buf = enc.EncodeFields(buf, e.Fields)
+
+	buf = append(buf, '}')
+	if enc.escapeHTML {
+		buf = appendHTMLEscaped(buf, start)
+	}
+	return buf
+}
+
+// EncodeFields appends fields as comma-prefixed JSON object members.
+// "trace_id", "span_id" and "trace_sampled" fields are written as the
+// Cloud Logging trace fields; with Config.GCPProjectID set, trace IDs are
+// expanded to "projects/<id>/traces/<trace_id>".
+func (enc gcpEncoder) EncodeFields(buf []byte, fields []Field) []byte {
+	for i := range fields {
+		key, ok := gcpFieldKeys[fields[i].Key]
+		if !ok {
+			if enc.lossyFloats {
+				buf = appendLossyJSONFields(buf, fields[i:i+1])
+			} else {
+				buf = appendJSONFields(buf, fields[i:i+1])
+			}
+			continue
+		}
+
+		buf = append(buf, ',', '"')
+		buf = append(buf, key...)
+		buf = append(buf, '"', ':')
+		if key == GCPTraceKey && enc.gcpProjectID != "" && fields[i].kind == stringKind &&
+			!strings.HasPrefix(fields[i].str, "projects/") {
+			buf = append(buf, `"projects/`...)
+			buf = appendJSONString(buf, enc.gcpProjectID)
+			buf = append(buf, `/traces/`...)
+			buf = appendJSONString(buf, fields[i].str)
+			buf = append(buf, '"')
+			continue
+		}
+		buf = appendJSONFieldValue(buf, fields[i])
+	}
+	return buf
+}
+
+// gcpSeverity returns the Cloud Logging severity of level.
+func gcpSeverity(level Level) string {
+	switch level {
+	case DebugLevel:
+		return "DEBUG"
+	case InfoLevel:
+		return "INFO"
+	case WarnLevel:
+		return "WARNING"
+	case ErrorLevel:
+		return "ERROR"
+	case PanicLevel:
+		return "ALERT"
+	case FatalLevel:
+		return "EMERGENCY"
+	default:
+		return "DEFAULT"
+	}
+}
+
+// HTTPRequest describes an HTTP request in the layout of Cloud Logging's
+// httpRequest field. Log it with HTTP; zero-valued members are left out.
+type HTTPRequest struct {
+	RequestMethod string
+	RequestURL    string
+	RequestSize   int64
+	Status        int
+	ResponseSize  int64
+	UserAgent     string
+	RemoteIP      string
+	ServerIP      string
+	Referer       string
+	Latency       time.Duration
+	Protocol      string
+}
+
+// HTTP constructs a field with the key "httpRequest" holding req, which
+// GCPFormat entries carry as Cloud Logging's httpRequest.
+func HTTP(req HTTPRequest) Field {
+	return Object(GCPHTTPRequestKey, req)
+}
+
+// MarshalLogObject implements LogObjectMarshaler. Sizes are written as
+// strings and the latency as seconds with an "s" suffix, as Cloud Logging
+// expects.
+func (r HTTPRequest) MarshalLogObject(enc ObjectEncoder) error {
+	addString := func(key, val string) {
+		if val != "" {
+			enc.AddString(key, val)
+		}
+	}
+
+	addString("requestMethod", r.RequestMethod)
+	addString("requestUrl", r.RequestURL)
+	if r.RequestSize > 0 {
+		enc.AddString("requestSize", strconv.FormatInt(r.RequestSize, 10))
+	}
+	if r.Status != 0 {
+		enc.AddInt("status", r.Status)
+	}
+	if r.ResponseSize > 0 {
+		enc.AddString("responseSize", strconv.FormatInt(r.ResponseSize, 10))
+	}
+	addString("userAgent", r.UserAgent)
+	addString("remoteIp", r.RemoteIP)
+	addString("serverIp", r.ServerIP)
+	addString("referer", r.Referer)
+	if r.Latency > 0 {
+		enc.AddString("latency", strconv.FormatFloat(r.Latency.Seconds(), 'f', -1, 64)+"s")
+	}
+	addString("protocol", r.Protocol)
+	return nil
+}
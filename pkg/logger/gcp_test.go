@@ -0,0 +1,106 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger_GCPFormat(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:        InfoLevel,
+		Format:       GCPFormat,
+		Output:       buf,
+		AddCaller:    true,
+		GCPProjectID: "my-project",
+	})
+
+	logger.With(String("trace_id", "4bf92f35")).Warn("slow request",
+		String("span_id", "00f067aa"),
+		Bool("trace_sampled", true),
+		Int("userID", 12345),
+	)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry), buf.String())
+
+	assert.Contains(t, entry, "time")
+	assert.Equal(t, "WARNING", entry["severity"])
+	assert.Equal(t, "slow request", entry["message"])
+	assert.Equal(t, "projects/my-project/traces/4bf92f35", entry[GCPTraceKey])
+	assert.Equal(t, "00f067aa", entry[GCPSpanIDKey])
+	assert.Equal(t, true, entry[GCPTraceSampledKey])
+	assert.Equal(t, float64(12345), entry["userID"])
+
+	location, ok := entry[GCPSourceLocationKey].(map[string]interface{})
+	require.True(t, ok)
+	assert.Contains(t, location["file"], "logger/gcp_test.go")
+	assert.IsType(t, "", location["line"])
+	assert.Contains(t, location["function"], "TestLogger_GCPFormat")
+}
+
+func TestLogger_GCPFormatTrace(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:    InfoLevel,
+		Format:   GCPFormat,
+		Output:   buf,
+		OmitTime: true,
+	})
+
+	logger.Info("no project", String("trace_id", "4bf92f35"))
+
+	assert.Equal(t, `{"severity":"INFO","message":"no project","logging.googleapis.com/trace":"4bf92f35"}`+"\n", buf.String())
+}
+
+func TestLogger_GCPFormatHTTPRequest(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: GCPFormat,
+		Output: buf,
+	})
+
+	logger.Info("request", HTTP(HTTPRequest{
+		RequestMethod: "GET",
+		RequestURL:    "/users/42",
+		Status:        200,
+		ResponseSize:  1024,
+		UserAgent:     "curl/8.0",
+		RemoteIP:      "10.0.0.1",
+		Latency:       1500 * time.Millisecond,
+		Protocol:      "HTTP/1.1",
+	}))
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry), buf.String())
+
+	assert.Equal(t, map[string]interface{}{
+		"requestMethod": "GET",
+		"requestUrl":    "/users/42",
+		"status":        float64(200),
+		"responseSize":  "1024",
+		"userAgent":     "curl/8.0",
+		"remoteIp":      "10.0.0.1",
+		"latency":       "1.5s",
+		"protocol":      "HTTP/1.1",
+	}, entry[GCPHTTPRequestKey])
+}
+
+func TestGCPSeverity(t *testing.T) {
+	assert.Equal(t, "DEBUG", gcpSeverity(DebugLevel))
+	assert.Equal(t, "INFO", gcpSeverity(InfoLevel))
+	assert.Equal(t, "WARNING", gcpSeverity(WarnLevel))
+	assert.Equal(t, "ERROR", gcpSeverity(ErrorLevel))
+	assert.Equal(t, "ALERT", gcpSeverity(PanicLevel))
+	assert.Equal(t, "EMERGENCY", gcpSeverity(FatalLevel))
+	assert.Equal(t, "DEFAULT", gcpSeverity(Level(42)))
+}
@@ -0,0 +1,43 @@
+package logger
+
+import "runtime"
+
+// Keys of the fields that tell apart the entries of concurrent goroutines.
+const (
+	// GoroutineKey is the key of the goroutine ID added by
+	// Config.AddGoroutineID.
+	GoroutineKey = "goroutine"
+
+	// WorkerKey is the key of Worker fields.
+	WorkerKey = "worker"
+)
+
+// Worker returns a field labeling entries with the name of the worker
+// that logs them. Bind it with Logger.With in each worker goroutine, as a
+// stable alternative to Config.AddGoroutineID:
+//
+//	log := log.With(logger.Worker("fetcher-" + strconv.Itoa(i)))
+func Worker(label string) Field {
+	return String(WorkerKey, label)
+}
+
+// goroutineID returns the ID of the calling goroutine, read from the
+// header of its stack trace, "goroutine 42 [running]:", or 0 if it cannot
+// be parsed.
+func goroutineID() int64 {
+	var buf [32]byte
+	n := runtime.Stack(buf[:], false)
+	const prefix = "goroutine "
+	if n <= len(prefix) || string(buf[:len(prefix)]) != prefix {
+		return 0
+	}
+
+	var id int64
+	for _, c := range buf[len(prefix):n] {
+		if c < '0' || c > '9' {
+			break
+		}
+		id = id*10 + int64(c-'0')
+	}
+	return id
+}
@@ -0,0 +1,60 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoroutineID(t *testing.T) {
+	// The ID is the number in the header of the goroutine's stack trace.
+	stack := make([]byte, 64)
+	stack = stack[:runtime.Stack(stack, false)]
+	header, _, _ := strings.Cut(strings.TrimPrefix(string(stack), "goroutine "), " ")
+	want, err := strconv.ParseInt(header, 10, 64)
+	require.NoError(t, err)
+	assert.Equal(t, want, goroutineID())
+
+	ids := make(chan int64, 2)
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ids <- goroutineID()
+		}()
+	}
+	wg.Wait()
+	close(ids)
+	a, b := <-ids, <-ids
+	assert.NotZero(t, a)
+	assert.NotEqual(t, a, b)
+	assert.NotEqual(t, want, a)
+}
+
+func TestConfig_AddGoroutineID(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := New(Config{Level: InfoLevel, Format: JSONFormat, Output: buf, OmitTime: true, AddGoroutineID: true})
+
+	log.Info("request", Int("status", 200))
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, float64(goroutineID()), entry[GoroutineKey])
+	assert.Equal(t, float64(200), entry["status"])
+}
+
+func TestWorker(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := New(Config{Level: InfoLevel, Format: TextFormat, Output: buf, OmitTime: true})
+
+	log.With(Worker("fetcher-3")).Info("fetched")
+	assert.Equal(t, "INFO fetched worker=fetcher-3\n", buf.String())
+}
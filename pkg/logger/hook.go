@@ -0,0 +1,65 @@
+package logger
+
+import (
+	"errors"
+)
+
+// ErrDropEntry can be returned by a Hook to discard the entry. Hooks after
+// the one that returned it are not run and nothing is written.
+var ErrDropEntry = errors.New("logger: entry dropped by hook")
+
+// Hook is run for every entry that passes the level check, before it is
+// encoded. Hooks may modify the entry in place, for example to add or
+// redact fields, forward it to an external system, or return ErrDropEntry
+// to filter it out. Any other error is ignored and the entry is still
+// written.
+//
+// Hooks are called on the logging goroutine and must be safe for concurrent use.
+type Hook interface {
+	Run(e *Entry) error
+}
+
+// HookFunc adapts an ordinary function to the Hook interface.
+type HookFunc func(e *Entry) error
+
+// Run calls f(e).
+func (f HookFunc) Run(e *Entry) error {
+	return f(e)
+}
+
+// AddHook registers hooks that run, in the order they were added, for every
+// entry written by the logger and by every logger derived from it.
+// It is safe to call while other goroutines are logging.
+//
+// Example:
+//
+//	logger.AddHook(logger.HookFunc(func(e *logger.Entry) error {
+//		if e.Level >= logger.ErrorLevel {
+//			errorCounter.Inc()
+//		}
+//		return nil
+//	}))
+func (l *Logger) AddHook(hooks ...Hook) {
+	l.core.mu.Lock()
+	defer l.core.mu.Unlock()
+
+	var current []Hook
+	if p := l.core.hooks.Load(); p != nil {
+		current = *p
+	}
+
+	next := make([]Hook, 0, len(current)+len(hooks))
+	next = append(next, current...)
+	next = append(next, hooks...)
+	l.core.hooks.Store(&next)
+}
+
+// runHooks runs hooks on e and reports whether the entry should be written.
+func runHooks(hooks []Hook, e *Entry) bool {
+	for _, h := range hooks {
+		if err := h.Run(e); errors.Is(err, ErrDropEntry) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,99 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger_HookEnrichesEntry(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: JSONFormat,
+		Output: buf,
+	})
+
+	logger.AddHook(HookFunc(func(e *Entry) error {
+		e.Fields = append(e.Fields, String("hooked", "yes"))
+		e.Message = "rewritten " + e.Message
+		return nil
+	}))
+
+	logger.With(String("service", "billing")).Info("message", Int("n", 1))
+
+	output := buf.String()
+	assert.Contains(t, output, `"message":"rewritten message","service":"billing","n":1,"hooked":"yes"`)
+}
+
+func TestLogger_HookDropsEntry(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: TextFormat,
+		Output: buf,
+	})
+
+	var after atomic.Int32
+	logger.AddHook(
+		HookFunc(func(e *Entry) error {
+			if e.Message == "noisy" {
+				return ErrDropEntry
+			}
+			return nil
+		}),
+		HookFunc(func(e *Entry) error {
+			after.Add(1)
+			return nil
+		}),
+	)
+
+	logger.Info("noisy")
+	logger.Info("kept")
+
+	assert.NotContains(t, buf.String(), "noisy")
+	assert.Contains(t, buf.String(), "kept")
+	assert.Equal(t, int32(1), after.Load())
+}
+
+func TestLogger_HookErrorIgnored(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: TextFormat,
+		Output: buf,
+	})
+
+	logger.AddHook(HookFunc(func(e *Entry) error {
+		return errors.New("sentry unavailable")
+	}))
+
+	logger.Error("still written")
+
+	assert.Contains(t, buf.String(), "still written")
+}
+
+func TestLogger_HookSkippedBelowLevel(t *testing.T) {
+	logger := New(Config{
+		Level:  WarnLevel,
+		Format: TextFormat,
+		Output: &bytes.Buffer{},
+	})
+
+	var calls atomic.Int32
+	logger.AddHook(HookFunc(func(e *Entry) error {
+		calls.Add(1)
+		return nil
+	}))
+
+	logger.Info("filtered")
+	logger.Warn("passed")
+
+	assert.Equal(t, int32(1), calls.Load())
+}
@@ -1,87 +1,238 @@
 package logger
 
 import (
+	"fmt"
+	"math"
+	"strconv"
 	"time"
+	"unicode/utf8"
 )
 
-// appendJSON formats a log entry in JSON format and appends it to the buffer.
-// It creates a JSON object with timestamp, level, message, and any additional fields.
+const hexDigits = "0123456789abcdef"
+
+// jsonEncoder is the Encoder used for JSONFormat.
+type jsonEncoder struct {
+	encoderOptions
+}
+
+// EncodeEntry formats a log entry in JSON format and appends it to the buffer.
+// It creates a JSON object with timestamp, level, message, caller (when
+// known), and any additional fields, under the keys set by Config.Keys.
 // This method is optimized for minimal allocations using buffer operations.
-func (l *Logger) appendJSON(buf []byte, level Level, msg string, fields ...Field) []byte {
+func (enc jsonEncoder) EncodeEntry(buf []byte, e *Entry) []byte {
+	start := len(buf)
 	buf = append(buf, '{')
 
-	now := time.Now()
-	if l.config.UseUTC {
-		now = now.UTC()
+	if !enc.omitTime {
+		buf = appendKey(buf, enc.keys.Time, DefaultTimeKey)
+		buf = enc.appendTimestamp(buf, e.Time, true)
+		buf = append(buf, ',')
 	}
 
-	buf = append(buf, `"timestamp":"`...)
-	buf = append(buf, now.Format(DefaultTimeFormat)...)
-	buf = append(buf, '"')
+	buf = appendKey(buf, enc.keys.Level, DefaultLevelKey)
+	if level, number := enc.levelText(e.Level); number {
+		buf = append(buf, level...)
+	} else {
+		buf = append(buf, '"')
+		buf = append(buf, level...)
+		buf = append(buf, '"')
+	}
+	buf = append(buf, ',')
 
-	buf = append(buf, `,"level":"`...)
-	buf = append(buf, level.String()...)
+	buf = appendKey(buf, enc.keys.Message, DefaultMessageKey)
 	buf = append(buf, '"')
-
-	buf = append(buf, `,"message":"`...)
-	buf = appendJSONString(buf, msg)
+	buf = appendJSONString(buf, e.Message)
 	buf = append(buf, '"')
 
+	if e.Caller != nil {
+		buf = append(buf, ',')
+		buf = appendKey(buf, enc.keys.Caller, DefaultCallerKey)
+		buf = append(buf, '"')
+		buf = appendJSONString(buf, e.Caller.File)
+		buf = append(buf, ':')
+		buf = appendInt(buf, int64(e.Caller.Line))
+		buf = append(buf, `","function":"`...)
+		buf = appendJSONString(buf, e.Caller.Function)
+		buf = append(buf, '"')
+	}
+
+	buf = append(buf, e.Context...)
+	buf = enc.EncodeFields(buf, e.Fields)
+
+	buf = append(buf, '}')
+	if enc.escapeHTML {
+		buf = appendHTMLEscaped(buf, start)
+	}
+	return buf
+}
+
+// EncodeFields appends fields as comma-prefixed JSON object members.
+func (enc jsonEncoder) EncodeFields(buf []byte, fields []Field) []byte {
+	if enc.lossyFloats {
+		return appendLossyJSONFields(buf, fields)
+	}
+	return appendJSONFields(buf, fields)
+}
+
+// appendJSONFields appends fields as comma-prefixed JSON object members.
+func appendJSONFields(buf []byte, fields []Field) []byte {
 	for _, field := range fields {
 		buf = append(buf, ',', '"')
 		buf = appendJSONString(buf, field.Key)
 		buf = append(buf, '"', ':')
-		buf = appendJSONValue(buf, field.Value)
+		buf = appendJSONFieldValue(buf, field)
+		if field.kind == errorKind {
+			buf = appendErrorDetailsJSON(buf, &field)
+		}
 	}
+	return buf
+}
 
-	buf = append(buf, '}')
+// appendLossyJSONFields is appendJSONFields with Float64 fields encoded by
+// appendLossyJSONFloat.
+func appendLossyJSONFields(buf []byte, fields []Field) []byte {
+	for i := range fields {
+		if fields[i].kind != float64Kind {
+			buf = appendJSONFields(buf, fields[i:i+1])
+			continue
+		}
+		buf = append(buf, ',', '"')
+		buf = appendJSONString(buf, fields[i].Key)
+		buf = append(buf, '"', ':')
+		buf = appendLossyJSONFloat(buf, math.Float64frombits(uint64(fields[i].num)))
+	}
 	return buf
 }
 
 // appendJSONString escapes and appends a string value to the JSON buffer.
-// It handles JSON string escaping for quotes, backslashes, and control characters.
-// This function is optimized for performance with minimal allocations.
+// Quotes and backslashes are escaped, control characters are written as
+// \n, \r, \t or \u00XX, and invalid UTF-8 bytes are replaced by \ufffd so
+// that the output is always valid JSON.
+// Runs of characters that need no escaping are copied in one append.
 func appendJSONString(buf []byte, s string) []byte {
-	for _, r := range []byte(s) {
-		switch r {
-		case '"':
-			buf = append(buf, '\\', '"')
-		case '\\':
-			buf = append(buf, '\\', '\\')
-		case '\n':
-			buf = append(buf, '\\', 'n')
-		case '\r':
-			buf = append(buf, '\\', 'r')
-		case '\t':
-			buf = append(buf, '\\', 't')
-		default:
-			buf = append(buf, r)
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			buf = append(buf, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				buf = append(buf, '\\', c)
+			case '\n':
+				buf = append(buf, '\\', 'n')
+			case '\r':
+				buf = append(buf, '\\', 'r')
+			case '\t':
+				buf = append(buf, '\\', 't')
+			default:
+				buf = append(buf, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			}
+			i++
+			start = i
+			continue
 		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, `\ufffd`...)
+			i++
+			start = i
+			continue
+		}
+		i += size
+	}
+	return append(buf, s[start:]...)
+}
+
+// appendHTMLEscaped escapes <, >, & and the line separators U+2028 and
+// U+2029 in buf[start:] as \u003c, \u003e, \u0026, \u2028 and \u2029, like
+// encoding/json does by default. buf[start:] must be JSON produced by this
+// package, where these characters only occur inside strings.
+func appendHTMLEscaped(buf []byte, start int) []byte {
+	i := start
+	for i < len(buf) && !needsHTMLEscape(buf, i) {
+		i++
+	}
+	if i == len(buf) {
+		return buf
+	}
+
+	tail := append([]byte(nil), buf[i:]...)
+	buf = buf[:i]
+	for j := 0; j < len(tail); j++ {
+		if !needsHTMLEscape(tail, j) {
+			buf = append(buf, tail[j])
+			continue
+		}
+		if c := tail[j]; c < utf8.RuneSelf {
+			buf = append(buf, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			continue
+		}
+		buf = append(buf, '\\', 'u', '2', '0', '2', hexDigits[tail[j+2]&0xf])
+		j += 2
 	}
 	return buf
 }
 
+// needsHTMLEscape reports whether buf[i] starts a character escaped by
+// appendHTMLEscaped.
+func needsHTMLEscape(buf []byte, i int) bool {
+	switch buf[i] {
+	case '<', '>', '&':
+		return true
+	case 0xe2:
+		// U+2028 and U+2029 are encoded as E2 80 A8 and E2 80 A9.
+		return i+2 < len(buf) && buf[i+1] == 0x80 && buf[i+2]&^1 == 0xa8
+	default:
+		return false
+	}
+}
+
 // appendJSONValue appends a typed value to the JSON buffer with proper JSON formatting.
-// It supports string, int, int64, float64, and bool types. Unknown types are
-// represented as the string "unknown".
+// It supports strings, booleans, all integer and float types, time.Time,
+// time.Duration (as a string such as "1.5s") and []byte (as base64).
+// Unknown types are represented as the string "unknown".
 func appendJSONValue(buf []byte, value interface{}) []byte {
+	if out, ok := appendInteger(buf, value); ok {
+		return out
+	}
+
 	switch v := value.(type) {
 	case string:
 		buf = append(buf, '"')
 		buf = appendJSONString(buf, v)
 		buf = append(buf, '"')
-	case int:
-		buf = appendInt(buf, int64(v))
-	case int64:
-		buf = appendInt(buf, v)
 	case float64:
-		buf = appendJSONFloat(buf, v)
+		buf = appendJSONFloat(buf, v, 64)
+	case float32:
+		buf = appendJSONFloat(buf, float64(v), 32)
 	case bool:
-		if v {
-			buf = append(buf, "true"...)
-		} else {
-			buf = append(buf, "false"...)
-		}
+		buf = appendBool(buf, v)
+	case time.Time:
+		buf = append(buf, '"')
+		buf = v.AppendFormat(buf, DefaultTimeFormat)
+		buf = append(buf, '"')
+	case time.Duration:
+		buf = append(buf, '"')
+		buf = append(buf, v.String()...)
+		buf = append(buf, '"')
+	case []byte:
+		buf = append(buf, '"')
+		buf = appendBase64(buf, v)
+		buf = append(buf, '"')
+	case error:
+		buf = append(buf, '"')
+		buf = appendJSONString(buf, v.Error())
+		buf = append(buf, '"')
+	case fmt.Stringer:
+		buf = append(buf, '"')
+		buf = appendJSONString(buf, stringerText(v))
+		buf = append(buf, '"')
 	default:
 		buf = append(buf, '"')
 		buf = appendJSONString(buf, "unknown")
@@ -90,29 +241,42 @@ func appendJSONValue(buf []byte, value interface{}) []byte {
 	return buf
 }
 
-// appendJSONFloat appends a float64 value to the JSON buffer.
-// It provides basic float formatting with 3 decimal places precision for the
-// fractional part. This is optimized for performance over full precision.
-func appendJSONFloat(buf []byte, f float64) []byte {
-	if f == 0.0 {
-		return append(buf, '0')
+// appendJSONFloat appends f as a JSON number using the shortest
+// representation that round-trips at the given bit size (32 or 64).
+// Like encoding/json, it switches to exponent notation for very small and
+// very large magnitudes. NaN and infinities, which JSON numbers cannot
+// represent, are written as the strings "NaN", "+Inf" and "-Inf".
+func appendJSONFloat(buf []byte, f float64, bitSize int) []byte {
+	switch {
+	case math.IsNaN(f):
+		return append(buf, `"NaN"`...)
+	case math.IsInf(f, 1):
+		return append(buf, `"+Inf"`...)
+	case math.IsInf(f, -1):
+		return append(buf, `"-Inf"`...)
 	}
 
-	if f < 0 {
-		buf = append(buf, '-')
-		f = -f
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bitSize == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) ||
+			bitSize == 64 && (abs < 1e-6 || abs >= 1e21) {
+			format = 'e'
+		}
 	}
+	return strconv.AppendFloat(buf, f, format, -1, bitSize)
+}
 
-	integer := int64(f)
-	fractional := f - float64(integer)
-
-	buf = appendInt(buf, integer)
-
-	if fractional > 0 {
-		buf = append(buf, '.')
-		fractional *= 1000
-		buf = appendInt(buf, int64(fractional))
+// appendLossyJSONFloat appends f with at most three decimal places,
+// truncating the rest. Values it cannot represent this way are encoded
+// by appendJSONFloat.
+func appendLossyJSONFloat(buf []byte, f float64) []byte {
+	if math.IsNaN(f) || math.IsInf(f, 0) || f >= math.MaxInt64 || f <= math.MinInt64 {
+		return appendJSONFloat(buf, f, 64)
 	}
-
-	return buf
+	f = math.Trunc(f*1000) / 1000
+	if f == 0 {
+		// Also for -0, from small negative values.
+		return append(buf, '0')
+	}
+	return strconv.AppendFloat(buf, f, 'f', -1, 64)
 }
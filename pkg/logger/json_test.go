@@ -0,0 +1,177 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendJSONFloat(t *testing.T) {
+	tests := []struct {
+		name string
+		in   float64
+		bits int
+		want string
+	}{
+		{"zero", 0, 64, "0"},
+		{"integer", 42, 64, "42"},
+		{"full precision", 0.123456789, 64, "0.123456789"},
+		{"small fraction", 3.0001, 64, "3.0001"},
+		{"negative", -2.5, 64, "-2.5"},
+		{"large", 1e20, 64, "100000000000000000000"},
+		{"very large", 1.5e300, 64, "1.5e+300"},
+		{"very small", 1e-9, 64, "1e-09"},
+		{"max", math.MaxFloat64, 64, "1.7976931348623157e+308"},
+		{"float32", float64(float32(0.1)), 32, "0.1"},
+		{"NaN", math.NaN(), 64, `"NaN"`},
+		{"+Inf", math.Inf(1), 64, `"+Inf"`},
+		{"-Inf", math.Inf(-1), 64, `"-Inf"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := appendJSONFloat(nil, tt.in, tt.bits)
+			assert.Equal(t, tt.want, string(got))
+			assert.True(t, json.Valid(got))
+		})
+	}
+}
+
+func TestJSONFloat_RoundTrips(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: JSONFormat,
+		Output: buf,
+	})
+
+	values := []float64{0.1 + 0.2, 123456.789012345, -1e-7, 6.02214076e23}
+	for _, v := range values {
+		buf.Reset()
+		logger.Info("metric", Float64("value", v))
+
+		var entry struct {
+			Value float64 `json:"value"`
+		}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, v, entry.Value)
+	}
+}
+
+func TestJSONFloat_Lossy(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:       InfoLevel,
+		Format:      JSONFormat,
+		Output:      buf,
+		LossyFloats: true,
+	})
+
+	logger.With(Float64("bound", 1.23456)).Info("metric",
+		Float64("ratio", 0.123456),
+		Float64("nan", math.NaN()),
+		Float64("huge", 1e30),
+		String("s", "v"),
+	)
+
+	output := buf.String()
+	assert.Contains(t, output, `"bound":1.234`)
+	assert.Contains(t, output, `"ratio":0.123,"nan":"NaN","huge":1e+30,"s":"v"`)
+	assert.True(t, json.Valid(bytes.TrimSpace(buf.Bytes())))
+}
+
+func TestAppendLossyJSONFloat(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want string
+	}{
+		{0, "0"},
+		{1, "1"},
+		{1.05, "1.05"},
+		{0.001, "0.001"},
+		{0.0009, "0"},
+		{12.3456, "12.345"},
+		{2.5, "2.5"},
+		{-1.05, "-1.05"},
+		{-0.0019, "-0.001"},
+		{-0.0001, "0"},
+		{-7.89999, "-7.899"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, string(appendLossyJSONFloat(nil, tt.in)), "%v", tt.in)
+	}
+}
+
+func TestAppendJSONString(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "hello world", "hello world"},
+		{"quotes and backslash", `say "hi" \ bye`, `say \"hi\" \\ bye`},
+		{"common controls", "a\nb\rc\td", `a\nb\rc\td`},
+		{"other controls", "\x00\x01\x1f", `\u0000\u0001\u001f`},
+		{"multibyte", "héllo, 世界 🙂", "héllo, 世界 🙂"},
+		{"invalid utf8", "bad\xffbyte\xc3", `bad\ufffdbyte\ufffd`},
+		{"html untouched", "<a href='x'>&</a>", "<a href='x'>&</a>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := appendJSONString(nil, tt.in)
+			assert.Equal(t, tt.want, string(got))
+			assert.True(t, json.Valid([]byte(`"`+string(got)+`"`)))
+		})
+	}
+}
+
+func TestJSONString_ValidOutput(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: JSONFormat,
+		Output: buf,
+	})
+
+	logger.With(String("bound\x02", "\x1b[31m")).Info("msg\x01\xfe", String("k\x7f", "v\x00"))
+
+	line := bytes.TrimSpace(buf.Bytes())
+	require.True(t, json.Valid(line), string(line))
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(line, &entry))
+	assert.Equal(t, "msg\x01�", entry["message"])
+	assert.Equal(t, "\x1b[31m", entry["bound\x02"])
+	assert.Equal(t, "v\x00", entry["k\x7f"])
+}
+
+func TestJSON_EscapeHTML(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:      InfoLevel,
+		Format:     JSONFormat,
+		Output:     buf,
+		EscapeHTML: true,
+	})
+
+	logger.With(String("bound", "a&b")).Info("<script>", String("sep", "x\u2028y\u2029z"))
+
+	output := buf.String()
+	assert.Contains(t, output, `"message":"\u003cscript\u003e"`)
+	assert.Contains(t, output, `"bound":"a\u0026b"`)
+	assert.Contains(t, output, `"sep":"x\u2028y\u2029z"`)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &entry))
+	assert.Equal(t, "<script>", entry["message"])
+	assert.Equal(t, "x\u2028y\u2029z", entry["sep"])
+}
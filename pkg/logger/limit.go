@@ -0,0 +1,95 @@
+package logger
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// TruncatedKey is the key of the field added to entries whose message or
+// field values were cut by Config.MaxValueBytes or Config.MaxEntryBytes.
+const TruncatedKey = "truncated"
+
+// truncate returns s cut to at most n bytes without splitting a UTF-8
+// sequence, and whether it was cut.
+func truncate(s string, n int) (string, bool) {
+	if len(s) <= n {
+		return s, false
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n], true
+}
+
+// limitValues cuts msg and the string and error values of fields to max
+// bytes, adding a TruncatedKey field if any was cut. fields is copied
+// before the first change, so that the caller's slice is left untouched.
+func limitValues(max int, msg string, fields []Field) (string, []Field) {
+	msg, cut := truncate(msg, max)
+
+	copied := false
+	for i := range fields {
+		f := &fields[i]
+
+		var s string
+		switch {
+		case f.kind == stringKind:
+			s = f.str
+		case f.kind == errorKind && f.Value != nil:
+			s = f.Value.(error).Error()
+		case f.kind == stringerKind:
+			s = stringerText(f.Value.(fmt.Stringer))
+		default:
+			continue
+		}
+
+		s, ok := truncate(s, max)
+		if !ok {
+			continue
+		}
+		if !copied {
+			fields = append(make([]Field, 0, len(fields)+1), fields...)
+			copied = true
+		}
+		fields[i] = String(f.Key, s)
+		cut = true
+	}
+
+	if cut {
+		fields = append(fields, Bool(TruncatedKey, true))
+	}
+	return msg, fields
+}
+
+// truncatedFields replaces the fields of entries cut by shrink.
+var truncatedFields = []Field{Bool(TruncatedKey, true)}
+
+// shrink re-encodes e, an entry that enc encoded into more than max bytes,
+// without its own fields and with its message cut so that it fits in about
+// max bytes. Bound fields are kept, so an entry can still exceed max if
+// they alone do.
+func shrink(enc Encoder, buf []byte, e *Entry, max int) []byte {
+	cut := *e
+	cut.Fields, cut.Message = truncatedFields, ""
+	buf = enc.EncodeEntry(buf[:0], &cut)
+
+	if room := max - len(buf); room > 0 && e.Message != "" {
+		cut.Message, _ = truncate(e.Message, room)
+		buf = enc.EncodeEntry(buf[:0], &cut)
+	}
+	return buf
+}
+
+// shrinkBuiltin is shrink for the built-in encoders, which it calls
+// through encodeBuiltin so that e does not escape.
+func shrinkBuiltin(enc Encoder, buf []byte, e *Entry, max int) []byte {
+	cut := *e
+	cut.Fields, cut.Message = truncatedFields, ""
+	buf = encodeBuiltin(enc, buf[:0], &cut)
+
+	if room := max - len(buf); room > 0 && e.Message != "" {
+		cut.Message, _ = truncate(e.Message, room)
+		buf = encodeBuiltin(enc, buf[:0], &cut)
+	}
+	return buf
+}
@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncate(t *testing.T) {
+	s, cut := truncate("héllo", 2)
+	assert.Equal(t, "h", s)
+	assert.True(t, cut)
+
+	s, cut = truncate("héllo", 3)
+	assert.Equal(t, "hé", s)
+	assert.True(t, cut)
+
+	s, cut = truncate("hello", 5)
+	assert.Equal(t, "hello", s)
+	assert.False(t, cut)
+}
+
+func TestConfig_MaxValueBytes(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(Config{Format: JSONFormat, Output: buf, OmitTime: true, MaxValueBytes: 4})
+
+	fields := []Field{String("body", "abcdefgh"), Err(errors.New("boom!")), Int("n", 123456)}
+	logger.Info("message", fields...)
+	assert.Equal(t, `{"level":"INFO","message":"mess","body":"abcd","error":"boom","n":123456,"truncated":true}`+"\n", buf.String())
+	assert.Equal(t, "abcdefgh", fields[0].str, "caller's fields must be left untouched")
+
+	buf.Reset()
+	logger.Info("ok", String("k", "v"))
+	assert.Equal(t, `{"level":"INFO","message":"ok","k":"v"}`+"\n", buf.String())
+
+	buf.Reset()
+	logger.With(String("bound", "abcdefgh")).Info("ok")
+	assert.Equal(t, `{"level":"INFO","message":"ok","bound":"abcd","truncated":true}`+"\n", buf.String())
+}
+
+func TestConfig_MaxEntryBytes(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(Config{Format: JSONFormat, Output: buf, OmitTime: true, MaxEntryBytes: 80})
+
+	logger.Info(strings.Repeat("x", 200), String("body", strings.Repeat("y", 200)))
+	line := bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+	assert.LessOrEqual(t, len(line), 80)
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(line, &entry))
+	assert.Equal(t, true, entry[TruncatedKey])
+	assert.NotContains(t, entry, "body")
+	assert.True(t, strings.HasPrefix(strings.Repeat("x", 200), entry["message"].(string)))
+	assert.NotEmpty(t, entry["message"])
+
+	buf.Reset()
+	logger.Info("short")
+	assert.Equal(t, `{"level":"INFO","message":"short"}`+"\n", buf.String())
+}
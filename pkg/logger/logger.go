@@ -19,16 +19,17 @@ package logger
 
 import (
 	"context"
+	"encoding/base64"
+	"fmt"
 	"io"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// contextKey is a custom type for context keys to avoid collisions.
-type contextKey string
-
 // Level represents the severity level of a log entry.
 // Lower values indicate more verbose logging.
 type Level int8
@@ -49,7 +50,7 @@ const (
 	// it shouldn't generate any error-level logs.
 	ErrorLevel
 
-	// FatalLevel logs a message, then calls os.Exit(1).
+	// FatalLevel logs a message, then exits with status 1.
 	FatalLevel
 
 	// PanicLevel logs a message, then panics.
@@ -58,6 +59,10 @@ const (
 	DefaultTimeFormat = "2006-01-02T15:04:05.000Z07:00"
 )
 
+// disabledLevel is above every level, so that a logger or encoder at it
+// writes nothing.
+const disabledLevel = PanicLevel + 1
+
 // String returns the string representation of the log level.
 func (l Level) String() string {
 	switch l {
@@ -78,6 +83,47 @@ func (l Level) String() string {
 	}
 }
 
+// MarshalText implements encoding.TextMarshaler, with the lower-case name
+// of the level, such as "info".
+func (l Level) MarshalText() ([]byte, error) {
+	if l < DebugLevel || l > PanicLevel {
+		return nil, fmt.Errorf("logger: unknown level %d", l)
+	}
+	return []byte(strings.ToLower(l.String())), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, parsing the level
+// with ParseLevel, so that levels can be read from flags and config files.
+func (l *Level) UnmarshalText(text []byte) error {
+	level, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = level
+	return nil
+}
+
+// ParseLevel returns the level named s, one of "debug", "info", "warn",
+// "error", "fatal" and "panic" in any case.
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case EnvDebugLevel:
+		return DebugLevel, nil
+	case EnvInfoLevel:
+		return InfoLevel, nil
+	case EnvWarnLevel:
+		return WarnLevel, nil
+	case EnvErrorLevel:
+		return ErrorLevel, nil
+	case EnvFatalLevel:
+		return FatalLevel, nil
+	case EnvPanicLevel:
+		return PanicLevel, nil
+	default:
+		return 0, fmt.Errorf("logger: unknown level %q", s)
+	}
+}
+
 // Format represents the output format for log entries.
 type Format int8
 
@@ -89,24 +135,157 @@ const (
 	// JSONFormat outputs logs in structured JSON format.
 	// Example: {"timestamp":"2024-01-20T15:04:05.000Z","level":"INFO","message":"User logged in","userID":12345}
 	JSONFormat
+
+	// ConsoleFormat outputs logs for local development: levels are aligned
+	// and color-coded and timestamps dimmed. Colors are only used when the
+	// output is a terminal and the NO_COLOR environment variable is unset.
+	// Example: "2024-01-20T15:04:05.000Z INFO  User logged in userID=12345"
+	ConsoleFormat
+
+	// ECSFormat outputs logs as Elastic Common Schema JSON documents, ready
+	// for Elasticsearch and Kibana without a pipeline rewriting field names.
+	// Example: {"@timestamp":"2024-01-20T15:04:05.000Z","log.level":"info","message":"User logged in","ecs.version":"8.11.0","userID":12345}
+	ECSFormat
+
+	// GCPFormat outputs logs as JSON with the special fields of Google Cloud
+	// Logging, so that entries from GKE and Cloud Run get their severity,
+	// source location and trace correlation.
+	// Example: {"time":"2024-01-20T15:04:05.000Z","severity":"INFO","message":"User logged in","userID":12345}
+	GCPFormat
+
+	// PrettyFormat outputs logs as indented, multi-line JSON for reading
+	// during local development, with colored keys when the output is a
+	// terminal and NO_COLOR is unset. Select it with LOG_FORMAT=pretty to
+	// read JSON entries without piping them through jq.
+	PrettyFormat
+
+	// CSVFormat outputs logs as CSV records with the columns set by
+	// Config.CSVColumns, for audit extracts and spreadsheets. Write the
+	// header with WriteCSVHeader.
+	// Example: "2024-01-20T15:04:05.000Z,INFO,User logged in,12345"
+	CSVFormat
 )
 
+// String returns the name of the format, such as "json".
+func (f Format) String() string {
+	switch f {
+	case TextFormat:
+		return EnvLogFormatText
+	case JSONFormat:
+		return EnvLogFormatJSON
+	case ConsoleFormat:
+		return EnvLogFormatConsole
+	case ECSFormat:
+		return EnvLogFormatECS
+	case GCPFormat:
+		return EnvLogFormatGCP
+	case PrettyFormat:
+		return EnvLogFormatPretty
+	case CSVFormat:
+		return EnvLogFormatCSV
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler, with the name of the
+// format.
+func (f Format) MarshalText() ([]byte, error) {
+	if f < TextFormat || f > CSVFormat {
+		return nil, fmt.Errorf("logger: unknown format %d", f)
+	}
+	return []byte(f.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, parsing the format
+// with ParseFormat.
+func (f *Format) UnmarshalText(text []byte) error {
+	format, err := ParseFormat(string(text))
+	if err != nil {
+		return err
+	}
+	*f = format
+	return nil
+}
+
+// ParseFormat returns the format named s, one of "text", "json",
+// "console", "ecs", "gcp", "pretty" and "csv" in any case.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case EnvLogFormatText:
+		return TextFormat, nil
+	case EnvLogFormatJSON:
+		return JSONFormat, nil
+	case EnvLogFormatConsole:
+		return ConsoleFormat, nil
+	case EnvLogFormatECS:
+		return ECSFormat, nil
+	case EnvLogFormatGCP:
+		return GCPFormat, nil
+	case EnvLogFormatPretty:
+		return PrettyFormat, nil
+	case EnvLogFormatCSV:
+		return CSVFormat, nil
+	default:
+		return 0, fmt.Errorf("logger: unknown format %q", s)
+	}
+}
+
 // Field represents a key-value pair that can be attached to a log entry.
 // Fields are used for structured logging to provide additional context.
+//
+// Fields built with the typed constructors (String, Int, Bool, ...) keep
+// their value in dedicated slots and never box it into an interface{}.
 type Field struct {
 	// Key is the field name
 	Key string
 
-	// Value is the field value, can be string, int, int64, float64, or bool
+	// Value is the value of fields built with Any, Err, Stringer, Reflect,
+	// Lazy, Object and Array. Typed constructors such as String and Int
+	// keep their value elsewhere; use Interface to read the value of any
+	// field.
 	Value interface{}
+
+	kind fieldKind
+	num  int64
+	str  string
+}
+
+// Entry is a single log entry as seen by hooks, before it is encoded.
+type Entry struct {
+	// Time is when the entry was logged.
+	Time time.Time
+
+	// Level is the severity of the entry.
+	Level Level
+
+	// Message is the log message.
+	Message string
+
+	// Fields holds the fields passed at the call site. Fields bound with
+	// Logger.With are pre-encoded in Context and not included.
+	Fields []Field
+
+	// Context holds the fields bound with Logger.With, already encoded by
+	// the logger's Encoder.EncodeFields. Encoders splice it into the entry.
+	Context []byte
+
+	// Caller is the call site of the entry, or nil unless Config.AddCaller is set.
+	Caller *Caller
 }
 
 // Config holds the configuration for a Logger instance.
 type Config struct {
 	// Level sets the minimum log level that will be output.
 	// Log entries below this level will be discarded.
+	// It can be changed at runtime with Logger.SetLevel.
 	Level Level
 
+	// Levels sets the minimum levels of loggers built with Named, keyed by
+	// logger name; "*" overrides Level. See Logger.SetLevels and
+	// ParseLevels.
+	Levels map[string]Level
+
 	// Format determines the output format (TextFormat or JSONFormat).
 	Format Format
 
@@ -119,18 +298,317 @@ type Config struct {
 	// I/O operations in cloud environments.
 	BufferSize int
 
+	// FlushInterval flushes the buffer periodically from a background
+	// goroutine when BufferSize > 0, so that entries logged at a low rate
+	// are not held back indefinitely. Close stops the goroutine.
+	FlushInterval time.Duration
+
+	// BufferShards splits the buffer of each output into this many
+	// buffers of BufferSize bytes when BufferSize > 0. Each entry goes to a
+	// shard that is not in use, picked from one chosen at random, so that
+	// goroutines logging concurrently rarely wait on each other; shards
+	// are written out as they fill, and all of them by Flush, Close and
+	// FlushInterval. Entries of different shards may reach the output out
+	// of order. Set it to runtime.GOMAXPROCS(0) for services logging at
+	// very high rates from many goroutines. Defaults to a single buffer.
+	BufferShards int
+
+	// CloseOutputs makes Close also close the outputs of the logger that
+	// implement io.Closer, other than os.Stdout and os.Stderr, once they
+	// have been flushed and synced. Set it when the logger owns its
+	// outputs, such as files or the batching writers of the otlp and loki
+	// packages, so that closing the logger releases them.
+	CloseOutputs bool
+
+	// OnFatal is called by Fatal after the entry has been written and the
+	// logger closed, right before the program exits. Use it to release
+	// resources that must be cleaned up on shutdown.
+	OnFatal func()
+
+	// ExitFunc replaces os.Exit as the function Fatal calls with status 1.
+	// Tests can set it to observe fatal entries without exiting.
+	ExitFunc func(code int)
+
+	// ErrorHandler is called when writing to an output fails, with the
+	// error and the bytes that could not be written: one entry, or the
+	// contents of the buffer when BufferSize > 0. It is called while the
+	// output is locked and must not log to the same logger.
+	// Failures are also counted in Stats.
+	ErrorHandler func(err error, entry []byte)
+
 	// UseUTC determines whether timestamps are in UTC (true) or local timezone (false).
 	// Defaults to false (local timezone).
 	UseUTC bool
+
+	// Clock returns the time of entries when not nil, instead of
+	// time.Now. Use it to freeze or replay timestamps, for example in
+	// golden-file tests. UseUTC still applies to the times it returns.
+	Clock func() time.Time
+
+	// Async moves writes to a background goroutine. Entries are still
+	// encoded on the calling goroutine, then handed over through a bounded
+	// lock-free queue; callers never contend on a lock and wait only while
+	// the queue is full. Close must be called on shutdown to drain the
+	// queue. Entries queued together are written to files and stream
+	// sockets in a single Write.
+	Async bool
+
+	// AsyncOverflow is what logging does in async mode while the queue is
+	// full: wait, drop the new or the oldest entry, or write synchronously.
+	// Defaults to AsyncBlock. Stats counts the entries that found the queue
+	// full and those dropped.
+	AsyncOverflow AsyncOverflow
+
+	// AsyncQueueSize is the number of entries the async queue can hold,
+	// rounded up to a power of two. Defaults to DefaultAsyncQueueSize when
+	// Async is set.
+	AsyncQueueSize int
+
+	// AddCaller annotates each entry with the file, line and function of
+	// its call site. It is emitted as Keys.Caller ("caller") and "function"
+	// in JSON and as a file:line prefix before the message in text.
+	AddCaller bool
+
+	// AddStacktrace adds the stack of the calling goroutine to entries at
+	// ErrorLevel and above, as a string field named by Keys.Stacktrace.
+	AddStacktrace bool
+
+	// AddGoroutineID adds the ID of the logging goroutine to every entry,
+	// as an integer field named GoroutineKey, to tell apart the interleaved
+	// entries of concurrent goroutines. Reading the ID costs about a
+	// microsecond per entry; bind a Worker label with With where the
+	// goroutines are known instead.
+	AddGoroutineID bool
+
+	// CallerSkip increases the number of stack frames skipped when AddCaller
+	// or AddStacktrace is set. Use it when the logger is wrapped by helper
+	// functions so that the reported call site is the helper's caller.
+	CallerSkip int
+
+	// Sampling enables sampling of repetitive entries when not nil.
+	// See SamplerConfig.
+	Sampling *SamplerConfig
+
+	// RateLimit enables per-key rate limiting of entries when not nil.
+	// See RateLimitConfig.
+	RateLimit *RateLimitConfig
+
+	// RingBuffer keeps the most recent entries at every level in memory
+	// when not nil, even those below Level. See RingBuffer.
+	RingBuffer *RingBuffer
+
+	// FlightRecorder keeps entries below Level in memory and writes those
+	// of a trace or request when it logs an error, when not nil.
+	// See FlightRecorderConfig.
+	FlightRecorder *FlightRecorderConfig
+
+	// Dedup collapses identical consecutive entries into one carrying a
+	// repeat count when not nil. See DedupConfig.
+	Dedup *DedupConfig
+
+	// Filter is called for every entry that passes the level check, with
+	// the fields of the call but not those bound with With, and drops the
+	// entry when it returns false. Use it to silence noise centrally, such
+	// as health checks. It runs before sampling, rate limiting and
+	// redaction, and must be safe for concurrent use.
+	Filter func(level Level, msg string, fields []Field) bool
+
+	// Transform is called after Filter and may rewrite the message and
+	// fields of an entry, returning them with true, or drop it by
+	// returning false. fields is a copy that Transform may modify in
+	// place. It must be safe for concurrent use.
+	Transform func(level Level, msg string, fields []Field) (string, []Field, bool)
+
+	// MaxValueBytes cuts messages and the values of string and error fields
+	// longer than this many bytes when > 0, and marks the entry with
+	// TruncatedKey, so that a rogue huge value cannot flood outputs.
+	MaxValueBytes int
+
+	// MaxEntryBytes bounds the size of encoded entries, not counting the
+	// trailing newline, when > 0. Entries that exceed it are written
+	// without the fields of the call and with their message cut, marked
+	// with TruncatedKey. Fields bound with With are kept.
+	MaxEntryBytes int
+
+	// Redact masks or hashes sensitive data in messages and fields before
+	// entries are encoded, when not nil. See RedactConfig.
+	Redact *RedactConfig
+
+	// Encoder replaces the built-in encoder selected by Format when not nil.
+	// Use it to write entries in custom formats.
+	Encoder Encoder
+
+	// LossyFloats encodes Float64 fields in JSON with at most three
+	// decimal places, which is slightly faster than the default
+	// full-precision encoding. NaN, infinities and values outside the
+	// int64 range are always encoded in full.
+	LossyFloats bool
+
+	// EscapeHTML escapes <, > and & in JSON strings as \u003c, \u003e and
+	// \u0026, so that entries can be embedded in HTML safely.
+	EscapeHTML bool
+
+	// TimeFormat is the layout of entry timestamps, such as time.RFC3339 or
+	// time.RFC3339Nano, or one of TimeFormatUnix, TimeFormatUnixMilli and
+	// TimeFormatUnixNano for epoch timestamps. Defaults to DefaultTimeFormat.
+	TimeFormat string
+
+	// TimeKey is the JSON key of entry timestamps. Defaults to DefaultTimeKey.
+	// Keys.Time takes precedence over it.
+	TimeKey string
+
+	// LevelEncoder selects how JSONFormat, TextFormat, ConsoleFormat,
+	// PrettyFormat and CSVFormat write levels. Defaults to
+	// UppercaseLevelEncoder.
+	LevelEncoder LevelEncoder
+
+	// Keys renames the timestamp, level, message and caller members of
+	// JSON entries, and the stack trace field. See Keys.
+	Keys Keys
+
+	// OmitTime leaves timestamps out of entries, for collectors that add
+	// their own.
+	OmitTime bool
+
+	// GCPProjectID is the Google Cloud project that GCPFormat uses to turn
+	// "trace_id" fields into "projects/<id>/traces/<trace_id>" trace names.
+	GCPProjectID string
+
+	// CSVColumns are the columns of CSVFormat entries, in order. The
+	// timestamp, level, message and caller are named by their JSON keys,
+	// such as "timestamp" or Keys.Time; other columns hold the value of the
+	// field with their key. Defaults to the timestamp, level and message.
+	CSVColumns []string
+
+	// Sinks routes entries to several outputs, each with its own levels
+	// and format. When set, Output, Format and Encoder are ignored;
+	// BufferSize applies to each sink. See Sink.
+	Sinks []Sink
+
+	// DuplicateKeys selects what happens to fields whose key is already
+	// used by a bound field or an earlier field of the call. Defaults to
+	// AllowDuplicateKeys. See DuplicateKeys.
+	DuplicateKeys DuplicateKeys
+
+	// SortFields writes fields in the order of their keys, for output that
+	// stays the same when the code building fields changes, as golden
+	// files, diffs and deduplication of JSON documents need. The fields
+	// bound with each With call and Fields, and those of each entry, are
+	// sorted separately: bound fields still come first, in the order they
+	// were bound. Fields are otherwise written in the order they are given.
+	// Sorting the fields of an entry that are not already in order costs
+	// a copy.
+	SortFields bool
+
+	// AddHostInfo binds the host name and process ID to every entry, as
+	// HostnameKey and PIDKey fields.
+	AddHostInfo bool
+
+	// Service, Env and Version, when set, are bound to every entry as
+	// ServiceKey, EnvKey and VersionKey fields, so that entries from every
+	// deployment identify their source the same way.
+	Service string
+	Env     string
+	Version string
+
+	// AddBuildInfo binds a BuildKey group to every entry with the module
+	// version, VCS revision and dirty flag that runtime/debug.ReadBuildInfo
+	// reports, so that entries identify the binary that wrote them. The
+	// revision and flag are only known for binaries built with VCS
+	// stamping, the default for "go build" in a repository.
+	AddBuildInfo bool
+
+	// Fields are bound to every entry, as if added with Logger.With, for
+	// static metadata. They follow the fields of AddHostInfo, Service, Env,
+	// Version and AddBuildInfo, which are encoded once along with them.
+	Fields []Field
+
+	// ContextExtractors turn values carried by a context into fields.
+	// ContextLogger runs them in order for each entry and logs their fields
+	// ahead of the call's own. See ContextValue.
+	ContextExtractors []func(context.Context) []Field
+
+	// TraceSampled, when not nil, decides whether the Debug entries of a
+	// ContextLogger are written, whatever the level: they are written when
+	// it reports the trace of the context as sampled and dropped otherwise,
+	// so that debug logging materializes for the sampled fraction of
+	// traffic only. With OpenTelemetry:
+	//
+	//	TraceSampled: func(ctx context.Context) bool {
+	//		return trace.SpanContextFromContext(ctx).IsSampled()
+	//	},
+	//
+	// A flag stored in the context with NewSampledContext takes precedence
+	// over it, and a level stored with NewLevelContext over both. Entries
+	// at other levels, and those of a Logger, are not affected.
+	TraceSampled func(context.Context) bool
 }
 
 // Logger is a high-performance logging instance that supports structured
 // logging with minimal memory allocations. It is safe for concurrent use.
 type Logger struct {
 	config Config
-	buffer []byte
-	pool   sync.Pool
-	mu     sync.Mutex
+	core   *core
+
+	// contexts holds the name and the fields bound with With, pre-encoded
+	// by each of core.encs. bound holds the fields alone, for Named.
+	contexts [][]byte
+	bound    [][]byte
+
+	// fields holds the fields encoded in bound when Config.DuplicateKeys
+	// is set, to check the keys of later fields against.
+	fields []Field
+
+	// name is the dotted name set with Named.
+	name string
+
+	// override replaces the level of the logger when not nil. It is set
+	// for a single entry by ContextLogger from NewLevelContext.
+	override *Level
+
+	// debugSampled, when not nil, decides whether Debug entries are
+	// written, whatever the level. It is set for a single entry by
+	// ContextLogger from NewSampledContext or Config.TraceSampled.
+	debugSampled *bool
+
+	// flightKey is the value of the flight recorder key field bound with
+	// With, if any.
+	flightKey string
+}
+
+// core holds the state shared between a Logger and the loggers derived
+// from it with With, so that all of them write through the same sinks.
+type core struct {
+	level   atomic.Int32
+	names   atomic.Pointer[map[string]Level]
+	pool    bufferPool
+	mu      sync.Mutex
+	async   *asyncWriter
+	hooks   atomic.Pointer[[]Hook]
+	sampler atomic.Pointer[sampler]
+	limiter *rateLimiter
+	deduper *deduper
+	// discard is set when every encoder is disabled because all sinks
+	// write to io.Discard, so that entries are not even built.
+	discard  bool
+	ring     *RingBuffer
+	ringEnc  int
+	recorder *flightRecorder
+	redactor atomic.Pointer[redactor]
+	flusher  *flusher
+	stats    *writeStats
+	// batchBuf holds the entries of a batch written by the async writer,
+	// which is its only user.
+	batchBuf []byte
+	// closed is set by Close, after which entries are dropped.
+	closed atomic.Bool
+
+	// encs are the distinct encoders used by sinks, encLevels the lowest
+	// level any sink using each of them accepts.
+	encs      []Encoder
+	encLevels []Level
+	builtin   bool
+	sinks     []*sink
 }
 
 // New creates a new Logger instance with the given configuration.
@@ -152,21 +630,183 @@ func New(config Config) *Logger {
 		config.Output = os.Stdout
 	}
 
+	c := &core{stats: &writeStats{handler: config.ErrorHandler}}
+	c.encs, c.encLevels, c.sinks, c.ringEnc = newSinks(config, c.stats)
+	c.ring = config.RingBuffer
+	if config.FlightRecorder != nil {
+		c.recorder = newFlightRecorder(config.FlightRecorder)
+	}
+	c.builtin = true
+	for _, enc := range c.encs {
+		c.builtin = c.builtin && isBuiltinEncoder(enc)
+	}
+	c.discard = true
+	for _, level := range c.encLevels {
+		c.discard = c.discard && level == disabledLevel
+	}
+
+	c.level.Store(int32(config.Level))
+
+	if config.Sampling != nil {
+		c.sampler.Store(newSampler(config.Sampling))
+	}
+
+	if config.Redact != nil {
+		c.redactor.Store(newRedactor(config.Redact))
+	}
+
 	l := &Logger{
-		config: config,
-		buffer: make([]byte, 0, config.BufferSize),
+		config:   config,
+		core:     c,
+		contexts: make([][]byte, len(c.encs)),
+	}
+	if fields := metadataFields(&config); len(fields) > 0 {
+		fields = resolveLazy(fields)
+		if config.SortFields {
+			fields = sortFields(fields)
+		}
+		if r := c.redactor.Load(); r != nil {
+			fields = r.fields(fields)
+		}
+		_, fields, _ = config.DuplicateKeys.dedupe(nil, fields)
+		for i, enc := range c.encs {
+			l.contexts[i] = enc.EncodeFields(nil, fields)
+		}
+		if config.DuplicateKeys != AllowDuplicateKeys {
+			l.fields = fields
+		}
+	}
+	l.bound = l.contexts
+	if len(config.Levels) > 0 {
+		l.SetLevels(config.Levels)
+	}
+
+	if config.Async {
+		c.async = newAsyncWriter(config.AsyncQueueSize, config.AsyncOverflow, c.stats, &c.pool)
+		go c.async.run(c)
 	}
 
-	l.pool = sync.Pool{
-		New: func() interface{} {
-			buf := make([]byte, 0, 256)
-			return &buf
-		},
+	if config.RateLimit != nil {
+		c.limiter = newRateLimiter(config.RateLimit)
+		go c.limiter.run(l)
+	}
+
+	if config.Dedup != nil {
+		c.deduper = newDeduper(config.Dedup)
+		go c.deduper.run()
+	}
+
+	if config.BufferSize > 0 && config.FlushInterval > 0 {
+		c.flusher = newFlusher(config.FlushInterval)
+		go c.flusher.run(c)
 	}
 
 	return l
 }
 
+// Nop returns a logger that writes nothing, for libraries that accept a
+// *Logger to default to when given none. Its logging methods return right
+// after the level check and its With calls encode nothing. Fatal still
+// exits and Panic still panics.
+func Nop() *Logger {
+	c := &core{stats: &writeStats{}, builtin: true, discard: true, ringEnc: -1}
+	c.level.Store(int32(disabledLevel))
+	return &Logger{core: c}
+}
+
+// With creates a child logger that adds the given fields to every entry
+// it writes. The fields are encoded once, when With is called, so binding
+// static metadata such as service name or version has no per-call cost.
+//
+// The child shares output and buffer with its parent; flushing either one
+// flushes both.
+//
+// Example:
+//
+//	serviceLogger := logger.With(
+//		logger.String("service", "billing"),
+//		logger.String("version", "1.4.2"),
+//	)
+//	serviceLogger.Info("Service started")
+func (l *Logger) With(fields ...Field) *Logger {
+	if len(fields) == 0 {
+		return l
+	}
+	fields = resolveLazy(fields)
+	if r := l.core.redactor.Load(); r != nil {
+		fields = r.fields(fields)
+	}
+	if l.config.MaxValueBytes > 0 {
+		_, fields = limitValues(l.config.MaxValueBytes, "", fields)
+	}
+	if l.config.SortFields {
+		fields = sortFields(fields)
+	}
+
+	child := *l
+	if fr := l.core.recorder; fr != nil {
+		for i := range fields {
+			if fields[i].Key == fr.keyField {
+				child.flightKey = fieldText(fields[i])
+			}
+		}
+	}
+
+	if l.config.DuplicateKeys != AllowDuplicateKeys {
+		bound, fields, _ := l.config.DuplicateKeys.dedupe(l.fields, fields)
+		child.bind(append(bound[:len(bound):len(bound)], fields...))
+		return &child
+	}
+
+	child.contexts = make([][]byte, len(l.contexts))
+	for i, enc := range l.core.encs {
+		context := make([]byte, 0, len(l.contexts[i])+len(fields)*32)
+		context = append(context, l.contexts[i]...)
+		child.contexts[i] = enc.EncodeFields(context, fields)
+	}
+
+	child.bound = child.contexts
+	if l.name != "" {
+		child.bound = make([][]byte, len(l.bound))
+		for i, enc := range l.core.encs {
+			bound := make([]byte, 0, len(l.bound[i])+len(fields)*32)
+			bound = append(bound, l.bound[i]...)
+			child.bound[i] = enc.EncodeFields(bound, fields)
+		}
+	}
+
+	return &child
+}
+
+// bind encodes fields as the bound fields of l, replacing those it had,
+// for Config.DuplicateKeys.
+func (l *Logger) bind(fields []Field) {
+	l.fields = fields
+	l.bound = make([][]byte, len(l.core.encs))
+	for i, enc := range l.core.encs {
+		l.bound[i] = enc.EncodeFields(nil, fields)
+	}
+
+	l.contexts = l.bound
+	if l.name != "" {
+		l.contexts = make([][]byte, len(l.bound))
+		nameField := []Field{String(NameKey, l.name)}
+		for i, enc := range l.core.encs {
+			context := enc.EncodeFields(nil, nameField)
+			l.contexts[i] = append(context, l.bound[i]...)
+		}
+	}
+}
+
+// WithCallerSkip returns a logger that reports call sites skip frames
+// further up the stack when AddCaller is set. Use it in helpers and
+// adapters that wrap the logger, so that entries point at their callers.
+func (l *Logger) WithCallerSkip(skip int) *Logger {
+	child := *l
+	child.config.CallerSkip += skip
+	return &child
+}
+
 // WithContext creates a ContextLogger that automatically extracts context
 // information from the provided context function for each log entry.
 //
@@ -192,37 +832,278 @@ func (l *Logger) WithContext(ctxFunc func() context.Context) *ContextLogger {
 //
 // This is useful when you have a context that remains constant throughout
 // the logger's lifetime. For dynamic contexts, prefer WithContext().
+// The fields of ctx are extracted once and bound as by With, so they are
+// encoded once rather than for every entry.
 //
 // Example:
 //
-//	ctx := context.WithValue(context.Background(), "serviceID", "user-service")
+//	ctx := context.WithValue(context.Background(), serviceKey{}, "user-service")
 //	contextLogger := logger.WithStaticContext(ctx)
 //	contextLogger.Info("Service started")
 func (l *Logger) WithStaticContext(ctx context.Context) *ContextLogger {
-	return &ContextLogger{
-		logger:  l,
-		ctxFunc: func() context.Context { return ctx },
+	if ctx == nil {
+		return &ContextLogger{logger: l}
 	}
+	// Resolved for DebugLevel, so that trace sampling is decided once too.
+	bound, fields := (&ContextLogger{logger: l, ctxFunc: func() context.Context { return ctx }}).resolve(DebugLevel, nil)
+	return &ContextLogger{logger: bound.With(fields...)}
+}
+
+// SetLevel atomically changes the minimum level that will be output.
+// It is safe to call while other goroutines are logging, and it affects
+// the logger and every logger derived from it with With or Named, except
+// named loggers with a level of their own. See SetLevels.
+func (l *Logger) SetLevel(level Level) {
+	l.core.level.Store(int32(level))
+}
+
+// GetLevel returns the minimum level that is currently being output by
+// the logger: the level set for its name or closest ancestor name, if any,
+// or else the logger-wide level.
+func (l *Logger) GetLevel() Level {
+	if l.override != nil {
+		return *l.override
+	}
+	if l.name != "" {
+		if names := l.core.names.Load(); names != nil {
+			if level, ok := nameLevel(*names, l.name); ok {
+				return level
+			}
+		}
+	}
+	return Level(l.core.level.Load())
+}
+
+// Enabled reports whether entries at level are currently written, or
+// kept by Config.RingBuffer or Config.FlightRecorder. Use it to skip
+// building expensive fields for disabled levels.
+func (l *Logger) Enabled(level Level) bool {
+	return (level >= l.GetLevel() && !l.discards()) || l.core.ring != nil || l.core.recorder != nil
+}
+
+// below reports whether entries at level are dropped by the level of the
+// logger or, for Debug entries, by trace sampling.
+func (l *Logger) below(level Level) bool {
+	if level == DebugLevel && l.debugSampled != nil {
+		return !*l.debugSampled
+	}
+	return level < l.GetLevel()
+}
+
+// discards reports whether entries are dropped without being built,
+// because the logger writes only to io.Discard and has no hooks.
+func (l *Logger) discards() bool {
+	return l.core.discard && l.core.hooks.Load() == nil
 }
 
 func (l *Logger) log(level Level, msg string, fields ...Field) {
-	if level < l.config.Level {
+	if l.core.closed.Load() {
+		return
+	}
+	if l.below(level) {
+		if l.core.ring != nil || l.core.recorder != nil {
+			l.retain(level, msg, fields)
+		}
 		return
 	}
+	if l.discards() {
+		return
+	}
+
+	if l.config.Filter != nil || l.config.Transform != nil {
+		var ok bool
+		if msg, fields, ok = l.filter(level, msg, fields); !ok {
+			return
+		}
+	}
 
-	bufPtr := l.pool.Get().(*[]byte)
-	defer l.pool.Put(bufPtr)
+	now := l.now()
 
-	buf := (*bufPtr)[:0]
+	if s := l.core.sampler.Load(); s != nil {
+		ok, dropped := s.check(level, msg, now)
+		if !ok {
+			return
+		}
+		if dropped > 0 {
+			fields = append(fields[:len(fields):len(fields)], Bool("sampled", true), Int64("dropped", int64(dropped)))
+		}
+	}
 
-	switch l.config.Format {
-	case JSONFormat:
-		buf = l.appendJSON(buf, level, msg, fields...)
-	default:
-		buf = l.appendText(buf, level, msg, fields...)
+	if l.core.limiter != nil && !l.core.limiter.allow(level, msg, fields) {
+		return
 	}
 
-	l.write(buf)
+	fields = resolveLazy(fields)
+
+	if l.config.DuplicateKeys != AllowDuplicateKeys {
+		bound, deduped, rebound := l.config.DuplicateKeys.dedupe(l.fields, fields)
+		fields = deduped
+		if rebound {
+			// A field of the call overrides a bound field: the entry is
+			// written by a copy of l without it.
+			child := *l
+			child.bind(bound)
+			l = &child
+		}
+	}
+
+	if r := l.core.redactor.Load(); r != nil {
+		msg = r.message(msg)
+		fields = r.fields(fields)
+	}
+
+	if l.config.MaxValueBytes > 0 {
+		msg, fields = limitValues(l.config.MaxValueBytes, msg, fields)
+	}
+
+	if l.core.deduper != nil && !l.core.deduper.check(l, level, msg, fields, now) {
+		return
+	}
+
+	if l.core.recorder != nil && level >= ErrorLevel {
+		if key := l.core.recorder.key(l, fields); key != "" {
+			l.writeFlight(key)
+		}
+	}
+
+	var c *Caller
+	if l.config.AddCaller {
+		if frame, ok := captureCaller(callerSkip + l.config.CallerSkip); ok {
+			c = &frame
+		}
+	}
+
+	if l.config.AddStacktrace && level >= ErrorLevel {
+		stack := String(l.config.Keys.stacktrace(), captureStacktrace(callerSkip+l.config.CallerSkip))
+		fields = append(fields[:len(fields):len(fields)], stack)
+	}
+
+	if l.config.AddGoroutineID {
+		fields = append(fields[:len(fields):len(fields)], Int64(GoroutineKey, goroutineID()))
+	}
+
+	if l.config.SortFields {
+		fields = sortFields(fields)
+	}
+
+	hooks := l.core.hooks.Load()
+	if hooks != nil || !l.core.builtin {
+		// Hooks and custom encoders get a heap copy of the entry, built
+		// without reading from the stack entry below, so that the caller's
+		// fields stay on the stack in the common case.
+		he := &Entry{
+			Time:    now,
+			Level:   level,
+			Message: msg,
+			Fields:  append([]Field(nil), fields...),
+			Caller:  c,
+		}
+		if hooks != nil && !runHooks(*hooks, he) {
+			return
+		}
+		l.emit(he)
+		return
+	}
+
+	e := Entry{
+		Time:    now,
+		Level:   level,
+		Message: msg,
+		Fields:  fields,
+		Caller:  c,
+	}
+	l.emitBuiltin(&e)
+}
+
+// emit encodes e once with each encoder used by a sink that accepts it,
+// and writes it. e escapes to the heap; use emitBuiltin on the hot path.
+func (l *Logger) emit(e *Entry) {
+	for i, enc := range l.core.encs {
+		if e.Level < l.core.encLevels[i] {
+			continue
+		}
+		e.Context = l.contexts[i]
+
+		bufPtr := l.core.pool.get()
+		buf := enc.EncodeEntry((*bufPtr)[:0], e)
+		if max := l.config.MaxEntryBytes; max > 0 && len(buf) > max {
+			buf = shrink(enc, buf, e, max)
+		}
+		if i == l.core.ringEnc {
+			l.core.ring.record(e.Level, buf)
+		}
+		l.output(bufPtr, buf, e.Level, i)
+	}
+}
+
+// emitBuiltin is emit for loggers using only built-in encoders. It calls
+// the encoders through their concrete types so that e does not escape.
+func (l *Logger) emitBuiltin(e *Entry) {
+	for i, enc := range l.core.encs {
+		if e.Level < l.core.encLevels[i] {
+			continue
+		}
+		e.Context = l.contexts[i]
+
+		bufPtr := l.core.pool.get()
+		buf := encodeBuiltin(enc, (*bufPtr)[:0], e)
+		if max := l.config.MaxEntryBytes; max > 0 && len(buf) > max {
+			buf = shrinkBuiltin(enc, buf, e, max)
+		}
+		if i == l.core.ringEnc {
+			l.core.ring.record(e.Level, buf)
+		}
+
+		l.output(bufPtr, buf, e.Level, i)
+	}
+}
+
+// encodeBuiltin encodes e with enc, one of the built-in encoders, through
+// its concrete type so that e does not escape.
+func encodeBuiltin(enc Encoder, buf []byte, e *Entry) []byte {
+	switch enc := enc.(type) {
+	case jsonEncoder:
+		return enc.EncodeEntry(buf, e)
+	case consoleEncoder:
+		return enc.EncodeEntry(buf, e)
+	case textEncoder:
+		return enc.EncodeEntry(buf, e)
+	case ecsEncoder:
+		return enc.EncodeEntry(buf, e)
+	case gcpEncoder:
+		return enc.EncodeEntry(buf, e)
+	case prettyEncoder:
+		return enc.EncodeEntry(buf, e)
+	case csvEncoder:
+		return enc.EncodeEntry(buf, e)
+	}
+	return buf
+}
+
+// output writes an entry encoded by encoder enc to the sinks using it,
+// or hands it to the async writer. buf must have been encoded into bufPtr,
+// which is returned to the pool with buf, however far it grew.
+func (l *Logger) output(bufPtr *[]byte, buf []byte, level Level, enc int) {
+	*bufPtr = buf
+	if l.core.async != nil {
+		switch l.core.async.enqueue(asyncEntry{bufPtr: bufPtr, level: level, enc: enc}) {
+		case entryQueued:
+			return
+		case entryDropped:
+			l.core.pool.put(bufPtr)
+			return
+		}
+	}
+
+	l.core.write(bufPtr, level, enc)
+	l.core.pool.put(bufPtr)
+}
+
+// Log logs a message at the given level. Unlike Fatal and Panic, it only
+// writes the entry, whatever the level. It is meant for adapters and
+// helpers that receive the level as a value.
+func (l *Logger) Log(level Level, msg string, fields ...Field) {
+	l.log(level, msg, fields...)
 }
 
 // Debug logs a message at DebugLevel. Debug logs are typically voluminous
@@ -249,59 +1130,125 @@ func (l *Logger) Error(msg string, fields ...Field) {
 	l.log(ErrorLevel, msg, fields...)
 }
 
-// Fatal logs a message at FatalLevel, then calls os.Exit(1).
-// This function does not return.
+// Fatal logs a message at FatalLevel, closes the logger so that buffered
+// and queued entries are written and synced to stable storage, runs
+// Config.OnFatal, and then calls Config.ExitFunc (os.Exit by default)
+// with code 1.
+// This function does not return unless ExitFunc does.
 func (l *Logger) Fatal(msg string, fields ...Field) {
 	l.log(FatalLevel, msg, fields...)
-	os.Exit(1)
+	l.exit()
 }
 
-// Panic logs a message at PanicLevel, then panics with the message.
+// Panic logs a message at PanicLevel, flushes the logger, then panics with
+// the message.
 // This function does not return.
 func (l *Logger) Panic(msg string, fields ...Field) {
 	l.log(PanicLevel, msg, fields...)
+	l.Flush()
 	panic(msg)
 }
 
-func (l *Logger) write(buf []byte) {
-	if l.config.BufferSize > 0 {
-		l.mu.Lock()
-		defer l.mu.Unlock()
+// exit terminates the program after a FatalLevel entry.
+func (l *Logger) exit() {
+	_ = l.Close()
+	if l.config.OnFatal != nil {
+		l.config.OnFatal()
+	}
+
+	exit := l.config.ExitFunc
+	if exit == nil {
+		exit = os.Exit
+	}
+	exit(1)
+}
 
-		if len(l.buffer)+len(buf) > l.config.BufferSize {
-			l.flush()
+// write writes an entry encoded by encoder enc to every sink using that
+// encoder whose level it meets. Each sink receives the entry and its
+// trailing newline in a single Write, so that datagram outputs get one
+// entry per packet. The newline is appended to *bufPtr.
+func (c *core) write(bufPtr *[]byte, level Level, enc int) {
+	*bufPtr = append(*bufPtr, '\n')
+	buf := *bufPtr
+	for _, s := range c.sinks {
+		if s.enc == enc && s.accepts(level) {
+			s.write(buf)
 		}
-		l.buffer = append(l.buffer, buf...)
-		l.buffer = append(l.buffer, '\n')
-	} else {
-		_, _ = l.config.Output.Write(buf)
-		_, _ = l.config.Output.Write([]byte{'\n'})
+	}
+}
+
+// writeBatch writes entries queued by the async writer and returns their
+// buffers to the pool. Sinks that accept batches receive all the entries
+// they write in one Write; the others receive them one by one, as from
+// write.
+func (c *core) writeBatch(batch []asyncEntry) {
+	for i := range batch {
+		*batch[i].bufPtr = append(*batch[i].bufPtr, '\n')
+	}
+
+	for _, s := range c.sinks {
+		if s.batch && s.bufferSize == 0 {
+			buf, n := c.batchBuf[:0], 0
+			for _, e := range batch {
+				if s.enc == e.enc && s.accepts(e.level) {
+					buf = append(buf, *e.bufPtr...)
+					n++
+				}
+			}
+			if n > 0 {
+				s.writeBatch(buf, n)
+			}
+			c.batchBuf = buf
+			continue
+		}
+		for _, e := range batch {
+			if s.enc == e.enc && s.accepts(e.level) {
+				s.write(*e.bufPtr)
+			}
+		}
+	}
+	if cap(c.batchBuf) > maxBatchBufSize {
+		c.batchBuf = nil
+	}
+
+	for _, e := range batch {
+		c.pool.put(e.bufPtr)
 	}
 }
 
 // Flush forces all buffered log entries to be written to the output.
-// This method is only effective when BufferSize > 0 in the Config.
+// In async mode it first waits for the entries queued so far to be written.
 // It is safe to call concurrently with other logger methods.
+//
+// Outputs that are WriteSyncers, such as files, are then synced to stable
+// storage; use Sync to get the error.
 func (l *Logger) Flush() {
-	if l.config.BufferSize > 0 {
-		l.mu.Lock()
-		defer l.mu.Unlock()
-		l.flush()
-	}
+	_ = l.Sync()
 }
 
-// flush is an internal method that writes all buffered content to the output.
-// It must be called with l.mu held.
-func (l *Logger) flush() {
-	if len(l.buffer) > 0 {
-		_, _ = l.config.Output.Write(l.buffer)
-		l.buffer = l.buffer[:0]
+// Sync flushes the logger as Flush does, then commits the outputs that are
+// WriteSyncers to stable storage, and returns the first error of their
+// Sync. Terminals and pipes, which cannot be synced, are skipped.
+func (l *Logger) Sync() error {
+	if l.core.async != nil {
+		l.core.async.sync()
+	}
+	for _, s := range l.core.sinks {
+		s.Flush()
+	}
+
+	var err error
+	for _, s := range l.core.sinks {
+		if serr := s.sync(); err == nil {
+			err = serr
+		}
 	}
+	return err
 }
 
 // ContextLogger is a logger that automatically extracts context information
 // for each log entry. It provides the same logging methods as Logger but
-// includes context fields like traceID and spanID.
+// includes the fields returned by Config.ContextExtractors.
 //
 // ContextLogger is created using Logger.WithContext() or Logger.WithStaticContext().
 type ContextLogger struct {
@@ -309,83 +1256,157 @@ type ContextLogger struct {
 	ctxFunc func() context.Context
 }
 
-// Debug logs a message at DebugLevel, automatically including context fields
-// such as traceID and spanID if present in the context.
+// Debug logs a message at DebugLevel, automatically including the
+// fields extracted from the context.
 func (cl *ContextLogger) Debug(msg string, fields ...Field) {
-	cl.logger.log(DebugLevel, msg, cl.extractContextFields(fields)...)
+	l, fields := cl.resolve(DebugLevel, fields)
+	l.log(DebugLevel, msg, fields...)
 }
 
-// Info logs a message at InfoLevel, automatically including context fields
-// such as traceID and spanID if present in the context.
+// Info logs a message at InfoLevel, automatically including the
+// fields extracted from the context.
 func (cl *ContextLogger) Info(msg string, fields ...Field) {
-	cl.logger.log(InfoLevel, msg, cl.extractContextFields(fields)...)
+	l, fields := cl.resolve(InfoLevel, fields)
+	l.log(InfoLevel, msg, fields...)
 }
 
-// Warn logs a message at WarnLevel, automatically including context fields
-// such as traceID and spanID if present in the context.
+// Warn logs a message at WarnLevel, automatically including the
+// fields extracted from the context.
 func (cl *ContextLogger) Warn(msg string, fields ...Field) {
-	cl.logger.log(WarnLevel, msg, cl.extractContextFields(fields)...)
+	l, fields := cl.resolve(WarnLevel, fields)
+	l.log(WarnLevel, msg, fields...)
 }
 
-// Error logs a message at ErrorLevel, automatically including context fields
-// such as traceID and spanID if present in the context.
+// Error logs a message at ErrorLevel, automatically including the
+// fields extracted from the context.
 func (cl *ContextLogger) Error(msg string, fields ...Field) {
-	cl.logger.log(ErrorLevel, msg, cl.extractContextFields(fields)...)
+	l, fields := cl.resolve(ErrorLevel, fields)
+	l.log(ErrorLevel, msg, fields...)
 }
 
-// Fatal logs a message at FatalLevel with context fields, then calls os.Exit(1).
-// This function does not return.
+// Fatal logs a message at FatalLevel with context fields, then exits like
+// Logger.Fatal.
 func (cl *ContextLogger) Fatal(msg string, fields ...Field) {
-	cl.logger.log(FatalLevel, msg, cl.extractContextFields(fields)...)
-	os.Exit(1)
+	l, fields := cl.resolve(FatalLevel, fields)
+	l.log(FatalLevel, msg, fields...)
+	cl.logger.exit()
 }
 
-// Panic logs a message at PanicLevel with context fields, then panics with the message.
+// Panic logs a message at PanicLevel with context fields, flushes the
+// logger, then panics with the message.
 // This function does not return.
 func (cl *ContextLogger) Panic(msg string, fields ...Field) {
-	cl.logger.log(PanicLevel, msg, cl.extractContextFields(fields)...)
+	l, fields := cl.resolve(PanicLevel, fields)
+	l.log(PanicLevel, msg, fields...)
+	cl.logger.Flush()
 	panic(msg)
 }
 
-func (cl *ContextLogger) extractContextFields(fields []Field) []Field {
-	contextFields := make([]Field, 0, 4)
+// resolve returns the logger to log an entry at level with, honoring a
+// level stored in the context with NewLevelContext and, for DebugLevel,
+// trace sampling, and fields with the context fields added.
+func (cl *ContextLogger) resolve(level Level, fields []Field) (*Logger, []Field) {
+	if cl.ctxFunc == nil {
+		return cl.logger, fields
+	}
+	ctx := cl.ctxFunc()
+	if ctx == nil {
+		return cl.logger, fields
+	}
 
-	if cl.ctxFunc != nil {
-		ctx := cl.ctxFunc()
-		if traceID := ctx.Value(contextKey("traceID")); traceID != nil {
-			contextFields = append(contextFields, Field{Key: "traceID", Value: traceID})
-		}
-		if spanID := ctx.Value(contextKey("spanID")); spanID != nil {
-			contextFields = append(contextFields, Field{Key: "spanID", Value: spanID})
+	l := cl.logger
+	if ctxLevel, ok := LevelFromContext(ctx); ok {
+		override := *l
+		override.override = &ctxLevel
+		l = &override
+	} else if level == DebugLevel {
+		if sampled, ok := l.traceSampled(ctx); ok {
+			override := *l
+			override.debugSampled = &sampled
+			l = &override
 		}
 	}
 
-	return append(contextFields, fields...)
+	extractors := l.config.ContextExtractors
+	if len(extractors) == 0 {
+		return l, fields
+	}
+
+	var contextFields []Field
+	for _, extract := range extractors {
+		contextFields = append(contextFields, extract(ctx)...)
+	}
+	return l, append(contextFields, fields...)
+}
+
+// traceSampled reports whether the trace of ctx is sampled, from
+// NewSampledContext or Config.TraceSampled, and whether either decided.
+func (l *Logger) traceSampled(ctx context.Context) (sampled, ok bool) {
+	if sampled, ok := SampledFromContext(ctx); ok {
+		return sampled, true
+	}
+	if l.config.TraceSampled != nil {
+		return l.config.TraceSampled(ctx), true
+	}
+	return false, false
 }
 
-func (l *Logger) appendText(buf []byte, level Level, msg string, fields ...Field) []byte {
-	now := time.Now()
+// ContextValue returns a context extractor that logs the value stored in
+// a context under key as a field named fieldKey, when present.
+//
+// Example:
+//
+//	type requestIDKey struct{}
+//
+//	log := logger.New(logger.Config{
+//		ContextExtractors: []func(context.Context) []logger.Field{
+//			logger.ContextValue(requestIDKey{}, "request_id"),
+//		},
+//	})
+func ContextValue(key interface{}, fieldKey string) func(context.Context) []Field {
+	return func(ctx context.Context) []Field {
+		v := ctx.Value(key)
+		if v == nil {
+			return nil
+		}
+		return []Field{Any(fieldKey, v)}
+	}
+}
+
+// now returns the current time, as given by Config.Clock if set, in the
+// configured time zone.
+func (l *Logger) now() time.Time {
+	var now time.Time
+	if l.config.Clock != nil {
+		now = l.config.Clock()
+	} else {
+		now = time.Now()
+	}
 	if l.config.UseUTC {
 		now = now.UTC()
 	}
+	return now
+}
 
-	buf = append(buf, now.Format(DefaultTimeFormat)...)
-	buf = append(buf, ' ')
-	buf = append(buf, level.String()...)
-	buf = append(buf, ' ')
-	buf = append(buf, msg...)
-
+// appendTextFields appends fields as space-separated key=value pairs.
+func appendTextFields(buf []byte, fields []Field) []byte {
 	for _, field := range fields {
 		buf = append(buf, ' ')
 		buf = append(buf, field.Key...)
 		buf = append(buf, '=')
-		buf = appendValue(buf, field.Value)
+		buf = appendFieldValue(buf, field)
+		if field.kind == errorKind {
+			buf = appendErrorDetailsText(buf, &field)
+		}
 	}
-
 	return buf
 }
 
 func appendValue(buf []byte, value interface{}) []byte {
+	if out, ok := appendInteger(buf, value); ok {
+		return out
+	}
+
 	switch v := value.(type) {
 	case string:
 		if needsQuoting(v) {
@@ -395,18 +1416,22 @@ func appendValue(buf []byte, value interface{}) []byte {
 		} else {
 			buf = append(buf, v...)
 		}
-	case int:
-		return appendInt(buf, int64(v))
-	case int64:
-		return appendInt(buf, v)
 	case float64:
-		return appendFloat(buf, v)
+		return appendFloat(buf, v, 64)
+	case float32:
+		return appendFloat(buf, float64(v), 32)
 	case bool:
-		if v {
-			buf = append(buf, "true"...)
-		} else {
-			buf = append(buf, "false"...)
-		}
+		return appendBool(buf, v)
+	case time.Time:
+		return v.AppendFormat(buf, DefaultTimeFormat)
+	case time.Duration:
+		return append(buf, v.String()...)
+	case []byte:
+		return appendBase64(buf, v)
+	case error:
+		return appendValue(buf, v.Error())
+	case fmt.Stringer:
+		return appendValue(buf, stringerText(v))
 	default:
 		buf = append(buf, '"')
 		buf = append(buf, "unknown"...)
@@ -415,6 +1440,42 @@ func appendValue(buf []byte, value interface{}) []byte {
 	return buf
 }
 
+// appendInteger appends value if it is one of the built-in integer types
+// and reports whether it was.
+func appendInteger(buf []byte, value interface{}) ([]byte, bool) {
+	switch v := value.(type) {
+	case int:
+		return appendInt(buf, int64(v)), true
+	case int8:
+		return appendInt(buf, int64(v)), true
+	case int16:
+		return appendInt(buf, int64(v)), true
+	case int32:
+		return appendInt(buf, int64(v)), true
+	case int64:
+		return appendInt(buf, v), true
+	case uint:
+		return appendUint(buf, uint64(v)), true
+	case uint8:
+		return appendUint(buf, uint64(v)), true
+	case uint16:
+		return appendUint(buf, uint64(v)), true
+	case uint32:
+		return appendUint(buf, uint64(v)), true
+	case uint64:
+		return appendUint(buf, v), true
+	case uintptr:
+		return appendUint(buf, uint64(v)), true
+	default:
+		return buf, false
+	}
+}
+
+// appendBase64 appends b encoded as standard base64, as encoding/json does.
+func appendBase64(buf []byte, b []byte) []byte {
+	return base64.StdEncoding.AppendEncode(buf, b)
+}
+
 func needsQuoting(s string) bool {
 	for _, r := range s {
 		if r == ' ' || r == '=' || r == '"' {
@@ -425,13 +1486,16 @@ func needsQuoting(s string) bool {
 }
 
 func appendInt(buf []byte, i int64) []byte {
-	if i == 0 {
-		return append(buf, '0')
-	}
-
 	if i < 0 {
 		buf = append(buf, '-')
-		i = -i
+		return appendUint(buf, uint64(-(i+1))+1)
+	}
+	return appendUint(buf, uint64(i))
+}
+
+func appendUint(buf []byte, i uint64) []byte {
+	if i == 0 {
+		return append(buf, '0')
 	}
 
 	var tmp [20]byte
@@ -445,8 +1509,8 @@ func appendInt(buf []byte, i int64) []byte {
 	return append(buf, tmp[idx:]...)
 }
 
-// appendFloat appends the string representation of a float64 to the buffer.
-func appendFloat(buf []byte, f float64) []byte {
-	// Use 'g' format for compact representation, 6 digits precision, -1 for all digits necessary
-	return append(buf, strconv.FormatFloat(f, 'g', -1, 64)...)
+// appendFloat appends the shortest representation of f that round-trips
+// at the given bit size (32 or 64).
+func appendFloat(buf []byte, f float64, bitSize int) []byte {
+	return strconv.AppendFloat(buf, f, 'g', -1, bitSize)
 }
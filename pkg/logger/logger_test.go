@@ -3,9 +3,17 @@ package logger
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"math"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -68,6 +76,16 @@ func TestLogger_JSONFormat(t *testing.T) {
 	assert.Contains(t, output, `"key2":42`)
 }
 
+type (
+	traceIDKey struct{}
+	spanIDKey  struct{}
+)
+
+var testContextExtractors = []func(context.Context) []Field{
+	ContextValue(traceIDKey{}, "traceID"),
+	ContextValue(spanIDKey{}, "spanID"),
+}
+
 func TestLogger_WithContext(t *testing.T) {
 	buf := &bytes.Buffer{}
 
@@ -75,10 +93,12 @@ func TestLogger_WithContext(t *testing.T) {
 		Level:  InfoLevel,
 		Format: JSONFormat,
 		Output: buf,
+
+		ContextExtractors: testContextExtractors,
 	})
 
-	ctx := context.WithValue(context.Background(), contextKey("traceID"), "trace123")
-	ctx = context.WithValue(ctx, contextKey("spanID"), "span456")
+	ctx := context.WithValue(context.Background(), traceIDKey{}, "trace123")
+	ctx = context.WithValue(ctx, spanIDKey{}, "span456")
 
 	contextLogger := logger.WithContext(func() context.Context { return ctx })
 	contextLogger.Info("test message", Field{Key: "custom", Value: "field"})
@@ -180,6 +200,61 @@ func TestFieldTypes(t *testing.T) {
 	assert.Contains(t, output, `"bool":true`)
 }
 
+func TestFieldTypes_Extended(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: JSONFormat,
+		Output: buf,
+	})
+
+	logger.Info("test",
+		Field{Key: "int8", Value: int8(-8)},
+		Field{Key: "int16", Value: int16(16)},
+		Field{Key: "int32", Value: int32(-32)},
+		Field{Key: "min_int64", Value: int64(math.MinInt64)},
+		Field{Key: "uint", Value: uint(7)},
+		Field{Key: "uint64", Value: uint64(math.MaxUint64)},
+		Field{Key: "float32", Value: float32(0.5)},
+		Field{Key: "time", Value: time.Date(2024, 1, 20, 15, 4, 5, 0, time.UTC)},
+		Field{Key: "duration", Value: 250 * time.Millisecond},
+		Field{Key: "bytes", Value: []byte("hello")},
+	)
+
+	output := buf.String()
+	assert.Contains(t, output, `"int8":-8`)
+	assert.Contains(t, output, `"int16":16`)
+	assert.Contains(t, output, `"int32":-32`)
+	assert.Contains(t, output, `"min_int64":-9223372036854775808`)
+	assert.Contains(t, output, `"uint":7`)
+	assert.Contains(t, output, `"uint64":18446744073709551615`)
+	assert.Contains(t, output, `"float32":0.5`)
+	assert.Contains(t, output, `"time":"2024-01-20T15:04:05.000Z"`)
+	assert.Contains(t, output, `"duration":"250ms"`)
+	assert.Contains(t, output, `"bytes":"aGVsbG8="`)
+	assert.NotContains(t, output, "unknown")
+}
+
+func TestFieldTypes_ExtendedText(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: TextFormat,
+		Output: buf,
+	})
+
+	logger.Info("test",
+		Field{Key: "uint32", Value: uint32(32)},
+		Field{Key: "duration", Value: time.Minute},
+		Field{Key: "bytes", Value: []byte("hello")},
+	)
+
+	output := buf.String()
+	assert.Contains(t, output, "uint32=32 duration=1m0s bytes=aGVsbG8=")
+}
+
 func TestLevelString(t *testing.T) {
 	assert.Equal(t, "DEBUG", DebugLevel.String())
 	assert.Equal(t, "INFO", InfoLevel.String())
@@ -217,9 +292,11 @@ func TestContextFieldsExtraction(t *testing.T) {
 		Level:  InfoLevel,
 		Format: JSONFormat,
 		Output: buf,
+
+		ContextExtractors: testContextExtractors,
 	})
 
-	ctx := context.WithValue(context.Background(), contextKey("traceID"), "trace123")
+	ctx := context.WithValue(context.Background(), traceIDKey{}, "trace123")
 	contextLogger := logger.WithContext(func() context.Context { return ctx })
 
 	contextLogger.Info("test")
@@ -236,9 +313,11 @@ func TestLogger_WithStaticContext(t *testing.T) {
 		Level:  InfoLevel,
 		Format: JSONFormat,
 		Output: buf,
+
+		ContextExtractors: testContextExtractors,
 	})
 
-	ctx := context.WithValue(context.Background(), contextKey("traceID"), "static123")
+	ctx := context.WithValue(context.Background(), traceIDKey{}, "static123")
 	contextLogger := logger.WithStaticContext(ctx)
 
 	contextLogger.Info("test message")
@@ -247,6 +326,34 @@ func TestLogger_WithStaticContext(t *testing.T) {
 	assert.Contains(t, output, `"traceID":"static123"`)
 }
 
+func TestLogger_WithStaticContextBindsOnce(t *testing.T) {
+	buf := &bytes.Buffer{}
+	var extracted int
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: JSONFormat,
+		Output: buf,
+		ContextExtractors: []func(context.Context) []Field{
+			func(ctx context.Context) []Field {
+				extracted++
+				return testContextExtractors[0](ctx)
+			},
+		},
+	})
+
+	ctx := context.WithValue(context.Background(), traceIDKey{}, "static123")
+	contextLogger := logger.WithStaticContext(NewLevelContext(ctx, WarnLevel))
+	contextLogger.Info("hidden")
+	contextLogger.Warn("first")
+	contextLogger.Warn("second", String("k", "v"))
+
+	assert.Equal(t, 1, extracted)
+	assert.NotContains(t, buf.String(), "hidden")
+	assert.Equal(t, 2, strings.Count(buf.String(), `"traceID":"static123"`))
+	assert.Contains(t, buf.String(), `"traceID":"static123","k":"v"}`)
+	assert.NotPanics(t, func() { logger.WithStaticContext(nil).Info("no context") })
+}
+
 func TestLogger_DynamicContext(t *testing.T) {
 	buf := &bytes.Buffer{}
 
@@ -254,12 +361,14 @@ func TestLogger_DynamicContext(t *testing.T) {
 		Level:  InfoLevel,
 		Format: JSONFormat,
 		Output: buf,
+
+		ContextExtractors: testContextExtractors,
 	})
 
 	traceCounter := 0
 	contextLogger := logger.WithContext(func() context.Context {
 		traceCounter++
-		return context.WithValue(context.Background(), contextKey("traceID"), "dynamic"+string(rune('0'+traceCounter)))
+		return context.WithValue(context.Background(), traceIDKey{}, "dynamic"+string(rune('0'+traceCounter)))
 	})
 
 	contextLogger.Info("first message")
@@ -277,6 +386,8 @@ func TestLogger_NilContextFunc(t *testing.T) {
 		Level:  InfoLevel,
 		Format: JSONFormat,
 		Output: buf,
+
+		ContextExtractors: testContextExtractors,
 	})
 
 	contextLogger := logger.WithContext(nil)
@@ -287,3 +398,331 @@ func TestLogger_NilContextFunc(t *testing.T) {
 	assert.NotContains(t, output, `"spanID"`)
 	assert.Contains(t, output, `"custom":"field"`)
 }
+
+func TestLogger_ContextExtractors(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	type tenantKey struct{}
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: JSONFormat,
+		Output: buf,
+
+		ContextExtractors: []func(context.Context) []Field{
+			func(ctx context.Context) []Field {
+				tenant, _ := ctx.Value(tenantKey{}).(string)
+				return []Field{String("tenant", tenant), Bool("authenticated", tenant != "")}
+			},
+			ContextValue(traceIDKey{}, "traceID"),
+		},
+	})
+
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	logger.WithStaticContext(ctx).Info("test", String("custom", "field"))
+
+	assert.Contains(t, buf.String(), `"tenant":"acme","authenticated":true,"custom":"field"`)
+	assert.NotContains(t, buf.String(), `"traceID"`)
+}
+
+func TestLogger_NoContextExtractors(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: JSONFormat,
+		Output: buf,
+	})
+
+	ctx := context.WithValue(context.Background(), traceIDKey{}, "trace123")
+	logger.WithStaticContext(ctx).Info("test")
+
+	assert.NotContains(t, buf.String(), `"traceID"`)
+}
+
+func TestLogger_With(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: JSONFormat,
+		Output: buf,
+	})
+
+	child := logger.With(String("service", "billing"), String("version", "1.4.2"))
+	grandchild := child.With(String("region", "eu-west-1"))
+
+	grandchild.Info("test message", Field{Key: "custom", Value: "field"})
+	logger.Info("parent message")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"message":"test message","service":"billing","version":"1.4.2","region":"eu-west-1","custom":"field"}`)
+	assert.NotContains(t, lines[1], `"service"`)
+}
+
+func TestLogger_WithText(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: TextFormat,
+		Output: buf,
+	})
+
+	logger.With(String("service", "billing")).Info("test message", Int("attempt", 2))
+
+	assert.Contains(t, buf.String(), "test message service=billing attempt=2")
+}
+
+func TestLogger_WithSharesBuffer(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:      InfoLevel,
+		Format:     TextFormat,
+		Output:     buf,
+		BufferSize: 1024,
+	})
+
+	logger.With(String("service", "billing")).Info("child message")
+	assert.Empty(t, buf.String())
+
+	logger.Flush()
+
+	assert.Contains(t, buf.String(), "child message service=billing")
+}
+
+func TestLogger_SetLevel(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: TextFormat,
+		Output: buf,
+	})
+	child := logger.With(String("service", "billing"))
+
+	assert.Equal(t, InfoLevel, logger.GetLevel())
+
+	child.Debug("debug before")
+	logger.SetLevel(DebugLevel)
+	child.Debug("debug after")
+
+	assert.Equal(t, DebugLevel, child.GetLevel())
+	assert.NotContains(t, buf.String(), "debug before")
+	assert.Contains(t, buf.String(), "debug after")
+
+	logger.SetLevel(ErrorLevel)
+	logger.Warn("warn filtered")
+	assert.NotContains(t, buf.String(), "warn filtered")
+}
+
+func TestLogger_SetLevelConcurrent(t *testing.T) {
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: JSONFormat,
+		Output: io.Discard,
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if i%2 == 0 {
+					logger.SetLevel(Level(j%3 - 1))
+				}
+				logger.Info("concurrent", Int("j", j))
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestLogger_FatalFlushesAndExits(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	var calls []string
+	logger := New(Config{
+		Level:      InfoLevel,
+		Format:     TextFormat,
+		Output:     buf,
+		BufferSize: 4096,
+		OnFatal: func() {
+			calls = append(calls, "onFatal:"+buf.String())
+		},
+		ExitFunc: func(code int) {
+			calls = append(calls, "exit:"+strconv.Itoa(code))
+		},
+	})
+
+	logger.Info("buffered")
+	logger.Fatal("fatal error")
+
+	require.Len(t, calls, 2)
+	assert.Contains(t, calls[0], "buffered")
+	assert.Contains(t, calls[0], "FATAL fatal error")
+	assert.Equal(t, "exit:1", calls[1])
+}
+
+func TestLogger_FatalVariantsExit(t *testing.T) {
+	var codes []int
+	logger := New(Config{
+		Level:    InfoLevel,
+		Output:   io.Discard,
+		ExitFunc: func(code int) { codes = append(codes, code) },
+	})
+
+	logger.Fatalf("fatal %d", 1)
+	logger.WithStaticContext(context.Background()).Fatal("fatal")
+
+	assert.Equal(t, []int{1, 1}, codes)
+}
+
+func TestLogger_PanicFlushes(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:      InfoLevel,
+		Format:     TextFormat,
+		Output:     buf,
+		BufferSize: 4096,
+	})
+
+	logger.Info("buffered")
+	assert.Panics(t, func() { logger.Panic("boom") })
+
+	assert.Contains(t, buf.String(), "buffered")
+	assert.Contains(t, buf.String(), "PANIC boom")
+}
+
+func TestLogger_Log(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	exited := false
+	logger := New(Config{
+		Level:    InfoLevel,
+		Format:   TextFormat,
+		Output:   buf,
+		ExitFunc: func(int) { exited = true },
+	})
+
+	logger.Log(DebugLevel, "hidden")
+	logger.Log(WarnLevel, "warn", String("k", "v"))
+	assert.NotPanics(t, func() { logger.Log(PanicLevel, "no panic") })
+	logger.Log(FatalLevel, "no exit")
+
+	output := buf.String()
+	assert.NotContains(t, output, "hidden")
+	assert.Contains(t, output, "WARN warn k=v")
+	assert.Contains(t, output, "PANIC no panic")
+	assert.Contains(t, output, "FATAL no exit")
+	assert.False(t, exited)
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, level := range []Level{DebugLevel, InfoLevel, WarnLevel, ErrorLevel, FatalLevel, PanicLevel} {
+		parsed, err := ParseLevel(level.String())
+		require.NoError(t, err)
+		assert.Equal(t, level, parsed)
+
+		text, err := level.MarshalText()
+		require.NoError(t, err)
+		var unmarshaled Level
+		require.NoError(t, unmarshaled.UnmarshalText(text))
+		assert.Equal(t, level, unmarshaled)
+	}
+
+	_, err := ParseLevel("verbose")
+	assert.EqualError(t, err, `logger: unknown level "verbose"`)
+	_, err = Level(42).MarshalText()
+	assert.Error(t, err)
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, format := range []Format{TextFormat, JSONFormat, ConsoleFormat, ECSFormat, GCPFormat} {
+		parsed, err := ParseFormat(strings.ToUpper(format.String()))
+		require.NoError(t, err)
+		assert.Equal(t, format, parsed)
+	}
+
+	_, err := ParseFormat("xml")
+	assert.EqualError(t, err, `logger: unknown format "xml"`)
+	_, err = Format(42).MarshalText()
+	assert.Error(t, err)
+	assert.Equal(t, "unknown", Format(42).String())
+}
+
+func TestLevelFormat_TextMarshaling(t *testing.T) {
+	var config struct {
+		Level  Level  `json:"level"`
+		Format Format `json:"format"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"level":"WARN","format":"ecs"}`), &config))
+	assert.Equal(t, WarnLevel, config.Level)
+	assert.Equal(t, ECSFormat, config.Format)
+
+	data, err := json.Marshal(config)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"level":"warn","format":"ecs"}`, string(data))
+
+	assert.Error(t, json.Unmarshal([]byte(`{"level":"loud"}`), &config))
+	assert.Equal(t, WarnLevel, config.Level)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	level, format := InfoLevel, TextFormat
+	fs.TextVar(&level, "level", InfoLevel, "log level")
+	fs.TextVar(&format, "format", TextFormat, "log format")
+	require.NoError(t, fs.Parse([]string{"-level=debug", "-format=json"}))
+	assert.Equal(t, DebugLevel, level)
+	assert.Equal(t, JSONFormat, format)
+}
+
+func TestNop(t *testing.T) {
+	logger := Nop()
+	assert.False(t, logger.Enabled(PanicLevel))
+
+	allocs := testing.AllocsPerRun(100, func() {
+		logger.Error("nop", String("k", "v"), Int("n", 1))
+	})
+	assert.Zero(t, allocs)
+	logger.With(String("k", "v")).Named("child").Error("nop")
+
+	assert.PanicsWithValue(t, "nop", func() { logger.Panic("nop") })
+
+	var hooked []string
+	logger.SetLevel(InfoLevel)
+	logger.AddHook(HookFunc(func(e *Entry) error {
+		hooked = append(hooked, e.Message)
+		return nil
+	}))
+	logger.Info("seen by hooks")
+	assert.Equal(t, []string{"seen by hooks"}, hooked)
+	assert.NoError(t, logger.Close())
+}
+
+func TestLogger_DiscardSkipsEncoding(t *testing.T) {
+	calls := &atomic.Int32{}
+	logger := New(Config{
+		Level: InfoLevel,
+		Sinks: []Sink{{Output: io.Discard, Encoder: countingEncoder{calls: calls}}},
+	})
+
+	assert.False(t, logger.Enabled(ErrorLevel))
+	logger.Error("dropped")
+	assert.Zero(t, calls.Load())
+
+	buf := &bytes.Buffer{}
+	logger = New(Config{
+		Level: InfoLevel,
+		Sinks: []Sink{
+			{Output: io.Discard, Encoder: countingEncoder{calls: calls}},
+			{Output: buf, Format: TextFormat},
+		},
+	})
+	assert.True(t, logger.Enabled(InfoLevel))
+	logger.Info("written")
+	assert.Zero(t, calls.Load())
+	assert.Contains(t, buf.String(), "written")
+}
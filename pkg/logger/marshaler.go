@@ -0,0 +1,398 @@
+package logger
+
+import (
+	"sort"
+	"time"
+)
+
+// LogObjectMarshaler is implemented by types that can write themselves as a
+// structured object, without reflection. Use it with the Object field
+// constructor.
+//
+// Example:
+//
+//	func (u User) MarshalLogObject(enc logger.ObjectEncoder) error {
+//		enc.AddInt64("id", u.ID)
+//		enc.AddString("email", u.Email)
+//		return nil
+//	}
+type LogObjectMarshaler interface {
+	MarshalLogObject(enc ObjectEncoder) error
+}
+
+// LogArrayMarshaler is implemented by types that can write themselves as an
+// array, without reflection. Use it with the Array field constructor.
+type LogArrayMarshaler interface {
+	MarshalLogArray(enc ArrayEncoder) error
+}
+
+// ObjectEncoder adds key-value pairs to the object being marshaled.
+type ObjectEncoder interface {
+	AddString(key, val string)
+	AddInt(key string, val int)
+	AddInt64(key string, val int64)
+	AddUint64(key string, val uint64)
+	AddFloat64(key string, val float64)
+	AddBool(key string, val bool)
+	AddDuration(key string, val time.Duration)
+	AddTime(key string, val time.Time)
+	AddObject(key string, obj LogObjectMarshaler) error
+	AddArray(key string, arr LogArrayMarshaler) error
+}
+
+// ArrayEncoder appends elements to the array being marshaled.
+type ArrayEncoder interface {
+	AppendString(val string)
+	AppendInt(val int)
+	AppendInt64(val int64)
+	AppendUint64(val uint64)
+	AppendFloat64(val float64)
+	AppendBool(val bool)
+	AppendDuration(val time.Duration)
+	AppendTime(val time.Time)
+	AppendObject(obj LogObjectMarshaler) error
+	AppendArray(arr LogArrayMarshaler) error
+}
+
+// Object constructs a field with the given key holding a LogObjectMarshaler.
+// The object is encoded as a nested JSON object in both JSON and text output.
+func Object(key string, obj LogObjectMarshaler) Field {
+	return Field{Key: key, kind: objectKind, Value: obj}
+}
+
+// Array constructs a field with the given key holding a LogArrayMarshaler.
+// The array is encoded as a JSON array in both JSON and text output.
+func Array(key string, arr LogArrayMarshaler) Field {
+	return Field{Key: key, kind: arrayKind, Value: arr}
+}
+
+// Group constructs a field with the given key holding fields, encoded as a
+// nested object in both JSON and text output, instead of flattening them
+// with key prefixes:
+//
+//	log.Info("request", logger.Group("http",
+//		logger.String("method", "GET"),
+//		logger.Int("status", 200),
+//	))
+//	// {"message":"request","http":{"method":"GET","status":200}}
+//
+// Groups nest. Redaction and MaxValueBytes apply only to top-level fields,
+// not to the members of a group.
+func Group(key string, fields ...Field) Field {
+	return Object(key, fieldGroup(fields))
+}
+
+// fieldGroup is the LogObjectMarshaler of Group fields.
+type fieldGroup []Field
+
+func (g fieldGroup) MarshalLogObject(enc ObjectEncoder) error {
+	if enc, ok := enc.(*objectEncoder); ok {
+		for i := range g {
+			enc.addKey(g[i].Key)
+			enc.buf = appendJSONFieldValue(enc.buf, g[i])
+		}
+		return nil
+	}
+
+	for _, f := range g {
+		switch f = f.resolve(); f.kind {
+		case stringKind:
+			enc.AddString(f.Key, f.str)
+		case int64Kind:
+			enc.AddInt64(f.Key, f.num)
+		case float64Kind, boolKind, durationKind, timeKind, timeFullKind:
+			switch v := f.Interface().(type) {
+			case float64:
+				enc.AddFloat64(f.Key, v)
+			case bool:
+				enc.AddBool(f.Key, v)
+			case time.Duration:
+				enc.AddDuration(f.Key, v)
+			case time.Time:
+				enc.AddTime(f.Key, v)
+			}
+		case objectKind:
+			if err := enc.AddObject(f.Key, f.Value.(LogObjectMarshaler)); err != nil {
+				return err
+			}
+		case arrayKind:
+			if err := enc.AddArray(f.Key, f.Value.(LogArrayMarshaler)); err != nil {
+				return err
+			}
+		default:
+			enc.AddString(f.Key, fieldText(f))
+		}
+	}
+	return nil
+}
+
+// Strings constructs a field with the given key holding a slice of
+// strings, encoded as an array without reflection.
+func Strings(key string, vals []string) Field {
+	return Array(key, stringArray(vals))
+}
+
+// Ints constructs a field with the given key holding a slice of ints,
+// encoded as an array without reflection.
+func Ints(key string, vals []int) Field {
+	return Array(key, intArray(vals))
+}
+
+// stringArray is the LogArrayMarshaler of Strings fields.
+type stringArray []string
+
+func (a stringArray) MarshalLogArray(enc ArrayEncoder) error {
+	for _, v := range a {
+		enc.AppendString(v)
+	}
+	return nil
+}
+
+// intArray is the LogArrayMarshaler of Ints fields.
+type intArray []int
+
+func (a intArray) MarshalLogArray(enc ArrayEncoder) error {
+	for _, v := range a {
+		enc.AppendInt(v)
+	}
+	return nil
+}
+
+// anyArray is the LogArrayMarshaler of []interface{} values logged with
+// Any. Each element is encoded as by Any.
+type anyArray []interface{}
+
+func (a anyArray) MarshalLogArray(enc ArrayEncoder) error {
+	oe, ok := enc.(*objectEncoder)
+	for _, v := range a {
+		f := Any("", v)
+		if !ok {
+			enc.AppendString(fieldText(f))
+			continue
+		}
+		oe.separate()
+		oe.buf = appendJSONFieldValue(oe.buf, f)
+	}
+	return nil
+}
+
+// mapObject is the LogObjectMarshaler of map[string]interface{} values
+// logged with Any. Keys are sorted and each value is encoded as by Any.
+type mapObject map[string]interface{}
+
+func (m mapObject) MarshalLogObject(enc ObjectEncoder) error {
+	return m.fields().MarshalLogObject(enc)
+}
+
+// fields returns the entries of m as fields built by Any, sorted by key.
+func (m mapObject) fields() fieldGroup {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fields := make(fieldGroup, len(keys))
+	for i, key := range keys {
+		fields[i] = Any(key, m[key])
+	}
+	return fields
+}
+
+// unwrap returns the value held by an objectKind or arrayKind field built
+// by the constructors of this package, or the marshaler itself.
+func unwrap(v interface{}) interface{} {
+	switch v := v.(type) {
+	case fieldGroup:
+		return []Field(v)
+	case stringArray:
+		return []string(v)
+	case intArray:
+		return []int(v)
+	case anyArray:
+		return []interface{}(v)
+	case mapObject:
+		return map[string]interface{}(v)
+	default:
+		return v
+	}
+}
+
+// objectEncoder implements ObjectEncoder and ArrayEncoder by appending JSON
+// directly to buf.
+type objectEncoder struct {
+	buf   []byte
+	empty bool
+}
+
+func (enc *objectEncoder) separate() {
+	if !enc.empty {
+		enc.buf = append(enc.buf, ',')
+	}
+	enc.empty = false
+}
+
+func (enc *objectEncoder) addKey(key string) {
+	enc.separate()
+	enc.buf = append(enc.buf, '"')
+	enc.buf = appendJSONString(enc.buf, key)
+	enc.buf = append(enc.buf, '"', ':')
+}
+
+func (enc *objectEncoder) AddString(key, val string) {
+	enc.addKey(key)
+	enc.appendString(val)
+}
+
+func (enc *objectEncoder) AddInt(key string, val int) {
+	enc.addKey(key)
+	enc.buf = appendInt(enc.buf, int64(val))
+}
+
+func (enc *objectEncoder) AddInt64(key string, val int64) {
+	enc.addKey(key)
+	enc.buf = appendInt(enc.buf, val)
+}
+
+func (enc *objectEncoder) AddUint64(key string, val uint64) {
+	enc.addKey(key)
+	enc.buf = appendUint(enc.buf, val)
+}
+
+func (enc *objectEncoder) AddFloat64(key string, val float64) {
+	enc.addKey(key)
+	enc.buf = appendJSONFloat(enc.buf, val, 64)
+}
+
+func (enc *objectEncoder) AddBool(key string, val bool) {
+	enc.addKey(key)
+	enc.buf = appendBool(enc.buf, val)
+}
+
+func (enc *objectEncoder) AddDuration(key string, val time.Duration) {
+	enc.addKey(key)
+	enc.buf = appendJSONValue(enc.buf, val)
+}
+
+func (enc *objectEncoder) AddTime(key string, val time.Time) {
+	enc.addKey(key)
+	enc.buf = appendJSONValue(enc.buf, val)
+}
+
+func (enc *objectEncoder) AddObject(key string, obj LogObjectMarshaler) error {
+	enc.addKey(key)
+	return enc.appendObject(obj)
+}
+
+func (enc *objectEncoder) AddArray(key string, arr LogArrayMarshaler) error {
+	enc.addKey(key)
+	return enc.appendArray(arr)
+}
+
+func (enc *objectEncoder) AppendString(val string) {
+	enc.separate()
+	enc.appendString(val)
+}
+
+func (enc *objectEncoder) AppendInt(val int) {
+	enc.separate()
+	enc.buf = appendInt(enc.buf, int64(val))
+}
+
+func (enc *objectEncoder) AppendInt64(val int64) {
+	enc.separate()
+	enc.buf = appendInt(enc.buf, val)
+}
+
+func (enc *objectEncoder) AppendUint64(val uint64) {
+	enc.separate()
+	enc.buf = appendUint(enc.buf, val)
+}
+
+func (enc *objectEncoder) AppendFloat64(val float64) {
+	enc.separate()
+	enc.buf = appendJSONFloat(enc.buf, val, 64)
+}
+
+func (enc *objectEncoder) AppendBool(val bool) {
+	enc.separate()
+	enc.buf = appendBool(enc.buf, val)
+}
+
+func (enc *objectEncoder) AppendDuration(val time.Duration) {
+	enc.separate()
+	enc.buf = appendJSONValue(enc.buf, val)
+}
+
+func (enc *objectEncoder) AppendTime(val time.Time) {
+	enc.separate()
+	enc.buf = appendJSONValue(enc.buf, val)
+}
+
+func (enc *objectEncoder) AppendObject(obj LogObjectMarshaler) error {
+	enc.separate()
+	return enc.appendObject(obj)
+}
+
+func (enc *objectEncoder) AppendArray(arr LogArrayMarshaler) error {
+	enc.separate()
+	return enc.appendArray(arr)
+}
+
+func (enc *objectEncoder) appendString(val string) {
+	enc.buf = append(enc.buf, '"')
+	enc.buf = appendJSONString(enc.buf, val)
+	enc.buf = append(enc.buf, '"')
+}
+
+func (enc *objectEncoder) appendObject(obj LogObjectMarshaler) error {
+	if obj == nil {
+		enc.buf = append(enc.buf, "null"...)
+		return nil
+	}
+
+	enc.buf = append(enc.buf, '{')
+	nested := objectEncoder{buf: enc.buf, empty: true}
+	err := obj.MarshalLogObject(&nested)
+	enc.buf = append(nested.buf, '}')
+	return err
+}
+
+func (enc *objectEncoder) appendArray(arr LogArrayMarshaler) error {
+	if arr == nil {
+		enc.buf = append(enc.buf, "null"...)
+		return nil
+	}
+
+	enc.buf = append(enc.buf, '[')
+	nested := objectEncoder{buf: enc.buf, empty: true}
+	err := arr.MarshalLogArray(&nested)
+	enc.buf = append(nested.buf, ']')
+	return err
+}
+
+// appendMarshaler appends the JSON encoding of a LogObjectMarshaler or
+// LogArrayMarshaler field value. If marshaling fails, the partial output is
+// discarded and the error is written in its place.
+func appendMarshaler(buf []byte, f *Field) []byte {
+	start := len(buf)
+	enc := objectEncoder{buf: buf}
+
+	var err error
+	switch v := f.Value.(type) {
+	case LogObjectMarshaler:
+		err = enc.appendObject(v)
+	case LogArrayMarshaler:
+		err = enc.appendArray(v)
+	default:
+		enc.buf = append(enc.buf, "null"...)
+	}
+
+	if err != nil {
+		buf = enc.buf[:start]
+		buf = append(buf, '"')
+		buf = appendJSONString(buf, "!marshal: "+err.Error())
+		return append(buf, '"')
+	}
+	return enc.buf
+}
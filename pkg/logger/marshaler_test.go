@@ -0,0 +1,203 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testUser struct {
+	ID    int64
+	Email string
+	Roles testRoles
+}
+
+func (u testUser) MarshalLogObject(enc ObjectEncoder) error {
+	enc.AddInt64("id", u.ID)
+	enc.AddString("email", u.Email)
+	enc.AddDuration("session", 90*time.Second)
+	return enc.AddArray("roles", u.Roles)
+}
+
+type testRoles []string
+
+func (r testRoles) MarshalLogArray(enc ArrayEncoder) error {
+	for _, role := range r {
+		enc.AppendString(role)
+	}
+	return nil
+}
+
+type failingObject struct{}
+
+func (failingObject) MarshalLogObject(enc ObjectEncoder) error {
+	enc.AddString("partial", "value")
+	return errors.New("cannot marshal")
+}
+
+func TestObjectField_JSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: JSONFormat,
+		Output: buf,
+	})
+
+	user := testUser{ID: 7, Email: "user@example.com", Roles: testRoles{"admin", "dev"}}
+	logger.Info("login", Object("user", user), Array("roles", user.Roles), Int("n", 1))
+
+	output := buf.String()
+	assert.Contains(t, output,
+		`"user":{"id":7,"email":"user@example.com","session":"1m30s","roles":["admin","dev"]},"roles":["admin","dev"],"n":1}`)
+}
+
+func TestObjectField_Text(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: TextFormat,
+		Output: buf,
+	})
+
+	logger.Info("login", Object("user", testUser{ID: 7, Email: "a@b.c"}))
+
+	assert.Contains(t, buf.String(), `user={"id":7,"email":"a@b.c","session":"1m30s","roles":[]}`)
+}
+
+func TestObjectField_Error(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: JSONFormat,
+		Output: buf,
+	})
+
+	logger.Info("login", Object("obj", failingObject{}), Object("nil", nil))
+
+	output := buf.String()
+	assert.Contains(t, output, `"obj":"!marshal: cannot marshal","nil":null}`)
+	assert.NotContains(t, output, "partial")
+}
+
+func TestGroup(t *testing.T) {
+	group := Group("http",
+		String("method", "GET"),
+		Int("status", 200),
+		Group("timing", Dur("total", 1500*time.Millisecond)),
+	)
+
+	buf := &bytes.Buffer{}
+	logger := New(Config{Format: JSONFormat, Output: buf, OmitTime: true})
+	logger.Info("request", group)
+	assert.Equal(t, `{"level":"INFO","message":"request","http":{"method":"GET","status":200,"timing":{"total":"1.5s"}}}`+"\n", buf.String())
+
+	buf.Reset()
+	logger = New(Config{Format: TextFormat, Output: buf, OmitTime: true})
+	logger.With(Group("svc", String("name", "billing"))).Info("request", group)
+	assert.Equal(t, `INFO request svc={"name":"billing"} http={"method":"GET","status":200,"timing":{"total":"1.5s"}}`+"\n", buf.String())
+
+	buf.Reset()
+	logger.Info("empty", Group("none"))
+	assert.Equal(t, "INFO empty none={}\n", buf.String())
+}
+
+// mapObjectEncoder is an ObjectEncoder other than the built-in one,
+// recording values by key.
+type mapObjectEncoder map[string]interface{}
+
+func (m mapObjectEncoder) AddString(key, val string)                 { m[key] = val }
+func (m mapObjectEncoder) AddInt(key string, val int)                { m[key] = val }
+func (m mapObjectEncoder) AddInt64(key string, val int64)            { m[key] = val }
+func (m mapObjectEncoder) AddUint64(key string, val uint64)          { m[key] = val }
+func (m mapObjectEncoder) AddFloat64(key string, val float64)        { m[key] = val }
+func (m mapObjectEncoder) AddBool(key string, val bool)              { m[key] = val }
+func (m mapObjectEncoder) AddDuration(key string, val time.Duration) { m[key] = val }
+func (m mapObjectEncoder) AddTime(key string, val time.Time)         { m[key] = val }
+func (m mapObjectEncoder) AddArray(key string, _ LogArrayMarshaler) error {
+	m[key] = "array"
+	return nil
+}
+func (m mapObjectEncoder) AddObject(key string, obj LogObjectMarshaler) error {
+	nested := mapObjectEncoder{}
+	m[key] = nested
+	return obj.MarshalLogObject(nested)
+}
+
+func TestGroup_OtherObjectEncoder(t *testing.T) {
+	enc := mapObjectEncoder{}
+	err := fieldGroup{
+		String("s", "v"),
+		Int("i", 1),
+		Bool("b", true),
+		Dur("d", time.Second),
+		Err(errors.New("failed")),
+		Group("nested", Float64("f", 0.5)),
+	}.MarshalLogObject(enc)
+
+	assert.NoError(t, err)
+	assert.Equal(t, mapObjectEncoder{
+		"s":      "v",
+		"i":      int64(1),
+		"b":      true,
+		"d":      time.Second,
+		"error":  "failed",
+		"nested": mapObjectEncoder{"f": 0.5},
+	}, enc)
+}
+
+func TestAny_SlicesAndMaps(t *testing.T) {
+	fields := []Field{
+		Any("tags", []string{"a", "b c"}),
+		Any("ids", []int{1, 2}),
+		Any("mixed", []interface{}{"x", 1, errors.New("failed"), nil}),
+		Any("attrs", []Field{String("k", "v"), Int("n", 1)}),
+		Any("meta", map[string]interface{}{"z": 1, "err": errors.New("boom"), "list": []string{"q"}}),
+		Strings("empty", nil),
+	}
+
+	buf := &bytes.Buffer{}
+	logger := New(Config{Format: JSONFormat, Output: buf, OmitTime: true})
+	logger.Info("m", fields...)
+	assert.Equal(t, `{"level":"INFO","message":"m","tags":["a","b c"],"ids":[1,2],"mixed":["x",1,"failed",null],`+
+		`"attrs":{"k":"v","n":1},"meta":{"err":"boom","list":["q"],"z":1},"empty":[]}`+"\n", buf.String())
+
+	buf.Reset()
+	logger = New(Config{Format: TextFormat, Output: buf, OmitTime: true})
+	logger.Info("m", fields[0], fields[1])
+	assert.Equal(t, `INFO m tags=["a","b c"] ids=[1,2]`+"\n", buf.String())
+
+	assert.Equal(t, []string{"a", "b c"}, fields[0].Interface())
+	assert.Equal(t, []int{1, 2}, fields[1].Interface())
+	assert.Equal(t, []Field{String("k", "v"), Int("n", 1)}, fields[3].Interface())
+}
+
+// jsonUser implements both LogObjectMarshaler and json.Marshaler.
+type jsonUser struct{ Name string }
+
+func (u jsonUser) MarshalLogObject(enc ObjectEncoder) error {
+	enc.AddString("custom_name", u.Name)
+	return nil
+}
+
+func (u jsonUser) MarshalJSON() ([]byte, error) { return []byte(`{"json_name":"` + u.Name + `"}`), nil }
+
+func TestAny_Marshalers(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(Config{Format: JSONFormat, Output: buf, OmitTime: true})
+
+	user := testUser{ID: 7, Email: "user@example.com", Roles: testRoles{"admin"}}
+	logger.Info("m", Any("user", user), Any("roles", user.Roles), Any("u", jsonUser{Name: "bob"}))
+	assert.Equal(t, `{"level":"INFO","message":"m",`+
+		`"user":{"id":7,"email":"user@example.com","session":"1m30s","roles":["admin"]},`+
+		`"roles":["admin"],"u":{"custom_name":"bob"}}`+"\n", buf.String())
+
+	buf.Reset()
+	logger.Infow("m", "u", jsonUser{Name: "bob"})
+	assert.Equal(t, `{"level":"INFO","message":"m","u":{"custom_name":"bob"}}`+"\n", buf.String())
+}
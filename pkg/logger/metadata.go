@@ -0,0 +1,63 @@
+package logger
+
+import (
+	"os"
+	"runtime/debug"
+)
+
+// Keys of the fields bound to every entry by Config.AddHostInfo,
+// Config.Service, Config.Env, Config.Version and Config.AddBuildInfo.
+const (
+	HostnameKey = "hostname"
+	PIDKey      = "pid"
+	ServiceKey  = "service"
+	EnvKey      = "env"
+	VersionKey  = "version"
+	BuildKey    = "build"
+)
+
+// metadataFields returns the fields describing the process set by config,
+// ahead of config.Fields. The hostname is left out if it cannot be read.
+func metadataFields(config *Config) []Field {
+	var fields []Field
+	if config.AddHostInfo {
+		if hostname, err := os.Hostname(); err == nil {
+			fields = append(fields, String(HostnameKey, hostname))
+		}
+		fields = append(fields, Int(PIDKey, os.Getpid()))
+	}
+	if config.Service != "" {
+		fields = append(fields, String(ServiceKey, config.Service))
+	}
+	if config.Env != "" {
+		fields = append(fields, String(EnvKey, config.Env))
+	}
+	if config.Version != "" {
+		fields = append(fields, String(VersionKey, config.Version))
+	}
+	if config.AddBuildInfo {
+		if info, ok := debug.ReadBuildInfo(); ok {
+			fields = append(fields, buildField(info))
+		}
+	}
+	if len(fields) == 0 {
+		return config.Fields
+	}
+	return append(fields, config.Fields...)
+}
+
+// buildField returns the BuildKey group describing the binary of info: the
+// version of its main module, and the VCS revision it was built from and
+// whether the work tree had uncommitted changes, when recorded.
+func buildField(info *debug.BuildInfo) Field {
+	fields := []Field{String("version", info.Main.Version)}
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			fields = append(fields, String("revision", s.Value))
+		case "vcs.modified":
+			fields = append(fields, Bool("dirty", s.Value == "true"))
+		}
+	}
+	return Group(BuildKey, fields...)
+}
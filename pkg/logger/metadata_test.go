@@ -0,0 +1,90 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"runtime/debug"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Metadata(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := New(Config{
+		Level:       InfoLevel,
+		Format:      JSONFormat,
+		Output:      buf,
+		OmitTime:    true,
+		AddHostInfo: true,
+		Service:     "billing",
+		Env:         "prod",
+		Version:     "1.4.2",
+		Fields:      []Field{String("team", "payments")},
+	})
+	log.Info("started")
+
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, map[string]interface{}{
+		"level":    "INFO",
+		"message":  "started",
+		"hostname": hostname,
+		"pid":      float64(os.Getpid()),
+		"service":  "billing",
+		"env":      "prod",
+		"version":  "1.4.2",
+		"team":     "payments",
+	}, entry)
+}
+
+func TestConfig_MetadataUnset(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := New(Config{Level: InfoLevel, Format: JSONFormat, Output: buf, OmitTime: true, Service: "api"})
+	log.Info("started")
+
+	assert.Equal(t, `{"level":"INFO","message":"started","service":"api"}`+"\n", buf.String())
+}
+
+func TestConfig_MetadataNoAllocations(t *testing.T) {
+	log := New(Config{Level: InfoLevel, Format: JSONFormat, Output: io.Discard, AddHostInfo: true, Service: "api"})
+
+	allocs := testing.AllocsPerRun(100, func() {
+		log.Info("request", Int("status", 200))
+	})
+	assert.Zero(t, allocs)
+}
+
+func TestConfig_AddBuildInfo(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := New(Config{Level: InfoLevel, Format: JSONFormat, Output: buf, OmitTime: true, AddBuildInfo: true})
+	log.Info("started")
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	require.Contains(t, entry, BuildKey)
+	assert.Contains(t, entry[BuildKey], "version")
+}
+
+func TestBuildField(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := New(Config{Level: InfoLevel, Format: JSONFormat, Output: buf, OmitTime: true})
+
+	log.Info("started", buildField(&debug.BuildInfo{
+		Main: debug.Module{Path: "example.com/app", Version: "v1.4.2"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs", Value: "git"},
+			{Key: "vcs.revision", Value: "4bf92f3577b3"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}))
+	log.Info("started", buildField(&debug.BuildInfo{Main: debug.Module{Version: "(devel)"}}))
+
+	assert.Equal(t, `{"level":"INFO","message":"started","build":{"version":"v1.4.2","revision":"4bf92f3577b3","dirty":true}}`+"\n"+
+		`{"level":"INFO","message":"started","build":{"version":"(devel)"}}`+"\n", buf.String())
+}
@@ -0,0 +1,111 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NameKey is the key under which the name of a logger built with Named is
+// logged.
+const NameKey = "logger"
+
+// Named returns a child logger whose name is name appended to the name of
+// l with a dot, such as "http.client" for Named("http").Named("client").
+// The name is logged under NameKey and selects the level of the child
+// among those set with Config.Levels or SetLevels.
+//
+// Like With, the child shares its outputs with l.
+func (l *Logger) Named(name string) *Logger {
+	if name == "" {
+		return l
+	}
+	if l.name != "" {
+		name = l.name + "." + name
+	}
+
+	child := *l
+	child.name = name
+	child.contexts = make([][]byte, len(l.contexts))
+	nameField := []Field{String(NameKey, name)}
+	for i, enc := range l.core.encs {
+		context := make([]byte, 0, len(l.bound[i])+len(name)+16)
+		context = enc.EncodeFields(context, nameField)
+		child.contexts[i] = append(context, l.bound[i]...)
+	}
+
+	return &child
+}
+
+// Name returns the name of the logger, or "" if it was not built with
+// Named.
+func (l *Logger) Name() string {
+	return l.name
+}
+
+// SetLevels atomically replaces the minimum levels of named loggers.
+// Each key of levels applies to the loggers of that name and to their
+// descendants, unless one of them has its own key: with "http" set to
+// DebugLevel and "http.server" to WarnLevel, "http.client" logs at
+// DebugLevel and "http.server.tls" at WarnLevel. The key "*" sets the
+// logger-wide level, like SetLevel, which applies to every other logger.
+//
+// Like SetLevel, it affects l and every logger sharing its outputs.
+func (l *Logger) SetLevels(levels map[string]Level) {
+	names := make(map[string]Level, len(levels))
+	for name, level := range levels {
+		if name == "*" {
+			l.core.level.Store(int32(level))
+			continue
+		}
+		names[name] = level
+	}
+
+	if len(names) == 0 {
+		l.core.names.Store(nil)
+		return
+	}
+	l.core.names.Store(&names)
+}
+
+// nameLevel returns the level set for name or its closest ancestor.
+func nameLevel(levels map[string]Level, name string) (Level, bool) {
+	for {
+		if level, ok := levels[name]; ok {
+			return level, true
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			return 0, false
+		}
+		name = name[:i]
+	}
+}
+
+// ParseLevels parses a comma-separated list of name=level pairs, such as
+// "http=debug,db=warn,*=info", for Config.Levels and SetLevels. A level
+// without a name is the same as "*=level".
+func ParseLevels(spec string) (map[string]Level, error) {
+	levels := make(map[string]Level)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			name, value = "*", part
+		}
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("logger: missing logger name in %q", part)
+		}
+
+		level, err := ParseLevel(value)
+		if err != nil {
+			return nil, err
+		}
+		levels[name] = level
+	}
+	return levels, nil
+}
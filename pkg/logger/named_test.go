@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger_Named(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(Config{Level: InfoLevel, Format: JSONFormat, Output: buf, OmitTime: true})
+
+	client := logger.With(String("service", "billing")).Named("http").Named("client")
+	assert.Equal(t, "http.client", client.Name())
+	assert.Equal(t, "", logger.Name())
+	assert.Same(t, logger, logger.Named(""))
+
+	client.Info("request sent")
+	client.With(Int("attempt", 2)).Named("retry").Info("retrying")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"logger":"http.client","service":"billing"}`)
+	assert.Contains(t, lines[1], `"logger":"http.client.retry","service":"billing","attempt":2}`)
+	assert.Equal(t, 1, strings.Count(lines[1], `"logger"`))
+}
+
+func TestLogger_NamedLevels(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: TextFormat,
+		Output: buf,
+		Levels: map[string]Level{"http": DebugLevel, "http.server": WarnLevel, "db": ErrorLevel},
+	})
+
+	logger.Debug("root debug")
+	logger.Named("http").Named("client").Debug("client debug")
+	logger.Named("http").Named("server").Named("tls").Info("tls info")
+	logger.Named("db").Warn("db warn")
+	logger.Named("cache").Info("cache info")
+
+	output := buf.String()
+	assert.NotContains(t, output, "root debug")
+	assert.Contains(t, output, "client debug logger=http.client")
+	assert.NotContains(t, output, "tls info")
+	assert.NotContains(t, output, "db warn")
+	assert.Contains(t, output, "cache info logger=cache")
+
+	db := logger.Named("db")
+	assert.Equal(t, ErrorLevel, db.GetLevel())
+	logger.SetLevels(map[string]Level{"db": DebugLevel, "*": WarnLevel})
+	assert.Equal(t, DebugLevel, db.GetLevel())
+	assert.Equal(t, WarnLevel, logger.GetLevel())
+	assert.Equal(t, WarnLevel, logger.Named("http").GetLevel())
+
+	logger.SetLevels(nil)
+	assert.Equal(t, WarnLevel, db.GetLevel())
+}
+
+func TestParseLevels(t *testing.T) {
+	levels, err := ParseLevels(" http=debug, db=WARN ,*=info,")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Level{"http": DebugLevel, "db": WarnLevel, "*": InfoLevel}, levels)
+
+	levels, err = ParseLevels("error,auth=debug")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Level{"*": ErrorLevel, "auth": DebugLevel}, levels)
+
+	_, err = ParseLevels("db=loud")
+	assert.EqualError(t, err, `logger: unknown level "loud"`)
+
+	_, err = ParseLevels("=info")
+	assert.EqualError(t, err, `logger: missing logger name in "=info"`)
+}
+
+func TestConfigFromEnv_Levels(t *testing.T) {
+	t.Setenv(EnvLogLevel, "info")
+	t.Setenv(EnvLogLevels, "db=debug,auth=warn")
+
+	config := ConfigFromEnv()
+	assert.Equal(t, InfoLevel, config.Level)
+	assert.Equal(t, map[string]Level{"db": DebugLevel, "auth": WarnLevel}, config.Levels)
+
+	logger := New(config)
+	assert.Equal(t, DebugLevel, logger.Named("db").GetLevel())
+	assert.Equal(t, InfoLevel, logger.Named("api").GetLevel())
+
+	t.Setenv(EnvLogLevels, "db=verbose")
+	assert.Nil(t, ConfigFromEnv().Levels)
+}
@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Pooled entry buffers come in size classes of minPooledBufferSize bytes
+// times a power of four, up to maxPooledBufferSize. Larger buffers, grown
+// by rare huge entries, are left to the garbage collector rather than
+// kept alive by the pool.
+const (
+	minPooledBufferSize = 256
+	maxPooledBufferSize = 64 << 10
+	bufferClasses       = 5
+)
+
+// bufferPool recycles the buffers entries are encoded into. Buffers are
+// pooled by pointer, with the length and capacity they grew to, in the
+// size class of their capacity. get hands out buffers of the class fitting
+// the entries last returned, so that a logger writing large entries does
+// not regrow small buffers for each of them.
+type bufferPool struct {
+	pools [bufferClasses]sync.Pool
+	// class is the size class of the last entry returned by put.
+	class atomic.Int32
+}
+
+// get returns an empty buffer, with the capacity of the class of recent
+// entries.
+func (p *bufferPool) get() *[]byte {
+	class := p.class.Load()
+	if bufPtr, _ := p.pools[class].Get().(*[]byte); bufPtr != nil {
+		return bufPtr
+	}
+	buf := make([]byte, 0, bufferClassSize(class))
+	return &buf
+}
+
+// put returns a buffer to the pool. *bufPtr must hold the entry encoded
+// into it, so that its size is accounted for and its grown capacity kept.
+func (p *bufferPool) put(bufPtr *[]byte) {
+	buf := *bufPtr
+	if class := bufferClassOf(len(buf)); class != p.class.Load() {
+		p.class.Store(class)
+	}
+	if cap(buf) > maxPooledBufferSize {
+		return
+	}
+
+	// A buffer goes to the largest class it can hold entries of.
+	class := int32(bufferClasses - 1)
+	for class > 0 && cap(buf) < bufferClassSize(class) {
+		class--
+	}
+	*bufPtr = buf[:0]
+	p.pools[class].Put(bufPtr)
+}
+
+// bufferClassSize returns the capacity of buffers of class.
+func bufferClassSize(class int32) int {
+	return minPooledBufferSize << (2 * class)
+}
+
+// bufferClassOf returns the smallest class holding n bytes, or the largest
+// class.
+func bufferClassOf(n int) int32 {
+	class := int32(0)
+	for class < bufferClasses-1 && n > bufferClassSize(class) {
+		class++
+	}
+	return class
+}
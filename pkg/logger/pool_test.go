@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBufferClasses(t *testing.T) {
+	assert.Equal(t, []int{256, 1024, 4096, 16384, 65536}, []int{
+		bufferClassSize(0), bufferClassSize(1), bufferClassSize(2), bufferClassSize(3), bufferClassSize(4),
+	})
+	assert.Equal(t, maxPooledBufferSize, bufferClassSize(bufferClasses-1))
+
+	tests := map[int]int32{0: 0, 256: 0, 257: 1, 1024: 1, 4000: 2, 16384: 3, 20000: 4, 1 << 20: 4}
+	for n, class := range tests {
+		assert.Equal(t, class, bufferClassOf(n), n)
+	}
+}
+
+func TestBufferPool_KeepsGrownBuffers(t *testing.T) {
+	var p bufferPool
+
+	bufPtr := p.get()
+	assert.Equal(t, 256, cap(*bufPtr))
+	*bufPtr = append(*bufPtr, strings.Repeat("x", 3000)...)
+	p.put(bufPtr)
+
+	// The pool now hands out buffers fitting 3000 bytes, whether the one
+	// returned or a new one.
+	assert.Equal(t, int32(2), p.class.Load())
+	got := p.get()
+	assert.Empty(t, *got)
+	assert.GreaterOrEqual(t, cap(*got), 4096)
+}
+
+func TestBufferPool_DropsHugeBuffers(t *testing.T) {
+	var p bufferPool
+
+	huge := make([]byte, maxPooledBufferSize+1)
+	p.put(&huge)
+	for class := range p.pools {
+		assert.Nil(t, p.pools[class].Get(), "class %d", class)
+	}
+	assert.Equal(t, int32(bufferClasses-1), p.class.Load())
+}
+
+func TestLogger_PoolTracksEntrySize(t *testing.T) {
+	log := New(Config{Level: InfoLevel, Format: JSONFormat, Output: discardWriter})
+
+	log.Info(strings.Repeat("m", 2000))
+	assert.Equal(t, int32(2), log.core.pool.class.Load())
+
+	log.Info("short")
+	assert.Equal(t, int32(0), log.core.pool.class.Load())
+}
+
+func TestLogger_LargeEntriesNoAllocations(t *testing.T) {
+	log := New(Config{Level: InfoLevel, Format: JSONFormat, Output: discardWriter})
+	msg := strings.Repeat("m", 3000)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		log.Info(msg)
+	})
+	assert.Zero(t, allocs)
+}
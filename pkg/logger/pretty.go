@@ -0,0 +1,89 @@
+package logger
+
+// prettyIndent is the indentation of each nesting level in PrettyFormat.
+const prettyIndent = "  "
+
+// prettyEncoder is the Encoder used for PrettyFormat. It encodes entries
+// as JSONFormat does, then indents them.
+type prettyEncoder struct {
+	jsonEncoder
+	color bool
+}
+
+// EncodeEntry formats a log entry as indented, multi-line JSON. Keys are
+// colored when enc.color is set.
+func (enc prettyEncoder) EncodeEntry(buf []byte, e *Entry) []byte {
+	start := len(buf)
+	buf = enc.jsonEncoder.EncodeEntry(buf, e)
+
+	// The compact entry is indented into the free capacity after it, then
+	// moved back over it.
+	compact := buf[start:]
+	buf = appendPrettyJSON(buf, compact, enc.color)
+	return append(buf[:start], buf[start+len(compact):]...)
+}
+
+// appendPrettyJSON appends the compact JSON document src indented by
+// prettyIndent, with object keys in blue when color is set.
+func appendPrettyJSON(buf, src []byte, color bool) []byte {
+	// objects tracks, for each open container, whether it is an object;
+	// key is set when the next string of an object is a key.
+	var stack [16]bool
+	objects := stack[:0]
+	key := false
+
+	newline := func() {
+		buf = append(buf, '\n')
+		for range objects {
+			buf = append(buf, prettyIndent...)
+		}
+	}
+
+	for i := 0; i < len(src); i++ {
+		switch c := src[i]; c {
+		case '{', '[':
+			if i+1 < len(src) && (src[i+1] == '}' || src[i+1] == ']') {
+				buf = append(buf, c, src[i+1])
+				i++
+				continue
+			}
+			objects = append(objects, c == '{')
+			key = c == '{'
+			buf = append(buf, c)
+			newline()
+		case '}', ']':
+			objects = objects[:len(objects)-1]
+			newline()
+			buf = append(buf, c)
+		case ',':
+			key = len(objects) > 0 && objects[len(objects)-1]
+			buf = append(buf, c)
+			newline()
+		case ':':
+			buf = append(buf, ':', ' ')
+		case '"':
+			end := i + 1
+			for end < len(src) && src[end] != '"' {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(src) {
+				end = len(src) - 1
+			}
+			if key && color {
+				buf = append(buf, colorBlue...)
+				buf = append(buf, src[i:end+1]...)
+				buf = append(buf, colorReset...)
+			} else {
+				buf = append(buf, src[i:end+1]...)
+			}
+			key = false
+			i = end
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return buf
+}
@@ -0,0 +1,56 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrettyFormat(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:    InfoLevel,
+		Format:   PrettyFormat,
+		Output:   buf,
+		OmitTime: true,
+	})
+
+	logger.With(String("service", "api")).Info("request",
+		String("path", `/a,b:{c}"`),
+		Group("http", Int("status", 200), Strings("tags", []string{"x", "y"})),
+		Strings("empty", nil),
+		Any("none", map[string]interface{}{}),
+	)
+
+	assert.Equal(t, `{
+  "level": "INFO",
+  "message": "request",
+  "service": "api",
+  "path": "/a,b:{c}\"",
+  "http": {
+    "status": 200,
+    "tags": [
+      "x",
+      "y"
+    ]
+  },
+  "empty": [],
+  "none": {}
+}
+`, buf.String())
+}
+
+func TestPrettyFormat_Color(t *testing.T) {
+	e := &Entry{Level: WarnLevel, Message: "m", Fields: []Field{Strings("k", []string{"v"})}}
+	enc := prettyEncoder{jsonEncoder: jsonEncoder{encoderOptions{omitTime: true}}, color: true}
+
+	assert.Equal(t, "{\n  "+colorBlue+`"level"`+colorReset+`: "WARN",`+"\n  "+
+		colorBlue+`"message"`+colorReset+`: "m",`+"\n  "+
+		colorBlue+`"k"`+colorReset+": [\n    \"v\"\n  ]\n}", string(enc.EncodeEntry([]byte("prefix"), e))[len("prefix"):])
+
+	format, err := ParseFormat("Pretty")
+	assert.NoError(t, err)
+	assert.Equal(t, PrettyFormat, format)
+}
@@ -0,0 +1,155 @@
+package logger
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultRateLimitInterval is the window used when RateLimitConfig.Interval is zero.
+const DefaultRateLimitInterval = time.Second
+
+// RateLimitConfig caps how many similar entries are written per interval.
+// Entries are similar when they share the message or, if KeyField is set,
+// the value of that field.
+//
+// At the end of every interval in which entries were suppressed, a summary
+// entry "suppressed N similar entries" is written at the highest level that
+// was suppressed, carrying the rate limit key and the suppressed count.
+type RateLimitConfig struct {
+	// Interval is the length of a rate limiting window.
+	// Defaults to DefaultRateLimitInterval.
+	Interval time.Duration
+
+	// Limit is the number of similar entries written per Interval.
+	Limit int
+
+	// KeyField groups entries by the value of the field with this key
+	// instead of by message. Entries without the field are grouped by message.
+	KeyField string
+}
+
+// rateLimiter counts similar entries per window and periodically reports
+// how many were suppressed.
+type rateLimiter struct {
+	interval time.Duration
+	limit    int
+	keyField string
+
+	mu      sync.Mutex
+	buckets map[string]*rateBucket
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+type rateBucket struct {
+	count      int
+	suppressed int
+	level      Level
+}
+
+func newRateLimiter(config *RateLimitConfig) *rateLimiter {
+	interval := config.Interval
+	if interval <= 0 {
+		interval = DefaultRateLimitInterval
+	}
+
+	return &rateLimiter{
+		interval: interval,
+		limit:    config.Limit,
+		keyField: config.KeyField,
+		buckets:  make(map[string]*rateBucket),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// allow reports whether an entry may be written in the current window.
+func (r *rateLimiter) allow(level Level, msg string, fields []Field) bool {
+	key := r.key(msg, fields)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, ok := r.buckets[key]
+	if !ok {
+		b = &rateBucket{}
+		r.buckets[key] = b
+	}
+
+	b.count++
+	if b.count <= r.limit {
+		return true
+	}
+
+	b.suppressed++
+	if level > b.level || b.suppressed == 1 {
+		b.level = level
+	}
+	return false
+}
+
+func (r *rateLimiter) key(msg string, fields []Field) string {
+	if r.keyField == "" {
+		return msg
+	}
+	for i := range fields {
+		if fields[i].Key == r.keyField {
+			return fieldText(fields[i])
+		}
+	}
+	return msg
+}
+
+// run resets the windows every interval and writes summaries through l
+// until close is called.
+func (r *rateLimiter) run(l *Logger) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.flush(l)
+		case <-r.stop:
+			r.flush(l)
+			return
+		}
+	}
+}
+
+// flush writes a summary for every key with suppressed entries and starts
+// a new window.
+func (r *rateLimiter) flush(l *Logger) {
+	r.mu.Lock()
+	buckets := r.buckets
+	r.buckets = make(map[string]*rateBucket, len(buckets))
+	r.mu.Unlock()
+
+	for key, b := range buckets {
+		if b.suppressed == 0 {
+			continue
+		}
+		l.emit(&Entry{
+			Time:    l.now(),
+			Level:   b.level,
+			Message: "suppressed " + strconv.Itoa(b.suppressed) + " similar entries",
+			Fields: []Field{
+				String("rate_limit_key", key),
+				Int("suppressed", b.suppressed),
+			},
+		})
+	}
+}
+
+// close stops the background goroutine after writing pending summaries.
+func (r *rateLimiter) close() {
+	r.once.Do(func() {
+		close(r.stop)
+	})
+	<-r.done
+}
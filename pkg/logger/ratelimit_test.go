@@ -0,0 +1,82 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger_RateLimitByMessage(t *testing.T) {
+	buf := &syncBuffer{}
+
+	logger := New(Config{
+		Level:     InfoLevel,
+		Format:    JSONFormat,
+		Output:    buf,
+		RateLimit: &RateLimitConfig{Interval: time.Hour, Limit: 2},
+	})
+
+	for i := 0; i < 5; i++ {
+		logger.Warn("db timeout")
+	}
+	logger.Error("db timeout")
+	logger.Info("other")
+
+	require.NoError(t, logger.Close())
+
+	output := buf.String()
+	assert.Equal(t, 2, strings.Count(output, `"message":"db timeout"`))
+	assert.Contains(t, output, `"message":"other"`)
+	assert.Contains(t, output,
+		`"level":"ERROR","message":"suppressed 4 similar entries","rate_limit_key":"db timeout","suppressed":4}`)
+}
+
+func TestLogger_RateLimitByKeyField(t *testing.T) {
+	buf := &syncBuffer{}
+
+	logger := New(Config{
+		Level:     InfoLevel,
+		Format:    TextFormat,
+		Output:    buf,
+		RateLimit: &RateLimitConfig{Interval: time.Hour, Limit: 1, KeyField: "tenant"},
+	})
+
+	logger.Info("request failed", String("tenant", "acme"))
+	logger.Info("request timed out", String("tenant", "acme"))
+	logger.Info("request failed", String("tenant", "globex"))
+	logger.Info("request failed", Int("tenant", 42))
+	logger.Info("request failed", Int("tenant", 42))
+
+	require.NoError(t, logger.Close())
+
+	output := buf.String()
+	assert.Contains(t, output, "request failed tenant=acme")
+	assert.NotContains(t, output, "request timed out")
+	assert.Contains(t, output, "request failed tenant=globex")
+	assert.Equal(t, 1, strings.Count(output, "tenant=42"))
+	assert.Contains(t, output, "suppressed 1 similar entries rate_limit_key=acme suppressed=1")
+	assert.Contains(t, output, "suppressed 1 similar entries rate_limit_key=42 suppressed=1")
+}
+
+func TestLogger_RateLimitWindowResets(t *testing.T) {
+	buf := &syncBuffer{}
+
+	logger := New(Config{
+		Level:     InfoLevel,
+		Format:    TextFormat,
+		Output:    buf,
+		RateLimit: &RateLimitConfig{Interval: time.Hour, Limit: 1},
+	})
+
+	logger.Info("burst")
+	logger.Info("burst")
+	logger.core.limiter.flush(logger)
+	logger.Info("burst")
+
+	require.NoError(t, logger.Close())
+
+	assert.Equal(t, 2, strings.Count(buf.String(), "INFO burst"))
+}
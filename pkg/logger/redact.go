@@ -0,0 +1,286 @@
+package logger
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultRedactMask replaces redacted data when RedactConfig.Mask is empty.
+const DefaultRedactMask = "[REDACTED]"
+
+// RedactMode selects how redacted data is replaced.
+type RedactMode int8
+
+const (
+	// RedactMask replaces redacted data with RedactConfig.Mask.
+	RedactMask RedactMode = iota
+
+	// RedactHash replaces redacted data with a pseudonym, "sha256:" and
+	// the first 16 hex digits of its SHA-256 hash, so that entries about
+	// the same value can still be correlated without revealing it. See
+	// RedactConfig.HashSecret.
+	RedactHash
+)
+
+var (
+	// DefaultRedactKeys are field keys that commonly hold credentials.
+	DefaultRedactKeys = []string{
+		"password", "passwd", "secret", "token", "access_token", "refresh_token",
+		"api_key", "apikey", "authorization", "cookie", "set-cookie",
+	}
+
+	// CreditCardPattern matches payment card numbers of 13 to 19 digits,
+	// optionally grouped with spaces or dashes.
+	CreditCardPattern = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+
+	// EmailPattern matches email addresses.
+	EmailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+)
+
+// RedactConfig configures the redaction of sensitive data before entries
+// are encoded, so that it never reaches hooks or outputs.
+//
+// Example:
+//
+//	log := logger.New(logger.Config{
+//		Redact: &logger.RedactConfig{
+//			Keys:     logger.DefaultRedactKeys,
+//			Patterns: []*regexp.Regexp{logger.CreditCardPattern, logger.EmailPattern},
+//		},
+//	})
+type RedactConfig struct {
+	// Keys are the keys of fields whose whole value is redacted, matched
+	// without regard to case.
+	//
+	// Keys, HashKeys and Patterns apply to top-level fields and to the
+	// fields nested in Group fields, and in the maps and []interface{}
+	// values logged with Any. The members of other LogObjectMarshaler and
+	// LogArrayMarshaler values, and of values encoded with encoding/json
+	// such as structs, are not inspected: redact them in their marshaler.
+	Keys []string
+
+	// Patterns are matched against messages, string fields, error
+	// messages and the text of Stringer fields; every match is redacted.
+	Patterns []*regexp.Regexp
+
+	// Mode selects how redacted data is replaced. Defaults to RedactMask.
+	Mode RedactMode
+
+	// Mask replaces redacted data in RedactMask mode.
+	// Defaults to DefaultRedactMask.
+	Mask string
+
+	// HashKeys are the keys of fields whose value is replaced with a
+	// pseudonym, whatever Mode is, matched without regard to case. Use it
+	// for identifiers such as user IDs and emails that must stay
+	// correlatable across entries without being stored.
+	HashKeys []string
+
+	// HashSecret keys the HMAC-SHA256 of pseudonyms: values of HashKeys
+	// fields, Hashed fields, and redacted data in RedactHash mode. Without
+	// it pseudonyms are plain SHA-256 hashes, which do not protect
+	// guessable values such as emails against dictionary attacks.
+	HashSecret []byte
+}
+
+// Hashed constructs a field with the given key whose value is logged as a
+// pseudonym, "sha256:" and the first 16 hex digits of its SHA-256 hash,
+// never in the clear. With RedactConfig.HashSecret set, the pseudonym is
+// an HMAC-SHA256 keyed with the secret instead, "hmac:" and 16 hex
+// digits.
+func Hashed(key, value string) Field {
+	return Field{Key: key, kind: hashedKind, str: value, Value: pseudonym(nil, value)}
+}
+
+// pseudonym returns the prefix and first 16 hex digits of the
+// HMAC-SHA256 of s keyed with secret, or of its SHA-256 without a secret.
+func pseudonym(secret []byte, s string) string {
+	if secret == nil {
+		sum := sha256.Sum256([]byte(s))
+		return "sha256:" + hex.EncodeToString(sum[:8])
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(s))
+	return "hmac:" + hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// SetRedact atomically replaces the redaction of sensitive data with
+// config, or disables it if config is nil. Fields bound with With before
+// the change stay as they were encoded. It affects the logger and every
+// logger sharing its outputs.
+func (l *Logger) SetRedact(config *RedactConfig) {
+	if config == nil {
+		l.core.redactor.Store(nil)
+		return
+	}
+	l.core.redactor.Store(newRedactor(config))
+}
+
+// redactor applies a RedactConfig.
+type redactor struct {
+	keys     []string
+	patterns []*regexp.Regexp
+	mode     RedactMode
+	mask     string
+	hashKeys []string
+	secret   []byte
+}
+
+func newRedactor(config *RedactConfig) *redactor {
+	r := &redactor{
+		keys:     config.Keys,
+		patterns: config.Patterns,
+		mode:     config.Mode,
+		mask:     config.Mask,
+		hashKeys: config.HashKeys,
+		secret:   config.HashSecret,
+	}
+	if r.mask == "" {
+		r.mask = DefaultRedactMask
+	}
+	return r
+}
+
+// message returns msg with the matches of every pattern redacted.
+func (r *redactor) message(msg string) string {
+	for _, p := range r.patterns {
+		msg = p.ReplaceAllStringFunc(msg, r.replace)
+	}
+	return msg
+}
+
+// fields returns fields with sensitive values redacted. fields is copied
+// before the first change, so that the caller's slice is left untouched.
+func (r *redactor) fields(fields []Field) []Field {
+	copied := false
+	for i := range fields {
+		f, ok := r.field(fields[i])
+		if !ok {
+			continue
+		}
+		if !copied {
+			fields = append([]Field(nil), fields...)
+			copied = true
+		}
+		fields[i] = f
+	}
+	return fields
+}
+
+// group returns the fields of g with sensitive values redacted, copied
+// before the first change, and whether any was. It is kept apart from
+// fields, which would otherwise be part of the recursion through field
+// and make the fields of every entry escape to the heap.
+func (r *redactor) group(g fieldGroup) (fieldGroup, bool) {
+	var redacted fieldGroup
+	for i := range g {
+		f, ok := r.field(g[i])
+		if !ok {
+			continue
+		}
+		if redacted == nil {
+			redacted = append(fieldGroup(nil), g...)
+		}
+		redacted[i] = f
+	}
+	return redacted, redacted != nil
+}
+
+// field returns the redacted form of f and true, or false if f holds no
+// sensitive data.
+func (r *redactor) field(f Field) (Field, bool) {
+	if f.kind == hashedKind {
+		if r.secret == nil {
+			return f, false
+		}
+		return String(f.Key, pseudonym(r.secret, f.str)), true
+	}
+	for _, key := range r.hashKeys {
+		if strings.EqualFold(f.Key, key) {
+			return String(f.Key, pseudonym(r.secret, r.text(f))), true
+		}
+	}
+	for _, key := range r.keys {
+		if strings.EqualFold(f.Key, key) {
+			return String(f.Key, r.replace(r.text(f))), true
+		}
+	}
+
+	if f.kind == objectKind || f.kind == arrayKind {
+		return r.nested(f)
+	}
+	if len(r.patterns) == 0 {
+		return f, false
+	}
+
+	var s string
+	switch f.kind {
+	case stringKind:
+		s = f.str
+	case errorKind:
+		if f.Value == nil {
+			return f, false
+		}
+		s = f.Value.(error).Error()
+	case stringerKind:
+		s = stringerText(f.Value.(fmt.Stringer))
+	default:
+		return f, false
+	}
+	if redacted := r.message(s); redacted != s {
+		return String(f.Key, redacted), true
+	}
+	return f, false
+}
+
+// nested returns the redacted form of a Group field, or of a map or
+// []interface{} field built by Any, and true, or false if it holds no
+// sensitive data. Other marshalers are left as they are.
+func (r *redactor) nested(f Field) (Field, bool) {
+	switch v := f.Value.(type) {
+	case fieldGroup:
+		if fields, ok := r.group(v); ok {
+			return Group(f.Key, fields...), true
+		}
+	case mapObject:
+		if fields, ok := r.group(v.fields()); ok {
+			return Group(f.Key, fields...), true
+		}
+	case anyArray:
+		var redacted anyArray
+		for i, elem := range v {
+			ef, ok := r.field(Any("", elem))
+			if !ok {
+				continue
+			}
+			if redacted == nil {
+				redacted = append(anyArray(nil), v...)
+			}
+			redacted[i] = ef.Interface()
+		}
+		if redacted != nil {
+			return Array(f.Key, redacted), true
+		}
+	}
+	return f, false
+}
+
+// text returns the value of f as TextFormat writes it, unquoted.
+func (r *redactor) text(f Field) string {
+	if f.kind == stringKind {
+		return f.str
+	}
+	return string(appendFieldValue(nil, f))
+}
+
+// replace returns the replacement of the sensitive data s.
+func (r *redactor) replace(s string) string {
+	if r.mode == RedactHash {
+		return pseudonym(r.secret, s)
+	}
+	return r.mask
+}
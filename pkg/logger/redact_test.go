@@ -0,0 +1,165 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger_RedactKeys(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(Config{
+		Format:   JSONFormat,
+		Output:   buf,
+		OmitTime: true,
+		Fields:   []Field{String("api_key", "k-123")},
+		Redact:   &RedactConfig{Keys: DefaultRedactKeys},
+	})
+
+	fields := []Field{String("user", "alice"), String("Password", "hunter2"), Int("token", 42)}
+	logger.With(String("Authorization", "Bearer abc")).Info("login", fields...)
+
+	assert.Equal(t, `{"level":"INFO","message":"login","api_key":"[REDACTED]","Authorization":"[REDACTED]",`+
+		`"user":"alice","Password":"[REDACTED]","token":"[REDACTED]"}`+"\n", buf.String())
+	assert.Equal(t, "hunter2", fields[1].Interface(), "caller's fields must not change")
+}
+
+func TestLogger_RedactNested(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(Config{
+		Format:   JSONFormat,
+		Output:   buf,
+		OmitTime: true,
+		Redact:   &RedactConfig{Keys: DefaultRedactKeys, Patterns: []*regexp.Regexp{EmailPattern}},
+	})
+
+	req := map[string]interface{}{
+		"user":     "alice",
+		"password": "hunter2",
+		"headers":  map[string]interface{}{"Authorization": "Bearer abc"},
+		"cc":       []interface{}{"bob@example.com", map[string]interface{}{"token": "t-1"}, 1},
+	}
+	logger.Info("login",
+		Any("req", req),
+		Group("db", String("host", "db1"), String("secret", "s3")),
+		Object("user", testUser{ID: 7, Email: "user@example.com"}))
+
+	assert.Equal(t, `{"level":"INFO","message":"login",`+
+		`"req":{"cc":["[REDACTED]",{"token":"[REDACTED]"},1],"headers":{"Authorization":"[REDACTED]"},`+
+		`"password":"[REDACTED]","user":"alice"},`+
+		`"db":{"host":"db1","secret":"[REDACTED]"},`+
+		// Other marshalers are not inspected.
+		`"user":{"id":7,"email":"user@example.com","session":"1m30s","roles":[]}}`+"\n", buf.String())
+	assert.Equal(t, "hunter2", req["password"], "caller's values must not change")
+}
+
+func TestLogger_RedactPatterns(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(Config{
+		Format:   TextFormat,
+		Output:   buf,
+		OmitTime: true,
+		Redact: &RedactConfig{
+			Patterns: []*regexp.Regexp{CreditCardPattern, EmailPattern},
+			Mask:     "***",
+		},
+	})
+
+	logger.Info("charged 4111 1111 1111 1111 for bob@example.com",
+		String("card", "4111-1111-1111-1111"),
+		String("note", "order 12345"),
+		Err(errors.New("declined for bob@example.com")),
+		Int("amount", 4111111111111111))
+
+	assert.Equal(t, "INFO charged *** for *** card=*** note=\"order 12345\" error=\"declined for ***\" amount=4111111111111111\n", buf.String())
+}
+
+func TestLogger_RedactHash(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(Config{
+		Format:   TextFormat,
+		Output:   buf,
+		OmitTime: true,
+		Redact:   &RedactConfig{Keys: []string{"email"}, Mode: RedactHash},
+	})
+
+	logger.Info("first", String("email", "bob@example.com"))
+	logger.Info("second", String("email", "bob@example.com"))
+	logger.Info("third", String("email", "eve@example.com"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	first := strings.TrimPrefix(lines[0], "INFO first email=")
+	assert.Regexp(t, `^sha256:[0-9a-f]{16}$`, first)
+	assert.Equal(t, "INFO second email="+first, lines[1])
+	assert.NotContains(t, lines[2], first)
+}
+
+func TestLogger_SetRedact(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(Config{Format: TextFormat, Output: buf, OmitTime: true})
+
+	logger.Info("plain", String("token", "t1"))
+	logger.SetRedact(&RedactConfig{Keys: []string{"token"}})
+	logger.Info("redacted", String("token", "t2"))
+	logger.SetRedact(nil)
+	logger.Info("plain again", String("token", "t3"))
+
+	assert.Equal(t, "INFO plain token=t1\nINFO redacted token=[REDACTED]\nINFO plain again token=t3\n", buf.String())
+}
+
+func TestHashed(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(Config{Format: JSONFormat, Output: buf, OmitTime: true})
+
+	field := Hashed("user", "alice@example.com")
+	assert.Regexp(t, `^sha256:[0-9a-f]{16}$`, field.Interface())
+	assert.Equal(t, field.Interface(), Hashed("user", "alice@example.com").Interface())
+	assert.NotEqual(t, field.Interface(), Hashed("user", "bob@example.com").Interface())
+
+	logger.Info("login", field)
+	assert.Equal(t, `{"level":"INFO","message":"login","user":"`+field.Interface().(string)+`"}`+"\n", buf.String())
+	assert.NotContains(t, buf.String(), "alice")
+}
+
+func TestLogger_RedactHashKeys(t *testing.T) {
+	var outputs []string
+	for _, secret := range []string{"first", "second"} {
+		buf := &bytes.Buffer{}
+		logger := New(Config{
+			Format:   TextFormat,
+			Output:   buf,
+			OmitTime: true,
+			Redact: &RedactConfig{
+				Keys:       []string{"password"},
+				HashKeys:   []string{"user_id"},
+				HashSecret: []byte(secret),
+			},
+		})
+		logger.Info("login", Int("user_id", 42), String("password", "hunter2"), Hashed("email", "alice@example.com"))
+		logger.Info("logout", Int("USER_ID", 42))
+		outputs = append(outputs, buf.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(outputs[0]), "\n")
+	require.Len(t, lines, 2)
+	assert.Regexp(t, `^INFO login user_id=hmac:[0-9a-f]{16} password=\[REDACTED\] email=hmac:[0-9a-f]{16}$`, lines[0])
+	userID := strings.Fields(lines[0])[2]
+	assert.Equal(t, "INFO logout USER_ID="+strings.TrimPrefix(userID, "user_id="), lines[1])
+
+	assert.NotContains(t, outputs[1], strings.TrimPrefix(userID, "user_id="), "pseudonyms depend on the secret")
+}
+
+func TestCreditCardPattern(t *testing.T) {
+	for _, s := range []string{"4111111111111111", "4111 1111 1111 1111", "3782-822463-10005"} {
+		assert.True(t, CreditCardPattern.MatchString(s), s)
+	}
+	for _, s := range []string{"order 12345", "2024-01-20", "+1 555 0100"} {
+		assert.False(t, CreditCardPattern.MatchString(s), s)
+	}
+}
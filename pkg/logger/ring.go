@@ -0,0 +1,198 @@
+package logger
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// DefaultRingBufferSize is the number of entries kept by a RingBuffer
+// created with a size of 0.
+const DefaultRingBufferSize = 1000
+
+// RingBuffer keeps the most recent entries of a logger in memory, at every
+// level, including those below the logger's level that are not written to
+// its outputs. Dump them when something goes wrong to get post-mortem
+// context without always-on debug logging:
+//
+//	ring := logger.NewRingBuffer(0)
+//	log := logger.New(logger.Config{Level: logger.InfoLevel, RingBuffer: ring})
+//	defer ring.DumpOnPanic(os.Stderr)
+//
+// Entries are encoded in Config.Format. Retaining entries below the level
+// costs as much as writing them, short of the I/O.
+//
+// It is safe for concurrent use.
+type RingBuffer struct {
+	mu      sync.Mutex
+	entries []ringEntry
+	next    int
+	full    bool
+}
+
+// ringEntry is an entry kept by a RingBuffer, without its trailing newline.
+type ringEntry struct {
+	level Level
+	data  []byte
+}
+
+// NewRingBuffer returns a RingBuffer that keeps the last size entries, or
+// DefaultRingBufferSize entries if size is 0 or less.
+func NewRingBuffer(size int) *RingBuffer {
+	if size <= 0 {
+		size = DefaultRingBufferSize
+	}
+	return &RingBuffer{entries: make([]ringEntry, size)}
+}
+
+// record keeps an encoded entry, reusing the memory of the entry it evicts.
+func (r *RingBuffer) record(level Level, buf []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e := &r.entries[r.next]
+	e.level = level
+	e.data = append(e.data[:0], buf...)
+	r.next = (r.next + 1) % len(r.entries)
+	r.full = r.full || r.next == 0
+}
+
+// Write keeps the entries in p, one per line, so that a RingBuffer can
+// also be used as the output of a Sink. It never fails.
+func (r *RingBuffer) Write(p []byte) (int, error) {
+	for rest := p; len(rest) > 0; {
+		line, tail, _ := bytes.Cut(rest, []byte{'\n'})
+		rest = tail
+		if len(line) > 0 {
+			r.record(InfoLevel, line)
+		}
+	}
+	return len(p), nil
+}
+
+// Entries returns copies of the entries kept, oldest first, each ending
+// in a newline.
+func (r *RingBuffer) Entries() [][]byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := make([][]byte, 0, r.len())
+	r.each(func(e *ringEntry) {
+		entry := make([]byte, len(e.data)+1)
+		copy(entry, e.data)
+		entry[len(e.data)] = '\n'
+		entries = append(entries, entry)
+	})
+	return entries
+}
+
+// Dump writes the entries kept to w, oldest first, one per line.
+func (r *RingBuffer) Dump(w io.Writer) error {
+	var buf []byte
+	r.mu.Lock()
+	r.each(func(e *ringEntry) {
+		buf = append(buf, e.data...)
+		buf = append(buf, '\n')
+	})
+	r.mu.Unlock()
+
+	_, err := w.Write(buf)
+	return err
+}
+
+// DumpOnPanic dumps the entries kept to w if the goroutine is panicking,
+// then resumes panicking. It must be deferred directly:
+//
+//	defer ring.DumpOnPanic(os.Stderr)
+func (r *RingBuffer) DumpOnPanic(w io.Writer) {
+	if v := recover(); v != nil {
+		_ = r.Dump(w)
+		panic(v)
+	}
+}
+
+// len must be called with r.mu held.
+func (r *RingBuffer) len() int {
+	if r.full {
+		return len(r.entries)
+	}
+	return r.next
+}
+
+// each calls fn for the entries kept, oldest first.
+// It must be called with r.mu held.
+func (r *RingBuffer) each(fn func(e *ringEntry)) {
+	n := r.len()
+	for i := r.next - n; i < r.next; i++ {
+		fn(&r.entries[(i+len(r.entries))%len(r.entries)])
+	}
+}
+
+// retain keeps an entry below the level of l, without writing it, in its
+// ring buffer and flight recorder. Only redaction and value limits are
+// applied: entries kept this way are not sampled, rate limited,
+// deduplicated or seen by hooks.
+func (l *Logger) retain(level Level, msg string, fields []Field) {
+	fields = resolveLazy(fields)
+	if r := l.core.redactor.Load(); r != nil {
+		msg = r.message(msg)
+		fields = r.fields(fields)
+	}
+	if l.config.MaxValueBytes > 0 {
+		msg, fields = limitValues(l.config.MaxValueBytes, msg, fields)
+	}
+
+	var (
+		key  string
+		data [][]byte
+	)
+	if fr := l.core.recorder; fr != nil {
+		if key = fr.key(l, fields); key != "" {
+			data = make([][]byte, len(l.core.encs))
+		}
+	}
+
+	now := l.now()
+	for i, enc := range l.core.encs {
+		toRing := i == l.core.ringEnc
+		toFlight := data != nil && level >= l.core.encLevels[i]
+		if !toRing && !toFlight {
+			continue
+		}
+
+		bufPtr := l.core.pool.get()
+		var buf []byte
+		if l.core.builtin {
+			e := Entry{
+				Time:    now,
+				Level:   level,
+				Message: msg,
+				Fields:  fields,
+				Context: l.contexts[i],
+			}
+			buf = encodeBuiltin(enc, (*bufPtr)[:0], &e)
+		} else {
+			buf = enc.EncodeEntry((*bufPtr)[:0], &Entry{
+				Time:    now,
+				Level:   level,
+				Message: msg,
+				Fields:  append([]Field(nil), fields...),
+				Context: l.contexts[i],
+			})
+		}
+
+		if toRing {
+			l.core.ring.record(level, buf)
+		}
+		if toFlight {
+			data[i] = append([]byte(nil), buf...)
+		}
+
+		*bufPtr = buf
+		l.core.pool.put(bufPtr)
+	}
+
+	if data != nil {
+		l.core.recorder.record(key, level, data)
+	}
+}
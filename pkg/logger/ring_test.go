@@ -0,0 +1,89 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRingBuffer(t *testing.T) {
+	buf := &bytes.Buffer{}
+	ring := NewRingBuffer(3)
+	logger := New(Config{
+		Level:      InfoLevel,
+		Format:     JSONFormat,
+		Output:     buf,
+		OmitTime:   true,
+		RingBuffer: ring,
+		Redact:     &RedactConfig{Keys: []string{"password"}},
+	})
+
+	logger.Debug("one")
+	logger.Info("two")
+	logger.Named("db").Debugf("three %d", 3)
+	logger.Debug("four", String("password", "hunter2"))
+
+	assert.Equal(t, `{"level":"INFO","message":"two"}`+"\n", buf.String())
+	assert.Equal(t, [][]byte{
+		[]byte(`{"level":"INFO","message":"two"}` + "\n"),
+		[]byte(`{"level":"DEBUG","message":"three 3","logger":"db"}` + "\n"),
+		[]byte(`{"level":"DEBUG","message":"four","password":"[REDACTED]"}` + "\n"),
+	}, ring.Entries())
+
+	dump := &bytes.Buffer{}
+	require.NoError(t, ring.Dump(dump))
+	assert.Equal(t, 3, strings.Count(dump.String(), "\n"))
+	assert.True(t, strings.HasPrefix(dump.String(), `{"level":"INFO","message":"two"}`))
+}
+
+func TestRingBuffer_Sinks(t *testing.T) {
+	buf := &bytes.Buffer{}
+	ring := NewRingBuffer(0)
+	logger := New(Config{
+		Level:      WarnLevel,
+		Format:     TextFormat,
+		OmitTime:   true,
+		Sinks:      []Sink{{Output: buf, Level: ErrorLevel, Format: JSONFormat}},
+		RingBuffer: ring,
+	})
+
+	logger.Info("kept")
+	logger.Warn("kept too")
+	logger.Error("written")
+
+	assert.Equal(t, `{"level":"ERROR","message":"written"}`+"\n", buf.String())
+	entries := ring.Entries()
+	require.Len(t, entries, 3)
+	assert.Equal(t, "INFO kept\n", string(entries[0]))
+	assert.Equal(t, "ERROR written\n", string(entries[2]))
+}
+
+func TestRingBuffer_DumpOnPanic(t *testing.T) {
+	ring := NewRingBuffer(10)
+	logger := New(Config{Level: ErrorLevel, Format: JSONFormat, Output: &bytes.Buffer{}, OmitTime: true, RingBuffer: ring})
+	dump := &bytes.Buffer{}
+
+	assert.PanicsWithValue(t, "boom", func() {
+		defer ring.DumpOnPanic(dump)
+		logger.Debug("before the crash")
+		panic("boom")
+	})
+	assert.Equal(t, `{"level":"DEBUG","message":"before the crash"}`+"\n", dump.String())
+
+	dump.Reset()
+	func() {
+		defer ring.DumpOnPanic(dump)
+	}()
+	assert.Empty(t, dump.String())
+}
+
+func TestRingBuffer_Write(t *testing.T) {
+	ring := NewRingBuffer(2)
+	n, err := ring.Write([]byte("a\nb\n\nc\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 7, n)
+	assert.Equal(t, [][]byte{[]byte("b\n"), []byte("c\n")}, ring.Entries())
+}
@@ -0,0 +1,119 @@
+package logger
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultSamplingTick is the sampling window used when SamplerConfig.Tick is zero.
+	DefaultSamplingTick = time.Second
+
+	samplerCounters = 4096
+
+	fnvOffset32 = 2166136261
+	fnvPrime32  = 16777619
+)
+
+// SamplerConfig configures sampling of repetitive entries. Entries are
+// grouped by level and message; within each Tick the first Initial entries
+// of a group are written, then only every Thereafter-th one.
+//
+// Entries written after others of their group were dropped carry
+// sampled=true and dropped=<count> fields, so downstream systems can tell
+// that sampling occurred and how much was discarded.
+//
+// Groups are tracked in a fixed-size table, so unrelated messages may
+// occasionally share a counter.
+type SamplerConfig struct {
+	// Tick is the length of a sampling window. Defaults to DefaultSamplingTick.
+	Tick time.Duration
+
+	// Initial is the number of entries per group written in full each Tick.
+	Initial int
+
+	// Thereafter writes every Thereafter-th entry once Initial is exceeded.
+	// Zero drops every entry past Initial until the next Tick.
+	Thereafter int
+}
+
+// sampler holds the per-group counters for a SamplerConfig.
+type sampler struct {
+	tick       int64
+	initial    uint64
+	thereafter uint64
+	counters   [samplerCounters]samplerCounter
+}
+
+type samplerCounter struct {
+	resetAt atomic.Int64
+	count   atomic.Uint64
+	dropped atomic.Uint64
+}
+
+func newSampler(config *SamplerConfig) *sampler {
+	tick := config.Tick
+	if tick <= 0 {
+		tick = DefaultSamplingTick
+	}
+
+	var initial, thereafter uint64
+	if config.Initial > 0 {
+		initial = uint64(config.Initial)
+	}
+	if config.Thereafter > 0 {
+		thereafter = uint64(config.Thereafter)
+	}
+
+	return &sampler{
+		tick:       int64(tick),
+		initial:    initial,
+		thereafter: thereafter,
+	}
+}
+
+// check reports whether an entry should be written and, if entries of its
+// group were dropped since the last one written, how many.
+func (s *sampler) check(level Level, msg string, now time.Time) (ok bool, dropped uint64) {
+	c := &s.counters[samplerKey(level, msg)]
+	n := c.incr(now.UnixNano(), s.tick)
+
+	if n <= s.initial {
+		return true, c.dropped.Swap(0)
+	}
+	if s.thereafter > 0 && (n-s.initial)%s.thereafter == 0 {
+		return true, c.dropped.Swap(0)
+	}
+
+	c.dropped.Add(1)
+	return false, 0
+}
+
+// incr counts an entry in the current window, starting a new window when
+// the previous one has elapsed, and returns the count within the window.
+func (c *samplerCounter) incr(now, tick int64) uint64 {
+	resetAt := c.resetAt.Load()
+	if resetAt > now {
+		return c.count.Add(1)
+	}
+
+	c.count.Store(1)
+	newResetAt := now + tick
+	if !c.resetAt.CompareAndSwap(resetAt, newResetAt) {
+		// Another goroutine started the window first.
+		return c.count.Add(1)
+	}
+	return 1
+}
+
+// samplerKey hashes level and message (FNV-1a) to a counter index.
+func samplerKey(level Level, msg string) uint32 {
+	h := uint32(fnvOffset32)
+	h ^= uint32(uint8(level))
+	h *= fnvPrime32
+	for i := 0; i < len(msg); i++ {
+		h ^= uint32(msg[i])
+		h *= fnvPrime32
+	}
+	return h % samplerCounters
+}
@@ -0,0 +1,92 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger_Sampling(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: JSONFormat,
+		Output: buf,
+		Sampling: &SamplerConfig{
+			Tick:       time.Minute,
+			Initial:    2,
+			Thereafter: 3,
+		},
+	})
+
+	for i := 0; i < 8; i++ {
+		logger.Info("repeated", Int("i", i))
+	}
+	logger.Info("other")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 5)
+	assert.Contains(t, lines[0], `"i":0}`)
+	assert.Contains(t, lines[1], `"i":1}`)
+	assert.Contains(t, lines[2], `"i":4,"sampled":true,"dropped":2}`)
+	assert.Contains(t, lines[3], `"i":7,"sampled":true,"dropped":2}`)
+	assert.Contains(t, lines[4], `"message":"other"}`)
+}
+
+func TestLogger_SamplingKeepsCallerFields(t *testing.T) {
+	logger := New(Config{
+		Level:    InfoLevel,
+		Format:   JSONFormat,
+		Output:   &bytes.Buffer{},
+		Sampling: &SamplerConfig{Tick: time.Minute, Initial: 1, Thereafter: 2},
+	})
+
+	// A reused slice with spare capacity must not receive the sampled and
+	// dropped fields.
+	fields := make([]Field, 1, 4)
+	fields[0] = String("k", "v")
+	for i := 0; i < 3; i++ {
+		logger.Info("repeated", fields...)
+	}
+
+	assert.Equal(t, []Field{String("k", "v")}, fields)
+	assert.Equal(t, Field{}, fields[:2][1], "the spare capacity is untouched")
+}
+
+func TestLogger_SamplingPerLevel(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:    InfoLevel,
+		Format:   TextFormat,
+		Output:   buf,
+		Sampling: &SamplerConfig{Tick: time.Minute, Initial: 1},
+	})
+
+	logger.Info("same message")
+	logger.Info("same message")
+	logger.Error("same message")
+
+	output := buf.String()
+	assert.Equal(t, 1, strings.Count(output, "INFO same message"))
+	assert.Equal(t, 1, strings.Count(output, "ERROR same message"))
+}
+
+func TestSampler_WindowReset(t *testing.T) {
+	s := newSampler(&SamplerConfig{Tick: time.Second, Initial: 1})
+	now := time.Unix(1000, 0)
+
+	ok, _ := s.check(InfoLevel, "msg", now)
+	assert.True(t, ok)
+	ok, _ = s.check(InfoLevel, "msg", now.Add(500*time.Millisecond))
+	assert.False(t, ok)
+
+	ok, dropped := s.check(InfoLevel, "msg", now.Add(time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1), dropped)
+}
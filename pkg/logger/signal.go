@@ -0,0 +1,12 @@
+package logger
+
+// SignalConfig configures Logger.HandleSignals.
+type SignalConfig struct {
+	// CloseOnTerm makes SIGTERM close the logger, writing and syncing
+	// buffered entries, then raise SIGTERM again for its default action,
+	// which ends the program. Set it only in programs that do not handle
+	// SIGTERM themselves: they would receive the signal twice, and keep
+	// logging after the logger was closed. Such programs close the logger
+	// at the end of their own shutdown instead.
+	CloseOnTerm bool
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package logger
+
+// HandleSignals does nothing on platforms without SIGUSR1, SIGUSR2 and
+// SIGTERM.
+// See the Unix version.
+func (l *Logger) HandleSignals(config SignalConfig) (stop func()) {
+	return func() {}
+}
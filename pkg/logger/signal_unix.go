@@ -0,0 +1,71 @@
+//go:build unix
+
+package logger
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// HandleSignals lets operators control the logger with signals, so that a
+// containerized service can be made verbose or drained without a restart:
+//
+//   - SIGUSR1 toggles between DebugLevel and the level set before.
+//   - SIGUSR2 flushes buffered entries.
+//   - SIGTERM, with config.CloseOnTerm, closes the logger, then raises
+//     SIGTERM again for its default action. Otherwise it is left to the
+//     program.
+//
+// Call the returned function to stop handling signals. HandleSignals does
+// nothing on platforms without these signals.
+func (l *Logger) HandleSignals(config SignalConfig) (stop func()) {
+	signals := make(chan os.Signal, 1)
+	if config.CloseOnTerm {
+		signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2, syscall.SIGTERM)
+	} else {
+		signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		// previous is the level restored by the next toggle from DebugLevel.
+		previous := InfoLevel
+		for {
+			select {
+			case sig := <-signals:
+				switch sig {
+				case syscall.SIGUSR1:
+					if level := l.GetLevel(); level != DebugLevel {
+						previous = level
+						l.SetLevel(DebugLevel)
+					} else {
+						l.SetLevel(previous)
+					}
+				case syscall.SIGUSR2:
+					l.Flush()
+				case syscall.SIGTERM:
+					_ = l.Close()
+					signal.Stop(signals)
+					_ = syscall.Kill(os.Getpid(), syscall.SIGTERM)
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(done)
+			<-stopped
+		})
+	}
+}
@@ -0,0 +1,101 @@
+//go:build unix
+
+package logger
+
+import (
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger_HandleSignals(t *testing.T) {
+	buf := &syncBuffer{}
+	logger := New(Config{Level: WarnLevel, Format: TextFormat, Output: buf, BufferSize: 4096})
+	stop := logger.HandleSignals(SignalConfig{})
+	defer stop()
+
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+	assert.Eventually(t, func() bool {
+		return logger.GetLevel() == DebugLevel
+	}, time.Second, time.Millisecond)
+
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+	assert.Eventually(t, func() bool {
+		return logger.GetLevel() == WarnLevel
+	}, time.Second, time.Millisecond)
+
+	logger.Warn("buffered")
+	assert.Empty(t, buf.String())
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR2))
+	assert.Eventually(t, func() bool {
+		return buf.String() != ""
+	}, time.Second, time.Millisecond)
+	assert.Contains(t, buf.String(), "WARN buffered")
+
+	stop()
+	stop()
+}
+
+func TestLogger_HandleSignalsStop(t *testing.T) {
+	logger := New(Config{Level: InfoLevel, Output: io.Discard})
+	logger.HandleSignals(SignalConfig{})()
+
+	// With the handler stopped, SIGUSR1 must not change the level. Ignore
+	// it so that its default action does not end the test binary.
+	signal.Ignore(syscall.SIGUSR1)
+	defer signal.Reset(syscall.SIGUSR1)
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, InfoLevel, logger.GetLevel())
+}
+
+func TestLogger_HandleSignalsLeavesTermToProgram(t *testing.T) {
+	// The program's own handler.
+	term := make(chan os.Signal, 2)
+	signal.Notify(term, syscall.SIGTERM)
+	defer signal.Stop(term)
+
+	buf := &syncBuffer{}
+	logger := New(Config{Level: InfoLevel, Format: TextFormat, Output: buf, OmitTime: true})
+	stop := logger.HandleSignals(SignalConfig{})
+	defer stop()
+
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+	select {
+	case <-term:
+	case <-time.After(time.Second):
+		t.Fatal("SIGTERM not delivered")
+	}
+	select {
+	case <-term:
+		t.Fatal("SIGTERM delivered twice")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	logger.Info("still logging during shutdown")
+	assert.Equal(t, "INFO still logging during shutdown\n", buf.String())
+}
+
+func TestLogger_HandleSignalsCloseOnTerm(t *testing.T) {
+	// Keeps the raised SIGTERM from ending the test binary.
+	term := make(chan os.Signal, 2)
+	signal.Notify(term, syscall.SIGTERM)
+	defer signal.Stop(term)
+
+	buf := &syncBuffer{}
+	logger := New(Config{Level: InfoLevel, Format: TextFormat, Output: buf, OmitTime: true, BufferSize: 4096})
+	stop := logger.HandleSignals(SignalConfig{CloseOnTerm: true})
+	defer stop()
+
+	logger.Info("buffered")
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+	assert.Eventually(t, func() bool { return len(term) == 2 }, time.Second, time.Millisecond,
+		"SIGTERM is raised again after closing")
+	assert.Equal(t, "INFO buffered\n", buf.String())
+}
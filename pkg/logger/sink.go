@@ -0,0 +1,394 @@
+package logger
+
+import (
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// WriteSyncer is an output that can commit the data written to it to
+// stable storage, such as *os.File and rotate.Writer. Logger.Sync, Flush
+// and Close, and Fatal before exiting, call Sync on such outputs so that
+// the last entries before a crash reach the disk instead of sitting in the
+// page cache of the OS.
+type WriteSyncer interface {
+	io.Writer
+	Sync() error
+}
+
+// Sink is one of several outputs of a Logger, each with its own minimum
+// level and format. Set Config.Sinks to route every entry to all sinks
+// whose level it meets, for example colored console output to stderr for
+// warnings and JSON to a file for everything:
+//
+//	log := logger.New(logger.Config{
+//		Level: logger.DebugLevel,
+//		Sinks: []logger.Sink{
+//			{Output: os.Stderr, Level: logger.WarnLevel, Format: logger.ConsoleFormat},
+//			{Output: file, Level: logger.DebugLevel, Format: logger.JSONFormat},
+//		},
+//	})
+//
+// An entry is encoded once per distinct format, however many sinks share it.
+type Sink struct {
+	// Output specifies where the sink writes. If nil, defaults to os.Stdout.
+	Output io.Writer
+
+	// Level is the minimum level written to this sink. Entries must also
+	// pass the logger-wide Config.Level.
+	Level Level
+
+	// Levels, when not empty, lists the only levels written to this sink
+	// and replaces Level. Use it to cap a sink, for example to keep
+	// warnings and errors out of stdout. See StdSinks.
+	Levels []Level
+
+	// Format is the output format of this sink.
+	Format Format
+
+	// Encoder replaces the built-in encoder selected by Format when not nil.
+	Encoder Encoder
+}
+
+// StdSinks returns sinks that split entries between the standard streams
+// by level, the convention container platforms rely on to tell error
+// output apart: entries below errLevel go to os.Stdout and entries at
+// errLevel or above to os.Stderr, both in format.
+//
+//	log := logger.New(logger.Config{
+//		Level: logger.InfoLevel,
+//		Sinks: logger.StdSinks(logger.JSONFormat, logger.WarnLevel),
+//	})
+func StdSinks(format Format, errLevel Level) []Sink {
+	var below []Level
+	for level := DebugLevel; level < errLevel; level++ {
+		below = append(below, level)
+	}
+
+	sinks := make([]Sink, 0, 2)
+	if len(below) > 0 {
+		sinks = append(sinks, Sink{Output: os.Stdout, Levels: below, Format: format})
+	}
+	return append(sinks, Sink{Output: os.Stderr, Level: errLevel, Format: format})
+}
+
+// sink is the runtime state of an output: its writer, level filter, the
+// index of its encoder in core.encs, and its buffer.
+type sink struct {
+	out   io.Writer
+	level Level
+	// levels is a bit set of the levels in Sink.Levels, indexed by
+	// level-DebugLevel, or 0 if the sink accepts every level from level.
+	levels     uint8
+	enc        int
+	bufferSize int
+	stats      *writeStats
+	// batch is set when out accepts several entries in one Write, so that
+	// the async writer hands it each batch of queued entries at once.
+	batch bool
+
+	// shards hold the entries buffered when bufferSize > 0: a single
+	// buffer, or one per shard with Config.BufferShards.
+	shards []sinkShard
+}
+
+// sinkShard is a buffer of a sink, padded to its own cache lines so that
+// goroutines writing to different shards do not contend.
+type sinkShard struct {
+	mu     sync.Mutex
+	buffer []byte
+	// pending is the number of entries in buffer.
+	pending int
+	_       [64]byte
+}
+
+// newSinks builds the sinks described by config and the encoders they use,
+// and returns the index of the encoder of Config.RingBuffer, or -1.
+// Without Config.Sinks, the logger has a single sink for Config.Output that
+// accepts every level. Sinks with the same built-in format share an encoder.
+func newSinks(config Config, stats *writeStats) ([]Encoder, []Level, []*sink, int) {
+	specs := config.Sinks
+	if len(specs) == 0 {
+		specs = []Sink{{
+			Output:  config.Output,
+			Level:   DebugLevel,
+			Format:  config.Format,
+			Encoder: config.Encoder,
+		}}
+	}
+
+	opts := newEncoderOptions(&config)
+
+	var (
+		encs      []Encoder
+		encLevels []Level
+		sinks     = make([]*sink, 0, len(specs))
+	)
+
+	// addEncoder returns the index of enc in encs, adding it if it is not
+	// a built-in encoder already there, and lowers its level to level.
+	addEncoder := func(enc Encoder, level Level) int {
+		idx := -1
+		if isBuiltinEncoder(enc) {
+			for i := range encs {
+				if encs[i] == enc {
+					idx = i
+					break
+				}
+			}
+		}
+		if idx < 0 {
+			idx = len(encs)
+			encs = append(encs, enc)
+			encLevels = append(encLevels, level)
+		}
+		if level < encLevels[idx] {
+			encLevels[idx] = level
+		}
+		return idx
+	}
+
+	for _, spec := range specs {
+		out := spec.Output
+		if out == nil {
+			out = os.Stdout
+		}
+
+		level, levels := spec.Level, uint8(0)
+		if len(spec.Levels) > 0 {
+			level = spec.Levels[0]
+			for _, l := range spec.Levels {
+				level = min(level, l)
+				levels |= levelBit(l)
+			}
+		}
+
+		sinks = append(sinks, &sink{
+			out:        out,
+			level:      level,
+			levels:     levels,
+			enc:        addEncoder(newEncoder(spec.Format, spec.Encoder, out, opts), level),
+			bufferSize: config.BufferSize,
+			stats:      stats,
+			batch:      acceptsBatches(out),
+			shards:     newSinkShards(config.BufferSize, config.BufferShards),
+		})
+	}
+
+	// Entries are not encoded at all for sinks that discard them.
+	for i := range encs {
+		discard := true
+		for _, s := range sinks {
+			if s.enc == i && s.out != io.Discard {
+				discard = false
+			}
+		}
+		if discard {
+			encLevels[i] = disabledLevel
+		}
+	}
+
+	ringEnc := -1
+	if r := config.RingBuffer; r != nil {
+		ringEnc = addEncoder(newEncoder(config.Format, config.Encoder, r, opts), DebugLevel)
+	}
+
+	return encs, encLevels, sinks, ringEnc
+}
+
+// levelBit returns the bit of level in sink.levels.
+func levelBit(level Level) uint8 {
+	return 1 << uint8(level-DebugLevel)
+}
+
+// accepts reports whether the sink writes entries at level.
+func (s *sink) accepts(level Level) bool {
+	if s.levels != 0 {
+		return s.levels&levelBit(level) != 0
+	}
+	return level >= s.level
+}
+
+// newSinkShards returns the buffers of a sink: none when size is 0, or
+// shards buffers of size bytes, at least one.
+func newSinkShards(size, shards int) []sinkShard {
+	if size <= 0 {
+		return nil
+	}
+	b := make([]sinkShard, max(shards, 1))
+	for i := range b {
+		b[i].buffer = make([]byte, 0, size)
+	}
+	return b
+}
+
+// lockShard locks and returns a buffer of the sink. With several shards,
+// it tries them in turn from one picked at random and waits on that one
+// only if all are busy.
+func (s *sink) lockShard() *sinkShard {
+	if len(s.shards) == 1 {
+		b := &s.shards[0]
+		b.mu.Lock()
+		return b
+	}
+	n := uint32(len(s.shards))
+	start := rand.Uint32N(n)
+	for i := uint32(0); i < n; i++ {
+		if b := &s.shards[(start+i)%n]; b.mu.TryLock() {
+			return b
+		}
+	}
+	b := &s.shards[start]
+	b.mu.Lock()
+	return b
+}
+
+// write writes an encoded entry, including its trailing newline.
+func (s *sink) write(buf []byte) {
+	if s.bufferSize > 0 {
+		b := s.lockShard()
+		defer b.mu.Unlock()
+
+		if len(b.buffer)+len(buf) > s.bufferSize {
+			s.flush(b)
+		}
+		b.buffer = append(b.buffer, buf...)
+		b.pending++
+	} else {
+		n, err := s.out.Write(buf)
+		s.stats.record(buf, 1, n, err)
+	}
+}
+
+// writeBatch writes n encoded entries, each including its trailing
+// newline, in a single Write. It must only be used for unbuffered sinks.
+func (s *sink) writeBatch(buf []byte, n int) {
+	written, err := s.out.Write(buf)
+	s.stats.record(buf, n, written, err)
+}
+
+// acceptsBatches reports whether out takes several entries in one Write:
+// files and stream sockets. Writers that frame each Write as one entry,
+// such as datagram sockets and the writers of the syslog, journald and
+// loki packages, are given entries one by one.
+func acceptsBatches(out io.Writer) bool {
+	switch out := out.(type) {
+	case *os.File:
+		return true
+	case net.Conn:
+		addr := out.LocalAddr()
+		if addr == nil {
+			return false
+		}
+		switch addr.Network() {
+		case "tcp", "tcp4", "tcp6", "unix":
+			return true
+		}
+	}
+	return false
+}
+
+// Flush writes all buffered content to the output, one shard at a time.
+func (s *sink) Flush() {
+	for i := range s.shards {
+		b := &s.shards[i]
+		b.mu.Lock()
+		s.flush(b)
+		b.mu.Unlock()
+	}
+}
+
+// sync commits the output to stable storage if it is a WriteSyncer.
+// Outputs that cannot be synced, such as terminals and pipes, are skipped.
+func (s *sink) sync() error {
+	ws, ok := s.out.(WriteSyncer)
+	if !ok {
+		return nil
+	}
+	err := ws.Sync()
+	if errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ENOTSUP) || errors.Is(err, os.ErrInvalid) {
+		return nil
+	}
+	return err
+}
+
+// closeOutputs closes the outputs of the sinks that are io.Closers, other
+// than the standard streams, once each however many sinks share it, and
+// returns the first error.
+func (c *core) closeOutputs() error {
+	var (
+		closed []io.Closer
+		err    error
+	)
+	for _, s := range c.sinks {
+		closer, ok := s.out.(io.Closer)
+		if !ok || s.out == os.Stdout || s.out == os.Stderr || slices.Contains(closed, closer) {
+			continue
+		}
+		closed = append(closed, closer)
+		if cerr := closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// flush writes the entries of shard b, which must be locked.
+func (s *sink) flush(b *sinkShard) {
+	if len(b.buffer) > 0 {
+		n, err := s.out.Write(b.buffer)
+		s.stats.record(b.buffer, b.pending, n, err)
+		b.buffer = b.buffer[:0]
+		b.pending = 0
+	}
+}
+
+// flusher flushes the sinks of a core on a fixed interval.
+type flusher struct {
+	interval time.Duration
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+func newFlusher(interval time.Duration) *flusher {
+	return &flusher{
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// run flushes the sinks of c every interval until close is called.
+func (f *flusher) run(c *core) {
+	defer close(f.done)
+
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, s := range c.sinks {
+				s.Flush()
+			}
+		case <-f.stop:
+			return
+		}
+	}
+}
+
+// close stops the background goroutine.
+func (f *flusher) close() {
+	f.once.Do(func() {
+		close(f.stop)
+	})
+	<-f.done
+}
@@ -0,0 +1,402 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingEncoder wraps jsonEncoder and counts EncodeEntry calls.
+type countingEncoder struct {
+	jsonEncoder
+	calls *atomic.Int32
+}
+
+func (enc countingEncoder) EncodeEntry(buf []byte, e *Entry) []byte {
+	enc.calls.Add(1)
+	return enc.jsonEncoder.EncodeEntry(buf, e)
+}
+
+func TestLogger_Sinks(t *testing.T) {
+	errs := &bytes.Buffer{}
+	all := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level: DebugLevel,
+		Sinks: []Sink{
+			{Output: errs, Level: WarnLevel, Format: TextFormat},
+			{Output: all, Level: DebugLevel, Format: JSONFormat},
+		},
+	})
+
+	child := logger.With(String("service", "billing"))
+	child.Debug("debug message")
+	child.Error("error message")
+
+	assert.NotContains(t, errs.String(), "debug message")
+	assert.Contains(t, errs.String(), "ERROR error message service=billing")
+
+	lines := strings.Split(strings.TrimSpace(all.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"message":"debug message","service":"billing"}`)
+	assert.Contains(t, lines[1], `"message":"error message","service":"billing"}`)
+}
+
+func TestLogger_SinksShareEncoding(t *testing.T) {
+	first := &bytes.Buffer{}
+	second := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level: InfoLevel,
+		Sinks: []Sink{
+			{Output: first, Level: InfoLevel, Format: JSONFormat},
+			{Output: second, Level: ErrorLevel, Format: JSONFormat},
+		},
+	})
+
+	require.Len(t, logger.core.encs, 1)
+	assert.Equal(t, InfoLevel, logger.core.encLevels[0])
+
+	logger.Info("info message")
+	logger.Error("error message")
+
+	assert.Equal(t, 2, strings.Count(first.String(), "\n"))
+	assert.Equal(t, 1, strings.Count(second.String(), "\n"))
+	assert.Contains(t, second.String(), "error message")
+}
+
+func TestLogger_SinksCustomEncoder(t *testing.T) {
+	var calls atomic.Int32
+	out := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level: InfoLevel,
+		Sinks: []Sink{
+			{Output: out, Level: WarnLevel, Encoder: countingEncoder{calls: &calls}},
+		},
+	})
+
+	logger.Info("skipped before encoding")
+	logger.Warn("written")
+
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Contains(t, out.String(), `"message":"written"`)
+}
+
+func TestLogger_SinksLevels(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level: DebugLevel,
+		Sinks: []Sink{
+			{Output: stdout, Levels: []Level{DebugLevel, InfoLevel}, Format: TextFormat},
+			{Output: stderr, Level: WarnLevel, Format: TextFormat},
+		},
+	})
+
+	require.Len(t, logger.core.encs, 1)
+	assert.Equal(t, DebugLevel, logger.core.encLevels[0])
+
+	logger.Debug("debug message")
+	logger.Info("info message")
+	logger.Warn("warn message")
+	logger.Error("error message")
+
+	assert.Equal(t, 2, strings.Count(stdout.String(), "\n"))
+	assert.Contains(t, stdout.String(), "DEBUG debug message")
+	assert.Contains(t, stdout.String(), "INFO info message")
+
+	assert.Equal(t, 2, strings.Count(stderr.String(), "\n"))
+	assert.Contains(t, stderr.String(), "WARN warn message")
+	assert.Contains(t, stderr.String(), "ERROR error message")
+}
+
+func TestStdSinks(t *testing.T) {
+	sinks := StdSinks(JSONFormat, WarnLevel)
+	require.Len(t, sinks, 2)
+
+	assert.Equal(t, io.Writer(os.Stdout), sinks[0].Output)
+	assert.Equal(t, []Level{DebugLevel, InfoLevel}, sinks[0].Levels)
+	assert.Equal(t, JSONFormat, sinks[0].Format)
+
+	assert.Equal(t, io.Writer(os.Stderr), sinks[1].Output)
+	assert.Equal(t, WarnLevel, sinks[1].Level)
+	assert.Empty(t, sinks[1].Levels)
+
+	sinks = StdSinks(TextFormat, DebugLevel)
+	require.Len(t, sinks, 1)
+	assert.Equal(t, io.Writer(os.Stderr), sinks[0].Output)
+}
+
+func TestLogger_SinksBuffered(t *testing.T) {
+	first := &bytes.Buffer{}
+	second := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:      InfoLevel,
+		BufferSize: 1024,
+		Sinks: []Sink{
+			{Output: first, Level: InfoLevel, Format: TextFormat},
+			{Output: second, Level: InfoLevel, Format: JSONFormat},
+		},
+	})
+
+	logger.Info("buffered")
+	assert.Empty(t, first.String())
+	assert.Empty(t, second.String())
+
+	logger.Flush()
+
+	assert.Contains(t, first.String(), "INFO buffered")
+	assert.Contains(t, second.String(), `"message":"buffered"`)
+}
+
+func TestLogger_FlushInterval(t *testing.T) {
+	buf := &syncBuffer{}
+
+	logger := New(Config{
+		Level:         InfoLevel,
+		Format:        TextFormat,
+		Output:        buf,
+		BufferSize:    4096,
+		FlushInterval: 10 * time.Millisecond,
+	})
+	defer logger.Close()
+
+	logger.Info("trickle")
+
+	assert.Eventually(t, func() bool {
+		return strings.Contains(buf.String(), "trickle")
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestLogger_FlushIntervalStopsOnClose(t *testing.T) {
+	buf := &syncBuffer{}
+
+	logger := New(Config{
+		Level:         InfoLevel,
+		Format:        TextFormat,
+		Output:        buf,
+		BufferSize:    4096,
+		FlushInterval: time.Hour,
+	})
+
+	logger.Info("pending")
+	require.NoError(t, logger.Close())
+	assert.Contains(t, buf.String(), "pending")
+
+	select {
+	case <-logger.core.flusher.done:
+	default:
+		t.Fatal("flusher still running after Close")
+	}
+}
+
+func TestLogger_FlushIntervalIgnoredWithoutBuffer(t *testing.T) {
+	logger := New(Config{
+		Level:         InfoLevel,
+		Output:        io.Discard,
+		FlushInterval: time.Millisecond,
+	})
+
+	assert.Nil(t, logger.core.flusher)
+}
+
+// syncingBuffer is a WriteSyncer recording what had been written at each
+// Sync.
+type syncingBuffer struct {
+	bytes.Buffer
+	synced []string
+	err    error
+}
+
+func (b *syncingBuffer) Sync() error {
+	b.synced = append(b.synced, b.String())
+	return b.err
+}
+
+func TestLogger_Sync(t *testing.T) {
+	out := &syncingBuffer{}
+	plain := &bytes.Buffer{}
+	log := New(Config{
+		Level:      InfoLevel,
+		BufferSize: 4096,
+		Sinks: []Sink{
+			{Output: out, Format: TextFormat},
+			{Output: plain, Format: TextFormat},
+		},
+		OmitTime: true,
+	})
+
+	log.Info("buffered")
+	assert.Empty(t, out.String())
+
+	require.NoError(t, log.Sync())
+	assert.Equal(t, []string{"INFO buffered\n"}, out.synced, "flushed before sync")
+	assert.Equal(t, "INFO buffered\n", plain.String())
+
+	log.Info("flushed")
+	log.Flush()
+	assert.Len(t, out.synced, 2)
+
+	out.err = errors.New("disk full")
+	assert.EqualError(t, log.Close(), "disk full")
+
+	// Outputs that cannot be synced, such as pipes, are skipped.
+	out.err = syscall.EINVAL
+	assert.NoError(t, log.Sync())
+}
+
+func TestLogger_FatalSyncs(t *testing.T) {
+	out := &syncingBuffer{}
+	log := New(Config{
+		Level:    InfoLevel,
+		Output:   out,
+		Format:   TextFormat,
+		OmitTime: true,
+		ExitFunc: func(int) {},
+	})
+
+	log.Fatal("shutting down")
+	assert.Equal(t, []string{"FATAL shutting down\n"}, out.synced)
+}
+
+func TestLogger_SyncFile(t *testing.T) {
+	f, err := os.Create(t.TempDir() + "/app.log")
+	require.NoError(t, err)
+	defer f.Close()
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+	defer w.Close()
+
+	log := New(Config{Level: InfoLevel, Sinks: []Sink{{Output: f}, {Output: w}}})
+	go io.Copy(io.Discard, r)
+	log.Info("synced")
+	assert.NoError(t, log.Sync())
+}
+
+// closingBuffer is a syncingBuffer that records Close calls.
+type closingBuffer struct {
+	syncingBuffer
+	closed int
+}
+
+func (b *closingBuffer) Close() error {
+	b.closed++
+	return nil
+}
+
+func TestLogger_CloseOutputs(t *testing.T) {
+	shared := &closingBuffer{}
+	log := New(Config{
+		Level:        InfoLevel,
+		BufferSize:   4096,
+		CloseOutputs: true,
+		Sinks: []Sink{
+			{Output: shared, Format: TextFormat},
+			{Output: shared, Format: JSONFormat},
+			{Output: os.Stderr, Level: ErrorLevel},
+		},
+		OmitTime: true,
+	})
+
+	log.Info("last")
+	require.NoError(t, log.Close())
+	assert.Equal(t, 1, shared.closed)
+	assert.Len(t, shared.synced, 2, "synced before close")
+	assert.Contains(t, shared.String(), "INFO last")
+
+	require.NoError(t, log.Close())
+	assert.Equal(t, 1, shared.closed)
+
+	kept := &closingBuffer{}
+	require.NoError(t, New(Config{Output: kept}).Close())
+	assert.Zero(t, kept.closed)
+}
+
+func TestLogger_LogDuringClose(t *testing.T) {
+	buf := &syncBuffer{}
+	log := New(Config{Level: InfoLevel, Output: buf, Format: TextFormat, Async: true, BufferSize: 512})
+
+	var (
+		wg     sync.WaitGroup
+		logged atomic.Int32
+	)
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 1000 {
+				log.Info("racing")
+				logged.Add(1)
+			}
+		}()
+	}
+	for logged.Load() == 0 {
+		runtime.Gosched()
+	}
+	require.NoError(t, log.Close())
+	wg.Wait()
+
+	log.Info("after")
+	assert.Contains(t, buf.String(), "racing")
+	assert.NotContains(t, buf.String(), "after")
+}
+
+func TestLogger_BufferShards(t *testing.T) {
+	buf := &syncBuffer{}
+	log := New(Config{
+		Level:        InfoLevel,
+		Format:       TextFormat,
+		Output:       buf,
+		OmitTime:     true,
+		BufferSize:   256,
+		BufferShards: 4,
+	})
+	require.Len(t, log.core.sinks[0].shards, 4)
+
+	var wg sync.WaitGroup
+	for p := 0; p < 8; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				log.Info("sharded", Int("p", p))
+			}
+		}()
+	}
+	wg.Wait()
+	log.Flush()
+
+	for p := 0; p < 8; p++ {
+		assert.Equal(t, 100, strings.Count(buf.String(), "INFO sharded p="+strconv.Itoa(p)+"\n"))
+	}
+	assert.Equal(t, uint64(800), log.Stats().Written)
+	for i := range log.core.sinks[0].shards {
+		assert.Empty(t, log.core.sinks[0].shards[i].buffer)
+	}
+}
+
+func TestLogger_BufferShardsNoAllocations(t *testing.T) {
+	log := New(Config{Level: InfoLevel, Format: JSONFormat, Output: discardWriter, BufferSize: 4096, BufferShards: 4})
+
+	allocs := testing.AllocsPerRun(100, func() {
+		log.Info("request", Int("status", 200))
+	})
+	assert.Zero(t, allocs)
+}
@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+// Stats reports the outcome of writes to the logger's outputs.
+// Counts are per sink: an entry written to two sinks counts twice.
+type Stats struct {
+	// Written is the number of entries written successfully.
+	Written uint64
+
+	// Failed is the number of writes that returned an error. A single
+	// failed flush of a buffered sink counts once.
+	Failed uint64
+
+	// Dropped is the number of entries lost because their write failed.
+	Dropped uint64
+
+	// QueueFull is the number of entries that found the async queue full,
+	// whatever Config.AsyncOverflow did with them. Entries count once,
+	// however many sinks they are for.
+	QueueFull uint64
+
+	// QueueDropped is the number of entries discarded by the
+	// AsyncDropNewest and AsyncDropOldest policies.
+	QueueDropped uint64
+}
+
+// writeStats counts write outcomes and reports failures to
+// Config.ErrorHandler. It is shared by all sinks of a core.
+type writeStats struct {
+	written atomic.Uint64
+	failed  atomic.Uint64
+	dropped atomic.Uint64
+
+	queueFull    atomic.Uint64
+	queueDropped atomic.Uint64
+
+	handler func(err error, entry []byte)
+}
+
+// record accounts for a write of n entries held in p that returned
+// written bytes and err.
+func (s *writeStats) record(p []byte, n int, written int, err error) {
+	if err == nil && written < len(p) {
+		err = io.ErrShortWrite
+	}
+	if err == nil {
+		s.written.Add(uint64(n))
+		return
+	}
+
+	s.failed.Add(1)
+	s.dropped.Add(uint64(n))
+	if s.handler != nil {
+		s.handler(err, p)
+	}
+}
+
+// Stats returns the number of entries written, failed writes and entries
+// dropped by the logger's outputs, and the overflows of the async queue,
+// since it was created. Loggers derived with With share the counts of
+// their parent.
+func (l *Logger) Stats() Stats {
+	s := l.core.stats
+	return Stats{
+		Written: s.written.Load(),
+		Failed:  s.failed.Load(),
+		Dropped: s.dropped.Load(),
+
+		QueueFull:    s.queueFull.Load(),
+		QueueDropped: s.queueDropped.Load(),
+	}
+}
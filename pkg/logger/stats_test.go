@@ -0,0 +1,101 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errBrokenPipe = errors.New("broken pipe")
+
+// failingWriter fails every write while fail is set.
+type failingWriter struct {
+	fail  bool
+	short bool
+	buf   bytes.Buffer
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.fail {
+		return 0, errBrokenPipe
+	}
+	if w.short {
+		return len(p) / 2, nil
+	}
+	return w.buf.Write(p)
+}
+
+func TestLogger_Stats(t *testing.T) {
+	w := &failingWriter{}
+
+	var (
+		handled []error
+		entries []string
+	)
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: TextFormat,
+		Output: w,
+		ErrorHandler: func(err error, entry []byte) {
+			handled = append(handled, err)
+			entries = append(entries, string(entry))
+		},
+	})
+
+	logger.Info("ok")
+	logger.With(String("k", "v")).Info("ok too")
+
+	w.fail = true
+	logger.Info("lost")
+
+	w.fail, w.short = false, true
+	logger.Info("truncated")
+
+	assert.Equal(t, Stats{Written: 2, Failed: 2, Dropped: 2}, logger.Stats())
+	require.Len(t, handled, 2)
+	assert.ErrorIs(t, handled[0], errBrokenPipe)
+	assert.ErrorIs(t, handled[1], io.ErrShortWrite)
+	assert.Contains(t, entries[0], "INFO lost\n")
+	assert.Contains(t, entries[1], "INFO truncated\n")
+}
+
+func TestLogger_StatsBuffered(t *testing.T) {
+	w := &failingWriter{}
+
+	var handled int
+	logger := New(Config{
+		Level:        InfoLevel,
+		Format:       TextFormat,
+		Output:       w,
+		BufferSize:   4096,
+		ErrorHandler: func(error, []byte) { handled++ },
+	})
+
+	logger.Info("one")
+	logger.Info("two")
+	logger.Flush()
+	assert.Equal(t, Stats{Written: 2}, logger.Stats())
+
+	w.fail = true
+	logger.Info("three")
+	logger.Info("four")
+	logger.Info("five")
+	logger.Flush()
+
+	assert.Equal(t, Stats{Written: 2, Failed: 1, Dropped: 3}, logger.Stats())
+	assert.Equal(t, 1, handled)
+}
+
+func TestLogger_StatsWithoutHandler(t *testing.T) {
+	logger := New(Config{
+		Level:  InfoLevel,
+		Output: &failingWriter{fail: true},
+	})
+
+	assert.NotPanics(t, func() { logger.Info("lost") })
+	assert.Equal(t, Stats{Failed: 1, Dropped: 1}, logger.Stats())
+}
@@ -0,0 +1,46 @@
+package logger
+
+import (
+	"bytes"
+	"io"
+	"log"
+)
+
+// stdLogCallerSkip is the number of frames the standard library's
+// log.Logger adds between its caller and Write: its Print method and
+// log.Logger.output.
+const stdLogCallerSkip = 2
+
+// levelWriter is the io.Writer returned by Logger.Writer.
+type levelWriter struct {
+	logger *Logger
+	level  Level
+}
+
+// Write logs p, without its trailing newline, as the message of one entry.
+func (w *levelWriter) Write(p []byte) (int, error) {
+	w.logger.log(w.level, string(bytes.TrimRight(p, "\n")))
+	return len(p), nil
+}
+
+// Writer returns an io.Writer that logs each Write as one entry at level,
+// with the written bytes as its message. Entries at FatalLevel and
+// PanicLevel are logged without exiting or panicking.
+func (l *Logger) Writer(level Level) io.Writer {
+	return &levelWriter{logger: l, level: level}
+}
+
+// StdLogger returns a standard library *log.Logger that logs each of its
+// entries at level, for components that only accept one, such as
+// http.Server.ErrorLog. The log.Logger has no prefix or flags of its own:
+// timestamps and formatting come from this logger.
+//
+// Example:
+//
+//	srv := &http.Server{
+//		Addr:     ":8080",
+//		ErrorLog: log.StdLogger(logger.ErrorLevel),
+//	}
+func (l *Logger) StdLogger(level Level) *log.Logger {
+	return log.New(l.WithCallerSkip(stdLogCallerSkip).Writer(level), "", 0)
+}
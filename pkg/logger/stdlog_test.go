@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger_Writer(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: JSONFormat,
+		Output: buf,
+	})
+
+	w := logger.With(String("component", "driver")).Writer(WarnLevel)
+	n, err := fmt.Fprintf(w, "connection reset\n")
+
+	assert.NoError(t, err)
+	assert.Equal(t, len("connection reset\n"), n)
+	assert.Contains(t, buf.String(), `"level":"WARN","message":"connection reset","component":"driver"`)
+}
+
+func TestLogger_WriterBelowLevel(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: TextFormat,
+		Output: buf,
+	})
+
+	_, _ = logger.Writer(DebugLevel).Write([]byte("hidden\n"))
+	assert.Empty(t, buf.String())
+}
+
+func TestLogger_StdLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:     InfoLevel,
+		Format:    TextFormat,
+		Output:    buf,
+		AddCaller: true,
+	})
+
+	std := logger.StdLogger(ErrorLevel)
+	std.Printf("http: TLS handshake error from %s", "10.0.0.1")
+	std.Println("second")
+
+	output := buf.String()
+	assert.Regexp(t, regexp.MustCompile(`ERROR logger/stdlog_test.go:\d+ http: TLS handshake error from 10.0.0.1\n`), output)
+	assert.Regexp(t, regexp.MustCompile(`ERROR logger/stdlog_test.go:\d+ second\n`), output)
+
+	// The original logger's call site depth is unaffected.
+	buf.Reset()
+	logger.Info("direct")
+	assert.Regexp(t, regexp.MustCompile(`INFO logger/stdlog_test.go:\d+ direct`), buf.String())
+}
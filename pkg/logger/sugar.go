@@ -0,0 +1,132 @@
+package logger
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// BadKey is the key under which Debugw, Infow, Warnw and Errorw log
+// arguments that are not part of a key-value pair: a trailing key
+// without a value, or a key that is not a string. Further such arguments
+// of the same call are logged under BadKey with a "_1", "_2"... suffix,
+// so that the keys stay unique.
+const BadKey = "!BADKEY"
+
+// Debugf formats a message with fmt.Sprintf and logs it at DebugLevel.
+// The message is only formatted if DebugLevel is enabled.
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	if l.Enabled(DebugLevel) {
+		l.log(DebugLevel, fmt.Sprintf(format, args...))
+	}
+}
+
+// Infof formats a message with fmt.Sprintf and logs it at InfoLevel.
+// The message is only formatted if InfoLevel is enabled.
+func (l *Logger) Infof(format string, args ...interface{}) {
+	if l.Enabled(InfoLevel) {
+		l.log(InfoLevel, fmt.Sprintf(format, args...))
+	}
+}
+
+// Warnf formats a message with fmt.Sprintf and logs it at WarnLevel.
+// The message is only formatted if WarnLevel is enabled.
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	if l.Enabled(WarnLevel) {
+		l.log(WarnLevel, fmt.Sprintf(format, args...))
+	}
+}
+
+// Errorf formats a message with fmt.Sprintf and logs it at ErrorLevel.
+// The message is only formatted if ErrorLevel is enabled.
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	if l.Enabled(ErrorLevel) {
+		l.log(ErrorLevel, fmt.Sprintf(format, args...))
+	}
+}
+
+// Fatalf formats a message with fmt.Sprintf and logs it at FatalLevel,
+// then exits like Fatal.
+func (l *Logger) Fatalf(format string, args ...interface{}) {
+	l.log(FatalLevel, fmt.Sprintf(format, args...))
+	l.exit()
+}
+
+// Panicf formats a message with fmt.Sprintf and logs it at PanicLevel,
+// flushes the logger, then panics with the message.
+func (l *Logger) Panicf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	l.log(PanicLevel, msg)
+	l.Flush()
+	panic(msg)
+}
+
+// Debugw logs a message at DebugLevel with fields built from alternating
+// keys and values. See Infow.
+func (l *Logger) Debugw(msg string, keysAndValues ...interface{}) {
+	if l.Enabled(DebugLevel) {
+		l.log(DebugLevel, msg, sweeten(keysAndValues)...)
+	}
+}
+
+// Infow logs a message at InfoLevel with fields built from alternating
+// keys and values, such as Infow("saved", "id", 42, "took", d).
+// Field values in the list are used as is. Arguments that do not form a
+// pair are logged under BadKey instead of causing a panic.
+func (l *Logger) Infow(msg string, keysAndValues ...interface{}) {
+	if l.Enabled(InfoLevel) {
+		l.log(InfoLevel, msg, sweeten(keysAndValues)...)
+	}
+}
+
+// Warnw logs a message at WarnLevel with fields built from alternating
+// keys and values. See Infow.
+func (l *Logger) Warnw(msg string, keysAndValues ...interface{}) {
+	if l.Enabled(WarnLevel) {
+		l.log(WarnLevel, msg, sweeten(keysAndValues)...)
+	}
+}
+
+// Errorw logs a message at ErrorLevel with fields built from alternating
+// keys and values. See Infow.
+func (l *Logger) Errorw(msg string, keysAndValues ...interface{}) {
+	if l.Enabled(ErrorLevel) {
+		l.log(ErrorLevel, msg, sweeten(keysAndValues)...)
+	}
+}
+
+// sweeten turns alternating keys and values into fields.
+func sweeten(keysAndValues []interface{}) []Field {
+	if len(keysAndValues) == 0 {
+		return nil
+	}
+
+	fields := make([]Field, 0, (len(keysAndValues)+1)/2)
+	bad := 0
+	for i := 0; i < len(keysAndValues); i++ {
+		switch v := keysAndValues[i].(type) {
+		case Field:
+			fields = append(fields, v)
+		case string:
+			if i == len(keysAndValues)-1 {
+				fields = append(fields, Any(badKey(bad), v))
+				bad++
+				break
+			}
+			i++
+			fields = append(fields, Any(v, keysAndValues[i]))
+		default:
+			fields = append(fields, Any(badKey(bad), v))
+			bad++
+		}
+	}
+	return fields
+}
+
+// badKey returns the key of the nth argument of a call logged under
+// BadKey.
+func badKey(n int) string {
+	if n == 0 {
+		return BadKey
+	}
+	return BadKey + "_" + strconv.Itoa(n)
+}
@@ -0,0 +1,154 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingStringer struct {
+	calls int
+}
+
+func (s *countingStringer) String() string {
+	s.calls++
+	return "stringer"
+}
+
+func TestLogger_Printf(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  DebugLevel,
+		Format: TextFormat,
+		Output: buf,
+	})
+
+	logger.Debugf("debug %d", 1)
+	logger.Infof("info %s", "two")
+	logger.Warnf("warn %v", 3.5)
+	logger.Errorf("error %q", "four")
+
+	output := buf.String()
+	assert.Contains(t, output, "DEBUG debug 1")
+	assert.Contains(t, output, "INFO info two")
+	assert.Contains(t, output, "WARN warn 3.5")
+	assert.Contains(t, output, `ERROR error "four"`)
+}
+
+func TestLogger_PrintfSkipsFormattingWhenDisabled(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  WarnLevel,
+		Format: TextFormat,
+		Output: buf,
+	})
+
+	s := &countingStringer{}
+	logger.Debugf("%s", s)
+	logger.Infof("%s", s)
+	assert.Equal(t, 0, s.calls)
+	assert.Empty(t, buf.String())
+
+	logger.Warnf("%s", s)
+	assert.Equal(t, 1, s.calls)
+	assert.Contains(t, buf.String(), "WARN stringer")
+}
+
+func TestLogger_Panicf(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: TextFormat,
+		Output: buf,
+	})
+
+	assert.PanicsWithValue(t, "bad value 7", func() { logger.Panicf("bad value %d", 7) })
+	assert.Contains(t, buf.String(), "PANIC bad value 7")
+}
+
+func TestLogger_PrintfCaller(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:     InfoLevel,
+		Format:    TextFormat,
+		Output:    buf,
+		AddCaller: true,
+	})
+
+	logger.Infof("hello %s", "world")
+
+	assert.Regexp(t, regexp.MustCompile(`INFO logger/sugar_test.go:\d+ hello world`), buf.String())
+}
+
+func TestLogger_KeyValues(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  DebugLevel,
+		Format: JSONFormat,
+		Output: buf,
+	})
+
+	logger.Infow("saved", "id", 42, "name", "widget", Bool("cached", true))
+
+	assert.Contains(t, buf.String(), `"message":"saved","id":42,"name":"widget","cached":true`)
+}
+
+func TestLogger_KeyValuesInvalid(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  DebugLevel,
+		Format: JSONFormat,
+		Output: buf,
+	})
+
+	assert.NotPanics(t, func() {
+		logger.Warnw("odd", "id", 1, "dangling")
+		logger.Errorw("non-string key", 7, "k", "v")
+	})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Contains(t, lines[0], `"id":1,"!BADKEY":"dangling"`)
+	assert.Contains(t, lines[1], `"!BADKEY":7,"k":"v"`)
+}
+
+func TestLogger_KeyValuesBadKeysUnique(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(Config{Level: InfoLevel, Format: JSONFormat, Output: buf, OmitTime: true})
+
+	logger.Infow("m", 3, 4, "k", "v", 5, "dangling")
+	assert.Equal(t, `{"level":"INFO","message":"m","!BADKEY":3,"!BADKEY_1":4,"k":"v","!BADKEY_2":5,"!BADKEY_3":"dangling"}`+"\n",
+		buf.String())
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Len(t, entry, 7, "no value is lost to a duplicate key")
+}
+
+func TestLogger_KeyValuesDisabled(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := New(Config{
+		Level:  InfoLevel,
+		Format: TextFormat,
+		Output: buf,
+	})
+
+	logger.Debugw("hidden", "k", "v")
+	assert.Empty(t, buf.String())
+
+	allocs := testing.AllocsPerRun(100, func() {
+		logger.Debugw("hidden", "k", "v")
+	})
+	assert.Zero(t, allocs)
+}
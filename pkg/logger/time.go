@@ -0,0 +1,85 @@
+package logger
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// cachedSecond holds the parts of DefaultTimeFormat that only change once
+// per second: the date and clock, and the zone offset.
+type cachedSecond struct {
+	sec    int64
+	offset int
+	date   [19]byte // 2006-01-02T15:04:05
+	zone   [6]byte  // Z or -07:00
+	zoneN  int
+}
+
+// lastSecond caches the second of the most recently formatted timestamp.
+var lastSecond atomic.Pointer[cachedSecond]
+
+// appendTime appends t in DefaultTimeFormat without allocating. The
+// formatted second is cached, so that for bursts of entries within the
+// same second only the milliseconds are recomputed.
+func appendTime(buf []byte, t time.Time) []byte {
+	sec := t.Unix()
+	_, offset := t.Zone()
+
+	c := lastSecond.Load()
+	if c == nil || c.sec != sec || c.offset != offset {
+		if year := t.Year(); year < 0 || year > 9999 {
+			return t.AppendFormat(buf, DefaultTimeFormat)
+		}
+		c = newCachedSecond(t, sec, offset)
+		lastSecond.Store(c)
+	}
+
+	ms := t.Nanosecond() / int(time.Millisecond)
+	buf = append(buf, c.date[:]...)
+	buf = append(buf, '.', byte('0'+ms/100), byte('0'+ms/10%10), byte('0'+ms%10))
+	return append(buf, c.zone[:c.zoneN]...)
+}
+
+// newCachedSecond formats the second of t. t's year must be in [0, 9999].
+func newCachedSecond(t time.Time, sec int64, offset int) *cachedSecond {
+	c := &cachedSecond{sec: sec, offset: offset}
+
+	year, month, day := t.Date()
+	hour, minute, second := t.Clock()
+	putDigits(c.date[0:4], year)
+	c.date[4] = '-'
+	putDigits(c.date[5:7], int(month))
+	c.date[7] = '-'
+	putDigits(c.date[8:10], day)
+	c.date[10] = 'T'
+	putDigits(c.date[11:13], hour)
+	c.date[13] = ':'
+	putDigits(c.date[14:16], minute)
+	c.date[16] = ':'
+	putDigits(c.date[17:19], second)
+
+	if offset == 0 {
+		c.zone[0] = 'Z'
+		c.zoneN = 1
+		return c
+	}
+
+	c.zone[0] = '+'
+	if offset < 0 {
+		c.zone[0] = '-'
+		offset = -offset
+	}
+	putDigits(c.zone[1:3], offset/3600)
+	c.zone[3] = ':'
+	putDigits(c.zone[4:6], offset/60%60)
+	c.zoneN = 6
+	return c
+}
+
+// putDigits writes n into b as len(b) zero-padded decimal digits.
+func putDigits(b []byte, n int) {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i] = byte('0' + n%10)
+		n /= 10
+	}
+}
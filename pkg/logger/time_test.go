@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppendTime_MatchesFormat(t *testing.T) {
+	zones := []*time.Location{
+		time.UTC,
+		time.FixedZone("east", 5*3600+30*60),
+		time.FixedZone("west", -8*3600),
+	}
+	times := []time.Time{
+		time.Date(2024, 1, 20, 15, 4, 5, 0, time.UTC),
+		time.Date(2024, 12, 31, 23, 59, 59, 999999999, time.UTC),
+		time.Date(1969, 7, 20, 20, 17, 40, 5000000, time.UTC),
+		time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC),
+		time.Date(12345, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(-1, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	for _, loc := range zones {
+		for _, ts := range times {
+			ts := ts.In(loc)
+			assert.Equal(t, ts.Format(DefaultTimeFormat), string(appendTime(nil, ts)))
+			// The second call is served from the cache.
+			assert.Equal(t, ts.Format(DefaultTimeFormat), string(appendTime(nil, ts)))
+		}
+	}
+}
+
+func TestAppendTime_SameSecond(t *testing.T) {
+	base := time.Date(2024, 1, 20, 15, 4, 5, 0, time.UTC)
+
+	for ms := 0; ms < 1000; ms += 111 {
+		ts := base.Add(time.Duration(ms) * time.Millisecond)
+		assert.Equal(t, ts.Format(DefaultTimeFormat), string(appendTime(nil, ts)))
+	}
+}
+
+func TestAppendTime_NoAllocations(t *testing.T) {
+	buf := make([]byte, 0, 64)
+	ts := time.Now()
+	appendTime(buf, ts)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		buf = appendTime(buf[:0], ts)
+	})
+	assert.Zero(t, allocs)
+}
+
+func TestConfig_Clock(t *testing.T) {
+	buf := &bytes.Buffer{}
+	frozen := time.Date(2024, 1, 20, 15, 4, 5, 0, time.FixedZone("east", 3600))
+	logger := New(Config{
+		Format: JSONFormat,
+		Output: buf,
+		UseUTC: true,
+		Clock:  func() time.Time { return frozen },
+	})
+
+	logger.Info("first")
+	logger.Info("second")
+	assert.Equal(t, `{"timestamp":"2024-01-20T14:04:05.000Z","level":"INFO","message":"first"}`+"\n"+
+		`{"timestamp":"2024-01-20T14:04:05.000Z","level":"INFO","message":"second"}`+"\n", buf.String())
+}
@@ -0,0 +1,100 @@
+package logger
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultWatchInterval is how often WatchConfigFile checks the file when
+// WatchConfig.Interval is not set.
+const DefaultWatchInterval = 5 * time.Second
+
+// WatchConfig holds the configuration of WatchConfigFile.
+type WatchConfig struct {
+	// Interval is how often the file is checked for changes.
+	// Defaults to DefaultWatchInterval.
+	Interval time.Duration
+
+	// OnReload is called after each attempt to reload the file, with the
+	// config read and a nil error if it was applied, or with the error
+	// that caused it to be rejected.
+	OnReload func(config Config, err error)
+}
+
+// SetSampling atomically replaces the sampling of repetitive entries with
+// config, or disables it if config is nil. Sampling counters start over.
+// It affects the logger and every logger sharing its outputs.
+func (l *Logger) SetSampling(config *SamplerConfig) {
+	if config == nil {
+		l.core.sampler.Store(nil)
+		return
+	}
+	l.core.sampler.Store(newSampler(config))
+}
+
+// WatchConfigFile polls the config file at path, which the logger is
+// assumed to have been built from, and reloads it with ConfigFromFile
+// whenever its modification time or size changes. The level, the levels
+// of named loggers, sampling and redaction of the new config are applied
+// to the live logger; other settings take effect on restart. A file that
+// cannot be read or parsed is rejected and the logger keeps its current
+// settings.
+//
+// Call the returned function to stop watching.
+func (l *Logger) WatchConfigFile(path string, config WatchConfig) (stop func()) {
+	interval := config.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	last, _ := os.Stat(path)
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				info, err := os.Stat(path)
+				if err == nil && last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+					continue
+				}
+				if err != nil && last == nil {
+					continue
+				}
+				last = info
+				l.reload(path, config.OnReload)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-stopped
+		})
+	}
+}
+
+// reload reads the config file at path and applies its runtime settings.
+func (l *Logger) reload(path string, onReload func(Config, error)) {
+	config, err := ConfigFromFile(path)
+	if err == nil {
+		l.SetLevel(config.Level)
+		l.SetLevels(config.Levels)
+		l.SetSampling(config.Sampling)
+		l.SetRedact(config.Redact)
+	}
+	if onReload != nil {
+		onReload(config, err)
+	}
+}
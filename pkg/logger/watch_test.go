@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger_WatchConfigFile(t *testing.T) {
+	path := writeConfigFile(t, "log.yaml", "level: info\n")
+	config, err := ConfigFromFile(path)
+	require.NoError(t, err)
+	config.Output = io.Discard
+	logger := New(config)
+
+	type reload struct {
+		config Config
+		err    error
+	}
+	reloads := make(chan reload, 4)
+	stop := logger.WatchConfigFile(path, WatchConfig{
+		Interval: 5 * time.Millisecond,
+		OnReload: func(config Config, err error) {
+			reloads <- reload{config, err}
+		},
+	})
+	defer stop()
+
+	require.NoError(t, os.WriteFile(path, []byte("level: debug\nlevels: {db: error}\nsampling: {initial: 1}\nredact: {keys: [password]}\n"), 0o644))
+	select {
+	case r := <-reloads:
+		require.NoError(t, r.err)
+		assert.Equal(t, DebugLevel, r.config.Level)
+	case <-time.After(5 * time.Second):
+		t.Fatal("config not reloaded")
+	}
+	assert.Equal(t, DebugLevel, logger.GetLevel())
+	assert.Equal(t, ErrorLevel, logger.Named("db").GetLevel())
+	assert.NotNil(t, logger.core.sampler.Load())
+	assert.NotNil(t, logger.core.redactor.Load())
+
+	require.NoError(t, os.WriteFile(path, []byte("level: verbose\n"), 0o644))
+	select {
+	case r := <-reloads:
+		assert.Error(t, r.err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("config not reloaded")
+	}
+	assert.Equal(t, DebugLevel, logger.GetLevel())
+	assert.Equal(t, ErrorLevel, logger.Named("db").GetLevel())
+
+	stop()
+	stop()
+}
+
+func TestLogger_SetSampling(t *testing.T) {
+	buf := &syncBuffer{}
+	logger := New(Config{Level: InfoLevel, Format: TextFormat, Output: buf})
+
+	logger.SetSampling(&SamplerConfig{Tick: time.Hour, Initial: 1})
+	logger.Info("repeated")
+	logger.Info("repeated")
+	assert.Equal(t, 1, strings.Count(buf.String(), "\n"))
+
+	logger.SetSampling(nil)
+	logger.Info("repeated")
+	assert.Equal(t, 2, strings.Count(buf.String(), "\n"))
+}
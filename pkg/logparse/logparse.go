@@ -0,0 +1,333 @@
+// Package logparse decodes the entries written by the logger package in
+// JSONFormat, TextFormat and ConsoleFormat back into structured entries,
+// for tools that read logs: test assertions, pretty printers and
+// shippers replaying files.
+//
+// Example usage:
+//
+//	scanner := bufio.NewScanner(file)
+//	for scanner.Scan() {
+//		e, err := logparse.Parse(scanner.Bytes())
+//		if err != nil {
+//			continue // not an entry
+//		}
+//		if e.Level >= logger.ErrorLevel {
+//			fmt.Println(e.Time, e.Message, e.Fields)
+//		}
+//	}
+package logparse
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// ErrNotEntry is returned for lines that are not log entries.
+var ErrNotEntry = errors.New("logparse: not a log entry")
+
+// Entry is a decoded log entry.
+type Entry struct {
+	// Time is the timestamp of the entry, or the zero time if it has none.
+	// A timestamp that cannot be parsed is kept in Fields instead.
+	Time time.Time
+
+	// Level is the severity of the entry.
+	Level logger.Level
+
+	// Message is the log message.
+	Message string
+
+	// Caller is the "dir/file.go:line" call site of entries written with
+	// Config.AddCaller, or "".
+	Caller string
+
+	// Fields holds the other members of the entry, in order. Values of
+	// JSON entries are decoded as by encoding/json with UseNumber:
+	// strings, json.Number, bools, nil, []any and map[string]any. Values
+	// of text entries are strings.
+	Fields []Field
+}
+
+// Field is a key and value of an Entry.
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Field returns the value of the first field of e with the given key.
+func (e *Entry) Field(key string) (any, bool) {
+	for _, f := range e.Fields {
+		if f.Key == key {
+			return f.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Parser decodes entries. Its zero value reads entries written with the
+// default keys.
+type Parser struct {
+	// Keys are the Config.Keys the entries were written with. Empty keys
+	// are their defaults.
+	Keys logger.Keys
+}
+
+// Parse decodes line with the zero Parser.
+func Parse(line []byte) (Entry, error) {
+	return Parser{}.Parse(line)
+}
+
+// Parse decodes line as a JSON entry if it starts with '{', and as a text
+// or console entry otherwise. It returns ErrNotEntry for other lines.
+func (p Parser) Parse(line []byte) (Entry, error) {
+	line = bytes.TrimSpace(line)
+	if len(line) > 0 && line[0] == '{' {
+		return p.ParseJSON(line)
+	}
+	return p.ParseText(line)
+}
+
+// ParseJSON decodes line as an entry written in JSONFormat.
+func (p Parser) ParseJSON(line []byte) (Entry, error) {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return Entry{}, ErrNotEntry
+	}
+
+	timeKey := orDefault(p.Keys.Time, logger.DefaultTimeKey)
+	levelKey := orDefault(p.Keys.Level, logger.DefaultLevelKey)
+	messageKey := orDefault(p.Keys.Message, logger.DefaultMessageKey)
+	callerKey := orDefault(p.Keys.Caller, logger.DefaultCallerKey)
+
+	var e Entry
+	hasLevel := false
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return Entry{}, fmt.Errorf("logparse: %w", err)
+		}
+		key, _ := tok.(string)
+
+		var value any
+		if err := dec.Decode(&value); err != nil {
+			return Entry{}, fmt.Errorf("logparse: %w", err)
+		}
+
+		s, isString := value.(string)
+		switch {
+		case key == levelKey && !hasLevel:
+			level, err := ParseLevel(text(value))
+			if err != nil {
+				return Entry{}, fmt.Errorf("logparse: %w", err)
+			}
+			e.Level, hasLevel = level, true
+		case key == messageKey && isString && e.Message == "":
+			e.Message = s
+		case key == callerKey && isString && e.Caller == "":
+			e.Caller = s
+		case key == timeKey && e.Time.IsZero():
+			if t, ok := parseTime(text(value)); ok {
+				e.Time = t
+				continue
+			}
+			e.Fields = append(e.Fields, Field{Key: key, Value: value})
+		default:
+			e.Fields = append(e.Fields, Field{Key: key, Value: value})
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return Entry{}, fmt.Errorf("logparse: %w", err)
+	}
+	if !hasLevel {
+		return Entry{}, ErrNotEntry
+	}
+	return e, nil
+}
+
+// callerPattern matches the "dir/file.go:line" call site of text entries.
+var callerPattern = regexp.MustCompile(`^\S+\.go:\d+$`)
+
+// ansiPattern matches the escape sequences coloring console entries.
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// ParseText decodes line as an entry written in TextFormat or
+// ConsoleFormat: an optional timestamp, the level, an optional call site,
+// the message and key=value fields. Since messages are not quoted, trailing
+// words of a message that look like key=value pairs are read as fields.
+func (p Parser) ParseText(line []byte) (Entry, error) {
+	s := strings.TrimSpace(ansiPattern.ReplaceAllString(string(line), ""))
+
+	var e Entry
+	word, rest := cut(s)
+	if t, ok := parseTime(word); ok {
+		if _, err := ParseLevel(word); err != nil {
+			e.Time = t
+			word, rest = cut(rest)
+		}
+	}
+
+	level, err := ParseLevel(word)
+	if err != nil {
+		return Entry{}, ErrNotEntry
+	}
+	e.Level = level
+
+	if word, after := cut(rest); callerPattern.MatchString(word) {
+		e.Caller, rest = word, after
+	}
+
+	tokens := tokenize(rest)
+	first := len(tokens)
+	for first > 0 && tokens[first-1].key != "" {
+		first--
+	}
+	if first > 0 {
+		last := tokens[first-1]
+		e.Message = rest[:last.end]
+	}
+	for _, tok := range tokens[first:] {
+		e.Fields = append(e.Fields, Field{Key: tok.key, Value: tok.value})
+	}
+	return e, nil
+}
+
+// cut returns the first space-separated word of s and the rest, with the
+// spaces between them removed.
+func cut(s string) (string, string) {
+	word, rest, _ := strings.Cut(s, " ")
+	return word, strings.TrimLeft(rest, " ")
+}
+
+// token is a word of a text entry: a key=value pair if key is set.
+type token struct {
+	key   string
+	value string
+	end   int
+}
+
+// tokenize splits the message and fields of a text entry into words. A
+// value starting with a double quote runs up to the next double quote
+// followed by a space or the end of s.
+func tokenize(s string) []token {
+	var tokens []token
+	for i := 0; i < len(s); {
+		if s[i] == ' ' {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(s) && s[i] != ' ' && s[i] != '=' {
+			i++
+		}
+		if i == start || i == len(s) || s[i] != '=' {
+			for i < len(s) && s[i] != ' ' {
+				i++
+			}
+			tokens = append(tokens, token{end: i})
+			continue
+		}
+
+		key := s[start:i]
+		i++
+		valueStart := i
+		if i < len(s) && s[i] == '"' {
+			for i++; i < len(s); i++ {
+				if s[i] == '"' && (i+1 == len(s) || s[i+1] == ' ') {
+					i++
+					break
+				}
+			}
+		} else {
+			for i < len(s) && s[i] != ' ' {
+				i++
+			}
+		}
+		tokens = append(tokens, token{key: key, value: unquote(s[valueStart:i]), end: i})
+	}
+	return tokens
+}
+
+// unquote removes the double quotes TextFormat wraps values in.
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// levelCodes maps the short names and the syslog and OpenTelemetry
+// severity numbers written by the level encoders of the logger to levels.
+// The two sets of numbers do not overlap.
+var levelCodes = map[string]logger.Level{
+	"D": logger.DebugLevel, "I": logger.InfoLevel, "W": logger.WarnLevel,
+	"E": logger.ErrorLevel, "F": logger.FatalLevel, "P": logger.PanicLevel,
+	"7": logger.DebugLevel, "6": logger.InfoLevel, "4": logger.WarnLevel,
+	"3": logger.ErrorLevel, "2": logger.FatalLevel, "1": logger.PanicLevel,
+	"5": logger.DebugLevel, "9": logger.InfoLevel, "13": logger.WarnLevel,
+	"17": logger.ErrorLevel, "23": logger.FatalLevel, "22": logger.PanicLevel,
+}
+
+// ParseLevel returns the level written as s by any logger.LevelEncoder,
+// such as "INFO", "info", "I", 6 or 9.
+func ParseLevel(s string) (logger.Level, error) {
+	if level, ok := levelCodes[strings.ToUpper(s)]; ok {
+		return level, nil
+	}
+	return logger.ParseLevel(s)
+}
+
+// timeLayouts are the layouts timestamps are parsed with, in order.
+var timeLayouts = []string{logger.DefaultTimeFormat, time.RFC3339Nano}
+
+// parseTime parses a timestamp written with one of timeLayouts, or as
+// seconds, milliseconds or nanoseconds since the Unix epoch, told apart by
+// their magnitude.
+func parseTime(s string) (time.Time, bool) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	switch {
+	case err != nil || n <= 0:
+		return time.Time{}, false
+	case n < 1e11:
+		return time.Unix(n, 0).UTC(), true
+	case n < 1e14:
+		return time.UnixMilli(n).UTC(), true
+	default:
+		return time.Unix(0, n).UTC(), true
+	}
+}
+
+// text returns value as a string if it is a string or a number.
+func text(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	default:
+		return ""
+	}
+}
+
+// orDefault returns key, or def if key is empty.
+func orDefault(key, def string) string {
+	if key == "" {
+		return def
+	}
+	return key
+}
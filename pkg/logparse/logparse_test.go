@@ -0,0 +1,133 @@
+package logparse
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+var clock = func() time.Time { return time.Date(2024, 1, 20, 15, 4, 5, 123e6, time.UTC) }
+
+func TestParse_RoundTrip(t *testing.T) {
+	for _, format := range []logger.Format{logger.JSONFormat, logger.TextFormat, logger.ConsoleFormat} {
+		t.Run(format.String(), func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			log := logger.New(logger.Config{
+				Level:     logger.DebugLevel,
+				Format:    format,
+				Output:    buf,
+				Clock:     clock,
+				AddCaller: true,
+			})
+
+			log.With(logger.String("service", "api")).Warn("disk almost full",
+				logger.Int("free", 42),
+				logger.String("path", "/var/my data"),
+			)
+
+			e, err := Parse(buf.Bytes())
+			require.NoError(t, err)
+			assert.True(t, clock().Equal(e.Time), e.Time)
+			assert.Equal(t, logger.WarnLevel, e.Level)
+			assert.Equal(t, "disk almost full", e.Message)
+			assert.Regexp(t, `^logparse/logparse_test.go:\d+$`, e.Caller)
+
+			service, _ := e.Field("service")
+			assert.Equal(t, "api", service)
+			path, _ := e.Field("path")
+			assert.Equal(t, "/var/my data", path)
+			free, ok := e.Field("free")
+			require.True(t, ok)
+			if format == logger.JSONFormat {
+				assert.Equal(t, json.Number("42"), free)
+			} else {
+				assert.Equal(t, "42", free)
+			}
+		})
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	p := Parser{Keys: logger.Keys{Time: "ts", Level: "lvl", Message: "msg"}}
+
+	e, err := p.Parse([]byte(`{"ts":1705763045123,"lvl":13,"msg":"slow","user":{"id":7},"tags":["a"],"ok":true,"none":null}`))
+	require.NoError(t, err)
+	assert.Equal(t, time.UnixMilli(1705763045123).UTC(), e.Time)
+	assert.Equal(t, logger.WarnLevel, e.Level)
+	assert.Equal(t, "slow", e.Message)
+	assert.Equal(t, []Field{
+		{Key: "user", Value: map[string]any{"id": json.Number("7")}},
+		{Key: "tags", Value: []any{"a"}},
+		{Key: "ok", Value: true},
+		{Key: "none", Value: nil},
+	}, e.Fields)
+
+	e, err = Parse([]byte(`{"timestamp":"yesterday","level":"E","message":"m"}`))
+	require.NoError(t, err)
+	assert.True(t, e.Time.IsZero())
+	assert.Equal(t, logger.ErrorLevel, e.Level)
+	assert.Equal(t, []Field{{Key: "timestamp", Value: "yesterday"}}, e.Fields)
+
+	_, err = Parse([]byte(`{"message":"no level"}`))
+	assert.ErrorIs(t, err, ErrNotEntry)
+	_, err = Parse([]byte(`{"level":"loud"}`))
+	assert.Error(t, err)
+	_, err = Parse([]byte(`{"level":"INFO"`))
+	assert.Error(t, err)
+}
+
+func TestParseText(t *testing.T) {
+	e, err := Parse([]byte(`INFO user a=b logged in id=7 note="x = y" quote="say "hi"" empty=`))
+	require.NoError(t, err)
+	assert.True(t, e.Time.IsZero())
+	assert.Equal(t, logger.InfoLevel, e.Level)
+	assert.Equal(t, "user a=b logged in", e.Message)
+	assert.Equal(t, []Field{
+		{Key: "id", Value: "7"},
+		{Key: "note", Value: "x = y"},
+		{Key: "quote", Value: `say "hi"`},
+		{Key: "empty", Value: ""},
+	}, e.Fields)
+
+	e, err = Parse([]byte("1705763045 6 started"))
+	require.NoError(t, err)
+	assert.Equal(t, time.Unix(1705763045, 0).UTC(), e.Time)
+	assert.Equal(t, logger.InfoLevel, e.Level)
+	assert.Equal(t, "started", e.Message)
+	assert.Empty(t, e.Fields)
+
+	for _, line := range []string{"", "hello world", "2024-01-20T15:04:05.000Z nothing"} {
+		_, err := Parse([]byte(line))
+		assert.True(t, errors.Is(err, ErrNotEntry), line)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, le := range []logger.LevelEncoder{
+		logger.UppercaseLevelEncoder, logger.LowercaseLevelEncoder, logger.ShortLevelEncoder,
+		logger.SyslogLevelEncoder, logger.OTelLevelEncoder,
+	} {
+		for level := logger.DebugLevel; level <= logger.PanicLevel; level++ {
+			buf := &bytes.Buffer{}
+			log := logger.New(logger.Config{
+				Level:        logger.DebugLevel,
+				Format:       logger.TextFormat,
+				Output:       buf,
+				OmitTime:     true,
+				LevelEncoder: le,
+			})
+			log.Log(level, "m")
+
+			e, err := Parse(buf.Bytes())
+			require.NoError(t, err, buf.String())
+			assert.Equal(t, level, e.Level, buf.String())
+		}
+	}
+}
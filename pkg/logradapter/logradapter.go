@@ -0,0 +1,151 @@
+// Package logradapter implements logr.LogSink on top of a logger.Logger, so
+// that code written against logr, such as Kubernetes controllers built
+// with controller-runtime, logs through this package.
+//
+// V-level 0 maps to InfoLevel and every higher V-level to DebugLevel.
+// Errors are logged at ErrorLevel with the error under the "error" key.
+//
+// Example usage:
+//
+//	log := logger.New(logger.Config{Level: logger.InfoLevel, Format: logger.JSONFormat})
+//	ctrl.SetLogger(logradapter.New(log))
+package logradapter
+
+import (
+	"strconv"
+
+	"github.com/go-logr/logr"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// NameKey is the key under which the name built with logr's WithName is
+// logged.
+const NameKey = "logger"
+
+// New returns a logr.Logger writing to l.
+func New(l *logger.Logger) logr.Logger {
+	return logr.New(NewSink(l))
+}
+
+// NewSink returns a logr.LogSink writing to l.
+func NewSink(l *logger.Logger) logr.LogSink {
+	return &sink{logger: l}
+}
+
+// sink implements logr.LogSink and logr.CallDepthLogSink.
+type sink struct {
+	logger *logger.Logger
+	name   string
+}
+
+var (
+	_ logr.LogSink          = (*sink)(nil)
+	_ logr.CallDepthLogSink = (*sink)(nil)
+)
+
+// levelOf maps a logr V-level to a logger level.
+func levelOf(v int) logger.Level {
+	if v > 0 {
+		return logger.DebugLevel
+	}
+	return logger.InfoLevel
+}
+
+// Init implements logr.LogSink. It accounts for the frames logr adds
+// between the caller and the sink when AddCaller is set.
+func (s *sink) Init(info logr.RuntimeInfo) {
+	s.logger = s.withCallDepth(info.CallDepth)
+}
+
+// Enabled implements logr.LogSink.
+func (s *sink) Enabled(level int) bool {
+	return s.logger.Enabled(levelOf(level))
+}
+
+// Info implements logr.LogSink.
+func (s *sink) Info(level int, msg string, keysAndValues ...interface{}) {
+	fields := s.fields(nil, keysAndValues)
+	if levelOf(level) == logger.DebugLevel {
+		s.logger.Debug(msg, fields...)
+	} else {
+		s.logger.Info(msg, fields...)
+	}
+}
+
+// Error implements logr.LogSink.
+func (s *sink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.logger.Error(msg, s.fields(err, keysAndValues)...)
+}
+
+// WithValues implements logr.LogSink.
+func (s *sink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	child := *s
+	child.logger = s.logger.With(toFields(nil, keysAndValues)...)
+	return &child
+}
+
+// WithName implements logr.LogSink. Names are joined with "/".
+func (s *sink) WithName(name string) logr.LogSink {
+	child := *s
+	if child.name == "" {
+		child.name = name
+	} else {
+		child.name += "/" + name
+	}
+	return &child
+}
+
+// WithCallDepth implements logr.CallDepthLogSink.
+func (s *sink) WithCallDepth(depth int) logr.LogSink {
+	child := *s
+	child.logger = s.withCallDepth(depth)
+	return &child
+}
+
+// withCallDepth returns the sink's logger reporting call sites depth
+// frames further up, plus the frame of the sink method itself.
+func (s *sink) withCallDepth(depth int) *logger.Logger {
+	return s.logger.WithCallerSkip(depth + 1)
+}
+
+// fields returns the fields of an entry: the sink's name, err if not nil,
+// and keysAndValues.
+func (s *sink) fields(err error, keysAndValues []interface{}) []logger.Field {
+	fields := make([]logger.Field, 0, 2+(len(keysAndValues)+1)/2)
+	if s.name != "" {
+		fields = append(fields, logger.String(NameKey, s.name))
+	}
+	if err != nil {
+		fields = append(fields, logger.Err(err))
+	}
+	return toFields(fields, keysAndValues)
+}
+
+// toFields appends alternating keys and values to fields. Values
+// implementing logr.Marshaler are logged as their MarshalLog result, and
+// arguments that do not form a pair are logged under logger.BadKey,
+// suffixed with "_1", "_2"... after the first one of the call.
+func toFields(fields []logger.Field, keysAndValues []interface{}) []logger.Field {
+	bad := 0
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key, ok := keysAndValues[i].(string)
+		if !ok || i == len(keysAndValues)-1 {
+			badKey := logger.BadKey
+			if bad > 0 {
+				badKey += "_" + strconv.Itoa(bad)
+			}
+			bad++
+			fields = append(fields, logger.Any(badKey, keysAndValues[i]))
+			i--
+			continue
+		}
+
+		value := keysAndValues[i+1]
+		if m, ok := value.(logr.Marshaler); ok {
+			value = m.MarshalLog()
+		}
+		fields = append(fields, logger.Any(key, value))
+	}
+	return fields
+}
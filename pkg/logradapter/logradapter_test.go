@@ -0,0 +1,121 @@
+package logradapter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+func newTestLogger(buf *bytes.Buffer, level logger.Level) *logger.Logger {
+	return logger.New(logger.Config{
+		Level:  level,
+		Format: logger.JSONFormat,
+		Output: buf,
+	})
+}
+
+func decode(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry), line)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+type secret string
+
+func (secret) MarshalLog() interface{} {
+	return "<redacted>"
+}
+
+func TestLogger_Levels(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := New(newTestLogger(buf, logger.DebugLevel))
+
+	log.Info("v0", "k", "v")
+	log.V(1).Info("v1")
+	log.V(4).Info("v4")
+	log.Error(errors.New("boom"), "failed", "attempt", 3)
+
+	entries := decode(t, buf)
+	require.Len(t, entries, 4)
+	assert.Equal(t, "INFO", entries[0]["level"])
+	assert.Equal(t, "v", entries[0]["k"])
+	assert.Equal(t, "DEBUG", entries[1]["level"])
+	assert.Equal(t, "DEBUG", entries[2]["level"])
+	assert.Equal(t, "ERROR", entries[3]["level"])
+	assert.Equal(t, "boom", entries[3]["error"])
+	assert.Equal(t, float64(3), entries[3]["attempt"])
+}
+
+func TestLogger_Enabled(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := New(newTestLogger(buf, logger.InfoLevel))
+
+	assert.True(t, log.Enabled())
+	assert.False(t, log.V(1).Enabled())
+
+	log.V(1).Info("hidden")
+	assert.Empty(t, buf.String())
+}
+
+func TestLogger_WithValuesAndName(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := New(newTestLogger(buf, logger.InfoLevel)).
+		WithName("controller").
+		WithName("reconciler").
+		WithValues("namespace", "default", "token", secret("s3cr3t"))
+
+	log.Info("reconciling", "odd")
+
+	entries := decode(t, buf)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "controller/reconciler", entries[0][NameKey])
+	assert.Equal(t, "default", entries[0]["namespace"])
+	assert.Equal(t, "<redacted>", entries[0]["token"])
+	assert.Equal(t, "odd", entries[0][logger.BadKey])
+}
+
+func TestLogger_BadKeysUnique(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := New(newTestLogger(buf, logger.InfoLevel))
+
+	log.Info("reconciling", 1, 2, "dangling")
+
+	entries := decode(t, buf)
+	require.Len(t, entries, 1)
+	assert.Equal(t, float64(1), entries[0][logger.BadKey])
+	assert.Equal(t, float64(2), entries[0][logger.BadKey+"_1"])
+	assert.Equal(t, "dangling", entries[0][logger.BadKey+"_2"])
+}
+
+func TestLogger_Caller(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := New(logger.New(logger.Config{
+		Level:     logger.InfoLevel,
+		Format:    logger.TextFormat,
+		Output:    buf,
+		AddCaller: true,
+	}))
+
+	_, _, line, _ := runtime.Caller(0)
+	log.Info("direct")
+	log.WithValues("k", "v").Error(errors.New("boom"), "derived")
+
+	output := buf.String()
+	assert.Contains(t, output, "INFO logradapter/logradapter_test.go:"+strconv.Itoa(line+1)+" direct")
+	assert.Contains(t, output, "ERROR logradapter/logradapter_test.go:"+strconv.Itoa(line+2)+" derived")
+}
@@ -0,0 +1,266 @@
+// Package logtest records the entries of a logger.Logger for tests, so
+// that they can assert on levels, messages and fields instead of matching
+// raw output.
+//
+// Example usage:
+//
+//	func TestCharge(t *testing.T) {
+//		log, logs := logtest.NewCaptureLogger(logger.DebugLevel)
+//		charge(log, 42)
+//		logs.AssertLogged(t, logger.InfoLevel, "charged", logtest.Field("amount", 42))
+//	}
+package logtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+	"github.com/barnowlsnest/go-logslib/pkg/logparse"
+)
+
+// Entry is an entry recorded by an Observer.
+type Entry struct {
+	Level   logger.Level
+	Message string
+
+	// Fields holds the fields of the entry, including those bound with
+	// With, as decoded from JSON: strings, float64 numbers, bools, nil,
+	// []any and map[string]any.
+	Fields map[string]any
+}
+
+// NewCaptureLogger returns a logger writing entries at level and above to
+// the returned Observer instead of an output.
+func NewCaptureLogger(level logger.Level) (*logger.Logger, *Observer) {
+	o := &Observer{}
+	log := logger.New(logger.Config{
+		Level:    level,
+		Format:   logger.JSONFormat,
+		Output:   o,
+		OmitTime: true,
+	})
+	return log, o
+}
+
+// Observer records the entries written by a capture logger. It is safe
+// for concurrent use.
+type Observer struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// Write decodes and records the JSON entries in p, one per line. It is
+// called by the capture logger.
+func (o *Observer) Write(p []byte) (int, error) {
+	for rest := p; len(rest) > 0; {
+		line, tail, _ := bytes.Cut(rest, []byte{'\n'})
+		rest = tail
+		if len(line) == 0 {
+			continue
+		}
+
+		e, err := decode(line)
+		if err != nil {
+			return 0, err
+		}
+		o.mu.Lock()
+		o.entries = append(o.entries, e)
+		o.mu.Unlock()
+	}
+	return len(p), nil
+}
+
+// decode decodes an entry written by the capture logger.
+func decode(line []byte) (Entry, error) {
+	e, err := logparse.Parser{}.ParseJSON(line)
+	if err != nil {
+		return Entry{}, fmt.Errorf("logtest: %w", err)
+	}
+
+	fields := make(map[string]any, len(e.Fields))
+	for _, f := range e.Fields {
+		fields[f.Key] = plain(f.Value)
+	}
+	return Entry{Level: e.Level, Message: e.Message, Fields: fields}, nil
+}
+
+// plain converts the json.Number values decoded by logparse, including
+// those nested in arrays and objects, to float64.
+func plain(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		f, _ := v.Float64()
+		return f
+	case []any:
+		for i := range v {
+			v[i] = plain(v[i])
+		}
+	case map[string]any:
+		for key := range v {
+			v[key] = plain(v[key])
+		}
+	}
+	return v
+}
+
+// Entries returns the entries recorded so far, oldest first.
+func (o *Observer) Entries() []Entry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]Entry(nil), o.entries...)
+}
+
+// Len returns the number of entries recorded so far.
+func (o *Observer) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.entries)
+}
+
+// Reset discards the entries recorded so far.
+func (o *Observer) Reset() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.entries = nil
+}
+
+// Find returns the entries at level whose message contains msgContains
+// and whose fields satisfy every matcher.
+func (o *Observer) Find(level logger.Level, msgContains string, matchers ...FieldMatcher) []Entry {
+	var found []Entry
+	for _, e := range o.Entries() {
+		if e.matches(level, msgContains, matchers) {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+// AssertLogged reports a test error unless an entry at level whose
+// message contains msgContains and whose fields satisfy every matcher was
+// recorded. It returns whether one was.
+func (o *Observer) AssertLogged(t testing.TB, level logger.Level, msgContains string, matchers ...FieldMatcher) bool {
+	t.Helper()
+
+	if len(o.Find(level, msgContains, matchers...)) > 0 {
+		return true
+	}
+	t.Errorf("logtest: no %s entry containing %q%s was logged; entries:\n%s",
+		level, msgContains, describe(matchers), o)
+	return false
+}
+
+// AssertNotLogged reports a test error if an entry at level whose message
+// contains msgContains and whose fields satisfy every matcher was
+// recorded. It returns whether none was.
+func (o *Observer) AssertNotLogged(t testing.TB, level logger.Level, msgContains string, matchers ...FieldMatcher) bool {
+	t.Helper()
+
+	found := o.Find(level, msgContains, matchers...)
+	if len(found) == 0 {
+		return true
+	}
+	t.Errorf("logtest: unexpected %s entry containing %q%s was logged: %s",
+		level, msgContains, describe(matchers), found[0])
+	return false
+}
+
+// String lists the entries recorded so far, one per line.
+func (o *Observer) String() string {
+	var b strings.Builder
+	for _, e := range o.Entries() {
+		b.WriteString("  ")
+		b.WriteString(e.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// String formats e for test failures.
+func (e Entry) String() string {
+	return fmt.Sprintf("%s %q %v", e.Level, e.Message, e.Fields)
+}
+
+func (e Entry) matches(level logger.Level, msgContains string, matchers []FieldMatcher) bool {
+	if e.Level != level || !strings.Contains(e.Message, msgContains) {
+		return false
+	}
+	for _, m := range matchers {
+		value, ok := e.Fields[m.key]
+		if !m.match(value, ok) {
+			return false
+		}
+	}
+	return true
+}
+
+// FieldMatcher is a condition on a field of an entry, for AssertLogged,
+// AssertNotLogged and Find.
+type FieldMatcher struct {
+	key   string
+	desc  string
+	match func(value any, ok bool) bool
+}
+
+// Field matches entries with a field key equal to value once both are
+// converted to JSON, so that Field("count", 3) matches Int("count", 3).
+func Field(key string, value any) FieldMatcher {
+	want := normalize(value)
+	return FieldMatcher{
+		key:  key,
+		desc: fmt.Sprintf("%s=%v", key, value),
+		match: func(got any, ok bool) bool {
+			return ok && reflect.DeepEqual(got, want)
+		},
+	}
+}
+
+// HasField matches entries with a field key, whatever its value.
+func HasField(key string) FieldMatcher {
+	return FieldMatcher{
+		key:   key,
+		desc:  "has " + key,
+		match: func(_ any, ok bool) bool { return ok },
+	}
+}
+
+// NoField matches entries without a field key.
+func NoField(key string) FieldMatcher {
+	return FieldMatcher{
+		key:   key,
+		desc:  "no " + key,
+		match: func(_ any, ok bool) bool { return !ok },
+	}
+}
+
+// normalize returns value as decoded from its JSON encoding, or value
+// itself if it cannot be encoded.
+func normalize(value any) any {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return value
+	}
+	return v
+}
+
+// describe formats matchers for test failures.
+func describe(matchers []FieldMatcher) string {
+	if len(matchers) == 0 {
+		return ""
+	}
+	descs := make([]string, len(matchers))
+	for i, m := range matchers {
+		descs[i] = m.desc
+	}
+	return " with " + strings.Join(descs, ", ")
+}
@@ -0,0 +1,69 @@
+package logtest
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// fakeT records the errors reported by assertions.
+type fakeT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestNewCaptureLogger(t *testing.T) {
+	log, logs := NewCaptureLogger(logger.InfoLevel)
+
+	log.Debug("hidden")
+	log.With(logger.String("service", "billing")).Info("charged",
+		logger.Int("amount", 42),
+		logger.Dur("took", time.Second),
+		logger.Err(errors.New("declined")),
+	)
+
+	require.Equal(t, 1, logs.Len())
+	assert.Equal(t, []Entry{{
+		Level:   logger.InfoLevel,
+		Message: "charged",
+		Fields: map[string]any{
+			"service": "billing",
+			"amount":  float64(42),
+			"took":    "1s",
+			"error":   "declined",
+		},
+	}}, logs.Entries())
+
+	logs.Reset()
+	assert.Zero(t, logs.Len())
+}
+
+func TestObserver_AssertLogged(t *testing.T) {
+	log, logs := NewCaptureLogger(logger.DebugLevel)
+	log.Warn("retrying request", logger.Int("attempt", 2), logger.String("host", "db1"))
+
+	assert.True(t, logs.AssertLogged(t, logger.WarnLevel, "retrying", Field("attempt", 2), HasField("host"), NoField("error")))
+	assert.True(t, logs.AssertNotLogged(t, logger.ErrorLevel, "retrying"))
+	assert.Len(t, logs.Find(logger.WarnLevel, ""), 1)
+
+	ft := &fakeT{}
+	assert.False(t, logs.AssertLogged(ft, logger.WarnLevel, "retrying", Field("attempt", 3)))
+	assert.False(t, logs.AssertLogged(ft, logger.InfoLevel, "retrying"))
+	assert.False(t, logs.AssertNotLogged(ft, logger.WarnLevel, "retry", Field("host", "db1")))
+	require.Len(t, ft.errors, 3)
+	assert.Contains(t, ft.errors[0], `no WARN entry containing "retrying" with attempt=3 was logged`)
+	assert.Contains(t, ft.errors[0], `WARN "retrying request"`)
+	assert.Contains(t, ft.errors[2], `unexpected WARN entry containing "retry" with host=db1`)
+}
@@ -0,0 +1,143 @@
+// Package loki provides a logger sink that pushes entries to Grafana Loki.
+//
+// Entries are batched in memory and sent to the Loki push API as
+// snappy-compressed protobuf. Each batch is split into streams by level:
+// every stream carries the static labels of Config.Labels plus the entry
+// level under Config.LevelLabel. Failed pushes are retried with
+// exponential backoff, and entries that do not fit in the bounded queue
+// are dropped.
+//
+// Example usage:
+//
+//	sink, w, err := loki.NewSink(loki.Config{
+//		URL:    "http://loki:3100/loki/api/v1/push",
+//		Labels: map[string]string{"service": "api", "env": "prod"},
+//		Format: logger.JSONFormat,
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	defer w.Close()
+//
+//	log := logger.New(logger.Config{
+//		Level: logger.InfoLevel,
+//		Sinks: []logger.Sink{sink},
+//	})
+//
+// The Writer expects one entry per Write, so leave Config.BufferSize of the
+// logger unset; the Writer does its own batching.
+package loki
+
+import (
+	"encoding/binary"
+	"net/http"
+	"time"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// Defaults applied by NewWriter to unset Config fields.
+const (
+	DefaultLevelLabel = "level"
+	DefaultBatchSize  = 1024
+	DefaultBatchWait  = time.Second
+	DefaultTimeout    = 10 * time.Second
+	DefaultMaxRetries = 5
+	DefaultMinBackoff = 500 * time.Millisecond
+	DefaultMaxBackoff = 30 * time.Second
+)
+
+// Config configures a Writer.
+type Config struct {
+	// URL is the Loki push endpoint, such as
+	// "http://loki:3100/loki/api/v1/push".
+	URL string
+
+	// Labels are attached to every stream, e.g. service and env.
+	Labels map[string]string
+
+	// LevelLabel is the name of the label holding the entry level, in
+	// lower case. Defaults to DefaultLevelLabel; "-" leaves it out.
+	LevelLabel string
+
+	// Format is the format of the log lines.
+	Format logger.Format
+
+	// TenantID is sent as the X-Scope-OrgID header for multi-tenant Loki.
+	TenantID string
+
+	// Headers are sent with every push, e.g. for authentication.
+	Headers map[string]string
+
+	// BatchSize is the maximum number of entries per push. Defaults to
+	// DefaultBatchSize.
+	BatchSize int
+
+	// BatchWait is how long an entry may wait for its batch to fill up
+	// before it is pushed. Defaults to DefaultBatchWait.
+	BatchWait time.Duration
+
+	// QueueSize is the maximum number of entries waiting to be pushed.
+	// Writes beyond it fail with ErrQueueFull. Defaults to 4 * BatchSize.
+	QueueSize int
+
+	// Timeout bounds each push attempt. Defaults to DefaultTimeout.
+	Timeout time.Duration
+
+	// MaxRetries is the number of times a failed push is retried when the
+	// failure is transient (network errors, 429 and 5xx responses).
+	// Defaults to DefaultMaxRetries; a negative value disables retries.
+	MaxRetries int
+
+	// MinBackoff is the delay before the first retry, doubled for each
+	// further one up to MaxBackoff. They default to DefaultMinBackoff and
+	// DefaultMaxBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// HTTPClient sends the pushes. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	// ErrorHandler, when not nil, is called with the error of every batch
+	// that could not be pushed. The batch is dropped.
+	ErrorHandler func(err error)
+}
+
+// NewSink creates a Writer for config and returns a logger.Sink writing to
+// it, along with the Writer so that the caller can flush and close it.
+func NewSink(config Config) (logger.Sink, *Writer, error) {
+	w, err := NewWriter(config)
+	if err != nil {
+		return logger.Sink{}, nil, err
+	}
+	enc := NewEncoder(logger.NewEncoder(logger.Config{Format: config.Format}))
+	return logger.Sink{Output: w, Encoder: enc}, w, nil
+}
+
+// headerLen is the size of the header NewEncoder puts before each line:
+// the level and the timestamp in Unix nanoseconds.
+const headerLen = 1 + 8
+
+// NewEncoder returns a logger.Encoder producing entries for a Writer: the
+// line encoded by enc, preceded by the level and timestamp the Writer
+// needs for labels and ordering.
+func NewEncoder(enc logger.Encoder) logger.Encoder {
+	return encoder{line: enc}
+}
+
+// encoder implements logger.Encoder for Writer.
+type encoder struct {
+	line logger.Encoder
+}
+
+// EncodeEntry implements logger.Encoder.
+func (enc encoder) EncodeEntry(buf []byte, e *logger.Entry) []byte {
+	buf = append(buf, byte(e.Level))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(e.Time.UnixNano()))
+	return enc.line.EncodeEntry(buf, e)
+}
+
+// EncodeFields implements logger.Encoder.
+func (enc encoder) EncodeFields(buf []byte, fields []logger.Field) []byte {
+	return enc.line.EncodeFields(buf, fields)
+}
@@ -0,0 +1,313 @@
+package loki
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// stream is a decoded StreamAdapter.
+type stream struct {
+	labels string
+	lines  []string
+	stamps []time.Time
+}
+
+// fields returns the length-delimited and varint fields of a protobuf
+// message numbered num.
+func fields(t *testing.T, b []byte, num protowire.Number) (msgs [][]byte, vals []uint64) {
+	t.Helper()
+
+	for len(b) > 0 {
+		n, typ, l := protowire.ConsumeTag(b)
+		require.GreaterOrEqual(t, l, 0)
+		b = b[l:]
+
+		switch typ {
+		case protowire.BytesType:
+			v, l := protowire.ConsumeBytes(b)
+			require.GreaterOrEqual(t, l, 0)
+			b = b[l:]
+			if n == num {
+				msgs = append(msgs, v)
+			}
+		case protowire.VarintType:
+			v, l := protowire.ConsumeVarint(b)
+			require.GreaterOrEqual(t, l, 0)
+			b = b[l:]
+			if n == num {
+				vals = append(vals, v)
+			}
+		default:
+			t.Fatalf("unexpected wire type %d", typ)
+		}
+	}
+	return msgs, vals
+}
+
+// decodePush decodes a snappy-compressed PushRequest.
+func decodePush(t *testing.T, body []byte) []stream {
+	t.Helper()
+
+	req, err := snappy.Decode(nil, body)
+	require.NoError(t, err)
+
+	var out []stream
+	msgs, _ := fields(t, req, pushStreams)
+	for _, m := range msgs {
+		labels, _ := fields(t, m, streamLabels)
+		require.Len(t, labels, 1)
+		s := stream{labels: string(labels[0])}
+
+		entries, _ := fields(t, m, streamEntries)
+		for _, e := range entries {
+			lines, _ := fields(t, e, entryLine)
+			stamps, _ := fields(t, e, entryTimestamp)
+			_, secs := fields(t, stamps[0], timestampSecs)
+			_, nanos := fields(t, stamps[0], timestampNanos)
+			s.lines = append(s.lines, string(lines[0]))
+			s.stamps = append(s.stamps, time.Unix(int64(secs[0]), int64(nanos[0])))
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// server is a Loki push endpoint recording requests.
+type server struct {
+	mu       sync.Mutex
+	bodies   [][]byte
+	headers  []http.Header
+	statuses []int
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.bodies = append(s.bodies, body)
+	s.headers = append(s.headers, r.Header.Clone())
+	if len(s.statuses) > 0 {
+		w.WriteHeader(s.statuses[0])
+		s.statuses = s.statuses[1:]
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.bodies)
+}
+
+func TestWriter_Push(t *testing.T) {
+	srv := &server{}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	sink, w, err := NewSink(Config{
+		URL:       ts.URL,
+		Labels:    map[string]string{"service": "api", "env": "prod"},
+		TenantID:  "team-a",
+		Format:    logger.JSONFormat,
+		BatchWait: time.Hour,
+	})
+	require.NoError(t, err)
+	defer w.Close()
+
+	log := logger.New(logger.Config{Level: logger.DebugLevel, Sinks: []logger.Sink{sink}})
+	log.Info("first", logger.Int("n", 1))
+	log.Error("failed")
+	log.Info("second")
+	require.NoError(t, w.Flush())
+
+	require.Equal(t, 1, srv.requests())
+	assert.Equal(t, "application/x-protobuf", srv.headers[0].Get("Content-Type"))
+	assert.Equal(t, "team-a", srv.headers[0].Get("X-Scope-OrgID"))
+
+	streams := decodePush(t, srv.bodies[0])
+	require.Len(t, streams, 2)
+
+	assert.Equal(t, `{env="prod", level="info", service="api"}`, streams[0].labels)
+	require.Len(t, streams[0].lines, 2)
+	assert.Contains(t, streams[0].lines[0], `"message":"first","n":1}`)
+	assert.Contains(t, streams[0].lines[1], `"message":"second"`)
+	assert.False(t, streams[0].stamps[1].Before(streams[0].stamps[0]))
+	assert.WithinDuration(t, time.Now(), streams[0].stamps[0], time.Minute)
+
+	assert.Equal(t, `{env="prod", level="error", service="api"}`, streams[1].labels)
+	assert.Len(t, streams[1].lines, 1)
+}
+
+func TestWriter_OrdersEntries(t *testing.T) {
+	srv := &server{}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	w, err := NewWriter(Config{URL: ts.URL, LevelLabel: "-", Labels: map[string]string{"job": "test"}, BatchWait: time.Hour})
+	require.NoError(t, err)
+	defer w.Close()
+
+	enc := NewEncoder(logger.NewEncoder(logger.Config{Format: logger.TextFormat, OmitTime: true}))
+	now := time.Now()
+	for _, e := range []*logger.Entry{
+		{Time: now.Add(time.Second), Level: logger.InfoLevel, Message: "later"},
+		{Time: now, Level: logger.WarnLevel, Message: "earlier"},
+	} {
+		_, err := w.Write(append(enc.EncodeEntry(nil, e), '\n'))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Flush())
+
+	streams := decodePush(t, srv.bodies[0])
+	require.Len(t, streams, 1)
+	assert.Equal(t, `{job="test"}`, streams[0].labels)
+	assert.Equal(t, []string{"WARN earlier", "INFO later"}, streams[0].lines)
+	assert.Equal(t, now.UnixNano(), streams[0].stamps[0].UnixNano())
+}
+
+func TestWriter_BatchWait(t *testing.T) {
+	srv := &server{}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	sink, w, err := NewSink(Config{URL: ts.URL, BatchWait: 10 * time.Millisecond})
+	require.NoError(t, err)
+	defer w.Close()
+
+	logger.New(logger.Config{Sinks: []logger.Sink{sink}}).Info("on timer")
+
+	assert.Eventually(t, func() bool { return srv.requests() == 1 }, time.Second, 5*time.Millisecond)
+}
+
+func TestWriter_BatchSize(t *testing.T) {
+	srv := &server{}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	sink, w, err := NewSink(Config{URL: ts.URL, BatchSize: 2, BatchWait: time.Hour})
+	require.NoError(t, err)
+	defer w.Close()
+
+	log := logger.New(logger.Config{Sinks: []logger.Sink{sink}})
+	for i := 0; i < 5; i++ {
+		log.Info("entry")
+	}
+	require.NoError(t, w.Flush())
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	total := 0
+	for _, body := range srv.bodies {
+		for _, s := range decodePush(t, body) {
+			assert.LessOrEqual(t, len(s.lines), 2)
+			total += len(s.lines)
+		}
+	}
+	assert.Equal(t, 5, total)
+}
+
+func TestWriter_Retry(t *testing.T) {
+	srv := &server{statuses: []int{http.StatusInternalServerError, http.StatusTooManyRequests}}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	sink, w, err := NewSink(Config{URL: ts.URL, BatchWait: time.Hour, MinBackoff: time.Millisecond})
+	require.NoError(t, err)
+	defer w.Close()
+
+	logger.New(logger.Config{Sinks: []logger.Sink{sink}}).Info("retried")
+	require.NoError(t, w.Flush())
+
+	assert.Equal(t, 3, srv.requests())
+}
+
+func TestWriter_PermanentFailure(t *testing.T) {
+	srv := &server{statuses: []int{http.StatusBadRequest}}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	var handled atomic.Int32
+	sink, w, err := NewSink(Config{
+		URL:          ts.URL,
+		BatchWait:    time.Hour,
+		MinBackoff:   time.Millisecond,
+		ErrorHandler: func(error) { handled.Add(1) },
+	})
+	require.NoError(t, err)
+	defer w.Close()
+
+	logger.New(logger.Config{Sinks: []logger.Sink{sink}}).Info("rejected")
+	err = w.Flush()
+
+	var statusErr *statusError
+	require.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, http.StatusBadRequest, statusErr.code)
+	assert.Equal(t, 1, srv.requests())
+	assert.Equal(t, int32(1), handled.Load())
+}
+
+func TestWriter_QueueFull(t *testing.T) {
+	w, err := NewWriter(Config{URL: "http://127.0.0.1:0", QueueSize: 1, BatchWait: time.Hour, MaxRetries: -1})
+	require.NoError(t, err)
+	defer w.Close()
+
+	line := make([]byte, headerLen+2)
+	_, err = w.Write(line)
+	require.NoError(t, err)
+	_, err = w.Write(line)
+	assert.ErrorIs(t, err, ErrQueueFull)
+}
+
+func TestWriter_Malformed(t *testing.T) {
+	w, err := NewWriter(Config{URL: "http://127.0.0.1:0", BatchWait: time.Hour})
+	require.NoError(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("short\n"))
+	assert.ErrorIs(t, err, ErrMalformed)
+}
+
+func TestWriter_Close(t *testing.T) {
+	srv := &server{}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	sink, w, err := NewSink(Config{URL: ts.URL, BatchWait: time.Hour})
+	require.NoError(t, err)
+
+	logger.New(logger.Config{Sinks: []logger.Sink{sink}}).Info("pending")
+
+	require.NoError(t, w.Close())
+	require.NoError(t, w.Close())
+	assert.Equal(t, 1, srv.requests())
+
+	_, err = w.Write(make([]byte, headerLen))
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestNewWriter_Invalid(t *testing.T) {
+	_, err := NewWriter(Config{})
+	assert.Error(t, err)
+
+	_, err = NewWriter(Config{URL: "http://loki", Labels: map[string]string{"bad-name": "x"}})
+	assert.Error(t, err)
+
+	_, err = NewWriter(Config{URL: "http://loki", LevelLabel: "1level"})
+	assert.Error(t, err)
+}
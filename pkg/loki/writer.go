@@ -0,0 +1,377 @@
+package loki
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// ErrClosed is returned by Write after Close has been called.
+var ErrClosed = errors.New("loki: writer is closed")
+
+// ErrQueueFull is returned by Write when Config.QueueSize entries are
+// already waiting to be pushed. The entry is dropped.
+var ErrQueueFull = errors.New("loki: push queue is full")
+
+// ErrMalformed is returned by Write for data not produced by an encoder
+// from NewEncoder.
+var ErrMalformed = errors.New("loki: entry without level and timestamp header")
+
+// labelName matches valid Loki label names.
+var labelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Field numbers of the Loki PushRequest, StreamAdapter, EntryAdapter and
+// Timestamp messages.
+const (
+	pushStreams    protowire.Number = 1
+	streamLabels   protowire.Number = 1
+	streamEntries  protowire.Number = 2
+	entryTimestamp protowire.Number = 1
+	entryLine      protowire.Number = 2
+	timestampSecs  protowire.Number = 1
+	timestampNanos protowire.Number = 2
+)
+
+// entry is a queued log line.
+type entry struct {
+	level logger.Level
+	ts    int64
+	line  string
+}
+
+// Writer is an io.Writer that queues entries produced by an encoder from
+// NewEncoder, one per Write, and pushes them to Loki in batches from a
+// background goroutine.
+type Writer struct {
+	config Config
+
+	mu      sync.Mutex
+	queue   []entry
+	closed  bool
+	full    chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+
+	// pushMu serializes pushes from the background goroutine and Flush.
+	pushMu sync.Mutex
+}
+
+// NewWriter validates config and returns a Writer pushing to config.URL,
+// with its background goroutine started.
+func NewWriter(config Config) (*Writer, error) {
+	if config.URL == "" {
+		return nil, errors.New("loki: URL is required")
+	}
+	if config.LevelLabel == "" {
+		config.LevelLabel = DefaultLevelLabel
+	}
+	for name := range config.Labels {
+		if !labelName.MatchString(name) {
+			return nil, fmt.Errorf("loki: invalid label name %q", name)
+		}
+	}
+	if config.LevelLabel != "-" && !labelName.MatchString(config.LevelLabel) {
+		return nil, fmt.Errorf("loki: invalid label name %q", config.LevelLabel)
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultBatchSize
+	}
+	if config.BatchWait <= 0 {
+		config.BatchWait = DefaultBatchWait
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = 4 * config.BatchSize
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = DefaultMaxRetries
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = DefaultMinBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = DefaultMaxBackoff
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+
+	w := &Writer{
+		config:  config,
+		full:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// Write queues p, without its trailing newline, as one entry.
+func (w *Writer) Write(p []byte) (int, error) {
+	if len(p) < headerLen {
+		return 0, ErrMalformed
+	}
+	e := entry{
+		level: logger.Level(int8(p[0])),
+		ts:    int64(binary.BigEndian.Uint64(p[1:headerLen])),
+		line:  string(bytes.TrimSuffix(p[headerLen:], []byte{'\n'})),
+	}
+
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return 0, ErrClosed
+	}
+	if len(w.queue) >= w.config.QueueSize {
+		w.mu.Unlock()
+		return 0, ErrQueueFull
+	}
+	w.queue = append(w.queue, e)
+	full := len(w.queue) >= w.config.BatchSize
+	w.mu.Unlock()
+
+	if full {
+		select {
+		case w.full <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// Flush pushes all queued entries and returns the first push error.
+func (w *Writer) Flush() error {
+	return w.push()
+}
+
+// Close stops the background goroutine and pushes the queued entries.
+// Writes after Close fail with ErrClosed.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+
+	close(w.done)
+	<-w.stopped
+	return w.push()
+}
+
+// run pushes batches when one fills up or BatchWait elapses.
+func (w *Writer) run() {
+	defer close(w.stopped)
+
+	ticker := time.NewTicker(w.config.BatchWait)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-w.full:
+		case <-ticker.C:
+		}
+		_ = w.push()
+	}
+}
+
+// push sends the queued entries in batches of at most BatchSize. Batches
+// that cannot be sent are reported to Config.ErrorHandler and dropped.
+func (w *Writer) push() error {
+	w.pushMu.Lock()
+	defer w.pushMu.Unlock()
+
+	var first error
+	for {
+		w.mu.Lock()
+		n := min(len(w.queue), w.config.BatchSize)
+		batch := w.queue[:n:n]
+		w.queue = w.queue[n:]
+		w.mu.Unlock()
+
+		if n == 0 {
+			return first
+		}
+
+		if err := w.sendWithRetry(snappy.Encode(nil, w.request(batch))); err != nil {
+			if w.config.ErrorHandler != nil {
+				w.config.ErrorHandler(err)
+			}
+			if first == nil {
+				first = err
+			}
+		}
+	}
+}
+
+// labels returns the label set of the stream holding entries at level,
+// in Loki's {name="value", ...} syntax with names sorted.
+func (w *Writer) labels(level logger.Level) string {
+	names := make([]string, 0, len(w.config.Labels)+1)
+	for name := range w.config.Labels {
+		if name != w.config.LevelLabel {
+			names = append(names, name)
+		}
+	}
+	if w.config.LevelLabel != "-" {
+		names = append(names, w.config.LevelLabel)
+	}
+	slices.Sort(names)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		value := w.config.Labels[name]
+		if name == w.config.LevelLabel {
+			value = strings.ToLower(level.String())
+		}
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(value))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// request encodes a PushRequest with one stream per label set in batch,
+// each holding its entries in timestamp order.
+func (w *Writer) request(batch []entry) []byte {
+	var labelSets []string
+	byLevel := make(map[logger.Level]string)
+	streams := make(map[string][]entry)
+	for _, e := range batch {
+		labels, ok := byLevel[e.level]
+		if !ok {
+			labels = w.labels(e.level)
+			byLevel[e.level] = labels
+		}
+		if _, ok := streams[labels]; !ok {
+			labelSets = append(labelSets, labels)
+		}
+		streams[labels] = append(streams[labels], e)
+	}
+
+	var buf []byte
+	for _, labels := range labelSets {
+		entries := streams[labels]
+		slices.SortStableFunc(entries, func(a, b entry) int {
+			return cmp.Compare(a.ts, b.ts)
+		})
+
+		var stream []byte
+		stream = protowire.AppendTag(stream, streamLabels, protowire.BytesType)
+		stream = protowire.AppendString(stream, labels)
+		for _, e := range entries {
+			var ts []byte
+			ts = protowire.AppendTag(ts, timestampSecs, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(e.ts/int64(time.Second)))
+			ts = protowire.AppendTag(ts, timestampNanos, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(e.ts%int64(time.Second)))
+
+			var ent []byte
+			ent = protowire.AppendTag(ent, entryTimestamp, protowire.BytesType)
+			ent = protowire.AppendBytes(ent, ts)
+			ent = protowire.AppendTag(ent, entryLine, protowire.BytesType)
+			ent = protowire.AppendString(ent, e.line)
+
+			stream = protowire.AppendTag(stream, streamEntries, protowire.BytesType)
+			stream = protowire.AppendBytes(stream, ent)
+		}
+
+		buf = protowire.AppendTag(buf, pushStreams, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, stream)
+	}
+	return buf
+}
+
+// sendWithRetry posts body, retrying transient failures up to MaxRetries
+// times with exponential backoff.
+func (w *Writer) sendWithRetry(body []byte) error {
+	backoff := w.config.MinBackoff
+	for attempt := 0; ; attempt++ {
+		err := w.send(body)
+		if err == nil {
+			return nil
+		}
+		if attempt >= w.config.MaxRetries || !retryable(err) {
+			return err
+		}
+
+		time.Sleep(backoff)
+		backoff = min(2*backoff, w.config.MaxBackoff)
+	}
+}
+
+// statusError is returned by send for non-2xx responses.
+type statusError struct {
+	code int
+	body string
+}
+
+func (err *statusError) Error() string {
+	return fmt.Sprintf("loki: push failed with HTTP status %d: %s", err.code, err.body)
+}
+
+// retryable reports whether err is a transient push failure: a network
+// error, a rate limit or a server error.
+func retryable(err error) bool {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.code == http.StatusTooManyRequests || statusErr.code >= 500
+	}
+	return true
+}
+
+// send posts a snappy-compressed PushRequest to the push endpoint.
+func (w *Writer) send(body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), w.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("loki: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	if w.config.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", w.config.TenantID)
+	}
+	for k, v := range w.config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := w.config.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("loki: %w", err)
+	}
+	defer resp.Body.Close()
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &statusError{code: resp.StatusCode, body: string(msg)}
+	}
+	return nil
+}
@@ -0,0 +1,328 @@
+// Package netwriter provides an io.Writer that sends log entries to a
+// remote collector over TCP, UDP or a unix socket, for use as the Output of
+// a logger or of one of its sinks.
+//
+// Writes are queued and sent by a background goroutine, which reconnects
+// with exponential backoff whenever the connection fails. When the queue is
+// full, Write either blocks or drops the entry, depending on the Overflow
+// policy.
+//
+// Example usage:
+//
+//	w, err := netwriter.New(netwriter.Config{URL: "tcp://logs.example.com:5170"})
+//	if err != nil {
+//		return err
+//	}
+//	defer w.Close()
+//
+//	log := logger.New(logger.Config{Format: logger.JSONFormat, Output: w})
+//
+// Stream transports (tcp, unix) send entries back to back, each ending with
+// its newline; datagram transports (udp, unixgram) send one entry per
+// packet.
+package netwriter
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Overflow is the policy applied by Write when the queue is full.
+type Overflow uint8
+
+const (
+	// Block makes Write wait until the queue has room.
+	Block Overflow = iota
+
+	// Drop makes Write discard the entry and return ErrQueueFull.
+	Drop
+)
+
+// Defaults applied by New to unset Config fields.
+const (
+	DefaultQueueSize    = 1024
+	DefaultDialTimeout  = 5 * time.Second
+	DefaultWriteTimeout = 5 * time.Second
+	DefaultMinBackoff   = 100 * time.Millisecond
+	DefaultMaxBackoff   = 30 * time.Second
+)
+
+// ErrClosed is returned by Write after Close has been called.
+var ErrClosed = errors.New("netwriter: writer is closed")
+
+// ErrQueueFull is returned by Write under the Drop policy when the queue is
+// full. The entry is discarded.
+var ErrQueueFull = errors.New("netwriter: queue is full")
+
+// Config configures a Writer.
+type Config struct {
+	// URL is the address of the collector, such as "tcp://host:5170",
+	// "udp://host:514", "unix:///run/collector.sock" or
+	// "unixgram:///dev/log". The tcp4, tcp6, udp4 and udp6 schemes are
+	// accepted too.
+	URL string
+
+	// QueueSize is the number of entries waiting to be sent before the
+	// Overflow policy applies. Defaults to DefaultQueueSize.
+	QueueSize int
+
+	// Overflow is the policy applied when the queue is full. Defaults to
+	// Block.
+	Overflow Overflow
+
+	// DialTimeout bounds each connection attempt. Defaults to
+	// DefaultDialTimeout.
+	DialTimeout time.Duration
+
+	// WriteTimeout is the write deadline of each entry. Defaults to
+	// DefaultWriteTimeout.
+	WriteTimeout time.Duration
+
+	// MinBackoff is the delay before the first reconnection attempt,
+	// doubled for each further one up to MaxBackoff. They default to
+	// DefaultMinBackoff and DefaultMaxBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// ErrorHandler, when not nil, is called with connection and write
+	// errors, which are otherwise only retried.
+	ErrorHandler func(err error)
+}
+
+// Writer is an io.Writer sending entries to a remote collector from a
+// background goroutine. It is safe for concurrent use.
+type Writer struct {
+	config  Config
+	network string
+	address string
+
+	queue chan []byte
+
+	// done is closed by Close to release blocked writes and retries, and
+	// drainNow once no write can enqueue anymore.
+	done     chan struct{}
+	drainNow chan struct{}
+	stopped  chan struct{}
+	once     sync.Once
+
+	mu     sync.RWMutex
+	closed bool
+
+	dropped atomic.Uint64
+
+	// conn is only used by the background goroutine.
+	conn net.Conn
+}
+
+// New parses config.URL and returns a Writer for it, with its background
+// goroutine started. The connection is established by the first Write.
+func New(config Config) (*Writer, error) {
+	network, address, err := parseURL(config.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultQueueSize
+	}
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = DefaultDialTimeout
+	}
+	if config.WriteTimeout <= 0 {
+		config.WriteTimeout = DefaultWriteTimeout
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = DefaultMinBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = DefaultMaxBackoff
+	}
+
+	w := &Writer{
+		config:   config,
+		network:  network,
+		address:  address,
+		queue:    make(chan []byte, config.QueueSize),
+		done:     make(chan struct{}),
+		drainNow: make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// parseURL splits a collector URL into the network and address of
+// net.Dial.
+func parseURL(raw string) (network, address string, err error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", "", fmt.Errorf("netwriter: %w", err)
+	}
+
+	switch u.Scheme {
+	case "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6":
+		if u.Host == "" {
+			return "", "", fmt.Errorf("netwriter: missing host in %q", raw)
+		}
+		return u.Scheme, u.Host, nil
+	case "unix", "unixgram":
+		path := u.Path
+		if path == "" {
+			path = u.Opaque
+		}
+		if path == "" {
+			return "", "", fmt.Errorf("netwriter: missing socket path in %q", raw)
+		}
+		return u.Scheme, path, nil
+	default:
+		return "", "", fmt.Errorf("netwriter: unsupported scheme in %q", raw)
+	}
+}
+
+// Write queues a copy of p to be sent. Under the Block policy it waits for
+// room in the queue; under Drop it fails with ErrQueueFull when the queue
+// is full.
+func (w *Writer) Write(p []byte) (int, error) {
+	msg := append([]byte(nil), p...)
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		return 0, ErrClosed
+	}
+
+	if w.config.Overflow == Drop {
+		select {
+		case w.queue <- msg:
+			return len(p), nil
+		default:
+			w.dropped.Add(1)
+			return 0, ErrQueueFull
+		}
+	}
+
+	select {
+	case w.queue <- msg:
+		return len(p), nil
+	case <-w.done:
+		return 0, ErrClosed
+	}
+}
+
+// Dropped returns the number of entries discarded because the queue was
+// full or because they could not be sent before Close.
+func (w *Writer) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// Close stops accepting entries and makes one attempt to send those still
+// queued, then closes the connection. Entries that cannot be sent are
+// counted as dropped.
+func (w *Writer) Close() error {
+	w.once.Do(func() {
+		close(w.done)
+
+		w.mu.Lock()
+		w.closed = true
+		w.mu.Unlock()
+
+		close(w.drainNow)
+	})
+	<-w.stopped
+	return nil
+}
+
+// run sends queued entries until Close is called, then drains the queue.
+func (w *Writer) run() {
+	defer close(w.stopped)
+
+	for {
+		select {
+		case msg := <-w.queue:
+			w.send(msg)
+		case <-w.drainNow:
+			w.drain()
+			return
+		}
+	}
+}
+
+// send writes msg, reconnecting with backoff until it succeeds or the
+// Writer is closed. An entry interrupted by Close is counted as dropped.
+func (w *Writer) send(msg []byte) {
+	backoff := w.config.MinBackoff
+	for {
+		err := w.write(msg)
+		if err == nil {
+			return
+		}
+		w.report(err)
+
+		select {
+		case <-time.After(backoff):
+		case <-w.done:
+			if w.write(msg) != nil {
+				w.dropped.Add(1)
+			}
+			return
+		}
+		backoff = min(2*backoff, w.config.MaxBackoff)
+	}
+}
+
+// drain makes one attempt to send each entry left in the queue, then
+// closes the connection.
+func (w *Writer) drain() {
+	failed := false
+	for {
+		select {
+		case msg := <-w.queue:
+			if failed || w.write(msg) != nil {
+				failed = true
+				w.dropped.Add(1)
+			}
+		default:
+			if w.conn != nil {
+				_ = w.conn.Close()
+				w.conn = nil
+			}
+			return
+		}
+	}
+}
+
+// write sends msg on the current connection, dialing first if needed. On
+// failure the connection is closed so that the next attempt redials.
+func (w *Writer) write(msg []byte) error {
+	if w.conn == nil {
+		conn, err := net.DialTimeout(w.network, w.address, w.config.DialTimeout)
+		if err != nil {
+			return fmt.Errorf("netwriter: %w", err)
+		}
+		w.conn = conn
+	}
+
+	err := w.conn.SetWriteDeadline(time.Now().Add(w.config.WriteTimeout))
+	if err == nil {
+		_, err = w.conn.Write(msg)
+	}
+	if err != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+		return fmt.Errorf("netwriter: %w", err)
+	}
+	return nil
+}
+
+// report passes err to Config.ErrorHandler, if set.
+func (w *Writer) report(err error) {
+	if w.config.ErrorHandler != nil {
+		w.config.ErrorHandler(err)
+	}
+}
@@ -0,0 +1,234 @@
+package netwriter
+
+import (
+	"bufio"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// lineServer accepts stream connections and records the lines received.
+type lineServer struct {
+	lis net.Listener
+
+	mu    sync.Mutex
+	lines []string
+}
+
+func newLineServer(t *testing.T, network, address string) *lineServer {
+	lis, err := net.Listen(network, address)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = lis.Close() })
+
+	s := &lineServer{lis: lis}
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(conn)
+		}
+	}()
+	return s
+}
+
+func (s *lineServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		s.mu.Lock()
+		s.lines = append(s.lines, scanner.Text())
+		s.mu.Unlock()
+	}
+}
+
+func (s *lineServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.lines...)
+}
+
+func TestWriter_TCP(t *testing.T) {
+	srv := newLineServer(t, "tcp", "127.0.0.1:0")
+
+	w, err := New(Config{URL: "tcp://" + srv.lis.Addr().String()})
+	require.NoError(t, err)
+
+	log := logger.New(logger.Config{Format: logger.TextFormat, Output: w, OmitTime: true})
+	log.Info("first")
+	log.Warn("second", logger.Int("n", 2))
+	require.NoError(t, w.Close())
+
+	require.Eventually(t, func() bool { return len(srv.received()) == 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"INFO first", "WARN second n=2"}, srv.received())
+}
+
+func TestWriter_Unix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "collector.sock")
+	srv := newLineServer(t, "unix", path)
+
+	w, err := New(Config{URL: "unix://" + path})
+	require.NoError(t, err)
+
+	_, err = w.Write([]byte("over unix\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	require.Eventually(t, func() bool { return len(srv.received()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "over unix", srv.received()[0])
+}
+
+func TestWriter_UDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	w, err := New(Config{URL: "udp://" + conn.LocalAddr().String()})
+	require.NoError(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("first\n"))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("second\n"))
+	require.NoError(t, err)
+
+	buf := make([]byte, 64)
+	for _, want := range []string{"first\n", "second\n"} {
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+		n, _, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+		assert.Equal(t, want, string(buf[:n]))
+	}
+}
+
+func TestWriter_Reconnect(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	require.NoError(t, lis.Close())
+
+	var errs int
+	var mu sync.Mutex
+	w, err := New(Config{
+		URL:        "tcp://" + addr,
+		MinBackoff: 5 * time.Millisecond,
+		MaxBackoff: 20 * time.Millisecond,
+		ErrorHandler: func(error) {
+			mu.Lock()
+			errs++
+			mu.Unlock()
+		},
+	})
+	require.NoError(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("queued while down\n"))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return errs > 0
+	}, time.Second, 5*time.Millisecond)
+
+	srv := newLineServer(t, "tcp", addr)
+	require.Eventually(t, func() bool { return len(srv.received()) == 1 }, 2*time.Second, 5*time.Millisecond)
+	assert.Equal(t, "queued while down", srv.received()[0])
+}
+
+// unreachable returns the URL of a TCP port nothing listens on.
+func unreachable(t *testing.T) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	require.NoError(t, lis.Close())
+	return "tcp://" + addr
+}
+
+func TestWriter_Drop(t *testing.T) {
+	w, err := New(Config{URL: unreachable(t), QueueSize: 1, Overflow: Drop, MinBackoff: time.Hour})
+	require.NoError(t, err)
+
+	var full int
+	for i := 0; i < 3; i++ {
+		if _, err := w.Write([]byte("entry\n")); err != nil {
+			assert.ErrorIs(t, err, ErrQueueFull)
+			full++
+		}
+	}
+	assert.Positive(t, full)
+
+	require.NoError(t, w.Close())
+	assert.Equal(t, uint64(3), w.Dropped())
+}
+
+func TestWriter_BlockReleasedByClose(t *testing.T) {
+	w, err := New(Config{URL: unreachable(t), QueueSize: 1, MinBackoff: time.Hour})
+	require.NoError(t, err)
+
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			_, err := w.Write([]byte("entry\n"))
+			errs <- err
+		}()
+	}
+
+	// At most two writes fit: one being sent and one queued.
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, w.Close())
+
+	var closed int
+	for i := 0; i < 3; i++ {
+		if err := <-errs; err != nil {
+			assert.ErrorIs(t, err, ErrClosed)
+			closed++
+		}
+	}
+	assert.Positive(t, closed)
+	assert.Equal(t, uint64(3-closed), w.Dropped())
+
+	_, err = w.Write([]byte("late\n"))
+	assert.ErrorIs(t, err, ErrClosed)
+	require.NoError(t, w.Close())
+}
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		network string
+		address string
+		wantErr bool
+	}{
+		{"tcp://localhost:5170", "tcp", "localhost:5170", false},
+		{"udp6://[::1]:514", "udp6", "[::1]:514", false},
+		{"unix:///run/collector.sock", "unix", "/run/collector.sock", false},
+		{"unixgram:///dev/log", "unixgram", "/dev/log", false},
+		{"http://localhost:80", "", "", true},
+		{"tcp://", "", "", true},
+		{"unix://", "", "", true},
+		{"://bad", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			network, address, err := parseURL(tt.url)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.network, network)
+			assert.Equal(t, tt.address, address)
+		})
+	}
+}
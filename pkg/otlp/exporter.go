@@ -0,0 +1,385 @@
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// exportMethod is the full name of the OTLP/gRPC logs export method.
+const exportMethod = "/opentelemetry.proto.collector.logs.v1.LogsService/Export"
+
+// maxRetryBackoff caps the delay between export retries.
+const maxRetryBackoff = 30 * time.Second
+
+// ErrClosed is returned by Write after Close has been called.
+var ErrClosed = errors.New("otlp: exporter is closed")
+
+// ErrQueueFull is returned by Write when Config.QueueSize records are
+// already waiting to be exported. The entry is dropped.
+var ErrQueueFull = errors.New("otlp: export queue is full")
+
+// Exporter is an io.Writer that collects LogRecord messages, one per
+// Write, and exports them in batches from a background goroutine.
+type Exporter struct {
+	config Config
+	send   func(ctx context.Context, body []byte) error
+	conn   *grpc.ClientConn
+
+	// resource and scope hold the encoded Resource and
+	// InstrumentationScope fields that start every export request.
+	resource []byte
+	scope    []byte
+
+	mu      sync.Mutex
+	queue   [][]byte
+	closed  bool
+	full    chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+
+	// exportMu serializes exports from the background goroutine and Flush.
+	exportMu sync.Mutex
+}
+
+// NewExporter returns an Exporter sending to the collector described by
+// config and starts its background goroutine. For GRPC, the connection is
+// established lazily.
+func NewExporter(config Config) (*Exporter, error) {
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultBatchSize
+	}
+	if config.BatchTimeout <= 0 {
+		config.BatchTimeout = DefaultBatchTimeout
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = 4 * config.BatchSize
+	}
+	if config.ExportTimeout <= 0 {
+		config.ExportTimeout = DefaultExportTimeout
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = DefaultMaxRetries
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = DefaultRetryBackoff
+	}
+	if config.ScopeName == "" {
+		config.ScopeName = DefaultScopeName
+	}
+
+	e := &Exporter{
+		config:  config,
+		full:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	switch config.Protocol {
+	case HTTP:
+		if e.config.Endpoint == "" {
+			e.config.Endpoint = DefaultHTTPEndpoint
+		}
+		if e.config.HTTPClient == nil {
+			e.config.HTTPClient = http.DefaultClient
+		}
+		e.send = e.sendHTTP
+	case GRPC:
+		if e.config.Endpoint == "" {
+			e.config.Endpoint = DefaultGRPCEndpoint
+		}
+		creds := credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+		if config.Insecure {
+			creds = insecure.NewCredentials()
+		}
+		conn, err := grpc.NewClient(e.config.Endpoint, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			return nil, fmt.Errorf("otlp: %w", err)
+		}
+		e.conn = conn
+		e.send = e.sendGRPC
+	default:
+		return nil, fmt.Errorf("otlp: unknown protocol %d", config.Protocol)
+	}
+
+	e.resource = appendMessage(nil, resourceLogsResource, resourceAttributesOf(config))
+	e.scope = appendMessage(nil, scopeLogsScope, appendString(nil, scopeName, config.ScopeName))
+
+	go e.run()
+	return e, nil
+}
+
+// resourceAttributesOf encodes the attributes of the Resource message, in
+// key order.
+func resourceAttributesOf(config Config) []byte {
+	attrs := make(map[string]string, len(config.Resource)+1)
+	for k, v := range config.Resource {
+		attrs[k] = v
+	}
+	if config.ServiceName != "" {
+		attrs["service.name"] = config.ServiceName
+	}
+
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	var buf []byte
+	for _, k := range keys {
+		buf = appendKeyValue(buf, resourceAttributes, k, attrs[k])
+	}
+	return buf
+}
+
+// Write queues p, without its trailing newline, as one LogRecord message.
+func (e *Exporter) Write(p []byte) (int, error) {
+	record := bytes.Clone(bytes.TrimSuffix(p, []byte{'\n'}))
+
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return 0, ErrClosed
+	}
+	if len(e.queue) >= e.config.QueueSize {
+		e.mu.Unlock()
+		return 0, ErrQueueFull
+	}
+	e.queue = append(e.queue, record)
+	full := len(e.queue) >= e.config.BatchSize
+	e.mu.Unlock()
+
+	if full {
+		select {
+		case e.full <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// Flush exports all queued records and returns the first export error.
+func (e *Exporter) Flush() error {
+	return e.export()
+}
+
+// Close stops the background goroutine, exports the queued records and
+// closes the gRPC connection. Writes after Close fail with ErrClosed.
+func (e *Exporter) Close() error {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return nil
+	}
+	e.closed = true
+	e.mu.Unlock()
+
+	close(e.done)
+	<-e.stopped
+
+	err := e.export()
+	if e.conn != nil {
+		if cerr := e.conn.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// run exports batches when one fills up or BatchTimeout elapses.
+func (e *Exporter) run() {
+	defer close(e.stopped)
+
+	ticker := time.NewTicker(e.config.BatchTimeout)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-e.full:
+		case <-ticker.C:
+		}
+		_ = e.export()
+	}
+}
+
+// export sends the queued records in batches of at most BatchSize. Batches
+// that cannot be sent are reported to Config.ErrorHandler and dropped.
+func (e *Exporter) export() error {
+	e.exportMu.Lock()
+	defer e.exportMu.Unlock()
+
+	var first error
+	for {
+		e.mu.Lock()
+		n := min(len(e.queue), e.config.BatchSize)
+		batch := e.queue[:n:n]
+		e.queue = e.queue[n:]
+		e.mu.Unlock()
+
+		if n == 0 {
+			return first
+		}
+
+		if err := e.sendWithRetry(e.request(batch)); err != nil {
+			if e.config.ErrorHandler != nil {
+				e.config.ErrorHandler(err)
+			}
+			if first == nil {
+				first = err
+			}
+		}
+	}
+}
+
+// request encodes an ExportLogsServiceRequest holding records.
+func (e *Exporter) request(records [][]byte) []byte {
+	scopeSize := len(e.scope)
+	for _, r := range records {
+		scopeSize += protowire.SizeTag(scopeLogsRecords) + protowire.SizeBytes(len(r))
+	}
+	resourceSize := len(e.resource) + protowire.SizeTag(resourceLogsScope) + protowire.SizeBytes(scopeSize)
+
+	buf := make([]byte, 0, protowire.SizeTag(requestResourceLogs)+protowire.SizeBytes(resourceSize))
+	buf = protowire.AppendTag(buf, requestResourceLogs, protowire.BytesType)
+	buf = protowire.AppendVarint(buf, uint64(resourceSize))
+	buf = append(buf, e.resource...)
+	buf = protowire.AppendTag(buf, resourceLogsScope, protowire.BytesType)
+	buf = protowire.AppendVarint(buf, uint64(scopeSize))
+	buf = append(buf, e.scope...)
+	for _, r := range records {
+		buf = appendMessage(buf, scopeLogsRecords, r)
+	}
+	return buf
+}
+
+// sendWithRetry sends body, retrying transient failures up to MaxRetries
+// times with exponential backoff.
+func (e *Exporter) sendWithRetry(body []byte) error {
+	backoff := e.config.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), e.config.ExportTimeout)
+		err := e.send(ctx, body)
+		cancel()
+
+		if err == nil {
+			return nil
+		}
+		if attempt >= e.config.MaxRetries || !retryable(err) {
+			return err
+		}
+
+		time.Sleep(backoff)
+		backoff = min(2*backoff, maxRetryBackoff)
+	}
+}
+
+// httpStatusError is returned by sendHTTP for non-2xx responses.
+type httpStatusError struct {
+	code int
+	body string
+}
+
+func (err *httpStatusError) Error() string {
+	return fmt.Sprintf("otlp: export failed with HTTP status %d: %s", err.code, err.body)
+}
+
+// retryable reports whether err is a transient export failure, as
+// defined by the OTLP specification.
+func retryable(err error) bool {
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.code {
+		case http.StatusTooManyRequests, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		default:
+			return false
+		}
+	}
+
+	if s, ok := status.FromError(err); ok {
+		switch s.Code() {
+		case codes.Canceled, codes.DeadlineExceeded, codes.ResourceExhausted,
+			codes.Aborted, codes.OutOfRange, codes.Unavailable, codes.DataLoss:
+			return true
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// sendHTTP posts body to the OTLP/HTTP endpoint.
+func (e *Exporter) sendHTTP(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("otlp: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	for k, v := range e.config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.config.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("otlp: %w", err)
+	}
+	defer resp.Body.Close()
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &httpStatusError{code: resp.StatusCode, body: string(msg)}
+	}
+	return nil
+}
+
+// sendGRPC calls the LogsService Export method with body.
+func (e *Exporter) sendGRPC(ctx context.Context, body []byte) error {
+	if len(e.config.Headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(e.config.Headers))
+	}
+	var resp []byte
+	return e.conn.Invoke(ctx, exportMethod, &body, &resp, grpc.ForceCodec(rawCodec{}))
+}
+
+// rawCodec passes pre-encoded protobuf messages through unchanged.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return nil, fmt.Errorf("otlp: unexpected message type %T", v)
+	}
+	return *b, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("otlp: unexpected message type %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
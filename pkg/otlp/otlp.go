@@ -0,0 +1,251 @@
+// Package otlp provides a logger sink that batches entries and exports
+// them to an OpenTelemetry collector over OTLP/HTTP or OTLP/gRPC.
+//
+// Entries are encoded as OpenTelemetry LogRecord protobuf messages: levels
+// map to severity numbers by SeverityNumber, fields become attributes, and
+// "trace_id" and "span_id" fields holding hex IDs fill the record's trace
+// context. The Exporter groups records into batches and retries failed
+// exports with exponential backoff.
+//
+// Example usage:
+//
+//	sink, exp, err := otlp.NewSink(otlp.Config{
+//		Protocol:    otlp.GRPC,
+//		Endpoint:    "otel-collector:4317",
+//		Insecure:    true,
+//		ServiceName: "api",
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	defer exp.Close()
+//
+//	log := logger.New(logger.Config{
+//		Level: logger.InfoLevel,
+//		Sinks: []logger.Sink{sink},
+//	})
+//
+// The Exporter expects one entry per Write, so leave Config.BufferSize of
+// the logger unset; the Exporter does its own batching.
+//
+// NewStreamSink writes the same records to an io.Writer instead, each
+// preceded by its size, for files and sockets read by collectors.
+package otlp
+
+import (
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// Protocol is the OTLP transport used by an Exporter.
+type Protocol uint8
+
+const (
+	// HTTP sends protobuf-encoded requests to an OTLP/HTTP endpoint.
+	HTTP Protocol = iota
+
+	// GRPC calls the LogsService of an OTLP/gRPC endpoint.
+	GRPC
+)
+
+// Defaults applied by NewExporter to unset Config fields.
+const (
+	DefaultHTTPEndpoint  = "http://localhost:4318/v1/logs"
+	DefaultGRPCEndpoint  = "localhost:4317"
+	DefaultBatchSize     = 512
+	DefaultBatchTimeout  = time.Second
+	DefaultExportTimeout = 10 * time.Second
+	DefaultMaxRetries    = 3
+	DefaultRetryBackoff  = 500 * time.Millisecond
+	DefaultScopeName     = "github.com/barnowlsnest/go-logslib"
+)
+
+// Config configures an Exporter.
+type Config struct {
+	// Protocol selects OTLP/HTTP or OTLP/gRPC. Defaults to HTTP.
+	Protocol Protocol
+
+	// Endpoint is the URL of the logs endpoint for HTTP, such as
+	// "https://collector:4318/v1/logs", or the host:port of the collector
+	// for GRPC. Defaults to DefaultHTTPEndpoint or DefaultGRPCEndpoint.
+	Endpoint string
+
+	// Insecure disables TLS for GRPC. HTTP follows the Endpoint scheme.
+	Insecure bool
+
+	// Headers are sent with every export, e.g. for authentication.
+	Headers map[string]string
+
+	// ServiceName is reported as the "service.name" resource attribute.
+	ServiceName string
+
+	// Resource holds additional resource attributes, such as
+	// "deployment.environment".
+	Resource map[string]string
+
+	// ScopeName is the instrumentation scope of the records. Defaults to
+	// DefaultScopeName.
+	ScopeName string
+
+	// BatchSize is the maximum number of records per export. Defaults to
+	// DefaultBatchSize.
+	BatchSize int
+
+	// BatchTimeout is how long a record may wait for its batch to fill up
+	// before it is exported. Defaults to DefaultBatchTimeout.
+	BatchTimeout time.Duration
+
+	// QueueSize is the maximum number of records waiting to be exported.
+	// Writes beyond it fail with ErrQueueFull. Defaults to 4 * BatchSize.
+	QueueSize int
+
+	// ExportTimeout bounds each export attempt. Defaults to
+	// DefaultExportTimeout.
+	ExportTimeout time.Duration
+
+	// MaxRetries is the number of times a failed export is retried when
+	// the failure is transient. Defaults to DefaultMaxRetries; a negative
+	// value disables retries.
+	MaxRetries int
+
+	// RetryBackoff is the delay before the first retry, doubled for each
+	// further one. Defaults to DefaultRetryBackoff.
+	RetryBackoff time.Duration
+
+	// HTTPClient sends HTTP exports. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	// ErrorHandler, when not nil, is called with the error of every batch
+	// that could not be exported. The batch is dropped.
+	ErrorHandler func(err error)
+}
+
+// SeverityNumber returns the OpenTelemetry severity number of level.
+// PanicLevel and FatalLevel map to FATAL2 and FATAL3.
+func SeverityNumber(level logger.Level) int32 {
+	switch level {
+	case logger.DebugLevel:
+		return 5
+	case logger.InfoLevel:
+		return 9
+	case logger.WarnLevel:
+		return 13
+	case logger.ErrorLevel:
+		return 17
+	case logger.PanicLevel:
+		return 22
+	case logger.FatalLevel:
+		return 23
+	default:
+		return 0
+	}
+}
+
+// NewSink creates an Exporter for config and returns a logger.Sink writing
+// to it, along with the Exporter so that the caller can flush and close it.
+func NewSink(config Config) (logger.Sink, *Exporter, error) {
+	exp, err := NewExporter(config)
+	if err != nil {
+		return logger.Sink{}, nil, err
+	}
+	return logger.Sink{Output: exp, Encoder: NewEncoder()}, exp, nil
+}
+
+// NewEncoder returns a logger.Encoder that encodes each entry as a
+// protobuf LogRecord message.
+func NewEncoder() logger.Encoder {
+	return encoder{}
+}
+
+// encoder implements logger.Encoder for LogRecord messages.
+type encoder struct{}
+
+// Field numbers of the OpenTelemetry LogRecord message.
+const (
+	recordTime         protowire.Number = 1
+	recordSeverity     protowire.Number = 2
+	recordSeverityText protowire.Number = 3
+	recordBody         protowire.Number = 5
+	recordAttributes   protowire.Number = 6
+	recordTraceID      protowire.Number = 9
+	recordSpanID       protowire.Number = 10
+	recordObservedTime protowire.Number = 11
+	traceIDLen                          = 16
+	spanIDLen                           = 8
+	traceIDKey                          = "trace_id"
+	spanIDKey                           = "span_id"
+	codeFilepathKey                     = "code.filepath"
+	codeLinenoKey                       = "code.lineno"
+	codeFunctionKey                     = "code.function"
+)
+
+// EncodeEntry implements logger.Encoder. The caller, when known, is
+// reported in the "code.filepath", "code.lineno" and "code.function"
+// attributes.
+func (enc encoder) EncodeEntry(buf []byte, e *logger.Entry) []byte {
+	ts := uint64(e.Time.UnixNano())
+	buf = protowire.AppendTag(buf, recordTime, protowire.Fixed64Type)
+	buf = protowire.AppendFixed64(buf, ts)
+	buf = protowire.AppendTag(buf, recordObservedTime, protowire.Fixed64Type)
+	buf = protowire.AppendFixed64(buf, ts)
+	buf = protowire.AppendTag(buf, recordSeverity, protowire.VarintType)
+	buf = protowire.AppendVarint(buf, uint64(SeverityNumber(e.Level)))
+	buf = appendString(buf, recordSeverityText, e.Level.String())
+
+	buf = protowire.AppendTag(buf, recordBody, protowire.BytesType)
+	buf = protowire.AppendVarint(buf, uint64(sizeString(anyString, e.Message)))
+	buf = appendString(buf, anyString, e.Message)
+
+	if e.Caller != nil {
+		buf = appendAttribute(buf, codeFilepathKey, e.Caller.File)
+		buf = appendAttribute(buf, codeLinenoKey, int64(e.Caller.Line))
+		buf = appendAttribute(buf, codeFunctionKey, e.Caller.Function)
+	}
+
+	buf = append(buf, e.Context...)
+	return enc.EncodeFields(buf, e.Fields)
+}
+
+// EncodeFields implements logger.Encoder. Fields are encoded as LogRecord
+// attributes, except "trace_id" and "span_id" fields holding hex IDs of
+// the right length, which set the record's trace and span IDs.
+func (encoder) EncodeFields(buf []byte, fields []logger.Field) []byte {
+	for _, f := range fields {
+		v := f.Interface()
+		if s, ok := v.(string); ok {
+			switch {
+			case f.Key == traceIDKey && hex.DecodedLen(len(s)) == traceIDLen:
+				if id, err := hex.DecodeString(s); err == nil {
+					buf = protowire.AppendTag(buf, recordTraceID, protowire.BytesType)
+					buf = protowire.AppendBytes(buf, id)
+					continue
+				}
+			case f.Key == spanIDKey && hex.DecodedLen(len(s)) == spanIDLen:
+				if id, err := hex.DecodeString(s); err == nil {
+					buf = protowire.AppendTag(buf, recordSpanID, protowire.BytesType)
+					buf = protowire.AppendBytes(buf, id)
+					continue
+				}
+			}
+		}
+
+		switch x := v.(type) {
+		case string, int64, float64, bool:
+		case time.Duration:
+			v = x.String()
+		case time.Time:
+			v = x.Format(time.RFC3339Nano)
+		case error:
+			v = x.Error()
+		default:
+			v = string(f.AppendJSONValue(nil))
+		}
+		buf = appendAttribute(buf, f.Key, v)
+	}
+	return buf
+}
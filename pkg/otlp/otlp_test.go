@@ -0,0 +1,437 @@
+package otlp
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// pbField is a decoded protobuf field: num holds varint and fixed values,
+// msg length-delimited ones.
+type pbField struct {
+	num protowire.Number
+	val uint64
+	msg []byte
+}
+
+// decode splits a protobuf message into its fields.
+func decode(t *testing.T, b []byte) []pbField {
+	t.Helper()
+
+	var fields []pbField
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.GreaterOrEqual(t, n, 0)
+		b = b[n:]
+
+		f := pbField{num: num}
+		switch typ {
+		case protowire.VarintType:
+			f.val, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			f.val, n = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			f.msg, n = protowire.ConsumeBytes(b)
+		default:
+			t.Fatalf("unexpected wire type %d", typ)
+		}
+		require.GreaterOrEqual(t, n, 0)
+		b = b[n:]
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// get returns the fields of msg numbered num.
+func get(t *testing.T, msg []byte, num protowire.Number) []pbField {
+	t.Helper()
+
+	var out []pbField
+	for _, f := range decode(t, msg) {
+		if f.num == num {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// attributes decodes the KeyValue fields numbered num of msg into Go values.
+func attributes(t *testing.T, msg []byte, num protowire.Number) map[string]interface{} {
+	t.Helper()
+
+	attrs := make(map[string]interface{})
+	for _, kv := range get(t, msg, num) {
+		key := string(get(t, kv.msg, kvKey)[0].msg)
+		value := decode(t, get(t, kv.msg, kvValue)[0].msg)
+		require.Len(t, value, 1)
+
+		switch value[0].num {
+		case anyString:
+			attrs[key] = string(value[0].msg)
+		case anyBool:
+			attrs[key] = value[0].val == 1
+		case anyInt:
+			attrs[key] = int64(value[0].val)
+		case anyDouble:
+			attrs[key] = math.Float64frombits(value[0].val)
+		}
+	}
+	return attrs
+}
+
+// records returns the LogRecord messages of an export request and the
+// resource attributes.
+func records(t *testing.T, req []byte) ([][]byte, map[string]interface{}) {
+	t.Helper()
+
+	resourceLogs := get(t, req, requestResourceLogs)
+	require.Len(t, resourceLogs, 1)
+
+	resource := get(t, resourceLogs[0].msg, resourceLogsResource)[0].msg
+	scopeLogs := get(t, resourceLogs[0].msg, resourceLogsScope)
+	require.Len(t, scopeLogs, 1)
+
+	scope := get(t, scopeLogs[0].msg, scopeLogsScope)[0].msg
+	assert.Equal(t, DefaultScopeName, string(get(t, scope, scopeName)[0].msg))
+
+	var out [][]byte
+	for _, r := range get(t, scopeLogs[0].msg, scopeLogsRecords) {
+		out = append(out, r.msg)
+	}
+	return out, attributes(t, resource, resourceAttributes)
+}
+
+// body returns the string body of a LogRecord.
+func body(t *testing.T, record []byte) string {
+	t.Helper()
+	return string(get(t, get(t, record, recordBody)[0].msg, anyString)[0].msg)
+}
+
+// collector is an OTLP/HTTP endpoint recording export requests.
+type collector struct {
+	mu       sync.Mutex
+	requests [][]byte
+	statuses []int
+	header   http.Header
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b, _ := io.ReadAll(r.Body)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.header = r.Header.Clone()
+	c.requests = append(c.requests, b)
+	if len(c.statuses) > 0 {
+		code := c.statuses[0]
+		c.statuses = c.statuses[1:]
+		w.WriteHeader(code)
+	}
+}
+
+func (c *collector) records(t *testing.T) [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var out [][]byte
+	for _, req := range c.requests {
+		recs, _ := records(t, req)
+		out = append(out, recs...)
+	}
+	return out
+}
+
+func TestSeverityNumber(t *testing.T) {
+	assert.Equal(t, int32(5), SeverityNumber(logger.DebugLevel))
+	assert.Equal(t, int32(9), SeverityNumber(logger.InfoLevel))
+	assert.Equal(t, int32(13), SeverityNumber(logger.WarnLevel))
+	assert.Equal(t, int32(17), SeverityNumber(logger.ErrorLevel))
+	assert.Equal(t, int32(22), SeverityNumber(logger.PanicLevel))
+	assert.Equal(t, int32(23), SeverityNumber(logger.FatalLevel))
+	assert.Equal(t, int32(0), SeverityNumber(logger.Level(42)))
+}
+
+func TestEncoder(t *testing.T) {
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	spanID := "00f067aa0ba902b7"
+	ts := time.Date(2024, 1, 20, 15, 4, 5, 0, time.UTC)
+
+	enc := NewEncoder()
+	e := &logger.Entry{
+		Time:    ts,
+		Level:   logger.WarnLevel,
+		Message: "slow request",
+		Context: enc.EncodeFields(nil, []logger.Field{logger.String("trace_id", traceID)}),
+		Fields: []logger.Field{
+			logger.String("span_id", spanID),
+			logger.String("user", "alice"),
+			logger.Int("attempt", 3),
+			logger.Float64("ratio", 0.5),
+			logger.Bool("cached", true),
+			logger.Dur("took", 1500*time.Millisecond),
+			logger.Err(errors.New("timeout")),
+			logger.Any("tags", []string{"a", "b"}),
+		},
+		Caller: &logger.Caller{File: "app/main.go", Line: 42, Function: "main.run"},
+	}
+
+	record := enc.EncodeEntry(nil, e)
+
+	assert.Equal(t, uint64(ts.UnixNano()), get(t, record, recordTime)[0].val)
+	assert.Equal(t, uint64(ts.UnixNano()), get(t, record, recordObservedTime)[0].val)
+	assert.Equal(t, uint64(13), get(t, record, recordSeverity)[0].val)
+	assert.Equal(t, "WARN", string(get(t, record, recordSeverityText)[0].msg))
+	assert.Equal(t, "slow request", body(t, record))
+	assert.Equal(t, traceID, hex.EncodeToString(get(t, record, recordTraceID)[0].msg))
+	assert.Equal(t, spanID, hex.EncodeToString(get(t, record, recordSpanID)[0].msg))
+
+	assert.Equal(t, map[string]interface{}{
+		codeFilepathKey: "app/main.go",
+		codeLinenoKey:   int64(42),
+		codeFunctionKey: "main.run",
+		"user":          "alice",
+		"attempt":       int64(3),
+		"ratio":         0.5,
+		"cached":        true,
+		"took":          "1.5s",
+		"error":         "timeout",
+		"tags":          `["a","b"]`,
+	}, attributes(t, record, recordAttributes))
+}
+
+func TestEncoder_InvalidTraceID(t *testing.T) {
+	record := NewEncoder().EncodeFields(nil, []logger.Field{logger.String("trace_id", "not-hex")})
+
+	assert.Empty(t, get(t, record, recordTraceID))
+	assert.Equal(t, "not-hex", attributes(t, record, recordAttributes)["trace_id"])
+}
+
+func TestExporter_HTTP(t *testing.T) {
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	sink, exp, err := NewSink(Config{
+		Endpoint:     srv.URL,
+		ServiceName:  "api",
+		Resource:     map[string]string{"deployment.environment": "prod"},
+		Headers:      map[string]string{"Authorization": "Bearer token"},
+		BatchTimeout: time.Hour,
+	})
+	require.NoError(t, err)
+	defer exp.Close()
+
+	log := logger.New(logger.Config{Level: logger.DebugLevel, Sinks: []logger.Sink{sink}})
+	log.Info("first")
+	log.Warn("second", logger.Int("n", 2))
+	require.NoError(t, exp.Flush())
+
+	require.Len(t, c.requests, 1)
+	recs, resource := records(t, c.requests[0])
+	require.Len(t, recs, 2)
+	assert.Equal(t, "first", body(t, recs[0]))
+	assert.Equal(t, "second", body(t, recs[1]))
+	assert.Equal(t, map[string]interface{}{"service.name": "api", "deployment.environment": "prod"}, resource)
+	assert.Equal(t, "application/x-protobuf", c.header.Get("Content-Type"))
+	assert.Equal(t, "Bearer token", c.header.Get("Authorization"))
+}
+
+func TestExporter_BatchSize(t *testing.T) {
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	exp, err := NewExporter(Config{Endpoint: srv.URL, BatchSize: 2, BatchTimeout: time.Hour})
+	require.NoError(t, err)
+	defer exp.Close()
+
+	enc := NewEncoder()
+	for i := 0; i < 5; i++ {
+		_, err := exp.Write(append(enc.EncodeEntry(nil, &logger.Entry{Message: "entry"}), '\n'))
+		require.NoError(t, err)
+	}
+	require.NoError(t, exp.Flush())
+
+	c.mu.Lock()
+	for _, req := range c.requests {
+		recs, _ := records(t, req)
+		assert.LessOrEqual(t, len(recs), 2)
+	}
+	c.mu.Unlock()
+	assert.Len(t, c.records(t), 5)
+}
+
+func TestExporter_BatchTimeout(t *testing.T) {
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	sink, exp, err := NewSink(Config{Endpoint: srv.URL, BatchTimeout: 10 * time.Millisecond})
+	require.NoError(t, err)
+	defer exp.Close()
+
+	logger.New(logger.Config{Sinks: []logger.Sink{sink}}).Info("on timer")
+
+	assert.Eventually(t, func() bool { return len(c.records(t)) == 1 }, time.Second, 5*time.Millisecond)
+}
+
+func TestExporter_Retry(t *testing.T) {
+	c := &collector{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	sink, exp, err := NewSink(Config{Endpoint: srv.URL, BatchTimeout: time.Hour, RetryBackoff: time.Millisecond})
+	require.NoError(t, err)
+	defer exp.Close()
+
+	logger.New(logger.Config{Sinks: []logger.Sink{sink}}).Info("retried")
+	require.NoError(t, exp.Flush())
+
+	assert.Len(t, c.requests, 3)
+}
+
+func TestExporter_PermanentFailure(t *testing.T) {
+	c := &collector{statuses: []int{http.StatusBadRequest}}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	var handled atomic.Int32
+	sink, exp, err := NewSink(Config{
+		Endpoint:     srv.URL,
+		BatchTimeout: time.Hour,
+		RetryBackoff: time.Millisecond,
+		ErrorHandler: func(error) { handled.Add(1) },
+	})
+	require.NoError(t, err)
+	defer exp.Close()
+
+	logger.New(logger.Config{Sinks: []logger.Sink{sink}}).Info("rejected")
+	err = exp.Flush()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "400")
+	assert.Len(t, c.requests, 1)
+	assert.Equal(t, int32(1), handled.Load())
+
+	// The batch was dropped.
+	require.NoError(t, exp.Flush())
+	assert.Len(t, c.requests, 1)
+}
+
+func TestExporter_QueueFull(t *testing.T) {
+	exp, err := NewExporter(Config{Endpoint: "http://127.0.0.1:0", QueueSize: 1, BatchTimeout: time.Hour, MaxRetries: -1})
+	require.NoError(t, err)
+	defer exp.Close()
+
+	_, err = exp.Write([]byte("a\n"))
+	require.NoError(t, err)
+	_, err = exp.Write([]byte("b\n"))
+	assert.ErrorIs(t, err, ErrQueueFull)
+}
+
+func TestExporter_Close(t *testing.T) {
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	sink, exp, err := NewSink(Config{Endpoint: srv.URL, BatchTimeout: time.Hour})
+	require.NoError(t, err)
+
+	log := logger.New(logger.Config{Sinks: []logger.Sink{sink}})
+	log.Info("pending")
+
+	require.NoError(t, exp.Close())
+	require.NoError(t, exp.Close())
+	assert.Len(t, c.records(t), 1)
+
+	_, err = exp.Write([]byte("late\n"))
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestExporter_UnknownProtocol(t *testing.T) {
+	_, err := NewExporter(Config{Protocol: Protocol(9)})
+	assert.Error(t, err)
+}
+
+func TestExporter_GRPC(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests [][]byte
+		auth     []string
+	)
+
+	handler := func(_ interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+		var req []byte
+		if err := dec(&req); err != nil {
+			return nil, err
+		}
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, req)
+		auth = md.Get("authorization")
+		return &[]byte{}, nil
+	}
+
+	srv := grpc.NewServer(grpc.ForceServerCodec(rawCodec{}))
+	srv.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "opentelemetry.proto.collector.logs.v1.LogsService",
+		HandlerType: (*interface{})(nil),
+		Methods:     []grpc.MethodDesc{{MethodName: "Export", Handler: handler}},
+	}, struct{}{})
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	sink, exp, err := NewSink(Config{
+		Protocol:     GRPC,
+		Endpoint:     lis.Addr().String(),
+		Insecure:     true,
+		Headers:      map[string]string{"authorization": "Bearer token"},
+		BatchTimeout: time.Hour,
+	})
+	require.NoError(t, err)
+	defer exp.Close()
+
+	logger.New(logger.Config{Sinks: []logger.Sink{sink}}).Error("over grpc")
+	require.NoError(t, exp.Flush())
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, requests, 1)
+	recs, _ := records(t, requests[0])
+	require.Len(t, recs, 1)
+	assert.Equal(t, "over grpc", body(t, recs[0]))
+	assert.Equal(t, []string{"Bearer token"}, auth)
+}
+
+func TestRetryable(t *testing.T) {
+	assert.True(t, retryable(&httpStatusError{code: http.StatusServiceUnavailable}))
+	assert.False(t, retryable(&httpStatusError{code: http.StatusBadRequest}))
+	assert.True(t, retryable(errors.New("connection refused")))
+	assert.True(t, retryable(status.Error(codes.Unavailable, "down")))
+	assert.False(t, retryable(status.Error(codes.InvalidArgument, "bad")))
+}
@@ -0,0 +1,100 @@
+package otlp
+
+import (
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Field numbers of the OpenTelemetry AnyValue and KeyValue messages.
+const (
+	anyString protowire.Number = 1
+	anyBool   protowire.Number = 2
+	anyInt    protowire.Number = 3
+	anyDouble protowire.Number = 4
+	kvKey     protowire.Number = 1
+	kvValue   protowire.Number = 2
+)
+
+// Field numbers of the messages wrapping LogRecords in an export request.
+const (
+	requestResourceLogs  protowire.Number = 1
+	resourceLogsResource protowire.Number = 1
+	resourceLogsScope    protowire.Number = 2
+	resourceAttributes   protowire.Number = 1
+	scopeLogsScope       protowire.Number = 1
+	scopeLogsRecords     protowire.Number = 2
+	scopeName            protowire.Number = 1
+)
+
+// appendString appends s as string field num.
+func appendString(buf []byte, num protowire.Number, s string) []byte {
+	buf = protowire.AppendTag(buf, num, protowire.BytesType)
+	return protowire.AppendString(buf, s)
+}
+
+// sizeString returns the encoded size of s as string field num.
+func sizeString(num protowire.Number, s string) int {
+	return protowire.SizeTag(num) + protowire.SizeBytes(len(s))
+}
+
+// appendAnyValue appends the fields of an AnyValue message holding v,
+// which must be a string, int64, float64 or bool.
+func appendAnyValue(buf []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case string:
+		return appendString(buf, anyString, v)
+	case bool:
+		buf = protowire.AppendTag(buf, anyBool, protowire.VarintType)
+		return protowire.AppendVarint(buf, protowire.EncodeBool(v))
+	case int64:
+		buf = protowire.AppendTag(buf, anyInt, protowire.VarintType)
+		return protowire.AppendVarint(buf, uint64(v))
+	case float64:
+		buf = protowire.AppendTag(buf, anyDouble, protowire.Fixed64Type)
+		return protowire.AppendFixed64(buf, math.Float64bits(v))
+	default:
+		return buf
+	}
+}
+
+// sizeAnyValue returns the size of the AnyValue message holding v.
+func sizeAnyValue(v interface{}) int {
+	switch v := v.(type) {
+	case string:
+		return sizeString(anyString, v)
+	case bool:
+		return protowire.SizeTag(anyBool) + 1
+	case int64:
+		return protowire.SizeTag(anyInt) + protowire.SizeVarint(uint64(v))
+	case float64:
+		return protowire.SizeTag(anyDouble) + protowire.SizeFixed64()
+	default:
+		return 0
+	}
+}
+
+// appendKeyValue appends a KeyValue message holding key and v as
+// length-delimited field num.
+func appendKeyValue(buf []byte, num protowire.Number, key string, v interface{}) []byte {
+	valueSize := sizeAnyValue(v)
+	size := sizeString(kvKey, key) + protowire.SizeTag(kvValue) + protowire.SizeBytes(valueSize)
+
+	buf = protowire.AppendTag(buf, num, protowire.BytesType)
+	buf = protowire.AppendVarint(buf, uint64(size))
+	buf = appendString(buf, kvKey, key)
+	buf = protowire.AppendTag(buf, kvValue, protowire.BytesType)
+	buf = protowire.AppendVarint(buf, uint64(valueSize))
+	return appendAnyValue(buf, v)
+}
+
+// appendAttribute appends a LogRecord attribute.
+func appendAttribute(buf []byte, key string, v interface{}) []byte {
+	return appendKeyValue(buf, recordAttributes, key, v)
+}
+
+// appendMessage appends msg as length-delimited field num.
+func appendMessage(buf []byte, num protowire.Number, msg []byte) []byte {
+	buf = protowire.AppendTag(buf, num, protowire.BytesType)
+	return protowire.AppendBytes(buf, msg)
+}
@@ -0,0 +1,101 @@
+package otlp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// MaxStreamRecordSize is the largest record a StreamReader accepts, so that
+// a corrupt length prefix does not make it allocate without bound.
+const MaxStreamRecordSize = 4 << 20
+
+// ErrRecordTooLarge is returned by StreamReader.Next for a record longer
+// than MaxStreamRecordSize.
+var ErrRecordTooLarge = errors.New("otlp: stream record is too large")
+
+// NewStreamSink returns a logger.Sink writing each entry to w as a
+// LogRecord message preceded by its size as a varint, the delimited
+// framing of protobuf writeDelimitedTo and parseDelimitedFrom. Files or
+// sockets written this way can be read by OTel collectors, or by a
+// StreamReader, without parsing JSON.
+//
+// The sink expects one entry per Write, so leave Config.BufferSize of the
+// logger unset; buffer w itself if needed.
+func NewStreamSink(w io.Writer) logger.Sink {
+	return logger.Sink{Output: NewStreamWriter(w), Encoder: NewEncoder()}
+}
+
+// StreamWriter frames the LogRecord messages written to it with their
+// size. It is safe for concurrent use.
+type StreamWriter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	buf []byte
+}
+
+// NewStreamWriter returns a StreamWriter writing to w.
+func NewStreamWriter(w io.Writer) *StreamWriter {
+	return &StreamWriter{w: w}
+}
+
+// Write writes p, without its trailing newline, as one length-prefixed
+// LogRecord message. The prefix and the message are written to the
+// underlying writer in a single call.
+func (s *StreamWriter) Write(p []byte) (int, error) {
+	record := bytes.TrimSuffix(p, []byte{'\n'})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.buf = protowire.AppendVarint(s.buf[:0], uint64(len(record)))
+	s.buf = append(s.buf, record...)
+	if _, err := s.w.Write(s.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// StreamReader reads the LogRecord messages of a stream written by a
+// StreamWriter. It is not safe for concurrent use.
+type StreamReader struct {
+	r   *bufio.Reader
+	buf []byte
+}
+
+// NewStreamReader returns a StreamReader reading from r.
+func NewStreamReader(r io.Reader) *StreamReader {
+	return &StreamReader{r: bufio.NewReader(r)}
+}
+
+// Next returns the next LogRecord message, which is only valid until the
+// next call. It returns io.EOF at the end of the stream, and
+// io.ErrUnexpectedEOF if the stream ends inside a record.
+func (s *StreamReader) Next() ([]byte, error) {
+	size, err := binary.ReadUvarint(s.r)
+	if err != nil {
+		return nil, err
+	}
+	if size > MaxStreamRecordSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrRecordTooLarge, size)
+	}
+	if uint64(cap(s.buf)) < size {
+		s.buf = make([]byte, size)
+	}
+	s.buf = s.buf[:size]
+	if _, err := io.ReadFull(s.r, s.buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return s.buf, nil
+}
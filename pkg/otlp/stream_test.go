@@ -0,0 +1,79 @@
+package otlp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+func TestStreamSink(t *testing.T) {
+	var out bytes.Buffer
+	log := logger.New(logger.Config{Sinks: []logger.Sink{NewStreamSink(&out)}})
+
+	log.Info("started", logger.Int("port", 8080))
+	// The record ends with the attribute value 10, the newline byte.
+	log.Error("0123456789", logger.Int("n", 10))
+	log.Warn(strings.Repeat("x", 300))
+
+	r := NewStreamReader(&out)
+	var messages []string
+	for {
+		record, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		messages = append(messages, body(t, record))
+	}
+	assert.Equal(t, []string{"started", "0123456789", strings.Repeat("x", 300)}, messages)
+}
+
+func TestStreamWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := NewStreamWriter(&out)
+
+	record := NewEncoder().EncodeEntry(nil, &logger.Entry{Level: logger.WarnLevel, Message: "slow"})
+	n, err := w.Write(append(record, '\n'))
+	require.NoError(t, err)
+	assert.Equal(t, len(record)+1, n)
+
+	size, n := protowire.ConsumeVarint(out.Bytes())
+	require.Greater(t, n, 0)
+	assert.Equal(t, uint64(len(record)), size)
+	assert.Equal(t, record, out.Bytes()[n:])
+	assert.Equal(t, uint64(13), get(t, out.Bytes()[n:], recordSeverity)[0].val)
+}
+
+func TestStreamWriter_Error(t *testing.T) {
+	w := NewStreamWriter(failingWriter{})
+	_, err := w.Write([]byte("x\n"))
+	assert.Error(t, err)
+}
+
+func TestStreamReader_Truncated(t *testing.T) {
+	_, err := NewStreamReader(bytes.NewReader([]byte{5, 'a', 'b'})).Next()
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	_, err = NewStreamReader(bytes.NewReader([]byte{0x80})).Next()
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestStreamReader_TooLarge(t *testing.T) {
+	prefix := protowire.AppendVarint(nil, MaxStreamRecordSize+1)
+	_, err := NewStreamReader(bytes.NewReader(prefix)).Next()
+	assert.ErrorIs(t, err, ErrRecordTooLarge)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
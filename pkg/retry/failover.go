@@ -0,0 +1,169 @@
+package retry
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// DefaultRecoverInterval is the default delay between attempts to switch a
+// Failover back to its primary writer.
+const DefaultRecoverInterval = 5 * time.Second
+
+// FailoverConfig configures a Failover.
+type FailoverConfig struct {
+	// RecoverInterval is how long a Failover writes to its secondary
+	// writer before trying the primary one again. Defaults to
+	// DefaultRecoverInterval. It is not used when the primary writer is a
+	// Writer, whose circuit breaker decides when it is tried again.
+	RecoverInterval time.Duration
+
+	// OnSwitch, when not nil, is called each time the Failover switches
+	// writer, with whether it now writes to the primary one. It must not
+	// block.
+	OnSwitch func(primary bool)
+
+	// ErrorHandler, when not nil, is called with the errors of the
+	// primary writer that made the Failover switch.
+	ErrorHandler func(err error)
+}
+
+// FailoverStats holds the counts of a Failover.
+type FailoverStats struct {
+	// Primary is whether the Failover currently writes to the primary
+	// writer.
+	Primary bool
+	// PrimaryWrites and SecondaryWrites are the numbers of writes that
+	// went to each writer.
+	PrimaryWrites   uint64
+	SecondaryWrites uint64
+	// Failovers is the number of switches to the secondary writer.
+	Failovers uint64
+}
+
+// Failover is an io.Writer writing to a primary writer, such as a network
+// sink, and falling back to a secondary one, such as a local file, while
+// the primary fails or its circuit is open. It switches back once the
+// primary accepts a write again. It is safe for concurrent use if both
+// writers are.
+type Failover struct {
+	primary   io.Writer
+	secondary io.Writer
+	breaker   bool
+	config    FailoverConfig
+
+	mu       sync.Mutex
+	failedAt time.Time
+	stats    FailoverStats
+
+	now func() time.Time
+}
+
+// NewFailover returns a Failover writing to primary, or to secondary while
+// primary fails.
+func NewFailover(primary, secondary io.Writer, config FailoverConfig) *Failover {
+	if config.RecoverInterval <= 0 {
+		config.RecoverInterval = DefaultRecoverInterval
+	}
+	_, breaker := primary.(*Writer)
+	return &Failover{
+		primary:   primary,
+		secondary: secondary,
+		breaker:   breaker,
+		config:    config,
+		stats:     FailoverStats{Primary: true},
+		now:       time.Now,
+	}
+}
+
+// FailoverSink returns primary with its output replaced by a Failover to
+// secondary, along with the Failover so that the caller can read its
+// Stats. Entries keep the encoder of primary on both writers. A nil output
+// is taken to be os.Stdout, as by the logger.
+func FailoverSink(primary logger.Sink, secondary io.Writer, config FailoverConfig) (logger.Sink, *Failover) {
+	if primary.Output == nil {
+		primary.Output = os.Stdout
+	}
+	f := NewFailover(primary.Output, secondary, config)
+	primary.Output = f
+	return primary, f
+}
+
+// Write writes p to the primary writer, or to the secondary one while the
+// primary fails. It only returns an error when the secondary writer fails.
+func (f *Failover) Write(p []byte) (int, error) {
+	if f.usePrimary() {
+		n, err := f.primary.Write(p)
+		if err == nil {
+			f.succeed()
+			return n, nil
+		}
+		f.fail(err)
+	}
+
+	f.mu.Lock()
+	f.stats.SecondaryWrites++
+	f.mu.Unlock()
+	return f.secondary.Write(p)
+}
+
+// Primary reports whether the Failover currently writes to the primary
+// writer.
+func (f *Failover) Primary() bool {
+	return f.Stats().Primary
+}
+
+// Stats returns the counts of the Failover.
+func (f *Failover) Stats() FailoverStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stats
+}
+
+// usePrimary reports whether a write should try the primary writer.
+func (f *Failover) usePrimary() bool {
+	if f.breaker {
+		return true
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stats.Primary || f.now().Sub(f.failedAt) >= f.config.RecoverInterval
+}
+
+// succeed records a write to the primary writer, switching back to it.
+func (f *Failover) succeed() {
+	f.mu.Lock()
+	f.stats.PrimaryWrites++
+	switched := !f.stats.Primary
+	f.stats.Primary = true
+	f.mu.Unlock()
+
+	if switched && f.config.OnSwitch != nil {
+		f.config.OnSwitch(true)
+	}
+}
+
+// fail records a failed write to the primary writer, switching to the
+// secondary one.
+func (f *Failover) fail(err error) {
+	f.mu.Lock()
+	f.failedAt = f.now()
+	switched := f.stats.Primary
+	if switched {
+		f.stats.Primary = false
+		f.stats.Failovers++
+	}
+	f.mu.Unlock()
+
+	if switched {
+		if f.config.OnSwitch != nil {
+			f.config.OnSwitch(false)
+		}
+		if f.config.ErrorHandler != nil {
+			f.config.ErrorHandler(err)
+		}
+	}
+}
@@ -0,0 +1,339 @@
+// Package retry provides an io.Writer that retries failed writes to
+// another writer, typically a network sink, with jittered exponential
+// backoff, and stops calling it for a while once it keeps failing.
+//
+// Writes are retried up to MaxAttempts times. A write failing every attempt
+// counts as a failure; after FailureThreshold consecutive failures the
+// circuit opens and writes fail fast with ErrOpen instead of waiting on a
+// writer known to be down. After OpenTimeout one write is let through as a
+// probe: the circuit closes if it succeeds and opens again if it fails.
+//
+// Example usage:
+//
+//	conn, err := net.Dial("tcp", "logs.example.com:5170")
+//	if err != nil {
+//		return err
+//	}
+//	sink, w := retry.WrapSink(logger.Sink{Output: conn, Format: logger.JSONFormat}, retry.Config{
+//		OnStateChange: func(from, to retry.State) {
+//			circuitState.Set(float64(to))
+//		},
+//	})
+//	defer w.Close()
+//
+//	log := logger.New(logger.Config{Sinks: []logger.Sink{sink}, Async: true})
+//
+// Retries wait inside Write, so the writer should sit behind an
+// asynchronous logger or a queue such as netwriter's rather than delay
+// the callers of the logger.
+//
+// A Failover writes to a secondary writer, such as a local file, while its
+// primary one fails or has its circuit open:
+//
+//	sink, f := retry.FailoverSink(sink, file, retry.FailoverConfig{})
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// Defaults applied by New to unset Config fields.
+const (
+	DefaultMaxAttempts      = 3
+	DefaultMinBackoff       = 50 * time.Millisecond
+	DefaultMaxBackoff       = 2 * time.Second
+	DefaultFailureThreshold = 5
+	DefaultOpenTimeout      = 30 * time.Second
+)
+
+// ErrOpen is returned by Write, without calling the underlying writer,
+// while the circuit is open.
+var ErrOpen = errors.New("retry: circuit is open")
+
+// ErrClosed is returned by Write after Close has been called.
+var ErrClosed = errors.New("retry: writer is closed")
+
+// State is the state of the circuit breaker of a Writer.
+type State int
+
+const (
+	// Closed is the healthy state: writes go to the underlying writer.
+	Closed State = iota
+	// Open is the state after FailureThreshold consecutive failures:
+	// writes fail with ErrOpen.
+	Open
+	// HalfOpen is the state once OpenTimeout has passed: the next write
+	// probes the underlying writer.
+	HalfOpen
+)
+
+// String returns the name of the state, such as "closed".
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half_open"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
+// Config configures a Writer.
+type Config struct {
+	// MaxAttempts is the number of times a write is tried before it fails.
+	// Defaults to DefaultMaxAttempts; 1 disables retries.
+	MaxAttempts int
+
+	// MinBackoff is the delay before the first retry, doubled for each
+	// further one up to MaxBackoff. Each delay is jittered by up to half
+	// of it to keep writers from retrying in lockstep. They default to
+	// DefaultMinBackoff and DefaultMaxBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// FailureThreshold is the number of consecutive failed writes that
+	// opens the circuit. Defaults to DefaultFailureThreshold.
+	FailureThreshold int
+
+	// OpenTimeout is how long the circuit stays open before a write probes
+	// the underlying writer. Defaults to DefaultOpenTimeout.
+	OpenTimeout time.Duration
+
+	// OnStateChange, when not nil, is called on every change of the state
+	// of the circuit, for example to export it as a metric. It is called
+	// without locks held but must not block.
+	OnStateChange func(from, to State)
+
+	// ErrorHandler, when not nil, is called with each failed attempt.
+	ErrorHandler func(err error)
+}
+
+// Stats holds the health of a Writer.
+type Stats struct {
+	// State is the current state of the circuit.
+	State State
+	// ConsecutiveFailures is the number of failed writes since the last
+	// successful one.
+	ConsecutiveFailures int
+	// Retries is the number of attempts made after a first one failed.
+	Retries uint64
+	// Failures is the number of writes that failed every attempt.
+	Failures uint64
+	// Rejected is the number of writes failed with ErrOpen.
+	Rejected uint64
+	// Trips is the number of times the circuit opened.
+	Trips uint64
+}
+
+// Writer is an io.Writer retrying writes to another writer behind a
+// circuit breaker. It is safe for concurrent use if the underlying writer
+// is.
+type Writer struct {
+	next   io.Writer
+	config Config
+
+	mu       sync.Mutex
+	state    State
+	openedAt time.Time
+	probing  bool
+	stats    Stats
+
+	done      chan struct{}
+	closeOnce sync.Once
+
+	now func() time.Time
+}
+
+// New returns a Writer retrying writes to next as configured.
+func New(next io.Writer, config Config) *Writer {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = DefaultMaxAttempts
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = DefaultMinBackoff
+	}
+	if config.MaxBackoff < config.MinBackoff {
+		config.MaxBackoff = max(DefaultMaxBackoff, config.MinBackoff)
+	}
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = DefaultFailureThreshold
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = DefaultOpenTimeout
+	}
+	return &Writer{next: next, config: config, done: make(chan struct{}), now: time.Now}
+}
+
+// WrapSink returns sink with its output wrapped in a Writer, along with
+// the Writer so that the caller can read its Stats and close it. A nil
+// output is taken to be os.Stdout, as by the logger.
+func WrapSink(sink logger.Sink, config Config) (logger.Sink, *Writer) {
+	if sink.Output == nil {
+		sink.Output = os.Stdout
+	}
+	w := New(sink.Output, config)
+	sink.Output = w
+	return sink, w
+}
+
+// Write writes p to the underlying writer, retrying it while it fails. It
+// returns ErrOpen at once while the circuit is open, and the last error
+// once every attempt failed.
+func (w *Writer) Write(p []byte) (int, error) {
+	probe, err := w.acquire()
+	if err != nil {
+		return 0, err
+	}
+
+	attempts := w.config.MaxAttempts
+	if probe {
+		// A single attempt decides whether the writer recovered.
+		attempts = 1
+	}
+	backoff := w.config.MinBackoff
+	for attempt := 1; ; attempt++ {
+		var n int
+		n, err = w.next.Write(p)
+		if err == nil {
+			w.succeed()
+			return n, nil
+		}
+		if w.config.ErrorHandler != nil {
+			w.config.ErrorHandler(err)
+		}
+		if attempt == attempts || !w.wait(jitter(backoff)) {
+			break
+		}
+		backoff = min(2*backoff, w.config.MaxBackoff)
+
+		w.mu.Lock()
+		w.stats.Retries++
+		w.mu.Unlock()
+	}
+	w.fail()
+	return 0, err
+}
+
+// Close stops pending retries, which fail with their last error, and makes
+// further writes fail with ErrClosed. It does not close the underlying
+// writer.
+func (w *Writer) Close() error {
+	w.closeOnce.Do(func() { close(w.done) })
+	return nil
+}
+
+// State returns the current state of the circuit.
+func (w *Writer) State() State {
+	return w.Stats().State
+}
+
+// Stats returns the health of the writer.
+func (w *Writer) Stats() Stats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	stats := w.stats
+	stats.State = w.state
+	if w.state == Open && w.now().Sub(w.openedAt) >= w.config.OpenTimeout {
+		stats.State = HalfOpen
+	}
+	return stats
+}
+
+// acquire reports whether a write may go to the underlying writer, and
+// whether it is the probe of a half-open circuit.
+func (w *Writer) acquire() (probe bool, err error) {
+	select {
+	case <-w.done:
+		return false, ErrClosed
+	default:
+	}
+
+	w.mu.Lock()
+	from := w.state
+	switch {
+	case w.state == Closed:
+		w.mu.Unlock()
+		return false, nil
+	case w.probing, w.state == Open && w.now().Sub(w.openedAt) < w.config.OpenTimeout:
+		w.stats.Rejected++
+		w.mu.Unlock()
+		return false, ErrOpen
+	}
+	w.state = HalfOpen
+	w.probing = true
+	w.mu.Unlock()
+
+	w.changed(from, HalfOpen)
+	return true, nil
+}
+
+// succeed records a successful write, closing the circuit.
+func (w *Writer) succeed() {
+	w.mu.Lock()
+	from := w.state
+	w.state = Closed
+	w.probing = false
+	w.stats.ConsecutiveFailures = 0
+	w.mu.Unlock()
+
+	w.changed(from, Closed)
+}
+
+// fail records a failed write, opening the circuit after enough of them
+// or when the probe of a half-open circuit failed.
+func (w *Writer) fail() {
+	w.mu.Lock()
+	from := w.state
+	w.stats.Failures++
+	w.stats.ConsecutiveFailures++
+	if w.probing || (w.state == Closed && w.stats.ConsecutiveFailures >= w.config.FailureThreshold) {
+		w.state = Open
+		w.openedAt = w.now()
+		w.probing = false
+		w.stats.Trips++
+	}
+	to := w.state
+	w.mu.Unlock()
+
+	w.changed(from, to)
+}
+
+// changed calls OnStateChange if the state changed.
+func (w *Writer) changed(from, to State) {
+	if from != to && w.config.OnStateChange != nil {
+		w.config.OnStateChange(from, to)
+	}
+}
+
+// wait sleeps for d, returning false if the writer was closed meanwhile.
+func (w *Writer) wait(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+// jitter returns d less a random part of up to half of it.
+func jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	return d - rand.N(d/2+1)
+}
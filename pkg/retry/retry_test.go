@@ -0,0 +1,292 @@
+package retry
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+var errDown = errors.New("collector down")
+
+// flakyWriter fails its next failures writes, recording the others.
+type flakyWriter struct {
+	mu       sync.Mutex
+	failures int
+	calls    int
+	buf      bytes.Buffer
+}
+
+func (f *flakyWriter) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+	if f.failures != 0 {
+		f.failures--
+		return 0, errDown
+	}
+	return f.buf.Write(p)
+}
+
+func (f *flakyWriter) fail(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures = n
+}
+
+func (f *flakyWriter) written() (string, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.buf.String(), f.calls
+}
+
+func fastConfig() Config {
+	return Config{MinBackoff: time.Microsecond, MaxBackoff: time.Millisecond, OpenTimeout: time.Hour}
+}
+
+func TestWriter_Retries(t *testing.T) {
+	next := &flakyWriter{failures: 2}
+	var errs []error
+	config := fastConfig()
+	config.ErrorHandler = func(err error) { errs = append(errs, err) }
+	w := New(next, config)
+
+	n, err := w.Write([]byte("a\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	written, calls := next.written()
+	assert.Equal(t, "a\n", written)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, []error{errDown, errDown}, errs)
+	assert.Equal(t, Stats{State: Closed, Retries: 2}, w.Stats())
+}
+
+func TestWriter_FailsAfterMaxAttempts(t *testing.T) {
+	next := &flakyWriter{failures: 4}
+	config := fastConfig()
+	config.MaxAttempts = 2
+	w := New(next, config)
+
+	_, err := w.Write([]byte("a\n"))
+	assert.ErrorIs(t, err, errDown)
+	_, err = w.Write([]byte("b\n"))
+	assert.ErrorIs(t, err, errDown)
+	_, err = w.Write([]byte("c\n"))
+	require.NoError(t, err)
+
+	_, calls := next.written()
+	assert.Equal(t, 5, calls)
+	assert.Equal(t, Stats{State: Closed, Retries: 2, Failures: 2}, w.Stats())
+}
+
+func TestWriter_CircuitBreaker(t *testing.T) {
+	next := &flakyWriter{failures: 100}
+	now := time.Unix(0, 0)
+	var changes []string
+	config := fastConfig()
+	config.MaxAttempts = 1
+	config.FailureThreshold = 3
+	config.OpenTimeout = time.Minute
+	config.OnStateChange = func(from, to State) { changes = append(changes, from.String()+"->"+to.String()) }
+	w := New(next, config)
+	w.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		_, err := w.Write([]byte("a\n"))
+		assert.ErrorIs(t, err, errDown)
+	}
+	assert.Equal(t, Open, w.State())
+
+	_, err := w.Write([]byte("a\n"))
+	assert.ErrorIs(t, err, ErrOpen)
+	_, calls := next.written()
+	assert.Equal(t, 3, calls, "an open circuit does not call the writer")
+
+	// A failed probe opens the circuit again.
+	now = now.Add(time.Minute)
+	assert.Equal(t, HalfOpen, w.State())
+	_, err = w.Write([]byte("a\n"))
+	assert.ErrorIs(t, err, errDown)
+	assert.Equal(t, Open, w.State())
+
+	// A successful one closes it.
+	next.fail(0)
+	now = now.Add(time.Minute)
+	_, err = w.Write([]byte("b\n"))
+	require.NoError(t, err)
+	assert.Equal(t, Closed, w.State())
+
+	assert.Equal(t, Stats{State: Closed, Failures: 4, Rejected: 1, Trips: 2}, w.Stats())
+	assert.Equal(t, []string{
+		"closed->open",
+		"open->half_open", "half_open->open",
+		"open->half_open", "half_open->closed",
+	}, changes)
+}
+
+func TestWriter_SingleProbe(t *testing.T) {
+	release := make(chan struct{})
+	probing := make(chan struct{})
+	next := &blockingWriter{release: release, started: probing}
+	w := New(next, Config{MaxAttempts: 1, FailureThreshold: 1, OpenTimeout: time.Nanosecond})
+	w.fail()
+	require.Equal(t, Open, w.state)
+
+	done := make(chan error)
+	go func() {
+		_, err := w.Write([]byte("probe\n"))
+		done <- err
+	}()
+	<-probing
+
+	_, err := w.Write([]byte("other\n"))
+	assert.ErrorIs(t, err, ErrOpen, "only one write probes a half-open circuit")
+	close(release)
+	require.NoError(t, <-done)
+	assert.Equal(t, Closed, w.State())
+}
+
+// blockingWriter signals started and waits for release on each Write.
+type blockingWriter struct {
+	release chan struct{}
+	started chan struct{}
+}
+
+func (b *blockingWriter) Write(p []byte) (int, error) {
+	b.started <- struct{}{}
+	<-b.release
+	return len(p), nil
+}
+
+func TestWriter_CloseStopsRetries(t *testing.T) {
+	next := &flakyWriter{failures: 100}
+	w := New(next, Config{MaxAttempts: 10, MinBackoff: time.Hour})
+
+	done := make(chan error)
+	go func() {
+		_, err := w.Write([]byte("a\n"))
+		done <- err
+	}()
+	require.Eventually(t, func() bool {
+		_, calls := next.written()
+		return calls == 1
+	}, time.Second, time.Millisecond)
+
+	require.NoError(t, w.Close())
+	assert.ErrorIs(t, <-done, errDown)
+	_, err := w.Write([]byte("b\n"))
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestWrapSink(t *testing.T) {
+	next := &flakyWriter{failures: 1}
+	sink, w := WrapSink(logger.Sink{Output: next, Format: logger.TextFormat}, fastConfig())
+	log := logger.New(logger.Config{Level: logger.InfoLevel, OmitTime: true, Sinks: []logger.Sink{sink}})
+
+	log.Info("hello")
+	written, _ := next.written()
+	assert.Equal(t, "INFO hello\n", written)
+	assert.Equal(t, uint64(1), w.Stats().Retries)
+	assert.Zero(t, log.Stats().Failed)
+}
+
+func TestJitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		d := jitter(100 * time.Millisecond)
+		assert.GreaterOrEqual(t, d, 50*time.Millisecond)
+		assert.LessOrEqual(t, d, 100*time.Millisecond)
+	}
+	assert.Equal(t, time.Duration(0), jitter(0))
+}
+
+func TestFailover(t *testing.T) {
+	primary := &flakyWriter{}
+	secondary := &flakyWriter{}
+	now := time.Unix(0, 0)
+	var switches []bool
+	var errs []error
+	f := NewFailover(primary, secondary, FailoverConfig{
+		RecoverInterval: time.Minute,
+		OnSwitch:        func(p bool) { switches = append(switches, p) },
+		ErrorHandler:    func(err error) { errs = append(errs, err) },
+	})
+	f.now = func() time.Time { return now }
+
+	_, err := f.Write([]byte("1\n"))
+	require.NoError(t, err)
+
+	primary.fail(100)
+	for _, entry := range []string{"2\n", "3\n"} {
+		n, err := f.Write([]byte(entry))
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+	}
+	assert.False(t, f.Primary())
+	_, calls := primary.written()
+	assert.Equal(t, 2, calls, "the primary is not tried again before RecoverInterval")
+
+	// Still failing after RecoverInterval.
+	now = now.Add(time.Minute)
+	_, err = f.Write([]byte("4\n"))
+	require.NoError(t, err)
+
+	primary.fail(0)
+	now = now.Add(time.Minute)
+	_, err = f.Write([]byte("5\n"))
+	require.NoError(t, err)
+	assert.True(t, f.Primary())
+
+	written, _ := primary.written()
+	assert.Equal(t, "1\n5\n", written)
+	written, _ = secondary.written()
+	assert.Equal(t, "2\n3\n4\n", written)
+	assert.Equal(t, FailoverStats{Primary: true, PrimaryWrites: 2, SecondaryWrites: 3, Failovers: 1}, f.Stats())
+	assert.Equal(t, []bool{false, true}, switches)
+	assert.Equal(t, []error{errDown}, errs)
+}
+
+func TestFailover_SecondaryError(t *testing.T) {
+	f := NewFailover(&flakyWriter{failures: 1}, &flakyWriter{failures: 1}, FailoverConfig{})
+	_, err := f.Write([]byte("a\n"))
+	assert.ErrorIs(t, err, errDown)
+}
+
+func TestFailover_CircuitBreaker(t *testing.T) {
+	next := &flakyWriter{failures: 100}
+	config := fastConfig()
+	config.MaxAttempts = 1
+	config.FailureThreshold = 1
+	config.OpenTimeout = time.Minute
+	now := time.Unix(0, 0)
+	w := New(next, config)
+	w.now = func() time.Time { return now }
+
+	secondary := &flakyWriter{}
+	sink, f := FailoverSink(logger.Sink{Output: w, Format: logger.TextFormat}, secondary, FailoverConfig{})
+	log := logger.New(logger.Config{Level: logger.InfoLevel, OmitTime: true, Sinks: []logger.Sink{sink}})
+
+	log.Info("one")
+	log.Info("two")
+	assert.Equal(t, Open, w.State())
+	_, calls := next.written()
+	assert.Equal(t, 1, calls, "the open circuit fails fast")
+
+	next.fail(0)
+	now = now.Add(time.Minute)
+	log.Info("three")
+	assert.True(t, f.Primary(), "the circuit decides when the primary is tried again")
+
+	written, _ := next.written()
+	assert.Equal(t, "INFO three\n", written)
+	written, _ = secondary.written()
+	assert.Equal(t, "INFO one\nINFO two\n", written)
+	assert.Zero(t, log.Stats().Failed)
+}
@@ -0,0 +1,441 @@
+// Package rotate provides an io.Writer that writes to a file and rotates it
+// by size and age, keeping a bounded number of optionally gzip-compressed
+// backups next to it.
+//
+// Example usage:
+//
+//	w := rotate.New(rotate.Config{
+//		Filename:   "/var/log/app/app.log",
+//		MaxSize:    100 << 20, // 100 MiB
+//		Interval:   24 * time.Hour,
+//		MaxBackups: 7,
+//		Compress:   true,
+//	})
+//	defer w.Close()
+//
+//	log := logger.New(logger.Config{
+//		Level:  logger.InfoLevel,
+//		Format: logger.JSONFormat,
+//		Output: w,
+//	})
+package rotate
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// BackupTimeFormat is the timestamp layout embedded in backup file names.
+	// Backups made within the same millisecond get a "_1", "_2"... suffix
+	// after the timestamp.
+	BackupTimeFormat = "2006-01-02T15-04-05.000"
+
+	compressSuffix = ".gz"
+	fileMode       = 0o644
+	dirMode        = 0o755
+)
+
+// ErrClosed is returned by Write and Rotate after Close has been called.
+var ErrClosed = errors.New("rotate: writer is closed")
+
+// Config holds the configuration for a Writer.
+type Config struct {
+	// Filename is the file to write to. Backups are created in the same
+	// directory as name-<timestamp>.ext.
+	Filename string
+
+	// MaxSize is the maximum size in bytes of the file before it is rotated.
+	// Zero disables size-based rotation.
+	MaxSize int64
+
+	// Interval rotates the file once it has been written to for this long.
+	// Zero disables time-based rotation.
+	Interval time.Duration
+
+	// MaxAge removes backups older than this duration.
+	// Zero keeps backups regardless of age.
+	MaxAge time.Duration
+
+	// MaxBackups is the maximum number of backups to keep.
+	// Zero keeps all backups.
+	MaxBackups int
+
+	// Compress gzips backups after rotation.
+	Compress bool
+
+	// UseUTC determines whether backup timestamps are in UTC (true) or local timezone (false).
+	UseUTC bool
+}
+
+// Writer is an io.WriteCloser that writes to Config.Filename and rotates it.
+// The file is opened lazily on the first Write. It is safe for concurrent use.
+type Writer struct {
+	config Config
+	now    func() time.Time
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	closed   bool
+
+	millOnce sync.Once
+	millCh   chan struct{}
+	millDone chan struct{}
+}
+
+// New creates a new Writer with the given configuration.
+func New(config Config) *Writer {
+	return &Writer{
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// Write writes p to the current file, rotating it first if the write would
+// exceed MaxSize or the file is older than Interval.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, ErrClosed
+	}
+
+	if w.file == nil {
+		if err := w.openExisting(int64(len(p))); err != nil {
+			return 0, err
+		}
+	}
+
+	if w.shouldRotate(int64(len(p))) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate closes the current file, moves it aside as a backup and opens
+// a new file in its place.
+func (w *Writer) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrClosed
+	}
+	return w.rotate()
+}
+
+// Sync commits the current contents of the file to stable storage.
+func (w *Writer) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Sync()
+}
+
+// Close closes the current file and waits for pending backup compression
+// and cleanup to finish. Writes after Close return ErrClosed.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	err := w.closeFile()
+	w.mu.Unlock()
+
+	if w.millCh != nil {
+		close(w.millCh)
+		<-w.millDone
+	}
+	return err
+}
+
+func (w *Writer) shouldRotate(n int64) bool {
+	if w.size == 0 {
+		return false
+	}
+	if w.config.MaxSize > 0 && w.size+n > w.config.MaxSize {
+		return true
+	}
+	return w.config.Interval > 0 && w.now().Sub(w.openedAt) >= w.config.Interval
+}
+
+// openExisting opens the current file for appending, or rotates it first
+// if it is already too large to take the next write.
+func (w *Writer) openExisting(n int64) error {
+	info, err := os.Stat(w.config.Filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return w.openNew()
+	}
+	if err != nil {
+		return fmt.Errorf("rotate: stat %s: %w", w.config.Filename, err)
+	}
+
+	if w.config.MaxSize > 0 && info.Size() > 0 && info.Size()+n > w.config.MaxSize {
+		return w.rotate()
+	}
+
+	f, err := os.OpenFile(w.config.Filename, os.O_APPEND|os.O_WRONLY, fileMode)
+	if err != nil {
+		return w.openNew()
+	}
+	w.file = f
+	w.size = info.Size()
+	w.openedAt = w.now()
+	return nil
+}
+
+func (w *Writer) openNew() error {
+	if err := os.MkdirAll(filepath.Dir(w.config.Filename), dirMode); err != nil {
+		return fmt.Errorf("rotate: create directory: %w", err)
+	}
+
+	f, err := os.OpenFile(w.config.Filename, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, fileMode)
+	if err != nil {
+		return fmt.Errorf("rotate: open %s: %w", w.config.Filename, err)
+	}
+	w.file = f
+	w.size = 0
+	w.openedAt = w.now()
+	return nil
+}
+
+// rotate must be called with w.mu held.
+func (w *Writer) rotate() error {
+	if err := w.closeFile(); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(w.config.Filename); err == nil {
+		if err := os.Rename(w.config.Filename, w.backupName(w.now())); err != nil {
+			return fmt.Errorf("rotate: rename %s: %w", w.config.Filename, err)
+		}
+	}
+
+	if err := w.openNew(); err != nil {
+		return err
+	}
+
+	w.mill()
+	return nil
+}
+
+func (w *Writer) closeFile() error {
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	w.size = 0
+	return err
+}
+
+// backupName returns the name of a backup made at t, with a counter suffix
+// if a backup made within the same millisecond exists, compressed or not.
+func (w *Writer) backupName(t time.Time) string {
+	if w.config.UseUTC {
+		t = t.UTC()
+	}
+	prefix, ext := w.nameParts()
+	stamp := prefix + t.Format(BackupTimeFormat)
+	name := filepath.Join(filepath.Dir(w.config.Filename), stamp+ext)
+	for seq := 1; exists(name) || exists(name+compressSuffix); seq++ {
+		name = filepath.Join(filepath.Dir(w.config.Filename), stamp+"_"+strconv.Itoa(seq)+ext)
+	}
+	return name
+}
+
+// exists reports whether a file exists at path.
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// nameParts returns the backup file name prefix ("app-") and extension (".log").
+func (w *Writer) nameParts() (prefix, ext string) {
+	base := filepath.Base(w.config.Filename)
+	ext = filepath.Ext(base)
+	return strings.TrimSuffix(base, ext) + "-", ext
+}
+
+// mill schedules compression and cleanup of backups on the background
+// goroutine. Requests made while a run is pending are coalesced.
+func (w *Writer) mill() {
+	if !w.config.Compress && w.config.MaxAge == 0 && w.config.MaxBackups == 0 {
+		return
+	}
+
+	w.millOnce.Do(func() {
+		w.millCh = make(chan struct{}, 1)
+		w.millDone = make(chan struct{})
+		go w.millRun()
+	})
+
+	select {
+	case w.millCh <- struct{}{}:
+	default:
+	}
+}
+
+func (w *Writer) millRun() {
+	defer close(w.millDone)
+	for range w.millCh {
+		_ = w.millRunOnce()
+	}
+}
+
+// backup is a rotated file found on disk.
+type backup struct {
+	path      string
+	timestamp time.Time
+	seq       int // counter suffix of backups made within one millisecond
+}
+
+func (w *Writer) millRunOnce() error {
+	backups, err := w.backups()
+	if err != nil {
+		return err
+	}
+
+	var remove []backup
+	if w.config.MaxBackups > 0 && len(backups) > w.config.MaxBackups {
+		remove = append(remove, backups[w.config.MaxBackups:]...)
+		backups = backups[:w.config.MaxBackups]
+	}
+	if w.config.MaxAge > 0 {
+		cutoff := w.now().Add(-w.config.MaxAge)
+		kept := backups[:0]
+		for _, b := range backups {
+			if b.timestamp.Before(cutoff) {
+				remove = append(remove, b)
+			} else {
+				kept = append(kept, b)
+			}
+		}
+		backups = kept
+	}
+
+	var errs []error
+	for _, b := range remove {
+		if err := os.Remove(b.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+
+	if w.config.Compress {
+		for _, b := range backups {
+			if strings.HasSuffix(b.path, compressSuffix) {
+				continue
+			}
+			if err := compressFile(b.path); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// backups returns the backups of the current file sorted newest first.
+func (w *Writer) backups() ([]backup, error) {
+	entries, err := os.ReadDir(filepath.Dir(w.config.Filename))
+	if err != nil {
+		return nil, fmt.Errorf("rotate: read directory: %w", err)
+	}
+
+	prefix, ext := w.nameParts()
+	loc := time.Local
+	if w.config.UseUTC {
+		loc = time.UTC
+	}
+
+	var backups []backup
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		name := strings.TrimSuffix(e.Name(), compressSuffix)
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		ts := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)
+		seq := 0
+		if i := strings.LastIndexByte(ts, '_'); i >= 0 {
+			if seq, err = strconv.Atoi(ts[i+1:]); err != nil || seq <= 0 {
+				continue
+			}
+			ts = ts[:i]
+		}
+		t, err := time.ParseInLocation(BackupTimeFormat, ts, loc)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{
+			path:      filepath.Join(filepath.Dir(w.config.Filename), e.Name()),
+			timestamp: t,
+			seq:       seq,
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].timestamp.Equal(backups[j].timestamp) {
+			return backups[i].timestamp.After(backups[j].timestamp)
+		}
+		return backups[i].seq > backups[j].seq
+	})
+	return backups, nil
+}
+
+// compressFile gzips src into src.gz and removes src on success.
+func compressFile(src string) error {
+	dst := src + compressSuffix
+	if err := gzipFile(src, dst); err != nil {
+		_ = os.Remove(dst)
+		return fmt.Errorf("rotate: compress backup: %w", err)
+	}
+	return os.Remove(src)
+}
+
+func gzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, fileMode)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,207 @@
+package rotate
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is read by the mill goroutine while tests advance it.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestWriter(t *testing.T, config Config) (*Writer, *fakeClock) {
+	t.Helper()
+
+	clock := &fakeClock{now: time.Date(2024, 1, 20, 15, 4, 5, 0, time.UTC)}
+	config.UseUTC = true
+	if config.Filename == "" {
+		config.Filename = filepath.Join(t.TempDir(), "app.log")
+	}
+
+	w := New(config)
+	w.now = clock.Now
+	return w, clock
+}
+
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestWriter_RotatesBySize(t *testing.T) {
+	w, clock := newTestWriter(t, Config{MaxSize: 10})
+	dir := filepath.Dir(w.config.Filename)
+
+	_, err := w.Write([]byte("123456\n"))
+	require.NoError(t, err)
+	clock.Advance(time.Second)
+	_, err = w.Write([]byte("abcdef\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	assert.ElementsMatch(t, []string{"app.log", "app-2024-01-20T15-04-06.000.log"}, listDir(t, dir))
+
+	current, err := os.ReadFile(w.config.Filename)
+	require.NoError(t, err)
+	assert.Equal(t, "abcdef\n", string(current))
+
+	backup, err := os.ReadFile(filepath.Join(dir, "app-2024-01-20T15-04-06.000.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "123456\n", string(backup))
+}
+
+func TestWriter_RotatesWithinOneMillisecond(t *testing.T) {
+	w, _ := newTestWriter(t, Config{MaxSize: 10, MaxBackups: 5})
+	dir := filepath.Dir(w.config.Filename)
+
+	// The clock is frozen: every rotation happens at the same instant.
+	for _, entry := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err := w.Write([]byte(entry))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+
+	assert.ElementsMatch(t, []string{
+		"app.log",
+		"app-2024-01-20T15-04-05.000.log",
+		"app-2024-01-20T15-04-05.000_1.log",
+		"app-2024-01-20T15-04-05.000_2.log",
+	}, listDir(t, dir))
+	for name, want := range map[string]string{
+		"app-2024-01-20T15-04-05.000.log":   "first\n",
+		"app-2024-01-20T15-04-05.000_1.log": "second\n",
+		"app-2024-01-20T15-04-05.000_2.log": "third\n",
+	} {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		assert.Equal(t, want, string(content), name)
+	}
+
+	backups, err := w.backups()
+	require.NoError(t, err)
+	require.Len(t, backups, 3)
+	assert.Equal(t, "app-2024-01-20T15-04-05.000_2.log", filepath.Base(backups[0].path), "newest first")
+	assert.Equal(t, "app-2024-01-20T15-04-05.000.log", filepath.Base(backups[2].path))
+}
+
+func TestWriter_RotatesByInterval(t *testing.T) {
+	w, clock := newTestWriter(t, Config{Interval: time.Hour})
+	dir := filepath.Dir(w.config.Filename)
+
+	_, err := w.Write([]byte("first\n"))
+	require.NoError(t, err)
+	clock.Advance(30 * time.Minute)
+	_, err = w.Write([]byte("second\n"))
+	require.NoError(t, err)
+	assert.Len(t, listDir(t, dir), 1)
+
+	clock.Advance(30 * time.Minute)
+	_, err = w.Write([]byte("third\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	assert.Len(t, listDir(t, dir), 2)
+	current, err := os.ReadFile(w.config.Filename)
+	require.NoError(t, err)
+	assert.Equal(t, "third\n", string(current))
+}
+
+func TestWriter_MaxBackupsAndCompression(t *testing.T) {
+	w, clock := newTestWriter(t, Config{MaxBackups: 2, Compress: true})
+	dir := filepath.Dir(w.config.Filename)
+
+	for i := 0; i < 4; i++ {
+		_, err := w.Write([]byte("entry\n"))
+		require.NoError(t, err)
+		clock.Advance(time.Second)
+		require.NoError(t, w.Rotate())
+	}
+	require.NoError(t, w.Close())
+
+	names := listDir(t, dir)
+	assert.Len(t, names, 3)
+	assert.Contains(t, names, "app-2024-01-20T15-04-09.000.log.gz")
+	assert.Contains(t, names, "app-2024-01-20T15-04-08.000.log.gz")
+
+	f, err := os.Open(filepath.Join(dir, "app-2024-01-20T15-04-09.000.log.gz"))
+	require.NoError(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	content, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	assert.Equal(t, "entry\n", string(content))
+}
+
+func TestWriter_MaxAge(t *testing.T) {
+	w, clock := newTestWriter(t, Config{MaxAge: time.Hour})
+	dir := filepath.Dir(w.config.Filename)
+
+	_, err := w.Write([]byte("old\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Rotate())
+
+	clock.Advance(2 * time.Hour)
+	_, err = w.Write([]byte("new\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Rotate())
+	require.NoError(t, w.Close())
+
+	names := listDir(t, dir)
+	assert.ElementsMatch(t, []string{"app.log", "app-2024-01-20T17-04-05.000.log"}, names)
+}
+
+func TestWriter_AppendsToExistingFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(filename, []byte("existing\n"), 0o600))
+
+	w, _ := newTestWriter(t, Config{Filename: filename, MaxSize: 100})
+	_, err := w.Write([]byte("appended\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	content, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	assert.Equal(t, "existing\nappended\n", string(content))
+}
+
+func TestWriter_WriteAfterClose(t *testing.T) {
+	w, _ := newTestWriter(t, Config{})
+
+	require.NoError(t, w.Close())
+
+	_, err := w.Write([]byte("late\n"))
+	assert.ErrorIs(t, err, ErrClosed)
+	assert.True(t, strings.HasPrefix(err.Error(), "rotate:"))
+}
@@ -0,0 +1,464 @@
+// Package sentry provides a logger.Hook forwarding Error, Fatal and Panic
+// entries to Sentry, or to any service accepting Sentry envelopes such as
+// GlitchTip, so that error tracking needs no instrumentation besides the
+// logging already in place.
+//
+// Each entry becomes an event carrying the message, the fields as extra
+// data, an exception built from the "error" field, the stack of the
+// logging goroutine, and the trace context from "trace_id" and "span_id"
+// fields. Events are sent from a background goroutine through a bounded
+// queue, except Fatal and Panic ones, which are sent before the program
+// exits or panics.
+//
+// Example usage:
+//
+//	hook, err := sentry.New(sentry.Config{
+//		DSN:         "https://public@o0.ingest.sentry.io/42",
+//		Environment: "prod",
+//		SampleRate:  0.5,
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	defer hook.Close()
+//
+//	log.AddHook(hook)
+//
+// Hooks only see the fields passed at the call site and those of
+// Config.ContextExtractors; fields bound with Logger.With are not part of
+// the events.
+package sentry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	mathrand "math/rand/v2"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// Defaults applied by New to unset Config fields.
+const (
+	DefaultQueueSize  = 100
+	DefaultTimeout    = 5 * time.Second
+	DefaultTraceIDKey = "trace_id"
+	DefaultSpanIDKey  = "span_id"
+)
+
+// loggerPackage prefixes the functions of the logger package, whose frames
+// are left out of the stack traces.
+const loggerPackage = "github.com/barnowlsnest/go-logslib/pkg/logger."
+
+// Config configures a Hook.
+type Config struct {
+	// DSN is the client key of the project, such as
+	// "https://public@o0.ingest.sentry.io/42".
+	DSN string
+
+	// Environment, Release and ServerName are set on every event.
+	// ServerName defaults to the host name.
+	Environment string
+	Release     string
+	ServerName  string
+
+	// Tags are set on every event.
+	Tags map[string]string
+
+	// SampleRate is the fraction of Error entries sent, between 0 and 1.
+	// Fatal and Panic entries are always sent. Defaults to 1.
+	SampleRate float64
+
+	// Fingerprint, when not nil, returns the fingerprint grouping the
+	// event of an entry into an issue. Returning nil keeps the default
+	// grouping of Sentry, by stack trace; "{{ default }}" can be combined
+	// with other parts to refine it, as in
+	// []string{"{{ default }}", e.Message}.
+	Fingerprint func(e *logger.Entry) []string
+
+	// TraceIDKey and SpanIDKey are the keys of the fields holding the
+	// trace context. They default to DefaultTraceIDKey and
+	// DefaultSpanIDKey.
+	TraceIDKey string
+	SpanIDKey  string
+
+	// QueueSize is the maximum number of events waiting to be sent.
+	// Further events are dropped. Defaults to DefaultQueueSize.
+	QueueSize int
+
+	// Timeout bounds each request. Defaults to DefaultTimeout.
+	Timeout time.Duration
+
+	// HTTPClient sends the events. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	// ErrorHandler, when not nil, is called with the error of every event
+	// that could not be sent.
+	ErrorHandler func(err error)
+}
+
+// Hook is a logger.Hook sending events to Sentry. It is safe for
+// concurrent use.
+type Hook struct {
+	config   Config
+	endpoint string
+	auth     string
+
+	mu      sync.RWMutex
+	closed  bool
+	queue   chan []byte
+	pending sync.WaitGroup
+	stopped chan struct{}
+	dropped atomic.Uint64
+}
+
+// New validates config and returns a Hook sending to the project of
+// config.DSN, with its background goroutine started.
+func New(config Config) (*Hook, error) {
+	dsn, err := url.Parse(config.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("sentry: invalid DSN: %w", err)
+	}
+	project := strings.Trim(dsn.Path, "/")
+	if dsn.User == nil || dsn.User.Username() == "" || project == "" || dsn.Host == "" {
+		return nil, errors.New("sentry: DSN must be like https://key@host/project")
+	}
+	prefix, project, _ := cutLast(project, "/")
+
+	if config.SampleRate <= 0 || config.SampleRate > 1 {
+		config.SampleRate = 1
+	}
+	if config.ServerName == "" {
+		config.ServerName, _ = os.Hostname()
+	}
+	if config.TraceIDKey == "" {
+		config.TraceIDKey = DefaultTraceIDKey
+	}
+	if config.SpanIDKey == "" {
+		config.SpanIDKey = DefaultSpanIDKey
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultQueueSize
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+
+	endpoint := dsn.Scheme + "://" + dsn.Host
+	if prefix != "" {
+		endpoint += "/" + prefix
+	}
+	h := &Hook{
+		config:   config,
+		endpoint: endpoint + "/api/" + project + "/envelope/",
+		auth:     "Sentry sentry_version=7, sentry_client=go-logslib, sentry_key=" + dsn.User.Username(),
+		queue:    make(chan []byte, config.QueueSize),
+		stopped:  make(chan struct{}),
+	}
+	go h.run()
+	return h, nil
+}
+
+// cutLast slices s around the last instance of sep, returning the text
+// before and after it; without sep, it returns "", s.
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return "", s, false
+}
+
+// Run implements logger.Hook. It sends an event for entries at ErrorLevel
+// and above, and leaves the entry unchanged.
+func (h *Hook) Run(e *logger.Entry) error {
+	if e.Level < logger.ErrorLevel {
+		return nil
+	}
+	urgent := e.Level >= logger.FatalLevel
+	if !urgent && h.config.SampleRate < 1 && mathrand.Float64() >= h.config.SampleRate {
+		return nil
+	}
+
+	body := h.envelope(h.event(e, stackFrames()))
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.closed {
+		return nil
+	}
+	if urgent {
+		h.report(h.send(body))
+		return nil
+	}
+	h.pending.Add(1)
+	select {
+	case h.queue <- body:
+	default:
+		h.pending.Done()
+		h.dropped.Add(1)
+	}
+	return nil
+}
+
+// Flush waits until the queued events are sent.
+func (h *Hook) Flush() {
+	h.pending.Wait()
+}
+
+// Close sends the queued events and stops the background goroutine.
+// Entries logged after Close are not sent.
+func (h *Hook) Close() error {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil
+	}
+	h.closed = true
+	close(h.queue)
+	h.mu.Unlock()
+
+	<-h.stopped
+	return nil
+}
+
+// Dropped returns the number of events dropped because the queue was full.
+func (h *Hook) Dropped() uint64 {
+	return h.dropped.Load()
+}
+
+// run sends the queued events until the queue is closed.
+func (h *Hook) run() {
+	defer close(h.stopped)
+	for body := range h.queue {
+		h.report(h.send(body))
+		h.pending.Done()
+	}
+}
+
+// report passes err, if any, to Config.ErrorHandler.
+func (h *Hook) report(err error) {
+	if err != nil && h.config.ErrorHandler != nil {
+		h.config.ErrorHandler(err)
+	}
+}
+
+// event is the subset of the Sentry event payload sent by the hook.
+type event struct {
+	EventID     string                     `json:"event_id"`
+	Timestamp   string                     `json:"timestamp"`
+	Platform    string                     `json:"platform"`
+	Level       string                     `json:"level"`
+	Message     *message                   `json:"message,omitempty"`
+	Environment string                     `json:"environment,omitempty"`
+	Release     string                     `json:"release,omitempty"`
+	ServerName  string                     `json:"server_name,omitempty"`
+	Tags        map[string]string          `json:"tags,omitempty"`
+	Extra       map[string]json.RawMessage `json:"extra,omitempty"`
+	Fingerprint []string                   `json:"fingerprint,omitempty"`
+	Exception   []exception                `json:"exception,omitempty"`
+	Contexts    map[string]traceContext    `json:"contexts,omitempty"`
+}
+
+type message struct {
+	Formatted string `json:"formatted"`
+}
+
+type exception struct {
+	Type       string      `json:"type"`
+	Value      string      `json:"value,omitempty"`
+	Stacktrace *stacktrace `json:"stacktrace,omitempty"`
+}
+
+type stacktrace struct {
+	Frames []frame `json:"frames"`
+}
+
+type frame struct {
+	Function string `json:"function"`
+	Module   string `json:"module,omitempty"`
+	Filename string `json:"filename"`
+	AbsPath  string `json:"abs_path"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+type traceContext struct {
+	TraceID string `json:"trace_id"`
+	SpanID  string `json:"span_id,omitempty"`
+}
+
+// event builds the event of e with the stack frames given.
+func (h *Hook) event(e *logger.Entry, frames []frame) *event {
+	ev := &event{
+		EventID:     newEventID(),
+		Timestamp:   e.Time.UTC().Format(time.RFC3339Nano),
+		Platform:    "go",
+		Level:       "error",
+		Message:     &message{Formatted: e.Message},
+		Environment: h.config.Environment,
+		Release:     h.config.Release,
+		ServerName:  h.config.ServerName,
+		Tags:        h.config.Tags,
+	}
+	if e.Level >= logger.FatalLevel {
+		ev.Level = "fatal"
+	}
+	if h.config.Fingerprint != nil {
+		ev.Fingerprint = h.config.Fingerprint(e)
+	}
+
+	exc := exception{Type: e.Message}
+	var trace traceContext
+	for _, f := range e.Fields {
+		switch f.Key {
+		case logger.DefaultStacktraceKey:
+			continue
+		case h.config.TraceIDKey:
+			trace.TraceID = fmt.Sprint(f.Interface())
+		case h.config.SpanIDKey:
+			trace.SpanID = fmt.Sprint(f.Interface())
+		}
+		if err, ok := f.Interface().(error); ok && err != nil && exc.Value == "" {
+			exc.Type = fmt.Sprintf("%T", err)
+			exc.Value = err.Error()
+		}
+		if ev.Extra == nil {
+			ev.Extra = make(map[string]json.RawMessage, len(e.Fields))
+		}
+		ev.Extra[f.Key] = f.AppendJSONValue(nil)
+	}
+	if len(frames) > 0 {
+		exc.Stacktrace = &stacktrace{Frames: frames}
+	}
+	ev.Exception = []exception{exc}
+	if trace.TraceID != "" {
+		ev.Contexts = map[string]traceContext{"trace": trace}
+	}
+	return ev
+}
+
+// envelope returns the envelope carrying ev.
+func (h *Hook) envelope(ev *event) []byte {
+	// The event only holds strings and JSON written by the logger.
+	payload, _ := json.Marshal(ev)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `{"event_id":%q,"sent_at":%q}`+"\n", ev.EventID, time.Now().UTC().Format(time.RFC3339Nano))
+	fmt.Fprintf(&buf, `{"type":"event","length":%d}`+"\n", len(payload))
+	buf.Write(payload)
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
+
+// send posts an envelope to the envelope endpoint.
+func (h *Hook) send(body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("sentry: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", h.auth)
+
+	resp, err := h.config.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("sentry: %w", err)
+	}
+	defer resp.Body.Close()
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("sentry: send failed with HTTP status %d: %s", resp.StatusCode, msg)
+	}
+	return nil
+}
+
+// newEventID returns a random event ID, 32 hex digits.
+func newEventID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// stackFrames returns the stack of the logging goroutine above the logger
+// package, outermost call first as Sentry expects.
+func stackFrames() []frame {
+	var pcs [64]uintptr
+	n := runtime.Callers(2, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var stack []frame
+	for {
+		f, more := frames.Next()
+		if strings.HasPrefix(f.Function, loggerPackage) {
+			// The frames collected so far are those of the hook and of
+			// the logger itself.
+			stack = stack[:0]
+		} else {
+			module, function := splitFunction(f.Function)
+			stack = append(stack, frame{
+				Function: function,
+				Module:   module,
+				Filename: trimPath(f.File),
+				AbsPath:  f.File,
+				Lineno:   f.Line,
+				InApp:    !standard(module),
+			})
+		}
+		if !more {
+			break
+		}
+	}
+	slices.Reverse(stack)
+	return stack
+}
+
+// splitFunction splits a function name such as "example.com/app/db.(*DB).Query"
+// into its package path and its name.
+func splitFunction(name string) (module, function string) {
+	slash := strings.LastIndexByte(name, '/')
+	if i := strings.IndexByte(name[slash+1:], '.'); i >= 0 {
+		i += slash + 1
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+// standard reports whether module is a package of the standard library,
+// whose path has no dot in its first element.
+func standard(module string) bool {
+	first, _, _ := strings.Cut(module, "/")
+	return !strings.Contains(first, ".")
+}
+
+// trimPath keeps the last directory and the file name of a path, as the
+// logger does for callers.
+func trimPath(file string) string {
+	i := strings.LastIndexByte(file, '/')
+	if i < 0 {
+		return file
+	}
+	if j := strings.LastIndexByte(file[:i], '/'); j >= 0 {
+		return file[j+1:]
+	}
+	return file
+}
@@ -0,0 +1,230 @@
+package sentry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// collector is a Sentry envelope endpoint recording the events it
+// receives.
+type collector struct {
+	t      *testing.T
+	mu     sync.Mutex
+	events []map[string]interface{}
+	paths  []string
+	auth   []string
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	require.NoError(c.t, err)
+
+	// Header, item header, payload.
+	lines := bytes.Split(bytes.TrimSuffix(body, []byte("\n")), []byte("\n"))
+	require.Len(c.t, lines, 3)
+	var item struct {
+		Type   string `json:"type"`
+		Length int    `json:"length"`
+	}
+	require.NoError(c.t, json.Unmarshal(lines[1], &item))
+	assert.Equal(c.t, "event", item.Type)
+	assert.Equal(c.t, len(lines[2]), item.Length)
+
+	var ev map[string]interface{}
+	require.NoError(c.t, json.Unmarshal(lines[2], &ev))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.events = append(c.events, ev)
+	c.paths = append(c.paths, r.URL.Path)
+	c.auth = append(c.auth, r.Header.Get("X-Sentry-Auth"))
+}
+
+func (c *collector) received() []map[string]interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]map[string]interface{}(nil), c.events...)
+}
+
+func newHook(t *testing.T, config Config) (*Hook, *collector) {
+	c := &collector{t: t}
+	srv := httptest.NewServer(c)
+	t.Cleanup(srv.Close)
+
+	config.DSN = strings.Replace(srv.URL, "://", "://public@", 1) + "/sentry/42"
+	h, err := New(config)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = h.Close() })
+	return h, c
+}
+
+func newLogger(h *Hook) *logger.Logger {
+	log := logger.New(logger.Config{Level: logger.InfoLevel, Output: io.Discard})
+	log.AddHook(h)
+	return log
+}
+
+func logError(log *logger.Logger) {
+	log.Error("charge failed",
+		logger.Err(errors.New("card declined")),
+		logger.String("trace_id", "4bf92f3577b34da6a3ce929d0e0e4736"),
+		logger.String("span_id", "00f067aa0ba902b7"),
+		logger.Int("amount", 42))
+}
+
+func TestHook_Event(t *testing.T) {
+	h, c := newHook(t, Config{
+		Environment: "prod",
+		Release:     "1.2.3",
+		ServerName:  "web-1",
+		Tags:        map[string]string{"region": "eu"},
+		Fingerprint: func(e *logger.Entry) []string { return []string{"{{ default }}", e.Message} },
+	})
+	log := newLogger(h)
+
+	log.Info("ignored")
+	log.Warn("ignored")
+	logError(log)
+	h.Flush()
+
+	events := c.received()
+	require.Len(t, events, 1)
+	ev := events[0]
+	assert.Equal(t, "/sentry/api/42/envelope/", c.paths[0])
+	assert.Contains(t, c.auth[0], "sentry_key=public")
+
+	assert.Len(t, ev["event_id"], 32)
+	assert.Equal(t, "error", ev["level"])
+	assert.Equal(t, "go", ev["platform"])
+	assert.Equal(t, "prod", ev["environment"])
+	assert.Equal(t, "1.2.3", ev["release"])
+	assert.Equal(t, "web-1", ev["server_name"])
+	assert.Equal(t, map[string]interface{}{"region": "eu"}, ev["tags"])
+	assert.Equal(t, map[string]interface{}{"formatted": "charge failed"}, ev["message"])
+	assert.Equal(t, []interface{}{"{{ default }}", "charge failed"}, ev["fingerprint"])
+	assert.Equal(t, map[string]interface{}{
+		"trace": map[string]interface{}{"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736", "span_id": "00f067aa0ba902b7"},
+	}, ev["contexts"])
+
+	extra := ev["extra"].(map[string]interface{})
+	assert.Equal(t, "card declined", extra["error"])
+	assert.Equal(t, float64(42), extra["amount"])
+
+	exc := ev["exception"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "*errors.errorString", exc["type"])
+	assert.Equal(t, "card declined", exc["value"])
+
+	frames := exc["stacktrace"].(map[string]interface{})["frames"].([]interface{})
+	require.NotEmpty(t, frames)
+	last := frames[len(frames)-1].(map[string]interface{})
+	assert.Equal(t, "logError", last["function"], "the innermost frame is the call site of the logger")
+	assert.Equal(t, "github.com/barnowlsnest/go-logslib/pkg/sentry", last["module"])
+	assert.Equal(t, "sentry/sentry_test.go", last["filename"])
+	assert.Equal(t, true, last["in_app"])
+	for _, f := range frames {
+		assert.NotContains(t, f.(map[string]interface{})["module"], "pkg/logger")
+	}
+}
+
+func TestHook_Sampling(t *testing.T) {
+	h, c := newHook(t, Config{SampleRate: 0.000001})
+	log := newLogger(h)
+
+	for i := 0; i < 10; i++ {
+		logError(log)
+	}
+	h.Flush()
+	assert.Empty(t, c.received())
+}
+
+func TestHook_FatalIsSentSynchronously(t *testing.T) {
+	h, c := newHook(t, Config{SampleRate: 0.000001})
+	log := newLogger(h)
+
+	assert.Panics(t, func() { log.Panic("boom") })
+	events := c.received()
+	require.Len(t, events, 1, "sent before returning, regardless of sampling")
+	assert.Equal(t, "fatal", events[0]["level"])
+	exc := events[0]["exception"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "boom", exc["type"])
+}
+
+func TestHook_QueueFull(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+
+	var errs []error
+	h, err := New(Config{
+		DSN:          strings.Replace(srv.URL, "://", "://key@", 1) + "/1",
+		QueueSize:    2,
+		ErrorHandler: func(err error) { errs = append(errs, err) },
+	})
+	require.NoError(t, err)
+	log := newLogger(h)
+
+	// One event in flight, two queued.
+	for i := 0; i < 6; i++ {
+		logError(log)
+	}
+	assert.GreaterOrEqual(t, h.Dropped(), uint64(3))
+	close(release)
+	require.NoError(t, h.Close())
+	assert.Empty(t, errs)
+
+	logError(log)
+}
+
+func TestHook_SendError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "rate limited", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	errs := make(chan error, 1)
+	h, err := New(Config{
+		DSN:          strings.Replace(srv.URL, "://", "://key@", 1) + "/1",
+		ErrorHandler: func(err error) { errs <- err },
+	})
+	require.NoError(t, err)
+	defer h.Close()
+
+	logError(newLogger(h))
+	assert.ErrorContains(t, <-errs, "429")
+}
+
+func TestNew_InvalidDSN(t *testing.T) {
+	for _, dsn := range []string{"", "https://host/1", "https://key@host", "://"} {
+		_, err := New(Config{DSN: dsn})
+		assert.Error(t, err, dsn)
+	}
+}
+
+func TestSplitFunction(t *testing.T) {
+	tests := []struct{ name, module, function string }{
+		{"example.com/app/db.(*DB).Query", "example.com/app/db", "(*DB).Query"},
+		{"main.main", "main", "main"},
+		{"net/http.HandlerFunc.ServeHTTP", "net/http", "HandlerFunc.ServeHTTP"},
+	}
+	for _, tt := range tests {
+		module, function := splitFunction(tt.name)
+		assert.Equal(t, tt.module, module)
+		assert.Equal(t, tt.function, function)
+	}
+	assert.True(t, standard("net/http"))
+	assert.False(t, standard("example.com/app"))
+}
@@ -0,0 +1,507 @@
+// Package spool provides an io.Writer that passes log entries to another
+// writer, typically a network sink, and spools them to a bounded queue on
+// disk while that writer fails, so that transient outages of the remote
+// end do not lose logs.
+//
+// Entries spooled are replayed in order by a background goroutine, one
+// entry per Write, once the writer accepts them again; new entries go to
+// the spool until it is empty, keeping their order. Segments left on disk
+// by a previous run are replayed too, so an entry may be sent twice if the
+// program stops while replaying.
+//
+// Example usage:
+//
+//	nw, err := netwriter.New(netwriter.Config{URL: "tcp://logs.example.com:5170", Overflow: netwriter.Drop})
+//	if err != nil {
+//		return err
+//	}
+//	w, err := spool.New(nw, spool.Config{Dir: "/var/spool/app-logs"})
+//	if err != nil {
+//		return err
+//	}
+//	defer w.Close()
+//
+//	log := logger.New(logger.Config{Format: logger.JSONFormat, Output: w})
+//
+// The spool only helps with writers whose Write fails while the remote end
+// is unavailable, such as netwriter.Writer under the Drop policy or a
+// net.Conn.
+package spool
+
+import (
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults applied by New to unset Config fields.
+const (
+	DefaultMaxBytes      = 256 << 20
+	DefaultSegmentSize   = 4 << 20
+	DefaultRetryInterval = 5 * time.Second
+)
+
+// ErrClosed is returned by Write after Close has been called.
+var ErrClosed = errors.New("spool: writer is closed")
+
+// ErrFull is returned by Write when an entry does not fit in the spool,
+// even after dropping its oldest segments. The entry is discarded.
+var ErrFull = errors.New("spool: spool is full")
+
+// headerLen is the size of the length prefix of each spooled entry.
+const headerLen = 4
+
+// Config configures a Writer.
+type Config struct {
+	// Dir is the directory holding the spool, created if needed. It must
+	// not be shared with another Writer.
+	Dir string
+
+	// MaxBytes bounds the size of the spool on disk. When an entry does
+	// not fit, the oldest segments are dropped to make room. Defaults to
+	// DefaultMaxBytes.
+	MaxBytes int64
+
+	// SegmentSize is the size of the files the spool is split into, which
+	// are deleted once replayed. Defaults to DefaultSegmentSize, or
+	// MaxBytes if smaller.
+	SegmentSize int64
+
+	// RetryInterval is the delay between attempts to replay the spool.
+	// Defaults to DefaultRetryInterval.
+	RetryInterval time.Duration
+
+	// ErrorHandler, when not nil, is called with the errors of the writer
+	// and of the spool, which are otherwise only retried or counted.
+	ErrorHandler func(err error)
+}
+
+// Writer is an io.Writer passing entries to another writer, spooling them
+// to disk while it fails. It is safe for concurrent use; writes to the
+// underlying writer are serialized.
+type Writer struct {
+	next   io.Writer
+	config Config
+
+	mu       sync.Mutex
+	closed   bool
+	segments []*segment
+	size     int64
+	seq      uint64
+	// active is the segment entries are appended to, the last one.
+	active  *os.File
+	dropped uint64
+
+	kick    chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// segment is a file of the spool, holding entries each prefixed with their
+// big-endian length.
+type segment struct {
+	path    string
+	size    int64
+	entries int
+
+	// reader, offset and replayed track the replay of the oldest segment.
+	reader   *os.File
+	offset   int64
+	replayed int
+}
+
+// New returns a Writer passing entries to next, with its spool in
+// config.Dir. Entries spooled by a previous Writer in the directory are
+// replayed first.
+func New(next io.Writer, config Config) (*Writer, error) {
+	if config.Dir == "" {
+		return nil, errors.New("spool: missing directory")
+	}
+	if config.MaxBytes <= 0 {
+		config.MaxBytes = DefaultMaxBytes
+	}
+	if config.SegmentSize <= 0 {
+		config.SegmentSize = DefaultSegmentSize
+	}
+	config.SegmentSize = min(config.SegmentSize, config.MaxBytes)
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = DefaultRetryInterval
+	}
+	if err := os.MkdirAll(config.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("spool: %w", err)
+	}
+
+	w := &Writer{
+		next:    next,
+		config:  config,
+		kick:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	if err := w.load(); err != nil {
+		_ = w.closeFiles()
+		return nil, err
+	}
+	if len(w.segments) > 0 {
+		w.kick <- struct{}{}
+	}
+	go w.run()
+	return w, nil
+}
+
+// load opens the segments found in the directory, oldest first, cutting
+// off entries torn by a crash.
+func (w *Writer) load() error {
+	paths, err := filepath.Glob(filepath.Join(w.config.Dir, "spool-*.log"))
+	if err != nil {
+		return fmt.Errorf("spool: %w", err)
+	}
+	type numbered struct {
+		path string
+		seq  uint64
+	}
+	var found []numbered
+	for _, path := range paths {
+		name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "spool-"), ".log")
+		if seq, err := strconv.ParseUint(name, 10, 64); err == nil {
+			found = append(found, numbered{path, seq})
+		}
+	}
+	slices.SortFunc(found, func(a, b numbered) int { return cmp.Compare(a.seq, b.seq) })
+
+	for _, f := range found {
+		seg, err := scanSegment(f.path)
+		if err != nil {
+			return err
+		}
+		w.seq = f.seq + 1
+		if seg.entries == 0 {
+			_ = os.Remove(f.path)
+			continue
+		}
+		w.segments = append(w.segments, seg)
+		w.size += seg.size
+	}
+
+	if len(w.segments) > 0 {
+		last := w.segments[len(w.segments)-1]
+		active, err := os.OpenFile(last.path, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			return fmt.Errorf("spool: %w", err)
+		}
+		w.active = active
+	}
+	return nil
+}
+
+// scanSegment counts the entries of the segment at path, truncating it
+// after the last complete one.
+func scanSegment(path string) (*segment, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("spool: %w", err)
+	}
+	seg := &segment{path: path}
+	for len(data)-int(seg.size) >= headerLen {
+		n := int64(binary.BigEndian.Uint32(data[seg.size:]))
+		if seg.size+headerLen+n > int64(len(data)) {
+			break
+		}
+		seg.size += headerLen + n
+		seg.entries++
+	}
+	if seg.size < int64(len(data)) {
+		if err := os.Truncate(path, seg.size); err != nil {
+			return nil, fmt.Errorf("spool: %w", err)
+		}
+	}
+	return seg, nil
+}
+
+// Write passes p to the underlying writer, or spools a copy of it when
+// that fails or when earlier entries are still spooled. It fails only if
+// the entry can be neither written nor spooled.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, ErrClosed
+	}
+	if len(w.segments) == 0 {
+		_, err := w.next.Write(p)
+		if err == nil {
+			return len(p), nil
+		}
+		w.report(err)
+	}
+
+	wasEmpty := len(w.segments) == 0
+	if err := w.append(p); err != nil {
+		w.report(err)
+		return 0, err
+	}
+	if wasEmpty {
+		select {
+		case w.kick <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// append spools p, dropping the oldest segments if it does not fit.
+// It must be called with w.mu held.
+func (w *Writer) append(p []byte) error {
+	n := int64(headerLen + len(p))
+	for w.size+n > w.config.MaxBytes && len(w.segments) > 1 {
+		w.dropOldest()
+	}
+	if w.size+n > w.config.MaxBytes || uint64(len(p)) > math.MaxUint32 {
+		w.dropped++
+		return ErrFull
+	}
+
+	last := len(w.segments) - 1
+	if last < 0 || w.segments[last].size+n > w.config.SegmentSize {
+		if err := w.addSegment(); err != nil {
+			w.dropped++
+			return err
+		}
+		last = len(w.segments) - 1
+	}
+
+	rec := make([]byte, headerLen, n)
+	binary.BigEndian.PutUint32(rec, uint32(len(p)))
+	rec = append(rec, p...)
+	if _, err := w.active.Write(rec); err != nil {
+		w.dropped++
+		return fmt.Errorf("spool: %w", err)
+	}
+	seg := w.segments[last]
+	seg.size += n
+	seg.entries++
+	w.size += n
+	return nil
+}
+
+// addSegment starts a new segment and makes it the active one.
+func (w *Writer) addSegment() error {
+	path := filepath.Join(w.config.Dir, fmt.Sprintf("spool-%020d.log", w.seq))
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("spool: %w", err)
+	}
+	if w.active != nil {
+		_ = w.active.Close()
+	}
+	w.active = f
+	w.seq++
+	w.segments = append(w.segments, &segment{path: path})
+	return nil
+}
+
+// dropOldest deletes the oldest segment, counting the entries it still
+// held as dropped.
+func (w *Writer) dropOldest() {
+	seg := w.segments[0]
+	w.dropped += uint64(seg.entries - seg.replayed)
+	w.removeOldest()
+}
+
+// removeOldest deletes the oldest segment.
+func (w *Writer) removeOldest() {
+	seg := w.segments[0]
+	if seg.reader != nil {
+		_ = seg.reader.Close()
+	}
+	if len(w.segments) == 1 && w.active != nil {
+		_ = w.active.Close()
+		w.active = nil
+	}
+	if err := os.Remove(seg.path); err != nil {
+		w.report(fmt.Errorf("spool: %w", err))
+	}
+	w.size -= seg.size
+	w.segments[0] = nil
+	w.segments = w.segments[1:]
+}
+
+// run replays the spool whenever it holds entries, retrying every
+// RetryInterval, until Close is called.
+func (w *Writer) run() {
+	defer close(w.stopped)
+
+	for {
+		select {
+		case <-w.kick:
+		case <-w.done:
+			return
+		}
+		for {
+			select {
+			case <-time.After(w.config.RetryInterval):
+			case <-w.done:
+				return
+			}
+			if w.replay() == nil {
+				break
+			}
+		}
+	}
+}
+
+// replay writes the spooled entries to the underlying writer, oldest
+// first, deleting each segment once written. It returns the first write
+// error, leaving the entry spooled for the next attempt.
+func (w *Writer) replay() error {
+	for {
+		w.mu.Lock()
+		if w.closed || len(w.segments) == 0 {
+			w.mu.Unlock()
+			return nil
+		}
+		seg := w.segments[0]
+		if seg.offset >= seg.size {
+			w.removeOldest()
+			w.mu.Unlock()
+			continue
+		}
+		entry, err := seg.read()
+		if err != nil {
+			w.report(err)
+			w.dropOldest()
+			w.mu.Unlock()
+			continue
+		}
+		w.mu.Unlock()
+
+		if _, err := w.next.Write(entry); err != nil {
+			w.report(err)
+			return err
+		}
+
+		w.mu.Lock()
+		// The segment may have been dropped to make room meanwhile.
+		if len(w.segments) > 0 && w.segments[0] == seg {
+			seg.offset += int64(headerLen + len(entry))
+			seg.replayed++
+		}
+		w.mu.Unlock()
+	}
+}
+
+// read returns the entry at the replay offset of seg.
+func (seg *segment) read() ([]byte, error) {
+	if seg.reader == nil {
+		f, err := os.Open(seg.path)
+		if err != nil {
+			return nil, fmt.Errorf("spool: %w", err)
+		}
+		seg.reader = f
+	}
+
+	var header [headerLen]byte
+	if _, err := seg.reader.ReadAt(header[:], seg.offset); err != nil {
+		return nil, fmt.Errorf("spool: %s: %w", seg.path, err)
+	}
+	n := int64(binary.BigEndian.Uint32(header[:]))
+	if seg.offset+headerLen+n > seg.size {
+		return nil, fmt.Errorf("spool: %s: corrupt entry at offset %d", seg.path, seg.offset)
+	}
+	entry := make([]byte, n)
+	if _, err := seg.reader.ReadAt(entry, seg.offset+headerLen); err != nil {
+		return nil, fmt.Errorf("spool: %s: %w", seg.path, err)
+	}
+	return entry, nil
+}
+
+// Spooled returns the number of entries waiting in the spool.
+func (w *Writer) Spooled() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n := 0
+	for _, seg := range w.segments {
+		n += seg.entries - seg.replayed
+	}
+	return n
+}
+
+// Dropped returns the number of entries discarded because the spool was
+// full or could not be written.
+func (w *Writer) Dropped() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.dropped
+}
+
+// Sync commits the active segment of the spool to stable storage.
+func (w *Writer) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.active == nil {
+		return nil
+	}
+	return w.active.Sync()
+}
+
+// Close stops replaying and closes the spool, leaving the entries still
+// spooled on disk for the next Writer in the directory. It does not close
+// the underlying writer.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+
+	close(w.done)
+	<-w.stopped
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.closeFiles()
+}
+
+// closeFiles closes the files of the spool.
+func (w *Writer) closeFiles() error {
+	var err error
+	if w.active != nil {
+		err = w.active.Sync()
+		if cerr := w.active.Close(); err == nil {
+			err = cerr
+		}
+		w.active = nil
+	}
+	for _, seg := range w.segments {
+		if seg.reader != nil {
+			_ = seg.reader.Close()
+			seg.reader = nil
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("spool: %w", err)
+	}
+	return nil
+}
+
+// report passes err to Config.ErrorHandler, if set.
+func (w *Writer) report(err error) {
+	if w.config.ErrorHandler != nil {
+		w.config.ErrorHandler(err)
+	}
+}
@@ -0,0 +1,193 @@
+package spool
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+var errDown = errors.New("collector down")
+
+// flakyWriter records each Write, failing while down is set.
+type flakyWriter struct {
+	mu      sync.Mutex
+	down    bool
+	entries []string
+}
+
+func (f *flakyWriter) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.down {
+		return 0, errDown
+	}
+	f.entries = append(f.entries, string(p))
+	return len(p), nil
+}
+
+func (f *flakyWriter) setDown(down bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.down = down
+}
+
+func (f *flakyWriter) received() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.entries...)
+}
+
+func segmentFiles(t *testing.T, dir string) []string {
+	files, err := filepath.Glob(filepath.Join(dir, "spool-*.log"))
+	require.NoError(t, err)
+	return files
+}
+
+func TestWriter_PassesThrough(t *testing.T) {
+	next := &flakyWriter{}
+	dir := t.TempDir()
+	w, err := New(next, Config{Dir: dir})
+	require.NoError(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("a\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a\n"}, next.received())
+	assert.Empty(t, segmentFiles(t, dir))
+}
+
+func TestWriter_SpoolsAndReplays(t *testing.T) {
+	next := &flakyWriter{down: true}
+	dir := t.TempDir()
+	var errs []error
+	var mu sync.Mutex
+	w, err := New(next, Config{
+		Dir:           dir,
+		SegmentSize:   64,
+		RetryInterval: 5 * time.Millisecond,
+		ErrorHandler: func(err error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		},
+	})
+	require.NoError(t, err)
+	defer w.Close()
+
+	var want []string
+	for i := 0; i < 20; i++ {
+		entry := "entry " + strconv.Itoa(i) + "\n"
+		want = append(want, entry)
+		n, err := w.Write([]byte(entry))
+		require.NoError(t, err)
+		assert.Equal(t, len(entry), n)
+	}
+	assert.Equal(t, 20, w.Spooled())
+	assert.Greater(t, len(segmentFiles(t, dir)), 1)
+
+	next.setDown(false)
+	_, err = w.Write([]byte("after\n"))
+	require.NoError(t, err)
+	want = append(want, "after\n")
+
+	require.Eventually(t, func() bool { return w.Spooled() == 0 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, want, next.received())
+	assert.Empty(t, segmentFiles(t, dir))
+	assert.Zero(t, w.Dropped())
+
+	_, err = w.Write([]byte("direct\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "direct\n", next.received()[len(want)])
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.NotEmpty(t, errs)
+	assert.ErrorIs(t, errs[0], errDown)
+}
+
+func TestWriter_DropsOldestWhenFull(t *testing.T) {
+	next := &flakyWriter{down: true}
+	w, err := New(next, Config{Dir: t.TempDir(), MaxBytes: 100, SegmentSize: 30, RetryInterval: time.Hour})
+	require.NoError(t, err)
+	defer w.Close()
+
+	// Each entry takes 10 bytes with its length prefix, 3 per segment.
+	for i := 0; i < 12; i++ {
+		_, err := w.Write([]byte("item " + strconv.Itoa(i%10)))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, uint64(3), w.Dropped())
+	assert.Equal(t, 9, w.Spooled())
+
+	_, err = w.Write([]byte(strings.Repeat("x", 200)))
+	assert.ErrorIs(t, err, ErrFull)
+	assert.Equal(t, uint64(3+6+1), w.Dropped(), "all but the active segment dropped trying to fit it")
+	assert.Equal(t, 3, w.Spooled())
+}
+
+func TestWriter_ReplaysAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	down := &flakyWriter{down: true}
+	w, err := New(down, Config{Dir: dir, RetryInterval: time.Hour})
+	require.NoError(t, err)
+	for _, entry := range []string{"one\n", "two\n"} {
+		_, err := w.Write([]byte(entry))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	_, err = w.Write([]byte("closed\n"))
+	assert.ErrorIs(t, err, ErrClosed)
+
+	// A torn entry at the end, as left by a crash, is cut off.
+	files := segmentFiles(t, dir)
+	require.Len(t, files, 1)
+	f, err := os.OpenFile(files[0], os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{0, 0, 0, 9, 't', 'o'})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	next := &flakyWriter{}
+	w, err = New(next, Config{Dir: dir, RetryInterval: time.Millisecond})
+	require.NoError(t, err)
+	defer w.Close()
+	assert.Equal(t, 2, w.Spooled())
+
+	_, err = w.Write([]byte("three\n"))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return w.Spooled() == 0 }, time.Second, time.Millisecond)
+	assert.Equal(t, []string{"one\n", "two\n", "three\n"}, next.received())
+	assert.Empty(t, segmentFiles(t, dir))
+}
+
+func TestWriter_Logger(t *testing.T) {
+	next := &flakyWriter{down: true}
+	w, err := New(next, Config{Dir: t.TempDir(), RetryInterval: time.Millisecond})
+	require.NoError(t, err)
+
+	log := logger.New(logger.Config{Level: logger.InfoLevel, Format: logger.TextFormat, Output: w, OmitTime: true, CloseOutputs: true})
+	log.Info("first")
+	log.Warn("second")
+	assert.Equal(t, uint64(0), log.Stats().Dropped)
+
+	next.setDown(false)
+	require.Eventually(t, func() bool { return w.Spooled() == 0 }, time.Second, time.Millisecond)
+	assert.Equal(t, []string{"INFO first\n", "WARN second\n"}, next.received())
+	require.NoError(t, log.Close())
+}
+
+func TestNew_RequiresDir(t *testing.T) {
+	_, err := New(&flakyWriter{}, Config{})
+	assert.Error(t, err)
+}
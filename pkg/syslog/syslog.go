@@ -0,0 +1,333 @@
+// Package syslog provides a logger sink that writes syslog messages to a
+// local or remote syslog daemon over a unix socket, UDP or TCP.
+//
+// Entries are formatted according to RFC 5424, with fields carried as
+// structured data, or according to the older BSD format of RFC 3164, with
+// fields appended to the message as key=value pairs. Logger levels are
+// mapped to syslog severities by SeverityOf.
+//
+// Example usage:
+//
+//	sink, w, err := syslog.NewSink(syslog.Config{
+//		Network:  "udp",
+//		Address:  "logs.example.com:514",
+//		Facility: syslog.Local0,
+//		AppName:  "api",
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	defer w.Close()
+//
+//	log := logger.New(logger.Config{
+//		Level: logger.InfoLevel,
+//		Sinks: []logger.Sink{sink},
+//	})
+package syslog
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// Facility is a syslog facility code.
+type Facility int
+
+// Syslog facilities as defined by RFC 5424.
+const (
+	Kern Facility = iota
+	User
+	Mail
+	Daemon
+	Auth
+	Syslog
+	LPR
+	News
+	UUCP
+	Cron
+	AuthPriv
+	FTP
+	_
+	_
+	_
+	_
+	Local0
+	Local1
+	Local2
+	Local3
+	Local4
+	Local5
+	Local6
+	Local7
+)
+
+// Severity is a syslog severity code.
+type Severity int
+
+// Syslog severities as defined by RFC 5424.
+const (
+	Emergency Severity = iota
+	Alert
+	Critical
+	Error
+	Warning
+	Notice
+	Informational
+	Debug
+)
+
+// Protocol selects the syslog message format.
+type Protocol int
+
+const (
+	// RFC5424 formats messages as described in RFC 5424, with fields
+	// written as structured data.
+	RFC5424 Protocol = iota
+	// RFC3164 formats messages in the BSD syslog format of RFC 3164, with
+	// fields appended to the message text.
+	RFC3164
+)
+
+// DefaultStructuredDataID is the SD-ID under which fields are written in
+// RFC 5424 messages when Config.StructuredDataID is empty.
+const DefaultStructuredDataID = "fields@32473"
+
+// nilValue is the RFC 5424 NILVALUE used for unknown header fields.
+const nilValue = "-"
+
+// Config holds the configuration for a syslog sink.
+type Config struct {
+	// Network is the transport: "udp", "tcp", "unix" or "unixgram" (and
+	// their 4/6 variants). An empty Network connects to the local syslog
+	// daemon through its unix socket.
+	Network string
+
+	// Address is the remote address or socket path. It is ignored when
+	// Network is empty.
+	Address string
+
+	// Facility is the facility all messages are logged under. Kern is
+	// reserved for the kernel, so the zero value is replaced by User.
+	Facility Facility
+
+	// Protocol selects the message format. Defaults to RFC5424.
+	Protocol Protocol
+
+	// Hostname identifies the machine. Defaults to os.Hostname.
+	Hostname string
+
+	// AppName identifies the application. Defaults to the program name.
+	AppName string
+
+	// StructuredDataID is the SD-ID of the element that carries fields in
+	// RFC 5424 messages. Defaults to DefaultStructuredDataID.
+	StructuredDataID string
+
+	// UseUTC formats timestamps in UTC instead of local time.
+	UseUTC bool
+}
+
+// SeverityOf returns the syslog severity for a logger level.
+func SeverityOf(level logger.Level) Severity {
+	switch {
+	case level <= logger.DebugLevel:
+		return Debug
+	case level == logger.InfoLevel:
+		return Informational
+	case level == logger.WarnLevel:
+		return Warning
+	case level == logger.ErrorLevel:
+		return Error
+	case level == logger.FatalLevel:
+		return Critical
+	default:
+		return Alert
+	}
+}
+
+// NewSink connects to the syslog daemon described by config and returns a
+// logger.Sink writing to it, along with the Writer so that the caller can
+// close the connection.
+func NewSink(config Config) (logger.Sink, *Writer, error) {
+	w, err := Dial(config.Network, config.Address)
+	if err != nil {
+		return logger.Sink{}, nil, err
+	}
+	return logger.Sink{Output: w, Encoder: NewEncoder(config)}, w, nil
+}
+
+// NewEncoder returns a logger.Encoder that formats entries as syslog
+// messages according to config.
+func NewEncoder(config Config) logger.Encoder {
+	if config.Facility == Kern {
+		config.Facility = User
+	}
+	if config.Hostname == "" {
+		config.Hostname, _ = os.Hostname()
+	}
+	if config.AppName == "" {
+		config.AppName = filepath.Base(os.Args[0])
+	}
+	if config.StructuredDataID == "" {
+		config.StructuredDataID = DefaultStructuredDataID
+	}
+
+	return &encoder{
+		config:   config,
+		hostname: headerValue(config.Hostname, 255),
+		appName:  headerValue(config.AppName, 48),
+		procID:   strconv.Itoa(os.Getpid()),
+	}
+}
+
+// encoder implements logger.Encoder for both syslog formats.
+type encoder struct {
+	config   Config
+	hostname string
+	appName  string
+	procID   string
+}
+
+// EncodeEntry implements logger.Encoder.
+func (enc *encoder) EncodeEntry(buf []byte, e *logger.Entry) []byte {
+	t := e.Time
+	if enc.config.UseUTC {
+		t = t.UTC()
+	}
+
+	pri := int(enc.config.Facility)*8 + int(SeverityOf(e.Level))
+	buf = append(buf, '<')
+	buf = strconv.AppendInt(buf, int64(pri), 10)
+	buf = append(buf, '>')
+
+	if enc.config.Protocol == RFC3164 {
+		return enc.encode3164(buf, t, e)
+	}
+	return enc.encode5424(buf, t, e)
+}
+
+// encode5424 appends "VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD MSG".
+func (enc *encoder) encode5424(buf []byte, t time.Time, e *logger.Entry) []byte {
+	buf = append(buf, "1 "...)
+	buf = t.AppendFormat(buf, "2006-01-02T15:04:05.000000Z07:00")
+	buf = append(buf, ' ')
+	buf = append(buf, enc.hostname...)
+	buf = append(buf, ' ')
+	buf = append(buf, enc.appName...)
+	buf = append(buf, ' ')
+	buf = append(buf, enc.procID...)
+	buf = append(buf, ' ')
+	buf = append(buf, nilValue...)
+	buf = append(buf, ' ')
+
+	if len(e.Context) == 0 && len(e.Fields) == 0 && e.Caller == nil {
+		buf = append(buf, nilValue...)
+	} else {
+		buf = append(buf, '[')
+		buf = append(buf, enc.config.StructuredDataID...)
+		if e.Caller != nil {
+			buf = appendParam(buf, "caller", e.Caller.File+":"+strconv.Itoa(e.Caller.Line))
+		}
+		buf = append(buf, e.Context...)
+		buf = enc.EncodeFields(buf, e.Fields)
+		buf = append(buf, ']')
+	}
+
+	if e.Message != "" {
+		buf = append(buf, ' ')
+		buf = append(buf, e.Message...)
+	}
+	return buf
+}
+
+// encode3164 appends "TIMESTAMP HOSTNAME TAG[PID]: MSG key=value...".
+func (enc *encoder) encode3164(buf []byte, t time.Time, e *logger.Entry) []byte {
+	buf = t.AppendFormat(buf, time.Stamp)
+	buf = append(buf, ' ')
+	buf = append(buf, enc.hostname...)
+	buf = append(buf, ' ')
+	buf = append(buf, enc.appName...)
+	buf = append(buf, '[')
+	buf = append(buf, enc.procID...)
+	buf = append(buf, "]: "...)
+	buf = append(buf, e.Message...)
+	if e.Caller != nil {
+		buf = append(buf, " caller="...)
+		buf = append(buf, e.Caller.File...)
+		buf = append(buf, ':')
+		buf = strconv.AppendInt(buf, int64(e.Caller.Line), 10)
+	}
+	buf = append(buf, e.Context...)
+	return enc.EncodeFields(buf, e.Fields)
+}
+
+// EncodeFields implements logger.Encoder. Fields are written as SD-PARAMs
+// for RFC 5424 and as key=value pairs for RFC 3164.
+func (enc *encoder) EncodeFields(buf []byte, fields []logger.Field) []byte {
+	for _, f := range fields {
+		if enc.config.Protocol == RFC3164 {
+			buf = append(buf, ' ')
+			buf = append(buf, f.Key...)
+			buf = append(buf, '=')
+			buf = f.AppendTextValue(buf)
+			continue
+		}
+
+		var value string
+		if s, ok := f.Interface().(string); ok {
+			value = s
+		} else {
+			value = string(f.AppendTextValue(nil))
+		}
+		buf = appendParam(buf, f.Key, value)
+	}
+	return buf
+}
+
+// appendParam appends an RFC 5424 SD-PARAM, sanitizing the name and
+// escaping the value.
+func appendParam(buf []byte, name, value string) []byte {
+	buf = append(buf, ' ')
+	n := 0
+	for i := 0; i < len(name) && n < 32; i++ {
+		c := name[i]
+		if c <= ' ' || c >= 0x7f || c == '=' || c == ']' || c == '"' {
+			c = '_'
+		}
+		buf = append(buf, c)
+		n++
+	}
+	if n == 0 {
+		buf = append(buf, '_')
+	}
+
+	buf = append(buf, '=', '"')
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '"', '\\', ']':
+			buf = append(buf, '\\', c)
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return append(buf, '"')
+}
+
+// headerValue returns s restricted to printable US-ASCII and at most max
+// bytes, as RFC 5424 requires for header fields, or the NILVALUE if empty.
+func headerValue(s string, max int) string {
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s) && len(b) < max; i++ {
+		if c := s[i]; c > ' ' && c < 0x7f {
+			b = append(b, c)
+		}
+	}
+	if len(b) == 0 {
+		return nilValue
+	}
+	return string(b)
+}
@@ -0,0 +1,196 @@
+package syslog
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+var testTime = time.Date(2024, 1, 20, 15, 4, 5, 123456000, time.UTC)
+
+func encode(config Config, e *logger.Entry) string {
+	config.Hostname = "host"
+	config.AppName = "app"
+	config.UseUTC = true
+	return string(NewEncoder(config).EncodeEntry(nil, e))
+}
+
+func TestSeverityOf(t *testing.T) {
+	assert.Equal(t, Debug, SeverityOf(logger.DebugLevel))
+	assert.Equal(t, Informational, SeverityOf(logger.InfoLevel))
+	assert.Equal(t, Warning, SeverityOf(logger.WarnLevel))
+	assert.Equal(t, Error, SeverityOf(logger.ErrorLevel))
+	assert.Equal(t, Critical, SeverityOf(logger.FatalLevel))
+	assert.Equal(t, Alert, SeverityOf(logger.PanicLevel))
+}
+
+func TestEncoder_RFC5424(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+
+	out := encode(Config{Facility: Local0}, &logger.Entry{
+		Time:    testTime,
+		Level:   logger.WarnLevel,
+		Message: "disk almost full",
+		Fields: []logger.Field{
+			logger.String("path", `/var/"data"]`),
+			logger.Int("percent", 93),
+		},
+	})
+
+	// Local0 (16) * 8 + Warning (4) = 132.
+	assert.Equal(t, "<132>1 2024-01-20T15:04:05.123456Z host app "+pid+
+		` - [fields@32473 path="/var/\"data\"\]" percent="93"] disk almost full`, out)
+}
+
+func TestEncoder_RFC5424_NoFields(t *testing.T) {
+	out := encode(Config{}, &logger.Entry{
+		Time:    testTime,
+		Level:   logger.InfoLevel,
+		Message: "started",
+	})
+
+	assert.Regexp(t, `^<14>1 \S+ host app \d+ - - started$`, out)
+}
+
+func TestEncoder_RFC5424_ContextAndCustomID(t *testing.T) {
+	enc := NewEncoder(Config{Hostname: "host", AppName: "app", StructuredDataID: "meta@1"})
+	ctx := enc.EncodeFields(nil, []logger.Field{logger.String("service", "api")})
+
+	out := string(enc.EncodeEntry(nil, &logger.Entry{
+		Time:    testTime,
+		Level:   logger.ErrorLevel,
+		Message: "failed",
+		Context: ctx,
+		Fields:  []logger.Field{logger.Bool("retry", true)},
+	}))
+
+	assert.Contains(t, out, `[meta@1 service="api" retry="true"] failed`)
+}
+
+func TestEncoder_RFC5424_SanitizesParamNames(t *testing.T) {
+	out := encode(Config{}, &logger.Entry{
+		Time:   testTime,
+		Level:  logger.InfoLevel,
+		Fields: []logger.Field{logger.String(`a b=c"d]`, "v")},
+	})
+
+	assert.Contains(t, out, `[fields@32473 a_b_c_d_="v"]`)
+}
+
+func TestEncoder_RFC3164(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+
+	out := encode(Config{Facility: Daemon, Protocol: RFC3164}, &logger.Entry{
+		Time:    testTime,
+		Level:   logger.ErrorLevel,
+		Message: "request failed",
+		Fields:  []logger.Field{logger.Int("status", 500)},
+	})
+
+	// Daemon (3) * 8 + Error (3) = 27.
+	assert.Equal(t, "<27>Jan 20 15:04:05 host app["+pid+"]: request failed status=500", out)
+}
+
+func TestWriter_UDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	w, err := Dial("udp", conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer w.Close()
+
+	log := logger.New(logger.Config{
+		Level: logger.InfoLevel,
+		Sinks: []logger.Sink{{Output: w, Encoder: NewEncoder(Config{AppName: "app"})}},
+	})
+	log.Info("first", logger.String("k", "v"))
+	log.Info("second")
+
+	buf := make([]byte, 1024)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.Regexp(t, `^<14>1 \S+ \S+ app \d+ - \[fields@32473 k="v"\] first$`, string(buf[:n]))
+
+	n, _, err = conn.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.Regexp(t, `^<14>1 \S+ \S+ app \d+ - - second$`, string(buf[:n]))
+}
+
+func TestWriter_TCPOctetCounting(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	w, err := Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	defer w.Close()
+
+	conn, err := ln.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = w.Write([]byte("<14>1 - - - - - - hello\n"))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("<14>1 - - - - - - world\n"))
+	require.NoError(t, err)
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	r := bufio.NewReader(conn)
+	for _, want := range []string{"<14>1 - - - - - - hello", "<14>1 - - - - - - world"} {
+		size, err := r.ReadString(' ')
+		require.NoError(t, err)
+		n, err := strconv.Atoi(size[:len(size)-1])
+		require.NoError(t, err)
+
+		msg := make([]byte, n)
+		_, err = r.Read(msg)
+		require.NoError(t, err)
+		assert.Equal(t, want, string(msg))
+	}
+}
+
+func TestWriter_Unixgram(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.sock")
+	conn, err := net.ListenPacket("unixgram", path)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	sink, w, err := NewSink(Config{Network: "unixgram", Address: path, Protocol: RFC3164})
+	require.NoError(t, err)
+	defer w.Close()
+
+	log := logger.New(logger.Config{Level: logger.InfoLevel, Sinks: []logger.Sink{sink}})
+	log.Warn("careful")
+
+	buf := make([]byte, 1024)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.Regexp(t, `^<12>\w{3} [ \d]\d \d\d:\d\d:\d\d \S+ \S+\[\d+\]: careful$`, string(buf[:n]))
+}
+
+func TestWriter_Closed(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	w, err := Dial("udp", conn.LocalAddr().String())
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	_, err = w.Write([]byte("msg"))
+	assert.True(t, errors.Is(err, ErrClosed))
+}
@@ -0,0 +1,150 @@
+package syslog
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+)
+
+// localSockets are the usual paths of the local syslog daemon's socket.
+var localSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// ErrNoLocalSyslog is returned by Dial when no local syslog socket accepts
+// connections.
+var ErrNoLocalSyslog = errors.New("syslog: no local syslog socket found")
+
+// ErrClosed is returned by Write after Close has been called.
+var ErrClosed = errors.New("syslog: writer is closed")
+
+// Writer is an io.Writer that sends each Write as one syslog message.
+//
+// Datagram transports send one message per packet. TCP uses the octet
+// counting framing of RFC 6587, and unix stream sockets terminate each
+// message with a newline. If a write fails, the Writer reconnects once
+// and retries.
+type Writer struct {
+	network string
+	address string
+
+	mu      sync.Mutex
+	conn    net.Conn
+	framing framing
+	closed  bool
+}
+
+// framing is the way messages are delimited on a connection.
+type framing uint8
+
+const (
+	noFraming framing = iota
+	octetCounting
+	newlineFraming
+)
+
+// framingOf returns the framing used on a network.
+func framingOf(network string) framing {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+		return octetCounting
+	case "unix":
+		return newlineFraming
+	default:
+		return noFraming
+	}
+}
+
+// Dial connects to the syslog daemon at address over network. An empty
+// network connects to the local syslog daemon.
+func Dial(network, address string) (*Writer, error) {
+	w := &Writer{network: network, address: address}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// connect (re)establishes the connection. It must be called with mu held
+// or before the Writer is shared.
+func (w *Writer) connect() error {
+	if w.conn != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+
+	if w.network != "" {
+		conn, err := net.Dial(w.network, w.address)
+		if err != nil {
+			return err
+		}
+		w.conn = conn
+		w.framing = framingOf(w.network)
+		return nil
+	}
+
+	for _, path := range localSockets {
+		for _, network := range []string{"unixgram", "unix"} {
+			conn, err := net.Dial(network, path)
+			if err == nil {
+				w.conn = conn
+				w.framing = framingOf(network)
+				return nil
+			}
+		}
+	}
+	return ErrNoLocalSyslog
+}
+
+// Write sends p, without its trailing newline, as a single message.
+func (w *Writer) Write(p []byte) (int, error) {
+	msg := bytes.TrimRight(p, "\n")
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, ErrClosed
+	}
+	if w.conn != nil {
+		if _, err := w.conn.Write(w.frame(msg)); err == nil {
+			return len(p), nil
+		}
+	}
+	if err := w.connect(); err != nil {
+		return 0, err
+	}
+	if _, err := w.conn.Write(w.frame(msg)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// frame applies the framing of the current connection to msg.
+func (w *Writer) frame(msg []byte) []byte {
+	switch w.framing {
+	case octetCounting:
+		out := make([]byte, 0, len(msg)+8)
+		out = strconv.AppendInt(out, int64(len(msg)), 10)
+		out = append(out, ' ')
+		return append(out, msg...)
+	case newlineFraming:
+		return append(msg[:len(msg):len(msg)], '\n')
+	default:
+		return msg
+	}
+}
+
+// Close closes the connection to the syslog daemon.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.closed = true
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
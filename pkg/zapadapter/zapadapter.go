@@ -0,0 +1,146 @@
+// Package zapadapter provides a zapcore.Core backed by a logger.Logger, so
+// that code still calling zap writes through this package's encoders,
+// sinks, buffering and sampling.
+//
+// Example usage:
+//
+//	log := logger.New(logger.Config{Level: logger.InfoLevel, Format: logger.JSONFormat})
+//	zl := zap.New(zapadapter.NewCore(log))
+//	zl.Info("still using zap", zap.Int("attempt", 3))
+//
+// Entry levels and filtering are decided by the logger.Logger; zap's Panic
+// and Fatal behavior still applies after the entry is written.
+package zapadapter
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+// Field keys of entry attributes that zap keeps outside its fields.
+const (
+	NameKey       = "logger"
+	StacktraceKey = "stacktrace"
+)
+
+// zapCallerSkip is the number of frames between a zap.Logger method's
+// caller and Core.Write: the zap.Logger method and CheckedEntry.Write.
+const zapCallerSkip = 2
+
+// core implements zapcore.Core.
+type core struct {
+	logger *logger.Logger
+}
+
+// NewCore returns a zapcore.Core that writes entries to l. When l has
+// AddCaller set, call sites are reported correctly for the methods of
+// zap.Logger; wrap l with WithCallerSkip for SugaredLogger or helpers.
+func NewCore(l *logger.Logger) zapcore.Core {
+	return &core{logger: l.WithCallerSkip(zapCallerSkip + 1)}
+}
+
+// LevelOf maps a zap level to a logger level. DPanicLevel maps to
+// ErrorLevel.
+func LevelOf(level zapcore.Level) logger.Level {
+	switch level {
+	case zapcore.DebugLevel:
+		return logger.DebugLevel
+	case zapcore.InfoLevel:
+		return logger.InfoLevel
+	case zapcore.WarnLevel:
+		return logger.WarnLevel
+	case zapcore.ErrorLevel, zapcore.DPanicLevel:
+		return logger.ErrorLevel
+	case zapcore.PanicLevel:
+		return logger.PanicLevel
+	case zapcore.FatalLevel:
+		return logger.FatalLevel
+	default:
+		if level < zapcore.DebugLevel {
+			return logger.DebugLevel
+		}
+		return logger.FatalLevel
+	}
+}
+
+// Enabled implements zapcore.LevelEnabler.
+func (c *core) Enabled(level zapcore.Level) bool {
+	return c.logger.Enabled(LevelOf(level))
+}
+
+// With implements zapcore.Core.
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	return &core{logger: c.logger.With(toFields(nil, fields)...)}
+}
+
+// Check implements zapcore.Core.
+func (c *core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	out := make([]logger.Field, 0, len(fields)+2)
+	if ent.LoggerName != "" {
+		out = append(out, logger.String(NameKey, ent.LoggerName))
+	}
+	out = toFields(out, fields)
+	if ent.Stack != "" {
+		out = append(out, logger.String(StacktraceKey, ent.Stack))
+	}
+
+	c.logger.Log(LevelOf(ent.Level), ent.Message, out...)
+	return nil
+}
+
+// Sync implements zapcore.Core by flushing the logger.
+func (c *core) Sync() error {
+	c.logger.Flush()
+	return nil
+}
+
+// toFields appends the logger equivalents of zap fields to out. Common
+// field types are converted directly; the others, including namespaces,
+// go through a zapcore.MapObjectEncoder.
+func toFields(out []logger.Field, fields []zapcore.Field) []logger.Field {
+	var enc *zapcore.MapObjectEncoder
+	for _, f := range fields {
+		switch f.Type {
+		case zapcore.StringType:
+			out = append(out, logger.String(f.Key, f.String))
+		case zapcore.Int64Type, zapcore.Int32Type, zapcore.Int16Type, zapcore.Int8Type:
+			out = append(out, logger.Int64(f.Key, f.Integer))
+		case zapcore.Float64Type:
+			out = append(out, logger.Float64(f.Key, math.Float64frombits(uint64(f.Integer))))
+		case zapcore.BoolType:
+			out = append(out, logger.Bool(f.Key, f.Integer == 1))
+		case zapcore.DurationType:
+			out = append(out, logger.Dur(f.Key, time.Duration(f.Integer)))
+		case zapcore.ErrorType:
+			err, _ := f.Interface.(error)
+			out = append(out, logger.Any(f.Key, err))
+		case zapcore.SkipType:
+		default:
+			if enc == nil {
+				enc = zapcore.NewMapObjectEncoder()
+			}
+			f.AddTo(enc)
+		}
+	}
+
+	if enc != nil {
+		for _, key := range slices.Sorted(maps.Keys(enc.Fields)) {
+			out = append(out, logger.Any(key, enc.Fields[key]))
+		}
+	}
+	return out
+}
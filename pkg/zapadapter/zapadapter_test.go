@@ -0,0 +1,173 @@
+package zapadapter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/barnowlsnest/go-logslib/pkg/logger"
+)
+
+func newTestLogger(buf *bytes.Buffer, level logger.Level) *logger.Logger {
+	return logger.New(logger.Config{
+		Level:  level,
+		Format: logger.JSONFormat,
+		Output: buf,
+	})
+}
+
+func decode(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry), line)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestLevelOf(t *testing.T) {
+	tests := []struct {
+		level    zapcore.Level
+		expected logger.Level
+	}{
+		{zapcore.DebugLevel - 1, logger.DebugLevel},
+		{zapcore.DebugLevel, logger.DebugLevel},
+		{zapcore.InfoLevel, logger.InfoLevel},
+		{zapcore.WarnLevel, logger.WarnLevel},
+		{zapcore.ErrorLevel, logger.ErrorLevel},
+		{zapcore.DPanicLevel, logger.ErrorLevel},
+		{zapcore.PanicLevel, logger.PanicLevel},
+		{zapcore.FatalLevel, logger.FatalLevel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			assert.Equal(t, tt.expected, LevelOf(tt.level))
+		})
+	}
+}
+
+func TestCore_Levels(t *testing.T) {
+	buf := &bytes.Buffer{}
+	zl := zap.New(NewCore(newTestLogger(buf, logger.InfoLevel)))
+
+	assert.False(t, zl.Core().Enabled(zapcore.DebugLevel))
+	assert.True(t, zl.Core().Enabled(zapcore.InfoLevel))
+
+	zl.Debug("hidden")
+	zl.Info("info")
+	zl.Warn("warn")
+	zl.Error("error")
+	zl.DPanic("dpanic")
+
+	entries := decode(t, buf)
+	require.Len(t, entries, 4)
+	assert.Equal(t, "INFO", entries[0]["level"])
+	assert.Equal(t, "WARN", entries[1]["level"])
+	assert.Equal(t, "ERROR", entries[2]["level"])
+	assert.Equal(t, "ERROR", entries[3]["level"])
+	assert.Equal(t, "dpanic", entries[3]["message"])
+}
+
+func TestCore_Panic(t *testing.T) {
+	buf := &bytes.Buffer{}
+	zl := zap.New(NewCore(newTestLogger(buf, logger.InfoLevel)))
+
+	assert.Panics(t, func() { zl.Panic("boom") })
+
+	entries := decode(t, buf)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "PANIC", entries[0]["level"])
+	assert.Equal(t, "boom", entries[0]["message"])
+}
+
+func TestCore_Fields(t *testing.T) {
+	buf := &bytes.Buffer{}
+	zl := zap.New(NewCore(newTestLogger(buf, logger.InfoLevel))).
+		Named("worker").
+		With(zap.String("service", "billing"))
+
+	zl.Info("processed",
+		zap.String("id", "abc"),
+		zap.Int("attempt", 3),
+		zap.Uint8("shard", 7),
+		zap.Float64("ratio", 0.5),
+		zap.Bool("retried", true),
+		zap.Duration("took", 1500*time.Millisecond),
+		zap.Error(errors.New("boom")),
+		zap.Strings("tags", []string{"a", "b"}),
+		zap.Skip(),
+	)
+
+	entries := decode(t, buf)
+	require.Len(t, entries, 1)
+	entry := entries[0]
+	assert.Equal(t, "worker", entry[NameKey])
+	assert.Equal(t, "billing", entry["service"])
+	assert.Equal(t, "abc", entry["id"])
+	assert.Equal(t, float64(3), entry["attempt"])
+	assert.Equal(t, float64(7), entry["shard"])
+	assert.Equal(t, 0.5, entry["ratio"])
+	assert.Equal(t, true, entry["retried"])
+	assert.Equal(t, "1.5s", entry["took"])
+	assert.Equal(t, "boom", entry["error"])
+	assert.Equal(t, []interface{}{"a", "b"}, entry["tags"])
+}
+
+func TestCore_Stacktrace(t *testing.T) {
+	buf := &bytes.Buffer{}
+	zl := zap.New(NewCore(newTestLogger(buf, logger.InfoLevel)), zap.AddStacktrace(zapcore.ErrorLevel))
+
+	zl.Error("failed")
+
+	entries := decode(t, buf)
+	require.Len(t, entries, 1)
+	assert.Contains(t, entries[0][StacktraceKey], "TestCore_Stacktrace")
+}
+
+func TestCore_Caller(t *testing.T) {
+	buf := &bytes.Buffer{}
+	zl := zap.New(NewCore(logger.New(logger.Config{
+		Level:     logger.InfoLevel,
+		Format:    logger.TextFormat,
+		Output:    buf,
+		AddCaller: true,
+	})))
+
+	_, _, line, _ := runtime.Caller(0)
+	zl.Info("direct")
+	zl.With(zap.String("k", "v")).Warn("derived")
+
+	output := buf.String()
+	assert.Contains(t, output, "INFO zapadapter/zapadapter_test.go:"+strconv.Itoa(line+1)+" direct")
+	assert.Contains(t, output, "WARN zapadapter/zapadapter_test.go:"+strconv.Itoa(line+2)+" derived")
+}
+
+func TestCore_Sync(t *testing.T) {
+	buf := &bytes.Buffer{}
+	zl := zap.New(NewCore(logger.New(logger.Config{
+		Level:      logger.InfoLevel,
+		Format:     logger.JSONFormat,
+		Output:     buf,
+		BufferSize: 4096,
+	})))
+
+	zl.Info("buffered")
+	assert.Empty(t, buf.String())
+
+	require.NoError(t, zl.Sync())
+	assert.Contains(t, buf.String(), "buffered")
+}